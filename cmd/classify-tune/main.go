@@ -0,0 +1,210 @@
+// Command classify-tune tunes the intent classifier on labeled queries.
+// It reads a CSV of queries and their intents, reports how the current
+// classifier does on them, tunes the keyword weights and threshold, and
+// writes a model file that CLASSIFIER_MODEL_FILE loads at startup.
+//
+//	go run ./cmd/classify-tune -data queries.csv -out classifier.json
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/files"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/registry"
+	"github.com/anuvratrastogi/multi-agent/pkg/bert"
+)
+
+func main() {
+	data := flag.String("data", "", "CSV of labeled queries with query and intent columns (required)")
+	out := flag.String("out", "classifier.json", "model file to write")
+	from := flag.String("model", "", "model file to start tuning from (default: the built-in classifier)")
+	rounds := flag.Int("rounds", 5, "passes over the keywords")
+	holdout := flag.Float64("holdout", 0.2, "share of the queries held out to measure the tuned classifier (0 = none)")
+	examples := flag.Bool("examples", false, "store the training queries as example questions for embedding classification")
+	embedder := flag.String("embedder", "", "also measure embedding classification: ollama or openai")
+	embedderURL := flag.String("embedder-url", "", "embedding server (default: http://localhost:11434 for ollama)")
+	embedderModel := flag.String("embedder-model", "nomic-embed-text", "embedding model")
+	flag.Parse()
+
+	if *data == "" {
+		fmt.Fprintln(os.Stderr, "usage: classify-tune -data queries.csv [-out classifier.json] [-holdout 0.2] [-examples] [-embedder ollama]")
+		os.Exit(2)
+	}
+	if err := run(*data, *out, *from, *rounds, *holdout, *examples, *embedder, *embedderURL, *embedderModel); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(data, out, from string, rounds int, holdout float64, examples bool, embedder, embedderURL, embedderModel string) error {
+	f, err := os.Open(data)
+	if err != nil {
+		return fmt.Errorf("failed to open samples: %w", err)
+	}
+	samples, err := bert.ReadSamples(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	train, test := bert.Split(samples, holdout)
+	fmt.Printf("📋 %d labeled queries: %d to tune on, %d held out\n", len(samples), len(train), len(test))
+
+	c := classifier()
+	if from != "" {
+		m, err := bert.LoadModel(from)
+		if err != nil {
+			return err
+		}
+		c.Apply(m)
+	}
+	for _, s := range samples {
+		if !slices.Contains(c.Intents(), s.Intent) {
+			fmt.Printf("⚠️  Intent %s has no keywords, so keyword classification never picks it\n", s.Intent)
+			break
+		}
+	}
+
+	report("Current keyword classifier", bert.Evaluate(c.Classify, train), bert.Evaluate(c.Classify, test))
+	m := c.Tune(train, rounds)
+	after := bert.Evaluate(c.Classify, test)
+	report("Tuned keyword classifier", bert.Evaluate(c.Classify, train), after)
+	if len(test) > 0 {
+		m.Accuracy = after.Accuracy()
+	}
+
+	if examples {
+		m.Examples = make(map[bert.Intent][]string)
+		for _, s := range train {
+			m.Examples[s.Intent] = append(m.Examples[s.Intent], s.Query)
+		}
+	}
+	if embedder != "" {
+		e, err := newEmbedder(embedder, embedderURL, embedderModel)
+		if err != nil {
+			return err
+		}
+		if err := reportSemantic(e, c, m, test); err != nil {
+			return err
+		}
+	}
+
+	if err := m.Save(out); err != nil {
+		return err
+	}
+	fmt.Printf("✅ Model written to %s; set CLASSIFIER_MODEL_FILE=%s to use it\n", out, out)
+	return nil
+}
+
+// classifier returns the classifier the Manager starts with: the built-in
+// intents, the Files agent's and those of the registered agents.
+func classifier() *bert.Classifier {
+	c := bert.NewClassifier()
+	c.AddIntent(files.Intent.Name, files.Intent.Keywords)
+	for _, spec := range registry.Specs() {
+		for _, intent := range spec.Intents {
+			c.AddIntent(intent.Name, intent.Keywords)
+		}
+	}
+	return c
+}
+
+func newEmbedder(name, url, model string) (bert.Embedder, error) {
+	switch name {
+	case "ollama":
+		return bert.OllamaEmbedder{URL: url, Model: model}, nil
+	case "openai":
+		if url == "" {
+			return nil, fmt.Errorf("-embedder-url is required for openai")
+		}
+		return bert.OpenAIEmbedder{URL: url, Model: model, APIKey: os.Getenv("INTENT_EMBEDDER_API_KEY")}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedder %q: want ollama or openai", name)
+	}
+}
+
+// reportSemantic measures embedding classification on the held-out
+// queries, with the default examples and with the model's examples added.
+func reportSemantic(e bert.Embedder, keywords *bert.Classifier, m *bert.Model, test []bert.Sample) error {
+	if len(test) == 0 {
+		fmt.Println("ℹ️  No held-out queries to measure embedding classification on")
+		return nil
+	}
+	measure := func(title string, s *bert.SemanticClassifier) error {
+		var failed error
+		ev := bert.Evaluate(func(query string) bert.Intent {
+			intent, _, err := s.ClassifyWithConfidence(context.Background(), query)
+			if err != nil && failed == nil {
+				failed = err
+			}
+			return intent
+		}, test)
+		if failed != nil {
+			return failed
+		}
+		report(title, bert.Evaluation{}, ev)
+		return nil
+	}
+
+	s := semantic(e, keywords, nil)
+	if err := measure("Embedding classifier", s); err != nil {
+		return err
+	}
+	if len(m.Examples) > 0 {
+		return measure("Embedding classifier with the training examples", semantic(e, keywords, m.Examples))
+	}
+	return nil
+}
+
+// semantic returns an embedding classifier with the intents of keywords,
+// whose keywords serve as examples of intents without default examples.
+func semantic(e bert.Embedder, keywords *bert.Classifier, examples map[bert.Intent][]string) *bert.SemanticClassifier {
+	s := bert.NewSemanticClassifier(e, keywords)
+	add := func(intent bert.Intent, words []string) {
+		if _, ok := bert.DefaultExamples[intent]; !ok {
+			s.AddIntent(intent, words)
+		}
+	}
+	add(files.Intent.Name, files.Intent.Keywords)
+	for _, spec := range registry.Specs() {
+		for _, intent := range spec.Intents {
+			add(intent.Name, intent.Keywords)
+		}
+	}
+	for intent, queries := range examples {
+		s.AddIntent(intent, queries)
+	}
+	return s
+}
+
+// report prints the accuracy on the tuning and held-out queries, per
+// intent and with the first misses.
+func report(title string, train, test bert.Evaluation) {
+	fmt.Printf("\n%s\n", title)
+	if train.Total > 0 {
+		fmt.Printf("  tuning set:   %5.1f%% (%d/%d)\n", 100*train.Accuracy(), train.Correct, train.Total)
+	}
+	if test.Total == 0 {
+		return
+	}
+	fmt.Printf("  held out:     %5.1f%% (%d/%d)\n", 100*test.Accuracy(), test.Correct, test.Total)
+	intents := make([]bert.Intent, 0, len(test.PerIntent))
+	for intent := range test.PerIntent {
+		intents = append(intents, intent)
+	}
+	slices.Sort(intents)
+	for _, intent := range intents {
+		count := test.PerIntent[intent]
+		fmt.Printf("    %-14s %d/%d\n", intent, count.Correct, count.Total)
+	}
+	for i, miss := range test.Misses {
+		if i == 5 {
+			fmt.Printf("    ... and %d more misses\n", len(test.Misses)-i)
+			break
+		}
+		fmt.Printf("    ✗ %q: %s, want %s\n", miss.Query, miss.Got, miss.Intent)
+	}
+}
@@ -1,226 +1,1053 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
-	"syscall"
+	"sync"
+	"time"
 
 	"github.com/anuvratrastogi/multi-agent/config"
-	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
-	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
 	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
-	"github.com/anuvratrastogi/multi-agent/pkg/localllm"
-	"google.golang.org/adk/agent"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+	"github.com/anuvratrastogi/multi-agent/internal/auth"
+	"github.com/anuvratrastogi/multi-agent/internal/backup"
+	"github.com/anuvratrastogi/multi-agent/internal/batch"
+	"github.com/anuvratrastogi/multi-agent/internal/canary"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/dashboard"
+	"github.com/anuvratrastogi/multi-agent/internal/eval"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/llm"
+	"github.com/anuvratrastogi/multi-agent/internal/repl"
+	"github.com/anuvratrastogi/multi-agent/internal/replay"
+	"github.com/anuvratrastogi/multi-agent/internal/schedule"
+	"github.com/anuvratrastogi/multi-agent/internal/server"
+	"github.com/anuvratrastogi/multi-agent/internal/teams"
+	"github.com/anuvratrastogi/multi-agent/internal/telegram"
+	"github.com/anuvratrastogi/multi-agent/internal/usage"
+	"github.com/anuvratrastogi/multi-agent/internal/wiring"
 	"google.golang.org/adk/model"
-	"google.golang.org/adk/model/gemini"
-	"google.golang.org/adk/runner"
-	"google.golang.org/adk/session"
-	"google.golang.org/genai"
 )
 
 func main() {
+	noEmoji := flag.Bool("no-emoji", false, "plain output without emoji or colors, for logs and limited terminals")
+	configFile := flag.String("config", "", "YAML or TOML config file (default: CONFIG_FILE or ./multiagent.yaml if present)")
+	flag.Parse()
+
+	// "openapi" prints the HTTP API's OpenAPI document for client generators
+	if flag.Arg(0) == "openapi" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(server.OpenAPI()); err != nil {
+			log.Fatalf("Failed to write OpenAPI document: %v", err)
+		}
+		return
+	}
+
+	// "usage" prints the usage report from USAGE_FILE and exits
+	var usageOpts *usageOptions
+	if flag.Arg(0) == "usage" {
+		opts, err := parseUsageFlags(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		usageOpts = opts
+	}
+
+	// "backup" and "restore" move the deployment's assets between
+	// environments and exit
+	var backupOpts *backupOptions
+	var restoreOpts *restoreOptions
+	switch flag.Arg(0) {
+	case "backup":
+		opts, err := parseBackupFlags(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		backupOpts = opts
+	case "restore":
+		opts, err := parseRestoreFlags(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		restoreOpts = opts
+	}
+
+	// "replay" re-renders a debug trace, or re-runs its turn
+	var replayOpts *replayOptions
+	if flag.Arg(0) == "replay" {
+		opts, err := parseReplayFlags(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		replayOpts = opts
+	}
+
+	// "upgrade-check" replays recent questions against a candidate model
+	var canaryOpts *canaryOptions
+	if flag.Arg(0) == "upgrade-check" {
+		opts, err := parseCanaryFlags(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		canaryOpts = opts
+	}
+
+	// "eval" measures intent classification and text-to-SQL on a dataset
+	var evalOpts *evalOptions
+	if flag.Arg(0) == "eval" {
+		opts, err := parseEvalFlags(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		evalOpts = opts
+	}
+
+	// "dashboard" refreshes saved dashboards and exits
+	var dashboardOpts *dashboardOptions
+	if flag.Arg(0) == "dashboard" {
+		opts, err := parseDashboardFlags(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		dashboardOpts = opts
+	}
+
+	// "schedule --run" runs one scheduled report now and exits
+	var scheduleOpts *scheduleOptions
+	if flag.Arg(0) == "schedule" {
+		opts, err := parseScheduleFlags(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		scheduleOpts = opts
+	}
+
+	// "run" answers questions non-interactively instead of starting the REPL
+	var runOpts *runOptions
+	if flag.Arg(0) == "run" {
+		opts, err := parseRunFlags(flag.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		runOpts = opts
+	}
+
+	// Load configuration
+	var cfg *config.Config
+	if *configFile != "" {
+		cfg = config.NewFromFile(*configFile)
+	} else {
+		cfg = config.New()
+	}
+
+	// Backups only copy files, so they work without a database or model,
+	// e.g. to restore into an environment that is not configured yet
+	if backupOpts != nil || restoreOpts != nil {
+		console.Init(console.Options{NoEmoji: *noEmoji || cfg.NoEmoji})
+		var err error
+		if backupOpts != nil {
+			err = runBackup(cfg, backupOpts)
+		} else {
+			err = runRestore(cfg, restoreOpts)
+		}
+		if err != nil {
+			log.Fatalf("Backup error: %v", err)
+		}
+		return
+	}
+
+	// Rendering a trace needs nothing else; a re-run answers from the trace
+	// instead of the model, so only the database must be configured
+	if replayOpts != nil && !replayOpts.rerun {
+		if err := replay.WriteText(os.Stdout, replayOpts.trace, replayOpts.full); err != nil {
+			log.Fatalf("Replay error: %v", err)
+		}
+		return
+	}
+	validate := cfg.Validate
+	if replayOpts != nil {
+		validate = cfg.ValidateSettings
+	}
+	if err := validate(); err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+
+	if usageOpts != nil {
+		if err := printUsage(cfg, usageOpts); err != nil {
+			log.Fatalf("Usage report error: %v", err)
+		}
+		return
+	}
+
+	console.Init(console.Options{NoEmoji: *noEmoji || cfg.NoEmoji, Stderr: runOpts != nil || canaryOpts != nil || evalOpts != nil || replayOpts != nil})
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Handle graceful shutdown
+	// Handle graceful shutdown (Ctrl+C, Ctrl+Break on Windows, SIGTERM).
+	// In the REPL, Ctrl+C first cancels the running turn.
+	interrupter := &repl.Interrupter{}
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, console.ShutdownSignals()...)
+	// A second signal while servers drain exits at once.
 	go func() {
-		<-sigChan
-		fmt.Println("\nShutting down...")
-		cancel()
+		stopping := false
+		for sig := range sigChan {
+			if sig == os.Interrupt && !stopping && interrupter.Interrupt() {
+				continue
+			}
+			if stopping {
+				console.Println("\nForcing exit")
+				os.Exit(1)
+			}
+			console.Println("\nShutting down...")
+			stopping = true
+			cancel()
+		}
 	}()
 
-	// Load configuration
-	cfg := config.New()
-	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Configuration error: %v", err)
+	console.Println("🤖 Multi-Agent System")
+	console.Println("=====================")
+	if cfg.ConfigFile != "" {
+		console.Printf("📄 Config file: %s\n", cfg.ConfigFile)
+	}
+
+	// Dashboards are plain SQL and need no model
+	if dashboardOpts != nil {
+		if err := refreshDashboards(ctx, cfg, dashboardOpts); err != nil {
+			log.Fatalf("Dashboard error: %v", err)
+		}
+		return
 	}
 
-	fmt.Println("🤖 Multi-Agent System")
-	fmt.Println("=====================")
+	// Connect the model and database and create the agents
+	var buildOpts wiring.Options
+	var player *replay.Player
+	if replayOpts != nil {
+		player = replay.NewPlayer(replayOpts.trace)
+		buildOpts.Model = player
+		console.Printf("🐞 Re-running a traced turn with its %d recorded model responses\n", len(replayOpts.trace.LLMCalls()))
+	}
+	sys, err := wiring.Build(ctx, cfg, buildOpts)
+	if err != nil {
+		log.Fatalf("Startup error: %v", err)
+	}
+	defer sys.Close()
+	assistant, toolClient, activeLLM := sys.App, sys.Tools, sys.Model
 
-	// Initialize LLM based on provider
-	var llm model.LLM
-	var err error
+	// Compare a candidate model with the recorded answers and exit
+	if canaryOpts != nil {
+		if err := runCanary(ctx, cfg, assistant, toolClient, activeLLM, canaryOpts); err != nil {
+			log.Printf("Upgrade check error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	if cfg.IsLocalLLM() {
-		fmt.Printf("🔧 Using Local LLM: %s\n", cfg.LocalLLMURL)
-		fmt.Printf("   Model: %s\n", cfg.Model)
-		llm = localllm.New(localllm.Config{
-			BaseURL: cfg.LocalLLMURL,
-			Model:   cfg.Model,
-		})
-	} else {
-		fmt.Printf("🔧 Using Gemini: %s\n", cfg.Model)
-		llm, err = gemini.NewModel(ctx, cfg.Model, &genai.ClientConfig{
-			APIKey: cfg.GoogleAPIKey,
-		})
+	// Re-run the traced turn and exit
+	if replayOpts != nil {
+		if err := runReplay(ctx, assistant, player, replayOpts); err != nil {
+			log.Printf("Replay error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Score the agents on a labeled dataset and exit
+	if evalOpts != nil {
+		if err := runEval(ctx, assistant, toolClient, evalOpts); err != nil {
+			log.Printf("Eval error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Answer the given questions and exit
+	if runOpts != nil {
+		err := batch.Run(ctx, batch.Config{
+			App:    assistant,
+			Format: runOpts.format,
+			Out:    os.Stdout,
+			DryRun: runOpts.dryRun,
+		}, runOpts.queries)
 		if err != nil {
-			log.Fatalf("Failed to initialize Gemini model: %v", err)
+			log.Printf("Batch error: %v", err)
+			os.Exit(1)
 		}
+		return
+	}
+
+	// Load the scheduled reports
+	var scheduler *schedule.Scheduler
+	if cfg.SchedulesEnabled() || scheduleOpts != nil {
+		scheduler, err = newScheduler(ctx, cfg, assistant, sys.Database, sys.Artifacts)
+		if err != nil {
+			log.Fatalf("Failed to load schedules: %v", err)
+		}
+	}
+	if scheduleOpts != nil {
+		job := scheduler.Job(scheduleOpts.name)
+		if job == nil {
+			log.Fatalf("Unknown schedule %q", scheduleOpts.name)
+		}
+		if _, err := scheduler.RunJob(ctx, job); err != nil {
+			log.Fatalf("Schedule error: %v", err)
+		}
+		return
+	}
+
+	// Serve the API and chat bots instead of the REPL when configured
+	if cfg.APIEnabled() || cfg.GRPCEnabled() || cfg.TeamsEnabled() || cfg.TelegramEnabled() || scheduler != nil {
+		err := runServers(ctx, cfg, assistant, sys.Usage, sys.Metrics, sys.Artifacts, scheduler)
+		// Close the database pools and flush the logs before exiting
+		if closeErr := sys.Close(); closeErr != nil {
+			log.Printf("⚠️  Shutdown error: %v", closeErr)
+		}
+		if err != nil {
+			log.Fatalf("Server error: %v", err)
+		}
+		console.Println("👋 Stopped")
+		return
 	}
-	fmt.Println()
 
-	// Initialize database client
-	fmt.Println("📊 Connecting to PostgreSQL...")
-	dbClient, err := sqlagent.NewDirectMCPClient(cfg.DatabaseURL)
+	// Start interactive REPL
+	r, err := repl.New(repl.Config{
+		App:      assistant,
+		Events:   sys.Events,
+		DB:       toolClient,
+		Schema:   sys.Schema,
+		AuditLog: sys.AuditLog,
+		Model:    activeLLM,
+		NewModel: func(ctx context.Context, name string) (model.LLM, error) {
+			return llm.New(ctx, cfg, name)
+		},
+		ListModels: func(ctx context.Context) ([]string, error) {
+			return llm.ListModels(ctx, cfg)
+		},
+		UserID:      cfg.REPLUser,
+		SessionID:   cfg.REPLSession,
+		HistoryFile: cfg.HistoryFile,
+		Interrupter: interrupter,
+	})
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to create REPL: %v", err)
 	}
-	defer dbClient.Close()
-	fmt.Println("✅ Database connected")
+	if err := r.Run(ctx, os.Stdin); err != nil {
+		log.Printf("REPL error: %v", err)
+	}
+}
 
-	// Fetch database schema for SQL agent
-	fmt.Println("📋 Loading database schema...")
-	dbSchema, err := dbClient.DescribeDatabase(ctx)
+// newAuthenticator builds the API's authenticator from the keys file and
+// OIDC settings, or returns nil if neither is configured.
+func newAuthenticator(cfg *config.Config) (*auth.Authenticator, error) {
+	if !cfg.APIAuthEnabled() {
+		return nil, nil
+	}
+	var keys []auth.Key
+	if cfg.APIKeysFile != "" {
+		var err error
+		if keys, err = auth.LoadKeys(cfg.APIKeysFile); err != nil {
+			return nil, err
+		}
+	}
+	authenticator, err := auth.New(auth.Config{
+		Keys:          keys,
+		OIDCIssuer:    cfg.APIOIDCIssuer,
+		OIDCAudience:  cfg.APIOIDCAudience,
+		OIDCUserClaim: cfg.APIOIDCUserClaim,
+		OIDCProfile:   auth.Profile(cfg.APIOIDCProfile),
+		OIDCRateLimit: cfg.APIOIDCRateLimit,
+	})
 	if err != nil {
-		log.Printf("⚠️  Warning: Could not load schema: %v", err)
-		dbSchema = ""
-	} else {
-		fmt.Println("✅ Schema loaded")
+		return nil, fmt.Errorf("failed to configure API authentication: %w", err)
 	}
+	return authenticator, nil
+}
 
-	// Create tools for SQL agent
-	sqlTools, err := sqlagent.CreateMCPTools(dbClient)
+// newScheduler loads the jobs of SchedulesFile and SchedulesTable.
+func newScheduler(ctx context.Context, cfg *config.Config, assistant *app.App, db schedule.Querier, store artifacts.Store) (*schedule.Scheduler, error) {
+	var jobs []*schedule.Job
+	if cfg.SchedulesFile != "" {
+		fileJobs, err := schedule.Load(cfg.SchedulesFile)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, fileJobs...)
+	}
+	if cfg.SchedulesTable != "" {
+		tableJobs, err := schedule.LoadTable(ctx, db, cfg.SchedulesTable)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, tableJobs...)
+	}
+	return schedule.New(schedule.Config{App: assistant, Jobs: jobs, Dir: cfg.ReportsDir, Artifacts: store})
+}
+
+// runServers serves the API, every configured chat bot and the scheduled
+// reports until ctx is cancelled or one fails. They then stop accepting
+// requests, and the turns in flight get until the shutdown timeout to
+// finish before they are cancelled.
+func runServers(ctx context.Context, cfg *config.Config, assistant *app.App, tracker *usage.Tracker, metrics *events.Metrics, store artifacts.Store, scheduler *schedule.Scheduler) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	turnTimeout, err := cfg.TurnTimeoutDuration()
+	if err != nil {
+		return err
+	}
+	shutdownTimeout, err := cfg.ShutdownTimeoutDuration()
 	if err != nil {
-		log.Fatalf("Failed to create SQL tools: %v", err)
+		return err
+	}
+	encryptionKey, err := cfg.APIEncryptionKeyBytes()
+	if err != nil {
+		return err
 	}
 
-	// Initialize Chart Agent
-	fmt.Println("📈 Initializing Chart Agent...")
-	chartAgent, err := chart.New(chart.Config{
-		Model: llm,
-	})
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	serve := func(run func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := run(); err != nil {
+				errs <- err
+				cancel()
+			}
+		}()
+	}
+
+	if cfg.APIEnabled() || cfg.GRPCEnabled() {
+		authenticator, err := newAuthenticator(cfg)
+		if err != nil {
+			return err
+		}
+		srv, err := server.New(server.Config{
+			App:               assistant,
+			TLSCertFile:       cfg.APITLSCertFile,
+			TLSKeyFile:        cfg.APITLSKeyFile,
+			ClientCAFile:      cfg.APITLSClientCAFile,
+			EncryptionKey:     encryptionKey,
+			RequireEncryption: cfg.APIRequireEncryption,
+			TurnTimeout:       turnTimeout,
+			ShutdownTimeout:   shutdownTimeout,
+			Usage:             tracker,
+			Metrics:           metrics,
+			Auth:              authenticator,
+			Artifacts:         store,
+
+			CallbackHosts:        cfg.CallbackHostList(),
+			CallbackAllowPrivate: cfg.APICallbackAllowPrivate,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create API server: %w", err)
+		}
+		scheme := "http"
+		if cfg.APITLSCertFile != "" {
+			scheme = "https"
+		}
+		if cfg.APIEnabled() {
+			console.Printf("🌐 API listening on %s://%s/v1\n", scheme, cfg.APIListenAddr)
+			serve(func() error { return srv.ListenAndServe(ctx, cfg.APIListenAddr) })
+		}
+		if cfg.GRPCEnabled() {
+			console.Printf("🌐 gRPC listening on %s (TLS: %v)\n", cfg.GRPCListenAddr, cfg.APITLSCertFile != "")
+			serve(func() error { return srv.ListenAndServeGRPC(ctx, cfg.GRPCListenAddr) })
+		}
+		if cfg.APITLSClientCAFile != "" {
+			console.Println("🔐 API requires client certificates")
+		}
+		if encryptionKey != nil {
+			console.Printf("🔐 End-to-end encryption enabled (required: %v)\n", cfg.APIRequireEncryption)
+		}
+		if authenticator != nil {
+			console.Println("🔑 API requires an API key or OIDC token")
+		}
+	}
+
+	chartStyle, err := cfg.ChartStyle()
 	if err != nil {
-		log.Fatalf("Failed to create Chart agent: %v", err)
+		return err
 	}
-	fmt.Println("✅ Chart Agent ready")
 
-	// Initialize SQL Agent with schema
-	fmt.Println("🔧 Initializing SQL Agent...")
-	sqlAgent, err := sqlagent.New(sqlagent.Config{
-		Model:          llm,
-		Tools:          sqlTools,
-		DatabaseSchema: dbSchema,
-	})
+	if cfg.TeamsEnabled() {
+		bot, err := teams.New(teams.Config{
+			AppID:       cfg.TeamsAppID,
+			AppPassword: cfg.TeamsAppPassword,
+			TenantID:    cfg.TeamsTenantID,
+			App:         assistant,
+			TurnTimeout: turnTimeout,
+			ChartStyle:  chartStyle,
+
+			ShutdownTimeout: shutdownTimeout,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create Teams bot: %w", err)
+		}
+		console.Printf("💬 Teams bot listening on %s/api/messages\n", cfg.TeamsListenAddr)
+		serve(func() error { return bot.ListenAndServe(ctx, cfg.TeamsListenAddr) })
+	}
+
+	if cfg.TelegramEnabled() {
+		chats, err := cfg.TelegramChatIDs()
+		if err != nil {
+			return err
+		}
+		bot, err := telegram.New(telegram.Config{
+			Token:        cfg.TelegramBotToken,
+			AllowedChats: chats,
+			App:          assistant,
+			TurnTimeout:  turnTimeout,
+			ChartStyle:   chartStyle,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create Telegram bot: %w", err)
+		}
+		console.Printf("💬 Telegram bot polling for %d allowed chat(s)\n", len(chats))
+		serve(func() error { return bot.Run(ctx) })
+	}
+
+	if scheduler != nil {
+		console.Printf("⏰ Running %d scheduled report(s), saved to %s\n", len(scheduler.Jobs()), cfg.ReportsDir)
+		serve(func() error { return scheduler.Run(ctx) })
+	}
+
+	// Refuse new turns on shutdown and wait for those in flight, which the
+	// servers above wait for in turn
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		console.Printf("⏳ Waiting up to %s for turns in flight...\n", shutdownTimeout)
+		drainCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := assistant.Drain(drainCtx); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+// usageOptions are the flags of the "usage" subcommand.
+type usageOptions struct {
+	days    int
+	groupBy string
+	format  string
+}
+
+// parseUsageFlags parses the "usage" subcommand.
+func parseUsageFlags(args []string) (*usageOptions, error) {
+	fs := flag.NewFlagSet("usage", flag.ContinueOnError)
+	days := fs.Int("days", 7, "number of days to report, including today")
+	groupBy := fs.String("by", usage.ByUser, "group by user or team")
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *format != "text" && *format != "json" {
+		return nil, fmt.Errorf("unknown format %q: use text or json", *format)
+	}
+	return &usageOptions{days: *days, groupBy: *groupBy, format: *format}, nil
+}
+
+// printUsage writes the usage report of the configured usage file to stdout.
+func printUsage(cfg *config.Config, opts *usageOptions) error {
+	if cfg.UsageFile == "" {
+		return fmt.Errorf("USAGE_FILE is not set")
+	}
+	tracker, err := usage.Open(cfg.UsageFile, cfg.UsageTeams)
 	if err != nil {
-		log.Fatalf("Failed to create SQL agent: %v", err)
+		return err
+	}
+	r, err := tracker.Report(opts.days, opts.groupBy)
+	if err != nil {
+		return err
 	}
-	fmt.Println("✅ SQL Agent ready")
 
-	// Initialize Manager Agent
-	fmt.Println("👔 Initializing Manager Agent...")
-	managerAgent, err := manager.New(manager.Config{
-		Model:      llm,
-		SQLAgent:   sqlAgent,
-		ChartAgent: chartAgent,
-	})
+	if opts.format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return usage.WriteText(os.Stdout, r)
+}
+
+// backupOptions are the flags of the "backup" subcommand.
+type backupOptions struct {
+	out        string
+	dashboards []string
+}
+
+// parseBackupFlags parses the "backup" subcommand.
+func parseBackupFlags(args []string) (*backupOptions, error) {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := fs.String("out", "multi-agent-backup-"+time.Now().Format("20060102-150405")+".tar.gz", "archive to write")
+	var dashboards stringList
+	fs.Var(&dashboards, "dashboard", "dashboard file to include with its snapshot (may be repeated)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return &backupOptions{out: *out, dashboards: dashboards}, nil
+}
+
+// runBackup writes the configured assets to an archive.
+func runBackup(cfg *config.Config, opts *backupOptions) error {
+	assets := []backup.Asset{
+		{Kind: backup.KindConfig, Path: cfg.ConfigFile},
+		{Kind: backup.KindRedactionPolicy, Path: cfg.RedactionPolicyFile},
+		{Kind: backup.KindSemanticCache, Path: cfg.SemanticCacheFile},
+		{Kind: backup.KindUsage, Path: cfg.UsageFile},
+		{Kind: backup.KindHistory, Path: cfg.HistoryFile},
+	}
+	for _, f := range opts.dashboards {
+		d, err := dashboard.Load(f)
+		if err != nil {
+			return err
+		}
+		assets = append(assets,
+			backup.Asset{Kind: backup.KindDashboard, Path: f},
+			backup.Asset{Kind: backup.KindDashboardSnapshot, Path: d.Snapshot})
+	}
+
+	f, err := os.OpenFile(opts.out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	m, err := backup.Export(f, assets)
+	if cerr := f.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("failed to write archive: %w", cerr)
+	}
 	if err != nil {
-		log.Fatalf("Failed to create Manager agent: %v", err)
+		os.Remove(opts.out)
+		return err
 	}
-	fmt.Println("✅ Manager Agent ready")
 
-	// Create the session service and session
-	fmt.Println("🏃 Creating ADK Runner...")
-	sessionService := session.InMemoryService()
+	for _, a := range m.Assets {
+		console.Printf("  📦 %-18s %s\n", a.Kind, a.Path)
+	}
+	console.Printf("✅ Backed up %d assets to %s\n", len(m.Assets), opts.out)
+	return nil
+}
 
-	adkRunner, err := runner.New(runner.Config{
-		AppName:        "multi-agent",
-		Agent:          managerAgent,
-		SessionService: sessionService,
-	})
+// restoreOptions are the flags of the "restore" subcommand.
+type restoreOptions struct {
+	archive string
+	dir     string
+	force   bool
+}
+
+// parseRestoreFlags parses the "restore" subcommand.
+func parseRestoreFlags(args []string) (*restoreOptions, error) {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "directory for assets whose location is not configured")
+	force := fs.Bool("force", false, "overwrite existing files")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		return nil, fmt.Errorf("usage: multi-agent restore [--dir .] [--force] backup.tar.gz")
+	}
+	return &restoreOptions{archive: fs.Arg(0), dir: *dir, force: *force}, nil
+}
+
+// runRestore restores an archive into this deployment. Assets go where the
+// current configuration expects them, or into the restore directory under
+// their original names.
+func runRestore(cfg *config.Config, opts *restoreOptions) error {
+	f, err := os.Open(opts.archive)
 	if err != nil {
-		log.Fatalf("Failed to create runner: %v", err)
+		return fmt.Errorf("failed to open archive: %w", err)
 	}
-	fmt.Println("✅ Runner ready")
-	fmt.Println()
+	defer f.Close()
 
-	// Start interactive REPL
-	fmt.Println("Type your queries below. Type 'quit' or 'exit' to stop.")
-	fmt.Println("Examples:")
-	fmt.Println("  - Show me all tables in the database")
-	fmt.Println("  - How many orders are there per month?")
-	fmt.Println("  - Create a bar chart of sales by month")
-	fmt.Println()
+	configured := map[backup.Kind]string{
+		backup.KindConfig:          cfg.ConfigFile,
+		backup.KindRedactionPolicy: cfg.RedactionPolicyFile,
+		backup.KindSemanticCache:   cfg.SemanticCacheFile,
+		backup.KindUsage:           cfg.UsageFile,
+		backup.KindHistory:         cfg.HistoryFile,
+	}
+	target := func(a backup.Asset) string {
+		if path := configured[a.Kind]; path != "" {
+			return path
+		}
+		return filepath.Join(opts.dir, filepath.Base(a.Path))
+	}
 
-	sessionID := "session-1"
-	userID := "user-1"
+	m, restored, err := backup.Import(f, target, opts.force)
+	for _, r := range restored {
+		console.Printf("  📦 %-18s %s\n", r.Kind, r.Target)
+	}
+	if err != nil {
+		return err
+	}
+	console.Printf("✅ Restored %d assets from a backup taken %s\n", len(restored), m.CreatedAt.Local().Format("2006-01-02 15:04"))
+	return nil
+}
 
-	// Create the session first
-	_, err = sessionService.Create(ctx, &session.CreateRequest{
-		AppName:   "multi-agent",
-		UserID:    userID,
-		SessionID: sessionID,
-	})
+// canaryOptions are the flags of the "upgrade-check" subcommand.
+type canaryOptions struct {
+	events        string
+	sample        int
+	model         string
+	minSimilarity float64
+	format        string
+}
+
+// parseCanaryFlags parses the "upgrade-check" subcommand.
+func parseCanaryFlags(args []string) (*canaryOptions, error) {
+	fs := flag.NewFlagSet("upgrade-check", flag.ContinueOnError)
+	eventsFile := fs.String("events", "", "event log to sample turns from (default: EVENT_LOG_FILE)")
+	sample := fs.Int("sample", 20, "number of recent questions to replay")
+	model := fs.String("model", "", "candidate model (default: the configured model)")
+	minSimilarity := fs.Float64("min-similarity", canary.DefaultMinSimilarity, "answer similarity below which a replay is a regression")
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *format != "text" && *format != "json" {
+		return nil, fmt.Errorf("unknown format %q: use text or json", *format)
+	}
+	if *sample < 1 {
+		return nil, fmt.Errorf("--sample must be at least 1")
+	}
+	return &canaryOptions{events: *eventsFile, sample: *sample, model: *model, minSimilarity: *minSimilarity, format: *format}, nil
+}
+
+// runCanary replays recent turns with the candidate and reports
+// regressions to stdout.
+func runCanary(ctx context.Context, cfg *config.Config, assistant *app.App, db sqlagent.MCPClient, active *llm.Switchable, opts *canaryOptions) error {
+	path := opts.events
+	if path == "" {
+		path = cfg.EventLogFile
+	}
+	if path == "" {
+		return fmt.Errorf("no event log to sample: set EVENT_LOG_FILE or pass --events")
+	}
+	turns, err := canary.LoadTurns(path, opts.sample)
 	if err != nil {
-		log.Fatalf("Failed to create session: %v", err)
+		return err
+	}
+	if len(turns) == 0 {
+		return fmt.Errorf("no successful turns with queries in %s", path)
 	}
 
-	scanner := bufio.NewScanner(os.Stdin)
-	for {
-		fmt.Print("You: ")
-		if !scanner.Scan() {
-			break
+	if opts.model != "" {
+		candidate, err := llm.New(ctx, cfg, opts.model)
+		if err != nil {
+			return fmt.Errorf("failed to initialize candidate model: %w", err)
 		}
+		active.Set(candidate)
+	}
+	console.Printf("🐤 Replaying %d recent questions against %s\n", len(turns), active.Name())
 
-		input := strings.TrimSpace(scanner.Text())
-		if input == "" {
-			continue
+	r, runErr := canary.Run(ctx, canary.Config{App: assistant, DB: db, MinSimilarity: opts.minSimilarity}, turns)
+	if r == nil {
+		return runErr
+	}
+	if opts.format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return err
 		}
+	} else if err := canary.WriteText(os.Stdout, r); err != nil {
+		return err
+	}
+	return runErr
+}
 
-		if input == "quit" || input == "exit" {
-			fmt.Println("Goodbye! 👋")
-			break
+// evalOptions are the flags of the "eval" subcommand.
+type evalOptions struct {
+	dataset           string
+	format            string
+	minIntentAccuracy float64
+	minExecutionMatch float64
+}
+
+// parseEvalFlags parses the "eval" subcommand.
+func parseEvalFlags(args []string) (*evalOptions, error) {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	dataset := fs.String("dataset", "", "YAML file of labeled intents and SQL questions (required)")
+	format := fs.String("format", "text", "output format: text or json")
+	minIntent := fs.Float64("min-intent-accuracy", 0, "fail if intent accuracy is below this fraction")
+	minExecution := fs.Float64("min-execution-match", 0, "fail if the SQL execution match is below this fraction")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *dataset == "" {
+		return nil, fmt.Errorf("--dataset is required")
+	}
+	if *format != "text" && *format != "json" {
+		return nil, fmt.Errorf("unknown format %q: use text or json", *format)
+	}
+	return &evalOptions{dataset: *dataset, format: *format, minIntentAccuracy: *minIntent, minExecutionMatch: *minExecution}, nil
+}
+
+// runEval scores the agents on the dataset and writes the report to
+// stdout. It fails if a score is below its minimum.
+func runEval(ctx context.Context, assistant *app.App, db sqlagent.MCPClient, opts *evalOptions) error {
+	ds, err := eval.Load(opts.dataset)
+	if err != nil {
+		return err
+	}
+	console.Printf("🧪 Evaluating %d intent and %d SQL cases from %s\n", len(ds.Intents), len(ds.SQL), opts.dataset)
+
+	r, err := eval.Run(ctx, eval.Config{App: assistant, DB: db}, ds)
+	if r == nil {
+		return err
+	}
+	if opts.format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return err
 		}
+	} else if err := eval.WriteText(os.Stdout, r); err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
 
-		// Show intent classification
-		result, _ := managerAgent.ProcessQuery(ctx, input)
-		fmt.Printf("\n📋 Intent: %s (confidence: %.2f)\n", result.ClassifiedIntent, result.Confidence)
-		fmt.Printf("🔄 Workflow: %s\n", result.Workflow)
-		fmt.Printf("🤖 Agents: %s\n\n", strings.Join(result.AgentsUsed, " → "))
+	if r.Intents != nil && r.Intents.Accuracy < opts.minIntentAccuracy {
+		return fmt.Errorf("intent accuracy %.3f is below %.3f", r.Intents.Accuracy, opts.minIntentAccuracy)
+	}
+	if r.SQL != nil && r.SQL.ExecutionMatch < opts.minExecutionMatch {
+		return fmt.Errorf("execution match %.3f is below %.3f", r.SQL.ExecutionMatch, opts.minExecutionMatch)
+	}
+	return nil
+}
 
-		// Create user message
-		userMsg := genai.NewContentFromText(input, genai.RoleUser)
+// replayOptions are the flags of the "replay" subcommand.
+type replayOptions struct {
+	trace *replay.Trace
+	rerun bool
+	full  bool
+}
 
-		// Execute through ADK runner
-		fmt.Println("⏳ Processing...")
-		var responseText strings.Builder
+// parseReplayFlags parses the "replay" subcommand and loads the trace.
+func parseReplayFlags(args []string) (*replayOptions, error) {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	rerun := fs.Bool("rerun", false, "run the turn again, with the recorded model responses and live tools")
+	full := fs.Bool("full", false, "show model requests and untruncated texts and results")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 1 {
+		return nil, fmt.Errorf("usage: multi-agent replay [--rerun] [--full] trace.json")
+	}
+	t, err := replay.Load(fs.Arg(0))
+	if err != nil {
+		return nil, err
+	}
+	if *rerun && t.Resumed {
+		return nil, fmt.Errorf("turns resuming a session, such as approvals, cannot be re-run; re-run the turn that proposed the write")
+	}
+	return &replayOptions{trace: t, rerun: *rerun, full: *full}, nil
+}
 
-		for event, err := range adkRunner.Run(ctx, userID, sessionID, userMsg, agent.RunConfig{}) {
-			if err != nil {
-				fmt.Printf("❌ Error: %v\n", err)
-				break
-			}
+// runReplay asks the traced question again in a new session, answered by
+// player, and writes the new turn and how it differs to stdout.
+func runReplay(ctx context.Context, assistant *app.App, player *replay.Player, opts *replayOptions) error {
+	recorded := opts.trace
+	sessionID := "replay-" + time.Now().Format("20060102-150405")
+	rerun := replay.NewTrace(recorded.UserID, sessionID, recorded.Question, false)
+	turn, err := assistant.Ask(replay.WithTrace(app.WithFresh(ctx), rerun), recorded.UserID, sessionID, recorded.Question, nil)
+	var answer, stopped string
+	if turn != nil {
+		answer, stopped = turn.Text, turn.Stopped
+	}
+	rerun.Complete(answer, stopped, err)
+	if err := replay.WriteText(os.Stdout, rerun, opts.full); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if diverged := player.Divergences(); len(diverged) > 0 {
+		calls := make([]string, len(diverged))
+		for i, n := range diverged {
+			calls[i] = strconv.Itoa(n + 1)
+		}
+		fmt.Printf("Model requests differing from the trace: %s of %d\n", strings.Join(calls, ", "), len(recorded.LLMCalls()))
+	} else {
+		fmt.Println("Model requests match the trace")
+	}
+	if n := player.Remaining(); n > 0 {
+		fmt.Printf("Recorded model responses not used: %d\n", n)
+	}
+	if answer == recorded.Answer {
+		fmt.Println("Answer unchanged")
+	} else {
+		fmt.Printf("Answer changed; recorded: %s\n", recorded.Answer)
+	}
+	return nil
+}
+
+// dashboardOptions are the flags of the "dashboard" subcommand.
+type dashboardOptions struct {
+	files []string
+	full  bool
+}
 
-			// Process the event
-			if event != nil && event.LLMResponse.Content != nil {
-				// Check for tool calls
-				for _, part := range event.LLMResponse.Content.Parts {
-					if part.FunctionCall != nil {
-						fmt.Printf("  🔧 [AGENT] Calling tool: %s\n", part.FunctionCall.Name)
-					}
-					if part.Text != "" {
-						responseText.WriteString(part.Text)
-					}
-				}
+// parseDashboardFlags parses the "dashboard" subcommand.
+func parseDashboardFlags(args []string) (*dashboardOptions, error) {
+	fs := flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	full := fs.Bool("full", false, "re-query every panel in full instead of only changed partitions")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		return nil, fmt.Errorf("usage: multi-agent dashboard [--full] dashboard.yaml...")
+	}
+	return &dashboardOptions{files: fs.Args(), full: *full}, nil
+}
+
+// refreshDashboards refreshes the given dashboards against the database.
+func refreshDashboards(ctx context.Context, cfg *config.Config, opts *dashboardOptions) error {
+	dashboards := make([]*dashboard.Dashboard, len(opts.files))
+	for i, f := range opts.files {
+		d, err := dashboard.Load(f)
+		if err != nil {
+			return err
+		}
+		dashboards[i] = d
+	}
+
+	dbClient, err := wiring.Connect(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer dbClient.Close()
+
+	failed := false
+	for _, d := range dashboards {
+		console.Printf("🔄 Refreshing dashboard %s\n", d.Name)
+		results, err := dashboard.Refresh(ctx, dbClient, d, opts.full)
+		for _, r := range results {
+			switch {
+			case r.Error != "":
+				console.Println(console.Red(fmt.Sprintf("  ❌ %s: %s", r.Panel, r.Error)))
+			case r.Mode == dashboard.ModeIncremental:
+				console.Printf("  ✅ %s: %d changed partitions, %d rows re-queried, %d total (%d ms)\n", r.Panel, r.Partitions, r.Fetched, r.Rows, r.DurationMS)
+			default:
+				console.Printf("  ✅ %s: %s, %d rows (%d ms)\n", r.Panel, r.Mode, r.Rows, r.DurationMS)
 			}
 		}
+		if errors.Is(err, dashboard.ErrFailed) {
+			failed = true
+		} else if err != nil {
+			return err
+		}
+		console.Printf("💾 Snapshot saved to %s\n", d.Snapshot)
+	}
+	if failed {
+		return dashboard.ErrFailed
+	}
+	return nil
+}
 
-		// Print the response
-		if responseText.Len() > 0 {
-			fmt.Printf("\n🤖 Agent: %s\n\n", responseText.String())
-		} else {
-			fmt.Println("\n💡 No response generated.\n")
+// scheduleOptions are the flags of the "schedule" subcommand.
+type scheduleOptions struct {
+	name string
+}
+
+// parseScheduleFlags parses the "schedule" subcommand.
+func parseScheduleFlags(args []string) (*scheduleOptions, error) {
+	fs := flag.NewFlagSet("schedule", flag.ContinueOnError)
+	name := fs.String("run", "", "name of the schedule to run now")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if *name == "" {
+		return nil, fmt.Errorf("usage: multi-agent schedule --run <name>")
+	}
+	return &scheduleOptions{name: *name}, nil
+}
+
+// runOptions are the flags of the "run" subcommand.
+type runOptions struct {
+	queries []string
+	format  string
+	dryRun  bool
+}
+
+// stringList is a flag that may be repeated.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ", ") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// parseRunFlags parses the "run" subcommand. Questions come from --query
+// flags, a --file with one question per line, or stdin when it is piped.
+func parseRunFlags(args []string) (*runOptions, error) {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	var queries stringList
+	fs.Var(&queries, "query", "question to answer (may be repeated)")
+	file := fs.String("file", "", "file with one question per line (- for stdin)")
+	format := fs.String("format", batch.FormatMarkdown, "output format: markdown or json")
+	dryRun := fs.Bool("dry-run", false, "generate SQL without executing it")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	opts := &runOptions{queries: queries, format: *format, dryRun: *dryRun}
+	if opts.format != batch.FormatJSON && opts.format != batch.FormatMarkdown {
+		return nil, fmt.Errorf("unknown format %q: use markdown or json", opts.format)
+	}
+
+	var in io.Reader
+	switch {
+	case *file == "-":
+		in = os.Stdin
+	case *file != "":
+		f, err := os.Open(*file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", *file, err)
+		}
+		defer f.Close()
+		in = f
+	case len(queries) == 0:
+		if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+			in = os.Stdin
+		}
+	}
+	if in != nil {
+		more, err := batch.ReadQueries(in)
+		if err != nil {
+			return nil, err
 		}
+		opts.queries = append(opts.queries, more...)
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Printf("Scanner error: %v", err)
+	if len(opts.queries) == 0 {
+		return nil, fmt.Errorf("usage: multi-agent run --query \"...\" [--file questions.txt] [--format markdown|json] [--dry-run]")
 	}
+	return opts, nil
 }
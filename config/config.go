@@ -1,59 +1,694 @@
 package config
 
 import (
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/dataset"
+	"github.com/anuvratrastogi/multi-agent/internal/i18n"
+	"github.com/anuvratrastogi/multi-agent/pkg/e2e"
 )
 
+// DefaultLocalModel is the model name sent to local servers when none is
+// configured. Servers such as LM Studio answer with whichever model is
+// loaded.
+const DefaultLocalModel = "local-model"
+
 // LLMProvider specifies which LLM backend to use
 type LLMProvider string
 
 const (
 	LLMProviderGemini LLMProvider = "gemini"
 	LLMProviderLocal  LLMProvider = "local"
+	// LLMProviderAzure uses a deployment of an Azure OpenAI resource
+	LLMProviderAzure LLMProvider = "azure"
+	// LLMProviderMock replays canned responses from MockLLMFixtures
+	LLMProviderMock LLMProvider = "mock"
+)
+
+// DBDriver specifies which database the agents query
+type DBDriver string
+
+const (
+	DBDriverPostgres DBDriver = "postgres"
+	// DBDriverBigQuery queries a BigQuery dataset
+	DBDriverBigQuery DBDriver = "bigquery"
+	// DBDriverClickHouse queries ClickHouse over its HTTP interface
+	DBDriverClickHouse DBDriver = "clickhouse"
+	// DBDriverDuckDB queries the files in FilesDir and an optional DuckDB
+	// database file with DuckDB
+	DBDriverDuckDB DBDriver = "duckdb"
+)
+
+// LLMCacheMode specifies how LLM responses are cached
+type LLMCacheMode string
+
+const (
+	// LLMCacheOff sends every request to the model
+	LLMCacheOff LLMCacheMode = "off"
+	// LLMCacheOn answers repeated requests from the cache
+	LLMCacheOn LLMCacheMode = "on"
+	// LLMCacheRecord sends every request to the model and stores the responses
+	LLMCacheRecord LLMCacheMode = "record"
+	// LLMCacheReplay answers only from the cache and fails on a miss
+	LLMCacheReplay LLMCacheMode = "replay"
 )
 
+// IntentEmbedder specifies the server of the sentence embeddings used to
+// classify intents
+type IntentEmbedder string
+
+const (
+	// IntentEmbedderOff classifies intents by keywords
+	IntentEmbedderOff IntentEmbedder = ""
+	// IntentEmbedderOllama embeds with an Ollama embedding model
+	IntentEmbedderOllama IntentEmbedder = "ollama"
+	// IntentEmbedderOpenAI embeds through an OpenAI-compatible /v1/embeddings endpoint
+	IntentEmbedderOpenAI IntentEmbedder = "openai"
+)
+
+// SchemaWatchMode specifies how changes to the database schema are detected
+type SchemaWatchMode string
+
+const (
+	// SchemaWatchOff describes the database once at startup
+	SchemaWatchOff SchemaWatchMode = "off"
+	// SchemaWatchPoll describes the database again every SchemaPollInterval
+	SchemaWatchPoll SchemaWatchMode = "poll"
+	// SchemaWatchNotify listens for the notifications PostgreSQL event
+	// triggers send on SchemaNotifyChannel
+	SchemaWatchNotify SchemaWatchMode = "notify"
+)
+
+// Agent names used to configure per-agent settings.
+const (
+	AgentManager = "manager"
+	AgentSQL     = "sql"
+	AgentChart   = "chart"
+)
+
+// Sampling holds generation parameters. Unset fields fall back to the
+// agent's defaults, then the provider's.
+type Sampling struct {
+	Temperature *float64 `yaml:"temperature"`
+	TopP        *float64 `yaml:"top_p"`
+	MaxTokens   int      `yaml:"max_tokens"`
+	Stop        []string `yaml:"stop"`
+}
+
+// Merge returns s with the fields set in override replaced.
+func (s Sampling) Merge(override Sampling) Sampling {
+	if override.Temperature != nil {
+		s.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		s.TopP = override.TopP
+	}
+	if override.MaxTokens != 0 {
+		s.MaxTokens = override.MaxTokens
+	}
+	if override.Stop != nil {
+		s.Stop = override.Stop
+	}
+	return s
+}
+
+func (s Sampling) validate() error {
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return ErrInvalidSampling
+	}
+	if s.TopP != nil && (*s.TopP < 0 || *s.TopP > 1) {
+		return ErrInvalidSampling
+	}
+	if s.MaxTokens < 0 {
+		return ErrInvalidSampling
+	}
+	return nil
+}
+
 // Config holds the application configuration.
 type Config struct {
-	// DatabaseURL is the PostgreSQL connection string
+	// DatabaseURL is the PostgreSQL or ClickHouse connection string, or the
+	// DuckDB database file
 	DatabaseURL string
-	// LLMProvider specifies which LLM to use: "gemini" or "local"
+	// DBSSLMode is the PostgreSQL sslmode: "disable", "require", "verify-ca" or
+	// "verify-full" (default: the one in DatabaseURL, else "require")
+	DBSSLMode string
+	// DBSSLRootCert is the CA bundle the PostgreSQL server certificate is verified against (optional)
+	DBSSLRootCert string
+	// DBSSLCert and DBSSLKey are the client certificate and key presented to PostgreSQL (optional)
+	DBSSLCert string
+	DBSSLKey  string
+	// DBDriver selects the database: "postgres", "bigquery", "clickhouse" or "duckdb"
+	DBDriver DBDriver
+	// BigQueryProject is the GCP project BigQuery jobs run and are billed in
+	BigQueryProject string
+	// BigQueryDataset is the dataset the agents see and query by default
+	BigQueryDataset string
+	// BigQueryLocation is the location jobs run in, e.g. "EU" (optional)
+	BigQueryLocation string
+	// BigQueryCredentialsFile is a service-account key file (default: Application Default Credentials)
+	BigQueryCredentialsFile string
+	// BigQueryMaxBytes rejects queries whose dry run estimates more bytes processed, e.g. "10GB" (optional)
+	BigQueryMaxBytes string
+	// FilesDir holds CSV, Parquet and JSON files the Files agent queries with DuckDB (optional)
+	FilesDir string
+	// DuckDBBinary is the duckdb command-line tool (default: "duckdb" on the PATH)
+	DuckDBBinary string
+	// LLMProvider specifies which LLM to use: "gemini", "local", "azure" or "mock"
 	LLMProvider LLMProvider
 	// GoogleAPIKey is the API key for Gemini (required if LLMProvider is "gemini")
 	GoogleAPIKey string
 	// Model is the model name to use
 	Model string
+	// CheckModel fails startup if the provider does not list Model among its models
+	CheckModel bool
+	// Sampling holds generation parameters for every agent
+	Sampling Sampling
+	// AgentSampling overrides Sampling per agent (keyed by AgentSQL, AgentChart, AgentManager)
+	AgentSampling map[string]Sampling
 	// LocalLLMURL is the URL for local LLM server (e.g., "http://localhost:1234")
 	LocalLLMURL string
+	// AzureOpenAIEndpoint is the Azure OpenAI resource (e.g., "https://my-resource.openai.azure.com")
+	AzureOpenAIEndpoint string
+	// AzureOpenAIAPIKey is a key of the resource (required if LLMProvider is "azure")
+	AzureOpenAIAPIKey string
+	// AzureOpenAIAPIVersion is the Azure OpenAI REST API version (e.g., "2024-10-21")
+	AzureOpenAIAPIVersion string
+	// AzureOpenAIDeployments maps model names to deployment names, e.g. "gpt-4o=prod-gpt4o" (default: the model name)
+	AzureOpenAIDeployments string
+	// LocalLLMToolRepair handles local model calls to unknown tools: "correct", "feedback" or "off"
+	LocalLLMToolRepair string
+	// LocalLLMToolCalling gives the local model tools through the tools API ("native"), the prompt ("prompt"), or the API until the server rejects it ("auto")
+	LocalLLMToolCalling string
+	// LocalLLMFlavor works around the differences of the local server: "vllm", "tgi", "lmstudio" or "llamacpp" (optional)
+	LocalLLMFlavor string
+	// LocalLLMCAFile is a CA bundle trusted for HTTPS to LocalLLMURL, in addition to the system roots (optional)
+	LocalLLMCAFile string
+	// LocalLLMCertFile and LocalLLMKeyFile are a client certificate and key presented to LocalLLMURL (optional)
+	LocalLLMCertFile string
+	LocalLLMKeyFile  string
+	// LocalLLMProxy is the proxy for requests to LocalLLMURL (default: HTTPS_PROXY and HTTP_PROXY)
+	LocalLLMProxy string
+	// LocalLLMInsecureSkipVerify accepts any certificate from LocalLLMURL; only for testing
+	LocalLLMInsecureSkipVerify bool
+	// LocalLLMTimeout bounds each request to LocalLLMURL, including reading the response (e.g., "5m")
+	LocalLLMTimeout string
+	// LocalLLMHeaders are sent with every request to LocalLLMURL, e.g. "Authorization=Bearer sk-1234,X-Team=analytics"
+	LocalLLMHeaders string
+	// LocalLLMKeepAlive reuses connections to LocalLLMURL between requests
+	LocalLLMKeepAlive bool
+	// LocalLLMIdleTimeout is how long an idle connection to LocalLLMURL is kept for reuse (e.g., "90s")
+	LocalLLMIdleTimeout string
+	// MockLLMFixtures is the fixture file replayed by the mock provider
+	MockLLMFixtures string
+	// LLMCache caches model responses: "off", "on", "record" or "replay"
+	LLMCache LLMCacheMode
+	// LLMCacheDir stores cached responses on disk (in memory if empty; required to record or replay)
+	LLMCacheDir string
+	// LLMRequestsPerMinute caps model calls across all agents; calls over the rate wait (0 = unlimited)
+	LLMRequestsPerMinute int
+	// LLMTokensPerMinute caps prompt and response tokens across all agents; calls over the rate wait (0 = unlimited)
+	LLMTokensPerMinute int
+	// LLMRateLimitRetries is how often a model call the provider refuses as rate limited (HTTP 429) is retried
+	LLMRateLimitRetries int
+	// SemanticCache answers questions similar to earlier ones from a cache
+	SemanticCache bool
+	// SemanticCacheThreshold is the cosine similarity a question needs to reuse an answer (0-1)
+	SemanticCacheThreshold float64
+	// SemanticCacheFile keeps cached answers between runs (optional)
+	SemanticCacheFile string
 	// MCPServerAddr is the address for the MCP server
 	MCPServerAddr string
+	// TeamsAppID is the Microsoft App ID of the Teams bot (enables Teams mode)
+	TeamsAppID string
+	// TeamsAppPassword is the client secret of the Teams bot
+	TeamsAppPassword string
+	// TeamsTenantID restricts the Teams bot to a single tenant (optional)
+	TeamsTenantID string
+	// TeamsListenAddr is the address the Teams messaging endpoint listens on
+	TeamsListenAddr string
+	// RedactionPolicyFile is a YAML file of columns to mask or drop from query results (optional)
+	RedactionPolicyFile string
+	// AccessPolicyFile is a YAML file mapping users to roles and roles to the tables they may query (optional)
+	AccessPolicyFile string
+	// GuardrailsFile is a YAML file of content rules that answers and tool
+	// arguments are checked against before they are shown or run (optional)
+	GuardrailsFile string
+	// SchedulesFile is a YAML file of questions to ask on cron schedules (optional)
+	SchedulesFile string
+	// SchedulesTable is a database table of questions to ask on cron schedules (optional)
+	SchedulesTable string
+	// ReportsDir is where scheduled reports are written
+	ReportsDir string
+	// ArtifactStore is a directory, or an s3://bucket/prefix or gs://bucket/prefix URL, where charts, exported results and reports are kept for download (optional)
+	ArtifactStore string
+	// ArtifactRetention is how long artifacts are kept (e.g., "168h")
+	ArtifactRetention string
+	// ArtifactStoreEndpoint replaces the S3 endpoint, for S3-compatible stores such as MinIO (optional)
+	ArtifactStoreEndpoint string
+	// ArtifactStoreRegion is the region of an S3 bucket (defaults to AWS_REGION, then us-east-1)
+	ArtifactStoreRegion string
+	// ArtifactStoreAccessKey and ArtifactStoreSecretKey are the AWS access keys, or GCS HMAC keys, of a bucket (default to AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)
+	ArtifactStoreAccessKey string
+	ArtifactStoreSecretKey string
+	// ArtifactStoreSessionToken accompanies temporary AWS credentials (AWS_SESSION_TOKEN, optional)
+	ArtifactStoreSessionToken string
+	// ArtifactURLExpiry is how long the signed URLs of artifacts in a bucket are valid (e.g., "1h")
+	ArtifactURLExpiry string
+	// AuditLogFile is an append-only JSONL file recording every executed query (optional)
+	AuditLogFile string
+	// EventLogFile is a JSONL file recording every agent lifecycle event (optional)
+	EventLogFile string
+	// DebugTraceDir saves every turn's model requests, responses and tool calls as a replayable trace file (optional)
+	DebugTraceDir string
+	// LogRedactColumns is a comma-separated list of columns whose values are masked in console output, logs and traces, like the credentials of the configuration (optional)
+	LogRedactColumns string
+	// UsageFile stores daily per-user usage rollups (optional)
+	UsageFile string
+	// UsageTeams lists the user IDs of each team for usage reports (config file only)
+	UsageTeams map[string][]string
+	// SQLWriteMode allows the SQL agent to propose INSERT/UPDATE/DELETE statements for approval
+	SQLWriteMode bool
+	// TelegramBotToken is the Telegram bot token (enables Telegram mode)
+	TelegramBotToken string
+	// TelegramAllowedChats is a comma-separated list of chat IDs the bot answers
+	TelegramAllowedChats string
+	// APIListenAddr is the address the HTTP API listens on (enables server mode)
+	APIListenAddr string
+	// GRPCListenAddr is the address the gRPC API listens on, with the HTTP API's TLS and credentials (enables server mode)
+	GRPCListenAddr string
+	// APITLSCertFile and APITLSKeyFile serve the HTTP API over HTTPS (optional)
+	APITLSCertFile string
+	APITLSKeyFile  string
+	// APITLSClientCAFile requires API clients to present a certificate signed by this CA (optional)
+	APITLSClientCAFile string
+	// APIEncryptionKey is a base64 AES-256 key shared with clients for end-to-end encryption (optional)
+	APIEncryptionKey string
+	// APIRequireEncryption rejects API requests that are not end-to-end encrypted
+	APIRequireEncryption bool
+	// APIKeysFile is a YAML file of API keys, each with a user, profile, rate limit and admin flag (enables authentication)
+	APIKeysFile string
+	// APIOIDCIssuer accepts bearer tokens issued by this OpenID provider (enables authentication)
+	APIOIDCIssuer string
+	// APIOIDCAudience is the audience OIDC tokens must be issued for
+	APIOIDCAudience string
+	// APIOIDCUserClaim is the OIDC token claim used as the user (defaults to "sub")
+	APIOIDCUserClaim string
+	// APIOIDCProfile is the database permission profile of OIDC users: read-only or read-write
+	APIOIDCProfile string
+	// APIOIDCRateLimit is the number of API requests each OIDC user may make per minute (0 = unlimited)
+	APIOIDCRateLimit int
+	// APICallbackHosts is a comma-separated list of the hosts ("host", "host:port", "*.domain") and URL prefixes callbacks may be posted to (optional; any host)
+	APICallbackHosts string
+	// APICallbackAllowPrivate lets callbacks reach loopback, private and link-local addresses
+	APICallbackAllowPrivate bool
+	// ResultMemoryLimit caps the memory held by query results before they spill to disk (e.g., "256MB")
+	ResultMemoryLimit string
+	// ResultSpillDir is where spilled results are written (defaults to the system temp dir)
+	ResultSpillDir string
+	// ResultPreviewRows is the number of rows of a query result shown to the model; larger results are summarized (0 = show every row)
+	ResultPreviewRows int
+	// ResultPreviewSize caps the size of the rows shown to the model (e.g., "32KB"; 0 = no cap)
+	ResultPreviewSize string
+	// ResultMaxRows caps the rows any query returns, whatever limit the model asks for (0 = no cap)
+	ResultMaxRows int
+	// ResultMaxColumns caps the columns of each row a query returns (0 = no cap)
+	ResultMaxColumns int
+	// ResultMaxSize caps the size of the rows a query returns (e.g., "10MB"; 0 = no cap)
+	ResultMaxSize string
+	// InferColumnTypes samples text columns at startup and suggests casts for those holding numbers, dates or booleans
+	InferColumnTypes bool
+	// InferColumnTypesSample is the number of values sampled per text column
+	InferColumnTypesSample int
+	// ColumnStats tells the SQL agent the values of low-cardinality columns, read from PostgreSQL's planner statistics at startup
+	ColumnStats bool
+	// ColumnStatsMaxValues is the most distinct values a column may have to be listed
+	ColumnStatsMaxValues int
+	// DataDictionaryFile is a YAML file describing tables, columns and business terms (optional)
+	DataDictionaryFile string
+	// DataDictionaryTable is a table of table_name, column_name and description rows (optional)
+	DataDictionaryTable string
+	// DataDictionaryComments adds the COMMENT ON descriptions of PostgreSQL tables and columns to the schema
+	DataDictionaryComments bool
+	// DBMaxConcurrent caps concurrent database queries across all sessions (0 = unlimited)
+	DBMaxConcurrent int
+	// DBMaxConcurrentPerSession caps concurrent database queries within a session (0 = unlimited)
+	DBMaxConcurrentPerSession int
+	// DBSearchPath is a comma-separated list of the PostgreSQL schemas whose tables the agents see, in order (e.g., "public,sales")
+	DBSearchPath string
+	// SchemaCacheFile keeps the database schema on disk, so startup skips describing the database (optional)
+	SchemaCacheFile string
+	// SchemaWatch refreshes the schema the agents see when the database changes: "off", "poll" or "notify" (PostgreSQL event triggers)
+	SchemaWatch SchemaWatchMode
+	// SchemaPollInterval is how often "poll" describes the database again to look for changes (e.g., "5m")
+	SchemaPollInterval string
+	// SchemaNotifyChannel is the channel "notify" listens on for DDL notifications
+	SchemaNotifyChannel string
+	// DBQueriesPerSecond caps database calls started per second across all sessions; calls over the rate wait (0 = unlimited)
+	DBQueriesPerSecond int
+	// HistoryFile stores REPL input history between runs
+	HistoryFile string
+	// REPLUser and REPLSession are the user and session the REPL starts in (default "user-1" and "session-1")
+	REPLUser    string
+	REPLSession string
+	// Locale is the BCP 47 tag used to read numbers and dates in questions (e.g., "de-DE")
+	Locale string
+	// ChartTheme is the Mermaid theme of charts: default, neutral, dark, forest or base (optional)
+	ChartTheme string
+	// ChartPalette is a comma-separated list of the colors of chart series and slices, e.g. "#1f77b4,#ff7f0e" (optional)
+	ChartPalette string
+	// ChartWidth and ChartHeight are the size in pixels of bar and line charts and of chart images (0 = default)
+	ChartWidth  int
+	ChartHeight int
+	// ChartDecimals is the number of digits chart values are rounded to (empty = the Chart agent decides)
+	ChartDecimals string
+	// ChartValueLabels draws the values on bars and points and next to pie slices
+	ChartValueLabels bool
+	// ChartLocale writes the numbers in chart titles and labels as in this BCP 47 tag, e.g. "de-DE" (optional)
+	ChartLocale string
+	// ClarifyConfidence is the intent confidence below which the Manager checks whether a question needs clarifying before answering (0 = never)
+	ClarifyConfidence float64
+	// ClassifierFallbackConfidence is the keyword classifier confidence below which the LLM classifies the question instead (0 = never)
+	ClassifierFallbackConfidence float64
+	// ClassifierModelFile is a model written by classify-tune with tuned keyword weights and example questions (optional)
+	ClassifierModelFile string
+	// IntentEmbedder classifies intents by the similarity of sentence embeddings to example questions instead of keywords: "ollama" or "openai" (off by default)
+	IntentEmbedder IntentEmbedder
+	// IntentEmbedderURL is the embedding server (default: http://localhost:11434 for ollama, LocalLLMURL for openai)
+	IntentEmbedderURL string
+	// IntentEmbedderModel is the embedding model
+	IntentEmbedderModel string
+	// IntentEmbedderAPIKey is sent to the openai embedding server as a bearer token (optional)
+	IntentEmbedderAPIKey string
+	// PlanExecute has the Manager plan each question as steps, shown to the user, and carry them out one at a time
+	PlanExecute bool
+	// PlanMaxReplans is how often the rest of a plan is made again after a step fails (0 = never)
+	PlanMaxReplans int
+	// StepRetries is how often a failed plan step is run again by step type, e.g. "sql=2,chart=0" (* applies to the other types; default sql=2)
+	StepRetries string
+	// StepOnFailure is what a plan does once a step's retries failed too, by step type: replan, skip or abort, e.g. "chart=skip,plan=abort" (default replan, and skip for plan)
+	StepOnFailure string
+	// TurnSummary ends every answer with what the agents did: the agents involved, queries with row counts, charts, tokens and duration
+	TurnSummary bool
+	// PromptsDir holds manager.tmpl, sql.tmpl, chart.tmpl and files.tmpl templates replacing the built-in agent instructions (optional)
+	PromptsDir string
+	// PromptsReload re-reads templates in PromptsDir when they change, without a restart
+	PromptsReload bool
+	// PromptVariants assigns sessions to the prompt variants in PromptsDir: a variant name, or weights such as "base=50,concise=50" (default: base)
+	PromptVariants string
+	// PromptGuidance is appended to every agent's instruction, e.g. naming conventions, the fiscal calendar or topics to avoid (optional)
+	PromptGuidance string
+	// PromptGuidanceFile holds more guidance for every agent, read at startup (optional)
+	PromptGuidanceFile string
+	// AgentGuidance is appended to the instruction of one agent, keyed by manager, sql, chart or files (config file only)
+	AgentGuidance map[string]string
+	// NoEmoji prints plain output without emoji or colors
+	NoEmoji bool
+	// KeepReasoning keeps the thinking of reasoning models for /trace and the trace API (discarded by default)
+	KeepReasoning bool
+	// TurnTimeout bounds how long a question may take to answer in the API and bots (e.g., "10m")
+	TurnTimeout string
+	// ShutdownTimeout bounds how long the API, bots and schedules wait for turns in flight on shutdown before cancelling them (e.g., "30s")
+	ShutdownTimeout string
+	// TurnMaxDuration stops a turn gracefully after this long, keeping what it did so far (e.g., "2m")
+	TurnMaxDuration string
+	// TurnMaxLLMCalls stops a turn after this many model responses (0 = unlimited)
+	TurnMaxLLMCalls int
+	// TurnMaxToolCalls stops a turn after this many tool calls (0 = unlimited)
+	TurnMaxToolCalls int
+	// TurnMaxTokens stops a turn after it used this many LLM tokens (0 = unlimited)
+	TurnMaxTokens int
+	// ToolLog logs every tool call with its agent, duration and outcome
+	ToolLog bool
+	// ToolRateLimits caps calls per minute per tool, e.g. "query_database=30,*=120" (* applies to the other tools)
+	ToolRateLimits string
+	// ContextLimits is the context window in tokens per model, e.g. "gemini-2.0-flash=1000000,*=32000" (* applies to the other models); unset disables compaction
+	ContextLimits string
+	// ContextCompactAt is the fraction of the context window at which older history is summarized (0-1)
+	ContextCompactAt float64
+	// ContextKeepTurns is the number of recent user messages always kept verbatim
+	ContextKeepTurns int
+	// ConfigFile is the config file the settings were loaded from, if any
+	ConfigFile string
+
+	fileErr error
 }
 
-// New creates a new Config from environment variables.
+// New creates a new Config from the config file named by CONFIG_FILE (or the
+// first of DefaultConfigFiles found), overridden by environment variables.
 func New() *Config {
-	provider := LLMProvider(getEnvOrDefault("LLM_PROVIDER", "gemini"))
+	return NewFromFile(findConfigFile())
+}
 
-	model := os.Getenv("LLM_MODEL")
-	if model == "" {
-		if provider == LLMProviderGemini {
-			model = "gemini-2.0-flash"
-		} else {
-			model = "local-model"
-		}
+// NewFromFile creates a new Config from a YAML or TOML file, overridden by
+// environment variables. An empty path uses environment variables only.
+// Errors reading the file are reported by Validate.
+func NewFromFile(path string) *Config {
+	c := &Config{
+		LLMProvider:       LLMProviderGemini,
+		DBDriver:          DBDriverPostgres,
+		LLMCache:          LLMCacheOff,
+		LocalLLMURL:       "http://localhost:1234",
+		CheckModel:        true,
+		MCPServerAddr:     "localhost:9000",
+		TeamsListenAddr:   ":3978",
+		ReportsDir:        "reports",
+		ResultMemoryLimit: "256MB",
+		ResultPreviewRows: 50,
+		ResultPreviewSize: "32KB",
+		ResultMaxRows:     10000,
+		ResultMaxColumns:  100,
+		ResultMaxSize:     "10MB",
+
+		SemanticCacheThreshold: 0.95,
+		ContextCompactAt:       0.8,
+		ContextKeepTurns:       4,
+		InferColumnTypesSample: 200,
+		ColumnStatsMaxValues:   20,
+
+		LocalLLMTimeout:     "5m",
+		LocalLLMKeepAlive:   true,
+		LocalLLMIdleTimeout: "90s",
+
+		DBMaxConcurrent:           10,
+		DBMaxConcurrentPerSession: 2,
+		LLMRateLimitRetries:       3,
+
+		DBSearchPath:        "public",
+		SchemaWatch:         SchemaWatchOff,
+		SchemaPollInterval:  "5m",
+		SchemaNotifyChannel: "multi_agent_schema",
+
+		HistoryFile: defaultHistoryFile(),
+		Locale:      i18n.DefaultLocale.Tag,
+
+		ClarifyConfidence: 0.2,
+		ShutdownTimeout:   "30s",
+		ArtifactRetention: "168h",
+		ArtifactURLExpiry: "1h",
+
+		IntentEmbedderModel: "nomic-embed-text",
+		PlanMaxReplans:      1,
+	}
+	if path != "" {
+		c.ConfigFile = path
+		c.fileErr = c.loadFile(path)
 	}
 
-	return &Config{
-		DatabaseURL:   os.Getenv("DATABASE_URL"),
-		LLMProvider:   provider,
-		GoogleAPIKey:  os.Getenv("GOOGLE_API_KEY"),
-		Model:         model,
-		LocalLLMURL:   getEnvOrDefault("LOCAL_LLM_URL", "http://localhost:1234"),
-		MCPServerAddr: getEnvOrDefault("MCP_SERVER_ADDR", "localhost:9000"),
+	c.DatabaseURL = getEnvOrDefault("DATABASE_URL", c.DatabaseURL)
+	c.DBDriver = DBDriver(getEnvOrDefault("DB_DRIVER", string(c.DBDriver)))
+	c.DBSSLMode = getEnvOrDefault("DB_SSLMODE", c.DBSSLMode)
+	c.DBSSLRootCert = getEnvOrDefault("DB_SSLROOTCERT", c.DBSSLRootCert)
+	c.DBSSLCert = getEnvOrDefault("DB_SSLCERT", c.DBSSLCert)
+	c.DBSSLKey = getEnvOrDefault("DB_SSLKEY", c.DBSSLKey)
+	c.BigQueryProject = getEnvOrDefault("BIGQUERY_PROJECT", c.BigQueryProject)
+	c.BigQueryDataset = getEnvOrDefault("BIGQUERY_DATASET", c.BigQueryDataset)
+	c.BigQueryLocation = getEnvOrDefault("BIGQUERY_LOCATION", c.BigQueryLocation)
+	c.BigQueryCredentialsFile = getEnvOrDefault("BIGQUERY_CREDENTIALS_FILE", c.BigQueryCredentialsFile)
+	c.BigQueryMaxBytes = getEnvOrDefault("BIGQUERY_MAX_BYTES", c.BigQueryMaxBytes)
+	c.FilesDir = getEnvOrDefault("FILES_DIR", c.FilesDir)
+	c.DuckDBBinary = getEnvOrDefault("DUCKDB_BINARY", c.DuckDBBinary)
+	c.LLMProvider = LLMProvider(getEnvOrDefault("LLM_PROVIDER", string(c.LLMProvider)))
+	c.GoogleAPIKey = getEnvOrDefault("GOOGLE_API_KEY", c.GoogleAPIKey)
+	c.Model = getEnvOrDefault("LLM_MODEL", c.Model)
+	c.CheckModel = getEnvBool("LLM_CHECK_MODEL", c.CheckModel)
+	c.LocalLLMURL = getEnvOrDefault("LOCAL_LLM_URL", c.LocalLLMURL)
+	c.AzureOpenAIEndpoint = getEnvOrDefault("AZURE_OPENAI_ENDPOINT", c.AzureOpenAIEndpoint)
+	c.AzureOpenAIAPIKey = getEnvOrDefault("AZURE_OPENAI_API_KEY", c.AzureOpenAIAPIKey)
+	c.AzureOpenAIAPIVersion = getEnvOrDefault("AZURE_OPENAI_API_VERSION", c.AzureOpenAIAPIVersion)
+	c.AzureOpenAIDeployments = getEnvOrDefault("AZURE_OPENAI_DEPLOYMENTS", c.AzureOpenAIDeployments)
+	c.MockLLMFixtures = getEnvOrDefault("MOCK_LLM_FIXTURES", c.MockLLMFixtures)
+	c.LocalLLMToolRepair = getEnvOrDefault("LOCAL_LLM_TOOL_REPAIR", c.LocalLLMToolRepair)
+	c.LocalLLMFlavor = getEnvOrDefault("LOCAL_LLM_FLAVOR", c.LocalLLMFlavor)
+	c.LocalLLMToolCalling = getEnvOrDefault("LOCAL_LLM_TOOL_CALLING", c.LocalLLMToolCalling)
+	c.LocalLLMCAFile = getEnvOrDefault("LOCAL_LLM_CA_FILE", c.LocalLLMCAFile)
+	c.LocalLLMCertFile = getEnvOrDefault("LOCAL_LLM_CERT_FILE", c.LocalLLMCertFile)
+	c.LocalLLMKeyFile = getEnvOrDefault("LOCAL_LLM_KEY_FILE", c.LocalLLMKeyFile)
+	c.LocalLLMProxy = getEnvOrDefault("LOCAL_LLM_PROXY", c.LocalLLMProxy)
+	c.LocalLLMInsecureSkipVerify = getEnvBool("LOCAL_LLM_INSECURE_SKIP_VERIFY", c.LocalLLMInsecureSkipVerify)
+	c.LocalLLMTimeout = getEnvOrDefault("LOCAL_LLM_TIMEOUT", c.LocalLLMTimeout)
+	c.LocalLLMHeaders = getEnvOrDefault("LOCAL_LLM_HEADERS", c.LocalLLMHeaders)
+	c.LocalLLMKeepAlive = getEnvBool("LOCAL_LLM_KEEP_ALIVE", c.LocalLLMKeepAlive)
+	c.LocalLLMIdleTimeout = getEnvOrDefault("LOCAL_LLM_IDLE_TIMEOUT", c.LocalLLMIdleTimeout)
+	c.LLMCache = LLMCacheMode(getEnvOrDefault("LLM_CACHE", string(c.LLMCache)))
+	c.LLMCacheDir = getEnvOrDefault("LLM_CACHE_DIR", c.LLMCacheDir)
+	c.LLMRequestsPerMinute = getEnvInt("LLM_REQUESTS_PER_MINUTE", c.LLMRequestsPerMinute)
+	c.LLMTokensPerMinute = getEnvInt("LLM_TOKENS_PER_MINUTE", c.LLMTokensPerMinute)
+	c.LLMRateLimitRetries = getEnvInt("LLM_RATE_LIMIT_RETRIES", c.LLMRateLimitRetries)
+	c.SemanticCache = getEnvBool("SEMANTIC_CACHE", c.SemanticCache)
+	c.SemanticCacheThreshold = *getEnvFloat("SEMANTIC_CACHE_THRESHOLD", &c.SemanticCacheThreshold)
+	c.SemanticCacheFile = getEnvOrDefault("SEMANTIC_CACHE_FILE", c.SemanticCacheFile)
+	c.Sampling.Temperature = getEnvFloat("LLM_TEMPERATURE", c.Sampling.Temperature)
+	c.Sampling.TopP = getEnvFloat("LLM_TOP_P", c.Sampling.TopP)
+	c.Sampling.MaxTokens = getEnvInt("LLM_MAX_TOKENS", c.Sampling.MaxTokens)
+	if stop := os.Getenv("LLM_STOP"); stop != "" {
+		c.Sampling.Stop = strings.Split(stop, ",")
 	}
+	c.MCPServerAddr = getEnvOrDefault("MCP_SERVER_ADDR", c.MCPServerAddr)
+
+	c.TeamsAppID = getEnvOrDefault("TEAMS_APP_ID", c.TeamsAppID)
+	c.TeamsAppPassword = getEnvOrDefault("TEAMS_APP_PASSWORD", c.TeamsAppPassword)
+	c.TeamsTenantID = getEnvOrDefault("TEAMS_TENANT_ID", c.TeamsTenantID)
+	c.TeamsListenAddr = getEnvOrDefault("TEAMS_LISTEN_ADDR", c.TeamsListenAddr)
+
+	c.RedactionPolicyFile = getEnvOrDefault("REDACTION_POLICY_FILE", c.RedactionPolicyFile)
+	c.AccessPolicyFile = getEnvOrDefault("ACCESS_POLICY_FILE", c.AccessPolicyFile)
+	c.GuardrailsFile = getEnvOrDefault("GUARDRAILS_FILE", c.GuardrailsFile)
+	c.SchedulesFile = getEnvOrDefault("SCHEDULES_FILE", c.SchedulesFile)
+	c.SchedulesTable = getEnvOrDefault("SCHEDULES_TABLE", c.SchedulesTable)
+	c.ReportsDir = getEnvOrDefault("REPORTS_DIR", c.ReportsDir)
+	c.ArtifactStore = getEnvOrDefault("ARTIFACT_STORE", c.ArtifactStore)
+	c.ArtifactRetention = getEnvOrDefault("ARTIFACT_RETENTION", c.ArtifactRetention)
+	c.ArtifactStoreEndpoint = getEnvOrDefault("ARTIFACT_STORE_ENDPOINT", c.ArtifactStoreEndpoint)
+	c.ArtifactStoreRegion = getEnvOrDefault("ARTIFACT_STORE_REGION", getEnvOrDefault("AWS_REGION", c.ArtifactStoreRegion))
+	c.ArtifactStoreAccessKey = getEnvOrDefault("ARTIFACT_STORE_ACCESS_KEY", getEnvOrDefault("AWS_ACCESS_KEY_ID", c.ArtifactStoreAccessKey))
+	c.ArtifactStoreSecretKey = getEnvOrDefault("ARTIFACT_STORE_SECRET_KEY", getEnvOrDefault("AWS_SECRET_ACCESS_KEY", c.ArtifactStoreSecretKey))
+	c.ArtifactStoreSessionToken = getEnvOrDefault("AWS_SESSION_TOKEN", c.ArtifactStoreSessionToken)
+	c.ArtifactURLExpiry = getEnvOrDefault("ARTIFACT_URL_EXPIRY", c.ArtifactURLExpiry)
+	c.AuditLogFile = getEnvOrDefault("AUDIT_LOG_FILE", c.AuditLogFile)
+	c.EventLogFile = getEnvOrDefault("EVENT_LOG_FILE", c.EventLogFile)
+	c.DebugTraceDir = getEnvOrDefault("DEBUG_TRACE_DIR", c.DebugTraceDir)
+	c.LogRedactColumns = getEnvOrDefault("LOG_REDACT_COLUMNS", c.LogRedactColumns)
+	c.UsageFile = getEnvOrDefault("USAGE_FILE", c.UsageFile)
+
+	c.SQLWriteMode = getEnvBool("SQL_WRITE_MODE", c.SQLWriteMode)
+
+	c.TelegramBotToken = getEnvOrDefault("TELEGRAM_BOT_TOKEN", c.TelegramBotToken)
+	c.TelegramAllowedChats = getEnvOrDefault("TELEGRAM_ALLOWED_CHATS", c.TelegramAllowedChats)
+
+	c.APIListenAddr = getEnvOrDefault("API_LISTEN_ADDR", c.APIListenAddr)
+	c.GRPCListenAddr = getEnvOrDefault("GRPC_LISTEN_ADDR", c.GRPCListenAddr)
+	c.APITLSCertFile = getEnvOrDefault("API_TLS_CERT_FILE", c.APITLSCertFile)
+	c.APITLSKeyFile = getEnvOrDefault("API_TLS_KEY_FILE", c.APITLSKeyFile)
+	c.APITLSClientCAFile = getEnvOrDefault("API_TLS_CLIENT_CA_FILE", c.APITLSClientCAFile)
+	c.APIEncryptionKey = getEnvOrDefault("API_ENCRYPTION_KEY", c.APIEncryptionKey)
+	c.APIRequireEncryption = getEnvBool("API_REQUIRE_ENCRYPTION", c.APIRequireEncryption)
+	c.APIKeysFile = getEnvOrDefault("API_KEYS_FILE", c.APIKeysFile)
+	c.APIOIDCIssuer = getEnvOrDefault("API_OIDC_ISSUER", c.APIOIDCIssuer)
+	c.APIOIDCAudience = getEnvOrDefault("API_OIDC_AUDIENCE", c.APIOIDCAudience)
+	c.APIOIDCUserClaim = getEnvOrDefault("API_OIDC_USER_CLAIM", c.APIOIDCUserClaim)
+	c.APIOIDCProfile = getEnvOrDefault("API_OIDC_PROFILE", c.APIOIDCProfile)
+	c.APIOIDCRateLimit = getEnvInt("API_OIDC_RATE_LIMIT", c.APIOIDCRateLimit)
+	c.APICallbackHosts = getEnvOrDefault("API_CALLBACK_HOSTS", c.APICallbackHosts)
+	c.APICallbackAllowPrivate = getEnvBool("API_CALLBACK_ALLOW_PRIVATE", c.APICallbackAllowPrivate)
+
+	c.ResultMemoryLimit = getEnvOrDefault("RESULT_MEMORY_LIMIT", c.ResultMemoryLimit)
+	c.ResultSpillDir = getEnvOrDefault("RESULT_SPILL_DIR", c.ResultSpillDir)
+	c.ResultPreviewRows = getEnvInt("RESULT_PREVIEW_ROWS", c.ResultPreviewRows)
+	c.ResultPreviewSize = getEnvOrDefault("RESULT_PREVIEW_SIZE", c.ResultPreviewSize)
+	c.ResultMaxRows = getEnvInt("RESULT_MAX_ROWS", c.ResultMaxRows)
+	c.ResultMaxColumns = getEnvInt("RESULT_MAX_COLUMNS", c.ResultMaxColumns)
+	c.ResultMaxSize = getEnvOrDefault("RESULT_MAX_SIZE", c.ResultMaxSize)
+
+	c.InferColumnTypes = getEnvBool("INFER_COLUMN_TYPES", c.InferColumnTypes)
+	c.InferColumnTypesSample = getEnvInt("INFER_COLUMN_TYPES_SAMPLE", c.InferColumnTypesSample)
+	c.ColumnStats = getEnvBool("COLUMN_STATS", c.ColumnStats)
+	c.ColumnStatsMaxValues = getEnvInt("COLUMN_STATS_MAX_VALUES", c.ColumnStatsMaxValues)
+	c.DataDictionaryFile = getEnvOrDefault("DATA_DICTIONARY_FILE", c.DataDictionaryFile)
+	c.DataDictionaryTable = getEnvOrDefault("DATA_DICTIONARY_TABLE", c.DataDictionaryTable)
+	c.DataDictionaryComments = getEnvBool("DATA_DICTIONARY_COMMENTS", c.DataDictionaryComments)
+
+	c.DBMaxConcurrent = getEnvInt("DB_MAX_CONCURRENT", c.DBMaxConcurrent)
+	c.DBMaxConcurrentPerSession = getEnvInt("DB_MAX_CONCURRENT_PER_SESSION", c.DBMaxConcurrentPerSession)
+	c.DBQueriesPerSecond = getEnvInt("DB_QUERIES_PER_SECOND", c.DBQueriesPerSecond)
+	c.DBSearchPath = getEnvOrDefault("DB_SEARCH_PATH", c.DBSearchPath)
+	c.SchemaCacheFile = getEnvOrDefault("SCHEMA_CACHE_FILE", c.SchemaCacheFile)
+	c.SchemaWatch = SchemaWatchMode(getEnvOrDefault("SCHEMA_WATCH", string(c.SchemaWatch)))
+	c.SchemaPollInterval = getEnvOrDefault("SCHEMA_POLL_INTERVAL", c.SchemaPollInterval)
+	c.SchemaNotifyChannel = getEnvOrDefault("SCHEMA_NOTIFY_CHANNEL", c.SchemaNotifyChannel)
+
+	c.HistoryFile = getEnvOrDefault("REPL_HISTORY_FILE", c.HistoryFile)
+	c.REPLUser = getEnvOrDefault("REPL_USER", c.REPLUser)
+	c.REPLSession = getEnvOrDefault("REPL_SESSION", c.REPLSession)
+	c.NoEmoji = getEnvBool("NO_EMOJI", c.NoEmoji)
+	c.KeepReasoning = getEnvBool("KEEP_REASONING", c.KeepReasoning)
+
+	c.Locale = getEnvOrDefault("LOCALE", c.Locale)
+	c.ChartTheme = getEnvOrDefault("CHART_THEME", c.ChartTheme)
+	c.ChartPalette = getEnvOrDefault("CHART_PALETTE", c.ChartPalette)
+	c.ChartWidth = getEnvInt("CHART_WIDTH", c.ChartWidth)
+	c.ChartHeight = getEnvInt("CHART_HEIGHT", c.ChartHeight)
+	c.ChartDecimals = getEnvOrDefault("CHART_DECIMALS", c.ChartDecimals)
+	c.ChartValueLabels = getEnvBool("CHART_VALUE_LABELS", c.ChartValueLabels)
+	c.ChartLocale = getEnvOrDefault("CHART_LOCALE", c.ChartLocale)
+	c.ClarifyConfidence = *getEnvFloat("CLARIFY_CONFIDENCE", &c.ClarifyConfidence)
+	c.ClassifierFallbackConfidence = *getEnvFloat("CLASSIFIER_FALLBACK_CONFIDENCE", &c.ClassifierFallbackConfidence)
+	c.ClassifierModelFile = getEnvOrDefault("CLASSIFIER_MODEL_FILE", c.ClassifierModelFile)
+	c.IntentEmbedder = IntentEmbedder(getEnvOrDefault("INTENT_EMBEDDER", string(c.IntentEmbedder)))
+	c.IntentEmbedderURL = getEnvOrDefault("INTENT_EMBEDDER_URL", c.IntentEmbedderURL)
+	c.IntentEmbedderModel = getEnvOrDefault("INTENT_EMBEDDER_MODEL", c.IntentEmbedderModel)
+	c.IntentEmbedderAPIKey = getEnvOrDefault("INTENT_EMBEDDER_API_KEY", c.IntentEmbedderAPIKey)
+	c.PlanExecute = getEnvBool("PLAN_EXECUTE", c.PlanExecute)
+	c.PlanMaxReplans = getEnvInt("PLAN_MAX_REPLANS", c.PlanMaxReplans)
+	c.StepRetries = getEnvOrDefault("STEP_RETRIES", c.StepRetries)
+	c.StepOnFailure = getEnvOrDefault("STEP_ON_FAILURE", c.StepOnFailure)
+	c.TurnSummary = getEnvBool("TURN_SUMMARY", c.TurnSummary)
+	c.PromptsDir = getEnvOrDefault("PROMPTS_DIR", c.PromptsDir)
+	c.PromptsReload = getEnvBool("PROMPTS_RELOAD", c.PromptsReload)
+	c.PromptVariants = getEnvOrDefault("PROMPT_VARIANTS", c.PromptVariants)
+	c.PromptGuidance = getEnvOrDefault("PROMPT_GUIDANCE", c.PromptGuidance)
+	c.PromptGuidanceFile = getEnvOrDefault("PROMPT_GUIDANCE_FILE", c.PromptGuidanceFile)
+
+	c.TurnTimeout = getEnvOrDefault("TURN_TIMEOUT", c.TurnTimeout)
+	c.ShutdownTimeout = getEnvOrDefault("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	c.TurnMaxDuration = getEnvOrDefault("TURN_MAX_DURATION", c.TurnMaxDuration)
+	c.TurnMaxLLMCalls = getEnvInt("TURN_MAX_LLM_CALLS", c.TurnMaxLLMCalls)
+	c.TurnMaxToolCalls = getEnvInt("TURN_MAX_TOOL_CALLS", c.TurnMaxToolCalls)
+	c.TurnMaxTokens = getEnvInt("TURN_MAX_TOKENS", c.TurnMaxTokens)
+
+	c.ToolLog = getEnvBool("TOOL_LOG", c.ToolLog)
+	c.ToolRateLimits = getEnvOrDefault("TOOL_RATE_LIMITS", c.ToolRateLimits)
+
+	c.ContextLimits = getEnvOrDefault("CONTEXT_LIMITS", c.ContextLimits)
+	c.ContextCompactAt = *getEnvFloat("CONTEXT_COMPACT_AT", &c.ContextCompactAt)
+	c.ContextKeepTurns = getEnvInt("CONTEXT_KEEP_TURNS", c.ContextKeepTurns)
+
+	if c.Model == "" {
+		switch c.LLMProvider {
+		case LLMProviderGemini:
+			c.Model = "gemini-2.0-flash"
+		case LLMProviderAzure:
+			c.Model = "gpt-4o"
+		default:
+			c.Model = DefaultLocalModel
+		}
+	}
+	return c
 }
 
 // Validate checks if the configuration is valid.
 func (c *Config) Validate() error {
-	if c.DatabaseURL == "" {
+	if c.fileErr != nil {
+		return c.fileErr
+	}
+	if (c.DBDriver == DBDriverPostgres || c.DBDriver == DBDriverClickHouse) && c.DatabaseURL == "" {
 		return ErrMissingDatabaseURL
 	}
 	if c.LLMProvider == LLMProviderGemini && c.GoogleAPIKey == "" {
@@ -62,14 +697,733 @@ func (c *Config) Validate() error {
 	if c.LLMProvider == LLMProviderLocal && c.LocalLLMURL == "" {
 		return ErrMissingLocalLLMURL
 	}
+	if c.LLMProvider == LLMProviderAzure && (c.AzureOpenAIEndpoint == "" || c.AzureOpenAIAPIKey == "") {
+		return ErrMissingAzureOpenAI
+	}
+	if c.LLMProvider == LLMProviderMock && c.MockLLMFixtures == "" {
+		return ErrMissingMockFixtures
+	}
+	return c.ValidateSettings()
+}
+
+// ValidateSettings checks the configuration apart from the database URL and
+// model credentials, for programs that supply their own database and model.
+func (c *Config) ValidateSettings() error {
+	if c.fileErr != nil {
+		return c.fileErr
+	}
+	switch c.DBDriver {
+	case DBDriverPostgres, DBDriverClickHouse:
+	case DBDriverBigQuery:
+		if c.BigQueryProject == "" || c.BigQueryDataset == "" {
+			return ErrMissingBigQueryDataset
+		}
+		if _, err := c.BigQueryMaxBytesValue(); err != nil {
+			return err
+		}
+	case DBDriverDuckDB:
+		if c.DatabaseURL == "" && c.FilesDir == "" {
+			return ErrMissingDuckDBData
+		}
+	default:
+		return ErrInvalidDBDriver
+	}
+	if c.TeamsAppID != "" && c.TeamsAppPassword == "" {
+		return ErrMissingTeamsPassword
+	}
+	if _, err := dataset.ParseSize(c.ResultMemoryLimit); err != nil {
+		return ErrInvalidMemoryLimit
+	}
+	if _, err := c.ResultPreviewBytes(); err != nil {
+		return err
+	}
+	if _, err := c.ResultMaxBytes(); err != nil {
+		return err
+	}
+	if c.DBMaxConcurrent < 0 || c.DBMaxConcurrentPerSession < 0 {
+		return ErrInvalidConcurrency
+	}
+	if c.LLMRequestsPerMinute < 0 || c.LLMTokensPerMinute < 0 || c.LLMRateLimitRetries < 0 || c.DBQueriesPerSecond < 0 {
+		return ErrInvalidRateLimit
+	}
+	if _, err := i18n.ParseLocale(c.Locale); err != nil {
+		return ErrInvalidLocale
+	}
+	if _, err := c.ChartStyle(); err != nil {
+		return err
+	}
+	if c.ClarifyConfidence < 0 || c.ClarifyConfidence > 1 {
+		return ErrInvalidClarify
+	}
+	if c.ClassifierFallbackConfidence < 0 || c.ClassifierFallbackConfidence > 1 {
+		return ErrInvalidClassifierFallback
+	}
+	switch c.IntentEmbedder {
+	case IntentEmbedderOff, IntentEmbedderOllama, IntentEmbedderOpenAI:
+	default:
+		return ErrInvalidIntentEmbedder
+	}
+	if c.PlanMaxReplans < 0 {
+		return ErrInvalidPlanReplans
+	}
+	if _, err := c.StepRetryValues(); err != nil {
+		return err
+	}
+	if _, err := c.StepOnFailureValues(); err != nil {
+		return err
+	}
+	if _, err := c.ShutdownTimeoutDuration(); err != nil {
+		return err
+	}
+	if _, err := c.ArtifactRetentionDuration(); err != nil {
+		return err
+	}
+	if err := c.validateArtifactStore(); err != nil {
+		return err
+	}
+	if _, err := c.TurnTimeoutDuration(); err != nil {
+		return err
+	}
+	if _, err := c.SchemaPollDuration(); err != nil {
+		return err
+	}
+	if _, err := c.TurnMaxDurationValue(); err != nil {
+		return err
+	}
+	if c.TurnMaxLLMCalls < 0 || c.TurnMaxToolCalls < 0 || c.TurnMaxTokens < 0 {
+		return ErrInvalidTurnBudget
+	}
+	if _, _, err := c.LocalLLMTimeouts(); err != nil {
+		return err
+	}
+	if _, err := c.LocalLLMHeaderValues(); err != nil {
+		return err
+	}
+	if _, err := c.AzureOpenAIDeployment(c.Model); err != nil {
+		return err
+	}
+	if _, err := c.ToolRateLimitValues(); err != nil {
+		return err
+	}
+	if err := c.Sampling.validate(); err != nil {
+		return err
+	}
+	switch c.LocalLLMToolRepair {
+	case "", "correct", "feedback", "off":
+	default:
+		return ErrInvalidToolRepair
+	}
+	switch c.LocalLLMFlavor {
+	case "", "vllm", "tgi", "lmstudio", "llamacpp":
+	default:
+		return ErrInvalidLocalLLMFlavor
+	}
+	switch c.LocalLLMToolCalling {
+	case "", "native", "prompt", "auto":
+	default:
+		return ErrInvalidToolCalling
+	}
+	switch c.LLMCache {
+	case "", LLMCacheOff, LLMCacheOn:
+	case LLMCacheRecord, LLMCacheReplay:
+		if c.LLMCacheDir == "" {
+			return ErrMissingLLMCacheDir
+		}
+	default:
+		return ErrInvalidLLMCache
+	}
+	if c.SemanticCacheThreshold <= 0 || c.SemanticCacheThreshold > 1 {
+		return ErrInvalidSemanticCache
+	}
+	if _, err := c.ContextLimit(""); err != nil {
+		return err
+	}
+	if c.ContextCompactAt <= 0 || c.ContextCompactAt > 1 || c.ContextKeepTurns < 1 {
+		return ErrInvalidContextCompaction
+	}
+	if c.InferColumnTypesSample < 1 {
+		return ErrInvalidTypeSample
+	}
+	if c.ColumnStatsMaxValues < 1 {
+		return ErrInvalidColumnStats
+	}
+	for name, s := range c.AgentSampling {
+		if name != AgentManager && name != AgentSQL && name != AgentChart {
+			return ErrUnknownAgent
+		}
+		if err := s.validate(); err != nil {
+			return err
+		}
+	}
+	for name := range c.AgentGuidance {
+		if !slices.Contains(guidanceAgents, name) {
+			return ErrUnknownGuidanceAgent
+		}
+	}
+	if (c.APITLSCertFile == "") != (c.APITLSKeyFile == "") || (c.APITLSClientCAFile != "" && c.APITLSCertFile == "") {
+		return ErrIncompleteAPITLS
+	}
+	switch c.DBSSLMode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		return ErrInvalidDBSSLMode
+	}
+	if (c.DBSSLCert == "") != (c.DBSSLKey == "") {
+		return ErrIncompleteDBSSLCert
+	}
+	if (c.LocalLLMCertFile == "") != (c.LocalLLMKeyFile == "") {
+		return ErrIncompleteLocalLLMCert
+	}
+	if c.LocalLLMProxy != "" {
+		if u, err := url.Parse(c.LocalLLMProxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return ErrInvalidLocalLLMProxy
+		}
+	}
+	if _, err := c.APIEncryptionKeyBytes(); err != nil {
+		return err
+	}
+	if c.APIRequireEncryption && c.APIEncryptionKey == "" {
+		return ErrMissingEncryptionKey
+	}
+	if c.APIOIDCIssuer != "" && c.APIOIDCAudience == "" {
+		return ErrMissingOIDCAudience
+	}
+	if c.APIOIDCProfile != "" && c.APIOIDCProfile != "read-only" && c.APIOIDCProfile != "read-write" || c.APIOIDCRateLimit < 0 {
+		return ErrInvalidOIDCProfile
+	}
+	if c.TelegramEnabled() {
+		chats, err := c.TelegramChatIDs()
+		if err != nil {
+			return err
+		}
+		if len(chats) == 0 {
+			return ErrMissingTelegramChats
+		}
+	}
 	return nil
 }
 
+// IntentEmbedderEndpoint returns the embedding server, defaulting by
+// IntentEmbedder.
+func (c *Config) IntentEmbedderEndpoint() string {
+	switch {
+	case c.IntentEmbedderURL != "":
+		return c.IntentEmbedderURL
+	case c.IntentEmbedder == IntentEmbedderOpenAI:
+		return c.LocalLLMURL
+	default:
+		return "http://localhost:11434"
+	}
+}
+
+// PostgresURL returns DatabaseURL with DBSSLMode, DBSSLRootCert, DBSSLCert
+// and DBSSLKey applied, overriding the same settings in the URL. Both
+// postgres:// URLs and key=value connection strings are supported.
+func (c *Config) PostgresURL() string {
+	params := [][2]string{
+		{"sslmode", c.DBSSLMode},
+		{"sslrootcert", c.DBSSLRootCert},
+		{"sslcert", c.DBSSLCert},
+		{"sslkey", c.DBSSLKey},
+	}
+	params = slices.DeleteFunc(params, func(p [2]string) bool { return p[1] == "" })
+	if len(params) == 0 {
+		return c.DatabaseURL
+	}
+
+	if strings.HasPrefix(c.DatabaseURL, "postgres://") || strings.HasPrefix(c.DatabaseURL, "postgresql://") {
+		u, err := url.Parse(c.DatabaseURL)
+		if err == nil {
+			q := u.Query()
+			for _, p := range params {
+				q.Set(p[0], p[1])
+			}
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	// In a key=value string the last setting wins
+	dsn := c.DatabaseURL
+	for _, p := range params {
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(p[1])
+		dsn += " " + p[0] + "='" + value + "'"
+	}
+	return strings.TrimSpace(dsn)
+}
+
 // IsLocalLLM returns true if using a local LLM
 func (c *Config) IsLocalLLM() bool {
 	return c.LLMProvider == LLMProviderLocal
 }
 
+// IsOpenAICompatible returns true if the LLM is reached through the
+// OpenAI chat completions API: a local LLM server or Azure OpenAI. The
+// LOCAL_LLM_* connection settings apply to both.
+func (c *Config) IsOpenAICompatible() bool {
+	return c.LLMProvider == LLMProviderLocal || c.LLMProvider == LLMProviderAzure
+}
+
+// TeamsEnabled returns true if the Teams bot is configured
+func (c *Config) TeamsEnabled() bool {
+	return c.TeamsAppID != ""
+}
+
+// TelegramEnabled returns true if the Telegram bot is configured
+func (c *Config) TelegramEnabled() bool {
+	return c.TelegramBotToken != ""
+}
+
+// APIEnabled returns true if the HTTP API server is configured
+func (c *Config) APIEnabled() bool {
+	return c.APIListenAddr != ""
+}
+
+// GRPCEnabled returns true if the gRPC API server is configured
+func (c *Config) GRPCEnabled() bool {
+	return c.GRPCListenAddr != ""
+}
+
+// SchedulesEnabled returns true if scheduled reports are configured
+func (c *Config) SchedulesEnabled() bool {
+	return c.SchedulesFile != "" || c.SchedulesTable != ""
+}
+
+// APIAuthEnabled returns true if API clients must present credentials
+func (c *Config) APIAuthEnabled() bool {
+	return c.APIKeysFile != "" || c.APIOIDCIssuer != ""
+}
+
+// ResultMemoryBytes parses ResultMemoryLimit.
+func (c *Config) ResultMemoryBytes() (int64, error) {
+	n, err := dataset.ParseSize(c.ResultMemoryLimit)
+	if err != nil {
+		return 0, ErrInvalidMemoryLimit
+	}
+	return n, nil
+}
+
+// BigQueryMaxBytesValue parses BigQueryMaxBytes; 0 means no limit.
+func (c *Config) BigQueryMaxBytesValue() (int64, error) {
+	if c.BigQueryMaxBytes == "" {
+		return 0, nil
+	}
+	n, err := dataset.ParseSize(c.BigQueryMaxBytes)
+	if err != nil || n < 0 {
+		return 0, ErrInvalidBigQueryMaxBytes
+	}
+	return n, nil
+}
+
+// ResultPreviewBytes parses ResultPreviewSize, checking ResultPreviewRows
+// too.
+func (c *Config) ResultPreviewBytes() (int, error) {
+	if c.ResultPreviewRows < 0 {
+		return 0, ErrInvalidResultPreview
+	}
+	if strings.TrimSpace(c.ResultPreviewSize) == "" {
+		return 0, nil
+	}
+	n, err := dataset.ParseSize(c.ResultPreviewSize)
+	if err != nil {
+		return 0, ErrInvalidResultPreview
+	}
+	return int(n), nil
+}
+
+// ResultMaxBytes parses ResultMaxSize, returning 0 if it is unset.
+func (c *Config) ResultMaxBytes() (int, error) {
+	if c.ResultMaxRows < 0 || c.ResultMaxColumns < 0 {
+		return 0, ErrInvalidResultCaps
+	}
+	if strings.TrimSpace(c.ResultMaxSize) == "" {
+		return 0, nil
+	}
+	n, err := dataset.ParseSize(c.ResultMaxSize)
+	if err != nil {
+		return 0, ErrInvalidResultCaps
+	}
+	return int(n), nil
+}
+
+// ToolRateLimitValues parses ToolRateLimits into calls per minute by tool
+// name, returning nil if it is unset.
+func (c *Config) ToolRateLimitValues() (map[string]int, error) {
+	return parseLimits(c.ToolRateLimits, 1, ErrInvalidToolRateLimit)
+}
+
+// ContextLimit returns the context window of the named model from
+// ContextLimits, or 0 if it has none.
+func (c *Config) ContextLimit(modelName string) (int, error) {
+	limits, err := parseLimits(c.ContextLimits, 1, ErrInvalidContextLimit)
+	if err != nil {
+		return 0, err
+	}
+	if n, ok := limits[modelName]; ok {
+		return n, nil
+	}
+	return limits["*"], nil
+}
+
+// StepRetryValues parses StepRetries into retries by step type, returning
+// nil if it is unset.
+func (c *Config) StepRetryValues() (map[string]int, error) {
+	return parseLimits(c.StepRetries, 0, ErrInvalidStepPolicy)
+}
+
+// LocalLLMTimeouts parses LocalLLMTimeout and LocalLLMIdleTimeout, returning
+// 0 for either if it is unset.
+func (c *Config) LocalLLMTimeouts() (request, idle time.Duration, err error) {
+	parse := func(s string) (time.Duration, error) {
+		if s == "" {
+			return 0, nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return 0, ErrInvalidLocalLLMTimeout
+		}
+		return d, nil
+	}
+	if request, err = parse(c.LocalLLMTimeout); err != nil {
+		return 0, 0, err
+	}
+	if idle, err = parse(c.LocalLLMIdleTimeout); err != nil {
+		return 0, 0, err
+	}
+	return request, idle, nil
+}
+
+// LocalLLMHeaderValues parses LocalLLMHeaders into header values by name,
+// returning nil if it is unset.
+func (c *Config) LocalLLMHeaderValues() (map[string]string, error) {
+	headers, err := parsePairs(c.LocalLLMHeaders, ErrInvalidLocalLLMHeaders)
+	for name := range headers {
+		if strings.ContainsAny(name, " :") {
+			return nil, ErrInvalidLocalLLMHeaders
+		}
+	}
+	return headers, err
+}
+
+// AzureOpenAIDeployment returns the deployment of the named model from
+// AzureOpenAIDeployments, or the model name if it has none.
+func (c *Config) AzureOpenAIDeployment(modelName string) (string, error) {
+	deployments, err := parsePairs(c.AzureOpenAIDeployments, ErrInvalidAzureDeployments)
+	if err != nil {
+		return "", err
+	}
+	if d, ok := deployments[modelName]; ok {
+		return d, nil
+	}
+	return modelName, nil
+}
+
+// parsePairs parses a comma-separated list of name=value with non-empty
+// names, returning nil if s is empty.
+func parsePairs(s string, invalid ConfigError) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	pairs := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(entry, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return nil, invalid
+		}
+		pairs[name] = value
+	}
+	return pairs, nil
+}
+
+// StepOnFailureValues parses StepOnFailure into the recovery of failed
+// steps by step type, returning nil if it is unset.
+func (c *Config) StepOnFailureValues() (map[string]string, error) {
+	if strings.TrimSpace(c.StepOnFailure) == "" {
+		return nil, nil
+	}
+	recoveries := make(map[string]string)
+	for _, entry := range strings.Split(c.StepOnFailure, ",") {
+		name, value, ok := strings.Cut(entry, "=")
+		name, value = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(value))
+		if !ok || name == "" || !slices.Contains([]string{"replan", "skip", "abort"}, value) {
+			return nil, ErrInvalidStepPolicy
+		}
+		recoveries[name] = value
+	}
+	return recoveries, nil
+}
+
+// parseLimits parses a comma-separated list of name=n with n of at least
+// least, returning nil if s is empty.
+func parseLimits(s string, least int, invalid ConfigError) (map[string]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || name == "" || err != nil || n < least {
+			return nil, invalid
+		}
+		limits[name] = n
+	}
+	return limits, nil
+}
+
+// APIEncryptionKeyBytes decodes APIEncryptionKey, returning nil if it is unset.
+func (c *Config) APIEncryptionKeyBytes() ([]byte, error) {
+	if c.APIEncryptionKey == "" {
+		return nil, nil
+	}
+	key, err := e2e.ParseKey(c.APIEncryptionKey)
+	if err != nil {
+		return nil, ErrInvalidEncryptionKey
+	}
+	return key, nil
+}
+
+// TurnTimeoutDuration parses TurnTimeout, returning 0 if it is unset.
+func (c *Config) TurnTimeoutDuration() (time.Duration, error) {
+	if c.TurnTimeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.TurnTimeout)
+	if err != nil || d <= 0 {
+		return 0, ErrInvalidTurnTimeout
+	}
+	return d, nil
+}
+
+// SchemaPollDuration validates the schema watch settings and returns how
+// often to poll, or 0 unless SchemaWatch is "poll".
+func (c *Config) SchemaPollDuration() (time.Duration, error) {
+	switch c.SchemaWatch {
+	case "", SchemaWatchOff:
+		return 0, nil
+	case SchemaWatchNotify:
+		if c.DBDriver != DBDriverPostgres || c.SchemaNotifyChannel == "" {
+			return 0, ErrInvalidSchemaWatch
+		}
+		return 0, nil
+	case SchemaWatchPoll:
+		d, err := time.ParseDuration(c.SchemaPollInterval)
+		if err != nil || d <= 0 {
+			return 0, ErrInvalidSchemaWatch
+		}
+		return d, nil
+	default:
+		return 0, ErrInvalidSchemaWatch
+	}
+}
+
+// ShutdownTimeoutDuration parses ShutdownTimeout.
+func (c *Config) ShutdownTimeoutDuration() (time.Duration, error) {
+	d, err := time.ParseDuration(c.ShutdownTimeout)
+	if err != nil || d <= 0 {
+		return 0, ErrInvalidShutdownTimeout
+	}
+	return d, nil
+}
+
+// ArtifactRetentionDuration parses ArtifactRetention.
+func (c *Config) ArtifactRetentionDuration() (time.Duration, error) {
+	d, err := time.ParseDuration(c.ArtifactRetention)
+	if err != nil || d <= 0 {
+		return 0, ErrInvalidArtifactRetention
+	}
+	return d, nil
+}
+
+// guidanceAgents are the agents AgentGuidance can address.
+var guidanceAgents = []string{AgentManager, AgentSQL, AgentChart, "files"}
+
+// Guidance returns the guidance appended to the agents' instructions,
+// keyed by agent or "all" for every agent, reading PromptGuidanceFile.
+func (c *Config) Guidance() (map[string]string, error) {
+	guidance := make(map[string]string, len(c.AgentGuidance)+1)
+	for name, text := range c.AgentGuidance {
+		guidance[name] = text
+	}
+	all := []string{c.PromptGuidance}
+	if c.PromptGuidanceFile != "" {
+		data, err := os.ReadFile(c.PromptGuidanceFile)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, string(data))
+	}
+	guidance["all"] = strings.TrimSpace(strings.Join(all, "\n"))
+	return guidance, nil
+}
+
+// ChartStyle returns the configured style of charts.
+func (c *Config) ChartStyle() (chart.Style, error) {
+	style := chart.Style{
+		Theme:  c.ChartTheme,
+		Width:  c.ChartWidth,
+		Height: c.ChartHeight,
+		Locale: c.ChartLocale,
+	}
+	for _, color := range strings.Split(c.ChartPalette, ",") {
+		if color = strings.TrimSpace(color); color != "" {
+			style.Palette = append(style.Palette, color)
+		}
+	}
+	if c.ChartDecimals != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(c.ChartDecimals))
+		if err != nil {
+			return chart.Style{}, ErrInvalidChartStyle
+		}
+		style.Decimals = &n
+	}
+	if c.ChartValueLabels {
+		style.ValueLabels = &c.ChartValueLabels
+	}
+	if err := style.Validate(); err != nil {
+		return chart.Style{}, ErrInvalidChartStyle
+	}
+	return style, nil
+}
+
+// ArtifactStoreIsBucket reports whether ArtifactStore is an S3 or GCS
+// bucket rather than a directory.
+func (c *Config) ArtifactStoreIsBucket() bool {
+	return strings.HasPrefix(c.ArtifactStore, "s3://") || strings.HasPrefix(c.ArtifactStore, "gs://")
+}
+
+// ArtifactURLExpiryDuration parses ArtifactURLExpiry.
+func (c *Config) ArtifactURLExpiryDuration() (time.Duration, error) {
+	d, err := time.ParseDuration(c.ArtifactURLExpiry)
+	if err != nil || d <= 0 || d > 7*24*time.Hour {
+		return 0, ErrInvalidArtifactStore
+	}
+	return d, nil
+}
+
+// validateArtifactStore checks the settings of a bucket ArtifactStore.
+func (c *Config) validateArtifactStore() error {
+	if !c.ArtifactStoreIsBucket() {
+		if strings.Contains(c.ArtifactStore, "://") {
+			return ErrInvalidArtifactStore
+		}
+		return nil
+	}
+	_, rest, _ := strings.Cut(c.ArtifactStore, "://")
+	if bucket, _, _ := strings.Cut(rest, "/"); bucket == "" {
+		return ErrInvalidArtifactStore
+	}
+	if _, err := c.ArtifactURLExpiryDuration(); err != nil {
+		return err
+	}
+	if c.ArtifactStoreAccessKey == "" || c.ArtifactStoreSecretKey == "" {
+		return ErrMissingArtifactKeys
+	}
+	return nil
+}
+
+// TurnMaxDurationValue parses TurnMaxDuration, returning 0 if it is unset.
+func (c *Config) TurnMaxDurationValue() (time.Duration, error) {
+	if c.TurnMaxDuration == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(c.TurnMaxDuration)
+	if err != nil || d <= 0 {
+		return 0, ErrInvalidTurnBudget
+	}
+	return d, nil
+}
+
+// SearchPath parses DBSearchPath into schema names, lowercased as
+// PostgreSQL folds unquoted names.
+func (c *Config) SearchPath() []string {
+	var schemas []string
+	for _, field := range strings.Split(c.DBSearchPath, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field != "" && !slices.Contains(schemas, field) {
+			schemas = append(schemas, field)
+		}
+	}
+	return schemas
+}
+
+// CallbackHostList returns the hosts and URL prefixes callbacks may be
+// posted to.
+func (c *Config) CallbackHostList() []string {
+	var hosts []string
+	for _, field := range strings.Split(c.APICallbackHosts, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			hosts = append(hosts, field)
+		}
+	}
+	return hosts
+}
+
+// TelegramChatIDs parses the Telegram chat allow-list.
+func (c *Config) TelegramChatIDs() ([]int64, error) {
+	var ids []int64
+	for _, field := range strings.Split(c.TelegramAllowedChats, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidTelegramChats
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SecretValues returns the credentials of the configuration, which are kept
+// out of console output, logs and traces: API keys, tokens, passwords, the
+// password in DatabaseURL and the values of LocalLLMHeaders.
+func (c *Config) SecretValues() []string {
+	values := []string{
+		c.GoogleAPIKey,
+		c.TeamsAppPassword,
+		c.ArtifactStoreAccessKey,
+		c.ArtifactStoreSecretKey,
+		c.ArtifactStoreSessionToken,
+		c.TelegramBotToken,
+		c.APIEncryptionKey,
+		c.IntentEmbedderAPIKey,
+		c.AzureOpenAIAPIKey,
+	}
+	headers, _ := c.LocalLLMHeaderValues()
+	for _, v := range headers {
+		values = append(values, v)
+	}
+	if u, err := url.Parse(c.DatabaseURL); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			values = append(values, password)
+		}
+	}
+	return slices.DeleteFunc(values, func(v string) bool { return v == "" })
+}
+
+// LogRedactColumnNames parses LogRedactColumns.
+func (c *Config) LogRedactColumnNames() []string {
+	var columns []string
+	for _, field := range strings.Split(c.LogRedactColumns, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+// defaultHistoryFile returns ~/.multi-agent_history, or "" if there is no home directory.
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".multi-agent_history")
+}
+
 func getEnvOrDefault(key, defaultVal string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -77,13 +1431,105 @@ func getEnvOrDefault(key, defaultVal string) string {
 	return defaultVal
 }
 
+// getEnvBool returns true if key is "true", false if it is any other
+// non-empty value, and defaultVal if it is unset.
+func getEnvBool(key string, defaultVal bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	return val == "true"
+}
+
+// getEnvFloat returns the float value of key, defaultVal if unset, or -1 if
+// it is not a number.
+func getEnvFloat(key string, defaultVal *float64) *float64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+	if err != nil {
+		f = -1
+	}
+	return &f
+}
+
+// getEnvInt returns the integer value of key, defaultVal if unset, or -1 if
+// it is not an integer.
+func getEnvInt(key string, defaultVal int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
 // Error definitions
 type ConfigError string
 
 func (e ConfigError) Error() string { return string(e) }
 
 const (
-	ErrMissingDatabaseURL ConfigError = "DATABASE_URL environment variable is required"
-	ErrMissingAPIKey      ConfigError = "GOOGLE_API_KEY environment variable is required when using Gemini"
-	ErrMissingLocalLLMURL ConfigError = "LOCAL_LLM_URL environment variable is required when using local LLM"
+	ErrMissingDatabaseURL        ConfigError = "DATABASE_URL environment variable is required"
+	ErrInvalidDBDriver           ConfigError = "DB_DRIVER must be postgres, bigquery, clickhouse or duckdb"
+	ErrMissingBigQueryDataset    ConfigError = "BIGQUERY_PROJECT and BIGQUERY_DATASET environment variables are required when DB_DRIVER is bigquery"
+	ErrInvalidBigQueryMaxBytes   ConfigError = "BIGQUERY_MAX_BYTES must be a size such as 10GB"
+	ErrMissingDuckDBData         ConfigError = "FILES_DIR or DATABASE_URL (a DuckDB database file) is required when DB_DRIVER is duckdb"
+	ErrMissingAPIKey             ConfigError = "GOOGLE_API_KEY environment variable is required when using Gemini"
+	ErrMissingLocalLLMURL        ConfigError = "LOCAL_LLM_URL environment variable is required when using local LLM"
+	ErrMissingAzureOpenAI        ConfigError = "AZURE_OPENAI_ENDPOINT and AZURE_OPENAI_API_KEY environment variables are required when using Azure OpenAI"
+	ErrMissingMockFixtures       ConfigError = "MOCK_LLM_FIXTURES environment variable is required when using the mock LLM"
+	ErrMissingTeamsPassword      ConfigError = "TEAMS_APP_PASSWORD environment variable is required when TEAMS_APP_ID is set"
+	ErrMissingTelegramChats      ConfigError = "TELEGRAM_ALLOWED_CHATS environment variable is required when TELEGRAM_BOT_TOKEN is set"
+	ErrInvalidTelegramChats      ConfigError = "TELEGRAM_ALLOWED_CHATS must be a comma-separated list of numeric chat IDs"
+	ErrInvalidMemoryLimit        ConfigError = "RESULT_MEMORY_LIMIT must be a size such as 256MB or 1G"
+	ErrInvalidResultPreview      ConfigError = "RESULT_PREVIEW_ROWS must be a non-negative integer and RESULT_PREVIEW_SIZE a size such as 32KB"
+	ErrInvalidResultCaps         ConfigError = "RESULT_MAX_ROWS and RESULT_MAX_COLUMNS must be non-negative integers and RESULT_MAX_SIZE a size such as 10MB"
+	ErrInvalidColumnStats        ConfigError = "COLUMN_STATS_MAX_VALUES must be at least 1"
+	ErrInvalidSchemaWatch        ConfigError = "SCHEMA_WATCH must be off, poll (with SCHEMA_POLL_INTERVAL a positive duration such as 5m) or notify (PostgreSQL only, with SCHEMA_NOTIFY_CHANNEL set)"
+	ErrInvalidConcurrency        ConfigError = "DB_MAX_CONCURRENT and DB_MAX_CONCURRENT_PER_SESSION must be non-negative integers"
+	ErrInvalidRateLimit          ConfigError = "LLM_REQUESTS_PER_MINUTE, LLM_TOKENS_PER_MINUTE, LLM_RATE_LIMIT_RETRIES and DB_QUERIES_PER_SECOND must be non-negative integers"
+	ErrInvalidLocale             ConfigError = "LOCALE must be a language tag such as en-US or de-DE"
+	ErrInvalidChartStyle         ConfigError = "CHART_THEME must be default, neutral, dark, forest or base, CHART_PALETTE a comma-separated list of #rrggbb colors, CHART_WIDTH and CHART_HEIGHT between 0 and 4000, CHART_DECIMALS between 0 and 10 and CHART_LOCALE a language tag such as de-DE"
+	ErrInvalidClarify            ConfigError = "CLARIFY_CONFIDENCE must be between 0 and 1"
+	ErrInvalidClassifierFallback ConfigError = "CLASSIFIER_FALLBACK_CONFIDENCE must be between 0 and 1"
+	ErrInvalidIntentEmbedder     ConfigError = "INTENT_EMBEDDER must be ollama, openai or empty"
+	ErrInvalidPlanReplans        ConfigError = "PLAN_MAX_REPLANS must be a non-negative integer"
+	ErrInvalidStepPolicy         ConfigError = "STEP_RETRIES must be a comma-separated list of step type=retries, e.g. sql=2,chart=0, and STEP_ON_FAILURE of step type=replan, skip or abort"
+	ErrInvalidTurnTimeout        ConfigError = "TURN_TIMEOUT must be a positive duration such as 90s or 10m"
+	ErrInvalidShutdownTimeout    ConfigError = "SHUTDOWN_TIMEOUT must be a positive duration such as 30s"
+	ErrInvalidArtifactRetention  ConfigError = "ARTIFACT_RETENTION must be a positive duration such as 168h"
+	ErrInvalidArtifactStore      ConfigError = "ARTIFACT_STORE must be a directory or an s3://bucket/prefix or gs://bucket/prefix URL, and ARTIFACT_URL_EXPIRY a duration of at most 168h"
+	ErrMissingArtifactKeys       ConfigError = "ARTIFACT_STORE_ACCESS_KEY and ARTIFACT_STORE_SECRET_KEY (or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY) are required when ARTIFACT_STORE is a bucket"
+	ErrInvalidTurnBudget         ConfigError = "TURN_MAX_DURATION must be a positive duration, and TURN_MAX_LLM_CALLS, TURN_MAX_TOOL_CALLS and TURN_MAX_TOKENS non-negative integers"
+	ErrInvalidToolRateLimit      ConfigError = "TOOL_RATE_LIMITS must be a comma-separated list of tool=calls per minute, e.g. query_database=30,*=120"
+	ErrInvalidContextLimit       ConfigError = "CONTEXT_LIMITS must be a comma-separated list of model=tokens, e.g. gemini-2.0-flash=1000000,*=32000"
+	ErrInvalidContextCompaction  ConfigError = "CONTEXT_COMPACT_AT must be greater than 0 and at most 1, and CONTEXT_KEEP_TURNS a positive integer"
+	ErrInvalidSampling           ConfigError = "LLM_TEMPERATURE must be between 0 and 2, LLM_TOP_P between 0 and 1, and LLM_MAX_TOKENS non-negative"
+	ErrUnknownAgent              ConfigError = "agent settings must be for manager, sql or chart"
+	ErrUnknownGuidanceAgent      ConfigError = "agent guidance must be for all, manager, sql, chart or files"
+	ErrInvalidToolRepair         ConfigError = "LOCAL_LLM_TOOL_REPAIR must be correct, feedback or off"
+	ErrInvalidLocalLLMFlavor     ConfigError = "LOCAL_LLM_FLAVOR must be vllm, tgi, lmstudio, llamacpp or empty"
+	ErrInvalidToolCalling        ConfigError = "LOCAL_LLM_TOOL_CALLING must be native, prompt or auto"
+	ErrInvalidLLMCache           ConfigError = "LLM_CACHE must be off, on, record or replay"
+	ErrMissingLLMCacheDir        ConfigError = "LLM_CACHE_DIR environment variable is required when LLM_CACHE is record or replay"
+	ErrInvalidSemanticCache      ConfigError = "SEMANTIC_CACHE_THRESHOLD must be greater than 0 and at most 1"
+	ErrInvalidTypeSample         ConfigError = "INFER_COLUMN_TYPES_SAMPLE must be a positive integer"
+	ErrIncompleteAPITLS          ConfigError = "API_TLS_CERT_FILE and API_TLS_KEY_FILE must be set together, and API_TLS_CLIENT_CA_FILE requires both"
+	ErrInvalidDBSSLMode          ConfigError = "DB_SSLMODE must be disable, require, verify-ca or verify-full"
+	ErrIncompleteDBSSLCert       ConfigError = "DB_SSLCERT and DB_SSLKEY must be set together"
+	ErrIncompleteLocalLLMCert    ConfigError = "LOCAL_LLM_CERT_FILE and LOCAL_LLM_KEY_FILE must be set together"
+	ErrInvalidLocalLLMProxy      ConfigError = "LOCAL_LLM_PROXY must be a URL such as http://proxy.example.com:3128"
+	ErrInvalidLocalLLMTimeout    ConfigError = "LOCAL_LLM_TIMEOUT and LOCAL_LLM_IDLE_TIMEOUT must be positive durations such as 5m or 90s"
+	ErrInvalidLocalLLMHeaders    ConfigError = "LOCAL_LLM_HEADERS must be a comma-separated list of header=value, e.g. Authorization=Bearer sk-1234,X-Team=analytics"
+	ErrInvalidAzureDeployments   ConfigError = "AZURE_OPENAI_DEPLOYMENTS must be a comma-separated list of model=deployment, e.g. gpt-4o=prod-gpt4o"
+	ErrInvalidEncryptionKey      ConfigError = "API_ENCRYPTION_KEY must be 32 bytes, base64-encoded (e.g. openssl rand -base64 32)"
+	ErrMissingEncryptionKey      ConfigError = "API_ENCRYPTION_KEY environment variable is required when API_REQUIRE_ENCRYPTION is set"
+	ErrMissingOIDCAudience       ConfigError = "API_OIDC_AUDIENCE environment variable is required when API_OIDC_ISSUER is set"
+	ErrInvalidOIDCProfile        ConfigError = "API_OIDC_PROFILE must be read-only or read-write, and API_OIDC_RATE_LIMIT a non-negative integer"
 )
@@ -0,0 +1,627 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFiles are looked up in the working directory when neither
+// --config nor CONFIG_FILE names a file.
+var DefaultConfigFiles = []string{"multiagent.yaml", "multiagent.yml", "multiagent.toml"}
+
+// fileConfig is the layout of a config file. Every setting is optional;
+// environment variables override the file.
+type fileConfig struct {
+	LLM struct {
+		Provider     string `yaml:"provider"`
+		Model        string `yaml:"model"`
+		CheckModel   *bool  `yaml:"check_model"`
+		GoogleAPIKey string `yaml:"google_api_key"`
+		LocalURL     string `yaml:"local_url"`
+		ToolRepair   string `yaml:"tool_repair"`
+		Flavor       string `yaml:"flavor"`
+		ToolCalling  string `yaml:"tool_calling"`
+		Proxy        string `yaml:"proxy"`
+		Timeout      string `yaml:"timeout"`
+		KeepAlive    *bool  `yaml:"keep_alive"`
+		IdleTimeout  string `yaml:"idle_timeout"`
+		TLS          struct {
+			CAFile             string `yaml:"ca_file"`
+			CertFile           string `yaml:"cert_file"`
+			KeyFile            string `yaml:"key_file"`
+			InsecureSkipVerify *bool  `yaml:"insecure_skip_verify"`
+		} `yaml:"tls"`
+		MockFixtures string `yaml:"mock_fixtures"`
+		Cache        string `yaml:"cache"`
+		CacheDir     string `yaml:"cache_dir"`
+		Sampling     `yaml:",inline"`
+		RateLimit    struct {
+			RequestsPerMinute *int `yaml:"requests_per_minute"`
+			TokensPerMinute   *int `yaml:"tokens_per_minute"`
+			Retries           *int `yaml:"retries"`
+		} `yaml:"rate_limit"`
+		Azure struct {
+			Endpoint    string            `yaml:"endpoint"`
+			APIKey      string            `yaml:"api_key"`
+			APIVersion  string            `yaml:"api_version"`
+			Deployments map[string]string `yaml:"deployments"`
+		} `yaml:"azure"`
+		// Headers are sent with every request to the local server
+		Headers map[string]string `yaml:"headers"`
+	} `yaml:"llm"`
+	Database struct {
+		URL      string `yaml:"url"`
+		Driver   string `yaml:"driver"`
+		BigQuery struct {
+			Project         string `yaml:"project"`
+			Dataset         string `yaml:"dataset"`
+			Location        string `yaml:"location"`
+			CredentialsFile string `yaml:"credentials_file"`
+			MaxBytes        string `yaml:"max_bytes"`
+		} `yaml:"bigquery"`
+		SSL struct {
+			Mode     string `yaml:"mode"`
+			RootCert string `yaml:"root_cert"`
+			Cert     string `yaml:"cert"`
+			Key      string `yaml:"key"`
+		} `yaml:"ssl"`
+		MCPServerAddr           string `yaml:"mcp_server_addr"`
+		MaxConcurrent           *int   `yaml:"max_concurrent"`
+		MaxConcurrentPerSession *int   `yaml:"max_concurrent_per_session"`
+		QueriesPerSecond        *int   `yaml:"queries_per_second"`
+		ResultMemoryLimit       string `yaml:"result_memory_limit"`
+		ResultSpillDir          string `yaml:"result_spill_dir"`
+		ResultPreviewRows       *int   `yaml:"result_preview_rows"`
+		ResultPreviewSize       string `yaml:"result_preview_size"`
+		ResultMaxRows           *int   `yaml:"result_max_rows"`
+		ResultMaxColumns        *int   `yaml:"result_max_columns"`
+		ResultMaxSize           string `yaml:"result_max_size"`
+		InferColumnTypes        *bool  `yaml:"infer_column_types"`
+		InferColumnTypesSample  *int   `yaml:"infer_column_types_sample"`
+		ColumnStats             *bool  `yaml:"column_stats"`
+		ColumnStatsMaxValues    *int   `yaml:"column_stats_max_values"`
+		DataDictionaryFile      string `yaml:"data_dictionary_file"`
+		DataDictionaryTable     string `yaml:"data_dictionary_table"`
+		DataDictionaryComments  *bool  `yaml:"data_dictionary_comments"`
+		SchemaCacheFile         string `yaml:"schema_cache_file"`
+		SchemaWatch             string `yaml:"schema_watch"`
+		SchemaPollInterval      string `yaml:"schema_poll_interval"`
+		SchemaNotifyChannel     string `yaml:"schema_notify_channel"`
+
+		SearchPath []string `yaml:"search_path"`
+	} `yaml:"database"`
+	Agents struct {
+		SQLWriteMode        *bool    `yaml:"sql_write_mode"`
+		RedactionPolicyFile string   `yaml:"redaction_policy_file"`
+		AccessPolicyFile    string   `yaml:"access_policy_file"`
+		GuardrailsFile      string   `yaml:"guardrails_file"`
+		Locale              string   `yaml:"locale"`
+		ClarifyConfidence   *float64 `yaml:"clarify_confidence"`
+		ClassifierFallback  *float64 `yaml:"classifier_fallback_confidence"`
+		ClassifierModelFile string   `yaml:"classifier_model_file"`
+		IntentEmbedder      string   `yaml:"intent_embedder"`
+		IntentEmbedderURL   string   `yaml:"intent_embedder_url"`
+		IntentEmbedderModel string   `yaml:"intent_embedder_model"`
+		PlanExecute         *bool    `yaml:"plan_execute"`
+		PlanMaxReplans      *int     `yaml:"plan_max_replans"`
+		TurnSummary         *bool    `yaml:"turn_summary"`
+		PromptsDir          string   `yaml:"prompts_dir"`
+		PromptsReload       *bool    `yaml:"prompts_reload"`
+		PromptVariants      string   `yaml:"prompt_variants"`
+		// Guidance is appended to the agents' instructions, keyed by agent
+		// or "all"
+		Guidance     map[string]string `yaml:"guidance"`
+		GuidanceFile string            `yaml:"guidance_file"`
+		// Sampling overrides generation parameters per agent
+		Sampling map[string]Sampling `yaml:"sampling"`
+		// StepPolicies sets the retries and recovery of failed plan steps
+		// by step type; "*" applies to the others
+		StepPolicies map[string]stepPolicy `yaml:"step_policies"`
+	} `yaml:"agents"`
+	API struct {
+		ListenAddr        string `yaml:"listen_addr"`
+		GRPCListenAddr    string `yaml:"grpc_listen_addr"`
+		TLSCertFile       string `yaml:"tls_cert_file"`
+		TLSKeyFile        string `yaml:"tls_key_file"`
+		TLSClientCAFile   string `yaml:"tls_client_ca_file"`
+		EncryptionKey     string `yaml:"encryption_key"`
+		RequireEncryption *bool  `yaml:"require_encryption"`
+		KeysFile          string `yaml:"keys_file"`
+		OIDC              struct {
+			Issuer    string `yaml:"issuer"`
+			Audience  string `yaml:"audience"`
+			UserClaim string `yaml:"user_claim"`
+			Profile   string `yaml:"profile"`
+			RateLimit *int   `yaml:"rate_limit"`
+		} `yaml:"oidc"`
+		Callbacks struct {
+			Hosts        []string `yaml:"hosts"`
+			AllowPrivate *bool    `yaml:"allow_private"`
+		} `yaml:"callbacks"`
+	} `yaml:"api"`
+	Teams struct {
+		AppID       string `yaml:"app_id"`
+		AppPassword string `yaml:"app_password"`
+		TenantID    string `yaml:"tenant_id"`
+		ListenAddr  string `yaml:"listen_addr"`
+	} `yaml:"teams"`
+	Telegram struct {
+		BotToken     string   `yaml:"bot_token"`
+		AllowedChats []string `yaml:"allowed_chats"`
+	} `yaml:"telegram"`
+	Logging struct {
+		AuditLogFile  string   `yaml:"audit_log_file"`
+		EventLogFile  string   `yaml:"event_log_file"`
+		DebugTraceDir string   `yaml:"debug_trace_dir"`
+		RedactColumns []string `yaml:"redact_columns"`
+		HistoryFile   string   `yaml:"history_file"`
+		NoEmoji       *bool    `yaml:"no_emoji"`
+		KeepReasoning *bool    `yaml:"keep_reasoning"`
+	} `yaml:"logging"`
+	REPL struct {
+		User    string `yaml:"user"`
+		Session string `yaml:"session"`
+	} `yaml:"repl"`
+	Timeouts struct {
+		Turn     string `yaml:"turn"`
+		Shutdown string `yaml:"shutdown"`
+	} `yaml:"timeouts"`
+	TurnBudget struct {
+		MaxDuration  string `yaml:"max_duration"`
+		MaxLLMCalls  *int   `yaml:"max_llm_calls"`
+		MaxToolCalls *int   `yaml:"max_tool_calls"`
+		MaxTokens    *int   `yaml:"max_tokens"`
+	} `yaml:"turn_budget"`
+	Tools struct {
+		Log *bool `yaml:"log"`
+		// RateLimits caps calls per minute by tool name; "*" applies to the others
+		RateLimits map[string]int `yaml:"rate_limits"`
+	} `yaml:"tools"`
+	Context struct {
+		// Limits is the context window in tokens by model name; "*" applies to the others
+		Limits    map[string]int `yaml:"limits"`
+		CompactAt *float64       `yaml:"compact_at"`
+		KeepTurns *int           `yaml:"keep_turns"`
+	} `yaml:"context"`
+	SemanticCache struct {
+		Enabled   *bool    `yaml:"enabled"`
+		Threshold *float64 `yaml:"threshold"`
+		File      string   `yaml:"file"`
+	} `yaml:"semantic_cache"`
+	Schedules struct {
+		File       string `yaml:"file"`
+		Table      string `yaml:"table"`
+		ReportsDir string `yaml:"reports_dir"`
+	} `yaml:"schedules"`
+	Files struct {
+		Dir          string `yaml:"dir"`
+		DuckDBBinary string `yaml:"duckdb_binary"`
+	} `yaml:"files"`
+	Usage struct {
+		File string `yaml:"file"`
+		// Teams lists the user IDs of each team
+		Teams map[string][]string `yaml:"teams"`
+	} `yaml:"usage"`
+	Charts struct {
+		Theme       string   `yaml:"theme"`
+		Palette     []string `yaml:"palette"`
+		Width       *int     `yaml:"width"`
+		Height      *int     `yaml:"height"`
+		Decimals    *int     `yaml:"decimals"`
+		ValueLabels *bool    `yaml:"value_labels"`
+		Locale      string   `yaml:"locale"`
+	} `yaml:"charts"`
+	Artifacts struct {
+		Store     string `yaml:"store"`
+		Retention string `yaml:"retention"`
+		Endpoint  string `yaml:"endpoint"`
+		Region    string `yaml:"region"`
+		AccessKey string `yaml:"access_key"`
+		SecretKey string `yaml:"secret_key"`
+		URLExpiry string `yaml:"url_expiry"`
+	} `yaml:"artifacts"`
+}
+
+// findConfigFile returns CONFIG_FILE, or the first of DefaultConfigFiles
+// that exists, or "".
+func findConfigFile() string {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return path
+	}
+	for _, name := range DefaultConfigFiles {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// loadFile applies the settings in a YAML or TOML file to c.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		values, err := parseTOML(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		if data, err = yaml.Marshal(values); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	var f fileConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	setString(&c.LLMProvider, LLMProvider(f.LLM.Provider))
+	setString(&c.Model, f.LLM.Model)
+	setValue(&c.CheckModel, f.LLM.CheckModel)
+	setString(&c.GoogleAPIKey, f.LLM.GoogleAPIKey)
+	setString(&c.LocalLLMURL, f.LLM.LocalURL)
+	setString(&c.LocalLLMToolRepair, f.LLM.ToolRepair)
+	setString(&c.LocalLLMFlavor, f.LLM.Flavor)
+	setString(&c.LocalLLMToolCalling, f.LLM.ToolCalling)
+	setString(&c.LocalLLMProxy, f.LLM.Proxy)
+	setString(&c.LocalLLMCAFile, f.LLM.TLS.CAFile)
+	setString(&c.LocalLLMCertFile, f.LLM.TLS.CertFile)
+	setString(&c.LocalLLMKeyFile, f.LLM.TLS.KeyFile)
+	setValue(&c.LocalLLMInsecureSkipVerify, f.LLM.TLS.InsecureSkipVerify)
+	setString(&c.LocalLLMTimeout, f.LLM.Timeout)
+	setValue(&c.LocalLLMKeepAlive, f.LLM.KeepAlive)
+	setString(&c.LocalLLMIdleTimeout, f.LLM.IdleTimeout)
+	setString(&c.LocalLLMHeaders, joinPairs(f.LLM.Headers))
+	setString(&c.AzureOpenAIEndpoint, f.LLM.Azure.Endpoint)
+	setString(&c.AzureOpenAIAPIKey, f.LLM.Azure.APIKey)
+	setString(&c.AzureOpenAIAPIVersion, f.LLM.Azure.APIVersion)
+	setString(&c.AzureOpenAIDeployments, joinPairs(f.LLM.Azure.Deployments))
+	setString(&c.MockLLMFixtures, f.LLM.MockFixtures)
+	setString(&c.LLMCache, LLMCacheMode(f.LLM.Cache))
+	setString(&c.LLMCacheDir, f.LLM.CacheDir)
+	setValue(&c.LLMRequestsPerMinute, f.LLM.RateLimit.RequestsPerMinute)
+	setValue(&c.LLMTokensPerMinute, f.LLM.RateLimit.TokensPerMinute)
+	setValue(&c.LLMRateLimitRetries, f.LLM.RateLimit.Retries)
+	c.Sampling = c.Sampling.Merge(f.LLM.Sampling)
+
+	setString(&c.DatabaseURL, f.Database.URL)
+	setString(&c.DBDriver, DBDriver(f.Database.Driver))
+	setString(&c.DBSSLMode, f.Database.SSL.Mode)
+	setString(&c.DBSSLRootCert, f.Database.SSL.RootCert)
+	setString(&c.DBSSLCert, f.Database.SSL.Cert)
+	setString(&c.DBSSLKey, f.Database.SSL.Key)
+	setString(&c.BigQueryProject, f.Database.BigQuery.Project)
+	setString(&c.BigQueryDataset, f.Database.BigQuery.Dataset)
+	setString(&c.BigQueryLocation, f.Database.BigQuery.Location)
+	setString(&c.BigQueryCredentialsFile, f.Database.BigQuery.CredentialsFile)
+	setString(&c.BigQueryMaxBytes, f.Database.BigQuery.MaxBytes)
+	setString(&c.MCPServerAddr, f.Database.MCPServerAddr)
+	setString(&c.FilesDir, f.Files.Dir)
+	setString(&c.DuckDBBinary, f.Files.DuckDBBinary)
+	setValue(&c.DBMaxConcurrent, f.Database.MaxConcurrent)
+	setValue(&c.DBMaxConcurrentPerSession, f.Database.MaxConcurrentPerSession)
+	setValue(&c.DBQueriesPerSecond, f.Database.QueriesPerSecond)
+	setString(&c.ResultMemoryLimit, f.Database.ResultMemoryLimit)
+	setString(&c.ResultSpillDir, f.Database.ResultSpillDir)
+	setValue(&c.ResultPreviewRows, f.Database.ResultPreviewRows)
+	setString(&c.ResultPreviewSize, f.Database.ResultPreviewSize)
+	setValue(&c.ResultMaxRows, f.Database.ResultMaxRows)
+	setValue(&c.ResultMaxColumns, f.Database.ResultMaxColumns)
+	setString(&c.ResultMaxSize, f.Database.ResultMaxSize)
+	setValue(&c.InferColumnTypes, f.Database.InferColumnTypes)
+	setValue(&c.InferColumnTypesSample, f.Database.InferColumnTypesSample)
+	setValue(&c.ColumnStats, f.Database.ColumnStats)
+	setValue(&c.ColumnStatsMaxValues, f.Database.ColumnStatsMaxValues)
+	setString(&c.DataDictionaryFile, f.Database.DataDictionaryFile)
+	setString(&c.DataDictionaryTable, f.Database.DataDictionaryTable)
+	setValue(&c.DataDictionaryComments, f.Database.DataDictionaryComments)
+	setString(&c.DBSearchPath, strings.Join(f.Database.SearchPath, ","))
+	setString(&c.SchemaCacheFile, f.Database.SchemaCacheFile)
+	setString(&c.SchemaWatch, SchemaWatchMode(f.Database.SchemaWatch))
+	setString(&c.SchemaPollInterval, f.Database.SchemaPollInterval)
+	setString(&c.SchemaNotifyChannel, f.Database.SchemaNotifyChannel)
+
+	setValue(&c.SQLWriteMode, f.Agents.SQLWriteMode)
+	setString(&c.RedactionPolicyFile, f.Agents.RedactionPolicyFile)
+	setString(&c.AccessPolicyFile, f.Agents.AccessPolicyFile)
+	setString(&c.GuardrailsFile, f.Agents.GuardrailsFile)
+	setString(&c.Locale, f.Agents.Locale)
+	setValue(&c.ClarifyConfidence, f.Agents.ClarifyConfidence)
+	setValue(&c.ClassifierFallbackConfidence, f.Agents.ClassifierFallback)
+	setString(&c.ClassifierModelFile, f.Agents.ClassifierModelFile)
+	setString(&c.IntentEmbedder, IntentEmbedder(f.Agents.IntentEmbedder))
+	setString(&c.IntentEmbedderURL, f.Agents.IntentEmbedderURL)
+	setString(&c.IntentEmbedderModel, f.Agents.IntentEmbedderModel)
+	setValue(&c.PlanExecute, f.Agents.PlanExecute)
+	setValue(&c.PlanMaxReplans, f.Agents.PlanMaxReplans)
+	setValue(&c.TurnSummary, f.Agents.TurnSummary)
+	retries, recoveries := joinStepPolicies(f.Agents.StepPolicies)
+	setString(&c.StepRetries, retries)
+	setString(&c.StepOnFailure, recoveries)
+	setString(&c.PromptsDir, f.Agents.PromptsDir)
+	setValue(&c.PromptsReload, f.Agents.PromptsReload)
+	setString(&c.PromptVariants, f.Agents.PromptVariants)
+	setString(&c.PromptGuidanceFile, f.Agents.GuidanceFile)
+	for name, text := range f.Agents.Guidance {
+		if name == "all" {
+			c.PromptGuidance = text
+			continue
+		}
+		if c.AgentGuidance == nil {
+			c.AgentGuidance = make(map[string]string)
+		}
+		c.AgentGuidance[name] = text
+	}
+	if f.Agents.Sampling != nil {
+		c.AgentSampling = f.Agents.Sampling
+	}
+
+	setString(&c.APIListenAddr, f.API.ListenAddr)
+	setString(&c.GRPCListenAddr, f.API.GRPCListenAddr)
+	setString(&c.APITLSCertFile, f.API.TLSCertFile)
+	setString(&c.APITLSKeyFile, f.API.TLSKeyFile)
+	setString(&c.APITLSClientCAFile, f.API.TLSClientCAFile)
+	setString(&c.APIEncryptionKey, f.API.EncryptionKey)
+	setValue(&c.APIRequireEncryption, f.API.RequireEncryption)
+	setString(&c.APIKeysFile, f.API.KeysFile)
+	setString(&c.APIOIDCIssuer, f.API.OIDC.Issuer)
+	setString(&c.APIOIDCAudience, f.API.OIDC.Audience)
+	setString(&c.APIOIDCUserClaim, f.API.OIDC.UserClaim)
+	setString(&c.APIOIDCProfile, f.API.OIDC.Profile)
+	setValue(&c.APIOIDCRateLimit, f.API.OIDC.RateLimit)
+	setString(&c.APICallbackHosts, strings.Join(f.API.Callbacks.Hosts, ","))
+	setValue(&c.APICallbackAllowPrivate, f.API.Callbacks.AllowPrivate)
+
+	setString(&c.TeamsAppID, f.Teams.AppID)
+	setString(&c.TeamsAppPassword, f.Teams.AppPassword)
+	setString(&c.TeamsTenantID, f.Teams.TenantID)
+	setString(&c.TeamsListenAddr, f.Teams.ListenAddr)
+
+	setString(&c.TelegramBotToken, f.Telegram.BotToken)
+	setString(&c.TelegramAllowedChats, strings.Join(f.Telegram.AllowedChats, ","))
+
+	setString(&c.AuditLogFile, f.Logging.AuditLogFile)
+	setString(&c.EventLogFile, f.Logging.EventLogFile)
+	setString(&c.DebugTraceDir, f.Logging.DebugTraceDir)
+	setString(&c.LogRedactColumns, strings.Join(f.Logging.RedactColumns, ","))
+	setString(&c.HistoryFile, f.Logging.HistoryFile)
+	setValue(&c.NoEmoji, f.Logging.NoEmoji)
+	setValue(&c.KeepReasoning, f.Logging.KeepReasoning)
+	setString(&c.REPLUser, f.REPL.User)
+	setString(&c.REPLSession, f.REPL.Session)
+
+	setString(&c.TurnTimeout, f.Timeouts.Turn)
+	setString(&c.ShutdownTimeout, f.Timeouts.Shutdown)
+	setString(&c.TurnMaxDuration, f.TurnBudget.MaxDuration)
+	setValue(&c.TurnMaxLLMCalls, f.TurnBudget.MaxLLMCalls)
+	setValue(&c.TurnMaxToolCalls, f.TurnBudget.MaxToolCalls)
+	setValue(&c.TurnMaxTokens, f.TurnBudget.MaxTokens)
+
+	setValue(&c.ToolLog, f.Tools.Log)
+	setString(&c.ToolRateLimits, joinLimits(f.Tools.RateLimits))
+
+	setString(&c.ContextLimits, joinLimits(f.Context.Limits))
+	setValue(&c.ContextCompactAt, f.Context.CompactAt)
+	setValue(&c.ContextKeepTurns, f.Context.KeepTurns)
+
+	setValue(&c.SemanticCache, f.SemanticCache.Enabled)
+	setValue(&c.SemanticCacheThreshold, f.SemanticCache.Threshold)
+	setString(&c.SemanticCacheFile, f.SemanticCache.File)
+
+	setString(&c.SchedulesFile, f.Schedules.File)
+	setString(&c.SchedulesTable, f.Schedules.Table)
+	setString(&c.ReportsDir, f.Schedules.ReportsDir)
+
+	setString(&c.UsageFile, f.Usage.File)
+	if f.Usage.Teams != nil {
+		c.UsageTeams = f.Usage.Teams
+	}
+
+	setString(&c.ChartTheme, f.Charts.Theme)
+	setString(&c.ChartPalette, strings.Join(f.Charts.Palette, ","))
+	setValue(&c.ChartWidth, f.Charts.Width)
+	setValue(&c.ChartHeight, f.Charts.Height)
+	if f.Charts.Decimals != nil {
+		c.ChartDecimals = strconv.Itoa(*f.Charts.Decimals)
+	}
+	setValue(&c.ChartValueLabels, f.Charts.ValueLabels)
+	setString(&c.ChartLocale, f.Charts.Locale)
+
+	setString(&c.ArtifactStore, f.Artifacts.Store)
+	setString(&c.ArtifactRetention, f.Artifacts.Retention)
+	setString(&c.ArtifactStoreEndpoint, f.Artifacts.Endpoint)
+	setString(&c.ArtifactStoreRegion, f.Artifacts.Region)
+	setString(&c.ArtifactStoreAccessKey, f.Artifacts.AccessKey)
+	setString(&c.ArtifactStoreSecretKey, f.Artifacts.SecretKey)
+	setString(&c.ArtifactURLExpiry, f.Artifacts.URLExpiry)
+	return nil
+}
+
+// joinLimits writes limits in the name=n list form of the environment.
+func joinLimits(limits map[string]int) string {
+	var entries []string
+	for name, n := range limits {
+		entries = append(entries, fmt.Sprintf("%s=%d", name, n))
+	}
+	slices.Sort(entries)
+	return strings.Join(entries, ",")
+}
+
+// joinPairs writes pairs in the name=value list form of the environment.
+func joinPairs(pairs map[string]string) string {
+	var entries []string
+	for name, value := range pairs {
+		entries = append(entries, name+"="+value)
+	}
+	slices.Sort(entries)
+	return strings.Join(entries, ",")
+}
+
+// stepPolicy is how failed plan steps of one type are recovered from.
+type stepPolicy struct {
+	Retries   *int   `yaml:"retries"`
+	OnFailure string `yaml:"on_failure"`
+}
+
+// joinStepPolicies writes policies in the type=value list forms of
+// STEP_RETRIES and STEP_ON_FAILURE.
+func joinStepPolicies(policies map[string]stepPolicy) (retries, recoveries string) {
+	var r, o []string
+	for name, p := range policies {
+		if p.Retries != nil {
+			r = append(r, fmt.Sprintf("%s=%d", name, *p.Retries))
+		}
+		if p.OnFailure != "" {
+			o = append(o, name+"="+p.OnFailure)
+		}
+	}
+	slices.Sort(r)
+	slices.Sort(o)
+	return strings.Join(r, ","), strings.Join(o, ",")
+}
+
+func setString[T ~string](dst *T, val T) {
+	if val != "" {
+		*dst = val
+	}
+}
+
+func setValue[T any](dst *T, val *T) {
+	if val != nil {
+		*dst = *val
+	}
+}
+
+// parseTOML reads the subset of TOML used by config files: [table] headers
+// (dotted names nest), key = value pairs, and string, integer, float,
+// boolean and single-line array values.
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	root := map[string]interface{}{}
+	table := root
+
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(stripTOMLComment(line))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: unsupported table header %q", n+1, line)
+			}
+			table = root
+			for _, name := range strings.Split(strings.Trim(line, "[]"), ".") {
+				name = strings.TrimSpace(name)
+				next, ok := table[name].(map[string]interface{})
+				if !ok {
+					next = map[string]interface{}{}
+					table[name] = next
+				}
+				table = next
+			}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		value, err := parseTOMLValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		table[strings.Trim(strings.TrimSpace(key), `"`)] = value
+	}
+	return root, nil
+}
+
+func parseTOMLValue(raw string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		return strconv.Unquote(raw)
+	case strings.HasPrefix(raw, "'") && strings.HasSuffix(raw, "'") && len(raw) >= 2:
+		return raw[1 : len(raw)-1], nil
+	case raw == "true" || raw == "false":
+		return raw == "true", nil
+	case strings.HasPrefix(raw, "[") && strings.HasSuffix(raw, "]"):
+		var items []interface{}
+		for _, item := range splitTOMLArray(raw[1 : len(raw)-1]) {
+			v, err := parseTOMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	}
+
+	raw = strings.ReplaceAll(raw, "_", "")
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("unsupported value %q", raw)
+}
+
+// splitTOMLArray splits array items on commas outside quotes.
+func splitTOMLArray(s string) []string {
+	var items []string
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	items = append(items, s[start:])
+
+	out := items[:0]
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// stripTOMLComment removes a # comment that is not inside a string.
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
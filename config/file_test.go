@@ -0,0 +1,339 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewFromFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		check   func(t *testing.T, c *Config)
+	}{
+		{
+			name: "yaml",
+			file: "multiagent.yaml",
+			content: `
+llm:
+  provider: local
+  local_url: http://llm:1234
+  check_model: false
+  flavor: vllm
+  tool_calling: auto
+  proxy: http://proxy:3128
+  timeout: 10m
+  headers:
+    Authorization: Bearer sk-gateway-1234
+    X-Team: analytics
+  tls:
+    ca_file: /etc/ssl/llm-ca.pem
+  rate_limit:
+    requests_per_minute: 60
+database:
+  url: postgres://db/app
+  ssl:
+    mode: verify-full
+    root_cert: /etc/ssl/db-ca.pem
+  max_concurrent: 4
+  queries_per_second: 5
+  schema_watch: notify
+  search_path: [Sales, public]
+  result_preview_rows: 20
+agents:
+  sql_write_mode: true
+  step_policies:
+    sql: {retries: 1}
+    chart: {retries: 0, on_failure: skip}
+  guidance:
+    all: Fiscal years start in February.
+    sql: Table names are plural.
+telegram:
+  allowed_chats: [12, 34]
+timeouts:
+  turn: 90s
+turn_budget:
+  max_duration: 2m
+  max_tool_calls: 12
+tools:
+  rate_limits:
+    query_database: 30
+    "*": 120
+api:
+  grpc_listen_addr: ":9090"
+  oidc:
+    issuer: https://idp.example.com
+    audience: multi-agent
+    rate_limit: 60
+context:
+  limits:
+    local-model: 8192
+  keep_turns: 2
+schedules:
+  file: schedules.yaml
+  reports_dir: /var/reports
+usage:
+  teams:
+    analytics: [alice, bob]
+files:
+  dir: data
+artifacts:
+  store: /var/artifacts
+  retention: 72h
+charts:
+  theme: forest
+  palette: ["#1f77b4", "#ff7f0e"]
+  width: 900
+  decimals: 0
+logging:
+  redact_columns: [email, " ssn"]
+`,
+			check: func(t *testing.T, c *Config) {
+				if c.LLMProvider != LLMProviderLocal || c.LocalLLMURL != "http://llm:1234" || c.Model != DefaultLocalModel || c.CheckModel {
+					t.Errorf("llm = %q %q %q, check %v", c.LLMProvider, c.LocalLLMURL, c.Model, c.CheckModel)
+				}
+				if c.LocalLLMFlavor != "vllm" || c.LocalLLMToolCalling != "auto" || c.LocalLLMProxy != "http://proxy:3128" || c.LocalLLMCAFile != "/etc/ssl/llm-ca.pem" || c.LocalLLMInsecureSkipVerify {
+					t.Errorf("llm proxy = %q, CA file %q, skip verify %v", c.LocalLLMProxy, c.LocalLLMCAFile, c.LocalLLMInsecureSkipVerify)
+				}
+				if request, idle, err := c.LocalLLMTimeouts(); err != nil || request != 10*time.Minute || idle != 90*time.Second || !c.LocalLLMKeepAlive {
+					t.Errorf("llm timeouts = %v, idle %v (%v), keep-alive %v", request, idle, err, c.LocalLLMKeepAlive)
+				}
+				if h, err := c.LocalLLMHeaderValues(); err != nil || h["X-Team"] != "analytics" || !slices.Contains(c.SecretValues(), "Bearer sk-gateway-1234") {
+					t.Errorf("llm headers = %v (%v) from %q", h, err, c.LocalLLMHeaders)
+				}
+				if u := c.PostgresURL(); u != "postgres://db/app?sslmode=verify-full&sslrootcert=%2Fetc%2Fssl%2Fdb-ca.pem" {
+					t.Errorf("postgres URL = %q", u)
+				}
+				if c.DatabaseURL != "postgres://db/app" || c.DBMaxConcurrent != 4 || c.DBMaxConcurrentPerSession != 2 {
+					t.Errorf("database = %q %d %d", c.DatabaseURL, c.DBMaxConcurrent, c.DBMaxConcurrentPerSession)
+				}
+				if c.LLMRequestsPerMinute != 60 || c.LLMTokensPerMinute != 0 || c.LLMRateLimitRetries != 3 || c.DBQueriesPerSecond != 5 {
+					t.Errorf("rate limits = %d rpm, %d tpm, %d retries, %d qps", c.LLMRequestsPerMinute, c.LLMTokensPerMinute, c.LLMRateLimitRetries, c.DBQueriesPerSecond)
+				}
+				if d, err := c.SchemaPollDuration(); err != nil || d != 0 || c.SchemaWatch != SchemaWatchNotify || c.SchemaNotifyChannel != "multi_agent_schema" {
+					t.Errorf("schema watch = %q on %q, every %v (%v)", c.SchemaWatch, c.SchemaNotifyChannel, d, err)
+				}
+				if path := c.SearchPath(); !reflect.DeepEqual(path, []string{"sales", "public"}) {
+					t.Errorf("search path = %v", path)
+				}
+				if n, err := c.ResultPreviewBytes(); err != nil || n != 32<<10 || c.ResultPreviewRows != 20 {
+					t.Errorf("result preview = %d rows, %d bytes (%v)", c.ResultPreviewRows, n, err)
+				}
+				if !c.SQLWriteMode || c.TelegramAllowedChats != "12,34" || c.TurnTimeout != "90s" {
+					t.Errorf("write=%v chats=%q timeout=%q", c.SQLWriteMode, c.TelegramAllowedChats, c.TurnTimeout)
+				}
+				if d, err := c.TurnMaxDurationValue(); err != nil || d.Minutes() != 2 || c.TurnMaxToolCalls != 12 || c.TurnMaxLLMCalls != 0 {
+					t.Errorf("turn budget = %v (%v), %d tool calls, %d LLM calls", d, err, c.TurnMaxToolCalls, c.TurnMaxLLMCalls)
+				}
+				if retries, err := c.StepRetryValues(); err != nil || retries["sql"] != 1 || c.StepRetries != "chart=0,sql=1" || c.StepOnFailure != "chart=skip" {
+					t.Errorf("step policies = %q and %q (%v)", c.StepRetries, c.StepOnFailure, err)
+				}
+				if limits, err := c.ToolRateLimitValues(); err != nil || limits["query_database"] != 30 || limits["*"] != 120 {
+					t.Errorf("tool rate limits = %v (%v) from %q", limits, err, c.ToolRateLimits)
+				}
+				if n, err := c.ContextLimit("local-model"); err != nil || n != 8192 || c.ContextKeepTurns != 2 || c.ContextCompactAt != 0.8 {
+					t.Errorf("context = %d (%v), keep %d, compact at %v", n, err, c.ContextKeepTurns, c.ContextCompactAt)
+				}
+				if !c.APIAuthEnabled() || c.APIOIDCAudience != "multi-agent" || c.APIOIDCRateLimit != 60 {
+					t.Errorf("api auth = %v, audience %q, rate limit %d", c.APIAuthEnabled(), c.APIOIDCAudience, c.APIOIDCRateLimit)
+				}
+				if !c.GRPCEnabled() || c.GRPCListenAddr != ":9090" {
+					t.Errorf("grpc listen addr = %q", c.GRPCListenAddr)
+				}
+				if !c.SchedulesEnabled() || c.SchedulesFile != "schedules.yaml" || c.ReportsDir != "/var/reports" {
+					t.Errorf("schedules = %q, reports in %q", c.SchedulesFile, c.ReportsDir)
+				}
+				if c.FilesDir != "data" {
+					t.Errorf("files dir = %q", c.FilesDir)
+				}
+				if d, err := c.ArtifactRetentionDuration(); err != nil || d.Hours() != 72 || c.ArtifactStore != "/var/artifacts" {
+					t.Errorf("artifacts = %q for %v (%v)", c.ArtifactStore, d, err)
+				}
+				if g, err := c.Guidance(); err != nil || g["all"] != "Fiscal years start in February." || g["sql"] != "Table names are plural." {
+					t.Errorf("guidance = %v (%v)", g, err)
+				}
+				if s, err := c.ChartStyle(); err != nil || s.Theme != "forest" || len(s.Palette) != 2 || s.Width != 900 || s.Decimals == nil || *s.Decimals != 0 {
+					t.Errorf("chart style = %+v (%v)", s, err)
+				}
+				if columns := c.LogRedactColumnNames(); !reflect.DeepEqual(columns, []string{"email", "ssn"}) {
+					t.Errorf("log redact columns = %v", columns)
+				}
+				if teams := c.UsageTeams["analytics"]; len(teams) != 2 || teams[1] != "bob" {
+					t.Errorf("usage teams = %v", c.UsageTeams)
+				}
+			},
+		},
+		{
+			name: "toml with env override",
+			file: "multiagent.toml",
+			content: `
+# LLM settings
+[llm]
+provider = "gemini"
+model = "gemini-2.5-pro" # pinned
+
+[database]
+url = 'postgres://file/app'
+max_concurrent_per_session = 1
+
+[logging]
+no_emoji = true
+keep_reasoning = true
+`,
+			env: map[string]string{"DATABASE_URL": "postgres://env/app", "SQL_WRITE_MODE": "true"},
+			check: func(t *testing.T, c *Config) {
+				if c.Model != "gemini-2.5-pro" || c.DBMaxConcurrentPerSession != 1 || !c.NoEmoji || !c.KeepReasoning {
+					t.Errorf("model=%q perSession=%d noEmoji=%v keepReasoning=%v", c.Model, c.DBMaxConcurrentPerSession, c.NoEmoji, c.KeepReasoning)
+				}
+				if c.DatabaseURL != "postgres://env/app" || !c.SQLWriteMode {
+					t.Errorf("env did not override file: url=%q write=%v", c.DatabaseURL, c.SQLWriteMode)
+				}
+			},
+		},
+		{
+			name: "bigquery",
+			file: "multiagent.yaml",
+			content: `
+llm:
+  provider: local
+database:
+  driver: bigquery
+  bigquery:
+    project: analytics-prod
+    dataset: sales
+    location: EU
+    max_bytes: 10GB
+`,
+			check: func(t *testing.T, c *Config) {
+				if c.DBDriver != DBDriverBigQuery || c.BigQueryProject != "analytics-prod" || c.BigQueryDataset != "sales" || c.BigQueryLocation != "EU" {
+					t.Errorf("bigquery = %q %q %q %q", c.DBDriver, c.BigQueryProject, c.BigQueryDataset, c.BigQueryLocation)
+				}
+				if n, err := c.BigQueryMaxBytesValue(); err != nil || n != 10<<30 {
+					t.Errorf("max bytes = %d (%v)", n, err)
+				}
+				if err := c.Validate(); err != nil {
+					t.Errorf("Validate() = %v, want no DATABASE_URL needed", err)
+				}
+			},
+		},
+		{
+			name: "azure",
+			file: "multiagent.yaml",
+			content: `
+llm:
+  provider: azure
+  azure:
+    endpoint: https://contoso.openai.azure.com
+    deployments:
+      gpt-4o: prod-gpt4o
+database:
+  url: postgres://db/app
+`,
+			env: map[string]string{"AZURE_OPENAI_API_KEY": "azure-key-1234"},
+			check: func(t *testing.T, c *Config) {
+				if !c.IsOpenAICompatible() || c.IsLocalLLM() || c.Model != "gpt-4o" {
+					t.Errorf("llm = %q %q", c.LLMProvider, c.Model)
+				}
+				if d, err := c.AzureOpenAIDeployment(c.Model); err != nil || d != "prod-gpt4o" {
+					t.Errorf("deployment = %q (%v)", d, err)
+				}
+				if d, _ := c.AzureOpenAIDeployment("gpt-4o-mini"); d != "gpt-4o-mini" {
+					t.Errorf("unmapped deployment = %q, want the model name", d)
+				}
+				if err := c.Validate(); err != nil || !slices.Contains(c.SecretValues(), "azure-key-1234") {
+					t.Errorf("Validate() = %v, secrets %d", err, len(c.SecretValues()))
+				}
+				c.AzureOpenAIAPIKey = ""
+				if err := c.Validate(); err != ErrMissingAzureOpenAI {
+					t.Errorf("Validate() without a key = %v, want %v", err, ErrMissingAzureOpenAI)
+				}
+			},
+		},
+		{
+			name:    "unknown key",
+			file:    "multiagent.yaml",
+			content: "llm:\n  temprature: 0.2\n",
+			check: func(t *testing.T, c *Config) {
+				if c.Validate() == nil {
+					t.Error("Validate() = nil, want error for unknown key")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DATABASE_URL", "DB_DRIVER", "DB_SSLMODE", "DB_SSLROOTCERT", "LOCAL_LLM_FLAVOR", "LOCAL_LLM_TOOL_CALLING", "LOCAL_LLM_PROXY", "LOCAL_LLM_CA_FILE", "LOCAL_LLM_TIMEOUT", "LOCAL_LLM_HEADERS", "AZURE_OPENAI_ENDPOINT", "AZURE_OPENAI_API_KEY", "AZURE_OPENAI_DEPLOYMENTS", "BIGQUERY_PROJECT", "BIGQUERY_DATASET", "BIGQUERY_LOCATION", "BIGQUERY_MAX_BYTES", "FILES_DIR", "LLM_PROVIDER", "LLM_MODEL", "LLM_CHECK_MODEL", "SQL_WRITE_MODE", "DB_MAX_CONCURRENT", "DB_MAX_CONCURRENT_PER_SESSION", "TELEGRAM_ALLOWED_CHATS", "TURN_TIMEOUT", "TURN_MAX_DURATION", "TURN_MAX_TOOL_CALLS", "TOOL_RATE_LIMITS", "CONTEXT_LIMITS", "CONTEXT_KEEP_TURNS", "RESULT_PREVIEW_ROWS", "RESULT_PREVIEW_SIZE", "API_OIDC_ISSUER", "API_OIDC_AUDIENCE", "API_OIDC_RATE_LIMIT", "SCHEDULES_FILE", "REPORTS_DIR", "NO_EMOJI"} {
+				t.Setenv(key, "")
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, NewFromFile(path))
+		})
+	}
+}
+
+func TestPostgresURL(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			name: "unchanged",
+			cfg:  Config{DatabaseURL: "postgres://app@db/shop?sslmode=require"},
+			want: "postgres://app@db/shop?sslmode=require",
+		},
+		{
+			name: "URL settings overridden",
+			cfg:  Config{DatabaseURL: "postgresql://app@db/shop?sslmode=disable&application_name=agents", DBSSLMode: "verify-ca", DBSSLCert: "client.crt", DBSSLKey: "client.key"},
+			want: "postgresql://app@db/shop?application_name=agents&sslcert=client.crt&sslkey=client.key&sslmode=verify-ca",
+		},
+		{
+			name: "key-value string",
+			cfg:  Config{DatabaseURL: "host=db dbname=shop", DBSSLMode: "verify-full", DBSSLRootCert: `/etc/ssl/it's ca.pem`},
+			want: `host=db dbname=shop sslmode='verify-full' sslrootcert='/etc/ssl/it\'s ca.pem'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.PostgresURL(); got != tt.want {
+				t.Errorf("PostgresURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	invalid := []struct {
+		set  func(c *Config)
+		want error
+	}{
+		{func(c *Config) { c.DBSSLMode = "prefer" }, ErrInvalidDBSSLMode},
+		{func(c *Config) { c.DBSSLCert = "client.crt" }, ErrIncompleteDBSSLCert},
+		{func(c *Config) { c.LocalLLMKeyFile = "client.key" }, ErrIncompleteLocalLLMCert},
+		{func(c *Config) { c.LocalLLMProxy = "proxy:3128" }, ErrInvalidLocalLLMProxy},
+		{func(c *Config) { c.LocalLLMTimeout = "0s" }, ErrInvalidLocalLLMTimeout},
+		{func(c *Config) { c.LocalLLMFlavor = "ollama" }, ErrInvalidLocalLLMFlavor},
+		{func(c *Config) { c.LocalLLMToolCalling = "react" }, ErrInvalidToolCalling},
+		{func(c *Config) { c.LocalLLMHeaders = "Authorization: Bearer x" }, ErrInvalidLocalLLMHeaders},
+	}
+	for _, tt := range invalid {
+		c := NewFromFile("")
+		tt.set(c)
+		if err := c.ValidateSettings(); err != tt.want {
+			t.Errorf("ValidateSettings() = %v, want %v", err, tt.want)
+		}
+	}
+}
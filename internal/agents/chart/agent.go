@@ -2,15 +2,21 @@
 package chart
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/anuvratrastogi/multi-agent/internal/prompts"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/model"
+	"google.golang.org/genai"
 )
 
+// mermaidImageBaseURL renders Mermaid definitions to PNG images.
+const mermaidImageBaseURL = "https://mermaid.ink/img/"
+
 const (
 	agentName      = "ChartAgent"
 	agentDesc      = "Creates data visualizations using Mermaid charts from query results"
@@ -25,67 +31,39 @@ type Agent struct {
 // Config holds configuration for the Chart agent.
 type Config struct {
 	Model model.LLM
+	// GenerateConfig sets generation parameters such as temperature (optional)
+	GenerateConfig *genai.GenerateContentConfig
+	// Prompts holds the template of the instruction (default: built-in)
+	Prompts *prompts.Store
 }
 
 // New creates a new Chart agent.
 func New(cfg Config) (*Agent, error) {
-	instruction := `You are a data visualization expert agent. Your job is to:
-1. Analyze the data provided (usually from SQL query results)
-2. Determine the most appropriate chart type for the data
-3. Generate a Mermaid chart in markdown format
-
-Mermaid Chart Types Available:
-- xychart-beta: For bar charts and line charts (use for comparisons and trends)
-- pie: For showing proportions of a whole
-
-Output Format:
-Return your response with the chart in a mermaid code block. Use this format:
-
-For bar/line charts:
-` + "```mermaid" + `
-xychart-beta
-    title "Chart Title"
-    x-axis [Label1, Label2, Label3]
-    y-axis "Y Axis Label" MIN --> MAX
-    bar [value1, value2, value3]
-` + "```" + `
-
-For line charts:
-` + "```mermaid" + `
-xychart-beta
-    title "Chart Title"
-    x-axis [Label1, Label2, Label3]  
-    y-axis "Y Axis Label" MIN --> MAX
-    line [value1, value2, value3]
-` + "```" + `
-
-For pie charts:
-` + "```mermaid" + `
-pie title "Chart Title"
-    "Label1" : value1
-    "Label2" : value2
-    "Label3" : value3
-` + "```" + `
-
-IMPORTANT Guidelines:
-- Set y-axis MIN to 0 and MAX to slightly above your highest data value (e.g., if max value is 135, use 0 --> 150)
-- Choose chart type based on data characteristics
-- Use clear, descriptive titles and labels
-- For time series data, prefer line charts (xychart-beta with line)
-- For category comparisons, prefer bar charts (xychart-beta with bar)
-- For proportions of a whole, prefer pie charts
-- Keep labels short to fit in the chart
-- Round numbers appropriately for readability
-- Always output valid Mermaid syntax
-
-Generate clean, readable Mermaid charts that can be rendered in any markdown viewer.`
+	if cfg.Prompts == nil {
+		cfg.Prompts = prompts.Default()
+	}
+	if _, err := cfg.Prompts.Render(prompts.Chart, nil); err != nil {
+		return nil, fmt.Errorf("failed to create Chart agent: %w", err)
+	}
+
+	// The chart style of the turn says how to write values
+	instruction := cfg.Prompts.Provider(prompts.Chart, nil)
+	styled := func(ctx agent.ReadonlyContext) (string, error) {
+		text, err := instruction(ctx)
+		if err != nil {
+			return "", err
+		}
+		return text + StyleFrom(ctx).Instruction(), nil
+	}
 
 	llmAgent, err := llmagent.New(llmagent.Config{
-		Name:        agentName,
-		Description: agentDesc,
-		Instruction: instruction,
-		Model:       cfg.Model,
-		OutputKey:   outputKeyChart,
+		Name:                agentName,
+		Description:         agentDesc,
+		InstructionProvider: styled,
+		Model:               cfg.Model,
+		OutputKey:           outputKeyChart,
+
+		GenerateContentConfig: cfg.GenerateConfig,
 	})
 
 	if err != nil {
@@ -210,3 +188,56 @@ func roundUpNice(val float64) float64 {
 	// Round up to nearest 1000
 	return float64(int(val/1000)+1) * 1000
 }
+
+// ExtractMermaid returns the bodies of all ```mermaid code blocks in text.
+func ExtractMermaid(text string) []string {
+	const fence = "```mermaid"
+
+	var charts []string
+	for {
+		start := strings.Index(text, fence)
+		if start == -1 {
+			return charts
+		}
+		text = text[start+len(fence):]
+
+		end := strings.Index(text, "```")
+		if end == -1 {
+			return charts
+		}
+		if body := strings.TrimSpace(text[:end]); body != "" {
+			charts = append(charts, body)
+		}
+		text = text[end+3:]
+	}
+}
+
+// StripMermaid removes all ```mermaid code blocks from text, for front-ends
+// that render charts separately.
+func StripMermaid(text string) string {
+	const fence = "```mermaid"
+
+	var out strings.Builder
+	for {
+		start := strings.Index(text, fence)
+		if start == -1 {
+			out.WriteString(text)
+			break
+		}
+		out.WriteString(text[:start])
+
+		rest := text[start+len(fence):]
+		end := strings.Index(rest, "```")
+		if end == -1 {
+			break
+		}
+		text = rest[end+3:]
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// MermaidImageURL returns a URL that renders the Mermaid definition as an
+// image, for front-ends that cannot render Mermaid themselves.
+func MermaidImageURL(mermaid string) string {
+	return mermaidImageBaseURL + base64.URLEncoding.EncodeToString([]byte(mermaid))
+}
@@ -0,0 +1,169 @@
+package chart
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxBins bounds the number of bins chosen automatically for a histogram.
+	maxBins = 20
+	// maxScatterPoints bounds the number of points drawn in a scatter plot.
+	maxScatterPoints = 200
+)
+
+// Bin is a bar of a histogram: the values from Low up to High (and High
+// itself for the last bin).
+type Bin struct {
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+	Count int     `json:"count"`
+}
+
+// Label names the bin by its range, e.g. "10–20".
+func (b Bin) Label() string {
+	return formatEdge(b.Low) + "–" + formatEdge(b.High)
+}
+
+// Histogram counts values into bins of equal width. With bins <= 0 the
+// number of bins follows Sturges' rule (at most 20) and their width is
+// rounded to 1, 2, 2.5 or 5 times a power of ten, so edges are round
+// numbers. NaN and infinite values are skipped.
+func Histogram(values []float64, bins int) []Bin {
+	var finite []float64
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			finite = append(finite, v)
+		}
+	}
+	if len(finite) == 0 {
+		return nil
+	}
+	lo, hi := finite[0], finite[0]
+	for _, v := range finite {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	if lo == hi {
+		return []Bin{{Low: lo, High: hi, Count: len(finite)}}
+	}
+
+	var start, width float64
+	if bins > 0 {
+		start, width = lo, (hi-lo)/float64(bins)
+	} else {
+		bins = int(math.Ceil(math.Log2(float64(len(finite))))) + 1
+		bins = min(max(bins, 1), maxBins)
+		width = niceStep((hi - lo) / float64(bins))
+		start = math.Floor(lo/width) * width
+		// The maximum closes the last bin rather than opening another
+		span := (hi - start) / width
+		bins = int(math.Ceil(span - 1e-9))
+		if bins < 1 {
+			bins = 1
+		}
+	}
+
+	out := make([]Bin, bins)
+	for i := range out {
+		out[i].Low = start + float64(i)*width
+		out[i].High = start + float64(i+1)*width
+	}
+	for _, v := range finite {
+		// Values on an edge belong to the bin above, despite rounding
+		i := int(math.Floor((v-start)/width + 1e-9))
+		out[min(max(i, 0), bins-1)].Count++
+	}
+	return out
+}
+
+// niceStep rounds a bin width up to 1, 2, 2.5 or 5 times a power of ten.
+func niceStep(step float64) float64 {
+	scale := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, m := range []float64{1, 2, 2.5, 5} {
+		if step <= m*scale*(1+1e-9) {
+			return m * scale
+		}
+	}
+	return 10 * scale
+}
+
+// formatEdge writes a bin edge without the noise of floating-point sums,
+// e.g. 0.30000000000000004 as 0.3.
+func formatEdge(f float64) string {
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', 10, 64), 64)
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// GenerateMermaidHistogram generates a Mermaid bar chart of the distribution
+// of values, binned as Histogram does.
+func GenerateMermaidHistogram(title string, values []float64, bins int, xAxisLabel string) string {
+	hist := Histogram(values, bins)
+	labels := make([]string, len(hist))
+	counts := make([]float64, len(hist))
+	for i, b := range hist {
+		labels[i] = b.Label()
+		counts[i] = float64(b.Count)
+	}
+	chart := GenerateMermaidBarChart(title, labels, counts, "count")
+	if xAxisLabel == "" {
+		return chart
+	}
+	return strings.Replace(chart, "    x-axis [", fmt.Sprintf("    x-axis %q [", xAxisLabel), 1)
+}
+
+// Point is a point of a scatter plot.
+type Point struct {
+	Label string
+	X, Y  float64
+}
+
+// GenerateMermaidScatterChart generates a Mermaid scatter plot of points.
+// Mermaid has no scatter chart, so the points are drawn on a quadrant chart
+// with both axes scaled to the range of the data, whose ends label the axes.
+// At most 200 points are drawn.
+func GenerateMermaidScatterChart(title string, points []Point, xAxisLabel, yAxisLabel string) string {
+	if len(points) > maxScatterPoints {
+		points = points[:maxScatterPoints]
+	}
+	minX, maxX := math.Inf(1), math.Inf(-1)
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	scale := func(v, lo, hi float64) float64 {
+		if hi <= lo {
+			return 0.5
+		}
+		return (v - lo) / (hi - lo)
+	}
+
+	var b strings.Builder
+	b.WriteString("```mermaid\nquadrantChart\n")
+	fmt.Fprintf(&b, "    title %s\n", strings.ReplaceAll(title, "\n", " "))
+	if len(points) > 0 {
+		fmt.Fprintf(&b, "    x-axis %s --> %s\n", axisEnd(xAxisLabel, minX), axisEnd("", maxX))
+		fmt.Fprintf(&b, "    y-axis %s --> %s\n", axisEnd(yAxisLabel, minY), axisEnd("", maxY))
+	}
+	for i, p := range points {
+		label := p.Label
+		if label == "" {
+			label = strconv.Itoa(i + 1)
+		}
+		fmt.Fprintf(&b, "    %s: [%.3f, %.3f]\n", quoteLabels([]string{strings.ReplaceAll(label, `"`, "'")})[0],
+			scale(p.X, minX, maxX), scale(p.Y, minY, maxY))
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+// axisEnd labels an end of a scatter plot's axis with its value.
+func axisEnd(label string, v float64) string {
+	text := formatEdge(v)
+	if label != "" {
+		text = strings.ReplaceAll(label, `"`, "'") + " " + text
+	}
+	return `"` + text + `"`
+}
@@ -0,0 +1,96 @@
+package chart
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		bins   int
+		want   []Bin
+	}{
+		{
+			name:   "automatic bins with round edges",
+			values: []float64{1, 3, 7, 12, 18, 19, 23, 38},
+			want: []Bin{
+				{Low: 0, High: 10, Count: 3},
+				{Low: 10, High: 20, Count: 3},
+				{Low: 20, High: 30, Count: 1},
+				{Low: 30, High: 40, Count: 1},
+			},
+		},
+		{
+			name:   "maximum closes the last bin",
+			values: []float64{0, 5, 10, 20},
+			want:   []Bin{{Low: 0, High: 10, Count: 2}, {Low: 10, High: 20, Count: 2}},
+		},
+		{
+			name:   "fixed number of bins",
+			values: []float64{2, 4, 6, 8},
+			bins:   3,
+			want:   []Bin{{Low: 2, High: 4, Count: 1}, {Low: 4, High: 6, Count: 1}, {Low: 6, High: 8, Count: 2}},
+		},
+		{
+			name:   "one value",
+			values: []float64{5, 5, math.NaN()},
+			want:   []Bin{{Low: 5, High: 5, Count: 2}},
+		},
+		{name: "no values", values: []float64{math.Inf(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Histogram(tt.values, tt.bins); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Histogram() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNiceStep(t *testing.T) {
+	for step, want := range map[float64]float64{0.7: 1, 1.5: 2, 2.2: 2.5, 3: 5, 7: 10, 0.03: 0.05, 1200: 2000} {
+		if got := niceStep(step); math.Abs(got-want) > 1e-12 {
+			t.Errorf("niceStep(%v) = %v, want %v", step, got, want)
+		}
+	}
+}
+
+func TestGenerateMermaidHistogram(t *testing.T) {
+	got := GenerateMermaidHistogram("Order sizes", []float64{0.1, 0.2, 0.25, 0.3, 0.32}, 0, "amount")
+	for _, w := range []string{
+		"```mermaid\nxychart-beta\n",
+		`title "Order sizes"`,
+		`x-axis "amount" ["0.1–0.2", "0.2–0.3", "0.3–0.4"]`,
+		`y-axis "count" 0 --> 10`,
+		"bar [1, 2, 2]",
+	} {
+		if !strings.Contains(got, w) {
+			t.Errorf("GenerateMermaidHistogram() = %q, want it to contain %q", got, w)
+		}
+	}
+}
+
+func TestGenerateMermaidScatterChart(t *testing.T) {
+	got := GenerateMermaidScatterChart("Price vs units", []Point{
+		{Label: `say "hi"`, X: 10, Y: -5},
+		{X: 30, Y: 15},
+		{X: 20, Y: 5},
+	}, "price", "units")
+	for _, w := range []string{
+		"```mermaid\nquadrantChart\n    title Price vs units\n",
+		`x-axis "price 10" --> "30"`,
+		`y-axis "units -5" --> "15"`,
+		`"say 'hi'": [0.000, 0.000]`,
+		`"2": [1.000, 1.000]`,
+		`"3": [0.500, 0.500]`,
+	} {
+		if !strings.Contains(got, w) {
+			t.Errorf("GenerateMermaidScatterChart() = %q, want it to contain %q", got, w)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package chart
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/joins"
+)
+
+// maxERTables bounds the number of tables drawn in an ER diagram; larger
+// diagrams are unreadable, so a subset must be asked for.
+const maxERTables = 40
+
+// erTable is a table of a DescribeDatabase description.
+type erTable struct {
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`
+	PrimaryKey []string `json:"primary_key"`
+}
+
+// erWord matches the runs of characters Mermaid accepts in entity names,
+// attribute types and attribute names.
+var erWord = regexp.MustCompile(`[^A-Za-z0-9_\-]+`)
+
+// ERDiagram renders a database description, as returned by
+// DescribeDatabase, and its foreign keys as a Mermaid erDiagram: each
+// table with its columns, primary and foreign key columns marked, and a
+// one-to-many relationship per foreign key labelled with its columns.
+// If tables is not empty only those tables are drawn, matched
+// case-insensitively, with the keys between them.
+func ERDiagram(description string, keys []joins.ForeignKey, tables []string) (string, error) {
+	var all []erTable
+	if err := json.Unmarshal([]byte(description), &all); err != nil {
+		return "", fmt.Errorf("failed to parse database description: %w", err)
+	}
+
+	drawn := all
+	if len(tables) > 0 {
+		drawn = nil
+		for _, name := range tables {
+			i := slices.IndexFunc(all, func(t erTable) bool { return strings.EqualFold(t.Table, name) })
+			if i < 0 {
+				return "", fmt.Errorf("table %s not found", name)
+			}
+			if !slices.ContainsFunc(drawn, func(t erTable) bool { return t.Table == all[i].Table }) {
+				drawn = append(drawn, all[i])
+			}
+		}
+	}
+	if len(drawn) == 0 {
+		return "", fmt.Errorf("the database has no tables")
+	}
+	if len(drawn) > maxERTables {
+		return "", fmt.Errorf("the database has %d tables, too many for one diagram: pass up to %d tables to draw", len(drawn), maxERTables)
+	}
+
+	isDrawn := func(table string) bool {
+		return slices.ContainsFunc(drawn, func(t erTable) bool { return strings.EqualFold(t.Table, table) })
+	}
+	// foreign holds the foreign key columns of each table drawn
+	foreign := make(map[string][]string)
+	var relations []joins.ForeignKey
+	for _, k := range keys {
+		if isDrawn(k.Table) && isDrawn(k.RefTable) {
+			foreign[strings.ToLower(k.Table)] = append(foreign[strings.ToLower(k.Table)], k.Columns...)
+			relations = append(relations, k)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("```mermaid\nerDiagram\n")
+	for _, t := range drawn {
+		fmt.Fprintf(&b, "    %s {\n", erName(t.Table))
+		for _, c := range t.Columns {
+			name, typ, _ := strings.Cut(c, " ")
+			var marks []string
+			if slices.Contains(t.PrimaryKey, name) {
+				marks = append(marks, "PK")
+			}
+			if slices.Contains(foreign[strings.ToLower(t.Table)], name) {
+				marks = append(marks, "FK")
+			}
+			line := erName(typ) + " " + erName(name)
+			if len(marks) > 0 {
+				line += " " + strings.Join(marks, ", ")
+			}
+			fmt.Fprintf(&b, "        %s\n", line)
+		}
+		b.WriteString("    }\n")
+	}
+	for _, k := range relations {
+		fmt.Fprintf(&b, "    %s ||--o{ %s : \"%s\"\n", erName(k.RefTable), erName(k.Table), strings.Join(k.Columns, ", "))
+	}
+	b.WriteString("```")
+	return b.String(), nil
+}
+
+// erName makes a table, column or type name a Mermaid word, e.g.
+// "sales.orders" becomes sales_orders and "character varying"
+// character_varying.
+func erName(s string) string {
+	s = strings.Trim(erWord.ReplaceAllString(strings.TrimSpace(s), "_"), "_")
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
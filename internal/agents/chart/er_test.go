@@ -0,0 +1,68 @@
+package chart
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/joins"
+)
+
+func TestERDiagram(t *testing.T) {
+	description := `[
+		{"table":"customers","columns":["id integer","name character varying"],"primary_key":["id"]},
+		{"table":"orders","columns":["id integer","customer_id integer","placed_at timestamp with time zone"],"primary_key":["id"]},
+		{"table":"sales.targets","columns":["region text","amount Nullable(Decimal(10, 2))"]}
+	]`
+	keys := []joins.ForeignKey{{Name: "orders_customer_fk", Table: "orders", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}}}
+
+	tests := []struct {
+		name    string
+		tables  []string
+		want    []string
+		notWant []string
+		wantErr string
+	}{
+		{
+			name: "all tables",
+			want: []string{
+				"```mermaid\nerDiagram\n",
+				"    customers {\n        integer id PK\n        character_varying name\n    }\n",
+				"        integer customer_id FK\n        timestamp_with_time_zone placed_at\n",
+				"    sales_targets {\n        text region\n        Nullable_Decimal_10_2 amount\n    }\n",
+				"    customers ||--o{ orders : \"customer_id\"\n```",
+			},
+		},
+		{
+			name:    "some tables",
+			tables:  []string{"ORDERS", "sales.targets"},
+			want:    []string{"    orders {", "    sales_targets {"},
+			notWant: []string{"customers", " FK"},
+		},
+		{name: "unknown table", tables: []string{"nope"}, wantErr: "table nope not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ERDiagram(description, keys, tt.tables)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("diagram lacks %q:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("diagram has %q:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package chart
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// maxPlanNodes bounds the number of steps drawn in a plan flowchart.
+const maxPlanNodes = 60
+
+// planNode is a step of a query plan with the fields shown in the chart.
+type planNode struct {
+	id       string
+	label    string
+	details  []string
+	cost     float64
+	hasCost  bool
+	children []*planNode
+}
+
+// PlanFlowchart renders a query plan, as returned by EXPLAIN in JSON
+// format, as a Mermaid flowchart in which rows flow from the scans up to
+// the result. The step with the highest cost of its own is highlighted.
+// PostgreSQL and ClickHouse plans ("Node Type", "Plans") and DuckDB plans
+// ("name", "children") are understood.
+func PlanFlowchart(plan string) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal([]byte(plan), &v); err != nil {
+		return "", fmt.Errorf("failed to parse query plan: %w", err)
+	}
+
+	var roots []map[string]interface{}
+	items, ok := v.([]interface{})
+	if !ok {
+		items = []interface{}{v}
+	}
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if p, ok := m["Plan"].(map[string]interface{}); ok {
+			m = p
+		}
+		roots = append(roots, m)
+	}
+
+	count := 0
+	var nodes []*planNode
+	for _, r := range roots {
+		if n := parsePlanNode(r, &count); n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("query plan has no steps")
+	}
+
+	var b strings.Builder
+	b.WriteString("```mermaid\nflowchart BT\n")
+	var costliest *planNode
+	maxSelf := 0.0
+	var walk func(n *planNode)
+	walk = func(n *planNode) {
+		label := n.label
+		if len(n.details) > 0 {
+			label += "<br/>" + strings.Join(n.details, "<br/>")
+		}
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", n.id, strings.ReplaceAll(label, `"`, "#quot;"))
+		self := n.cost
+		for _, c := range n.children {
+			walk(c)
+			fmt.Fprintf(&b, "    %s --> %s\n", c.id, n.id)
+			self -= c.cost
+		}
+		if n.hasCost && self > maxSelf {
+			costliest, maxSelf = n, self
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	if costliest != nil {
+		fmt.Fprintf(&b, "    style %s fill:#f96,stroke:#c00\n", costliest.id)
+	}
+	b.WriteString("```")
+	return b.String(), nil
+}
+
+// parsePlanNode reads a step and its inputs, numbering them in count.
+func parsePlanNode(m map[string]interface{}, count *int) *planNode {
+	if *count >= maxPlanNodes {
+		return nil
+	}
+	*count++
+	n := &planNode{id: fmt.Sprintf("n%d", *count)}
+
+	n.label = strings.TrimSpace(planString(m["Node Type"]))
+	if n.label == "" {
+		n.label = strings.TrimSpace(planString(m["name"]))
+	}
+	if n.label == "" {
+		n.label = "Step"
+	}
+
+	extra, _ := m["extra_info"].(map[string]interface{})
+	if s := planString(m["Join Type"]); s != "" {
+		n.label = s + " " + n.label
+	}
+	if s := planString(m["Relation Name"]); s != "" {
+		n.details = append(n.details, "on "+s)
+	} else if s := planString(extra["Table"]); s != "" {
+		n.details = append(n.details, "on "+s)
+	}
+	if s := planString(m["Index Name"]); s != "" {
+		n.details = append(n.details, "using "+s)
+	}
+	if s := planString(m["Description"]); s != "" {
+		n.details = append(n.details, s)
+	}
+
+	var stats []string
+	if f, ok := planNumber(m["Total Cost"]); ok {
+		n.cost, n.hasCost = f, true
+		stats = append(stats, "cost "+formatPlanNumber(f))
+	}
+	if f, ok := planNumber(m["Plan Rows"]); ok {
+		stats = append(stats, "rows "+formatPlanNumber(f))
+	} else if f, ok := planNumber(extra["Estimated Cardinality"]); ok {
+		stats = append(stats, "rows "+formatPlanNumber(f))
+	}
+	if f, ok := planNumber(m["Actual Total Time"]); ok {
+		stats = append(stats, formatPlanNumber(f)+" ms")
+	}
+	if len(stats) > 0 {
+		n.details = append(n.details, strings.Join(stats, " · "))
+	}
+
+	children, _ := m["Plans"].([]interface{})
+	if children == nil {
+		children, _ = m["children"].([]interface{})
+	}
+	for _, c := range children {
+		cm, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if child := parsePlanNode(cm, count); child != nil {
+			n.children = append(n.children, child)
+		}
+	}
+	return n
+}
+
+func planString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, p := range v {
+			parts[i] = planString(p)
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+func planNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(v), "~"), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func formatPlanNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,62 @@
+package chart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanFlowchart(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "postgres",
+			plan: `[{"Plan": {"Node Type": "Hash Join", "Join Type": "Inner", "Total Cost": 520.5, "Plan Rows": 1000, "Plans": [
+				{"Node Type": "Seq Scan", "Relation Name": "orders", "Total Cost": 431, "Plan Rows": 10000},
+				{"Node Type": "Hash", "Total Cost": 12.5, "Plan Rows": 50, "Plans": [
+					{"Node Type": "Index Scan", "Relation Name": "customers", "Index Name": "customers_pkey", "Total Cost": 12.5, "Plan Rows": 50}
+				]}
+			]}}]`,
+			want: []string{
+				"```mermaid\nflowchart BT\n",
+				`n1["Inner Hash Join<br/>cost 520.5 · rows 1000"]`,
+				`n2["Seq Scan<br/>on orders<br/>cost 431 · rows 10000"]`,
+				`n4["Index Scan<br/>on customers<br/>using customers_pkey<br/>cost 12.5 · rows 50"]`,
+				"n2 --> n1\n", "n4 --> n3\n", "n3 --> n1\n",
+				"style n2 fill:#f96",
+			},
+		},
+		{
+			name: "duckdb",
+			plan: `[{"name": "PROJECTION", "children": [{"name": "SEQ_SCAN ", "extra_info": {"Table": "sales", "Estimated Cardinality": "~120"}, "children": []}]}]`,
+			want: []string{`n1["PROJECTION"]`, `n2["SEQ_SCAN<br/>on sales<br/>rows 120"]`, "n2 --> n1\n"},
+		},
+		{
+			name: "quotes in labels",
+			plan: `{"Plan": {"Node Type": "Result", "Description": "say \"hi\""}}`,
+			want: []string{`n1["Result<br/>say #quot;hi#quot;"]`},
+		},
+		{name: "not json", plan: "Seq Scan on orders", wantErr: true},
+		{name: "no steps", plan: `[]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PlanFlowchart(tt.plan)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("chart missing %q:\n%s", want, got)
+				}
+			}
+			if !tt.wantErr && len(ExtractMermaid(got)) != 1 {
+				t.Errorf("chart is not one mermaid block:\n%s", got)
+			}
+		})
+	}
+}
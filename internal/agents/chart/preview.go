@@ -0,0 +1,84 @@
+package chart
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxPreviewPoints bounds the number of bars drawn in a preview chart.
+const maxPreviewPoints = 50
+
+// Preview builds a provisional bar chart from (possibly partial) query rows so
+// front-ends can draw something while the query and the Chart agent are still
+// running. The first column supplies the labels and the last numeric column
+// the values. It returns false if the rows have no such column pair.
+func Preview(title string, columns []string, rows []map[string]interface{}) (string, bool) {
+	if len(columns) < 2 || len(rows) == 0 {
+		return "", false
+	}
+
+	label := columns[0]
+	value := ""
+	for i := len(columns) - 1; i > 0; i-- {
+		if numericColumn(rows, columns[i]) {
+			value = columns[i]
+			break
+		}
+	}
+	if value == "" {
+		return "", false
+	}
+
+	if len(rows) > maxPreviewPoints {
+		rows = rows[:maxPreviewPoints]
+	}
+	labels := make([]string, len(rows))
+	values := make([]float64, len(rows))
+	for i, row := range rows {
+		labels[i] = strings.ReplaceAll(fmt.Sprint(row[label]), `"`, "'")
+		values[i], _ = toFloat(row[value])
+	}
+
+	charts := ExtractMermaid(GenerateMermaidBarChart(title, labels, values, value))
+	if len(charts) == 0 {
+		return "", false
+	}
+	return charts[0], true
+}
+
+// numericColumn reports whether every non-null value of column is a number.
+func numericColumn(rows []map[string]interface{}, column string) bool {
+	seen := false
+	for _, row := range rows {
+		v, ok := row[column]
+		if !ok || v == nil {
+			continue
+		}
+		if _, ok := toFloat(v); !ok {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case string:
+		// NUMERIC columns are returned as strings
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,61 @@
+package chart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		rows    []map[string]interface{}
+		wantOK  bool
+		want    string
+	}{
+		{
+			name:    "label and numeric value",
+			columns: []string{"month", "orders"},
+			rows: []map[string]interface{}{
+				{"month": "Jan", "orders": int64(12)},
+				{"month": "Feb", "orders": int64(30)},
+			},
+			wantOK: true,
+			want:   `bar [12, 30]`,
+		},
+		{
+			name:    "numeric strings from NUMERIC columns",
+			columns: []string{"region", "revenue"},
+			rows: []map[string]interface{}{
+				{"region": "EU", "revenue": "1200.50"},
+				{"region": "US", "revenue": nil},
+			},
+			wantOK: true,
+			want:   `x-axis ["EU", "US"]`,
+		},
+		{
+			name:    "no numeric column",
+			columns: []string{"name", "email"},
+			rows:    []map[string]interface{}{{"name": "a", "email": "b"}},
+			wantOK:  false,
+		},
+		{
+			name:    "single column",
+			columns: []string{"count"},
+			rows:    []map[string]interface{}{{"count": int64(1)}},
+			wantOK:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Preview("Preview", tt.columns, tt.rows)
+			if ok != tt.wantOK {
+				t.Fatalf("Preview() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !strings.Contains(got, tt.want) {
+				t.Errorf("Preview() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
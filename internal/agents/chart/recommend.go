@@ -0,0 +1,240 @@
+package chart
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Chart types recommended for query results.
+const (
+	ChartBar       = "bar"
+	ChartLine      = "line"
+	ChartPie       = "pie"
+	ChartHistogram = "histogram"
+	ChartScatter   = "scatter"
+)
+
+const (
+	// maxPieSlices is the largest number of categories shown as a pie.
+	maxPieSlices = 6
+	// maxBars is the largest number of categories drawn as bars.
+	maxBars = 30
+)
+
+// Recommendation is the chart suggested for a query result: its type, the
+// column on the x-axis (the labels of a pie) and the columns plotted.
+// Histograms and scatter plots, which the model cannot reliably work out
+// from rows, come drawn in Mermaid.
+type Recommendation struct {
+	Chart   string   `json:"chart"`
+	X       string   `json:"x"`
+	Y       []string `json:"y"`
+	Reason  string   `json:"reason"`
+	Mermaid string   `json:"mermaid,omitempty"`
+}
+
+// timeColumnName matches columns named after a unit of time, whose
+// numbers (years, months, ...) are points in time rather than amounts.
+var timeColumnName = regexp.MustCompile(`(?i)(^|_)(date|day|week|month|quarter|year|hour)$`)
+
+// idColumnName matches identifier columns, which are labels, not amounts.
+var idColumnName = regexp.MustCompile(`(?i)(^|_)id$`)
+
+// Recommend proposes a chart for a query result from the shape of its
+// columns: a line over a time-like column, a pie for a few categories of a
+// single non-negative amount, bars for other categories, and for amounts
+// without labels a histogram of one or a scatter plot of the first two. It
+// returns false if no chart fits, e.g. for a single row or a result without
+// amounts.
+func Recommend(columns []string, rows []map[string]interface{}) (Recommendation, bool) {
+	if len(rows) < 2 || len(columns) == 0 {
+		return Recommendation{}, false
+	}
+
+	var timeCol, labelCol string
+	var measures []string
+	for _, c := range columns {
+		switch {
+		case timeColumn(rows, c):
+			if timeCol == "" {
+				timeCol = c
+			}
+		case numericColumn(rows, c) && !idColumnName.MatchString(c):
+			measures = append(measures, c)
+		case labelCol == "":
+			labelCol = c
+		}
+	}
+	if len(measures) == 0 {
+		return Recommendation{}, false
+	}
+
+	if timeCol != "" {
+		reason := fmt.Sprintf("%s is a time series; draw a line per amount in time order", timeCol)
+		if labelCol != "" {
+			reason = fmt.Sprintf("%s is a time series; draw a line per value of %s in time order", timeCol, labelCol)
+		}
+		return Recommendation{Chart: ChartLine, X: timeCol, Y: measures, Reason: reason}, true
+	}
+	if labelCol == "" {
+		return distribution(rows, measures), true
+	}
+
+	categories := distinctValues(rows, labelCol)
+	if len(measures) == 1 && categories <= maxPieSlices && nonNegative(rows, measures[0]) {
+		return Recommendation{
+			Chart:  ChartPie,
+			X:      labelCol,
+			Y:      measures,
+			Reason: fmt.Sprintf("%d categories of %s share %s", categories, labelCol, measures[0]),
+		}, true
+	}
+	reason := fmt.Sprintf("compare %s across the %d values of %s", strings.Join(measures, ", "), categories, labelCol)
+	if categories > maxBars {
+		reason += fmt.Sprintf("; show the top %d by value", maxBars)
+	}
+	return Recommendation{Chart: ChartBar, X: labelCol, Y: measures, Reason: reason}, true
+}
+
+// RecommendJSON is Recommend for a query result as a JSON array of rows,
+// whose columns are taken in the order of the first row.
+func RecommendJSON(data string) (Recommendation, bool) {
+	var rows []map[string]interface{}
+	if json.Unmarshal([]byte(data), &rows) != nil || len(rows) == 0 {
+		return Recommendation{}, false
+	}
+	var first []json.RawMessage
+	if json.Unmarshal([]byte(data), &first) != nil {
+		return Recommendation{}, false
+	}
+	columns, err := objectKeys(first[0])
+	if err != nil {
+		return Recommendation{}, false
+	}
+	return Recommend(columns, rows)
+}
+
+// objectKeys returns the keys of a JSON object in order.
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("row is not a JSON object")
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		keys = append(keys, key)
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// timeColumn reports whether column holds dates or times: every non-null
+// value is an ISO date or timestamp, or a number in a column named after a
+// unit of time.
+func timeColumn(rows []map[string]interface{}, column string) bool {
+	if timeColumnName.MatchString(column) && numericColumn(rows, column) {
+		return true
+	}
+	seen := false
+	for _, row := range rows {
+		v := row[column]
+		if v == nil {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok || !isDate(s) {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+// isDate reports whether s starts with an ISO date or year-month.
+func isDate(s string) bool {
+	if len(s) >= 10 {
+		if _, err := time.Parse("2006-01-02", s[:10]); err == nil {
+			return len(s) == 10 || s[10] == 'T' || s[10] == ' '
+		}
+	}
+	_, err := time.Parse("2006-01", s)
+	return err == nil
+}
+
+// distribution recommends a histogram of a single amount or a scatter plot
+// of the first two.
+func distribution(rows []map[string]interface{}, measures []string) Recommendation {
+	x := measures[0]
+	if len(measures) == 1 {
+		values := columnValues(rows, x)
+		bins := Histogram(values, 0)
+		return Recommendation{
+			Chart:   ChartHistogram,
+			X:       x,
+			Y:       []string{"count"},
+			Reason:  fmt.Sprintf("distribution of %s over %d rows in %d bins; return the mermaid chart, with a title of your own", x, len(values), len(bins)),
+			Mermaid: GenerateMermaidHistogram("Distribution of "+x, values, 0, x),
+		}
+	}
+
+	y := measures[1]
+	var points []Point
+	for _, row := range rows {
+		px, okX := toFloat(row[x])
+		py, okY := toFloat(row[y])
+		if okX && okY {
+			points = append(points, Point{X: px, Y: py})
+		}
+	}
+	reason := fmt.Sprintf("relationship between %s and %s; return the mermaid chart, with a title of your own", x, y)
+	if len(points) > maxScatterPoints {
+		reason += fmt.Sprintf("; it shows the first %d of %d points", maxScatterPoints, len(points))
+	}
+	return Recommendation{
+		Chart:   ChartScatter,
+		X:       x,
+		Y:       []string{y},
+		Reason:  reason,
+		Mermaid: GenerateMermaidScatterChart(y+" by "+x, points, x, y),
+	}
+}
+
+// columnValues returns the numbers of a column, skipping nulls.
+func columnValues(rows []map[string]interface{}, column string) []float64 {
+	var values []float64
+	for _, row := range rows {
+		if f, ok := toFloat(row[column]); ok {
+			values = append(values, f)
+		}
+	}
+	return values
+}
+
+func distinctValues(rows []map[string]interface{}, column string) int {
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		seen[fmt.Sprint(row[column])] = true
+	}
+	return len(seen)
+}
+
+func nonNegative(rows []map[string]interface{}, column string) bool {
+	for _, row := range rows {
+		if f, ok := toFloat(row[column]); ok && f < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package chart
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecommendJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		want   Recommendation
+		wantOK bool
+		// wantMermaid is the start of the recommendation's chart
+		wantMermaid string
+	}{
+		{
+			name:   "dates",
+			data:   `[{"day":"2026-01-01","orders":12,"revenue":"150.5"},{"day":"2026-01-02","orders":9,"revenue":"99"}]`,
+			want:   Recommendation{Chart: ChartLine, X: "day", Y: []string{"orders", "revenue"}},
+			wantOK: true,
+		},
+		{
+			name:   "timestamps by region",
+			data:   `[{"region":"EU","at":"2026-01-01T00:00:00Z","n":1},{"region":"US","at":"2026-01-01 00:00:00","n":2}]`,
+			want:   Recommendation{Chart: ChartLine, X: "at", Y: []string{"n"}},
+			wantOK: true,
+		},
+		{
+			name:   "numeric years",
+			data:   `[{"order_year":2024,"total":10},{"order_year":2025,"total":12}]`,
+			want:   Recommendation{Chart: ChartLine, X: "order_year", Y: []string{"total"}},
+			wantOK: true,
+		},
+		{
+			name:   "few categories",
+			data:   `[{"status":"paid","count":7},{"status":"refunded","count":1},{"status":"open","count":null}]`,
+			want:   Recommendation{Chart: ChartPie, X: "status", Y: []string{"count"}},
+			wantOK: true,
+		},
+		{
+			name:   "negative amounts",
+			data:   `[{"status":"paid","margin":7},{"status":"refunded","margin":-3}]`,
+			want:   Recommendation{Chart: ChartBar, X: "status", Y: []string{"margin"}},
+			wantOK: true,
+		},
+		{
+			name:   "several amounts by id",
+			data:   `[{"customer_id":1,"orders":3,"spent":40},{"customer_id":2,"orders":1,"spent":12}]`,
+			want:   Recommendation{Chart: ChartBar, X: "customer_id", Y: []string{"orders", "spent"}},
+			wantOK: true,
+		},
+		{
+			name:        "one amount",
+			data:        `[{"amount":12.5},{"amount":40},{"amount":null},{"amount":"7"}]`,
+			want:        Recommendation{Chart: ChartHistogram, X: "amount", Y: []string{"count"}},
+			wantOK:      true,
+			wantMermaid: "```mermaid\nxychart-beta\n    title \"Distribution of amount\"",
+		},
+		{
+			name:        "two amounts",
+			data:        `[{"price":3,"units":10},{"price":5,"units":4},{"price":9,"units":null}]`,
+			want:        Recommendation{Chart: ChartScatter, X: "price", Y: []string{"units"}},
+			wantOK:      true,
+			wantMermaid: "```mermaid\nquadrantChart\n    title units by price\n",
+		},
+		{name: "single row", data: `[{"status":"paid","count":7}]`},
+		{name: "no amounts", data: `[{"name":"Ada","email":"a@x"},{"name":"Grace","email":"g@x"}]`},
+		{name: "not rows", data: `{"error":"boom"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RecommendJSON(tt.data)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v (%+v)", ok, tt.wantOK, got)
+			}
+			got.Reason = ""
+			if !strings.HasPrefix(got.Mermaid, tt.wantMermaid) || (tt.wantMermaid == "") != (got.Mermaid == "") {
+				t.Errorf("mermaid = %q, want it to start with %q", got.Mermaid, tt.wantMermaid)
+			}
+			got.Mermaid = ""
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
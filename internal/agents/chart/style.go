@@ -0,0 +1,257 @@
+package chart
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/i18n"
+)
+
+// Themes of Mermaid charts.
+const (
+	ThemeDefault = "default"
+	ThemeNeutral = "neutral"
+	ThemeDark    = "dark"
+	ThemeForest  = "forest"
+	ThemeBase    = "base"
+)
+
+// Themes lists the Mermaid themes.
+var Themes = []string{ThemeDefault, ThemeNeutral, ThemeDark, ThemeForest, ThemeBase}
+
+const (
+	// maxChartSize bounds the width and height of charts in pixels.
+	maxChartSize = 4000
+	// maxDecimals bounds the digits after the decimal point of values.
+	maxDecimals = 10
+	// maxPieColors is the number of slice colors Mermaid themes define.
+	maxPieColors = 12
+)
+
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Style is how charts look. Unset fields keep Mermaid's defaults, or those
+// of the style a request's style is merged into.
+type Style struct {
+	// Theme is a Mermaid theme, one of Themes
+	Theme string `json:"theme,omitempty"`
+	// Palette lists the colors of series and pie slices, as #rrggbb
+	Palette []string `json:"palette,omitempty"`
+	// Width and Height are the size of bar and line charts and of rendered
+	// images, in pixels
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// Decimals is the number of digits values are rounded to
+	Decimals *int `json:"decimals,omitempty"`
+	// ValueLabels draws the values on bars and points and next to pie slices
+	ValueLabels *bool `json:"value_labels,omitempty"`
+	// Locale formats the numbers written in titles and labels, e.g. de-DE
+	Locale string `json:"locale,omitempty"`
+}
+
+// Validate checks that the style's settings can be applied.
+func (s Style) Validate() error {
+	if s.Theme != "" && !slices.Contains(Themes, s.Theme) {
+		return fmt.Errorf("unknown chart theme %q (want one of %s)", s.Theme, strings.Join(Themes, ", "))
+	}
+	for _, c := range s.Palette {
+		if !hexColor.MatchString(c) {
+			return fmt.Errorf("invalid chart color %q (want #rrggbb)", c)
+		}
+	}
+	if s.Width < 0 || s.Width > maxChartSize || s.Height < 0 || s.Height > maxChartSize {
+		return fmt.Errorf("chart width and height must be between 0 and %d", maxChartSize)
+	}
+	if s.Decimals != nil && (*s.Decimals < 0 || *s.Decimals > maxDecimals) {
+		return fmt.Errorf("chart decimals must be between 0 and %d", maxDecimals)
+	}
+	if _, err := i18n.ParseLocale(s.Locale); err != nil {
+		return fmt.Errorf("invalid chart locale: %w", err)
+	}
+	return nil
+}
+
+// Merge returns s with the fields set in o replacing its own.
+func (s Style) Merge(o Style) Style {
+	if o.Theme != "" {
+		s.Theme = o.Theme
+	}
+	if len(o.Palette) > 0 {
+		s.Palette = o.Palette
+	}
+	if o.Width > 0 {
+		s.Width = o.Width
+	}
+	if o.Height > 0 {
+		s.Height = o.Height
+	}
+	if o.Decimals != nil {
+		s.Decimals = o.Decimals
+	}
+	if o.ValueLabels != nil {
+		s.ValueLabels = o.ValueLabels
+	}
+	if o.Locale != "" {
+		s.Locale = o.Locale
+	}
+	return s
+}
+
+// FormatValue writes a value as the style's locale does, rounded to its
+// decimals (or to whole numbers).
+func (s Style) FormatValue(v float64) string {
+	loc, err := i18n.ParseLocale(s.Locale)
+	if err != nil {
+		loc = i18n.DefaultLocale
+	}
+	decimals := 0
+	if s.Decimals != nil {
+		decimals = *s.Decimals
+	}
+	return loc.FormatNumber(v, decimals)
+}
+
+// valueLabels reports whether values are drawn on the chart.
+func (s Style) valueLabels() bool {
+	return s.ValueLabels != nil && *s.ValueLabels
+}
+
+// Directive returns the Mermaid init directive setting the style's theme,
+// palette, size and value labels, or "" if none is set.
+func (s Style) Directive() string {
+	init := map[string]interface{}{}
+	theme := s.Theme
+	if len(s.Palette) > 0 {
+		// Only the base theme takes custom colors
+		theme = ThemeBase
+		vars := map[string]interface{}{
+			"xyChart": map[string]string{"plotColorPalette": strings.Join(s.Palette, ", ")},
+		}
+		for i := 0; i < maxPieColors; i++ {
+			vars["pie"+strconv.Itoa(i+1)] = s.Palette[i%len(s.Palette)]
+		}
+		init["themeVariables"] = vars
+	}
+	if theme != "" {
+		init["theme"] = theme
+	}
+	xy := map[string]interface{}{}
+	if s.Width > 0 {
+		xy["width"] = s.Width
+	}
+	if s.Height > 0 {
+		xy["height"] = s.Height
+	}
+	if s.valueLabels() {
+		xy["showDataLabel"] = true
+	}
+	if len(xy) > 0 {
+		init["xyChart"] = xy
+	}
+	if len(init) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(init)
+	return "%%{init: " + string(data) + "}%%"
+}
+
+// Apply styles every ```mermaid block of text that does not set its own
+// init directive.
+func (s Style) Apply(text string) string {
+	const fence = "```mermaid"
+	directive := s.Directive()
+	if directive == "" && !s.valueLabels() {
+		return text
+	}
+
+	var out strings.Builder
+	for {
+		start := strings.Index(text, fence)
+		if start == -1 {
+			out.WriteString(text)
+			return out.String()
+		}
+		out.WriteString(text[:start+len(fence)])
+		text = text[start+len(fence):]
+		end := strings.Index(text, "```")
+		if end == -1 {
+			out.WriteString(text)
+			return out.String()
+		}
+		out.WriteString(s.style(text[:end], directive))
+		text = text[end:]
+		out.WriteString(text[:3])
+		text = text[3:]
+	}
+}
+
+// style adds the directive and value labels to a chart's body.
+func (s Style) style(body, directive string) string {
+	trimmed := strings.TrimLeft(body, " \t\r\n")
+	if strings.HasPrefix(trimmed, "%%{") {
+		return body
+	}
+	if s.valueLabels() && strings.HasPrefix(trimmed, "pie") && !strings.HasPrefix(trimmed, "pie showData") {
+		trimmed = "pie showData" + strings.TrimPrefix(trimmed, "pie")
+	}
+	if directive != "" {
+		trimmed = directive + "\n" + trimmed
+	}
+	return "\n" + trimmed
+}
+
+// Instruction tells the Chart agent how to write values, or returns "" if
+// the style leaves it to the agent.
+func (s Style) Instruction() string {
+	var lines []string
+	if s.Decimals != nil {
+		lines = append(lines, fmt.Sprintf("- Round values to %d decimal places", *s.Decimals))
+	}
+	if s.Locale != "" {
+		lines = append(lines, fmt.Sprintf("- Write numbers in titles and labels as in %s, e.g. %s; data values stay plain numbers such as 1234.5", s.Locale, s.FormatValue(1234.5)))
+	}
+	if s.valueLabels() {
+		lines = append(lines, "- Values are shown on the chart, so do not repeat them in the labels")
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\nChart style:\n" + strings.Join(lines, "\n")
+}
+
+// ImageURL returns a URL that renders the Mermaid definition as an image of
+// the style's size.
+func (s Style) ImageURL(mermaid string) string {
+	u := MermaidImageURL(mermaid)
+	query := url.Values{}
+	if s.Width > 0 {
+		query.Set("width", strconv.Itoa(s.Width))
+	}
+	if s.Height > 0 {
+		query.Set("height", strconv.Itoa(s.Height))
+	}
+	if len(query) == 0 {
+		return u
+	}
+	return u + "?" + query.Encode()
+}
+
+type styleKey struct{}
+
+// WithStyle returns a context whose turns draw charts in the given style,
+// merged into the configured one.
+func WithStyle(ctx context.Context, s Style) context.Context {
+	return context.WithValue(ctx, styleKey{}, s)
+}
+
+// StyleFrom returns the chart style of a context.
+func StyleFrom(ctx context.Context) Style {
+	s, _ := ctx.Value(styleKey{}).(Style)
+	return s
+}
@@ -0,0 +1,144 @@
+package chart
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStyleApply(t *testing.T) {
+	yes := true
+	const bar = "Sales:\n```mermaid\nxychart-beta\n    bar [1, 2]\n```\n"
+	const pie = "```mermaid\npie title Orders\n    \"paid\" : 7\n```"
+
+	tests := []struct {
+		name    string
+		style   Style
+		text    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "unset style leaves charts alone",
+			text:    bar,
+			want:    []string{bar},
+			notWant: []string{"%%{"},
+		},
+		{
+			name:  "theme and size",
+			style: Style{Theme: ThemeDark, Width: 900, Height: 400},
+			text:  bar,
+			want: []string{
+				"Sales:\n```mermaid\n%%{init: {",
+				`"theme":"dark"`,
+				`"xyChart":{"height":400,"width":900}`,
+				"}}%%\nxychart-beta\n    bar [1, 2]\n```\n",
+			},
+		},
+		{
+			name:  "palette switches to the base theme",
+			style: Style{Theme: ThemeDark, Palette: []string{"#ff0000", "#00ff00"}},
+			text:  pie,
+			want:  []string{`"theme":"base"`, `"plotColorPalette":"#ff0000, #00ff00"`, `"pie1":"#ff0000"`, `"pie2":"#00ff00"`, `"pie3":"#ff0000"`},
+		},
+		{
+			name:  "value labels",
+			style: Style{ValueLabels: &yes},
+			text:  bar + pie,
+			want:  []string{`"showDataLabel":true`, "pie showData title Orders"},
+		},
+		{
+			name:    "charts with their own directive",
+			style:   Style{Theme: ThemeForest},
+			text:    "```mermaid\n%%{init: {\"theme\":\"neutral\"}}%%\npie\n```",
+			want:    []string{`"theme":"neutral"`},
+			notWant: []string{"forest"},
+		},
+		{
+			name:    "other code blocks",
+			style:   Style{Theme: ThemeForest},
+			text:    "```sql\nSELECT 1\n```",
+			want:    []string{"```sql\nSELECT 1\n```"},
+			notWant: []string{"%%{"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.style.Apply(tt.text)
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("Apply() = %q, want it to contain %q", got, w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("Apply() = %q, want it not to contain %q", got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestStyleValidate(t *testing.T) {
+	two, eleven := 2, 11
+	tests := []struct {
+		name    string
+		style   Style
+		wantErr bool
+	}{
+		{name: "empty", style: Style{}},
+		{name: "full", style: Style{Theme: ThemeNeutral, Palette: []string{"#abc", "#1f77b4"}, Width: 800, Height: 600, Decimals: &two, Locale: "de-DE"}},
+		{name: "unknown theme", style: Style{Theme: "pink"}, wantErr: true},
+		{name: "named color", style: Style{Palette: []string{"red"}}, wantErr: true},
+		{name: "too wide", style: Style{Width: 5000}, wantErr: true},
+		{name: "too many decimals", style: Style{Decimals: &eleven}, wantErr: true},
+		{name: "bad locale", style: Style{Locale: "not a locale!"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.style.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStyleMerge(t *testing.T) {
+	one, no := 1, false
+	base := Style{Theme: ThemeDark, Width: 800, Palette: []string{"#000000"}, Locale: "en-US"}
+	got := base.Merge(StyleFrom(WithStyle(context.Background(), Style{Theme: ThemeForest, Decimals: &one, ValueLabels: &no})))
+
+	if got.Theme != ThemeForest || got.Width != 800 || len(got.Palette) != 1 || got.Locale != "en-US" {
+		t.Errorf("Merge() = %+v, want the request's theme over the configured style", got)
+	}
+	if got.Decimals == nil || *got.Decimals != 1 || got.ValueLabels == nil || *got.ValueLabels {
+		t.Errorf("Merge() = %+v, want the request's decimals and value labels", got)
+	}
+	if StyleFrom(context.Background()).Directive() != "" {
+		t.Error("StyleFrom() of a plain context should be empty")
+	}
+}
+
+func TestStyleInstruction(t *testing.T) {
+	two := 2
+	if got := (Style{Theme: ThemeDark}).Instruction(); got != "" {
+		t.Errorf("Instruction() = %q, want empty for a theme only", got)
+	}
+	got := Style{Decimals: &two, Locale: "de-DE"}.Instruction()
+	for _, w := range []string{"Round values to 2 decimal places", "as in de-DE, e.g. 1.234,50"} {
+		if !strings.Contains(got, w) {
+			t.Errorf("Instruction() = %q, want it to contain %q", got, w)
+		}
+	}
+}
+
+func TestStyleImageURL(t *testing.T) {
+	if got := (Style{}).ImageURL("pie"); got != MermaidImageURL("pie") {
+		t.Errorf("ImageURL() = %q, want the plain URL", got)
+	}
+	if got := (Style{Width: 640, Height: 480}).ImageURL("pie"); !strings.HasSuffix(got, "?height=480&width=640") {
+		t.Errorf("ImageURL() = %q, want the size in the query", got)
+	}
+}
@@ -0,0 +1,165 @@
+// Package files implements a Files agent that answers questions about the
+// CSV, Parquet and JSON files in a local directory by querying them with
+// DuckDB.
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/registry"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/duckdb"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/prompts"
+	"github.com/anuvratrastogi/multi-agent/internal/toolschema"
+	"github.com/anuvratrastogi/multi-agent/pkg/bert"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+const (
+	agentName = "FilesAgent"
+	agentDesc = "Answers questions about local CSV, Parquet and JSON data files by querying them with DuckDB SQL"
+)
+
+// Intent is the classifier intent routed to the Files agent.
+var Intent = registry.Intent{
+	Name:     bert.Intent("files"),
+	Keywords: []string{"files", "csv", "parquet", "jsonl", "spreadsheet"},
+}
+
+// Querier lists and queries data files. *duckdb.Client implements it.
+type Querier interface {
+	Files() ([]duckdb.File, error)
+	QueryFile(ctx context.Context, file, query string, limit int) (string, error)
+}
+
+// Config holds configuration for the Files agent.
+type Config struct {
+	Model model.LLM
+	Files Querier
+	// GenerateConfig sets generation parameters such as temperature (optional)
+	GenerateConfig *genai.GenerateContentConfig
+	// Prompts holds the template of the instruction (default: built-in)
+	Prompts *prompts.Store
+}
+
+// New creates a new Files agent, ready to be added to the Manager's
+// specialists.
+func New(cfg Config) (registry.Agent, error) {
+	if cfg.Prompts == nil {
+		cfg.Prompts = prompts.Default()
+	}
+	if _, err := cfg.Prompts.Render(prompts.Files, nil); err != nil {
+		return registry.Agent{}, fmt.Errorf("failed to create Files agent: %w", err)
+	}
+
+	tools, err := Tools(cfg.Files)
+	if err != nil {
+		return registry.Agent{}, err
+	}
+	llmAgent, err := llmagent.New(llmagent.Config{
+		Name:                agentName,
+		Description:         agentDesc,
+		InstructionProvider: cfg.Prompts.Provider(prompts.Files, nil),
+		Model:               cfg.Model,
+		Tools:               tools,
+
+		GenerateContentConfig: cfg.GenerateConfig,
+	})
+	if err != nil {
+		return registry.Agent{}, fmt.Errorf("failed to create Files agent: %w", err)
+	}
+
+	return registry.Agent{
+		Agent: llmAgent,
+		Spec:  registry.Spec{Name: agentName, Description: agentDesc, Intents: []registry.Intent{Intent}},
+	}, nil
+}
+
+// ListFilesResult is the result of list_files.
+type ListFilesResult struct {
+	Files []duckdb.File `json:"files"`
+	Error string        `json:"error,omitempty"`
+}
+
+// QueryFileArgs are the arguments of query_file.
+type QueryFileArgs struct {
+	File  string `json:"file" description:"The file to query, as its path from list_files"`
+	SQL   string `json:"sql" description:"The DuckDB SQL query to execute; the file is the table named data"`
+	Limit int    `json:"limit,omitempty" description:"Maximum number of rows to return (default: 100)"`
+}
+
+// errReadOnly is returned for data-modifying statements.
+const errReadOnly = "query_file is read-only; data files cannot be modified."
+
+// Tools returns the list_files and query_file tools.
+func Tools(files Querier) ([]tool.Tool, error) {
+	listFilesTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "list_files",
+			Description: "List the CSV, Parquet and JSON files that can be queried",
+		},
+		func(ctx tool.Context, args sqlagent.EmptyArgs) (ListFilesResult, error) {
+			list, err := files.Files()
+			if err != nil {
+				return ListFilesResult{Error: err.Error()}, nil
+			}
+			return ListFilesResult{Files: list}, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create list_files tool: %w", err)
+	}
+
+	schema, err := toolschema.For[QueryFileArgs]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query_file schema: %w", err)
+	}
+	queryFileTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "query_file",
+			Description: "Execute a SQL query against a data file and return results as JSON",
+			InputSchema: schema,
+		},
+		func(ctx tool.Context, args QueryFileArgs) (sqlagent.QueryResult2, error) {
+			if sqlagent.IsWriteStatement(args.SQL) {
+				events.Publish(ctx, &events.SQLExecuted{SQL: args.SQL, Error: errReadOnly, Rejected: true})
+				return sqlagent.QueryResult2{Error: errReadOnly}, nil
+			}
+			if sqlagent.IsDryRun(ctx) {
+				events.Publish(ctx, &events.SQLExecuted{SQL: args.SQL, DryRun: true})
+				return sqlagent.QueryResult2{Notice: sqlagent.DryRunNotice}, nil
+			}
+			limit := args.Limit
+			if limit == 0 {
+				limit = 100
+			}
+			start := time.Now()
+			data, err := files.QueryFile(ctx, args.File, args.SQL, limit)
+			executed := &events.SQLExecuted{SQL: args.SQL, DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				executed.Error = err.Error()
+				events.Publish(ctx, executed)
+				return sqlagent.QueryResult2{Error: err.Error()}, nil
+			}
+			var rows []json.RawMessage
+			if json.Unmarshal([]byte(data), &rows) == nil {
+				executed.RowCount = len(rows)
+			}
+			events.Publish(ctx, executed)
+			return sqlagent.QueryResult2{Data: data}, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create query_file tool: %w", err)
+	}
+
+	return []tool.Tool{listFilesTool, queryFileTool}, nil
+}
@@ -4,14 +4,22 @@ package manager
 import (
 	"context"
 	"fmt"
+	"log"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/registry"
 	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/clarify"
+	"github.com/anuvratrastogi/multi-agent/internal/prompts"
 	"github.com/anuvratrastogi/multi-agent/pkg/bert"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
 )
 
 const (
@@ -23,9 +31,19 @@ const (
 type Agent struct {
 	agent.Agent
 	classifier *bert.Classifier
+	// semantic classifies by sentence embeddings when an Embedder is set
+	semantic   *bert.SemanticClassifier
 	sqlAgent   *sqlagent.Agent
 	chartAgent *chart.Agent
 	llmAgent   agent.Agent
+	// routes maps the intents of registered agents to their names
+	routes map[bert.Intent]string
+	// model classifies queries below fallbackBelow confidence
+	model         model.LLM
+	fallbackBelow float64
+	choices       []intentChoice
+	// planAgents are the agents the planner gives steps to
+	planAgents []planAgent
 }
 
 // Config holds configuration for the Manager agent.
@@ -33,40 +51,97 @@ type Config struct {
 	Model      model.LLM
 	SQLAgent   *sqlagent.Agent
 	ChartAgent *chart.Agent
+	// Specialists are registered agents delegated to alongside the SQL
+	// and Chart agents (optional)
+	Specialists []registry.Agent
+	// Tools are the Manager's own tools, such as ask_clarification (optional)
+	Tools []tool.Tool
+	// FallbackBelow asks Model for the intent of queries the keyword
+	// classifier is less confident about (0 = never)
+	FallbackBelow float64
+	// Embedder classifies queries by their similarity to example
+	// questions of each intent instead of keywords (optional)
+	Embedder bert.Embedder
+	// ClassifierModel tunes the keyword classifier and adds example
+	// questions to the embedding classifier (optional)
+	ClassifierModel *bert.Model
+	// GenerateConfig sets generation parameters such as temperature (optional)
+	GenerateConfig *genai.GenerateContentConfig
+	// Prompts holds the template of the instruction (default: built-in)
+	Prompts *prompts.Store
 }
 
 // New creates a new Manager agent with hierarchical sub-agents.
 func New(cfg Config) (*Agent, error) {
 	classifier := bert.NewClassifier()
+	var semantic *bert.SemanticClassifier
+	if cfg.Embedder != nil {
+		semantic = bert.NewSemanticClassifier(cfg.Embedder, classifier)
+	}
+	subAgents := []agent.Agent{cfg.SQLAgent, cfg.ChartAgent}
+	routes := make(map[bert.Intent]string)
+	choices := slices.Clone(builtinChoices)
+	planAgents := []planAgent{
+		{"SQLAgent", "looks up, counts, aggregates or compares data in the database", bert.IntentSQLQuery},
+		{"ChartAgent", "draws a chart of data fetched by an earlier step", bert.IntentVisualization},
+	}
+	count := "two"
+	if len(cfg.Specialists) > 0 {
+		count = strconv.Itoa(2 + len(cfg.Specialists))
+	}
+	data := prompts.ManagerData{
+		Count:   count,
+		Clarify: slices.ContainsFunc(cfg.Tools, func(t tool.Tool) bool { return t.Name() == clarify.ToolName }),
+	}
+	if m := cfg.ClassifierModel; m != nil {
+		classifier.Apply(m)
+		if semantic != nil {
+			for intent, examples := range m.Examples {
+				semantic.AddIntent(intent, examples)
+			}
+		}
+	}
+	for _, s := range cfg.Specialists {
+		subAgents = append(subAgents, s.Agent)
+		intent := bert.IntentGeneral
+		if len(s.Spec.Intents) > 0 {
+			intent = s.Spec.Intents[0].Name
+		}
+		planAgents = append(planAgents, planAgent{s.Spec.Name, s.Spec.Description, intent})
+		data.Specialists = append(data.Specialists, prompts.SubAgent{Name: s.Spec.Name, Description: s.Spec.Description})
+		for _, intent := range s.Spec.Intents {
+			classifier.AddIntent(intent.Name, intent.Keywords)
+			if semantic != nil {
+				semantic.AddIntent(intent.Name, intent.Keywords)
+			}
+			routes[intent.Name] = s.Spec.Name
+			if !slices.ContainsFunc(choices, func(c intentChoice) bool { return c.Intent == intent.Name }) {
+				choices = append(choices, intentChoice{intent.Name, s.Spec.Description})
+			}
+		}
+	}
 
-	instruction := `You are a manager agent that coordinates between specialized sub-agents.
-Your role is to:
-1. Understand user requests
-2. Route requests to the appropriate sub-agent based on intent
-3. Combine results from multiple agents when needed
-
-You have access to two sub-agents:
-- SQLAgent: For database queries and SQL operations
-- ChartAgent: For data visualization and chart generation
-
-Workflow patterns:
-1. SQL-only: User wants data → delegate to SQLAgent
-2. Combined: User wants to see data as a chart → first SQLAgent, then ChartAgent with the results
-
-CRITICAL RULES:
-- ChartAgent CANNOT access the database directly. It only creates charts from data passed in context.
-- If the user asks for a chart but HAS NOT provided specific data numbers, you MUST delegate to SQLAgent FIRST to fetch the data.
-- Once SQLAgent returns the data (as JSON or Table), you MUST call ChartAgent and PASS THAT DATA in your request (e.g., "Create a chart from this data: ...").
-- NEVER delegate directly to ChartAgent if data is missing. Always SQLAgent first.
+	planAgents = append(planAgents,
+		planAgent{ExportStep, "saves the last query result to a CSV or JSON file once the other steps are done", bert.IntentExport},
+		planAgent{agentName, "answers greetings, help and questions that need no data itself", bert.IntentGeneral},
+	)
 
-Always provide clear, helpful responses that summarize what was done.`
+	if cfg.Prompts == nil {
+		cfg.Prompts = prompts.Default()
+	}
+	if _, err := cfg.Prompts.Render(prompts.Manager, data); err != nil {
+		return nil, fmt.Errorf("failed to create Manager agent: %w", err)
+	}
 
 	llmAgent, err := llmagent.New(llmagent.Config{
-		Name:        agentName,
-		Description: agentDesc,
-		SubAgents:   []agent.Agent{cfg.SQLAgent, cfg.ChartAgent},
-		Instruction: instruction,
-		Model:       cfg.Model,
+		Name:                agentName,
+		Description:         agentDesc,
+		SubAgents:           subAgents,
+		Tools:               cfg.Tools,
+		InstructionProvider: cfg.Prompts.Provider(prompts.Manager, data),
+		Model:               cfg.Model,
+
+		GenerateContentConfig: cfg.GenerateConfig,
 	})
 
 	if err != nil {
@@ -76,21 +151,55 @@ Always provide clear, helpful responses that summarize what was done.`
 	return &Agent{
 		Agent:      llmAgent,
 		classifier: classifier,
+		semantic:   semantic,
 		sqlAgent:   cfg.SQLAgent,
 		chartAgent: cfg.ChartAgent,
 		llmAgent:   llmAgent,
+		routes:     routes,
+
+		model:         cfg.Model,
+		fallbackBelow: cfg.FallbackBelow,
+		choices:       choices,
+		planAgents:    planAgents,
 	}, nil
 }
 
 // ProcessQuery processes a user query by classifying intent and delegating.
 func (a *Agent) ProcessQuery(ctx context.Context, query string) (*Result, error) {
-	// Classify the intent
+	// Classify the intent, by embeddings if configured and they work
 	intent, confidence := a.classifier.ClassifyWithConfidence(query)
+	classifier := ClassifierKeyword
+	if a.semantic != nil {
+		var err error
+		intent, confidence, err = a.semantic.ClassifyWithConfidence(ctx, query)
+		if err != nil {
+			log.Printf("⚠️  Intent embedding failed, classifying by keywords: %v", err)
+		} else {
+			classifier = ClassifierEmbedding
+		}
+	}
 
 	result := &Result{
 		Query:            query,
 		ClassifiedIntent: string(intent),
 		Confidence:       confidence,
+		Classifier:       classifier,
+	}
+
+	// Ask the LLM when the first reading is inconclusive, keeping both
+	// readings to evaluate the keyword or embedding classifier against
+	if confidence < a.fallbackBelow {
+		keyword := Classification{Classifier: classifier, Intent: string(intent), Confidence: confidence}
+		llm, err := classifyWithLLM(ctx, a.model, a.choices, query)
+		if err != nil {
+			llm = Classification{Classifier: ClassifierLLM, Error: err.Error()}
+		} else {
+			intent = bert.Intent(llm.Intent)
+			result.ClassifiedIntent = llm.Intent
+			result.Confidence = llm.Confidence
+			result.Classifier = ClassifierLLM
+		}
+		result.Classifications = []Classification{keyword, llm}
 	}
 
 	// Determine which agents to use based on intent
@@ -110,13 +219,45 @@ func (a *Agent) ProcessQuery(ctx context.Context, query string) (*Result, error)
 		}
 
 	default:
+		if name, ok := a.routes[intent]; ok {
+			result.AgentsUsed = []string{name}
+			result.Workflow = string(intent)
+			break
+		}
 		result.AgentsUsed = []string{"ManagerAgent"}
 		result.Workflow = "general"
 	}
 
+	// A compound request is planned as steps across the agents
+	if steps := a.classifier.Steps(query); len(steps) > 1 {
+		result.AgentsUsed = nil
+		for _, s := range steps {
+			agent := a.agentFor(s.Intent)
+			result.Steps = append(result.Steps, Step{Intent: string(s.Intent), Agent: agent, Task: s.Text})
+			result.AgentsUsed = append(result.AgentsUsed, agent)
+		}
+		result.Workflow = WorkflowCompound
+	}
+
 	return result, nil
 }
 
+// agentFor returns the name of the agent handling intent.
+func (a *Agent) agentFor(intent bert.Intent) string {
+	switch intent {
+	case bert.IntentSQLQuery:
+		return "SQLAgent"
+	case bert.IntentVisualization:
+		return "ChartAgent"
+	case bert.IntentExport:
+		return ExportStep
+	}
+	if name, ok := a.routes[intent]; ok {
+		return name
+	}
+	return "ManagerAgent"
+}
+
 // needsDataFetch determines if the query needs to fetch data first.
 func needsDataFetch(query string) bool {
 	queryLower := strings.ToLower(query)
@@ -145,6 +286,14 @@ type Result struct {
 	SQLResult        string   `json:"sql_result,omitempty"`
 	ChartResult      string   `json:"chart_result,omitempty"`
 	Error            string   `json:"error,omitempty"`
+	// Classifier is the classifier that decided the intent
+	Classifier string `json:"classifier,omitempty"`
+	// Classifications holds the keyword and the LLM classifications when
+	// the LLM was asked
+	Classifications []Classification `json:"classifications,omitempty"`
+	// Steps is the plan of a compound request, one step per clause in
+	// order; set only when the request has more than one
+	Steps []Step `json:"steps,omitempty"`
 }
 
 // GetClassifier returns the intent classifier.
@@ -0,0 +1,184 @@
+package manager_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/registry"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+)
+
+func TestSpecialists(t *testing.T) {
+	llm := mockllm.New()
+	sqlAgent, err := sqlagent.New(sqlagent.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartAgent, err := chart.New(chart.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forecast, err := llmagent.New(llmagent.Config{Name: "ForecastAgent", Description: "Projects time series", Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := manager.New(manager.Config{
+		Model:      llm,
+		SQLAgent:   sqlAgent,
+		ChartAgent: chartAgent,
+		Specialists: []registry.Agent{{Agent: forecast, Spec: registry.Spec{
+			Name:        "ForecastAgent",
+			Description: "Projects time series",
+			Intents:     []registry.Intent{{Name: "forecast", Keywords: []string{"forecast", "predict"}}},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.ContainsFunc(mgr.SubAgents(), func(a agent.Agent) bool { return a.Name() == "ForecastAgent" }) {
+		t.Errorf("sub-agents do not include ForecastAgent")
+	}
+
+	tests := []struct {
+		query      string
+		wantAgents []string
+	}{
+		{query: "Forecast revenue for next quarter", wantAgents: []string{"ForecastAgent"}},
+		{query: "Select all records where status is active", wantAgents: []string{"SQLAgent"}},
+		{query: "Get monthly sales, chart it, then forecast next quarter and export to CSV", wantAgents: []string{"SQLAgent", "ChartAgent", "ForecastAgent", manager.ExportStep}},
+	}
+	for _, tt := range tests {
+		res, err := mgr.ProcessQuery(context.Background(), tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(res.AgentsUsed, ",") != strings.Join(tt.wantAgents, ",") {
+			t.Errorf("%q routed to %v (%s), want %v", tt.query, res.AgentsUsed, res.ClassifiedIntent, tt.wantAgents)
+		}
+		if compound := len(tt.wantAgents) > 1; compound != (res.Workflow == manager.WorkflowCompound) || compound != (len(res.Steps) == len(tt.wantAgents)) {
+			t.Errorf("%q workflow %s with steps %+v", tt.query, res.Workflow, res.Steps)
+		}
+		if note := manager.PlanNote(res.Steps); len(res.Steps) > 1 && !strings.Contains(note, `4. "export to CSV": the application saves`) {
+			t.Errorf("PlanNote() = %q", note)
+		}
+	}
+}
+
+func TestLLMFallback(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "you classify the questions", User: "numbers lately", Respond: mockllm.Response{
+			Text: "```json\n{\"intent\": \"visualization\", \"confidence\": 0.8, \"reasoning\": \"Asks to see a trend.\"}\n```",
+		}},
+		mockllm.Rule{Agent: "you classify the questions", User: "florp", Respond: mockllm.Response{Text: `{"intent": "poetry", "confidence": 1}`}},
+	)
+	sqlAgent, err := sqlagent.New(sqlagent.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartAgent, err := chart.New(chart.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := manager.New(manager.Config{Model: llm, SQLAgent: sqlAgent, ChartAgent: chartAgent, FallbackBelow: 0.2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query          string
+		wantIntent     string
+		wantClassifier string
+		wantLLM        bool
+		wantLLMError   bool
+	}{
+		// No keywords match, so the LLM decides
+		{query: "our numbers lately", wantIntent: "visualization", wantClassifier: manager.ClassifierLLM, wantLLM: true},
+		// An intent the Manager cannot route keeps the keyword reading
+		{query: "florp", wantIntent: "general", wantClassifier: manager.ClassifierKeyword, wantLLM: true, wantLLMError: true},
+		// Confident keyword matches never reach the LLM
+		{query: "Select all records where status is active", wantIntent: "sql_query", wantClassifier: manager.ClassifierKeyword},
+	}
+	for _, tt := range tests {
+		res, err := mgr.ProcessQuery(context.Background(), tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ClassifiedIntent != tt.wantIntent || res.Classifier != tt.wantClassifier {
+			t.Errorf("%q classified as %s by %s, want %s by %s", tt.query, res.ClassifiedIntent, res.Classifier, tt.wantIntent, tt.wantClassifier)
+		}
+		if !tt.wantLLM {
+			if len(res.Classifications) != 0 {
+				t.Errorf("%q classifications = %+v, want none", tt.query, res.Classifications)
+			}
+			continue
+		}
+		if len(res.Classifications) != 2 || res.Classifications[0].Classifier != manager.ClassifierKeyword || (res.Classifications[1].Error != "") != tt.wantLLMError {
+			t.Errorf("%q classifications = %+v, want the keyword and LLM readings", tt.query, res.Classifications)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "these steps were already carried out", Respond: mockllm.Response{Text: `{"steps": [{"agent": "SQLAgent", "task": "get revenue from orders"}]}`}},
+		mockllm.Rule{Agent: "you plan how", User: "revenue chart", Respond: mockllm.Response{
+			Text: "```json\n{\"steps\": [{\"agent\": \"sqlagent\", \"task\": \"get revenue\"}, {\"agent\": \"ChartAgent\", \"task\": \"chart it\"}, {\"agent\": \"Export\", \"task\": \"save as CSV\"}]}\n```",
+		}},
+		mockllm.Rule{Agent: "you plan how", User: "poem", Respond: mockllm.Response{Text: `{"steps": [{"agent": "PoetAgent", "task": "write a poem"}]}`}},
+		mockllm.Rule{Agent: "you plan how", Respond: mockllm.Response{Text: `{"steps": []}`}},
+	)
+	sqlAgent, err := sqlagent.New(sqlagent.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartAgent, err := chart.New(chart.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := manager.New(manager.Config{Model: llm, SQLAgent: sqlAgent, ChartAgent: chartAgent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	steps, err := mgr.Plan(ctx, "revenue chart, then export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []manager.Step{
+		{Intent: "sql_query", Agent: "SQLAgent", Task: "get revenue"},
+		{Intent: "visualization", Agent: "ChartAgent", Task: "chart it"},
+		{Intent: "export", Agent: manager.ExportStep, Task: "save as CSV"},
+	}
+	if !slices.Equal(steps, want) {
+		t.Errorf("Plan() = %+v, want %+v", steps, want)
+	}
+	note := manager.StepNote(steps, 1, []string{"Revenue was 960."})
+	if !strings.Contains(note, `step 2 of 3 of the plan for this request: "chart it". Delegate it to ChartAgent.`) || !strings.Contains(note, "1. SQLAgent: Revenue was 960.") {
+		t.Errorf("StepNote() = %q", note)
+	}
+
+	for _, query := range []string{"a poem", "nothing"} {
+		if steps, err := mgr.Plan(ctx, query); err == nil {
+			t.Errorf("Plan(%q) = %+v, want an error", query, steps)
+		}
+	}
+
+	failed := []manager.Step{{Intent: "sql_query", Agent: "SQLAgent", Task: "get revenue from payments", Status: manager.StepFailed, Error: `relation "payments" does not exist`}}
+	rest, err := mgr.Replan(ctx, "revenue chart", failed)
+	if err != nil || len(rest) != 1 || rest[0].Task != "get revenue from orders" {
+		t.Fatalf("Replan() = %+v, %v", rest, err)
+	}
+	instruction := llm.Requests()[len(llm.Requests())-1].Config.SystemInstruction.Parts[0].Text
+	if !strings.Contains(instruction, `1. SQLAgent: "get revenue from payments" failed: relation "payments" does not exist`) {
+		t.Errorf("replan instruction = %q, want the failed step", instruction)
+	}
+}
@@ -0,0 +1,125 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/pkg/bert"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// Classifiers that can decide a query's intent.
+const (
+	ClassifierKeyword   = "keyword"
+	ClassifierEmbedding = "embedding"
+	ClassifierLLM       = "llm"
+)
+
+// Classification is one classifier's reading of a query.
+type Classification struct {
+	Classifier string  `json:"classifier"`
+	Intent     string  `json:"intent"`
+	Confidence float64 `json:"confidence"`
+	Reasoning  string  `json:"reasoning,omitempty"`
+	// Error is set if the classifier failed and its reading was not used
+	Error string `json:"error,omitempty"`
+}
+
+// intentChoice is an intent the LLM classifier may pick.
+type intentChoice struct {
+	Intent      bert.Intent
+	Description string
+}
+
+// builtinChoices describe the intents of the built-in agents.
+var builtinChoices = []intentChoice{
+	{bert.IntentSQLQuery, "questions answered from the database: looking up, counting, aggregating or comparing data, or the structure of tables"},
+	{bert.IntentVisualization, "requests for a chart, graph or plot, of new or already fetched data"},
+	{bert.IntentGeneral, "greetings, help and questions that need no data"},
+}
+
+// classifyInstruction asks the model for a constrained classification.
+const classifyInstruction = `You classify the questions users ask a data analysis assistant. Pick the one intent that best describes the question:
+%s
+Reply with JSON only: {"intent": "<one of the intents>", "confidence": <0 to 1>, "reasoning": "<one short sentence>"}`
+
+// classificationSchema constrains the model's reply to a valid intent.
+func classificationSchema(intents []string) *genai.Schema {
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"intent":     {Type: genai.TypeString, Enum: intents},
+			"confidence": {Type: genai.TypeNumber, Description: "How sure you are, from 0 to 1"},
+			"reasoning":  {Type: genai.TypeString, Description: "One short sentence"},
+		},
+		Required:         []string{"intent", "confidence", "reasoning"},
+		PropertyOrdering: []string{"intent", "confidence", "reasoning"},
+	}
+}
+
+// classifyWithLLM asks the model for the intent of a query. The model must
+// pick one of the known intents.
+func classifyWithLLM(ctx context.Context, llm model.LLM, choices []intentChoice, query string) (Classification, error) {
+	var list strings.Builder
+	intents := make([]string, len(choices))
+	for i, c := range choices {
+		intents[i] = string(c.Intent)
+		fmt.Fprintf(&list, "- %s: %s\n", c.Intent, c.Description)
+	}
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText(query, genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(fmt.Sprintf(classifyInstruction, list.String()), genai.RoleUser),
+			ResponseMIMEType:  "application/json",
+			ResponseSchema:    classificationSchema(intents),
+			Temperature:       genai.Ptr[float32](0),
+		},
+	}
+	var reply strings.Builder
+	for resp, err := range llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return Classification{}, fmt.Errorf("failed to classify with the LLM: %w", err)
+		}
+		if resp == nil || resp.Content == nil {
+			continue
+		}
+		for _, p := range resp.Content.Parts {
+			if !p.Thought {
+				reply.WriteString(p.Text)
+			}
+		}
+	}
+	return parseClassification(reply.String(), intents)
+}
+
+// parseClassification reads the model's JSON reply, tolerating text or code
+// fences around it.
+func parseClassification(reply string, intents []string) (Classification, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return Classification{}, fmt.Errorf("failed to classify with the LLM: no JSON in reply %q", reply)
+	}
+	var out struct {
+		Intent     string  `json:"intent"`
+		Confidence float64 `json:"confidence"`
+		Reasoning  string  `json:"reasoning"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &out); err != nil {
+		return Classification{}, fmt.Errorf("failed to classify with the LLM: %w", err)
+	}
+	i := slices.IndexFunc(intents, func(intent string) bool { return strings.EqualFold(intent, strings.TrimSpace(out.Intent)) })
+	if i < 0 {
+		return Classification{}, fmt.Errorf("failed to classify with the LLM: unknown intent %q", out.Intent)
+	}
+	return Classification{
+		Classifier: ClassifierLLM,
+		Intent:     intents[i],
+		Confidence: min(max(out.Confidence, 0), 1),
+		Reasoning:  strings.TrimSpace(out.Reasoning),
+	}, nil
+}
@@ -0,0 +1,240 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/pkg/bert"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// Workflows of requests carried out as several steps.
+const (
+	// WorkflowCompound is the workflow of a request planned as several
+	// steps that the Manager carries out in one turn.
+	WorkflowCompound = "compound"
+	// WorkflowPlan is the workflow of a request whose steps the
+	// application runs one at a time.
+	WorkflowPlan = "plan"
+)
+
+// ExportStep is the agent of a step saving the result to a file. No agent
+// takes it: the front-end saves the last query result after the turn.
+const ExportStep = "Export"
+
+// Statuses of the steps of a plan being carried out. Steps that have not
+// run have none.
+const (
+	StepDone    = "done"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// Step is a step of the plan of a compound request.
+type Step struct {
+	Intent string `json:"intent"`
+	// Agent is the agent carrying out the step, or ExportStep
+	Agent string `json:"agent"`
+	// Task is the part of the request the step covers
+	Task string `json:"task"`
+	// Status is StepDone, StepFailed or StepSkipped once the step has run
+	Status string `json:"status,omitempty"`
+	// Error is why the step failed, or was skipped
+	Error string `json:"error,omitempty"`
+}
+
+// PlanNote tells the Manager the steps of a compound request, to carry out
+// in order, or returns "" for a request of one step.
+func PlanNote(steps []Step) string {
+	if len(steps) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("[This request has several steps. Carry them out in this order, delegating each to its agent and building on the result of the previous step:")
+	for i, s := range steps {
+		if s.Agent == ExportStep {
+			fmt.Fprintf(&b, "\n%d. %q: the application saves the last query result to a file after your answer, so only make sure that result holds the data to save.", i+1, s.Task)
+			continue
+		}
+		fmt.Fprintf(&b, "\n%d. %s: %q", i+1, s.Agent, s.Task)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// StepNote tells the Manager to carry out step i of a plan and nothing
+// else. results holds the outcome of each step before it.
+func StepNote(steps []Step, i int, results []string) string {
+	var b strings.Builder
+	s := steps[i]
+	fmt.Fprintf(&b, "[Carry out only step %d of %d of the plan for this request: %q. ", i+1, len(steps), s.Task)
+	if s.Agent == agentName {
+		b.WriteString("Answer it yourself.")
+	} else {
+		fmt.Fprintf(&b, "Delegate it to %s.", s.Agent)
+	}
+	b.WriteString(" The application runs the other steps.")
+	if i > 0 {
+		b.WriteString("\nOutcome of the earlier steps:")
+		for j, r := range results[:i] {
+			fmt.Fprintf(&b, "\n%d. %s: %s", j+1, steps[j].Agent, r)
+		}
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// RetryNote tells the Manager why the previous attempt at a step failed,
+// for the step to be carried out again.
+func RetryNote(failure string) string {
+	return fmt.Sprintf("[The previous attempt at this step failed: %s. Fix the cause and try again, another way if needed.]", failure)
+}
+
+// StepsOf returns the steps a routing plans: those of a compound request,
+// or one step of the whole query per agent used.
+func StepsOf(r *Result) []Step {
+	if len(r.Steps) > 0 {
+		return slices.Clone(r.Steps)
+	}
+	steps := make([]Step, len(r.AgentsUsed))
+	for i, agent := range r.AgentsUsed {
+		steps[i] = Step{Intent: r.ClassifiedIntent, Agent: agent, Task: r.Query}
+	}
+	return steps
+}
+
+// planAgent is an agent the planner may give a step to.
+type planAgent struct {
+	Name        string
+	Description string
+	Intent      bert.Intent
+}
+
+// planInstruction asks the model for the steps of a request. The agents
+// and, when planning again, the steps carried out so far are appended.
+const planInstruction = `You plan how a data analysis assistant carries out a user's request. Split the request into the fewest steps that carry it out in order, each done by one of these agents:
+%s
+A step can build on the results of the steps before it. Reply with JSON only: {"steps": [{"agent": "<one of the agents>", "task": "<what the step does, in the user's words>"}]}`
+
+// replanInstruction tells the model which steps were carried out before
+// one failed.
+const replanInstruction = `
+
+These steps were already carried out:
+%s
+The last one failed. Plan only the steps that still finish the request, working around the failure, for instance by fetching the data another way. Do not repeat the steps that succeeded.`
+
+// planSchema constrains the model's reply to steps of known agents.
+func planSchema(agents []string) *genai.Schema {
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"steps": {
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"agent": {Type: genai.TypeString, Enum: agents},
+						"task":  {Type: genai.TypeString, Description: "What the step does"},
+					},
+					Required:         []string{"agent", "task"},
+					PropertyOrdering: []string{"agent", "task"},
+				},
+			},
+		},
+		Required: []string{"steps"},
+	}
+}
+
+// Plan asks the model for the steps that carry out query, in order.
+func (a *Agent) Plan(ctx context.Context, query string) ([]Step, error) {
+	return planWithLLM(ctx, a.model, a.planAgents, query, nil)
+}
+
+// Replan asks the model for the steps that finish query after the steps
+// carried out so far, the last of which failed.
+func (a *Agent) Replan(ctx context.Context, query string, done []Step) ([]Step, error) {
+	return planWithLLM(ctx, a.model, a.planAgents, query, done)
+}
+
+// planWithLLM asks the model for a plan of query, or for the rest of one
+// after the steps done.
+func planWithLLM(ctx context.Context, llm model.LLM, agents []planAgent, query string, done []Step) ([]Step, error) {
+	var list strings.Builder
+	names := make([]string, len(agents))
+	for i, ag := range agents {
+		names[i] = ag.Name
+		fmt.Fprintf(&list, "- %s: %s\n", ag.Name, ag.Description)
+	}
+	instruction := fmt.Sprintf(planInstruction, list.String())
+	if len(done) > 0 {
+		var steps strings.Builder
+		for i, s := range done {
+			fmt.Fprintf(&steps, "%d. %s: %q", i+1, s.Agent, s.Task)
+			if s.Error != "" {
+				fmt.Fprintf(&steps, " failed: %s", s.Error)
+			}
+			steps.WriteString("\n")
+		}
+		instruction += fmt.Sprintf(replanInstruction, steps.String())
+	}
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText(query, genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(instruction, genai.RoleUser),
+			ResponseMIMEType:  "application/json",
+			ResponseSchema:    planSchema(names),
+			Temperature:       genai.Ptr[float32](0),
+		},
+	}
+	var reply strings.Builder
+	for resp, err := range llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan with the LLM: %w", err)
+		}
+		if resp == nil || resp.Content == nil {
+			continue
+		}
+		for _, p := range resp.Content.Parts {
+			if !p.Thought {
+				reply.WriteString(p.Text)
+			}
+		}
+	}
+	return parsePlan(reply.String(), agents)
+}
+
+// parsePlan reads the model's JSON plan, tolerating text or code fences
+// around it.
+func parsePlan(reply string, agents []planAgent) ([]Step, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("failed to plan with the LLM: no JSON in reply %q", reply)
+	}
+	var out struct {
+		Steps []struct {
+			Agent string `json:"agent"`
+			Task  string `json:"task"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &out); err != nil {
+		return nil, fmt.Errorf("failed to plan with the LLM: %w", err)
+	}
+	if len(out.Steps) == 0 {
+		return nil, fmt.Errorf("failed to plan with the LLM: the plan has no steps")
+	}
+	steps := make([]Step, len(out.Steps))
+	for i, s := range out.Steps {
+		j := slices.IndexFunc(agents, func(ag planAgent) bool { return strings.EqualFold(ag.Name, strings.TrimSpace(s.Agent)) })
+		if j < 0 {
+			return nil, fmt.Errorf("failed to plan with the LLM: unknown agent %q", s.Agent)
+		}
+		steps[i] = Step{Intent: string(agents[j].Intent), Agent: agents[j].Name, Task: strings.TrimSpace(s.Task)}
+	}
+	return steps, nil
+}
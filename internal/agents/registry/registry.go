@@ -0,0 +1,157 @@
+// Package registry holds the specialist agents that run alongside the SQL
+// and Chart agents. A plugin registers itself from an init function and is
+// compiled in with a blank import; the Manager then lists it among its
+// sub-agents and the classifier routes its intents to it:
+//
+//	func init() {
+//		registry.Register(registry.Spec{
+//			Name:        "ForecastAgent",
+//			Description: "Projects time series into the future",
+//			Factory:     newForecastAgent,
+//			Intents:     []registry.Intent{{Name: "forecast", Keywords: []string{"forecast", "predict"}}},
+//		})
+//	}
+//
+// A Factory only has to return an agent.Agent, so agents loaded from Go
+// plugins or run as subprocesses can be registered the same way.
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/pkg/bert"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+)
+
+// Deps are what a Factory builds its agent from.
+type Deps struct {
+	// Model is the model shared by all agents
+	Model model.LLM
+	// DB is the database client of the SQL agent, with the limits and
+	// policies applied
+	DB sqlagent.MCPClient
+	// Schema describes the database at startup
+	Schema string
+	// CurrentSchema returns the description as refreshed after schema
+	// changes since, if they are watched (optional)
+	CurrentSchema func() string
+	// Guidance is the deployment's guidance for every agent, formatted to
+	// be appended to the agent's instruction ("" if there is none)
+	Guidance string
+}
+
+// Factory creates a registered agent.
+type Factory func(Deps) (agent.Agent, error)
+
+// Intent is a classifier intent routed to a registered agent, recognized
+// by its keywords.
+type Intent struct {
+	Name     bert.Intent
+	Keywords []string
+}
+
+// Spec describes a specialist agent.
+type Spec struct {
+	// Name is the name the Manager transfers to; it must be unique
+	Name string
+	// Description tells the Manager when to use the agent
+	Description string
+	Factory     Factory
+	// Intents are routed to the agent (optional)
+	Intents []Intent
+}
+
+// Agent is a registered agent built by its Factory.
+type Agent struct {
+	agent.Agent
+	Spec Spec
+}
+
+// reserved are the names of the built-in agents.
+var reserved = map[string]bool{"ManagerAgent": true, "SQLAgent": true, "ChartAgent": true, "FilesAgent": true}
+
+// builtinIntents are handled by the built-in agents.
+var builtinIntents = map[bert.Intent]bool{bert.IntentSQLQuery: true, bert.IntentVisualization: true, bert.IntentGeneral: true, "files": true}
+
+var validName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+var (
+	mu    sync.Mutex
+	specs = map[string]Spec{}
+)
+
+// Register adds an agent to the registry. Like database/sql.Register, it
+// panics if the spec is invalid or its name or an intent is taken, since
+// registration happens at init time.
+func Register(spec Spec) {
+	mu.Lock()
+	defer mu.Unlock()
+	if err := validate(spec); err != nil {
+		panic("registry: " + err.Error())
+	}
+	specs[spec.Name] = spec
+}
+
+func validate(spec Spec) error {
+	if !validName.MatchString(spec.Name) {
+		return fmt.Errorf("invalid agent name %q", spec.Name)
+	}
+	if reserved[spec.Name] {
+		return fmt.Errorf("agent name %q is built in", spec.Name)
+	}
+	if _, ok := specs[spec.Name]; ok {
+		return fmt.Errorf("agent %q registered twice", spec.Name)
+	}
+	if spec.Description == "" || spec.Factory == nil {
+		return fmt.Errorf("agent %q needs a description and a factory", spec.Name)
+	}
+	for _, intent := range spec.Intents {
+		if intent.Name == "" || len(intent.Keywords) == 0 {
+			return fmt.Errorf("agent %q has an intent without a name or keywords", spec.Name)
+		}
+		if builtinIntents[intent.Name] {
+			return fmt.Errorf("intent %q of agent %q is built in", intent.Name, spec.Name)
+		}
+		for _, other := range specs {
+			for _, o := range other.Intents {
+				if o.Name == intent.Name {
+					return fmt.Errorf("intent %q of agent %q is routed to %s", intent.Name, spec.Name, other.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Specs returns the registered agents, sorted by name.
+func Specs() []Spec {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make([]Spec, 0, len(specs))
+	for _, s := range specs {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Build creates every registered agent.
+func Build(deps Deps) ([]Agent, error) {
+	var agents []Agent
+	for _, spec := range Specs() {
+		a, err := spec.Factory(deps)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", spec.Name, err)
+		}
+		if a.Name() != spec.Name {
+			return nil, fmt.Errorf("factory of %s created an agent named %q", spec.Name, a.Name())
+		}
+		agents = append(agents, Agent{Agent: a, Spec: spec})
+	}
+	return agents, nil
+}
@@ -0,0 +1,85 @@
+package registry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+)
+
+func namedAgent(name string) Factory {
+	return func(Deps) (agent.Agent, error) {
+		return llmagent.New(llmagent.Config{Name: name, Description: "test agent"})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer func() { specs = map[string]Spec{} }()
+	Register(Spec{Name: "ForecastAgent", Description: "Forecasts", Factory: namedAgent("ForecastAgent"),
+		Intents: []Intent{{Name: "forecast", Keywords: []string{"forecast"}}}})
+
+	tests := []struct {
+		name    string
+		spec    Spec
+		wantErr string
+	}{
+		{name: "valid", spec: Spec{Name: "AnomalyAgent", Description: "Finds outliers", Factory: namedAgent("AnomalyAgent")}},
+		{name: "invalid name", spec: Spec{Name: "my agent", Description: "x", Factory: namedAgent("x")}, wantErr: "invalid agent name"},
+		{name: "built-in name", spec: Spec{Name: "SQLAgent", Description: "x", Factory: namedAgent("SQLAgent")}, wantErr: "built in"},
+		{name: "duplicate", spec: Spec{Name: "ForecastAgent", Description: "x", Factory: namedAgent("ForecastAgent")}, wantErr: "registered twice"},
+		{name: "no factory", spec: Spec{Name: "EmptyAgent", Description: "x"}, wantErr: "needs a description and a factory"},
+		{name: "built-in intent", spec: Spec{Name: "OtherAgent", Description: "x", Factory: namedAgent("OtherAgent"),
+			Intents: []Intent{{Name: "sql_query", Keywords: []string{"sql"}}}}, wantErr: "is built in"},
+		{name: "taken intent", spec: Spec{Name: "OtherAgent", Description: "x", Factory: namedAgent("OtherAgent"),
+			Intents: []Intent{{Name: "forecast", Keywords: []string{"predict"}}}}, wantErr: "routed to ForecastAgent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg string
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						msg = r.(string)
+					}
+				}()
+				Register(tt.spec)
+			}()
+			if tt.wantErr == "" && msg != "" || !strings.Contains(msg, tt.wantErr) {
+				t.Errorf("panic = %q, want %q", msg, tt.wantErr)
+			}
+		})
+	}
+
+	names := []string{}
+	for _, s := range Specs() {
+		names = append(names, s.Name)
+	}
+	if strings.Join(names, ",") != "AnomalyAgent,ForecastAgent" {
+		t.Errorf("specs = %v", names)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	defer func() { specs = map[string]Spec{} }()
+	Register(Spec{Name: "ForecastAgent", Description: "Forecasts", Factory: namedAgent("ForecastAgent")})
+
+	agents, err := Build(Deps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 1 || agents[0].Name() != "ForecastAgent" || agents[0].Spec.Description != "Forecasts" {
+		t.Errorf("agents = %+v", agents)
+	}
+
+	Register(Spec{Name: "MisnamedAgent", Description: "x", Factory: namedAgent("Other")})
+	if _, err := Build(Deps{}); err == nil || !strings.Contains(err.Error(), `named "Other"`) {
+		t.Errorf("err = %v", err)
+	}
+	specs = map[string]Spec{}
+	Register(Spec{Name: "FailingAgent", Description: "x", Factory: func(Deps) (agent.Agent, error) { return nil, errors.New("no model") }})
+	if _, err := Build(Deps{}); err == nil || !strings.Contains(err.Error(), "failed to create FailingAgent: no model") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -4,17 +4,30 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/casts"
+	"github.com/anuvratrastogi/multi-agent/internal/colstats"
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+	"github.com/anuvratrastogi/multi-agent/internal/dictionary"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/joins"
+	"github.com/anuvratrastogi/multi-agent/internal/prompts"
+	"github.com/anuvratrastogi/multi-agent/internal/timerange"
+	"github.com/anuvratrastogi/multi-agent/internal/toolschema"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
 )
 
 const (
 	agentName    = "SQLAgent"
-	agentDesc    = "Converts natural language queries to SQL and executes them against the PostgreSQL database"
+	agentDesc    = "Converts natural language queries to SQL and executes them against the database"
 	outputKeySQL = "sql_result"
 )
 
@@ -27,54 +40,77 @@ type Agent struct {
 type Config struct {
 	Model          model.LLM
 	Tools          []tool.Tool
-	DatabaseSchema string // Optional: pre-loaded database schema for better SQL generation
+	DatabaseSchema string                       // Optional: pre-loaded database schema for better SQL generation
+	SchemaSource   func() string                // Optional: the current schema, replacing DatabaseSchema in every instruction
+	ColumnCasts    []casts.Suggestion           // Optional: casts for text columns holding numbers, dates or booleans
+	ColumnStats    []colstats.Column            // Optional: values of low-cardinality columns
+	Terms          map[string]string            // Optional: business terms and their definitions
+	WriteMode      bool                         // Optional: allow approval-gated writes via propose_write
+	Explain        bool                         // Optional: explain_query is among the tools
+	Profile        bool                         // Optional: profile_table is among the tools
+	Transform      bool                         // Optional: transform_result is among the tools
+	Stats          bool                         // Optional: detect_anomalies and describe_result are among the tools
+	Clarify        bool                         // Optional: ask_clarification is among the tools
+	GenerateConfig *genai.GenerateContentConfig // Optional: generation parameters such as temperature
+	Dialect        dialect.Dialect              // Optional: the SQL the database speaks (default: PostgreSQL)
+	Prompts        *prompts.Store               // Optional: templates of the instruction (default: built-in)
 }
 
 // New creates a new SQL agent.
 func New(cfg Config) (*Agent, error) {
-	instruction := `You are a SQL expert agent. Your job is to:
-1. Understand the user's natural language query about data
-2. Convert it to a valid PostgreSQL query
-3. Execute the query using the available database tools
-4. Return the results in a structured format
-
-Guidelines:
-- Write efficient SQL queries with appropriate WHERE clauses
-- Limit results to a reasonable number unless specifically asked for all
-- Format dates and numbers appropriately
-- If the query is ambiguous, make reasonable assumptions and explain them
-- Use the database schema provided below to write accurate queries
-- CRITICAL: Use PostgreSQL specific syntax!
-  - Use TO_CHAR(date, 'YYYY-MM') for date formatting (not DATE_FORMAT)
-  - Use 'LIMIT n' for limiting results
-  - Use double quotes "Identifier" for mixed-case table/column names if needed (but usually lowercase is fine)
-
-Visualizations:
-- If the user explicitly requested a chart/visualization (e.g., "bar chart", "plot this"):
-  1. FIRST, execute the SQL query to get the data.
-  2. RETURN the data in your response.
-  3. DO NOT worry about creating the chart yourself. The Manager will handle it.
-
-Available tools:
-- query_database: Execute SQL queries and get results
-- get_schema: Get the schema of a specific table (if you need more details)
-- list_tables: List all available tables
-- describe_database: Get an overview of the database structure`
-
-	// Add database schema to instruction if provided
-	if cfg.DatabaseSchema != "" {
-		instruction += "\n\n## Database Schema\n" + cfg.DatabaseSchema
-	}
-
-	instruction += "\n\nAlways return the query results as structured JSON data."
+	if cfg.Dialect == nil {
+		cfg.Dialect = dialect.Postgres{}
+	}
+	if cfg.Prompts == nil {
+		cfg.Prompts = prompts.Default()
+	}
+
+	data := prompts.SQLData{
+		Dialect:      cfg.Dialect.Name(),
+		Placeholders: []string{cfg.Dialect.Placeholder(1), cfg.Dialect.Placeholder(2)},
+		Hints:        cfg.Dialect.Hints(),
+		Schema:       cfg.DatabaseSchema,
+		WriteMode:    cfg.WriteMode,
+		Explain:      cfg.Explain,
+		Profile:      cfg.Profile,
+		Transform:    cfg.Transform,
+		Stats:        cfg.Stats,
+		Clarify:      cfg.Clarify,
+	}
+	// Foreign keys spare the model guessing join keys
+	data.Relationships = Relationships(cfg.DatabaseSchema)
+	// Text columns holding typed values fail to aggregate without a cast
+	if len(cfg.ColumnCasts) > 0 {
+		data.Casts = casts.Guidance(cfg.ColumnCasts)
+	}
+	// Enum-like columns are filtered on values that exist
+	data.ColumnValues = colstats.Guidance(cfg.ColumnStats)
+	// Business terms map to the columns that compute them
+	data.Glossary = dictionary.Glossary(cfg.Terms)
+	if _, err := cfg.Prompts.Render(prompts.SQL, data); err != nil {
+		return nil, fmt.Errorf("failed to create SQL agent: %w", err)
+	}
+
+	instruction := cfg.Prompts.Provider(prompts.SQL, data)
+	if cfg.SchemaSource != nil {
+		// The schema may have been refreshed since the agent was created
+		instruction = func(ctx agent.ReadonlyContext) (string, error) {
+			current := data
+			current.Schema = cfg.SchemaSource()
+			current.Relationships = Relationships(current.Schema)
+			return cfg.Prompts.Provider(prompts.SQL, current)(ctx)
+		}
+	}
 
 	llmAgent, err := llmagent.New(llmagent.Config{
-		Name:        agentName,
-		Description: agentDesc,
-		Instruction: instruction,
-		Model:       cfg.Model,
-		Tools:       cfg.Tools,
-		OutputKey:   outputKeySQL,
+		Name:                agentName,
+		Description:         agentDesc,
+		InstructionProvider: instruction,
+		Model:               cfg.Model,
+		Tools:               cfg.Tools,
+		OutputKey:           outputKeySQL,
+
+		GenerateContentConfig: cfg.GenerateConfig,
 	})
 
 	if err != nil {
@@ -112,9 +148,20 @@ type QueryArgs struct {
 	Limit int    `json:"limit,omitempty" description:"Maximum number of rows to return (default: 100)"`
 }
 
+type QueryParamsArgs struct {
+	SQL   string        `json:"sql" description:"The SQL query to execute, with placeholders for values"`
+	Args  []interface{} `json:"args" description:"The values bound to the placeholders, in order"`
+	Limit int           `json:"limit,omitempty" description:"Maximum number of rows to return (default: 100)"`
+}
+
 type QueryResult2 struct {
-	Data  string `json:"data"`
-	Error string `json:"error,omitempty"`
+	Data   string `json:"data"`
+	Error  string `json:"error,omitempty"`
+	Notice string `json:"notice,omitempty"`
+	// Capped is set if the rows were cut to fit the result caps
+	Capped *Truncation `json:"capped,omitempty"`
+	// Chart is the chart suggested by the shape of the rows, if any
+	Chart *chart.Recommendation `json:"chart,omitempty"`
 }
 
 type SchemaArgs struct {
@@ -138,29 +185,83 @@ type DescribeResult struct {
 	Error       string `json:"error,omitempty"`
 }
 
+type JoinPathArgs struct {
+	FromTable string `json:"from_table" description:"The table to start from"`
+	ToTable   string `json:"to_table" description:"The table to join to"`
+}
+
+type JoinPathResult struct {
+	Tables     []string `json:"tables,omitempty"`
+	JoinClause string   `json:"join_clause,omitempty"`
+	Notes      []string `json:"notes,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+type ERDiagramArgs struct {
+	Tables []string `json:"tables,omitempty" description:"The tables to draw; all tables if empty"`
+}
+
+type ERDiagramResult struct {
+	Mermaid string `json:"mermaid,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type TimeRangeArgs struct {
+	Expression string `json:"expression" description:"The time expression, e.g. 'last quarter', 'past 30 days', 'YTD', 'Q3 2024', 'March 2024'"`
+	Column     string `json:"column" description:"The date or timestamp column to filter on, e.g. 'orders.created_at'"`
+}
+
+type TimeRangeResult struct {
+	Start  string `json:"start,omitempty"`
+	End    string `json:"end,omitempty"`
+	Label  string `json:"label,omitempty"`
+	Today  string `json:"today"`
+	Filter string `json:"filter,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// newTool creates a function tool whose parameter schema, descriptions
+// included, is generated from its argument struct.
+func newTool[TArgs, TResults any](cfg functiontool.Config, handler functiontool.Func[TArgs, TResults]) (tool.Tool, error) {
+	if cfg.InputSchema == nil {
+		schema, err := toolschema.For[TArgs]()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate %s schema: %w", cfg.Name, err)
+		}
+		cfg.InputSchema = schema
+	}
+	return functiontool.New(cfg, handler)
+}
+
+// ERDiagramToolName is the name of the tool drawing the database.
+const ERDiagramToolName = "er_diagram"
+
+// ERDiagram draws the tables of the database, or the given ones, and the
+// foreign keys between them as a Mermaid erDiagram block.
+func ERDiagram(ctx context.Context, mcpClient MCPClient, tables []string) (string, error) {
+	desc, err := mcpClient.DescribeDatabase(ctx)
+	if err != nil {
+		return "", err
+	}
+	keys, err := mcpClient.ForeignKeys(ctx)
+	if err != nil {
+		return "", err
+	}
+	return chart.ERDiagram(desc, keys, tables)
+}
+
 // CreateMCPTools creates the MCP tools for the SQL agent using functiontool.
 func CreateMCPTools(mcpClient MCPClient) ([]tool.Tool, error) {
 	var tools []tool.Tool
 
 	// Query database tool
-	queryTool, err := functiontool.New(
+	queryTool, err := newTool(
 		functiontool.Config{
 			Name:        "query_database",
 			Description: "Execute a SQL query and return results as JSON",
 		},
 		func(ctx tool.Context, args QueryArgs) (QueryResult2, error) {
-			fmt.Printf("  📝 [SQL] Executing query: %s\n", args.SQL)
-			limit := args.Limit
-			if limit == 0 {
-				limit = 100
-			}
-			data, err := mcpClient.Query(context.Background(), args.SQL, limit)
-			if err != nil {
-				fmt.Printf("  ❌ [SQL] Query error: %v\n", err)
-				return QueryResult2{Error: err.Error()}, nil
-			}
-			fmt.Printf("  ✅ [SQL] Query completed successfully\n")
-			return QueryResult2{Data: data}, nil
+			return runQuery(ctx, mcpClient, args.SQL, nil, args.Limit), nil
 		},
 	)
 	if err != nil {
@@ -168,15 +269,32 @@ func CreateMCPTools(mcpClient MCPClient) ([]tool.Tool, error) {
 	}
 	tools = append(tools, queryTool)
 
+	// Parameterized query tool
+	paramsTool, err := newTool(
+		functiontool.Config{
+			Name:        QueryParamsToolName,
+			Description: "Execute a SQL query whose placeholders are bound to the values in args, and return results as JSON",
+		},
+		func(ctx tool.Context, args QueryParamsArgs) (QueryResult2, error) {
+			if args.Args == nil {
+				args.Args = []interface{}{}
+			}
+			return runQuery(ctx, mcpClient, args.SQL, args.Args, args.Limit), nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", QueryParamsToolName, err)
+	}
+	tools = append(tools, paramsTool)
+
 	// Get schema tool
-	schemaTool, err := functiontool.New(
+	schemaTool, err := newTool(
 		functiontool.Config{
 			Name:        "get_schema",
 			Description: "Get the schema of a specific table",
 		},
 		func(ctx tool.Context, args SchemaArgs) (SchemaResult, error) {
-			fmt.Printf("  📋 [TOOL] get_schema: %s\n", args.TableName)
-			schema, err := mcpClient.GetSchema(context.Background(), args.TableName)
+			schema, err := mcpClient.GetSchema(toolSession(ctx), args.TableName)
 			if err != nil {
 				return SchemaResult{Error: err.Error()}, nil
 			}
@@ -189,14 +307,13 @@ func CreateMCPTools(mcpClient MCPClient) ([]tool.Tool, error) {
 	tools = append(tools, schemaTool)
 
 	// List tables tool
-	listTablesTool, err := functiontool.New(
+	listTablesTool, err := newTool(
 		functiontool.Config{
 			Name:        "list_tables",
 			Description: "List all tables in the database",
 		},
 		func(ctx tool.Context, args EmptyArgs) (ListTablesResult, error) {
-			fmt.Printf("  📋 [TOOL] list_tables\n")
-			tables, err := mcpClient.ListTables(context.Background())
+			tables, err := mcpClient.ListTables(toolSession(ctx))
 			if err != nil {
 				return ListTablesResult{Error: err.Error()}, nil
 			}
@@ -209,14 +326,13 @@ func CreateMCPTools(mcpClient MCPClient) ([]tool.Tool, error) {
 	tools = append(tools, listTablesTool)
 
 	// Describe database tool
-	describeTool, err := functiontool.New(
+	describeTool, err := newTool(
 		functiontool.Config{
 			Name:        "describe_database",
 			Description: "Get an overview of the database structure including all tables and their columns",
 		},
 		func(ctx tool.Context, args EmptyArgs) (DescribeResult, error) {
-			fmt.Printf("  📋 [TOOL] describe_database\n")
-			desc, err := mcpClient.DescribeDatabase(context.Background())
+			desc, err := mcpClient.DescribeDatabase(toolSession(ctx))
 			if err != nil {
 				return DescribeResult{Error: err.Error()}, nil
 			}
@@ -228,13 +344,139 @@ func CreateMCPTools(mcpClient MCPClient) ([]tool.Tool, error) {
 	}
 	tools = append(tools, describeTool)
 
+	// Find join path tool
+	joinPathTool, err := newTool(
+		functiontool.Config{
+			Name:        "find_join_path",
+			Description: "Find the shortest chain of foreign-key joins between two tables and return the FROM ... JOIN clause to use",
+		},
+		func(ctx tool.Context, args JoinPathArgs) (JoinPathResult, error) {
+			keys, err := mcpClient.ForeignKeys(toolSession(ctx))
+			if err != nil {
+				return JoinPathResult{Error: err.Error()}, nil
+			}
+			steps, err := joins.Path(keys, args.FromTable, args.ToTable)
+			if err != nil {
+				return JoinPathResult{Error: err.Error()}, nil
+			}
+			result := JoinPathResult{
+				Tables:     []string{strings.ToLower(args.FromTable)},
+				JoinClause: joins.Clause(strings.ToLower(args.FromTable), steps),
+			}
+			for _, s := range steps {
+				result.Tables = append(result.Tables, s.To)
+				for _, alt := range s.Alternatives {
+					result.Notes = append(result.Notes, fmt.Sprintf("%s and %s are also joined by %s; pick the key the question means", s.From, s.To, alt))
+				}
+			}
+			return result, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create find_join_path tool: %w", err)
+	}
+	tools = append(tools, joinPathTool)
+
+	// ER diagram tool
+	erDiagramTool, err := newTool(
+		functiontool.Config{
+			Name:        ERDiagramToolName,
+			Description: "Draw the tables, columns and foreign-key relationships of the database, or of some tables, as a Mermaid entity-relationship diagram",
+		},
+		func(ctx tool.Context, args ERDiagramArgs) (ERDiagramResult, error) {
+			diagram, err := ERDiagram(toolSession(ctx), mcpClient, args.Tables)
+			if err != nil {
+				return ERDiagramResult{Error: err.Error()}, nil
+			}
+			return ERDiagramResult{Mermaid: diagram}, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", ERDiagramToolName, err)
+	}
+	tools = append(tools, erDiagramTool)
+
+	// Resolve time range tool
+	timeRangeTool, err := newTool(
+		functiontool.Config{
+			Name:        "resolve_timerange",
+			Description: "Resolve a relative time expression into concrete dates: start (inclusive), end (exclusive) and a WHERE condition using them",
+		},
+		func(ctx tool.Context, args TimeRangeArgs) (TimeRangeResult, error) {
+			now := time.Now()
+			result := TimeRangeResult{Today: now.Format(timerange.DateLayout)}
+			column := strings.TrimSpace(args.Column)
+			if column == "" {
+				result.Error = "column is required: pass the date or timestamp column to filter on, such as orders.created_at. Call get_schema if you don't know it."
+				return result, nil
+			}
+			r, err := timerange.Resolve(args.Expression, now)
+			if err != nil {
+				result.Error = err.Error()
+				return result, nil
+			}
+			result.Start = r.Start.Format(timerange.DateLayout)
+			result.End = r.End.Format(timerange.DateLayout)
+			result.Label = r.String()
+			result.Filter = r.Filter(column)
+			return result, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resolve_timerange tool: %w", err)
+	}
+	tools = append(tools, timeRangeTool)
+
 	return tools, nil
 }
 
+// runQuery runs a read-only query for the query tools, binding args if not
+// nil, and publishes its outcome.
+func runQuery(ctx tool.Context, mcpClient MCPClient, sql string, args []interface{}, limit int) QueryResult2 {
+	if IsWriteStatement(sql) {
+		events.Publish(ctx, &events.SQLExecuted{SQL: sql, Args: args, Error: ErrReadOnly, Rejected: true})
+		return QueryResult2{Error: ErrReadOnly}
+	}
+	if IsDryRun(ctx) {
+		events.Publish(ctx, &events.SQLExecuted{SQL: sql, Args: args, DryRun: true})
+		return QueryResult2{Notice: DryRunNotice}
+	}
+	if limit == 0 {
+		limit = 100
+	}
+	queryCtx := toolSession(ctx)
+	if args != nil {
+		queryCtx = WithArgs(queryCtx, args)
+	}
+	var truncated *Truncation
+	queryCtx = WithTruncation(queryCtx, &truncated)
+	start := time.Now()
+	data, err := mcpClient.Query(queryCtx, sql, limit)
+	executed := &events.SQLExecuted{SQL: sql, Args: args, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		executed.Error = err.Error()
+		events.Publish(ctx, executed)
+		return QueryResult2{Error: err.Error()}
+	}
+	executed.RowCount = ResultRows(queryCtx, data)
+	events.Publish(ctx, executed)
+	result := QueryResult2{Data: data, Capped: truncated}
+	if s := SpillFrom(queryCtx); s != nil {
+		var shown []json.RawMessage
+		json.Unmarshal([]byte(data), &shown)
+		result.Notice = fmt.Sprintf("Showing the first %d of %d rows; the result is too large to return in full.", len(shown), s.Len())
+	}
+	if rec, ok := chart.RecommendJSON(data); ok {
+		result.Chart = &rec
+	}
+	return result
+}
+
 // MCPClient interface for database operations.
 type MCPClient interface {
 	Query(ctx context.Context, query string, limit int) (string, error)
 	GetSchema(ctx context.Context, tableName string) (string, error)
 	ListTables(ctx context.Context) (string, error)
 	DescribeDatabase(ctx context.Context) (string, error)
+	ForeignKeys(ctx context.Context) ([]joins.ForeignKey, error)
 }
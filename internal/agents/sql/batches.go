@@ -0,0 +1,348 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/anuvratrastogi/multi-agent/internal/dataset"
+	"google.golang.org/adk/tool"
+)
+
+// BatchSize is the number of rows reported per RowBatch.
+const BatchSize = 200
+
+// SpillRows and SpillBytes bound the rows of a spilled result encoded in the
+// JSON its query returns; the others are read from the Spill.
+const (
+	SpillRows  = 100
+	SpillBytes = 1 << 20
+)
+
+// RowBatch is a group of rows read while a query is still running.
+type RowBatch struct {
+	SQL     string
+	Columns []string
+	Rows    []map[string]interface{}
+	// Total is the number of rows read so far, including this batch.
+	Total int
+}
+
+// RowHandler receives row batches as a query progresses.
+type RowHandler func(RowBatch)
+
+type rowHandlerKey struct{}
+
+// WithRowHandler returns a context in which queries report their rows to h in
+// batches as they are read, before the complete result is returned.
+func WithRowHandler(ctx context.Context, h RowHandler) context.Context {
+	return context.WithValue(ctx, rowHandlerKey{}, h)
+}
+
+// RowHandlerFrom returns the handler set with WithRowHandler, or nil.
+func RowHandlerFrom(ctx context.Context) RowHandler {
+	h, _ := ctx.Value(rowHandlerKey{}).(RowHandler)
+	return h
+}
+
+// Spill is the complete result of a query whose rows spilled to disk, handed
+// over to a SpillCapture instead of being encoded whole. It is shared by
+// reference counting and safe for concurrent use.
+type Spill struct {
+	data  *dataset.Dataset
+	refs  atomic.Int32
+	limit int
+	edits []func(row map[string]interface{})
+
+	mu sync.Mutex
+}
+
+// errStop ends Each early.
+var errStop = errors.New("stop")
+
+// Len returns the number of rows Each reads.
+func (s *Spill) Len() int {
+	if s.limit >= 0 {
+		return min(s.data.Len(), s.limit)
+	}
+	return s.data.Len()
+}
+
+// Columns returns the columns of the query, before any edit.
+func (s *Spill) Columns() []string {
+	return s.data.Columns()
+}
+
+// Truncate limits the rows Each reads to the first n.
+func (s *Spill) Truncate(n int) {
+	if s.limit < 0 || n < s.limit {
+		s.limit = n
+	}
+}
+
+// Edit makes Each apply edit to every row, after the edits added before, as
+// the clients wrapping the database do to the JSON of the result. Keys edit
+// deletes are left out; keys it adds are ignored.
+func (s *Spill) Edit(edit func(row map[string]interface{})) {
+	s.edits = append(s.edits, edit)
+}
+
+// Each calls fn with every row, encoded as a JSON object with keys in
+// column order, until fn returns an error. The row is reused between calls.
+func (s *Spill) Each(fn func(row json.RawMessage) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	columns := s.data.Columns()
+	keys := make([][]byte, len(columns))
+	for i, c := range columns {
+		k, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("json error: %w", err)
+		}
+		keys[i] = append(k, ':')
+	}
+
+	var buf bytes.Buffer
+	n := 0
+	err := s.data.Each(func(values []interface{}) error {
+		if s.limit >= 0 && n == s.limit {
+			return errStop
+		}
+		n++
+
+		var edited map[string]interface{}
+		if len(s.edits) > 0 {
+			edited = make(map[string]interface{}, len(columns))
+			for i, c := range columns {
+				edited[c] = values[i]
+			}
+			for _, edit := range s.edits {
+				edit(edited)
+			}
+		}
+
+		buf.Reset()
+		buf.WriteByte('{')
+		first := true
+		for i, v := range values {
+			if edited != nil {
+				var ok bool
+				if v, ok = edited[columns[i]]; !ok {
+					continue
+				}
+			}
+			val, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("json error: %w", err)
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			buf.Write(keys[i])
+			buf.Write(val)
+		}
+		buf.WriteByte('}')
+		return fn(buf.Bytes())
+	})
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+// head encodes the leading rows that fit in SpillRows and SpillBytes as a
+// JSON array, with at least one row.
+func (s *Spill) head() (string, error) {
+	var out strings.Builder
+	out.WriteByte('[')
+	n := 0
+	err := s.Each(func(row json.RawMessage) error {
+		if n == SpillRows || (n > 0 && out.Len()+1+len(row)+1 > SpillBytes) {
+			return errStop
+		}
+		if n > 0 {
+			out.WriteByte(',')
+		}
+		out.Write(row)
+		n++
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return "", err
+	}
+	out.WriteByte(']')
+	return out.String(), nil
+}
+
+// Retain adds a reference to s, for a store that keeps it.
+func (s *Spill) Retain() *Spill {
+	s.refs.Add(1)
+	return s
+}
+
+// Close drops a reference to s, removing the rows with the last one.
+func (s *Spill) Close() error {
+	if s.refs.Add(-1) > 0 {
+		return nil
+	}
+	return s.data.Close()
+}
+
+type spillKey struct{}
+
+// SpillCapture receives the spilled result of a query tool call.
+type SpillCapture struct {
+	spill *Spill
+	// outer is the capture this one shares, if it was made inside another
+	outer *SpillCapture
+}
+
+// spillContext is a tool context carrying a SpillCapture.
+type spillContext struct {
+	tool.Context
+	capture *SpillCapture
+}
+
+func (c spillContext) Value(key any) any {
+	if key == (spillKey{}) {
+		return c.capture
+	}
+	return c.Context.Value(key)
+}
+
+// CaptureSpill returns a tool context in which a query whose rows spill to
+// disk hands its complete result to the returned capture, and returns only
+// its leading rows as JSON. Middleware around the same call share the result.
+func CaptureSpill(ctx tool.Context) (tool.Context, *SpillCapture) {
+	if outer, ok := ctx.Value(spillKey{}).(*SpillCapture); ok {
+		return ctx, &SpillCapture{outer: outer}
+	}
+	c := &SpillCapture{}
+	return spillContext{Context: ctx, capture: c}, c
+}
+
+// Spill returns the spilled result of the call, or nil if it did not spill.
+func (c *SpillCapture) Spill() *Spill {
+	if c.outer != nil {
+		return c.outer.Spill()
+	}
+	return c.spill
+}
+
+// Close drops the call's reference to the spilled result, so it is removed
+// unless a store retained it. It does nothing for a shared capture.
+func (c *SpillCapture) Close() error {
+	if c.outer != nil || c.spill == nil {
+		return nil
+	}
+	s := c.spill
+	c.spill = nil
+	return s.Close()
+}
+
+// SpillFrom returns the spilled result of the query made with ctx, or nil.
+// Clients wrapping the database apply to it what they do to the JSON.
+func SpillFrom(ctx context.Context) *Spill {
+	c, _ := ctx.Value(spillKey{}).(*SpillCapture)
+	if c == nil {
+		return nil
+	}
+	return c.spill
+}
+
+// ResultRows returns the number of rows of the result data of the query made
+// with ctx, counting the spilled ones.
+func ResultRows(ctx context.Context, data string) int {
+	if s := SpillFrom(ctx); s != nil {
+		return s.Len()
+	}
+	var rows []json.RawMessage
+	if json.Unmarshal([]byte(data), &rows) != nil {
+		return 0
+	}
+	return len(rows)
+}
+
+// Results collects the rows of a query into its JSON result, within the
+// memory budget of a dataset, and reports them in batches to the context's
+// RowHandler. Database clients share it so every driver streams and spills
+// rows the same way.
+type Results struct {
+	sql     string
+	columns []string
+	data    *dataset.Dataset
+	onBatch RowHandler
+	batch   []map[string]interface{}
+	capture *SpillCapture
+}
+
+// NewResults starts collecting the rows of sql.
+func NewResults(ctx context.Context, sql string, columns []string, cfg dataset.Config) *Results {
+	capture, _ := ctx.Value(spillKey{}).(*SpillCapture)
+	return &Results{sql: sql, columns: columns, data: dataset.New(columns, cfg), onBatch: RowHandlerFrom(ctx), capture: capture}
+}
+
+// Append adds a row, with values in column order.
+func (r *Results) Append(values []interface{}) error {
+	if err := r.data.Append(values); err != nil {
+		return err
+	}
+	if r.onBatch == nil {
+		return nil
+	}
+	row := make(map[string]interface{}, len(r.columns))
+	for i, col := range r.columns {
+		row[col] = values[i]
+	}
+	r.batch = append(r.batch, row)
+	if len(r.batch) == BatchSize {
+		r.flush()
+	}
+	return nil
+}
+
+func (r *Results) flush() {
+	if len(r.batch) > 0 {
+		r.onBatch(RowBatch{SQL: r.sql, Columns: r.columns, Rows: r.batch, Total: r.data.Len()})
+		r.batch = nil
+	}
+}
+
+// JSON reports the last batch and returns the rows as a JSON array of
+// objects. Rows that spilled to disk are handed to the context's
+// SpillCapture, if it has one, and only the leading ones are returned.
+func (r *Results) JSON() (string, error) {
+	if r.onBatch != nil {
+		r.flush()
+	}
+	if r.capture != nil && r.data.Spilled() {
+		s := &Spill{data: r.data, limit: -1}
+		s.refs.Store(1)
+		r.data = nil
+		if r.capture.spill != nil {
+			r.capture.spill.Close()
+		}
+		r.capture.spill = s
+		return s.head()
+	}
+	var out strings.Builder
+	if err := r.data.WriteJSON(&out); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Close releases the rows, unless they were handed to a SpillCapture.
+func (r *Results) Close() error {
+	if r.data == nil {
+		return nil
+	}
+	return r.data.Close()
+}
@@ -0,0 +1,89 @@
+package sql_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/dataset"
+	"google.golang.org/adk/tool"
+)
+
+// toolContext is the part of a tool.Context the results use.
+type toolContext struct {
+	tool.Context
+}
+
+func (c toolContext) Value(key any) any { return context.Background().Value(key) }
+
+// collect returns n rows of id and name in a dataset that spills after the
+// first, as the database clients do.
+func collect(t *testing.T, ctx context.Context, n int) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	results := sqlagent.NewResults(ctx, "SELECT id, name FROM users", []string{"id", "name"},
+		dataset.Config{Budget: dataset.NewBudget(40), Dir: dir})
+	defer results.Close()
+	for i := range n {
+		if err := results.Append([]interface{}{int64(i), fmt.Sprintf("user%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := results.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, dir
+}
+
+func TestResultsSpill(t *testing.T) {
+	data, _ := collect(t, context.Background(), 250)
+	var rows []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &rows); err != nil || len(rows) != 250 {
+		t.Fatalf("JSON() without a capture = %d rows, %v; want all 250", len(rows), err)
+	}
+
+	ctx, capture := sqlagent.CaptureSpill(toolContext{})
+	data, dir := collect(t, ctx, 250)
+	if err := json.Unmarshal([]byte(data), &rows); err != nil || len(rows) != sqlagent.SpillRows {
+		t.Fatalf("JSON() with a capture = %d rows, %v; want the first %d", len(rows), err, sqlagent.SpillRows)
+	}
+	spill := capture.Spill()
+	if spill == nil || spill.Len() != 250 || sqlagent.ResultRows(ctx, data) != 250 {
+		t.Fatalf("Spill() = %v, want the 250 rows", spill)
+	}
+
+	_, shared := sqlagent.CaptureSpill(ctx)
+	if shared.Spill() != spill {
+		t.Error("a capture inside another does not share its result")
+	}
+	shared.Close()
+
+	spill.Truncate(3)
+	spill.Edit(func(row map[string]interface{}) { row["name"] = "[REDACTED]" })
+	var got []string
+	if err := spill.Each(func(row json.RawMessage) error {
+		got = append(got, string(row))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":0,"name":"[REDACTED]"} {"id":1,"name":"[REDACTED]"} {"id":2,"name":"[REDACTED]"}`
+	if strings.Join(got, " ") != want {
+		t.Errorf("Each() = %s, want %s", strings.Join(got, " "), want)
+	}
+
+	spill.Retain()
+	capture.Close()
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Errorf("%d spill files while a store retains the result, want 1", len(files))
+	}
+	spill.Close()
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d spill files once released, want 0", len(files))
+	}
+}
@@ -0,0 +1,91 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/anuvratrastogi/multi-agent/internal/casts"
+	"github.com/lib/pq"
+)
+
+// InferColumnTypes samples up to sampleSize values of every text column in
+// the schemas of the search path and returns the casts suggested for columns holding
+// numbers, dates, timestamps or booleans. GetSchema reports the suggestions
+// from then on. Call it before the client is shared.
+func (c *DirectMCPClient) InferColumnTypes(ctx context.Context, sampleSize int) ([]casts.Suggestion, error) {
+	query := `
+		SELECT table_schema, table_name, column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = ANY($1)
+			AND data_type IN ('text', 'character varying', 'character')
+		ORDER BY table_schema, table_name, ordinal_position
+	`
+
+	d := c.postgres()
+	rows, err := c.db.QueryContext(ctx, query, pq.Array(d.SearchPath()))
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	var columns []casts.Suggestion
+	var schemas []string
+	for rows.Next() {
+		var s casts.Suggestion
+		var schema string
+		if err := rows.Scan(&schema, &s.Table, &s.Column, &s.DataType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		columns = append(columns, s)
+		schemas = append(schemas, schema)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+
+	var suggestions []casts.Suggestion
+	c.casts = make(map[string]map[string]casts.Suggestion)
+	for i, s := range columns {
+		values, err := c.sampleColumn(ctx, schemas[i], s.Table, s.Column, sampleSize)
+		if err != nil {
+			// Columns the role cannot read are left to the agent
+			continue
+		}
+		s.Table = d.TableName(schemas[i], s.Table)
+		kind, cast, ok := casts.Infer(pq.QuoteIdentifier(s.Column), values)
+		if !ok {
+			continue
+		}
+		s.Kind, s.Cast, s.Sampled = kind, cast, len(values)
+		suggestions = append(suggestions, s)
+
+		if c.casts[s.Table] == nil {
+			c.casts[s.Table] = make(map[string]casts.Suggestion)
+		}
+		c.casts[s.Table][s.Column] = s
+	}
+	return suggestions, nil
+}
+
+// sampleColumn returns up to n non-empty values of a column.
+func (c *DirectMCPClient) sampleColumn(ctx context.Context, schema, table, column string, n int) ([]string, error) {
+	col := pq.QuoteIdentifier(column)
+	query := fmt.Sprintf("SELECT %s FROM %s.%s WHERE trim(%s) <> '' LIMIT %d", col, pq.QuoteIdentifier(schema), pq.QuoteIdentifier(table), col, n)
+
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v sql.NullString
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		values = append(values, v.String)
+	}
+	return values, rows.Err()
+}
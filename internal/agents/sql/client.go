@@ -3,16 +3,25 @@ package sql
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/anuvratrastogi/multi-agent/internal/casts"
+	"github.com/anuvratrastogi/multi-agent/internal/dataset"
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+	"github.com/lib/pq"
 )
 
 // DirectMCPClient is a direct database client implementing MCPClient interface.
 type DirectMCPClient struct {
-	db *sql.DB
+	// Introspector reads tables, columns and foreign keys through the
+	// PostgreSQL dialect
+	dialect.Introspector
+	db      *sql.DB
+	results dataset.Config
+	// casts holds the casts suggested by InferColumnTypes by table and column
+	casts map[string]map[string]casts.Suggestion
 }
 
 // NewDirectMCPClient creates a new direct MCP client.
@@ -26,20 +35,56 @@ func NewDirectMCPClient(databaseURL string) (*DirectMCPClient, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DirectMCPClient{db: db}, nil
+	c := &DirectMCPClient{db: db}
+	c.Introspector = dialect.Introspector{Dialect: dialect.Postgres{}, Query: dialect.SQLQuery(db), Annotate: c.annotate}
+	return c, nil
 }
 
-// Query executes a SQL query and returns results as JSON.
+// SetSearchPath sets the schemas whose tables the agents see, in order.
+// Tables outside the public schema are named schema.table. By default only
+// the public schema is introspected. Call it before the client is shared.
+func (c *DirectMCPClient) SetSearchPath(schemas []string) {
+	c.Dialect = dialect.Postgres{Schemas: schemas}
+}
+
+// postgres returns the client's dialect.
+func (c *DirectMCPClient) postgres() dialect.Postgres {
+	d, _ := c.Dialect.(dialect.Postgres)
+	return d
+}
+
+// SetResultStorage sets the memory budget and spill directory used while
+// reading query results. By default results are held entirely in memory.
+func (c *DirectMCPClient) SetResultStorage(cfg dataset.Config) {
+	c.results = cfg
+}
+
+// Query executes a SQL query and returns results as JSON. Statements other
+// than approved writes run in a read-only transaction.
 func (c *DirectMCPClient) Query(ctx context.Context, query string, limit int) (string, error) {
 	// Add LIMIT if not present and it's a SELECT query
-	queryUpper := strings.ToUpper(strings.TrimSpace(query))
-	if strings.HasPrefix(queryUpper, "SELECT") && !strings.Contains(queryUpper, "LIMIT") {
-		query = fmt.Sprintf("%s LIMIT %d", query, limit)
+	query = c.Dialect.Limit(query, limit)
+
+	var db interface {
+		QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	} = c.db
+	if !IsApprovedWrite(ctx) {
+		tx, err := c.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return "", fmt.Errorf("failed to begin read-only transaction: %w", err)
+		}
+		defer tx.Rollback()
+		// Session-level advisory locks are allowed read-only and outlive
+		// the transaction, holding the pooled connection's locks
+		if strings.Contains(strings.ToLower(query), "advisory") {
+			defer tx.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock_all()")
+		}
+		db = tx
 	}
 
-	rows, err := c.db.QueryContext(ctx, query)
+	rows, err := db.QueryContext(ctx, query, ArgsFrom(ctx)...)
 	if err != nil {
-		return "", fmt.Errorf("query error: %w", err)
+		return "", queryError(err)
 	}
 	defer rows.Close()
 
@@ -48,7 +93,10 @@ func (c *DirectMCPClient) Query(ctx context.Context, query string, limit int) (s
 		return "", fmt.Errorf("failed to get columns: %w", err)
 	}
 
-	var results []map[string]interface{}
+	// Rows are buffered column by column and spill to disk past the budget
+	result := NewResults(ctx, query, columns, c.results)
+	defer result.Close()
+
 	for rows.Next() {
 		values := make([]interface{}, len(columns))
 		valuePtrs := make([]interface{}, len(columns))
@@ -60,142 +108,39 @@ func (c *DirectMCPClient) Query(ctx context.Context, query string, limit int) (s
 			return "", fmt.Errorf("scan error: %w", err)
 		}
 
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			// Convert []byte to string for JSON serialization
+		// Convert []byte to string for JSON serialization
+		for i, val := range values {
 			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
-			} else {
-				row[col] = val
+				values[i] = string(b)
 			}
 		}
-		results = append(results, row)
-	}
-
-	jsonResult, err := json.Marshal(results)
-	if err != nil {
-		return "", fmt.Errorf("json error: %w", err)
-	}
-
-	return string(jsonResult), nil
-}
-
-// GetSchema returns the schema of a table as JSON.
-func (c *DirectMCPClient) GetSchema(ctx context.Context, tableName string) (string, error) {
-	query := `
-		SELECT column_name, data_type, is_nullable, column_default
-		FROM information_schema.columns
-		WHERE table_name = $1
-		ORDER BY ordinal_position
-	`
-
-	rows, err := c.db.QueryContext(ctx, query, tableName)
-	if err != nil {
-		return "", fmt.Errorf("query error: %w", err)
-	}
-	defer rows.Close()
-
-	var schema []map[string]interface{}
-	for rows.Next() {
-		var columnName, dataType, isNullable string
-		var columnDefault sql.NullString
-
-		if err := rows.Scan(&columnName, &dataType, &isNullable, &columnDefault); err != nil {
-			return "", fmt.Errorf("scan error: %w", err)
-		}
-
-		col := map[string]interface{}{
-			"column_name": columnName,
-			"data_type":   dataType,
-			"nullable":    isNullable == "YES",
-		}
-		if columnDefault.Valid {
-			col["default"] = columnDefault.String
+		if err := result.Append(values); err != nil {
+			return "", err
 		}
-		schema = append(schema, col)
 	}
-
-	jsonResult, err := json.Marshal(schema)
-	if err != nil {
-		return "", fmt.Errorf("json error: %w", err)
+	if err := rows.Err(); err != nil {
+		return "", queryError(err)
 	}
-
-	return string(jsonResult), nil
+	return result.JSON()
 }
 
-// ListTables returns a list of tables as JSON.
-func (c *DirectMCPClient) ListTables(ctx context.Context) (string, error) {
-	query := `
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = 'public' 
-		ORDER BY table_name
-	`
-
-	rows, err := c.db.QueryContext(ctx, query)
-	if err != nil {
-		return "", fmt.Errorf("query error: %w", err)
-	}
-	defer rows.Close()
-
-	var tables []string
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			return "", fmt.Errorf("scan error: %w", err)
-		}
-		tables = append(tables, tableName)
+// queryError wraps an error of Query, explaining failures of the read-only
+// transaction to the agent.
+func queryError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "25006" {
+		return fmt.Errorf("query error: %w. %s", err, ErrReadOnlyTransaction)
 	}
-
-	jsonResult, err := json.Marshal(tables)
-	if err != nil {
-		return "", fmt.Errorf("json error: %w", err)
-	}
-
-	return string(jsonResult), nil
+	return fmt.Errorf("query error: %w", err)
 }
 
-// DescribeDatabase returns database structure as JSON.
-func (c *DirectMCPClient) DescribeDatabase(ctx context.Context) (string, error) {
-	query := `
-		SELECT 
-			t.table_name,
-			array_agg(c.column_name || ' ' || c.data_type ORDER BY c.ordinal_position) as columns
-		FROM information_schema.tables t
-		JOIN information_schema.columns c ON t.table_name = c.table_name AND t.table_schema = c.table_schema
-		WHERE t.table_schema = 'public'
-		GROUP BY t.table_name
-		ORDER BY t.table_name
-	`
-
-	rows, err := c.db.QueryContext(ctx, query)
-	if err != nil {
-		return "", fmt.Errorf("query error: %w", err)
+// annotate adds the cast suggested by InferColumnTypes to a column of
+// GetSchema.
+func (c *DirectMCPClient) annotate(table string, col map[string]interface{}) {
+	if s, ok := c.casts[table][col["column_name"].(string)]; ok {
+		col["inferred_type"] = s.Kind
+		col["suggested_cast"] = s.Cast
 	}
-	defer rows.Close()
-
-	var tables []map[string]interface{}
-	for rows.Next() {
-		var tableName string
-		var columns []string
-
-		if err := rows.Scan(&tableName, &columns); err != nil {
-			return "", fmt.Errorf("scan error: %w", err)
-		}
-
-		tables = append(tables, map[string]interface{}{
-			"table":   tableName,
-			"columns": columns,
-		})
-	}
-
-	jsonResult, err := json.Marshal(tables)
-	if err != nil {
-		return "", fmt.Errorf("json error: %w", err)
-	}
-
-	return string(jsonResult), nil
 }
 
 // Close closes the database connection.
@@ -0,0 +1,136 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+	"github.com/lib/pq"
+)
+
+// fakeConnector opens connections that log the statements they run and,
+// like PostgreSQL, refuse nextval() in a read-only transaction.
+type fakeConnector struct {
+	log []string
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{c: c}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	c        *fakeConnector
+	readOnly bool
+}
+
+func (f *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (f *fakeConn) Close() error                        { return nil }
+func (f *fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (f *fakeConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	f.readOnly = opts.ReadOnly
+	if f.readOnly {
+		f.c.log = append(f.c.log, "BEGIN READ ONLY")
+	} else {
+		f.c.log = append(f.c.log, "BEGIN")
+	}
+	return f, nil
+}
+
+func (f *fakeConn) Commit() error {
+	f.c.log, f.readOnly = append(f.c.log, "COMMIT"), false
+	return nil
+}
+
+func (f *fakeConn) Rollback() error {
+	f.c.log, f.readOnly = append(f.c.log, "ROLLBACK"), false
+	return nil
+}
+
+func (f *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	f.c.log = append(f.c.log, query)
+	if f.readOnly && strings.Contains(query, "nextval") {
+		return nil, &pq.Error{Code: "25006", Message: "cannot execute nextval() in a read-only transaction"}
+	}
+	return &fakeRows{}, nil
+}
+
+func (f *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	f.c.log = append(f.c.log, query)
+	return driver.RowsAffected(0), nil
+}
+
+// fakeRows is a single row with the value 1.
+type fakeRows struct {
+	done bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done, dest[0] = true, int64(1)
+	return nil
+}
+
+func TestDirectClientReadOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		query    string
+		wantErr  string
+		wantLog  []string
+		wantData string
+	}{
+		{
+			name:    "function that writes",
+			ctx:     context.Background(),
+			query:   "SELECT nextval('orders_id_seq')",
+			wantErr: ErrReadOnlyTransaction,
+			wantLog: []string{"BEGIN READ ONLY", "SELECT nextval('orders_id_seq') LIMIT 10", "ROLLBACK"},
+		},
+		{
+			name:     "approved write",
+			ctx:      WithApprovedWrite(context.Background()),
+			query:    "SELECT nextval('orders_id_seq')",
+			wantLog:  []string{"SELECT nextval('orders_id_seq') LIMIT 10"},
+			wantData: `[{"n":1}]`,
+		},
+		{
+			name:     "advisory lock",
+			ctx:      context.Background(),
+			query:    "SELECT pg_advisory_lock(1)",
+			wantLog:  []string{"BEGIN READ ONLY", "SELECT pg_advisory_lock(1) LIMIT 10", "SELECT pg_advisory_unlock_all()", "ROLLBACK"},
+			wantData: `[{"n":1}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &fakeConnector{}
+			db := sql.OpenDB(conn)
+			defer db.Close()
+			c := &DirectMCPClient{db: db}
+			c.Introspector = dialect.Introspector{Dialect: dialect.Postgres{}}
+
+			data, err := c.Query(tt.ctx, tt.query, 10)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Query() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil || data != tt.wantData {
+				t.Errorf("Query() = %s, %v; want %s", data, err, tt.wantData)
+			}
+			if !slices.Equal(conn.log, tt.wantLog) {
+				t.Errorf("statements = %q, want %q", conn.log, tt.wantLog)
+			}
+		})
+	}
+}
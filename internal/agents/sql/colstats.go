@@ -0,0 +1,68 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anuvratrastogi/multi-agent/internal/colstats"
+	"github.com/lib/pq"
+)
+
+// ColumnStats returns the columns of the schemas of the search path that
+// hold at most maxValues distinct values, with their most common values,
+// as estimated by the planner statistics in pg_stats. It reads no table
+// data, so only tables ANALYZE (or autovacuum) has visited are covered,
+// and only columns the role may read.
+func (c *DirectMCPClient) ColumnStats(ctx context.Context, maxValues int) ([]colstats.Column, error) {
+	// Inherited statistics cover a parent and its partitions and come first
+	query := `
+		SELECT s.schemaname, s.tablename, s.attname, s.n_distinct, greatest(cl.reltuples, 0),
+			s.most_common_vals::text::text[]
+		FROM pg_stats s
+		JOIN pg_namespace n ON n.nspname = s.schemaname
+		JOIN pg_class cl ON cl.relnamespace = n.oid AND cl.relname = s.tablename
+		JOIN pg_attribute a ON a.attrelid = cl.oid AND a.attname = s.attname
+		WHERE s.schemaname = ANY($1)
+			AND s.most_common_vals IS NOT NULL
+			AND a.atttypid <> 'boolean'::regtype
+		ORDER BY s.schemaname, s.tablename, a.attnum, s.inherited DESC
+	`
+
+	d := c.postgres()
+	rows, err := c.db.QueryContext(ctx, query, pq.Array(d.SearchPath()))
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []colstats.Column
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var schema, table, column string
+		var nDistinct, reltuples float64
+		var values pq.StringArray
+		if err := rows.Scan(&schema, &table, &column, &nDistinct, &reltuples, &values); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		key := schema + "." + table + "." + column
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		distinct := max(colstats.Distinct(nDistinct, reltuples), len(values))
+		if !colstats.Listable(distinct, values, maxValues) {
+			continue
+		}
+		columns = append(columns, colstats.Column{
+			Table:    d.TableName(schema, table),
+			Column:   column,
+			Distinct: distinct,
+			Values:   values,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	return columns, nil
+}
@@ -0,0 +1,44 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anuvratrastogi/multi-agent/internal/dictionary"
+	"github.com/lib/pq"
+)
+
+// Comments returns the descriptions given to the tables, views and columns
+// of the schemas of the search path with COMMENT ON.
+func (c *DirectMCPClient) Comments(ctx context.Context) (*dictionary.Dictionary, error) {
+	query := `
+		SELECT n.nspname, cl.relname, coalesce(a.attname, ''), d.description
+		FROM pg_description d
+		JOIN pg_class cl ON cl.oid = d.objoid AND d.classoid = 'pg_class'::regclass
+		JOIN pg_namespace n ON n.oid = cl.relnamespace
+		LEFT JOIN pg_attribute a ON a.attrelid = cl.oid AND a.attnum = d.objsubid AND d.objsubid > 0
+		WHERE n.nspname = ANY($1)
+			AND cl.relkind IN ('r', 'p', 'v', 'm', 'f')
+			AND (d.objsubid = 0 OR a.attname IS NOT NULL)
+	`
+
+	d := c.postgres()
+	rows, err := c.db.QueryContext(ctx, query, pq.Array(d.SearchPath()))
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	defer rows.Close()
+
+	dict := &dictionary.Dictionary{}
+	for rows.Next() {
+		var schema, table, column, description string
+		if err := rows.Scan(&schema, &table, &column, &description); err != nil {
+			return nil, fmt.Errorf("scan error: %w", err)
+		}
+		dict.Describe(d.TableName(schema, table), column, description)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+	return dict, nil
+}
@@ -0,0 +1,20 @@
+package sql
+
+import "context"
+
+type dryRunKey struct{}
+
+// DryRunNotice is returned to the agent instead of query results in dry-run mode.
+const DryRunNotice = "Dry run: the query was NOT executed. Do not retry it. Reply with the SQL you generated and a short explanation of what it does."
+
+// WithDryRun returns a context in which query_database only records the
+// generated SQL instead of executing it.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx was created with WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
@@ -0,0 +1,95 @@
+package sql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ExplainToolName is the name of the tool returning query plans.
+const ExplainToolName = "explain_query"
+
+type ExplainArgs struct {
+	SQL string `json:"sql" description:"The SQL query to explain"`
+}
+
+type ExplainResult struct {
+	Plan    string `json:"plan,omitempty"`
+	Mermaid string `json:"mermaid,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CreateExplainTools creates the explain_query tool, which returns the plan
+// of a query in JSON together with a Mermaid flowchart of it. It returns no
+// tools if the dialect cannot explain queries.
+func CreateExplainTools(mcpClient MCPClient, d dialect.Dialect) ([]tool.Tool, error) {
+	if d == nil {
+		d = dialect.Postgres{}
+	}
+	if _, ok := d.Explain("SELECT 1"); !ok {
+		return nil, nil
+	}
+
+	explainTool, err := newTool(
+		functiontool.Config{
+			Name:        ExplainToolName,
+			Description: "Get the execution plan of a SQL query, without running it, as JSON and as a Mermaid flowchart",
+		},
+		func(ctx tool.Context, args ExplainArgs) (ExplainResult, error) {
+			if IsWriteStatement(args.SQL) {
+				return ExplainResult{Error: ErrReadOnly}, nil
+			}
+			query, _ := d.Explain(args.SQL)
+			data, err := mcpClient.Query(toolSession(ctx), query, 1000)
+			if err != nil {
+				return ExplainResult{Error: err.Error()}, nil
+			}
+			plan, err := planJSON(data)
+			if err != nil {
+				return ExplainResult{Error: err.Error()}, nil
+			}
+			mermaid, err := chart.PlanFlowchart(plan)
+			if err != nil {
+				return ExplainResult{Plan: plan, Error: err.Error()}, nil
+			}
+			return ExplainResult{Plan: plan, Mermaid: mermaid}, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", ExplainToolName, err)
+	}
+	return []tool.Tool{explainTool}, nil
+}
+
+// planJSON returns the plan held in the rows of an EXPLAIN result: the
+// values that are JSON documents, such as PostgreSQL's "QUERY PLAN" column,
+// joined in row order.
+func planJSON(data string) (string, error) {
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &rows); err != nil {
+		return "", fmt.Errorf("failed to read EXPLAIN output: %w", err)
+	}
+	var plan strings.Builder
+	for _, row := range rows {
+		for _, v := range row {
+			switch v := v.(type) {
+			case string:
+				if s := strings.TrimSpace(v); strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") {
+					plan.WriteString(s)
+				}
+			case []interface{}, map[string]interface{}:
+				b, _ := json.Marshal(v)
+				plan.Write(b)
+			}
+		}
+	}
+	if plan.Len() == 0 {
+		return "", fmt.Errorf("EXPLAIN returned no plan")
+	}
+	return plan.String(), nil
+}
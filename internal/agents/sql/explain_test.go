@@ -0,0 +1,29 @@
+package sql
+
+import "testing"
+
+func TestPlanJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "postgres", data: `[{"QUERY PLAN":"[{\"Plan\": {\"Node Type\": \"Seq Scan\"}}]"}]`, want: `[{"Plan": {"Node Type": "Seq Scan"}}]`},
+		{name: "duckdb", data: `[{"explain_key":"physical_plan","explain_value":"[{\"name\": \"SEQ_SCAN\"}]"}]`, want: `[{"name": "SEQ_SCAN"}]`},
+		{name: "decoded json", data: `[{"QUERY PLAN":[{"Plan":{"Node Type":"Result"}}]}]`, want: `[{"Plan":{"Node Type":"Result"}}]`},
+		{name: "text plan", data: `[{"QUERY PLAN":"Seq Scan on orders"}]`, wantErr: true},
+		{name: "not rows", data: `"oops"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := planJSON(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package sql_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+)
+
+// TestReadOnlyTransaction runs statements that write without a write
+// command against a seeded PostgreSQL database.
+func TestReadOnlyTransaction(t *testing.T) {
+	url := testutil.StartPostgres(t)
+	testutil.Seed(t, url)
+
+	db, err := sqlagent.NewDirectMCPClient(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if _, err := db.Query(sqlagent.WithApprovedWrite(ctx), "CREATE SEQUENCE order_numbers", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Query(ctx, "SELECT count(*) FROM orders", 10); err != nil {
+		t.Errorf("read: error = %v", err)
+	}
+	for _, query := range []string{
+		"SELECT nextval('order_numbers')",
+		"SELECT * FROM orders FOR UPDATE",
+	} {
+		if _, err := db.Query(ctx, query, 10); err == nil || !strings.Contains(err.Error(), sqlagent.ErrReadOnlyTransaction) {
+			t.Errorf("Query(%q) error = %v, want the read-only transaction to refuse it", query, err)
+		}
+	}
+	if _, err := db.Query(sqlagent.WithApprovedWrite(ctx), "SELECT nextval('order_numbers')", 10); err != nil {
+		t.Errorf("approved nextval: error = %v", err)
+	}
+}
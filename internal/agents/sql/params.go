@@ -0,0 +1,22 @@
+package sql
+
+import "context"
+
+// QueryParamsToolName is the name of the query tool that binds an args
+// array to the placeholders of its SQL.
+const QueryParamsToolName = "query_database_params"
+
+type argsKey struct{}
+
+// WithArgs returns a context in which MCPClient.Query binds args to the
+// placeholders of the query, in order. Wrapping clients pass the context
+// through, so access checks, redaction and limits apply unchanged.
+func WithArgs(ctx context.Context, args []interface{}) context.Context {
+	return context.WithValue(ctx, argsKey{}, args)
+}
+
+// ArgsFrom returns the bind parameters set with WithArgs, or nil.
+func ArgsFrom(ctx context.Context) []interface{} {
+	args, _ := ctx.Value(argsKey{}).([]interface{})
+	return args
+}
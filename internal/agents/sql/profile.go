@@ -0,0 +1,235 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ProfileToolName is the name of the tool profiling the data of a table.
+const ProfileToolName = "profile_table"
+
+const (
+	// defaultTopValues is the number of most common values listed per column.
+	defaultTopValues = 5
+	// maxProfileColumns bounds the number of columns profiled in one call.
+	maxProfileColumns = 40
+)
+
+type ProfileArgs struct {
+	TableName string `json:"table_name" description:"The name of the table to profile"`
+	TopValues int    `json:"top_values,omitempty" description:"Number of most common values to list per column (default: 5)"`
+}
+
+type ProfileResult struct {
+	Table    string          `json:"table,omitempty"`
+	RowCount int64           `json:"row_count"`
+	Columns  []ColumnProfile `json:"columns,omitempty"`
+	Notes    []string        `json:"notes,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// ColumnProfile summarizes the values of a table column.
+type ColumnProfile struct {
+	Name      string  `json:"name"`
+	DataType  string  `json:"data_type"`
+	Nulls     int64   `json:"nulls"`
+	NullRatio float64 `json:"null_ratio"`
+	// Distinct, Min and Max are left out for types that cannot be compared
+	// or ordered, such as JSON and arrays
+	Distinct  *int64       `json:"distinct,omitempty"`
+	Min       interface{}  `json:"min,omitempty"`
+	Max       interface{}  `json:"max,omitempty"`
+	TopValues []ValueCount `json:"top_values,omitempty"`
+}
+
+// ValueCount is a value of a column and the number of rows holding it.
+type ValueCount struct {
+	Value interface{} `json:"value"`
+	Count int64       `json:"count"`
+}
+
+// CreateProfileTools creates the profile_table tool.
+func CreateProfileTools(mcpClient MCPClient, d dialect.Dialect) ([]tool.Tool, error) {
+	if d == nil {
+		d = dialect.Postgres{}
+	}
+
+	profileTool, err := newTool(
+		functiontool.Config{
+			Name:        ProfileToolName,
+			Description: "Profile the data of a table: row count and, per column, NULL ratio, distinct count, min/max and most common values",
+		},
+		func(ctx tool.Context, args ProfileArgs) (ProfileResult, error) {
+			result, err := profileTable(toolSession(ctx), mcpClient, d, args.TableName, args.TopValues)
+			if err != nil {
+				return ProfileResult{Table: args.TableName, Error: err.Error()}, nil
+			}
+			return result, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", ProfileToolName, err)
+	}
+	return []tool.Tool{profileTool}, nil
+}
+
+// profileTable profiles a table in one query counting the NULL and
+// distinct values of every column, then a query per column for its min and
+// max and one for its most common values. The values are returned under
+// the column's own name, so redaction policies apply to them as they do to
+// any query.
+func profileTable(ctx context.Context, mcpClient MCPClient, d dialect.Dialect, table string, top int) (ProfileResult, error) {
+	if top <= 0 {
+		top = defaultTopValues
+	}
+	result := ProfileResult{Table: table}
+
+	schema, err := mcpClient.GetSchema(ctx, table)
+	if err != nil {
+		return result, err
+	}
+	var columns []struct {
+		Name     string `json:"column_name"`
+		DataType string `json:"data_type"`
+	}
+	if err := json.Unmarshal([]byte(schema), &columns); err != nil {
+		return result, fmt.Errorf("failed to read schema of %s: %w", table, err)
+	}
+	if len(columns) == 0 {
+		return result, fmt.Errorf("table %q not found", table)
+	}
+	if len(columns) > maxProfileColumns {
+		result.Notes = append(result.Notes, fmt.Sprintf("only the first %d of %d columns were profiled", maxProfileColumns, len(columns)))
+		columns = columns[:maxProfileColumns]
+	}
+	from := quoteTable(d, table)
+
+	// Count rows, and non-NULL and distinct values per column
+	selects := []string{"COUNT(*) AS row_count"}
+	for i, c := range columns {
+		col := d.QuoteIdent(c.Name)
+		selects = append(selects, fmt.Sprintf("COUNT(%s) AS non_null_%d", col, i))
+		if distinct, _ := profileKind(c.DataType); distinct {
+			selects = append(selects, fmt.Sprintf("COUNT(DISTINCT %s) AS distinct_%d", col, i))
+		}
+	}
+	rows, err := profileQuery(ctx, mcpClient, fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), from), 1)
+	if err != nil {
+		return result, err
+	}
+	if len(rows) != 1 {
+		return result, fmt.Errorf("count query returned %d rows, want 1", len(rows))
+	}
+	counts := rows[0]
+	result.RowCount = toCount(counts["row_count"])
+
+	for i, c := range columns {
+		p := ColumnProfile{Name: c.Name, DataType: c.DataType}
+		nonNull := toCount(counts[fmt.Sprintf("non_null_%d", i)])
+		p.Nulls = result.RowCount - nonNull
+		if result.RowCount > 0 {
+			p.NullRatio = float64(p.Nulls) / float64(result.RowCount)
+		}
+		distinct, ordered := profileKind(c.DataType)
+		if distinct {
+			n := toCount(counts[fmt.Sprintf("distinct_%d", i)])
+			p.Distinct = &n
+		}
+		if nonNull == 0 {
+			result.Columns = append(result.Columns, p)
+			continue
+		}
+
+		col := d.QuoteIdent(c.Name)
+		if ordered {
+			query := fmt.Sprintf("SELECT 'min' AS profile_stat, MIN(%[1]s) AS %[1]s FROM %[2]s UNION ALL SELECT 'max' AS profile_stat, MAX(%[1]s) AS %[1]s FROM %[2]s", col, from)
+			rows, err := profileQuery(ctx, mcpClient, query, 2)
+			if err != nil {
+				return result, err
+			}
+			for _, row := range rows {
+				switch row["profile_stat"] {
+				case "min":
+					p.Min = row[c.Name]
+				case "max":
+					p.Max = row[c.Name]
+				}
+			}
+		}
+
+		// Unique columns have no common values worth listing
+		if p.Distinct != nil && *p.Distinct < nonNull {
+			query := fmt.Sprintf("SELECT %[1]s AS %[1]s, COUNT(*) AS profile_count FROM %[2]s WHERE %[1]s IS NOT NULL GROUP BY %[1]s ORDER BY profile_count DESC LIMIT %[3]d", col, from, top)
+			rows, err := profileQuery(ctx, mcpClient, query, top)
+			if err != nil {
+				return result, err
+			}
+			for _, row := range rows {
+				if v, ok := row[c.Name]; ok {
+					p.TopValues = append(p.TopValues, ValueCount{Value: v, Count: toCount(row["profile_count"])})
+				}
+			}
+		}
+		result.Columns = append(result.Columns, p)
+	}
+	return result, nil
+}
+
+// profileQuery runs a query of profileTable and decodes its rows.
+func profileQuery(ctx context.Context, mcpClient MCPClient, query string, limit int) ([]map[string]interface{}, error) {
+	data, err := mcpClient.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &rows); err != nil {
+		return nil, fmt.Errorf("failed to read profile query result: %w", err)
+	}
+	return rows, nil
+}
+
+// profileKind reports whether the values of a column of the data type can
+// be counted distinctly, and whether they can be ordered for MIN and MAX.
+func profileKind(dataType string) (distinct, ordered bool) {
+	t := strings.ToLower(dataType)
+	for _, s := range []string{"json", "array", "[]", "struct", "record", "map(", "tuple(", "geo", "bytea", "blob", "xml", "tsvector"} {
+		if strings.Contains(t, s) {
+			return false, false
+		}
+	}
+	for _, s := range []string{"bool", "uuid"} {
+		if strings.Contains(t, s) {
+			return true, false
+		}
+	}
+	return true, true
+}
+
+// quoteTable quotes each part of a possibly qualified table name.
+func quoteTable(d dialect.Dialect, table string) string {
+	parts := strings.Split(table, ".")
+	for i, p := range parts {
+		parts[i] = d.QuoteIdent(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+// toCount converts a count read from a result; some databases return
+// 64-bit integers as strings.
+func toCount(v interface{}) int64 {
+	switch v := v.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}
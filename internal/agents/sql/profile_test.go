@@ -0,0 +1,66 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+)
+
+// profileDB answers the queries of profileTable for an orders table.
+type profileDB struct {
+	MCPClient
+	queries []string
+}
+
+func (d *profileDB) GetSchema(ctx context.Context, table string) (string, error) {
+	if table != "orders" {
+		return "[]", nil
+	}
+	return `[{"column_name":"id","data_type":"integer"},{"column_name":"status","data_type":"text"},{"column_name":"tags","data_type":"jsonb"},{"column_name":"note","data_type":"text"}]`, nil
+}
+
+func (d *profileDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	d.queries = append(d.queries, query)
+	switch {
+	case strings.HasPrefix(query, "SELECT COUNT(*)"):
+		return `[{"row_count":4,"non_null_0":4,"distinct_0":4,"non_null_1":3,"distinct_1":2,"non_null_2":"1","non_null_3":0,"distinct_3":0}]`, nil
+	case strings.Contains(query, `MIN("id")`):
+		return `[{"profile_stat":"min","id":1},{"profile_stat":"max","id":4}]`, nil
+	case strings.Contains(query, `MIN("status")`):
+		return `[{"profile_stat":"min","status":"paid"},{"profile_stat":"max","status":"shipped"}]`, nil
+	case strings.Contains(query, `GROUP BY "status"`):
+		return `[{"status":"paid","profile_count":2},{"status":"shipped","profile_count":1}]`, nil
+	}
+	return "", fmt.Errorf("unexpected query %q", query)
+}
+
+func TestProfileTable(t *testing.T) {
+	db := &profileDB{}
+	got, err := profileTable(context.Background(), db, dialect.Postgres{}, "orders", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(got)
+	want := `{"table":"orders","row_count":4,"columns":[` +
+		`{"name":"id","data_type":"integer","nulls":0,"null_ratio":0,"distinct":4,"min":1,"max":4},` +
+		`{"name":"status","data_type":"text","nulls":1,"null_ratio":0.25,"distinct":2,"min":"paid","max":"shipped","top_values":[{"value":"paid","count":2},{"value":"shipped","count":1}]},` +
+		`{"name":"tags","data_type":"jsonb","nulls":3,"null_ratio":0.75},` +
+		`{"name":"note","data_type":"text","nulls":4,"null_ratio":1,"distinct":0}]}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+	if strings.Contains(db.queries[0], `DISTINCT "tags"`) {
+		t.Errorf("count query counts distinct JSON values: %s", db.queries[0])
+	}
+	if len(db.queries) != 4 {
+		t.Errorf("ran %d queries, want 4: %q", len(db.queries), db.queries)
+	}
+
+	if _, err := profileTable(context.Background(), db, dialect.Postgres{}, "nope", 0); err == nil {
+		t.Error("profiling a missing table succeeded")
+	}
+}
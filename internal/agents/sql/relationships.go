@@ -0,0 +1,35 @@
+package sql
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Relationships lists the foreign keys of a DescribeDatabase description
+// as a section of the SQL agent's instruction, or returns "" if there are
+// none, so the model joins tables on their real keys.
+func Relationships(schema string) string {
+	var tables []struct {
+		ForeignKeys []string `json:"foreign_keys"`
+	}
+	if err := json.Unmarshal([]byte(schema), &tables); err != nil {
+		return ""
+	}
+
+	var keys []string
+	for _, t := range tables {
+		keys = append(keys, t.ForeignKeys...)
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## Relationships\n")
+	b.WriteString("Join tables on these foreign keys, column for column, rather than on columns that merely share a name; ")
+	b.WriteString("for tables not related directly, call find_join_path:\n")
+	for _, k := range keys {
+		b.WriteString("- " + k + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package sql
+
+import (
+	"context"
+
+	"google.golang.org/adk/tool"
+)
+
+type sessionKey struct{}
+
+type sessionInfo struct {
+	userID    string
+	sessionID string
+}
+
+// WithSession returns a context identifying the user and session a database
+// call is made for, so MCPClient wrappers can attribute and limit it.
+func WithSession(ctx context.Context, userID, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionInfo{userID: userID, sessionID: sessionID})
+}
+
+// SessionFrom returns the user and session set with WithSession, falling
+// back to a context that exposes them directly (such as an ADK tool context).
+func SessionFrom(ctx context.Context) (userID, sessionID string, ok bool) {
+	if info, ok := ctx.Value(sessionKey{}).(sessionInfo); ok {
+		return info.userID, info.sessionID, true
+	}
+	if sc, ok := ctx.(interface {
+		UserID() string
+		SessionID() string
+	}); ok {
+		return sc.UserID(), sc.SessionID(), true
+	}
+	return "", "", false
+}
+
+// toolSession carries the tool context's user and session as values, which
+// survive wrapping by further context.WithValue calls.
+func toolSession(ctx tool.Context) context.Context {
+	return WithSession(ctx, ctx.UserID(), ctx.SessionID())
+}
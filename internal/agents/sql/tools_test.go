@@ -0,0 +1,229 @@
+package sql_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockdb"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestQueryDatabaseTool(t *testing.T) {
+	tests := []struct {
+		name      string
+		sql       string
+		wantData  string
+		wantError string
+		// executed is whether the statement reaches the database
+		executed bool
+	}{
+		{name: "rows", sql: "SELECT * FROM customers", wantData: `[{"id":1,"name":"Ada"},{"id":2,"name":"Grace"}]`, executed: true},
+		{name: "database error", sql: "SELECT * FROM refunds", wantError: `query error: relation "refunds" does not exist`, executed: true},
+		{name: "write rejected", sql: "DELETE FROM orders", wantError: sqlagent.ErrReadOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := mockdb.Load("../../../pkg/mockdb/testdata/store.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			llm := mockllm.New(
+				mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+				mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "Done."}},
+				mockllm.Rule{Agent: "SQL expert", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": tt.sql}}}}},
+			)
+			a := testutil.NewApp(t, llm, db)
+
+			turn, err := a.Ask(context.Background(), "u", "s", "Run it", nil)
+			if err != nil {
+				t.Fatalf("Ask() error = %v", err)
+			}
+			if len(turn.Queries) != 1 {
+				t.Fatalf("queries = %+v, want one", turn.Queries)
+			}
+			if q := turn.Queries[0]; q.Data != tt.wantData || q.Error != tt.wantError {
+				t.Errorf("query = data %q, error %q; want data %q, error %q", q.Data, q.Error, tt.wantData, tt.wantError)
+			}
+			if got := len(db.Queries()) > 0; got != tt.executed {
+				t.Errorf("executed = %v, want %v", got, tt.executed)
+			}
+		})
+	}
+}
+
+// argsDB records the bind parameters its queries receive.
+type argsDB struct {
+	*mockdb.DB
+	args []interface{}
+}
+
+func (d *argsDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	d.args = sqlagent.ArgsFrom(ctx)
+	return d.DB.Query(ctx, query, limit)
+}
+
+func TestQueryDatabaseParamsTool(t *testing.T) {
+	db, err := mockdb.Load("../../../pkg/mockdb/testdata/store.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &argsDB{DB: db}
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: sqlagent.QueryParamsToolName, Respond: mockllm.Response{Text: "Done."}},
+		mockllm.Rule{Agent: "SQL expert", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: sqlagent.QueryParamsToolName, Args: map[string]any{
+			"sql":  "SELECT * FROM customers WHERE name = $1 OR id = $2",
+			"args": []any{"O'Brien", 2},
+		}}}}},
+	)
+	a := testutil.NewApp(t, llm, client)
+
+	turn, err := a.Ask(context.Background(), "u", "s", "Find O'Brien", nil)
+	if err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+	if len(turn.Queries) != 1 || turn.Queries[0].SQL != "SELECT * FROM customers WHERE name = $1 OR id = $2" {
+		t.Fatalf("queries = %+v, want the parameterized query", turn.Queries)
+	}
+	if want := []interface{}{"O'Brien", float64(2)}; !reflect.DeepEqual(client.args, want) {
+		t.Errorf("args = %#v, want %#v", client.args, want)
+	}
+	if got := db.Queries(); len(got) != 1 || strings.Contains(got[0], "O'Brien") {
+		t.Errorf("database queries = %q, want the values kept out of the SQL", got)
+	}
+}
+
+// noQueryDB fails the test if a query reaches the database.
+type noQueryDB struct {
+	*mockdb.DB
+	t *testing.T
+}
+
+func (d *noQueryDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	d.t.Errorf("Query(%q) called in dry-run mode", query)
+	return d.DB.Query(ctx, query, limit)
+}
+
+func TestDryRun(t *testing.T) {
+	tests := []struct {
+		tool string
+		args map[string]any
+	}{
+		{"query_database", map[string]any{"sql": "SELECT * FROM customers WHERE id = 1"}},
+		{sqlagent.QueryParamsToolName, map[string]any{"sql": "SELECT * FROM customers WHERE id = $1", "args": []any{1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			db, err := mockdb.Load("../../../pkg/mockdb/testdata/store.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			llm := mockllm.New(
+				mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+				mockllm.Rule{After: tt.tool, Respond: mockllm.Response{Text: "Here is the SQL."}},
+				mockllm.Rule{Agent: "SQL expert", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: tt.tool, Args: tt.args}}}},
+			)
+			a := testutil.NewApp(t, llm, &noQueryDB{DB: db, t: t})
+
+			turn, err := a.Ask(sqlagent.WithDryRun(context.Background()), "u", "s", "Find customer 1", nil)
+			if err != nil {
+				t.Fatalf("Ask() error = %v", err)
+			}
+			if !turn.DryRun {
+				t.Error("turn.DryRun = false, want true")
+			}
+			if len(turn.Queries) != 1 || turn.Queries[0].SQL != tt.args["sql"] {
+				t.Fatalf("queries = %+v, want the generated SQL", turn.Queries)
+			}
+			if q := turn.Queries[0]; q.Data != "" || q.Error != "" {
+				t.Errorf("query = data %q, error %q; want neither", q.Data, q.Error)
+			}
+			if got := db.Queries(); len(got) != 0 {
+				t.Errorf("database queries = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestResolveTimeRangeTool(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       map[string]any
+		wantFilter string
+		wantError  string
+	}{
+		{name: "column", args: map[string]any{"expression": "2024", "column": "orders.created_at"}, wantFilter: "orders.created_at >= '2024-01-01' AND orders.created_at < '2025-01-01'"},
+		{name: "no column", args: map[string]any{"expression": "2024"}, wantError: `missing properties: ["column"]`},
+		{name: "blank column", args: map[string]any{"expression": "2024", "column": " "}, wantError: "column is required"},
+		{name: "bad expression", args: map[string]any{"expression": "someday", "column": "created_at"}, wantError: "someday"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := mockdb.Load("../../../pkg/mockdb/testdata/store.yaml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			llm := mockllm.New(
+				mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+				mockllm.Rule{After: "resolve_timerange", Respond: mockllm.Response{Text: "Done."}},
+				mockllm.Rule{Agent: "SQL expert", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "resolve_timerange", Args: tt.args}}}},
+			)
+			a := testutil.NewApp(t, llm, db)
+			if _, err := a.Ask(context.Background(), "u", "s", "Orders in 2024", nil); err != nil {
+				t.Fatalf("Ask() error = %v", err)
+			}
+
+			reqs := llm.Requests()
+			var result map[string]any
+			for _, c := range reqs[len(reqs)-1].Contents {
+				for _, p := range c.Parts {
+					if p.FunctionResponse != nil && p.FunctionResponse.Name == "resolve_timerange" {
+						result = p.FunctionResponse.Response
+					}
+				}
+			}
+			filter, _ := result["filter"].(string)
+			errMsg, _ := result["error"].(string)
+			if filter != tt.wantFilter || !strings.Contains(errMsg, tt.wantError) || (tt.wantError == "") != (errMsg == "") {
+				t.Errorf("resolve_timerange = filter %q, error %q; want filter %q, error containing %q", filter, errMsg, tt.wantFilter, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestRelationships(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   []string
+	}{
+		{"no foreign keys", `[{"table":"orders","columns":["id integer"]}]`, nil},
+		{"not a description", "orders(id)", nil},
+		{
+			"foreign keys",
+			`[{"table":"lines","foreign_keys":["lines(order_id) -> orders(id)"]},{"table":"orders","foreign_keys":["orders(customer_id) -> customers(id)"]}]`,
+			[]string{"## Relationships\n", "\n- lines(order_id) -> orders(id)\n- orders(customer_id) -> customers(id)\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sqlagent.Relationships(tt.schema)
+			if tt.want == nil && got != "" {
+				t.Errorf("Relationships() = %q, want none", got)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("Relationships() = %q, lacks %q", got, w)
+				}
+			}
+		})
+	}
+}
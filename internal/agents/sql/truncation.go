@@ -0,0 +1,39 @@
+package sql
+
+import "context"
+
+// Caps reported in Truncation.Reasons.
+const (
+	CapRows    = "rows"
+	CapColumns = "columns"
+	CapBytes   = "bytes"
+)
+
+// Truncation describes how a query result was cut to fit the result caps.
+// The query tools return it with the result, so the agent can tell the
+// user the data is incomplete.
+type Truncation struct {
+	// Reasons lists the caps the result reached: CapRows, CapColumns or
+	// CapBytes
+	Reasons      []string `json:"reasons"`
+	ReturnedRows int      `json:"returned_rows"`
+	// DroppedColumns are the columns left out of every row
+	DroppedColumns []string `json:"dropped_columns,omitempty"`
+	Notice         string   `json:"notice"`
+}
+
+type truncationKey struct{}
+
+// WithTruncation returns a context in which MCPClient wrappers that cut
+// the result of a query report it in *t.
+func WithTruncation(ctx context.Context, t **Truncation) context.Context {
+	return context.WithValue(ctx, truncationKey{}, t)
+}
+
+// ReportTruncation reports that the result of the query made with ctx was
+// cut. It does nothing for contexts not from WithTruncation.
+func ReportTruncation(ctx context.Context, t *Truncation) {
+	if p, ok := ctx.Value(truncationKey{}).(**Truncation); ok {
+		*p = t
+	}
+}
@@ -0,0 +1,117 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ProposeWriteToolName is the name of the long-running tool used to request
+// approval for data-modifying statements.
+const ProposeWriteToolName = "propose_write"
+
+// Approval statuses reported back to the agent through propose_write.
+const (
+	WriteStatusPending  = "pending_approval"
+	WriteStatusExecuted = "executed"
+	WriteStatusRejected = "rejected"
+	WriteStatusFailed   = "failed"
+)
+
+// writeKeywords match the commands that start a data-modifying statement,
+// an EXPLAIN ANALYZE of one, which runs it, or a data-modifying CTE, and
+// SELECT ... INTO, which creates a table. FOR UPDATE and columns named like
+// a command are not writes.
+var writeKeywords = regexp.MustCompile(`(?i)(?:^|;|\()\s*(?:EXPLAIN\s+(?:\([^)]*\)\s*)?(?:ANALY[SZ]E\s+)?(?:VERBOSE\s+)?)?(?:INSERT|UPDATE|DELETE|MERGE|CREATE|DROP|ALTER|TRUNCATE|GRANT|REVOKE|COPY|VACUUM|REINDEX|CLUSTER|REFRESH|CALL|LOCK|DO)\b|\bINTO\b`)
+
+var (
+	lineComment  = regexp.MustCompile(`--[^\n]*`)
+	blockComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	quoted       = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"`)
+)
+
+// IsWriteStatement reports whether query is a command that modifies data or
+// schema, which query_database refuses so that the agent proposes it for
+// approval instead. Keywords inside comments, string literals and quoted
+// identifiers are ignored. Writes through functions, such as nextval(), are
+// not recognized: database clients run every statement but approved writes
+// in a read-only transaction, which refuses them.
+func IsWriteStatement(query string) bool {
+	stripped := blockComment.ReplaceAllString(query, " ")
+	stripped = lineComment.ReplaceAllString(stripped, " ")
+	stripped = quoted.ReplaceAllString(stripped, " ")
+	return writeKeywords.MatchString(stripped)
+}
+
+// ProposeWriteArgs are the arguments of the propose_write tool.
+type ProposeWriteArgs struct {
+	SQL    string `json:"sql" description:"The INSERT, UPDATE or DELETE statement to run"`
+	Reason string `json:"reason" description:"A short explanation of what the statement changes and why"`
+}
+
+// ProposeWriteResult is returned by propose_write while approval is pending.
+type ProposeWriteResult struct {
+	Status string `json:"status"`
+	SQL    string `json:"sql"`
+}
+
+// CreateWriteTools creates the approval-gated tools used in write mode.
+// propose_write never executes anything itself: it is a long-running tool
+// whose final result is supplied by the caller once the user has approved or
+// rejected the statement.
+func CreateWriteTools() ([]tool.Tool, error) {
+	proposeTool, err := newTool(
+		functiontool.Config{
+			Name:          ProposeWriteToolName,
+			Description:   "Propose an INSERT, UPDATE or DELETE statement. The user must approve it before it is executed.",
+			IsLongRunning: true,
+		},
+		func(ctx tool.Context, args ProposeWriteArgs) (ProposeWriteResult, error) {
+			return ProposeWriteResult{Status: WriteStatusPending, SQL: args.SQL}, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", ProposeWriteToolName, err)
+	}
+
+	return []tool.Tool{proposeTool}, nil
+}
+
+type readOnlyKey struct{}
+
+// WithReadOnly returns a context in which proposed writes may be rejected
+// but not approved, e.g. for API clients with a read-only profile.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey{}, true)
+}
+
+// IsReadOnly reports whether ctx was created with WithReadOnly.
+func IsReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyKey{}).(bool)
+	return readOnly
+}
+
+type approvedWriteKey struct{}
+
+// WithApprovedWrite returns a context for running a statement the user
+// approved through propose_write. Database clients run every other
+// statement in a read-only transaction.
+func WithApprovedWrite(ctx context.Context) context.Context {
+	return context.WithValue(ctx, approvedWriteKey{}, true)
+}
+
+// IsApprovedWrite reports whether ctx was created with WithApprovedWrite.
+func IsApprovedWrite(ctx context.Context) bool {
+	approved, _ := ctx.Value(approvedWriteKey{}).(bool)
+	return approved
+}
+
+// ErrReadOnly is returned by query_database for data-modifying statements.
+const ErrReadOnly = "query_database is read-only. Data-modifying statements must be proposed with propose_write, which is only available when write mode is enabled."
+
+// ErrReadOnlyTransaction is added to the errors of statements that failed
+// because they ran in a read-only transaction.
+const ErrReadOnlyTransaction = "query_database runs in a read-only transaction, so statements that lock rows (FOR UPDATE or FOR SHARE) or call functions that write, such as nextval(), fail. Leave the lock or function out; data changes must be proposed with propose_write, which is only available when write mode is enabled."
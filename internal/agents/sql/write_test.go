@@ -0,0 +1,37 @@
+package sql
+
+import "testing"
+
+func TestIsWriteStatement(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected bool
+	}{
+		{name: "select", query: "SELECT * FROM orders", expected: false},
+		{name: "insert", query: "INSERT INTO orders (id) VALUES (1)", expected: true},
+		{name: "lowercase update", query: "update orders set status = 'shipped'", expected: true},
+		{name: "data-modifying CTE", query: "WITH d AS (DELETE FROM orders RETURNING *) SELECT * FROM d", expected: true},
+		{name: "keyword in string literal", query: "SELECT * FROM logs WHERE action = 'DELETE'", expected: false},
+		{name: "keyword in quoted identifier", query: `SELECT "update" FROM audit`, expected: false},
+		{name: "keyword in comment", query: "SELECT 1 -- drop table orders", expected: false},
+		{name: "similar column name", query: "SELECT created_at, updated_at FROM orders", expected: false},
+		{name: "columns named like commands", query: "SELECT a.update, a.lock FROM audit a", expected: false},
+		{name: "select for update", query: "SELECT * FROM orders WHERE id = 1 FOR UPDATE", expected: false},
+		{name: "select for share", query: "SELECT * FROM orders FOR NO KEY UPDATE OF orders SKIP LOCKED; SELECT 1 FOR SHARE", expected: false},
+		// Left to the read-only transaction the clients run queries in
+		{name: "function that writes", query: "SELECT nextval('orders_id_seq'), pg_advisory_lock(1)", expected: false},
+		{name: "explain analyze", query: "EXPLAIN (ANALYZE, BUFFERS) DELETE FROM orders", expected: true},
+		{name: "select into", query: "SELECT * INTO orders_backup FROM orders", expected: true},
+		{name: "second statement", query: "SELECT 1; DROP TABLE orders", expected: true},
+		{name: "anonymous block", query: "DO $$ BEGIN PERFORM 1; END $$", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsWriteStatement(tt.query); got != tt.expected {
+				t.Errorf("IsWriteStatement(%q) = %v, want %v", tt.query, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,1032 @@
+// Package app executes conversational turns against the agent hierarchy so
+// that every front-end (REPL, chat bots) shares the same runner wiring.
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+	"github.com/anuvratrastogi/multi-agent/internal/clarify"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/filters"
+	"github.com/anuvratrastogi/multi-agent/internal/frame"
+	"github.com/anuvratrastogi/multi-agent/internal/guardrails"
+	"github.com/anuvratrastogi/multi-agent/internal/i18n"
+	"github.com/anuvratrastogi/multi-agent/internal/prompts"
+	"github.com/anuvratrastogi/multi-agent/internal/replay"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// AppName is the ADK application name used for runners and sessions.
+const AppName = "multi-agent"
+
+// App runs user queries through the Manager agent.
+type App struct {
+	manager        *manager.Agent
+	runner         *runner.Runner
+	resumer        *runner.Runner
+	sessionService session.Service
+	db             sqlagent.MCPClient
+	locale         i18n.Locale
+	usage          UsageRecorder
+	keepReasoning  bool
+	cache          AnswerCache
+	cacheScope     func(userID string) string
+	events         *events.Bus
+	budget         Budget
+	results        ResultStore
+	clarifyBelow   float64
+	prompts        *prompts.Store
+	traces         *replay.Recorder
+	planExecute    bool
+	maxReplans     int
+	policies       StepPolicies
+	appendSummary  bool
+	artifacts      artifacts.Store
+	chartStyle     chart.Style
+	guardrails     *guardrails.Guard
+
+	mu sync.Mutex
+	// scopes holds the filters applied so far in each session
+	scopes map[string][]filters.Filter
+	// cleared marks sessions whose next question must not reuse filters
+	cleared map[string]bool
+	// asked marks sessions whose last turn asked a clarifying question
+	asked map[string]bool
+	// variants holds the prompt variants sessions were pinned to
+	variants map[string]string
+	// resolving marks the writes whose decision is being carried out
+	resolving map[string]bool
+	// draining refuses new turns; active counts those in flight
+	draining bool
+	active   int
+	inflight sync.WaitGroup
+	// abort is cancelled when Drain stops waiting for turns
+	abort      context.Context
+	abortTurns context.CancelFunc
+}
+
+// Config holds configuration for the App.
+type Config struct {
+	Manager        *manager.Agent
+	SessionService session.Service
+	// DB executes statements the user approved in write mode (optional)
+	DB sqlagent.MCPClient
+	// Locale is used to read numbers and dates in questions (defaults to en-US)
+	Locale *i18n.Locale
+	// Usage accumulates questions and tokens per user (optional)
+	Usage UsageRecorder
+	// KeepReasoning stores the models' reasoning in Turn.Reasoning; it is
+	// discarded by default
+	KeepReasoning bool
+	// Cache answers questions similar to earlier ones without running the
+	// agents (optional)
+	Cache AnswerCache
+	// CacheScope partitions the cached answers: a user is only served
+	// answers cached for users of the same scope, such as those granted the
+	// same tables (optional; all users share answers by default)
+	CacheScope func(userID string) string
+	// Events receives the lifecycle events of every turn (optional)
+	Events *events.Bus
+	// Budget limits the time, LLM calls, tool calls and tokens of each turn
+	Budget Budget
+	// Results holds the complete data of query results the model was only
+	// shown a preview of (optional)
+	Results ResultStore
+	// ClarifyBelow asks the Manager to check whether a question needs
+	// clarifying when its intent is classified with lower confidence
+	// (0 = never)
+	ClarifyBelow float64
+	// Prompts assigns sessions to prompt variants and tags their turns'
+	// metrics with them (optional)
+	Prompts *prompts.Store
+	// Traces saves every turn's model and tool calls for the replay command
+	// (optional)
+	Traces *replay.Recorder
+	// PlanExecute has the Manager plan each question as steps, which are
+	// run one at a time, each within the Budget
+	PlanExecute bool
+	// MaxReplans is how often the steps after a failed one are planned
+	// again in a turn (0 = never)
+	MaxReplans int
+	// StepPolicies says how failed steps of a plan are recovered from
+	// (default: DefaultStepPolicies)
+	StepPolicies StepPolicies
+	// AppendSummary ends every answer with its Summary, except questions
+	// asked back to the user
+	AppendSummary bool
+	// Artifacts stores the charts and exported results of turns, which
+	// Turn.Artifacts then lists with their download URLs (optional)
+	Artifacts artifacts.Store
+	// ChartStyle is how charts look unless a turn's context, from
+	// chart.WithStyle, changes it
+	ChartStyle chart.Style
+	// Guardrails checks the text and query results of every answer before
+	// it is returned (optional)
+	Guardrails *guardrails.Guard
+}
+
+// ResultStore holds the complete data of query results that were replaced
+// by a preview with a result_id before reaching the model.
+type ResultStore interface {
+	// Full returns the complete data of the result with the given ID.
+	Full(resultID string) (string, bool)
+}
+
+// AnswerCache stores answered questions and finds the answer to a similar
+// question.
+type AnswerCache interface {
+	// Lookup returns a copy of an answer cached under scope with Cached
+	// set, or nil.
+	Lookup(ctx context.Context, scope, question string) (*Turn, error)
+	// Store caches the answer to a question under scope if it is complete.
+	Store(ctx context.Context, scope, question string, turn *Turn) error
+}
+
+// UsageRecorder is notified of every completed turn, for usage analytics.
+type UsageRecorder interface {
+	// RecordTurn adds a turn's token usage to the user's totals; question is
+	// false for turns that resume a session, such as approvals.
+	RecordTurn(userID string, question bool, tokens TokenUsage)
+}
+
+// New creates a new App with an ADK runner rooted at the Manager agent.
+func New(cfg Config) (*App, error) {
+	if cfg.SessionService == nil {
+		cfg.SessionService = session.InMemoryService()
+	}
+	cfg.SessionService = isolated{cfg.SessionService}
+
+	r, err := runner.New(runner.Config{
+		AppName:        AppName,
+		Agent:          cfg.Manager,
+		SessionService: cfg.SessionService,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create runner: %w", err)
+	}
+	// Every question starts at the Manager, but decisions on writes must
+	// reach the SQL agent that proposed them
+	resumer, err := runner.New(runner.Config{
+		AppName:        AppName,
+		Agent:          cfg.Manager.GetSQLAgent().Agent,
+		SessionService: cfg.SessionService,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create runner: %w", err)
+	}
+
+	locale := i18n.DefaultLocale
+	if cfg.Locale != nil {
+		locale = *cfg.Locale
+	}
+
+	if cfg.StepPolicies == nil {
+		cfg.StepPolicies = DefaultStepPolicies()
+	}
+
+	abort, abortTurns := context.WithCancel(context.Background())
+	return &App{
+		manager:        cfg.Manager,
+		runner:         r,
+		resumer:        resumer,
+		sessionService: cfg.SessionService,
+		db:             cfg.DB,
+		locale:         locale,
+		usage:          cfg.Usage,
+		keepReasoning:  cfg.KeepReasoning,
+		cache:          cfg.Cache,
+		cacheScope:     cfg.CacheScope,
+		events:         cfg.Events,
+		budget:         cfg.Budget,
+		results:        cfg.Results,
+		clarifyBelow:   cfg.ClarifyBelow,
+		prompts:        cfg.Prompts,
+		traces:         cfg.Traces,
+		planExecute:    cfg.PlanExecute,
+		maxReplans:     cfg.MaxReplans,
+		policies:       cfg.StepPolicies,
+		appendSummary:  cfg.AppendSummary,
+		artifacts:      cfg.Artifacts,
+		chartStyle:     cfg.ChartStyle,
+		guardrails:     cfg.Guardrails,
+		scopes:         make(map[string][]filters.Filter),
+		cleared:        make(map[string]bool),
+		asked:          make(map[string]bool),
+		variants:       make(map[string]string),
+		resolving:      make(map[string]bool),
+		abort:          abort,
+		abortTurns:     abortTurns,
+	}, nil
+}
+
+// Turn is the outcome of a single user query.
+type Turn struct {
+	// Routing is the intent classification and planned workflow.
+	Routing *manager.Result `json:"routing,omitempty"`
+	// Text is the concatenated narrative produced by the agents.
+	Text string `json:"text"`
+	// ToolCalls lists the tools invoked during the turn, in order.
+	ToolCalls []string `json:"tool_calls,omitempty"`
+	// Queries holds every query_database and query_database_params call and
+	// its result.
+	Queries []Query `json:"queries,omitempty"`
+	// Charts holds the Mermaid chart definitions found in Text.
+	Charts []string `json:"charts,omitempty"`
+	// Approvals lists proposed writes awaiting the user's decision.
+	Approvals []Approval `json:"approvals,omitempty"`
+	// Clarification is the question an agent asked instead of answering;
+	// the user's reply is the next question of the session.
+	Clarification *clarify.Question `json:"clarification,omitempty"`
+	// DryRun is true if queries were generated but not executed.
+	DryRun bool `json:"dry_run,omitempty"`
+	// Slots lists the locale-formatted numbers and dates found in the
+	// question and the canonical values passed to the agents.
+	Slots []i18n.Slot `json:"slots,omitempty"`
+	// Filters is the union of the filters the session's queries have
+	// applied so far, which follow-up questions may carry over.
+	Filters []filters.Filter `json:"filters,omitempty"`
+	// Tokens is the LLM usage reported for the turn.
+	Tokens TokenUsage `json:"tokens"`
+	// Cached is set if the turn is the stored answer to a similar question
+	// rather than a fresh one.
+	Cached *CacheHit `json:"cached,omitempty"`
+	// Stopped says which limit of the App's Budget cut the turn short; the
+	// rest of the turn holds what was done until then.
+	Stopped string `json:"stopped,omitempty"`
+	// Reasoning is the thinking the models emitted alongside their output,
+	// kept only if the App is configured to. It is never part of Text and
+	// is left out of the turn's JSON.
+	Reasoning []Thought `json:"-"`
+	// Summary accounts for the agents, queries, charts, tokens and time of
+	// the turn.
+	Summary *Summary `json:"summary,omitempty"`
+	// Artifacts are the stored charts and exported results of the turn.
+	Artifacts []artifacts.Artifact `json:"artifacts,omitempty"`
+	// Guardrails lists the content rules that redacted or blocked parts of
+	// the answer.
+	Guardrails []guardrails.Violation `json:"guardrails,omitempty"`
+
+	// agents are the agents that responded, in order
+	agents []string
+}
+
+// Thought is a reasoning segment emitted by an agent's model.
+type Thought struct {
+	Agent string `json:"agent"`
+	Text  string `json:"text"`
+}
+
+// CacheHit describes the earlier question whose answer was reused.
+type CacheHit struct {
+	Question   string    `json:"question"`
+	Similarity float64   `json:"similarity"`
+	AnsweredAt time.Time `json:"answered_at"`
+}
+
+// TokenUsage counts the LLM tokens consumed by a turn. Providers that do not
+// report usage leave it zero.
+type TokenUsage struct {
+	Prompt     int `json:"prompt"`
+	Completion int `json:"completion"`
+}
+
+// Approval is a data-modifying statement proposed by the SQL agent that must
+// be approved or rejected with Resolve before the session can continue.
+type Approval struct {
+	// CallID is the ID of the pending propose_write function call.
+	CallID string `json:"call_id"`
+	// SQL is the proposed statement. It is shown to the user; Resolve
+	// executes the statement recorded in the session under CallID.
+	SQL    string `json:"sql"`
+	Reason string `json:"reason,omitempty"`
+}
+
+var (
+	// ErrApprovalNotFound is returned for call IDs that are not a write
+	// proposed in the session.
+	ErrApprovalNotFound = errors.New("no write awaiting approval with this call ID")
+	// ErrApprovalAnswered is returned for writes that were already approved
+	// or rejected.
+	ErrApprovalAnswered = errors.New("write was already approved or rejected")
+)
+
+// Query is a single SQL execution performed during a turn.
+type Query struct {
+	SQL   string `json:"sql"`
+	Data  string `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+	// answered is set once the query's result has arrived
+	answered bool
+}
+
+// EventHandler is called for every event emitted while a turn runs.
+type EventHandler func(*session.Event)
+
+// EnsureSession creates the session if it does not exist yet.
+func (a *App) EnsureSession(ctx context.Context, userID, sessionID string) error {
+	_, err := a.sessionService.Get(ctx, &session.GetRequest{
+		AppName:   AppName,
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+	if err == nil {
+		return nil
+	}
+
+	_, err = a.sessionService.Create(ctx, &session.CreateRequest{
+		AppName:   AppName,
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+// SessionInfo summarizes a conversation session.
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Events    int       `json:"events"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListSessions returns the user's sessions, most recently updated first.
+func (a *App) ListSessions(ctx context.Context, userID string) ([]SessionInfo, error) {
+	resp, err := a.sessionService.List(ctx, &session.ListRequest{AppName: AppName, UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := make([]SessionInfo, 0, len(resp.Sessions))
+	for _, s := range resp.Sessions {
+		sessions = append(sessions, SessionInfo{
+			ID:        s.ID(),
+			UserID:    s.UserID(),
+			Events:    s.Events().Len(),
+			UpdatedAt: s.LastUpdateTime(),
+		})
+	}
+	slices.SortFunc(sessions, func(x, y SessionInfo) int { return y.UpdatedAt.Compare(x.UpdatedAt) })
+	return sessions, nil
+}
+
+// ErrSessionNotFound is returned for sessions the user does not have.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Message is a question or answer in a session's conversation.
+type Message struct {
+	// Author is "user" or the agent that answered
+	Author string    `json:"author"`
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+}
+
+// GetSession returns the session and its conversation: the user's questions
+// and the agents' narrative, without tool calls or reasoning.
+func (a *App) GetSession(ctx context.Context, userID, sessionID string) (*SessionInfo, []Message, error) {
+	sessions, err := a.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	i := slices.IndexFunc(sessions, func(s SessionInfo) bool { return s.ID == sessionID })
+	if i < 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	resp, err := a.sessionService.Get(ctx, &session.GetRequest{AppName: AppName, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	var messages []Message
+	for event := range resp.Session.Events().All() {
+		if event.Partial || event.LLMResponse.Content == nil {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range event.LLMResponse.Content.Parts {
+			if part.Text != "" && !part.Thought {
+				text.WriteString(part.Text)
+			}
+		}
+		if text.Len() > 0 {
+			messages = append(messages, Message{Author: event.Author, Text: text.String(), Time: event.Timestamp})
+		}
+	}
+	info := sessions[i]
+	info.Events = resp.Session.Events().Len()
+	return &info, messages, nil
+}
+
+// KeepsReasoning reports whether turns keep the models' reasoning.
+func (a *App) KeepsReasoning() bool {
+	return a.keepReasoning
+}
+
+// filtersClearedNote is added to the question after ClearFilters.
+const filtersClearedNote = "[The user cleared all filters. Do not reuse WHERE conditions from earlier questions unless this question asks for them.]"
+
+// Filters returns the filters applied so far in the session.
+func (a *App) Filters(userID, sessionID string) []filters.Filter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]filters.Filter(nil), a.scopes[userID+"/"+sessionID]...)
+}
+
+// ClearFilters forgets the session's filters and tells the agents not to
+// carry them over into the next question.
+func (a *App) ClearFilters(userID, sessionID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := userID + "/" + sessionID
+	delete(a.scopes, key)
+	a.cleared[key] = true
+}
+
+// takeCleared reports whether the session's filters were cleared since its
+// last question, and resets the mark.
+func (a *App) takeCleared(userID, sessionID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := userID + "/" + sessionID
+	cleared := a.cleared[key]
+	delete(a.cleared, key)
+	return cleared
+}
+
+// swapAsked records whether the session's latest turn asked a clarifying
+// question and returns the previous mark.
+func (a *App) swapAsked(userID, sessionID string, asked bool) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := userID + "/" + sessionID
+	prev := a.asked[key]
+	if asked {
+		a.asked[key] = true
+	} else {
+		delete(a.asked, key)
+	}
+	return prev
+}
+
+// PromptVariants lists the prompt variants sessions can be pinned to.
+func (a *App) PromptVariants() []string {
+	if a.prompts == nil {
+		return []string{prompts.Base}
+	}
+	return a.prompts.Variants()
+}
+
+// PromptVariant returns the prompt variant of the session: the one it was
+// pinned to, or else the one assigned by the Prompts' weights.
+func (a *App) PromptVariant(userID, sessionID string) string {
+	if a.prompts == nil {
+		return prompts.Base
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := userID + "/" + sessionID
+	if v, ok := a.variants[key]; ok {
+		return v
+	}
+	return a.prompts.Assign(key)
+}
+
+// SetPromptVariant pins the session to a prompt variant; "" unpins it.
+func (a *App) SetPromptVariant(userID, sessionID, variant string) error {
+	if variant != "" && !slices.Contains(a.PromptVariants(), variant) {
+		return fmt.Errorf("unknown prompt variant %q; variants are %s", variant, strings.Join(a.PromptVariants(), ", "))
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := userID + "/" + sessionID
+	if variant == "" {
+		delete(a.variants, key)
+	} else {
+		a.variants[key] = variant
+	}
+	return nil
+}
+
+// withVariant renders the agents' prompts in the session's variant. Turns
+// are tagged with it only while there are variants to compare.
+func (a *App) withVariant(ctx context.Context, userID, sessionID string) context.Context {
+	if a.prompts == nil || !a.prompts.Experimenting() {
+		return ctx
+	}
+	return prompts.WithVariant(ctx, a.PromptVariant(userID, sessionID))
+}
+
+// applyFilters adds the filters of the turn's successful queries to the
+// session's scope and returns the scope. Dry runs do not change it.
+func (a *App) applyFilters(userID, sessionID string, turn *Turn) []filters.Filter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := userID + "/" + sessionID
+	if !turn.DryRun {
+		for _, q := range turn.Queries {
+			if q.Error == "" {
+				a.scopes[key] = filters.Merge(a.scopes[key], filters.Extract(q.SQL))
+			}
+		}
+	}
+	return append([]filters.Filter(nil), a.scopes[key]...)
+}
+
+// Route classifies a query without running it.
+func (a *App) Route(ctx context.Context, query string) (*manager.Result, error) {
+	routing, err := a.manager.ProcessQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify query: %w", err)
+	}
+	return routing, nil
+}
+
+// Ask runs a query through the agents and collects the resulting Turn.
+// If onEvent is non-nil it is invoked for each event as it arrives. Pass a
+// context from sqlagent.WithDryRun to generate SQL without executing it.
+// With an answer cache, a question similar to an earlier one is answered
+// from the cache unless the context comes from WithFresh. Once Drain was
+// called, it fails with ErrDraining.
+func (a *App) Ask(ctx context.Context, userID, sessionID, query string, onEvent EventHandler) (*Turn, error) {
+	ctx, done, err := a.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	ctx = a.withVariant(events.NewContext(ctx, a.events, userID, sessionID), userID, sessionID)
+	ctx, trace := a.startTrace(ctx, userID, sessionID, query, false)
+	events.Publish(ctx, &events.TurnStarted{Question: query})
+	start := time.Now()
+	turn, err := a.ask(ctx, userID, sessionID, query, onEvent)
+	a.guard(ctx, turn)
+	if err == nil {
+		a.saveArtifacts(ctx, userID, sessionID, turn)
+	}
+	a.summarize(turn, err, start)
+	publishCompleted(ctx, start, turn, err)
+	a.saveTrace(trace, turn, err)
+	return turn, err
+}
+
+func (a *App) ask(ctx context.Context, userID, sessionID, query string, onEvent EventHandler) (*Turn, error) {
+	if err := a.EnsureSession(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+
+	routing, err := a.Route(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	classified := &events.IntentClassified{
+		Intent:     routing.ClassifiedIntent,
+		Confidence: routing.Confidence,
+		Workflow:   routing.Workflow,
+		Agents:     routing.AgentsUsed,
+		Classifier: routing.Classifier,
+	}
+	for _, s := range routing.Steps {
+		classified.Steps = append(classified.Steps, s.Task)
+	}
+	for _, c := range routing.Classifications {
+		switch c.Classifier {
+		case manager.ClassifierKeyword, manager.ClassifierEmbedding:
+			classified.KeywordIntent, classified.KeywordConfidence = c.Intent, c.Confidence
+		case manager.ClassifierLLM:
+			classified.LLMIntent, classified.LLMReasoning, classified.LLMError = c.Intent, c.Reasoning, c.Error
+		}
+	}
+	events.Publish(ctx, classified)
+
+	if turn := a.cachedAnswer(ctx, userID, sessionID, query, routing); turn != nil {
+		a.recordUsage(userID, true, turn)
+		return turn, nil
+	}
+	cache := a.shouldCache(ctx, userID, sessionID)
+
+	slots := a.locale.Extract(query)
+	request := i18n.Annotate(query, slots)
+	text := request
+	if a.takeCleared(userID, sessionID) {
+		text += "\n\n" + filtersClearedNote
+	}
+	// A reply to a clarifying question is short and often unclear on its
+	// own, so it is not flagged again
+	if answering := a.swapAsked(userID, sessionID, false); !answering && routing.Confidence < a.clarifyBelow {
+		text += "\n\n" + clarify.Note(routing.Confidence)
+	}
+	var turn *Turn
+	if a.planExecute {
+		turn, err = a.execute(ctx, userID, sessionID, request, text, routing, onEvent)
+	} else {
+		if note := manager.PlanNote(routing.Steps); note != "" {
+			text += "\n\n" + note
+		}
+		turn, err = a.run(ctx, userID, sessionID, genai.NewContentFromText(text, genai.RoleUser), routing, onEvent)
+	}
+	if turn != nil {
+		turn.Slots = slots
+		a.swapAsked(userID, sessionID, turn.Clarification != nil)
+	}
+	if cache && err == nil && turn.Stopped == "" && turn.Clarification == nil {
+		a.storeAnswer(ctx, userID, query, turn)
+	}
+	a.recordUsage(userID, true, turn)
+	return turn, err
+}
+
+// PendingApproval returns the write the SQL agent proposed in the session
+// under callID, which must not have been approved or rejected yet.
+func (a *App) PendingApproval(ctx context.Context, userID, sessionID, callID string) (Approval, error) {
+	resp, err := a.sessionService.Get(ctx, &session.GetRequest{AppName: AppName, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		return Approval{}, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	var approval *Approval
+	answered := false
+	for event := range resp.Session.Events().All() {
+		if event.LLMResponse.Content == nil {
+			continue
+		}
+		for _, part := range event.LLMResponse.Content.Parts {
+			if call := part.FunctionCall; call != nil && call.ID == callID && call.Name == sqlagent.ProposeWriteToolName &&
+				slices.Contains(event.LongRunningToolIDs, call.ID) {
+				sql, _ := call.Args["sql"].(string)
+				reason, _ := call.Args["reason"].(string)
+				approval = &Approval{CallID: call.ID, SQL: sql, Reason: reason}
+			}
+			// propose_write itself answers with the pending status; the
+			// decision is any other.
+			if resp := part.FunctionResponse; resp != nil && resp.ID == callID && resp.Response["status"] != sqlagent.WriteStatusPending {
+				answered = true
+			}
+		}
+	}
+	switch {
+	case approval == nil:
+		return Approval{}, fmt.Errorf("%w: %s", ErrApprovalNotFound, callID)
+	case answered:
+		return Approval{}, fmt.Errorf("%w: %s", ErrApprovalAnswered, callID)
+	}
+	return *approval, nil
+}
+
+// claimApproval marks the write under callID as being resolved, and reports
+// whether another Resolve was already carrying it out.
+func (a *App) claimApproval(callID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.resolving[callID] {
+		return false
+	}
+	a.resolving[callID] = true
+	return true
+}
+
+// releaseApproval undoes claimApproval.
+func (a *App) releaseApproval(callID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.resolving, callID)
+}
+
+// Resolve records the user's decision on the write pending in the session
+// under callID and resumes the session. Approved statements, as proposed by
+// the agent, are executed before the agent is told the outcome. Unknown
+// call IDs fail with ErrApprovalNotFound and writes that were decided
+// already with ErrApprovalAnswered. Writes cannot be approved in a context
+// from sqlagent.WithReadOnly.
+func (a *App) Resolve(ctx context.Context, userID, sessionID, callID string, approved bool, onEvent EventHandler) (*Turn, error) {
+	ctx, done, err := a.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	if !a.claimApproval(callID) {
+		return nil, fmt.Errorf("%w: %s", ErrApprovalAnswered, callID)
+	}
+	defer a.releaseApproval(callID)
+	approval, err := a.PendingApproval(ctx, userID, sessionID, callID)
+	if err != nil {
+		return nil, err
+	}
+	response := map[string]any{"status": sqlagent.WriteStatusRejected, "sql": approval.SQL}
+	if approved {
+		if sqlagent.IsReadOnly(ctx) {
+			return nil, fmt.Errorf("writes cannot be approved with read-only permissions")
+		}
+		if a.db == nil {
+			return nil, fmt.Errorf("write mode is not configured")
+		}
+		data, err := a.db.Query(sqlagent.WithApprovedWrite(sqlagent.WithSession(ctx, userID, sessionID)), approval.SQL, 0)
+		if err != nil {
+			response["status"] = sqlagent.WriteStatusFailed
+			response["error"] = err.Error()
+		} else {
+			response["status"] = sqlagent.WriteStatusExecuted
+			response["result"] = data
+		}
+	}
+
+	decision := "Rejected"
+	if approved {
+		decision = "Approved"
+	}
+	ctx = a.withVariant(events.NewContext(ctx, a.events, userID, sessionID), userID, sessionID)
+	ctx, trace := a.startTrace(ctx, userID, sessionID, decision+": "+approval.SQL, true)
+	events.Publish(ctx, &events.TurnStarted{Question: decision + ": " + approval.SQL, Resumed: true})
+	start := time.Now()
+
+	msg := &genai.Content{
+		Role: genai.RoleUser,
+		Parts: []*genai.Part{{
+			FunctionResponse: &genai.FunctionResponse{
+				ID:       approval.CallID,
+				Name:     sqlagent.ProposeWriteToolName,
+				Response: response,
+			},
+		}},
+	}
+	turn, err := a.run(ctx, userID, sessionID, msg, nil, onEvent)
+	a.recordUsage(userID, false, turn)
+	a.guard(ctx, turn)
+	if err == nil {
+		a.saveArtifacts(ctx, userID, sessionID, turn)
+	}
+	a.summarize(turn, err, start)
+	publishCompleted(ctx, start, turn, err)
+	a.saveTrace(trace, turn, err)
+	return turn, err
+}
+
+// Guarded reports whether answers are checked by guardrails, in which case
+// front-ends must not show the agents' text before the turn is complete.
+func (a *App) Guarded() bool {
+	return a.guardrails != nil
+}
+
+// summarize sets the Summary of a turn started at start and, if the App is
+// configured to, appends it to the answer.
+func (a *App) summarize(turn *Turn, err error, start time.Time) {
+	if turn == nil {
+		return
+	}
+	turn.Summary = summarize(turn, time.Since(start))
+	if a.appendSummary && err == nil && turn.Clarification == nil {
+		turn.Text = strings.TrimSpace(turn.Text + "\n\n" + turn.Summary.String())
+	}
+}
+
+// publishCompleted publishes TurnCompleted for a turn started at start.
+func publishCompleted(ctx context.Context, start time.Time, turn *Turn, err error) {
+	completed := &events.TurnCompleted{DurationMS: time.Since(start).Milliseconds()}
+	if turn != nil {
+		completed.PromptTokens = turn.Tokens.Prompt
+		completed.CompletionTokens = turn.Tokens.Completion
+		completed.Cached = turn.Cached != nil
+		completed.Stopped = turn.Stopped
+		completed.Answer = turn.Text
+		for _, q := range turn.Queries {
+			completed.Queries++
+			if q.Error != "" {
+				completed.FailedQueries++
+			}
+		}
+	}
+	if err != nil {
+		completed.Error = err.Error()
+	}
+	completed.PromptVariant = prompts.VariantFrom(ctx)
+	events.Publish(ctx, completed)
+}
+
+// recordUsage reports a turn, complete or not, to the usage recorder.
+func (a *App) recordUsage(userID string, question bool, turn *Turn) {
+	if a.usage == nil {
+		return
+	}
+	var tokens TokenUsage
+	if turn != nil {
+		tokens = turn.Tokens
+	}
+	a.usage.RecordTurn(userID, question, tokens)
+}
+
+// run sends msg to the runner and collects the resulting Turn.
+func (a *App) run(ctx context.Context, userID, sessionID string, msg *genai.Content, routing *manager.Result, onEvent EventHandler) (*Turn, error) {
+	turn := &Turn{Routing: routing, DryRun: sqlagent.IsDryRun(ctx)}
+	style := a.chartStyle.Merge(chart.StyleFrom(ctx))
+	ctx = chart.WithStyle(ctx, style)
+	pending := make(map[string]int)
+	var text strings.Builder
+	var author string
+	var llmCalls int
+	trace := replay.FromContext(ctx)
+
+	runCtx, cancel := a.budget.context(ctx)
+	defer cancel()
+	r := a.runner
+	if slices.ContainsFunc(msg.Parts, func(p *genai.Part) bool {
+		return p.FunctionResponse != nil && p.FunctionResponse.Name == sqlagent.ProposeWriteToolName
+	}) {
+		r = a.resumer
+	}
+	for event, err := range r.Run(runCtx, userID, sessionID, msg, agent.RunConfig{}) {
+		if err != nil && timedOut(runCtx) {
+			turn.Stopped = fmt.Sprintf("reached the time limit of %s", a.budget.MaxDuration)
+			break
+		}
+		if err != nil {
+			turn.Text = text.String()
+			return turn, err
+		}
+		if event == nil {
+			continue
+		}
+		if onEvent != nil {
+			onEvent(event)
+		}
+		if event.Author != author && event.Author != "user" {
+			author = event.Author
+			if !slices.Contains(turn.agents, author) {
+				turn.agents = append(turn.agents, author)
+			}
+			events.Publish(ctx, &events.AgentInvoked{Agent: author})
+		}
+		if u := event.LLMResponse.UsageMetadata; u != nil {
+			turn.Tokens.Prompt += int(u.PromptTokenCount)
+			turn.Tokens.Completion += int(u.CandidatesTokenCount)
+		}
+		if isModelResponse(event) {
+			llmCalls++
+		}
+		if event.LLMResponse.Content == nil {
+			continue
+		}
+
+		for _, part := range event.LLMResponse.Content.Parts {
+			if part.FunctionCall != nil {
+				trace.ToolCalled(event.Author, part.FunctionCall)
+				turn.ToolCalls = append(turn.ToolCalls, part.FunctionCall.Name)
+				events.Publish(ctx, &events.ToolCalled{
+					Agent: event.Author,
+					Tool:  part.FunctionCall.Name,
+					Args:  part.FunctionCall.Args,
+				})
+				switch part.FunctionCall.Name {
+				case "query_database", sqlagent.QueryParamsToolName, frame.ToolName:
+					sql, _ := part.FunctionCall.Args["sql"].(string)
+					pending[part.FunctionCall.ID] = len(turn.Queries)
+					turn.Queries = append(turn.Queries, Query{SQL: sql})
+				case sqlagent.ProposeWriteToolName:
+					if slices.Contains(event.LongRunningToolIDs, part.FunctionCall.ID) {
+						sql, _ := part.FunctionCall.Args["sql"].(string)
+						reason, _ := part.FunctionCall.Args["reason"].(string)
+						turn.Approvals = append(turn.Approvals, Approval{
+							CallID: part.FunctionCall.ID,
+							SQL:    sql,
+							Reason: reason,
+						})
+					}
+				case clarify.ToolName:
+					q := clarify.FromArgs(part.FunctionCall.Args)
+					turn.Clarification = &q
+				}
+			}
+			if part.FunctionResponse != nil {
+				trace.ToolAnswered(part.FunctionResponse)
+			}
+			if part.FunctionResponse != nil && (part.FunctionResponse.Name == "query_database" || part.FunctionResponse.Name == sqlagent.QueryParamsToolName || part.FunctionResponse.Name == frame.ToolName) {
+				idx, ok := pending[part.FunctionResponse.ID]
+				if !ok {
+					// Some providers omit call IDs; fall back to the oldest
+					// unanswered query, as parallel results arrive in order.
+					idx = slices.IndexFunc(turn.Queries, func(q Query) bool { return !q.answered })
+				}
+				delete(pending, part.FunctionResponse.ID)
+				if idx >= 0 {
+					turn.Queries[idx].answered = true
+					data, _ := part.FunctionResponse.Response["data"].(string)
+					errMsg, _ := part.FunctionResponse.Response["error"].(string)
+					if id, _ := part.FunctionResponse.Response["result_id"].(string); id != "" && a.results != nil {
+						if full, ok := a.results.Full(id); ok {
+							data = full
+						}
+					}
+					// A transformed result names the query it was made from
+					if sql, _ := part.FunctionResponse.Response["sql"].(string); sql != "" {
+						turn.Queries[idx].SQL = sql
+					}
+					turn.Queries[idx].Data = data
+					turn.Queries[idx].Error = errMsg
+				}
+			}
+			if part.Thought {
+				if a.keepReasoning && part.Text != "" {
+					turn.Reasoning = append(turn.Reasoning, Thought{Agent: event.Author, Text: part.Text})
+				}
+				continue
+			}
+			if part.Text != "" {
+				text.WriteString(part.Text)
+			}
+		}
+
+		// The budget is checked once tools have answered, before the model
+		// is called again, so the session never holds unanswered calls
+		if hasToolResponse(event) {
+			if reason := a.budget.exceeded(turn, llmCalls); reason != "" {
+				turn.Stopped = reason
+				break
+			}
+		}
+	}
+
+	turn.Text = style.Apply(text.String())
+	turn.Charts = chart.ExtractMermaid(turn.Text)
+	for _, c := range turn.Charts {
+		events.Publish(ctx, &events.ChartGenerated{Mermaid: c})
+	}
+	turn.Filters = a.applyFilters(userID, sessionID, turn)
+	return turn, nil
+}
+
+// Rows decodes a query_database JSON payload into ordered columns and
+// stringified rows. Column order follows the first row's keys as they appear
+// in the JSON document.
+func Rows(data string) ([]string, [][]string, error) {
+	if strings.TrimSpace(data) == "" || data == "null" {
+		return nil, nil, nil
+	}
+
+	var records []map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &records); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode rows: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, nil
+	}
+
+	columns, err := orderedKeys(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([][]string, 0, len(records))
+	for _, rec := range records {
+		row := make([]string, len(columns))
+		for i, col := range columns {
+			if v, ok := rec[col]; ok && v != nil {
+				row[i] = fmt.Sprint(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, nil
+}
+
+// orderedKeys returns the keys of the first object in a JSON array in
+// document order, which encoding/json maps do not preserve.
+func orderedKeys(data string) ([]string, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	// Consume '[' and '{'
+	for i := 0; i < 2; i++ {
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("failed to decode rows: %w", err)
+		}
+	}
+
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode rows: %w", err)
+		}
+		key, ok := tok.(string)
+		if !ok {
+			break
+		}
+		keys = append(keys, key)
+
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, fmt.Errorf("failed to decode rows: %w", err)
+		}
+	}
+	return keys, nil
+}
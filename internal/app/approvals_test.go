@@ -0,0 +1,80 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+// writeDB records the statements it executes.
+type writeDB struct {
+	sqlagent.MCPClient
+	mu       sync.Mutex
+	executed []string
+}
+
+func (db *writeDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.executed = append(db.executed, query)
+	return `{"rows_affected":1}`, nil
+}
+
+func TestResolve(t *testing.T) {
+	const proposed = "DELETE FROM orders WHERE id = 1"
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: sqlagent.ProposeWriteToolName, Respond: mockllm.Response{Text: "Done."}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: sqlagent.ProposeWriteToolName, Args: map[string]any{"sql": proposed, "reason": "cancelled"}}}}},
+	)
+	db := &writeDB{}
+	a := testutil.NewWriteApp(t, llm, db)
+	ctx := context.Background()
+
+	turn, err := a.Ask(ctx, "u", "s", "Delete order 1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(turn.Approvals) != 1 || turn.Approvals[0].SQL != proposed {
+		t.Fatalf("approvals = %+v, want %q", turn.Approvals, proposed)
+	}
+	callID := turn.Approvals[0].CallID
+
+	if _, err := a.Resolve(ctx, "u", "s", "unknown", true, nil); !errors.Is(err, app.ErrApprovalNotFound) {
+		t.Errorf("Resolve(unknown call) error = %v, want %v", err, app.ErrApprovalNotFound)
+	}
+	if _, err := a.Resolve(ctx, "u", "other", callID, true, nil); err == nil {
+		t.Error("Resolve() in another session succeeded")
+	}
+
+	approval, err := a.PendingApproval(ctx, "u", "s", callID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approval.SQL != proposed || approval.Reason != "cancelled" {
+		t.Errorf("PendingApproval() = %+v, want %q", approval, proposed)
+	}
+	if _, err := a.Resolve(ctx, "u", "s", callID, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{proposed}; !slices.Equal(db.executed, want) {
+		t.Errorf("executed %q, want %q", db.executed, want)
+	}
+
+	if _, err := a.Resolve(ctx, "u", "s", callID, true, nil); !errors.Is(err, app.ErrApprovalAnswered) {
+		t.Errorf("Resolve() twice error = %v, want %v", err, app.ErrApprovalAnswered)
+	}
+	if _, err := a.PendingApproval(ctx, "u", "s", callID); !errors.Is(err, app.ErrApprovalAnswered) {
+		t.Errorf("PendingApproval() after Resolve error = %v, want %v", err, app.ErrApprovalAnswered)
+	}
+	if len(db.executed) != 1 {
+		t.Errorf("executed %q, want the statement once", db.executed)
+	}
+}
@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+)
+
+// Formats a query result can be exported in.
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// contentTypes are the content types of the files turns produce.
+var contentTypes = map[string]string{
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatJSON: "application/json",
+	"mmd":      "text/vnd.mermaid; charset=utf-8",
+}
+
+// ExportFormat is the format an export step asks for: JSON if it says so,
+// CSV otherwise.
+func ExportFormat(task string) string {
+	if strings.Contains(strings.ToLower(task), FormatJSON) {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// Export encodes the result of a query as CSV or indented JSON.
+func (q Query) Export(format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		var v interface{}
+		if err := json.Unmarshal([]byte(q.Data), &v); err != nil {
+			return nil, fmt.Errorf("failed to decode result: %w", err)
+		}
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("json error: %w", err)
+		}
+		return append(out, '\n'), nil
+	case FormatCSV:
+		columns, rows, err := Rows(q.Data)
+		if err != nil {
+			return nil, err
+		}
+		var buf strings.Builder
+		w := csv.NewWriter(&buf)
+		w.Write(columns)
+		w.WriteAll(rows)
+		if err := w.Error(); err != nil {
+			return nil, fmt.Errorf("csv error: %w", err)
+		}
+		return []byte(buf.String()), nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// lastResult is the last query of a turn that returned data.
+func lastResult(turn *Turn) *Query {
+	for i := len(turn.Queries) - 1; i >= 0; i-- {
+		if turn.Queries[i].Data != "" {
+			return &turn.Queries[i]
+		}
+	}
+	return nil
+}
+
+// saveArtifacts stores the charts of a turn and carries out the export
+// steps of its plan, saving the last query result, and lists what was
+// stored in Turn.Artifacts. Saved export steps are marked done.
+func (a *App) saveArtifacts(ctx context.Context, userID, sessionID string, turn *Turn) {
+	if a.artifacts == nil || turn == nil {
+		return
+	}
+	// A cached answer lists the artifacts of whoever asked first
+	turn.Artifacts = nil
+	stamp := time.Now().Format("20060102-150405")
+	save := func(kind artifacts.Kind, name, ext string, data []byte) bool {
+		art, err := a.artifacts.Put(ctx, artifacts.Artifact{
+			Kind:        kind,
+			Name:        name + "." + ext,
+			ContentType: contentTypes[ext],
+			UserID:      userID,
+			SessionID:   sessionID,
+		}, data)
+		if err != nil {
+			console.Printf("  ⚠️  [ARTIFACTS] Failed to save %s: %v\n", kind, err)
+			return false
+		}
+		art.URL = artifacts.URL(a.artifacts, art)
+		turn.Artifacts = append(turn.Artifacts, art)
+		return true
+	}
+
+	for i, c := range turn.Charts {
+		save(artifacts.KindChart, fmt.Sprintf("chart-%s-%d", stamp, i+1), "mmd", []byte(c))
+	}
+
+	if turn.Routing == nil || turn.DryRun {
+		return
+	}
+	q := lastResult(turn)
+	if q == nil {
+		return
+	}
+	for i, step := range turn.Routing.Steps {
+		if step.Agent != manager.ExportStep || step.Status == manager.StepDone {
+			continue
+		}
+		format := ExportFormat(step.Task)
+		data, err := q.Export(format)
+		if err != nil {
+			console.Printf("  ⚠️  [ARTIFACTS] %s: %v\n", step.Task, err)
+			continue
+		}
+		if save(artifacts.KindExport, "result-"+stamp, format, data) {
+			turn.Routing.Steps[i].Status = manager.StepDone
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestArtifacts(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 4 orders.\n```mermaid\npie\n  \"orders\" : 4\n```"}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) FROM orders"}}}}},
+	)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantKinds []artifacts.Kind
+		wantData  string
+	}{
+		{name: "chart", query: "How many orders are there?", wantKinds: []artifacts.Kind{artifacts.KindChart}},
+		{name: "export", query: "Count the orders and export them to CSV", wantKinds: []artifacts.Kind{artifacts.KindChart, artifacts.KindExport}, wantData: "orders\n4\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := artifacts.NewDir(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, countDB{}), Artifacts: store})
+			if err != nil {
+				t.Fatal(err)
+			}
+			turn, err := a.Ask(context.Background(), "u", tt.name, tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(turn.Artifacts) != len(tt.wantKinds) {
+				t.Fatalf("artifacts = %+v, want %v", turn.Artifacts, tt.wantKinds)
+			}
+			for i, art := range turn.Artifacts {
+				stored, data, err := store.Get(context.Background(), art.ID)
+				if err != nil || art.Kind != tt.wantKinds[i] || stored.UserID != "u" || art.URL != artifacts.Path(art.ID) {
+					t.Fatalf("artifact %d = %+v (%v), want a stored %s of u", i, art, err, tt.wantKinds[i])
+				}
+				if art.Kind == artifacts.KindExport && string(data) != tt.wantData {
+					t.Errorf("export = %q, want %q", data, tt.wantData)
+				}
+			}
+			for _, step := range turn.Routing.Steps {
+				if step.Agent == manager.ExportStep && step.Status != manager.StepDone {
+					t.Errorf("export step %q not done", step.Task)
+				}
+			}
+		})
+	}
+}
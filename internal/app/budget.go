@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// Budget caps the work of a single turn, so a model that keeps calling
+// tools cannot run forever. Zero fields are unlimited.
+type Budget struct {
+	// MaxDuration bounds the wall-clock time of the turn
+	MaxDuration time.Duration
+	// MaxLLMCalls bounds the model responses of the turn, across agents
+	MaxLLMCalls int
+	// MaxToolCalls bounds the tool calls of the turn
+	MaxToolCalls int
+	// MaxTokens bounds the prompt and completion tokens of the turn
+	MaxTokens int
+}
+
+// errTimeBudget cancels a turn that ran out of time.
+var errTimeBudget = errors.New("turn time budget exhausted")
+
+// context returns a context that is cancelled once the turn runs out of
+// time.
+func (b Budget) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if b.MaxDuration <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, b.MaxDuration, errTimeBudget)
+}
+
+// timedOut reports whether the turn's context was cancelled by the time
+// budget rather than by the caller.
+func timedOut(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errTimeBudget)
+}
+
+// exceeded returns why the turn must stop, or "" if it is within budget.
+func (b Budget) exceeded(turn *Turn, llmCalls int) string {
+	switch {
+	case b.MaxLLMCalls > 0 && llmCalls >= b.MaxLLMCalls:
+		return fmt.Sprintf("reached the limit of %d LLM calls", b.MaxLLMCalls)
+	case b.MaxToolCalls > 0 && len(turn.ToolCalls) >= b.MaxToolCalls:
+		return fmt.Sprintf("reached the limit of %d tool calls", b.MaxToolCalls)
+	case b.MaxTokens > 0 && turn.Tokens.Prompt+turn.Tokens.Completion >= b.MaxTokens:
+		return fmt.Sprintf("reached the limit of %d tokens", b.MaxTokens)
+	}
+	return ""
+}
+
+// isModelResponse reports whether an event is a complete model response,
+// which counts as an LLM call.
+func isModelResponse(event *session.Event) bool {
+	return !event.Partial && event.LLMResponse.Content != nil && event.LLMResponse.Content.Role == genai.RoleModel
+}
+
+// hasToolResponse reports whether an event carries tool results, after
+// which the model would be called again.
+func hasToolResponse(event *session.Event) bool {
+	if event.LLMResponse.Content == nil {
+		return false
+	}
+	for _, part := range event.LLMResponse.Content.Parts {
+		if part.FunctionResponse != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// StopReason describes why the turn was cut short and what it did until
+// then, or returns "" if it ran to completion.
+func (t *Turn) StopReason() string {
+	if t == nil || t.Stopped == "" {
+		return ""
+	}
+	return fmt.Sprintf("Stopped early: %s after %d tool calls and %d queries.", t.Stopped, len(t.ToolCalls), len(t.Queries))
+}
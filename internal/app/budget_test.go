@@ -0,0 +1,66 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name        string
+		budget      app.Budget
+		wantStopped string
+		wantTools   int
+		wantQueries int
+	}{
+		{
+			name:        "tool calls",
+			budget:      app.Budget{MaxToolCalls: 4},
+			wantStopped: "limit of 4 tool calls",
+			wantTools:   4,
+			wantQueries: 3,
+		},
+		{
+			name:        "LLM calls",
+			budget:      app.Budget{MaxLLMCalls: 3},
+			wantStopped: "limit of 3 LLM calls",
+			wantTools:   3,
+			wantQueries: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The SQL agent never stops querying, like a confused local model
+			llm := mockllm.New(
+				mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+				mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) FROM orders"}}}}},
+			)
+			a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, countDB{}), Budget: tt.budget})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			turn, err := a.Ask(context.Background(), "u", "s", "How many orders are there?", nil)
+			if err != nil {
+				t.Fatalf("Ask() error = %v", err)
+			}
+			if !strings.Contains(turn.Stopped, tt.wantStopped) {
+				t.Errorf("Stopped = %q, want %q", turn.Stopped, tt.wantStopped)
+			}
+			if len(turn.ToolCalls) != tt.wantTools || len(turn.Queries) != tt.wantQueries {
+				t.Errorf("tool calls = %v, queries = %d, want %d and %d", turn.ToolCalls, len(turn.Queries), tt.wantTools, tt.wantQueries)
+			}
+			for _, q := range turn.Queries {
+				if q.Data == "" {
+					t.Errorf("query %q has no result", q.SQL)
+				}
+			}
+		})
+	}
+}
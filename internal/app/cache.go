@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+type freshKey struct{}
+
+// WithFresh returns a context whose questions are answered by the agents
+// even if the answer cache holds a similar one.
+func WithFresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshKey{}, true)
+}
+
+// IsFresh reports whether ctx asks for a fresh answer.
+func IsFresh(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshKey{}).(bool)
+	return fresh
+}
+
+// cachedAnswer returns the cached answer to a question similar to query,
+// or nil. The exchange is added to the session so follow-up questions can
+// refer to it.
+func (a *App) cachedAnswer(ctx context.Context, userID, sessionID, query string, routing *manager.Result) *Turn {
+	if a.cache == nil || IsFresh(ctx) || sqlagent.IsDryRun(ctx) {
+		return nil
+	}
+	turn, err := a.cache.Lookup(ctx, a.scope(userID), query)
+	if err != nil {
+		console.Printf("  ⚠️  [CACHE] Lookup failed: %v\n", err)
+		return nil
+	}
+	if turn == nil {
+		return nil
+	}
+
+	if err := a.appendExchange(ctx, userID, sessionID, query, turn.Text); err != nil {
+		console.Printf("  ⚠️  [CACHE] %v\n", err)
+		return nil
+	}
+	turn.Routing = routing
+	turn.Filters = a.applyFilters(userID, sessionID, turn)
+	return turn
+}
+
+// shouldCache reports whether the answer to the next question may be
+// cached. Only questions that open a session are cached, because follow-up
+// questions depend on the conversation before them; a fresh answer
+// replaces the cached answer it was asked instead of.
+func (a *App) shouldCache(ctx context.Context, userID, sessionID string) bool {
+	if a.cache == nil {
+		return false
+	}
+	if IsFresh(ctx) {
+		return true
+	}
+	resp, err := a.sessionService.Get(ctx, &session.GetRequest{AppName: AppName, UserID: userID, SessionID: sessionID})
+	return err == nil && resp.Session.Events().Len() == 0
+}
+
+// storeAnswer caches turn as userID's answer to query.
+func (a *App) storeAnswer(ctx context.Context, userID, query string, turn *Turn) {
+	if err := a.cache.Store(ctx, a.scope(userID), query, turn); err != nil {
+		console.Printf("  ⚠️  [CACHE] Failed to store answer: %v\n", err)
+	}
+}
+
+// scope is the partition of the answer cache serving userID.
+func (a *App) scope(userID string) string {
+	if a.cacheScope == nil {
+		return ""
+	}
+	return a.cacheScope(userID)
+}
+
+// appendExchange records a question and its cached answer in the session as
+// if the Manager had answered it.
+func (a *App) appendExchange(ctx context.Context, userID, sessionID, query, answer string) error {
+	resp, err := a.sessionService.Get(ctx, &session.GetRequest{AppName: AppName, UserID: userID, SessionID: sessionID})
+	if err != nil {
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+
+	invocationID := "cached-" + resp.Session.ID()
+	question := session.NewEvent(invocationID)
+	question.Author = "user"
+	question.LLMResponse.Content = genai.NewContentFromText(query, genai.RoleUser)
+
+	reply := session.NewEvent(invocationID)
+	reply.Author = a.manager.Name()
+	reply.LLMResponse.Content = genai.NewContentFromText(answer, genai.RoleModel)
+
+	for _, event := range []*session.Event{question, reply} {
+		if err := a.sessionService.AppendEvent(ctx, resp.Session, event); err != nil {
+			return fmt.Errorf("failed to record cached answer: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/semcache"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+	"google.golang.org/adk/model"
+)
+
+func TestAnswerCache(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", User: "orders", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Text: "You asked about orders."}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 4 orders."}},
+		mockllm.Rule{User: "orders", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) FROM orders"}}}}},
+	)
+	cache, err := semcache.Open(semcache.Config{Schema: "orders"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, countDB{}), Cache: cache})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	first, err := a.Ask(ctx, "u", "s1", "How many orders are there?", nil)
+	if err != nil || first.Cached != nil || cache.Len() != 1 {
+		t.Fatalf("first Ask() = %+v, %v; cached %d", first, err, cache.Len())
+	}
+	requests := len(llm.Requests())
+
+	// A rewording in another session is answered without the model
+	again, err := a.Ask(ctx, "u", "s2", "how many orders are there", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Cached == nil || again.Text != first.Text || len(llm.Requests()) != requests {
+		t.Errorf("second Ask() = %+v, want the cached answer without model requests", again)
+	}
+
+	// The cached exchange is part of the session's history
+	follow, err := a.Ask(ctx, "u", "s2", "What did I ask?", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := llm.Requests()
+	if follow.Cached != nil || !hasText(reqs[len(reqs)-1], first.Text) {
+		t.Errorf("follow-up did not see the cached answer in its history")
+	}
+
+	// WithFresh runs the agents again
+	fresh, err := a.Ask(app.WithFresh(ctx), "u", "s3", "How many orders are there?", nil)
+	if err != nil || fresh.Cached != nil || len(fresh.Queries) != 1 {
+		t.Errorf("fresh Ask() = %+v, %v, want a recomputed answer", fresh, err)
+	}
+}
+
+func hasText(req *model.LLMRequest, text string) bool {
+	for _, c := range req.Contents {
+		for _, p := range c.Parts {
+			if strings.Contains(p.Text, text) {
+				return true
+			}
+		}
+	}
+	return false
+}
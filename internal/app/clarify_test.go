@@ -0,0 +1,98 @@
+package app_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/clarify"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestClarification(t *testing.T) {
+	ask := mockllm.Call{Name: clarify.ToolName, Args: map[string]any{
+		"question": "By revenue, do you mean order totals or payments received?",
+		"options":  []any{"Order totals", "Payments received"},
+	}}
+	llm := mockllm.New(
+		// Unclear questions are flagged to the Manager, which asks itself
+		mockllm.Rule{Agent: "manager agent", User: "intent of this question is unclear", Respond: mockllm.Response{Calls: []mockllm.Call{ask}}},
+		mockllm.Rule{Agent: "manager agent", User: "payments received", Respond: mockllm.Response{Text: "Revenue from payments was 1,200."}},
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: clarify.ToolName, Respond: mockllm.Response{Text: "Summarized the question instead of asking it."}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{ask}}},
+	)
+
+	tools, err := clarify.Tools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlAgent, err := sqlagent.New(sqlagent.Config{Model: llm, Tools: tools, Clarify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartAgent, err := chart.New(chart.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := manager.New(manager.Config{Model: llm, SQLAgent: sqlAgent, ChartAgent: chartAgent, Tools: tools})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		clarifyBelow float64
+		question     string
+		wantCalls    []string
+	}{
+		{
+			name:      "SQL agent finds several matching columns",
+			question:  "Show me the revenue by region",
+			wantCalls: []string{"transfer_to_agent", clarify.ToolName},
+		},
+		{
+			name:         "low classifier confidence",
+			clarifyBelow: 1.01,
+			question:     "revenue?",
+			wantCalls:    []string{clarify.ToolName},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := app.New(app.Config{Manager: mgr, ClarifyBelow: tt.clarifyBelow})
+			if err != nil {
+				t.Fatal(err)
+			}
+			turn, err := a.Ask(context.Background(), "u", tt.name, tt.question, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(turn.ToolCalls, tt.wantCalls) {
+				t.Errorf("tool calls = %v, want %v", turn.ToolCalls, tt.wantCalls)
+			}
+			// The question ends the turn without a summary by the model
+			if turn.Text != "" || len(turn.Queries) != 0 {
+				t.Errorf("turn = %+v, want only the question", turn)
+			}
+			c := turn.Clarification
+			if c == nil || !strings.HasPrefix(c.Question, "By revenue") || len(c.Options) != 2 {
+				t.Fatalf("clarification = %+v, want the question and two options", c)
+			}
+
+			// The reply is the next question of the session
+			turn, err = a.Ask(context.Background(), "u", tt.name, c.Answer("2"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if turn.Clarification != nil || !strings.Contains(turn.Text, "payments") {
+				t.Errorf("answered turn = %+v, want an answer", turn)
+			}
+		})
+	}
+}
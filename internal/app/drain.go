@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDraining is returned for turns started after Drain was called.
+var ErrDraining = errors.New("shutting down: not accepting new questions")
+
+// cancelGrace bounds how long Drain waits for turns to return once it has
+// cancelled them.
+const cancelGrace = 5 * time.Second
+
+// begin registers an in-flight turn. The returned context is cancelled if
+// Drain gives up waiting; call done when the turn has returned.
+func (a *App) begin(ctx context.Context) (context.Context, func(), error) {
+	a.mu.Lock()
+	if a.draining {
+		a.mu.Unlock()
+		return nil, nil, ErrDraining
+	}
+	a.active++
+	a.inflight.Add(1)
+	a.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(a.abort, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		a.mu.Lock()
+		a.active--
+		a.mu.Unlock()
+		a.inflight.Done()
+	}, nil
+}
+
+// Draining reports whether Drain was called, so new work is refused.
+func (a *App) Draining() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.draining
+}
+
+// Drain stops accepting turns and waits for the turns in flight, with their
+// queries, to finish. When ctx is done first, the remaining turns are
+// cancelled and given a few seconds to return.
+func (a *App) Drain(ctx context.Context) error {
+	a.mu.Lock()
+	a.draining = true
+	a.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		a.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	a.mu.Lock()
+	n := a.active
+	a.mu.Unlock()
+	a.abortTurns()
+	select {
+	case <-done:
+		return fmt.Errorf("cancelled %d turn(s) still running at the drain deadline", n)
+	case <-time.After(cancelGrace):
+		return fmt.Errorf("%d turn(s) did not return after being cancelled", n)
+	}
+}
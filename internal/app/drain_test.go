@@ -0,0 +1,94 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+// slowDB holds every query until release is closed or its context is done.
+type slowDB struct {
+	sqlagent.MCPClient
+	started chan struct{}
+	release chan struct{}
+}
+
+func (db slowDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	db.started <- struct{}{}
+	select {
+	case <-db.release:
+		return `[{"orders":4}]`, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name     string
+		finishes bool
+		wantErr  bool
+	}{
+		{name: "turn finishes", finishes: true},
+		{name: "turn cancelled at the deadline", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := mockllm.New(
+				mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+				mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 4 orders."}},
+				mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) FROM orders"}}}}},
+			)
+			db := slowDB{started: make(chan struct{}, 1), release: make(chan struct{})}
+			a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, db)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			type result struct {
+				turn *app.Turn
+				err  error
+			}
+			asked := make(chan result, 1)
+			go func() {
+				turn, err := a.Ask(context.Background(), "u", "s", "How many orders are there?", nil)
+				asked <- result{turn, err}
+			}()
+			<-db.started
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			drained := make(chan error, 1)
+			go func() { drained <- a.Drain(ctx) }()
+
+			// New questions are refused while the turn in flight finishes
+			for !a.Draining() {
+				time.Sleep(time.Millisecond)
+			}
+			if _, err := a.Ask(context.Background(), "u", "other", "How many orders are there?", nil); !errors.Is(err, app.ErrDraining) {
+				t.Errorf("Ask() while draining error = %v, want ErrDraining", err)
+			}
+			if tt.finishes {
+				close(db.release)
+			}
+
+			if err := <-drained; (err != nil) != tt.wantErr {
+				t.Errorf("Drain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			r := <-asked
+			if tt.finishes && (r.err != nil || r.turn.Text != "There are 4 orders.") {
+				t.Errorf("Ask() = %+v, %v; want the answer", r.turn, r.err)
+			}
+			if !tt.finishes && (r.turn == nil || len(r.turn.Queries) != 1 || r.turn.Queries[0].Error == "") {
+				t.Errorf("Ask() = %+v, %v; want the query cancelled", r.turn, r.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package app_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestEvents(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 4 orders."}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) FROM orders"}}}}},
+	)
+	bus := events.New()
+	var mu sync.Mutex
+	var got []string
+	bus.Subscribe(func(e events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		name := e.Kind()
+		switch e := e.(type) {
+		case *events.AgentInvoked:
+			name += " " + e.Agent
+		case *events.ToolCalled:
+			name += " " + e.Tool
+		case *events.SQLExecuted:
+			if e.UserID != "u" || e.SessionID != "s" || e.RowCount != 1 {
+				t.Errorf("sql_executed = %+v, want 1 row for u/s", e)
+			}
+		}
+		got = append(got, name)
+	})
+	metrics := events.NewMetrics()
+	bus.Subscribe(metrics.Handle)
+
+	a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, countDB{}), Events: bus})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Ask(context.Background(), "u", "s", "How many orders are there?", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"turn_started",
+		"intent_classified",
+		"agent_invoked ManagerAgent",
+		"tool_called transfer_to_agent",
+		"agent_invoked SQLAgent",
+		"tool_called query_database",
+		"sql_executed",
+		"turn_completed",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("events =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+
+	snap := metrics.Snapshot()
+	if snap.Turns != 1 || snap.Queries != 1 || snap.Tools["query_database"] != 1 || snap.Agents["SQLAgent"] != 1 {
+		t.Errorf("metrics = %+v", snap)
+	}
+}
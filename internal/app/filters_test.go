@@ -0,0 +1,77 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/filters"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+// countDB answers every query with a single count.
+type countDB struct {
+	sqlagent.MCPClient
+}
+
+func (countDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	return `[{"orders":4}]`, nil
+}
+
+func TestFilterScope(t *testing.T) {
+	query := func(sql string) mockllm.Response {
+		return mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": sql}}}}
+	}
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "Done."}},
+		mockllm.Rule{User: "emea", Respond: query("SELECT count(*) AS orders FROM orders WHERE region = 'EMEA'")},
+		mockllm.Rule{User: "last 90 days", Respond: query("SELECT count(*) AS orders FROM orders WHERE region = 'EMEA' AND order_date >= CURRENT_DATE - INTERVAL '90 days'")},
+		mockllm.Rule{User: "all orders", Respond: query("SELECT count(*) AS orders FROM orders")},
+	)
+	a := testutil.NewApp(t, llm, countDB{})
+	ctx := context.Background()
+
+	steps := []struct {
+		question string
+		clear    bool
+		want     string
+	}{
+		{question: "How many orders in EMEA?", want: "region = EMEA"},
+		{question: "And in the last 90 days?", want: "region = EMEA, order_date: last 90 days"},
+		{question: "How many orders in all orders?", want: "region = EMEA, order_date: last 90 days"},
+		{question: "How many orders in all orders?", clear: true, want: ""},
+	}
+	for _, step := range steps {
+		if step.clear {
+			a.ClearFilters("u", "s")
+		}
+		turn, err := a.Ask(ctx, "u", "s", step.question, nil)
+		if err != nil {
+			t.Fatalf("Ask(%q) error = %v", step.question, err)
+		}
+		if got := filters.Format(turn.Filters); got != step.want {
+			t.Errorf("Ask(%q) filters = %q, want %q", step.question, got, step.want)
+		}
+	}
+
+	// Only the question after ClearFilters tells the agents
+	var notes int
+	for _, req := range llm.Requests() {
+		for _, c := range req.Contents {
+			for _, p := range c.Parts {
+				if strings.Contains(p.Text, "cleared all filters") {
+					notes++
+				}
+			}
+		}
+	}
+	if notes == 0 {
+		t.Error("the question after ClearFilters does not mention it")
+	}
+	if got := a.Filters("u", "s"); len(got) != 0 {
+		t.Errorf("Filters() = %v after clearing, want none", got)
+	}
+}
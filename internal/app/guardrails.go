@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/guardrails"
+)
+
+// withheldData replaces query results whose redaction left invalid JSON.
+const withheldData = "result withheld by the content policy"
+
+// guard applies the content rules to the text and query results of a turn
+// before it is returned. If a rule blocks any of them, or they could not be
+// checked, the whole answer is replaced by the rule's message and its
+// charts and results are dropped.
+func (a *App) guard(ctx context.Context, turn *Turn) {
+	if a.guardrails == nil || turn == nil {
+		return
+	}
+	check := func(text string) (guardrails.Result, bool) {
+		res, err := a.guardrails.CheckResponse(ctx, text)
+		if err != nil {
+			console.Printf("  ⚠️  [GUARDRAIL] %v\n", err)
+		}
+		guardrails.Publish(ctx, res)
+		turn.Guardrails = append(turn.Guardrails, res.Violations...)
+		if res.Blocked {
+			withhold(turn, res.Text)
+		}
+		return res, !res.Blocked
+	}
+
+	res, ok := check(turn.Text)
+	if !ok {
+		return
+	}
+	if res.Text != turn.Text {
+		turn.Text = res.Text
+		turn.Charts = chart.ExtractMermaid(turn.Text)
+	}
+	for i := range turn.Queries {
+		q := &turn.Queries[i]
+		if q.Data == "" {
+			continue
+		}
+		res, ok := check(q.Data)
+		if !ok {
+			return
+		}
+		if res.Text != q.Data && !json.Valid([]byte(res.Text)) {
+			q.Data, q.Error = "", withheldData
+			continue
+		}
+		q.Data = res.Text
+	}
+}
+
+// withhold replaces the answer of a turn by message.
+func withhold(turn *Turn, message string) {
+	turn.Text, turn.Charts = message, nil
+	for i := range turn.Queries {
+		if turn.Queries[i].Data != "" {
+			turn.Queries[i].Data, turn.Queries[i].Error = "", withheldData
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/guardrails"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestGuardrails(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 4 orders, call 555-0100."}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) AS orders FROM orders"}}}}},
+	)
+
+	tests := []struct {
+		name      string
+		rule      guardrails.Rule
+		wantText  string
+		wantData  string
+		wantRules int
+	}{
+		{
+			name:      "redacted answer",
+			rule:      guardrails.Rule{Name: "phone", Pattern: `\d{3}-\d{4}`, Action: guardrails.ActionRedact},
+			wantText:  "There are 4 orders, call [REDACTED].",
+			wantData:  `[{"orders":4}]`,
+			wantRules: 1,
+		},
+		{
+			name:      "blocked result",
+			rule:      guardrails.Rule{Name: "orders", Pattern: `"orders"`, AppliesTo: []guardrails.Target{guardrails.TargetResponse}, Message: "Withheld."},
+			wantText:  "Withheld.",
+			wantRules: 1,
+		},
+		{
+			name:     "rules for tool arguments leave the answer alone",
+			rule:     guardrails.Rule{Name: "orders", Pattern: `orders`, AppliesTo: []guardrails.Target{guardrails.TargetToolArgs}},
+			wantText: "There are 4 orders, call 555-0100.",
+			wantData: `[{"orders":4}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := guardrails.New(guardrails.Config{Policy: &guardrails.Policy{Rules: []guardrails.Rule{tt.rule}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, countDB{}), Guardrails: g})
+			if err != nil {
+				t.Fatal(err)
+			}
+			turn, err := a.Ask(context.Background(), "u", tt.name, "How many orders are there?", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if turn.Text != tt.wantText {
+				t.Errorf("Ask() text = %q, want %q", turn.Text, tt.wantText)
+			}
+			if len(turn.Queries) != 1 || turn.Queries[0].Data != tt.wantData {
+				t.Errorf("Ask() queries = %+v, want data %q", turn.Queries, tt.wantData)
+			}
+			if len(turn.Guardrails) != tt.wantRules {
+				t.Errorf("Ask() guardrails = %+v, want %d", turn.Guardrails, tt.wantRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package app_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+// TestIntegration runs a conversation through the real agents and a seeded
+// PostgreSQL database, with the model replaced by testdata/store.yaml.
+func TestIntegration(t *testing.T) {
+	url := testutil.StartPostgres(t)
+	testutil.Seed(t, url)
+
+	db, err := sqlagent.NewDirectMCPClient(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	llm, err := mockllm.Load("testdata/store.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := testutil.NewApp(t, llm, db)
+
+	// The steps share a session, so the chart is drawn from the revenue
+	// fetched by the step before it.
+	steps := []struct {
+		question      string
+		wantToolCalls []string
+		wantRows      [][]string // rows of the turn's query, nil if none
+		wantCharts    int
+	}{
+		{
+			question:      "How many orders per region?",
+			wantToolCalls: []string{"transfer_to_agent", "query_database"},
+			wantRows:      [][]string{{"AMER", "3"}, {"APAC", "3"}, {"EMEA", "4"}},
+		},
+		{
+			question:      "What was the revenue by month?",
+			wantToolCalls: []string{"transfer_to_agent", "query_database"},
+			wantRows:      [][]string{{"2024-01", "200.00"}, {"2024-02", "230.00"}, {"2024-03", "530.00"}},
+		},
+		{
+			question:      "Now show that as a chart",
+			wantToolCalls: []string{"transfer_to_agent"},
+			wantCharts:    1,
+		},
+	}
+
+	for _, step := range steps {
+		turn, err := a.Ask(context.Background(), "user", "store", step.question, nil)
+		if err != nil {
+			t.Fatalf("Ask(%q) error = %v", step.question, err)
+		}
+		if !slices.Equal(turn.ToolCalls, step.wantToolCalls) {
+			t.Errorf("Ask(%q) tool calls = %v, want %v", step.question, turn.ToolCalls, step.wantToolCalls)
+		}
+		if turn.Text == "" {
+			t.Errorf("Ask(%q) returned no text", step.question)
+		}
+		if len(turn.Charts) != step.wantCharts {
+			t.Errorf("Ask(%q) charts = %d, want %d", step.question, len(turn.Charts), step.wantCharts)
+		}
+
+		if step.wantRows == nil {
+			if len(turn.Queries) != 0 {
+				t.Errorf("Ask(%q) queries = %+v, want none", step.question, turn.Queries)
+			}
+			continue
+		}
+		if len(turn.Queries) != 1 || turn.Queries[0].Error != "" {
+			t.Fatalf("Ask(%q) queries = %+v, want one successful query", step.question, turn.Queries)
+		}
+		_, rows, err := app.Rows(turn.Queries[0].Data)
+		if err != nil {
+			t.Fatalf("Rows() error = %v", err)
+		}
+		if !slices.EqualFunc(rows, step.wantRows, slices.Equal) {
+			t.Errorf("Ask(%q) rows = %v, want %v", step.question, rows, step.wantRows)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"google.golang.org/genai"
+)
+
+// stepResultRunes bounds the outcome of a step passed to the steps after it.
+// The session holds the complete results.
+const stepResultRunes = 500
+
+// execute plans a question as steps and runs each step as a turn of its
+// own, telling the Manager the outcome of the steps before it. Failed steps
+// are recovered from as the policy of their step type says. request is the
+// question as the agents read it; text adds the notes for the first step.
+func (a *App) execute(ctx context.Context, userID, sessionID, request, text string, routing *manager.Result, onEvent EventHandler) (*Turn, error) {
+	steps, err := a.plan(ctx, request, routing)
+	if err != nil {
+		return nil, err
+	}
+	publishPlan(ctx, steps, 0)
+
+	turn := &Turn{Routing: routing, DryRun: sqlagent.IsDryRun(ctx)}
+	defer func() { setPlan(routing, steps) }()
+	var results []string
+	replans := 0
+	for i := 0; i < len(steps); i++ {
+		// The front-end saves the result once the turn is over
+		if steps[i].Agent == manager.ExportStep {
+			results = append(results, "saved to a file after the answer")
+			continue
+		}
+		msg := request
+		if i == 0 {
+			msg = text
+		}
+		msg += "\n\n" + manager.StepNote(steps, i, results)
+		policy := a.policies.For(StepType(steps[i].Agent))
+
+		var step *Turn
+		var failure string
+		var recovery Recovery
+		for attempt := 1; ; attempt++ {
+			retry := msg
+			if attempt > 1 {
+				retry += "\n\n" + manager.RetryNote(failure)
+			}
+			events.Publish(ctx, &events.StepStarted{Step: i + 1, Steps: len(steps), Agent: steps[i].Agent, Task: steps[i].Task, Attempt: attempt})
+			start := time.Now()
+			step, err = a.run(ctx, userID, sessionID, genai.NewContentFromText(retry, genai.RoleUser), routing, onEvent)
+			failure = stepFailure(steps[i], step, err)
+			recovery = a.recovery(policy, failure, attempt, replans, step, err)
+			events.Publish(ctx, &events.StepCompleted{
+				Step:       i + 1,
+				Agent:      steps[i].Agent,
+				Attempt:    attempt,
+				DurationMS: time.Since(start).Milliseconds(),
+				Error:      failure,
+				Recovery:   string(recovery),
+			})
+			// The answer of an attempt made again is superseded
+			if recovery == RecoverRetry {
+				step.Text, step.Charts = "", nil
+			}
+			merge(turn, step)
+			if recovery != RecoverRetry {
+				break
+			}
+		}
+
+		switch recovery {
+		case "":
+			steps[i].Status = manager.StepDone
+			results = append(results, truncateRunes(step.Text, stepResultRunes))
+			// The rest of the plan waits for the user
+			if step.Clarification != nil || len(step.Approvals) > 0 {
+				return turn, nil
+			}
+		case RecoverSkip:
+			steps[i].Status, steps[i].Error = manager.StepSkipped, failure
+			results = append(results, "skipped after failing: "+failure)
+		case RecoverReplan:
+			steps[i].Status, steps[i].Error = manager.StepFailed, failure
+			results = append(results, "failed: "+failure)
+			replans++
+			rest, err := a.manager.Replan(ctx, request, steps[:i+1])
+			if err != nil {
+				console.Printf("  ⚠️  [PLAN] %v\n", err)
+				return turn, nil
+			}
+			steps = append(steps[:i+1], rest...)
+			publishPlan(ctx, rest, i+2)
+		default:
+			steps[i].Status, steps[i].Error = manager.StepFailed, failure
+			return turn, err
+		}
+	}
+	return turn, nil
+}
+
+// plan asks the Manager for the steps of a question, as often as the
+// StepPlan policy allows. If they all fail the classifier's steps are used,
+// unless the policy aborts.
+func (a *App) plan(ctx context.Context, request string, routing *manager.Result) ([]manager.Step, error) {
+	policy := a.policies.For(StepPlan)
+	var err error
+	for attempt := 0; attempt <= policy.Retries; attempt++ {
+		var steps []manager.Step
+		if steps, err = a.manager.Plan(ctx, request); err == nil {
+			return steps, nil
+		}
+		console.Printf("  ⚠️  [PLAN] %v\n", err)
+	}
+	if policy.OnFailure == RecoverAbort {
+		return nil, fmt.Errorf("failed to plan the question: %w", err)
+	}
+	console.Printf("  ⚠️  [PLAN] Carrying out the question as classified\n")
+	return manager.StepsOf(routing), nil
+}
+
+// recovery decides what follows a step: nothing if it succeeded, another
+// attempt while the policy allows, or else the policy's recovery. A step
+// that failed to run or reached the budget aborts the plan, as does one to
+// replan after MaxReplans.
+func (a *App) recovery(policy StepPolicy, failure string, attempt, replans int, step *Turn, err error) Recovery {
+	switch {
+	case failure == "":
+		return ""
+	case err != nil || step.Stopped != "":
+		return RecoverAbort
+	case attempt <= policy.Retries:
+		return RecoverRetry
+	case policy.OnFailure == RecoverReplan && replans >= a.maxReplans:
+		return RecoverAbort
+	}
+	return policy.OnFailure
+}
+
+// publishPlan publishes the steps of a plan, numbered from first if they
+// replace the steps after a failed one.
+func publishPlan(ctx context.Context, steps []manager.Step, first int) {
+	plan := &events.PlanMade{Replanned: first > 0, First: first}
+	for _, s := range steps {
+		plan.Agents = append(plan.Agents, s.Agent)
+		plan.Tasks = append(plan.Tasks, s.Task)
+	}
+	events.Publish(ctx, plan)
+}
+
+// setPlan makes steps the planned workflow of a routing.
+func setPlan(routing *manager.Result, steps []manager.Step) {
+	routing.Steps = steps
+	routing.Workflow = manager.WorkflowPlan
+	routing.AgentsUsed = nil
+	for _, s := range steps {
+		routing.AgentsUsed = append(routing.AgentsUsed, s.Agent)
+	}
+}
+
+// stepFailure says why a step failed: its run failed or was cut short,
+// every query it ran failed, or a chart step drew no chart. It returns ""
+// for a step that succeeded.
+func stepFailure(s manager.Step, step *Turn, err error) string {
+	switch {
+	case err != nil:
+		return err.Error()
+	case step.Stopped != "":
+		return "stopped: " + step.Stopped
+	case s.Agent == "ChartAgent" && len(step.Charts) == 0 && step.Clarification == nil && !step.DryRun:
+		return "no chart was drawn"
+	}
+	for _, q := range step.Queries {
+		if q.Error == "" {
+			return ""
+		}
+	}
+	if n := len(step.Queries); n > 0 {
+		return step.Queries[n-1].Error
+	}
+	return ""
+}
+
+// merge adds the outcome of a step to the turn of the whole plan.
+func merge(turn, step *Turn) {
+	if step == nil {
+		return
+	}
+	if turn.Text != "" && step.Text != "" {
+		turn.Text += "\n\n"
+	}
+	turn.Text += step.Text
+	for _, agent := range step.agents {
+		if !slices.Contains(turn.agents, agent) {
+			turn.agents = append(turn.agents, agent)
+		}
+	}
+	turn.ToolCalls = append(turn.ToolCalls, step.ToolCalls...)
+	turn.Queries = append(turn.Queries, step.Queries...)
+	turn.Charts = append(turn.Charts, step.Charts...)
+	turn.Approvals = append(turn.Approvals, step.Approvals...)
+	turn.Reasoning = append(turn.Reasoning, step.Reasoning...)
+	turn.Tokens.Prompt += step.Tokens.Prompt
+	turn.Tokens.Completion += step.Tokens.Completion
+	turn.Clarification = step.Clarification
+	turn.Filters = step.Filters
+	turn.Stopped = step.Stopped
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,206 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+// revenueDB has no payments table.
+type revenueDB struct {
+	sqlagent.MCPClient
+}
+
+func (revenueDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	if strings.Contains(query, "payments") {
+		return "", errors.New(`relation "payments" does not exist`)
+	}
+	return `[{"month":"2024-01","revenue":200},{"month":"2024-02","revenue":230}]`, nil
+}
+
+func TestPlanExecute(t *testing.T) {
+	transfer := func(agent string) mockllm.Response {
+		return mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": agent}}}}
+	}
+	query := func(sql string) mockllm.Response {
+		return mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": sql}}}}
+	}
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "these steps were already carried out", Respond: mockllm.Response{
+			Text: `{"steps": [{"agent": "SQLAgent", "task": "get monthly revenue from orders"}, {"agent": "ChartAgent", "task": "chart it"}]}`,
+		}},
+		mockllm.Rule{Agent: "you plan how", Respond: mockllm.Response{
+			Text: `{"steps": [{"agent": "SQLAgent", "task": "get monthly revenue from payments"}, {"agent": "ChartAgent", "task": "chart it"}]}`,
+		}},
+		mockllm.Rule{Agent: "manager agent", User: "delegate it to SQLAgent", Respond: transfer("SQLAgent")},
+		mockllm.Rule{Agent: "manager agent", User: "delegate it to ChartAgent", Respond: transfer("ChartAgent")},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "Fetched the monthly revenue."}},
+		mockllm.Rule{User: "step 1 of 2", Respond: query("SELECT month, sum(amount) AS revenue FROM payments GROUP BY month")},
+		mockllm.Rule{User: "step 2 of 3", Respond: query("SELECT month, sum(total) AS revenue FROM orders GROUP BY month")},
+		mockllm.Rule{User: "step 3 of 3", Respond: mockllm.Response{Text: "```mermaid\npie\n\"2024-01\" : 200\n\"2024-02\" : 230\n```"}},
+	)
+	bus := events.New()
+	var got []string
+	bus.Subscribe(func(e events.Event) {
+		switch e := e.(type) {
+		case *events.PlanMade:
+			got = append(got, e.Kind()+" "+strings.Join(e.Agents, ","))
+		case *events.StepCompleted:
+			got = append(got, e.Kind()+" "+e.Agent+" "+e.Error)
+		}
+	})
+
+	a, err := app.New(app.Config{
+		Manager:      testutil.NewManager(t, llm, revenueDB{}),
+		Events:       bus,
+		PlanExecute:  true,
+		MaxReplans:   1,
+		StepPolicies: app.StepPolicies{app.StepAny: {OnFailure: app.RecoverReplan}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	turn, err := a.Ask(context.Background(), "u", "s", "Chart the monthly revenue", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"plan_made SQLAgent,ChartAgent",
+		`step_completed SQLAgent relation "payments" does not exist`,
+		"plan_made SQLAgent,ChartAgent",
+		"step_completed SQLAgent ",
+		"step_completed ChartAgent ",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("events =\n  %s\nwant\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+
+	r := turn.Routing
+	if r.Workflow != manager.WorkflowPlan || len(r.Steps) != 3 {
+		t.Fatalf("routing = %+v, want the plan of three steps", r)
+	}
+	for i, status := range []string{manager.StepFailed, manager.StepDone, manager.StepDone} {
+		if r.Steps[i].Status != status {
+			t.Errorf("step %d = %+v, want %s", i+1, r.Steps[i], status)
+		}
+	}
+	if len(turn.Queries) != 2 || turn.Queries[0].Error == "" || turn.Queries[1].Error != "" || len(turn.Charts) != 1 {
+		t.Errorf("turn = %+v, want the failed and the replanned query and a chart", turn)
+	}
+}
+
+func TestStepPolicies(t *testing.T) {
+	tests := []struct {
+		name         string
+		question     string
+		policies     app.StepPolicies
+		wantStatuses []string
+		wantQueries  int
+		wantErr      bool
+	}{
+		{
+			name:         "retry fixes the query",
+			question:     "Chart the monthly revenue",
+			policies:     app.StepPolicies{"sql": {Retries: 1}},
+			wantStatuses: []string{manager.StepDone, manager.StepDone},
+			wantQueries:  2,
+		},
+		{
+			name:         "skip",
+			question:     "Chart the monthly revenue",
+			policies:     app.StepPolicies{"sql": {OnFailure: app.RecoverSkip}},
+			wantStatuses: []string{manager.StepSkipped, manager.StepDone},
+			wantQueries:  1,
+		},
+		{
+			name:         "abort",
+			question:     "Chart the monthly revenue",
+			policies:     app.StepPolicies{"sql": {OnFailure: app.RecoverAbort}},
+			wantStatuses: []string{manager.StepFailed, ""},
+			wantQueries:  1,
+		},
+		{
+			name:         "unplanned question as classified",
+			question:     "Write me a poem",
+			policies:     app.DefaultStepPolicies(),
+			wantStatuses: []string{manager.StepDone},
+		},
+		{
+			name:     "unplanned question aborted",
+			question: "Write me a poem",
+			policies: app.StepPolicies{app.StepPlan: {Retries: 1, OnFailure: app.RecoverAbort}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfer := func(agent string) mockllm.Response {
+				return mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": agent}}}}
+			}
+			query := func(sql string) mockllm.Response {
+				return mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": sql}}}}
+			}
+			llm := mockllm.New(
+				mockllm.Rule{Agent: "you plan how", User: "poem", Respond: mockllm.Response{Error: "planner unavailable"}},
+				mockllm.Rule{Agent: "you plan how", Respond: mockllm.Response{
+					Text: `{"steps": [{"agent": "SQLAgent", "task": "get monthly revenue from payments"}, {"agent": "ChartAgent", "task": "chart it"}]}`,
+				}},
+				mockllm.Rule{Agent: "manager agent", User: "answer it yourself", Respond: mockllm.Response{Text: "Roses are red."}},
+				mockllm.Rule{Agent: "manager agent", User: "delegate it to SQLAgent", Respond: transfer("SQLAgent")},
+				mockllm.Rule{Agent: "manager agent", User: "delegate it to ChartAgent", Respond: transfer("ChartAgent")},
+				mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "Fetched the monthly revenue."}},
+				mockllm.Rule{User: "previous attempt at this step failed: relation", Respond: query("SELECT month, sum(total) AS revenue FROM orders GROUP BY month")},
+				mockllm.Rule{User: "step 1 of 2", Respond: query("SELECT month, sum(amount) AS revenue FROM payments GROUP BY month")},
+				mockllm.Rule{User: "step 2 of 2", Respond: mockllm.Response{Text: "```mermaid\npie\n\"2024-01\" : 200\n```"}},
+			)
+			a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, revenueDB{}), PlanExecute: true, StepPolicies: tt.policies})
+			if err != nil {
+				t.Fatal(err)
+			}
+			turn, err := a.Ask(context.Background(), "u", "s", tt.question, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Ask() = %+v, want an error", turn)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var statuses []string
+			for _, s := range turn.Routing.Steps {
+				statuses = append(statuses, s.Status)
+			}
+			if !slices.Equal(statuses, tt.wantStatuses) {
+				t.Errorf("step statuses = %q, want %q", statuses, tt.wantStatuses)
+			}
+			if len(turn.Queries) != tt.wantQueries {
+				t.Errorf("queries = %+v, want %d", turn.Queries, tt.wantQueries)
+			}
+			// The steps after a skipped one are told why it failed
+			if tt.wantStatuses[0] == manager.StepSkipped {
+				reqs := llm.Requests()
+				var text strings.Builder
+				for _, c := range reqs[len(reqs)-1].Contents {
+					for _, p := range c.Parts {
+						text.WriteString(p.Text)
+					}
+				}
+				if !strings.Contains(text.String(), `skipped after failing: relation "payments" does not exist`) {
+					t.Errorf("chart step request = %q, want the failure of step 1", text.String())
+				}
+			}
+		})
+	}
+}
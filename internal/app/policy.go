@@ -0,0 +1,77 @@
+package app
+
+import "strings"
+
+// Recovery is what a plan does after a step failed.
+type Recovery string
+
+// Recoveries from a failed step.
+const (
+	// RecoverRetry runs the step again, told why it failed.
+	RecoverRetry Recovery = "retry"
+	// RecoverReplan plans the steps after it again, up to MaxReplans
+	// times a question, and aborts after that.
+	RecoverReplan Recovery = "replan"
+	// RecoverSkip goes on with the next step, told of the failure.
+	RecoverSkip Recovery = "skip"
+	// RecoverAbort ends the plan, leaving the steps after it undone.
+	RecoverAbort Recovery = "abort"
+)
+
+// Step types with policies of their own besides the agents'.
+const (
+	// StepPlan is the planning of the question. Its retries ask for the
+	// plan again; skipping falls back to the classifier's steps and
+	// aborting fails the turn.
+	StepPlan = "plan"
+	// StepAny applies to the step types without a policy.
+	StepAny = "*"
+)
+
+// StepPolicy says how a plan recovers from a failed step.
+type StepPolicy struct {
+	// Retries is how often the step is run again before OnFailure
+	Retries int
+	// OnFailure is RecoverReplan, RecoverSkip or RecoverAbort (default:
+	// that of StepAny, or else RecoverReplan)
+	OnFailure Recovery
+}
+
+// StepPolicies holds the StepPolicy of each step type, as returned by
+// StepType, or StepPlan.
+type StepPolicies map[string]StepPolicy
+
+// DefaultStepPolicies retries SQL steps twice, since the SQL agent can
+// usually fix a query given its error, and other steps never. Failed steps
+// have the rest of the plan made again; a plan that cannot be made falls
+// back to the classifier's steps.
+func DefaultStepPolicies() StepPolicies {
+	return StepPolicies{
+		"sql":    {Retries: 2},
+		"chart":  {},
+		StepPlan: {OnFailure: RecoverSkip},
+		StepAny:  {OnFailure: RecoverReplan},
+	}
+}
+
+// For returns the policy of a step type.
+func (p StepPolicies) For(stepType string) StepPolicy {
+	policy, ok := p[stepType]
+	if !ok {
+		policy = p[StepAny]
+	}
+	if policy.OnFailure == "" {
+		policy.OnFailure = p[StepAny].OnFailure
+	}
+	if policy.OnFailure == "" {
+		policy.OnFailure = RecoverReplan
+	}
+	return policy
+}
+
+// StepType returns the step type of the steps of an agent: its name in
+// lower case without the "Agent" suffix, such as "sql", "chart" or
+// "manager", or "export" for export steps.
+func StepType(agent string) string {
+	return strings.ToLower(strings.TrimSuffix(agent, "Agent"))
+}
@@ -0,0 +1,96 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/preview"
+	"github.com/anuvratrastogi/multi-agent/internal/toolmw"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+// rowsDB answers every query with 30 orders.
+type rowsDB struct {
+	sqlagent.MCPClient
+}
+
+func (rowsDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	rows := make([]string, 30)
+	for i := range rows {
+		rows[i] = fmt.Sprintf(`{"id":%d,"amount":%d}`, i+1, i*10)
+	}
+	return "[" + strings.Join(rows, ",") + "]", nil
+}
+
+func TestResultPreview(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "Here are the orders."}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT id, amount FROM orders"}}}}},
+	)
+
+	results := preview.New(preview.Config{MaxRows: 5})
+	tools, err := sqlagent.CreateMCPTools(rowsDB{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetch, err := results.Tools()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools = toolmw.Wrap(append(tools, fetch...), results.Middleware())
+	sqlAgent, err := sqlagent.New(sqlagent.Config{Model: llm, Tools: tools})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartAgent, err := chart.New(chart.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := manager.New(manager.Config{Model: llm, SQLAgent: sqlAgent, ChartAgent: chartAgent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := app.New(app.Config{Manager: mgr, Results: results})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	turn, err := a.Ask(context.Background(), "u", "s1", "List all orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The turn holds every row
+	if len(turn.Queries) != 1 {
+		t.Fatalf("queries = %+v, want one", turn.Queries)
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(turn.Queries[0].Data), &rows); err != nil || len(rows) != 30 {
+		t.Errorf("turn data has %d rows (%v), want all 30", len(rows), err)
+	}
+
+	// The model saw the preview
+	reqs := llm.Requests()
+	var seen map[string]any
+	for _, c := range reqs[len(reqs)-1].Contents {
+		for _, p := range c.Parts {
+			if p.FunctionResponse != nil && p.FunctionResponse.Name == "query_database" {
+				seen = p.FunctionResponse.Response
+			}
+		}
+	}
+	if seen["truncated"] != true || seen["row_count"] != float64(30) || seen["result_id"] == nil {
+		t.Errorf("model saw %v, want a preview of 30 rows", seen)
+	}
+	if data, _ := seen["data"].(string); strings.Contains(data, `"id":6`) {
+		t.Errorf("model saw row 6 of a 5-row preview: %s", data)
+	}
+}
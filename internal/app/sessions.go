@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/adk/session"
+)
+
+// errNoUser is returned for session requests that name no user.
+var errNoUser = errors.New("a user ID is required to access sessions")
+
+// isolated keeps every user's sessions to themselves: requests must name a
+// user, which ADK's services otherwise treat as a wildcard when listing,
+// and only that user's sessions are returned.
+type isolated struct {
+	session.Service
+}
+
+// Create implements session.Service.
+func (s isolated) Create(ctx context.Context, req *session.CreateRequest) (*session.CreateResponse, error) {
+	if req.UserID == "" {
+		return nil, errNoUser
+	}
+	return s.Service.Create(ctx, req)
+}
+
+// Get implements session.Service.
+func (s isolated) Get(ctx context.Context, req *session.GetRequest) (*session.GetResponse, error) {
+	if req.UserID == "" {
+		return nil, errNoUser
+	}
+	resp, err := s.Service.Get(ctx, req)
+	if err == nil && resp.Session.UserID() != req.UserID {
+		return nil, errors.New("session not found")
+	}
+	return resp, err
+}
+
+// List implements session.Service.
+func (s isolated) List(ctx context.Context, req *session.ListRequest) (*session.ListResponse, error) {
+	if req.UserID == "" {
+		return nil, errNoUser
+	}
+	resp, err := s.Service.List(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	own := make([]session.Session, 0, len(resp.Sessions))
+	for _, sess := range resp.Sessions {
+		if sess.UserID() == req.UserID {
+			own = append(own, sess)
+		}
+	}
+	return &session.ListResponse{Sessions: own}, nil
+}
+
+// Delete implements session.Service.
+func (s isolated) Delete(ctx context.Context, req *session.DeleteRequest) error {
+	if req.UserID == "" {
+		return errNoUser
+	}
+	return s.Service.Delete(ctx, req)
+}
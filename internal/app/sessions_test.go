@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/adk/session"
+)
+
+func TestIsolatedSessions(t *testing.T) {
+	ctx := context.Background()
+	svc := isolated{session.InMemoryService()}
+	for _, user := range []string{"alice", "bob"} {
+		if _, err := svc.Create(ctx, &session.CreateRequest{AppName: AppName, UserID: user, SessionID: "s1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		user      string
+		wantErr   bool
+		wantCount int
+	}{
+		{name: "own sessions", user: "alice", wantCount: 1},
+		{name: "other user", user: "bob", wantCount: 1},
+		{name: "unknown user", user: "carol", wantCount: 0},
+		{name: "no user", user: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := svc.List(ctx, &session.ListRequest{AppName: AppName, UserID: tt.user})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("List() = %d sessions, want an error", len(resp.Sessions))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Sessions) != tt.wantCount {
+				t.Fatalf("List() = %d sessions, want %d", len(resp.Sessions), tt.wantCount)
+			}
+			for _, s := range resp.Sessions {
+				if s.UserID() != tt.user {
+					t.Errorf("List(%s) returned a session of %s", tt.user, s.UserID())
+				}
+			}
+		})
+	}
+
+	if _, err := svc.Get(ctx, &session.GetRequest{AppName: AppName, SessionID: "s1"}); err == nil {
+		t.Error("Get() without a user succeeded")
+	}
+}
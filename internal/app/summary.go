@@ -0,0 +1,105 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+)
+
+// summarySQLRunes bounds the statements quoted in a summary's text.
+const summarySQLRunes = 120
+
+// Summary is an account of what the agents did in a turn, for users to
+// audit an answer.
+type Summary struct {
+	// Agents are the agents that responded, in order
+	Agents  []string       `json:"agents,omitempty"`
+	Queries []QuerySummary `json:"queries,omitempty"`
+	Charts  int            `json:"charts,omitempty"`
+	// StepsDone and Steps count the steps of a plan carried out and planned
+	StepsDone int `json:"steps_done,omitempty"`
+	Steps     int `json:"steps,omitempty"`
+	// Cached is true if the answer came from the answer cache
+	Cached     bool       `json:"cached,omitempty"`
+	Tokens     TokenUsage `json:"tokens"`
+	DurationMS int64      `json:"duration_ms"`
+}
+
+// QuerySummary is a query of a turn and its outcome.
+type QuerySummary struct {
+	SQL   string `json:"sql"`
+	Rows  int    `json:"rows"`
+	Error string `json:"error,omitempty"`
+	// DryRun is true if the query was generated but not run
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// summarize describes what was done in a turn that took d. The queries
+// and charts of a cached answer were not made again, so they are left out.
+func summarize(turn *Turn, d time.Duration) *Summary {
+	s := &Summary{
+		Cached:     turn.Cached != nil,
+		Tokens:     turn.Tokens,
+		DurationMS: d.Milliseconds(),
+	}
+	if s.Cached {
+		return s
+	}
+	s.Agents, s.Charts = turn.agents, len(turn.Charts)
+	for _, q := range turn.Queries {
+		_, rows, _ := Rows(q.Data)
+		s.Queries = append(s.Queries, QuerySummary{SQL: q.SQL, Rows: len(rows), Error: q.Error, DryRun: turn.DryRun})
+	}
+	if r := turn.Routing; r != nil && r.Workflow == manager.WorkflowPlan {
+		for _, step := range r.Steps {
+			if step.Agent == manager.ExportStep {
+				continue
+			}
+			s.Steps++
+			if step.Status == manager.StepDone {
+				s.StepsDone++
+			}
+		}
+	}
+	return s
+}
+
+// String lists what was done, one line each, to append to an answer.
+func (s *Summary) String() string {
+	var b strings.Builder
+	b.WriteString("What I did:")
+	if s.Cached {
+		b.WriteString("\n- Reused the answer to a similar question")
+	}
+	if len(s.Agents) > 0 {
+		fmt.Fprintf(&b, "\n- Agents: %s", strings.Join(s.Agents, " → "))
+	}
+	for _, q := range s.Queries {
+		sql := truncateRunes(strings.Join(strings.Fields(q.SQL), " "), summarySQLRunes)
+		switch {
+		case q.Error != "":
+			fmt.Fprintf(&b, "\n- Query failed: %s (%s)", sql, q.Error)
+		case q.DryRun:
+			fmt.Fprintf(&b, "\n- Wrote a query, not run: %s", sql)
+		case q.Rows == 1:
+			fmt.Fprintf(&b, "\n- Query returned 1 row: %s", sql)
+		default:
+			fmt.Fprintf(&b, "\n- Query returned %d rows: %s", q.Rows, sql)
+		}
+	}
+	switch s.Charts {
+	case 0:
+	case 1:
+		b.WriteString("\n- Drew 1 chart")
+	default:
+		fmt.Fprintf(&b, "\n- Drew %d charts", s.Charts)
+	}
+	if s.Steps > 0 {
+		fmt.Fprintf(&b, "\n- Carried out %d of %d planned steps", s.StepsDone, s.Steps)
+	}
+	duration := (time.Duration(s.DurationMS) * time.Millisecond).Round(100 * time.Millisecond)
+	fmt.Fprintf(&b, "\n- Used %d tokens (%d prompt, %d completion) in %s", s.Tokens.Prompt+s.Tokens.Completion, s.Tokens.Prompt, s.Tokens.Completion, duration)
+	return b.String()
+}
@@ -0,0 +1,49 @@
+package app_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestSummary(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 4 orders."}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*)\n  FROM orders"}}}}},
+	)
+
+	tests := []struct {
+		name   string
+		append bool
+		// wantText is the answer, or its start when it ends with the duration
+		wantText string
+	}{
+		{name: "summary in the result", wantText: "There are 4 orders."},
+		{name: "summary appended", append: true, wantText: "There are 4 orders.\n\nWhat I did:\n- Agents: ManagerAgent → SQLAgent\n- Query returned 1 row: SELECT count(*) FROM orders\n- Used 0 tokens (0 prompt, 0 completion) in "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, countDB{}), AppendSummary: tt.append})
+			if err != nil {
+				t.Fatal(err)
+			}
+			turn, err := a.Ask(context.Background(), "u", tt.name, "How many orders are there?", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := turn.Summary
+			if s == nil || !slices.Equal(s.Agents, []string{"ManagerAgent", "SQLAgent"}) || len(s.Queries) != 1 || s.Queries[0].Rows != 1 || s.Charts != 0 {
+				t.Fatalf("summary = %+v, want two agents and one query of one row", s)
+			}
+			if (turn.Text != tt.wantText) != tt.append || !strings.HasPrefix(turn.Text, tt.wantText) {
+				t.Errorf("text = %q, want %q", turn.Text, tt.wantText)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"context"
+
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/replay"
+)
+
+// startTrace begins recording a turn for the debug recorder. Turns whose
+// context already carries a trace, such as replays, are recorded there
+// instead.
+func (a *App) startTrace(ctx context.Context, userID, sessionID, question string, resumed bool) (context.Context, *replay.Trace) {
+	if a.traces == nil || replay.FromContext(ctx) != nil {
+		return ctx, nil
+	}
+	t := replay.NewTrace(userID, sessionID, question, resumed)
+	return replay.WithTrace(ctx, t), t
+}
+
+// saveTrace completes and saves a trace from startTrace, if any.
+func (a *App) saveTrace(t *replay.Trace, turn *Turn, err error) {
+	if t == nil {
+		return
+	}
+	var answer, stopped string
+	if turn != nil {
+		answer, stopped = turn.Text, turn.Stopped
+	}
+	t.Complete(answer, stopped, err)
+	path, err := a.traces.Save(t)
+	if err != nil {
+		console.Printf("  ⚠️  [TRACE] %v\n", err)
+		return
+	}
+	console.Printf("🐞 Trace saved to %s\n", path)
+}
@@ -0,0 +1,79 @@
+package app_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/prompts"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestPromptVariants(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "manager.concise.tmpl"), []byte("You are a terse router for {{.Count}} sub-agents.\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := prompts.New(prompts.Config{Dir: dir, Variants: "concise"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "terse router for two sub-agents", Respond: mockllm.Response{Text: "Concise answer."}},
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Text: "Base answer."}},
+	)
+	sqlAgent, err := sqlagent.New(sqlagent.Config{Model: llm, Prompts: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartAgent, err := chart.New(chart.Config{Model: llm, Prompts: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := manager.New(manager.Config{Model: llm, SQLAgent: sqlAgent, ChartAgent: chartAgent, Prompts: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := events.New()
+	metrics := events.NewMetrics()
+	bus.Subscribe(metrics.Handle)
+	a, err := app.New(app.Config{Manager: mgr, Events: bus, Prompts: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.SetPromptVariant("u", "pinned", prompts.Base); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetPromptVariant("u", "pinned", "verbose"); err == nil {
+		t.Error("pinned an unknown variant")
+	}
+	tests := []struct {
+		session string
+		want    string
+	}{
+		{"assigned", "Concise answer."},
+		{"pinned", "Base answer."},
+	}
+	for _, tt := range tests {
+		turn, err := a.Ask(context.Background(), "u", tt.session, "Hello there", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if turn.Text != tt.want {
+			t.Errorf("%s session: text = %q, want %q", tt.session, turn.Text, tt.want)
+		}
+	}
+
+	snap := metrics.Snapshot()
+	if snap.Variants["concise"].Turns != 1 || snap.Variants[prompts.Base].Turns != 1 {
+		t.Errorf("variants = %+v, want a turn each for concise and base", snap.Variants)
+	}
+}
@@ -0,0 +1,136 @@
+// Package artifacts stores the files turns produce, such as charts, exported
+// results and reports, under IDs, so every front-end can hand them out and
+// old ones are cleaned up.
+package artifacts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+)
+
+// Kind says what produced an artifact.
+type Kind string
+
+const (
+	KindChart  Kind = "chart"
+	KindExport Kind = "export"
+	KindReport Kind = "report"
+)
+
+// ErrNotFound is returned for artifacts that do not exist, or no longer do.
+var ErrNotFound = errors.New("artifact not found")
+
+// Artifact describes a stored file.
+type Artifact struct {
+	ID   string `json:"id"`
+	Kind Kind   `json:"kind"`
+	// Name is the file name to save the artifact as
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	// UserID and SessionID are whose turn produced the artifact; only that
+	// user may download it
+	UserID    string    `json:"user_id,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	Created   time.Time `json:"created"`
+	// URL is where the artifact can be downloaded
+	URL string `json:"url,omitempty"`
+}
+
+// Store keeps artifacts.
+type Store interface {
+	// Put stores data as the artifact a, returning a with its ID, Size and
+	// Created set.
+	Put(ctx context.Context, a Artifact, data []byte) (Artifact, error)
+	// Get returns an artifact and its content, or ErrNotFound.
+	Get(ctx context.Context, id string) (Artifact, []byte, error)
+	// List returns every stored artifact.
+	List(ctx context.Context) ([]Artifact, error)
+	// Delete removes an artifact. Deleting a missing one is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// Signer is implemented by stores that hand out signed URLs to download
+// artifacts from directly, such as buckets.
+type Signer interface {
+	// SignedURL returns a URL that downloads a for a limited time.
+	SignedURL(a Artifact) (string, error)
+}
+
+// Path is where the API serves an artifact.
+func Path(id string) string {
+	return "/artifacts/" + id
+}
+
+// URL is where a stored artifact can be downloaded: a signed URL if the
+// store hands them out, or else its Path on the API.
+func URL(s Store, a Artifact) string {
+	if signer, ok := s.(Signer); ok {
+		if u, err := signer.SignedURL(a); err == nil {
+			return u
+		}
+	}
+	return Path(a.ID)
+}
+
+// NewID returns a random artifact ID.
+func NewID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+var validID = regexp.MustCompile(`^[0-9a-f]{24}$`)
+
+// ValidID reports whether id could be an ID from NewID, so stores never
+// build paths or keys from anything else.
+func ValidID(id string) bool {
+	return validID.MatchString(id)
+}
+
+// Expire deletes the artifacts created more than maxAge before now and
+// returns how many it deleted.
+func Expire(ctx context.Context, s Store, maxAge time.Duration, now time.Time) (int, error) {
+	all, err := s.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	deleted := 0
+	for _, a := range all {
+		if now.Sub(a.Created) <= maxAge {
+			continue
+		}
+		if err := s.Delete(ctx, a.ID); err != nil {
+			return deleted, fmt.Errorf("failed to delete artifact %s: %w", a.ID, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// Retain runs Expire every interval until ctx is cancelled.
+func Retain(ctx context.Context, s Store, maxAge, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		n, err := Expire(ctx, s, maxAge, time.Now())
+		switch {
+		case err != nil:
+			console.Printf("  ⚠️  [ARTIFACTS] %v\n", err)
+		case n > 0:
+			console.Printf("  🧹 [ARTIFACTS] Removed %d artifacts older than %s\n", n, maxAge)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package artifacts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+)
+
+func TestDir(t *testing.T) {
+	ctx := context.Background()
+	store, err := artifacts.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.Put(ctx, artifacts.Artifact{Kind: artifacts.KindExport, Name: "result.csv", ContentType: "text/csv", UserID: "u"}, []byte("orders\n4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !artifacts.ValidID(a.ID) || a.Size != 9 || a.Created.IsZero() {
+		t.Fatalf("stored artifact = %+v, want an ID, size and creation time", a)
+	}
+
+	tests := []struct {
+		name     string
+		id       string
+		wantErr  error
+		wantData string
+	}{
+		{name: "stored", id: a.ID, wantData: "orders\n4\n"},
+		{name: "missing", id: artifacts.NewID(), wantErr: artifacts.ErrNotFound},
+		{name: "invalid ID", id: "../" + a.ID, wantErr: artifacts.ErrNotFound},
+		{name: "metadata file", id: a.ID + ".json", wantErr: artifacts.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, data, err := store.Get(ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (string(data) != tt.wantData || got.Name != "result.csv" || got.UserID != "u") {
+				t.Errorf("got %+v %q, want result.csv of u with %q", got, data, tt.wantData)
+			}
+		})
+	}
+
+	if all, err := store.List(ctx); err != nil || len(all) != 1 || all[0].ID != a.ID {
+		t.Fatalf("List = %+v, %v, want the stored artifact", all, err)
+	}
+	if err := store.Delete(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, a.ID); err != nil {
+		t.Errorf("deleting a missing artifact: %v", err)
+	}
+	if _, _, err := store.Get(ctx, a.ID); !errors.Is(err, artifacts.ErrNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	ctx := context.Background()
+	store, err := artifacts.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.Put(ctx, artifacts.Artifact{Kind: artifacts.KindChart, Name: "chart.mmd"}, []byte("pie"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		wantDeleted int
+	}{
+		{name: "within retention", now: a.Created.Add(time.Hour), wantDeleted: 0},
+		{name: "expired", now: a.Created.Add(25 * time.Hour), wantDeleted: 1},
+		{name: "already deleted", now: a.Created.Add(25 * time.Hour), wantDeleted: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := artifacts.Expire(ctx, store, 24*time.Hour, tt.now)
+			if err != nil || n != tt.wantDeleted {
+				t.Errorf("Expire = %d, %v, want %d", n, err, tt.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,445 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bucket URL schemes.
+const (
+	SchemeS3  = "s3"
+	SchemeGCS = "gs"
+)
+
+const (
+	// defaultURLExpiry is how long signed URLs are valid by default.
+	defaultURLExpiry = time.Hour
+	// maxURLExpiry is the longest validity SigV4 allows.
+	maxURLExpiry = 7 * 24 * time.Hour
+	// maxErrorBody bounds how much of an error response is reported.
+	maxErrorBody = 1 << 10
+)
+
+// IsBucketURL reports whether store names an S3 or GCS bucket rather than a
+// directory.
+func IsBucketURL(store string) bool {
+	return strings.HasPrefix(store, SchemeS3+"://") || strings.HasPrefix(store, SchemeGCS+"://")
+}
+
+// BucketConfig holds configuration for a Bucket store.
+type BucketConfig struct {
+	// URL is s3://bucket/prefix or gs://bucket/prefix
+	URL string
+	// Endpoint replaces the provider's endpoint, for S3-compatible stores
+	// such as MinIO (optional)
+	Endpoint string
+	// Region of an S3 bucket (defaults to us-east-1)
+	Region string
+	// AccessKeyID and SecretAccessKey are AWS access keys, or HMAC keys of
+	// a GCS service account
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken accompanies temporary AWS credentials (optional)
+	SessionToken string
+	// URLExpiry is how long signed URLs are valid (defaults to 1h, at most 7 days)
+	URLExpiry time.Duration
+	// HTTPClient makes the requests (defaults to a client with a 30s timeout)
+	HTTPClient *http.Client
+}
+
+// Bucket stores artifacts as objects in an S3 or GCS bucket, each as an
+// object named by its ID and a .json object describing it. GCS is used
+// through its S3-compatible XML API. Turns list signed URLs, so downloads
+// need not pass through the API.
+type Bucket struct {
+	// base is the URL of the bucket, which object keys are appended to
+	base   *url.URL
+	prefix string
+	signer signer
+	expiry time.Duration
+	http   *http.Client
+}
+
+// NewBucket creates a Bucket store.
+func NewBucket(cfg BucketConfig) (*Bucket, error) {
+	scheme, rest, ok := strings.Cut(cfg.URL, "://")
+	if !ok || (scheme != SchemeS3 && scheme != SchemeGCS) {
+		return nil, fmt.Errorf("artifact store %q is not an s3:// or gs:// URL", cfg.URL)
+	}
+	name, prefix, _ := strings.Cut(rest, "/")
+	if name == "" {
+		return nil, fmt.Errorf("artifact store %q names no bucket", cfg.URL)
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("artifact store %s requires an access key and secret", cfg.URL)
+	}
+
+	region := cfg.Region
+	var base string
+	switch {
+	case cfg.Endpoint != "":
+		base = strings.TrimSuffix(cfg.Endpoint, "/") + "/" + name
+	case scheme == SchemeGCS:
+		base = "https://storage.googleapis.com/" + name
+		region = "auto"
+	case strings.Contains(name, "."):
+		// Virtual-hosted names with dots do not match the certificate
+		base = "https://s3." + orDefault(region, "us-east-1") + ".amazonaws.com/" + name
+	default:
+		base = "https://" + name + ".s3." + orDefault(region, "us-east-1") + ".amazonaws.com"
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact store endpoint: %w", err)
+	}
+
+	b := &Bucket{
+		base:   u,
+		prefix: strings.Trim(prefix, "/"),
+		signer: signer{
+			accessKey: cfg.AccessKeyID,
+			secretKey: cfg.SecretAccessKey,
+			token:     cfg.SessionToken,
+			region:    orDefault(region, "us-east-1"),
+		},
+		expiry: cfg.URLExpiry,
+		http:   cfg.HTTPClient,
+	}
+	if b.expiry <= 0 {
+		b.expiry = defaultURLExpiry
+	}
+	if b.expiry > maxURLExpiry {
+		return nil, fmt.Errorf("signed URLs may be valid for at most %s", maxURLExpiry)
+	}
+	if b.http == nil {
+		b.http = &http.Client{Timeout: 30 * time.Second}
+	}
+	return b, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// Put implements Store.
+func (b *Bucket) Put(ctx context.Context, a Artifact, data []byte) (Artifact, error) {
+	a.ID, a.Size, a.Created = NewID(), int64(len(data)), time.Now().UTC()
+	meta, err := json.Marshal(a)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to encode artifact: %w", err)
+	}
+	header := http.Header{"Content-Disposition": {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})}}
+	if a.ContentType != "" {
+		header.Set("Content-Type", a.ContentType)
+	}
+	if _, err := b.do(ctx, http.MethodPut, b.key(a.ID), nil, header, data); err != nil {
+		return Artifact{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	// The description is written last, so listed artifacts are complete
+	header = http.Header{"Content-Type": {"application/json"}}
+	if _, err := b.do(ctx, http.MethodPut, b.key(a.ID+metaExt), nil, header, meta); err != nil {
+		b.do(ctx, http.MethodDelete, b.key(a.ID), nil, nil, nil)
+		return Artifact{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	return a, nil
+}
+
+// Get implements Store.
+func (b *Bucket) Get(ctx context.Context, id string) (Artifact, []byte, error) {
+	if !ValidID(id) {
+		return Artifact{}, nil, ErrNotFound
+	}
+	a, err := b.meta(ctx, id)
+	if err != nil {
+		return Artifact{}, nil, err
+	}
+	data, err := b.do(ctx, http.MethodGet, b.key(id), nil, nil, nil)
+	if err != nil {
+		return Artifact{}, nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return a, data, nil
+}
+
+// listResult is the part of a ListObjectsV2 response that is used.
+type listResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List implements Store.
+func (b *Bucket) List(ctx context.Context) ([]Artifact, error) {
+	var all []Artifact
+	query := url.Values{"list-type": {"2"}, "prefix": {b.key("")}}
+	for {
+		body, err := b.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+		var page listResult
+		if err := xml.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode artifact list: %w", err)
+		}
+		for _, c := range page.Contents {
+			id, ok := strings.CutSuffix(strings.TrimPrefix(c.Key, b.key("")), metaExt)
+			if !ok || !ValidID(id) {
+				continue
+			}
+			a, err := b.meta(ctx, id)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, a)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return all, nil
+		}
+		query.Set("continuation-token", page.NextContinuationToken)
+	}
+}
+
+// Delete implements Store.
+func (b *Bucket) Delete(ctx context.Context, id string) error {
+	if !ValidID(id) {
+		return nil
+	}
+	// The description goes first, so a half-deleted artifact is not listed
+	for _, name := range []string{id + metaExt, id} {
+		if _, err := b.do(ctx, http.MethodDelete, b.key(name), nil, nil, nil); err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("failed to delete artifact: %w", err)
+		}
+	}
+	return nil
+}
+
+// SignedURL implements Signer.
+func (b *Bucket) SignedURL(a Artifact) (string, error) {
+	if !ValidID(a.ID) {
+		return "", ErrNotFound
+	}
+	return b.signer.presign(b.object(b.key(a.ID), nil), b.expiry, time.Now()), nil
+}
+
+// meta reads the description of an artifact.
+func (b *Bucket) meta(ctx context.Context, id string) (Artifact, error) {
+	data, err := b.do(ctx, http.MethodGet, b.key(id+metaExt), nil, nil, nil)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return Artifact{}, err
+		}
+		return Artifact{}, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	var a Artifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		return Artifact{}, fmt.Errorf("failed to decode artifact %s: %w", id, err)
+	}
+	return a, nil
+}
+
+// key is the object key of an artifact's file.
+func (b *Bucket) key(name string) string {
+	if b.prefix == "" {
+		return name
+	}
+	return b.prefix + "/" + name
+}
+
+// object is the URL of an object, or of the bucket if key is empty.
+func (b *Bucket) object(key string, query url.Values) *url.URL {
+	u := *b.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// do sends a signed request and returns the response body. Missing
+// objects are ErrNotFound.
+func (b *Bucket) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, b.object(key, query).String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	b.signer.sign(req, body, time.Now())
+
+	resp, err := b.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode/100 != 2:
+		if len(data) > maxErrorBody {
+			data = data[:maxErrorBody]
+		}
+		return nil, fmt.Errorf("%s %s: %s: %s", method, key, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
+
+// signer signs requests with AWS Signature Version 4, which GCS accepts
+// for HMAC keys.
+type signer struct {
+	accessKey, secretKey, token, region string
+}
+
+const (
+	sigAlgorithm    = "AWS4-HMAC-SHA256"
+	sigService      = "s3"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+// sign adds the Authorization header to a request with the given body.
+func (s signer) sign(req *http.Request, body []byte, now time.Time) {
+	stamp := now.UTC().Format("20060102T150405Z")
+	payload := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+	if s.token != "" {
+		req.Header.Set("X-Amz-Security-Token", s.token)
+	}
+
+	signed := []string{"host"}
+	for name := range req.Header {
+		if name := strings.ToLower(name); strings.HasPrefix(name, "x-amz-") {
+			signed = append(signed, name)
+		}
+	}
+	sort.Strings(signed)
+	var headers strings.Builder
+	for _, name := range signed {
+		value := req.URL.Host
+		if name != "host" {
+			value = strings.TrimSpace(req.Header.Get(name))
+		}
+		headers.WriteString(name + ":" + value + "\n")
+	}
+
+	scope := s.scope(stamp)
+	canonical := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		headers.String(),
+		strings.Join(signed, ";"),
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigAlgorithm, s.accessKey, scope, strings.Join(signed, ";"), s.signature(stamp, scope, canonical)))
+}
+
+// presign returns u signed to be fetched with GET until expiry has passed.
+func (s signer) presign(u *url.URL, expiry time.Duration, now time.Time) string {
+	stamp := now.UTC().Format("20060102T150405Z")
+	scope := s.scope(stamp)
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", sigAlgorithm)
+	query.Set("X-Amz-Credential", s.accessKey+"/"+scope)
+	query.Set("X-Amz-Date", stamp)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiry.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if s.token != "" {
+		query.Set("X-Amz-Security-Token", s.token)
+	}
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		escapePath(u.Path),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+	signed := *u
+	signed.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + s.signature(stamp, scope, canonical)
+	return signed.String()
+}
+
+// scope is the credential scope of a signature made at stamp.
+func (s signer) scope(stamp string) string {
+	return stamp[:8] + "/" + s.region + "/" + sigService + "/aws4_request"
+}
+
+// signature signs a canonical request.
+func (s signer) signature(stamp, scope, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := sigAlgorithm + "\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{stamp[:8], s.region, sigService, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// escape encodes s as SigV4 requires: everything but unreserved
+// characters, with spaces as %20.
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// escapePath escapes each segment of a path.
+func escapePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = escape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery encodes a query sorted by key, then value.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, escape(key)+"="+escape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
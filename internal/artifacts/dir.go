@@ -0,0 +1,122 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// metaExt is the extension of the file describing an artifact next to its
+// content.
+const metaExt = ".json"
+
+// Dir stores artifacts in a local directory, each as a file named by its ID
+// and a .json file describing it.
+type Dir struct {
+	dir string
+}
+
+// NewDir creates a Dir store, creating the directory if needed.
+func NewDir(dir string) (*Dir, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	return &Dir{dir: dir}, nil
+}
+
+// Put implements Store.
+func (d *Dir) Put(_ context.Context, a Artifact, data []byte) (Artifact, error) {
+	a.ID, a.Size, a.Created = NewID(), int64(len(data)), time.Now().UTC()
+	meta, err := json.Marshal(a)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to encode artifact: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.dir, a.ID), data, 0o644); err != nil {
+		return Artifact{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	// The description is written last, so listed artifacts are complete
+	if err := os.WriteFile(filepath.Join(d.dir, a.ID+metaExt), meta, 0o644); err != nil {
+		os.Remove(filepath.Join(d.dir, a.ID))
+		return Artifact{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	return a, nil
+}
+
+// Get implements Store.
+func (d *Dir) Get(_ context.Context, id string) (Artifact, []byte, error) {
+	if !ValidID(id) {
+		return Artifact{}, nil, ErrNotFound
+	}
+	a, err := d.meta(id)
+	if err != nil {
+		return Artifact{}, nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(d.dir, id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Artifact{}, nil, ErrNotFound
+	}
+	if err != nil {
+		return Artifact{}, nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return a, data, nil
+}
+
+// List implements Store.
+func (d *Dir) List(_ context.Context) ([]Artifact, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact directory: %w", err)
+	}
+	var all []Artifact
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), metaExt)
+		if !ok || !ValidID(id) {
+			continue
+		}
+		a, err := d.meta(id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, a)
+	}
+	return all, nil
+}
+
+// Delete implements Store.
+func (d *Dir) Delete(_ context.Context, id string) error {
+	if !ValidID(id) {
+		return nil
+	}
+	// The description goes first, so a half-deleted artifact is not listed
+	for _, name := range []string{id + metaExt, id} {
+		if err := os.Remove(filepath.Join(d.dir, name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to delete artifact: %w", err)
+		}
+	}
+	return nil
+}
+
+// meta reads the description of an artifact.
+func (d *Dir) meta(id string) (Artifact, error) {
+	data, err := os.ReadFile(filepath.Join(d.dir, id+metaExt))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Artifact{}, ErrNotFound
+	}
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	var a Artifact
+	if err := json.Unmarshal(data, &a); err != nil {
+		return Artifact{}, fmt.Errorf("failed to decode artifact %s: %w", id, err)
+	}
+	return a, nil
+}
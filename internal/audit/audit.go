@@ -0,0 +1,143 @@
+// Package audit records every SQL query executed by the agents.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/secrets"
+)
+
+// Entry is a single audited query execution.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	UserID    string    `json:"user_id,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	SQL       string    `json:"sql"`
+	// Args are the values bound to the query's placeholders, if any
+	Args       []interface{} `json:"args,omitempty"`
+	DurationMS int64         `json:"duration_ms"`
+	RowCount   int           `json:"row_count"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Log is an append-only JSONL audit log.
+type Log struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens (or creates) the audit log at path for appending.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{path: path, file: f}, nil
+}
+
+// Record appends an entry to the log, with secrets redacted from the SQL,
+// the error and the text bound to placeholders.
+func (l *Log) Record(e Entry) error {
+	e.SQL, e.Error = secrets.Redact(e.SQL), secrets.Redact(e.Error)
+	if e.Args != nil {
+		args := make([]interface{}, len(e.Args))
+		for i, arg := range e.Args {
+			if s, ok := arg.(string); ok {
+				arg = secrets.Redact(s)
+			}
+			args[i] = arg
+		}
+		e.Args = args
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("json error: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// Recent returns up to n of the most recent entries, oldest first.
+func (l *Log) Recent(n int) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) > n {
+			entries = entries[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// Client wraps an MCPClient and records every query to a Log.
+type Client struct {
+	sqlagent.MCPClient
+	log *Log
+}
+
+// NewClient creates a client that audits queries sent to inner.
+func NewClient(inner sqlagent.MCPClient, log *Log) *Client {
+	return &Client{MCPClient: inner, log: log}
+}
+
+// Query executes the query and records it in the audit log.
+func (c *Client) Query(ctx context.Context, query string, limit int) (string, error) {
+	start := time.Now()
+	data, err := c.MCPClient.Query(ctx, query, limit)
+
+	entry := Entry{
+		Time:       start.UTC(),
+		SQL:        query,
+		Args:       sqlagent.ArgsFrom(ctx),
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	entry.UserID, entry.SessionID, _ = sqlagent.SessionFrom(ctx)
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.RowCount = sqlagent.ResultRows(ctx, data)
+	}
+
+	if logErr := c.log.Record(entry); logErr != nil {
+		console.Printf("  ⚠️  [AUDIT] %v\n", logErr)
+	}
+	return data, err
+}
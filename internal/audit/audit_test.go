@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/secrets"
+)
+
+// fakeClient answers every query with rows, or fails with err.
+type fakeClient struct {
+	sqlagent.MCPClient
+	rows string
+	err  error
+}
+
+func (c *fakeClient) Query(ctx context.Context, query string, limit int) (string, error) {
+	return c.rows, c.err
+}
+
+func openLog(t *testing.T) (*Log, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.Close() })
+	return log, path
+}
+
+func TestRecordFormat(t *testing.T) {
+	log, path := openLog(t)
+	e := Entry{
+		Time:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		UserID:     "alice",
+		SessionID:  "s1",
+		SQL:        "SELECT * FROM orders WHERE id = $1",
+		Args:       []interface{}{int64(7)},
+		DurationMS: 12,
+		RowCount:   1,
+	}
+	if err := log.Record(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Record(Entry{Time: e.Time, SQL: "SELECT 1", Error: "boom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"time":"2024-05-01T12:00:00Z","user_id":"alice","session_id":"s1","sql":"SELECT * FROM orders WHERE id = $1","args":[7],"duration_ms":12,"row_count":1}
+{"time":"2024-05-01T12:00:00Z","sql":"SELECT 1","duration_ms":0,"row_count":0,"error":"boom"}
+`
+	if string(data) != want {
+		t.Errorf("log =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestRecordRedacts(t *testing.T) {
+	secrets.Configure(secrets.Config{Values: []string{"hunter2secret"}})
+	t.Cleanup(func() { secrets.Configure(secrets.Config{}) })
+
+	log, _ := openLog(t)
+	args := []interface{}{"hunter2secret", int64(3), nil}
+	if err := log.Record(Entry{
+		SQL:   "SELECT * FROM users WHERE token = 'hunter2secret'",
+		Args:  args,
+		Error: "bad token hunter2secret",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if args[0] != "hunter2secret" {
+		t.Error("Record() redacted the caller's args in place")
+	}
+
+	entries, err := log.Recent(1)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Recent(1) = %v, %v; want the entry", entries, err)
+	}
+	e := entries[0]
+	if strings.Contains(e.SQL, "hunter2secret") || strings.Contains(e.Error, "hunter2secret") {
+		t.Errorf("entry leaks the secret: sql %q, error %q", e.SQL, e.Error)
+	}
+	// JSON numbers read back as float64
+	if want := []interface{}{secrets.Mask, float64(3), nil}; !reflect.DeepEqual(e.Args, want) {
+		t.Errorf("args = %v, want %v", e.Args, want)
+	}
+}
+
+func TestRecent(t *testing.T) {
+	log, path := openLog(t)
+	for _, sql := range []string{"SELECT 1", "SELECT 2", "SELECT 3"} {
+		if err := log.Record(Entry{SQL: sql}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not json\n")
+	f.Close()
+	if err := log.Record(Entry{SQL: "SELECT 4"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{1, []string{"SELECT 4"}},
+		{2, []string{"SELECT 3", "SELECT 4"}},
+		{10, []string{"SELECT 1", "SELECT 2", "SELECT 3", "SELECT 4"}},
+	}
+	for _, tt := range tests {
+		entries, err := log.Recent(tt.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.SQL)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Recent(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	log, _ := openLog(t)
+	ctx := sqlagent.WithSession(context.Background(), "alice", "s1")
+
+	client := NewClient(&fakeClient{rows: `[{"id":1},{"id":2}]`}, log)
+	if _, err := client.Query(sqlagent.WithArgs(ctx, []interface{}{"open"}), "SELECT id FROM orders WHERE status = $1", 10); err != nil {
+		t.Fatal(err)
+	}
+	failing := NewClient(&fakeClient{err: errors.New("relation does not exist")}, log)
+	if _, err := failing.Query(ctx, "SELECT * FROM missing", 10); err == nil {
+		t.Fatal("Query() hid the inner client's error")
+	}
+
+	entries, err := log.Recent(2)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Recent(2) = %v, %v; want both queries", entries, err)
+	}
+	ok, failed := entries[0], entries[1]
+	if ok.UserID != "alice" || ok.SessionID != "s1" || ok.RowCount != 2 || ok.Error != "" ||
+		!reflect.DeepEqual(ok.Args, []interface{}{"open"}) {
+		t.Errorf("entry = %+v, want alice/s1 with 2 rows and args [open]", ok)
+	}
+	if failed.Error != "relation does not exist" || failed.RowCount != 0 || failed.Args != nil {
+		t.Errorf("entry = %+v, want the error and no rows or args", failed)
+	}
+}
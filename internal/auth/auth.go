@@ -0,0 +1,240 @@
+// Package auth authenticates API clients with static API keys or OIDC bearer
+// tokens. Each client gets an Identity: the user it acts as, its database
+// permission profile and its request rate limit.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile is a client's database permission profile.
+type Profile string
+
+// Permission profiles.
+const (
+	// ProfileReadOnly may query the database but never approve writes
+	ProfileReadOnly Profile = "read-only"
+	// ProfileReadWrite may also approve writes proposed in write mode
+	ProfileReadWrite Profile = "read-write"
+)
+
+// KeyHeader is an alternative to "Authorization: Bearer" for API keys.
+const KeyHeader = "X-API-Key"
+
+// ErrUnauthenticated is returned for requests without valid credentials.
+var ErrUnauthenticated = errors.New("missing or invalid credentials")
+
+// now is replaced in tests.
+var now = time.Now
+
+// Key is a static API key.
+type Key struct {
+	// Name identifies the key in logs and errors
+	Name string `yaml:"name"`
+	// Key is the secret clients send
+	Key string `yaml:"key"`
+	// User is the user the key acts as (defaults to Name)
+	User string `yaml:"user"`
+	// Profile is the key's database permissions (defaults to read-only)
+	Profile Profile `yaml:"profile"`
+	// RateLimit is the number of requests allowed per minute (0 = unlimited)
+	RateLimit int `yaml:"rate_limit"`
+	// Admin allows the key to use the /v1/admin endpoints
+	Admin bool `yaml:"admin"`
+}
+
+// Identity is an authenticated client.
+type Identity struct {
+	// Name is the API key's name, or "oidc:" and the user for tokens
+	Name      string
+	User      string
+	Profile   Profile
+	RateLimit int
+	Admin     bool
+}
+
+// ReadOnly reports whether the client may not approve writes.
+func (id *Identity) ReadOnly() bool {
+	return id.Profile != ProfileReadWrite
+}
+
+// Config holds configuration for an Authenticator.
+type Config struct {
+	// Keys are the accepted API keys
+	Keys []Key
+	// OIDCIssuer accepts bearer tokens signed by this OpenID provider (optional)
+	OIDCIssuer string
+	// OIDCAudience is the audience tokens must be issued for (required with OIDCIssuer)
+	OIDCAudience string
+	// OIDCUserClaim is the token claim used as the user (defaults to "sub")
+	OIDCUserClaim string
+	// OIDCProfile is the permission profile of token holders (defaults to read-only)
+	OIDCProfile Profile
+	// OIDCRateLimit is the number of requests each token user may make per minute (0 = unlimited)
+	OIDCRateLimit int
+	// HTTPClient fetches the provider's signing keys (defaults to http.DefaultClient)
+	HTTPClient *http.Client
+}
+
+// Authenticator checks credentials and enforces rate limits. It is safe for
+// concurrent use.
+type Authenticator struct {
+	// keys maps the SHA-256 of each secret to its identity
+	keys map[[sha256.Size]byte]*Identity
+	oidc *oidcVerifier
+
+	mu       sync.Mutex
+	requests map[string][]time.Time
+}
+
+// New creates an Authenticator.
+func New(cfg Config) (*Authenticator, error) {
+	if len(cfg.Keys) == 0 && cfg.OIDCIssuer == "" {
+		return nil, fmt.Errorf("authentication requires API keys or an OIDC issuer")
+	}
+
+	a := &Authenticator{
+		keys:     make(map[[sha256.Size]byte]*Identity, len(cfg.Keys)),
+		requests: make(map[string][]time.Time),
+	}
+	names := make(map[string]bool, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		if k.Name == "" || k.Key == "" {
+			return nil, fmt.Errorf("API keys require a name and a key")
+		}
+		if names[k.Name] {
+			return nil, fmt.Errorf("duplicate API key name %q", k.Name)
+		}
+		names[k.Name] = true
+		profile, err := checkProfile(k.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("API key %q: %w", k.Name, err)
+		}
+		if k.RateLimit < 0 {
+			return nil, fmt.Errorf("API key %q: rate_limit must not be negative", k.Name)
+		}
+		hash := sha256.Sum256([]byte(k.Key))
+		if _, ok := a.keys[hash]; ok {
+			return nil, fmt.Errorf("API key %q reuses the secret of another key", k.Name)
+		}
+		user := k.User
+		if user == "" {
+			user = k.Name
+		}
+		a.keys[hash] = &Identity{Name: k.Name, User: user, Profile: profile, RateLimit: k.RateLimit, Admin: k.Admin}
+	}
+
+	if cfg.OIDCIssuer != "" {
+		if cfg.OIDCAudience == "" {
+			return nil, fmt.Errorf("OIDC validation requires an audience")
+		}
+		profile, err := checkProfile(cfg.OIDCProfile)
+		if err != nil {
+			return nil, fmt.Errorf("OIDC: %w", err)
+		}
+		a.oidc = newOIDCVerifier(cfg, profile)
+	}
+	return a, nil
+}
+
+// LoadKeys reads API keys from a YAML file with a top-level "keys" list.
+func LoadKeys(path string) ([]Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+	var file struct {
+		Keys []Key `yaml:"keys"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys file: %w", err)
+	}
+	return file.Keys, nil
+}
+
+// Authenticate returns the identity of the request's credentials: an API key
+// in the X-API-Key header, or an API key or OIDC token sent as
+// "Authorization: Bearer".
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	secret := r.Header.Get(KeyHeader)
+	if secret == "" {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return nil, ErrUnauthenticated
+		}
+		secret = strings.TrimSpace(token)
+	}
+	return a.AuthenticateSecret(r.Context(), secret)
+}
+
+// AuthenticateSecret returns the identity of an API key or OIDC token, for
+// transports other than HTTP headers.
+func (a *Authenticator) AuthenticateSecret(ctx context.Context, secret string) (*Identity, error) {
+	if secret == "" {
+		return nil, ErrUnauthenticated
+	}
+	if id, ok := a.keys[sha256.Sum256([]byte(secret))]; ok {
+		return id, nil
+	}
+	if a.oidc != nil && strings.Count(secret, ".") == 2 {
+		return a.oidc.verify(ctx, secret)
+	}
+	return nil, ErrUnauthenticated
+}
+
+// Allow counts a request against id's rate limit. Over the limit, it returns
+// false and how long to wait before retrying.
+func (a *Authenticator) Allow(id *Identity) (bool, time.Duration) {
+	if id.RateLimit <= 0 {
+		return true, 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Forget requests older than the one-minute window
+	cutoff := now().Add(-time.Minute)
+	recent := a.requests[id.Name]
+	for len(recent) > 0 && !recent[0].After(cutoff) {
+		recent = recent[1:]
+	}
+	if len(recent) >= id.RateLimit {
+		a.requests[id.Name] = recent
+		return false, recent[0].Sub(cutoff)
+	}
+	a.requests[id.Name] = append(recent, now())
+	return true, 0
+}
+
+type identityKey struct{}
+
+// NewContext returns a context carrying id.
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity in ctx, or nil for unauthenticated
+// requests.
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+func checkProfile(p Profile) (Profile, error) {
+	switch p {
+	case "":
+		return ProfileReadOnly, nil
+	case ProfileReadOnly, ProfileReadWrite:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown profile %q: use %s or %s", p, ProfileReadOnly, ProfileReadWrite)
+}
@@ -0,0 +1,242 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthenticateKeys(t *testing.T) {
+	a, err := New(Config{Keys: []Key{
+		{Name: "dashboard", Key: "secret-1"},
+		{Name: "ops", Key: "secret-2", User: "alice", Profile: ProfileReadWrite, Admin: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		header      string
+		value       string
+		wantUser    string
+		wantProfile Profile
+		wantErr     bool
+	}{
+		{name: "bearer key", header: "Authorization", value: "Bearer secret-1", wantUser: "dashboard", wantProfile: ProfileReadOnly},
+		{name: "key header", header: KeyHeader, value: "secret-2", wantUser: "alice", wantProfile: ProfileReadWrite},
+		{name: "lowercase scheme", header: "Authorization", value: "bearer secret-1", wantUser: "dashboard", wantProfile: ProfileReadOnly},
+		{name: "unknown key", header: KeyHeader, value: "secret-3", wantErr: true},
+		{name: "basic auth", header: "Authorization", value: "Basic c2VjcmV0LTE=", wantErr: true},
+		{name: "no credentials", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/v1/sessions", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			id, err := a.Authenticate(r)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("Authenticate() error = %v, want ErrUnauthenticated", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id.User != tt.wantUser || id.Profile != tt.wantProfile {
+				t.Errorf("Authenticate() = %s (%s), want %s (%s)", id.User, id.Profile, tt.wantUser, tt.wantProfile)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "nothing configured", cfg: Config{}},
+		{name: "missing secret", cfg: Config{Keys: []Key{{Name: "a"}}}},
+		{name: "duplicate name", cfg: Config{Keys: []Key{{Name: "a", Key: "1"}, {Name: "a", Key: "2"}}}},
+		{name: "shared secret", cfg: Config{Keys: []Key{{Name: "a", Key: "1"}, {Name: "b", Key: "1"}}}},
+		{name: "unknown profile", cfg: Config{Keys: []Key{{Name: "a", Key: "1", Profile: "admin"}}}},
+		{name: "OIDC without audience", cfg: Config{OIDCIssuer: "https://idp.example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("New() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	data := "keys:\n  - name: etl\n    key: s3cret\n    profile: read-write\n    rate_limit: 30\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeys(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Name != "etl" || keys[0].Profile != ProfileReadWrite || keys[0].RateLimit != 30 {
+		t.Errorf("LoadKeys() = %+v", keys)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	a, err := New(Config{Keys: []Key{{Name: "a", Key: "1", RateLimit: 2}, {Name: "b", Key: "2"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limited := &Identity{Name: "a", RateLimit: 2}
+	unlimited := &Identity{Name: "b"}
+
+	steps := []struct {
+		advance  time.Duration
+		id       *Identity
+		want     bool
+		wantWait time.Duration
+	}{
+		{id: limited, want: true},
+		{advance: 20 * time.Second, id: limited, want: true},
+		{advance: 10 * time.Second, id: limited, wantWait: 30 * time.Second},
+		{id: unlimited, want: true},
+		{advance: 31 * time.Second, id: limited, want: true},
+		{id: limited, wantWait: 19 * time.Second},
+	}
+	for i, s := range steps {
+		clock = clock.Add(s.advance)
+		ok, wait := a.Allow(s.id)
+		if ok != s.want || wait != s.wantWait {
+			t.Errorf("step %d: Allow() = %v, %v; want %v, %v", i, ok, wait, s.want, s.wantWait)
+		}
+	}
+}
+
+// provider is a fake OpenID provider signing tokens with an RSA key.
+type provider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+}
+
+func newProvider(t *testing.T) *provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &provider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": p.URL, "jwks_uri": p.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": "k1", "kty": "RSA", "use": "sig",
+			"n": b64(key.N.Bytes()),
+			"e": b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// token signs claims with the provider's key, under key ID kid.
+func (p *provider) token(t *testing.T, kid string, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + b64(sig)
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestAuthenticateOIDC(t *testing.T) {
+	p := newProvider(t)
+	a, err := New(Config{OIDCIssuer: p.URL, OIDCAudience: "multi-agent", OIDCRateLimit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := map[string]any{"iss": p.URL, "aud": "multi-agent", "sub": "alice", "exp": exp}
+	with := func(key string, value any) map[string]any {
+		claims := map[string]any{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[key] = value
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		kid     string
+		claims  map[string]any
+		tamper  bool
+		wantErr bool
+	}{
+		{name: "valid", kid: "k1", claims: valid},
+		{name: "audience list", kid: "k1", claims: with("aud", []string{"other", "multi-agent"})},
+		{name: "wrong audience", kid: "k1", claims: with("aud", "other"), wantErr: true},
+		{name: "wrong issuer", kid: "k1", claims: with("iss", "https://evil.example.com"), wantErr: true},
+		{name: "expired", kid: "k1", claims: with("exp", time.Now().Add(-time.Hour).Unix()), wantErr: true},
+		{name: "not yet valid", kid: "k1", claims: with("nbf", time.Now().Add(time.Hour).Unix()), wantErr: true},
+		{name: "no subject", kid: "k1", claims: with("sub", ""), wantErr: true},
+		{name: "unknown key", kid: "k2", claims: valid, wantErr: true},
+		{name: "tampered", kid: "k1", claims: valid, tamper: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := p.token(t, tt.kid, tt.claims)
+			if tt.tamper {
+				forged, _ := json.Marshal(with("sub", "admin"))
+				parts := strings.Split(token, ".")
+				token = parts[0] + "." + b64(forged) + "." + parts[2]
+			}
+			r := httptest.NewRequest("GET", "/v1/sessions", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			id, err := a.Authenticate(r)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Authenticate() = %s, want an error", id.User)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id.User != "alice" || !id.ReadOnly() || id.RateLimit != 10 {
+				t.Errorf("Authenticate() = %+v, want read-only alice limited to 10", id)
+			}
+		})
+	}
+}
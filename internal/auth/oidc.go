@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/jwt"
+)
+
+const (
+	// clockSkew is the leeway given to exp and nbf
+	clockSkew = time.Minute
+	// keyRefresh is the least time between fetches of the provider's keys,
+	// so tokens with unknown key IDs cannot hammer it
+	keyRefresh = time.Minute
+)
+
+// oidcVerifier validates JWTs signed with RS256 or ES256 by an OpenID
+// provider, whose keys are discovered from its issuer URL on first use.
+type oidcVerifier struct {
+	issuer    string
+	audience  string
+	userClaim string
+	profile   Profile
+	rateLimit int
+	jwt       *jwt.Verifier
+}
+
+func newOIDCVerifier(cfg Config, profile Profile) *oidcVerifier {
+	v := &oidcVerifier{
+		issuer:    strings.TrimSuffix(cfg.OIDCIssuer, "/"),
+		audience:  cfg.OIDCAudience,
+		userClaim: cfg.OIDCUserClaim,
+		profile:   profile,
+		rateLimit: cfg.OIDCRateLimit,
+	}
+	if v.userClaim == "" {
+		v.userClaim = "sub"
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	v.jwt = &jwt.Verifier{
+		MetadataURL: v.issuer + "/.well-known/openid-configuration",
+		Skew:        clockSkew,
+		Refresh:     keyRefresh,
+		Client:      client,
+		Now:         func() time.Time { return now() },
+	}
+	return v
+}
+
+// verify checks the token's signature and claims and returns its holder.
+func (v *oidcVerifier) verify(ctx context.Context, token string) (*Identity, error) {
+	claims, err := v.jwt.Verify(ctx, token)
+	if errors.Is(err, jwt.ErrInvalid) {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(claims.String("iss"), "/") != v.issuer {
+		return nil, ErrUnauthenticated
+	}
+	if !claims.HasAudience(v.audience) {
+		return nil, ErrUnauthenticated
+	}
+	user := claims.String(v.userClaim)
+	if user == "" {
+		return nil, ErrUnauthenticated
+	}
+	return &Identity{Name: "oidc:" + user, User: user, Profile: v.profile, RateLimit: v.rateLimit}, nil
+}
@@ -0,0 +1,253 @@
+// Package backup bundles a deployment's conversational assets, such as its
+// config, policies, cached answers and dashboards, into a single versioned
+// archive, and restores them from one.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FormatVersion is the archive format written by Export. Import reads
+// archives up to this version.
+const FormatVersion = 1
+
+// manifestName is the first entry of every archive.
+const manifestName = "manifest.json"
+
+// Kind identifies what an asset is, so Import can put it where the target
+// deployment expects it.
+type Kind string
+
+const (
+	KindConfig            Kind = "config"
+	KindRedactionPolicy   Kind = "redaction_policy"
+	KindSemanticCache     Kind = "semantic_cache"
+	KindUsage             Kind = "usage"
+	KindHistory           Kind = "history"
+	KindDashboard         Kind = "dashboard"
+	KindDashboardSnapshot Kind = "dashboard_snapshot"
+)
+
+// Asset is a file in an archive.
+type Asset struct {
+	Kind Kind `json:"kind"`
+	// Path is where the file was exported from
+	Path string `json:"path"`
+	// File is the name of the file within the archive
+	File   string `json:"file,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Manifest describes an archive.
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Assets    []Asset   `json:"assets"`
+}
+
+// Export writes the assets to w as a gzipped tar archive. Assets without a
+// path or whose file does not exist are left out.
+func Export(w io.Writer, assets []Asset) (*Manifest, error) {
+	m := &Manifest{Version: FormatVersion, CreatedAt: time.Now().UTC()}
+	for _, a := range assets {
+		if a.Path == "" {
+			continue
+		}
+		info, err := os.Stat(a.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", a.Path, err)
+		}
+		sum, err := hashFile(a.Path)
+		if err != nil {
+			return nil, err
+		}
+		a.File = fmt.Sprintf("assets/%02d-%s", len(m.Assets)+1, filepath.Base(a.Path))
+		a.Size = info.Size()
+		a.SHA256 = sum
+		m.Assets = append(m.Assets, a)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+	if err := writeEntry(tw, manifestName, int64(len(data)), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, a := range m.Assets {
+		err := writeEntry(tw, a.File, a.Size, func(w io.Writer) error {
+			f, err := os.Open(a.Path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.CopyN(w, f, a.Size)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive %s: %w", a.Path, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return m, nil
+}
+
+// Restored is an asset written by Import.
+type Restored struct {
+	Asset
+	// Target is where the asset was written
+	Target string `json:"target"`
+}
+
+// Import restores the assets of the archive in r. target returns where an
+// asset belongs in this deployment, or "" to skip it. Existing files are
+// only replaced if overwrite is set; otherwise Import fails before writing
+// anything. Each file is checked against its checksum and replaced
+// atomically.
+func Import(r io.Reader, target func(Asset) string, overwrite bool) (*Manifest, []Restored, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, nil, fmt.Errorf("not a backup archive: missing %s", manifestName)
+	}
+	var m Manifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", manifestName, err)
+	}
+	if m.Version < 1 || m.Version > FormatVersion {
+		return nil, nil, fmt.Errorf("unsupported backup version %d (this build reads up to %d)", m.Version, FormatVersion)
+	}
+
+	// Decide every target up front so a conflict leaves nothing half-restored
+	targets := make(map[string]Restored)
+	for _, a := range m.Assets {
+		dst := target(a)
+		if dst == "" {
+			continue
+		}
+		if !overwrite {
+			if _, err := os.Stat(dst); err == nil {
+				return &m, nil, fmt.Errorf("%s already exists (use --force to overwrite)", dst)
+			}
+		}
+		targets[a.File] = Restored{Asset: a, Target: dst}
+	}
+
+	var restored []Restored
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return &m, restored, fmt.Errorf("failed to read archive: %w", err)
+		}
+		res, ok := targets[hdr.Name]
+		if !ok {
+			continue
+		}
+		if err := restoreFile(tr, res); err != nil {
+			return &m, restored, err
+		}
+		restored = append(restored, res)
+		delete(targets, hdr.Name)
+	}
+	for name := range targets {
+		return &m, restored, fmt.Errorf("archive is missing %s", name)
+	}
+	return &m, restored, nil
+}
+
+// restoreFile writes an asset next to its target, verifies it and moves it
+// into place.
+func restoreFile(r io.Reader, res Restored) error {
+	if err := os.MkdirAll(filepath.Dir(res.Target), 0o755); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", res.Target, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(res.Target), ".restore-*")
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", res.Target, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to restore %s: %w", res.Target, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", res.Target, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != res.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: archive is corrupt", res.File)
+	}
+	// Assets may hold credentials, so they are never world-readable
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", res.Target, err)
+	}
+	if err := os.Rename(tmp.Name(), res.Target); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", res.Target, err)
+	}
+	return nil
+}
+
+// writeEntry adds a regular file to the archive.
+func writeEntry(tw *tar.Writer, name string, size int64, write func(io.Writer) error) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    size,
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return write(tw)
+}
+
+// hashFile returns the hex SHA-256 of a file.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,109 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportImport(t *testing.T) {
+	src := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(src, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	assets := []Asset{
+		{Kind: KindConfig, Path: write("multiagent.yaml", "llm:\n  provider: local\n")},
+		{Kind: KindRedactionPolicy, Path: write("redaction.yaml", "tables: {}\n")},
+		{Kind: KindSemanticCache, Path: filepath.Join(src, "never-written.json")},
+		{Kind: KindUsage},
+		{Kind: KindDashboard, Path: write("sales.yaml", "panels: []\n")},
+	}
+
+	var archive bytes.Buffer
+	m, err := Export(&archive, assets)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(m.Assets) != 3 {
+		t.Fatalf("exported %d assets, want 3 (missing and unset files skipped)", len(m.Assets))
+	}
+
+	dst := t.TempDir()
+	target := func(a Asset) string {
+		if a.Kind == KindRedactionPolicy {
+			return ""
+		}
+		return filepath.Join(dst, filepath.Base(a.Path))
+	}
+	_, restored, err := Import(bytes.NewReader(archive.Bytes()), target, false)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(restored) != 2 {
+		t.Fatalf("restored %d assets, want 2", len(restored))
+	}
+	got, err := os.ReadFile(filepath.Join(dst, "sales.yaml"))
+	if err != nil || string(got) != "panels: []\n" {
+		t.Errorf("restored dashboard = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "redaction.yaml")); err == nil {
+		t.Error("skipped asset was restored")
+	}
+
+	// A second restore must not overwrite without being told to
+	if _, _, err := Import(bytes.NewReader(archive.Bytes()), target, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Import() over existing files error = %v, want already exists", err)
+	}
+	if _, _, err := Import(bytes.NewReader(archive.Bytes()), target, true); err != nil {
+		t.Errorf("Import() with overwrite error = %v", err)
+	}
+}
+
+func TestImportRejects(t *testing.T) {
+	archive := func(manifest, file, content string) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for _, e := range [][2]string{{manifestName, manifest}, {file, content}} {
+			if e[0] == "" {
+				continue
+			}
+			tw.WriteHeader(&tar.Header{Name: e[0], Mode: 0o600, Size: int64(len(e[1]))})
+			tw.Write([]byte(e[1]))
+		}
+		tw.Close()
+		gz.Close()
+		return buf.Bytes()
+	}
+	asset := `{"kind":"config","path":"multiagent.yaml","file":"assets/01-multiagent.yaml","sha256":"00"}`
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "newer version", data: archive(`{"version":2,"assets":[]}`, "", ""), wantErr: "unsupported backup version 2"},
+		{name: "corrupt file", data: archive(`{"version":1,"assets":[`+asset+`]}`, "assets/01-multiagent.yaml", "x"), wantErr: "checksum mismatch"},
+		{name: "missing file", data: archive(`{"version":1,"assets":[`+asset+`]}`, "", ""), wantErr: "archive is missing"},
+		{name: "not an archive", data: []byte("hello"), wantErr: "failed to read archive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			target := func(a Asset) string { return filepath.Join(dir, filepath.Base(a.Path)) }
+			_, _, err := Import(bytes.NewReader(tt.data), target, false)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Import() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Package batch answers a list of questions non-interactively, for scripts
+// and scheduled reports.
+package batch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/report"
+)
+
+// Output formats.
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+)
+
+// userID identifies batch runs in sessions and the audit log.
+const userID = "batch"
+
+// Config holds configuration for a batch run.
+type Config struct {
+	// App executes the questions
+	App *app.App
+	// Format is FormatJSON or FormatMarkdown
+	Format string
+	// Out receives the results
+	Out io.Writer
+	// DryRun generates SQL without executing it
+	DryRun bool
+}
+
+// Result is the outcome of one question in JSON output.
+type Result struct {
+	Query string    `json:"query"`
+	Turn  *app.Turn `json:"turn,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// ErrFailed is returned by Run when at least one question failed.
+var ErrFailed = errors.New("one or more queries failed")
+
+// Run answers queries in order within a single session, so later questions
+// can refer to earlier answers, and writes the results to cfg.Out.
+func Run(ctx context.Context, cfg Config, queries []string) error {
+	if cfg.App == nil {
+		return fmt.Errorf("batch requires an app")
+	}
+	if cfg.Format != FormatJSON && cfg.Format != FormatMarkdown {
+		return fmt.Errorf("unknown output format %q (want %s or %s)", cfg.Format, FormatJSON, FormatMarkdown)
+	}
+
+	sessionID := "batch-" + time.Now().UTC().Format("20060102T150405")
+	if cfg.DryRun {
+		ctx = sqlagent.WithDryRun(ctx)
+	}
+
+	var results []Result
+	failed := false
+	for i, query := range queries {
+		console.Printf("⏳ [BATCH] %d/%d: %s\n", i+1, len(queries), query)
+
+		turn, err := cfg.App.Ask(ctx, userID, sessionID, query, nil)
+		result := Result{Query: query, Turn: turn}
+		if err != nil {
+			result.Error = err.Error()
+			failed = true
+			console.Printf("❌ [BATCH] %v\n", err)
+		}
+		results = append(results, result)
+
+		if cfg.Format == FormatMarkdown {
+			if err := writeMarkdown(cfg.Out, result); err != nil {
+				return err
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if cfg.Format == FormatJSON {
+		enc := json.NewEncoder(cfg.Out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+	}
+
+	if failed {
+		return ErrFailed
+	}
+	return nil
+}
+
+func writeMarkdown(w io.Writer, result Result) error {
+	if result.Error != "" && result.Turn == nil {
+		_, err := fmt.Fprintf(w, "## %s\n\n> Error: %s\n\n", result.Query, result.Error)
+		return err
+	}
+	if err := report.WriteMarkdown(w, result.Query, result.Turn); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	if result.Error != "" {
+		_, err := fmt.Fprintf(w, "> Error: %s\n\n", result.Error)
+		return err
+	}
+	return nil
+}
+
+// ReadQueries reads one question per line, skipping blank lines and lines
+// starting with "#".
+func ReadQueries(r io.Reader) ([]string, error) {
+	var queries []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		queries = append(queries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read queries: %w", err)
+	}
+	return queries, nil
+}
@@ -0,0 +1,440 @@
+// Package bigquery implements the SQL agent's database client for a
+// BigQuery dataset, through the BigQuery REST API. Queries run as jobs in
+// the configured project; each is dry-run first so the bytes it would
+// process can be checked against a limit before it is billed.
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/dataset"
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+)
+
+const (
+	// Endpoint is the BigQuery REST API.
+	Endpoint = "https://bigquery.googleapis.com/bigquery/v2"
+	// Scope is the OAuth scope the client requests.
+	Scope = "https://www.googleapis.com/auth/bigquery"
+)
+
+// pageSize is the number of rows fetched per results page.
+const pageSize = 10000
+
+// Config holds configuration for the BigQuery client.
+type Config struct {
+	// Project is the project jobs run and are billed in
+	Project string
+	// Dataset is the dataset listed to the agents and used for unqualified table names
+	Dataset string
+	// Location is the location jobs run in, e.g. "EU" (optional)
+	Location string
+	// CredentialsFile is a service-account key file (default: Application Default Credentials)
+	CredentialsFile string
+	// MaxBytes rejects queries whose dry run estimates more bytes processed (0: no limit)
+	MaxBytes int64
+	// Results sets the memory budget and spill directory for query results
+	Results dataset.Config
+	// HTTPClient sends the requests, already authenticated (default: one
+	// authorized with the credentials above)
+	HTTPClient *http.Client
+	// Endpoint overrides the API URL (default: Endpoint)
+	Endpoint string
+}
+
+// Client queries a BigQuery dataset. It implements sqlagent.MCPClient.
+type Client struct {
+	// Introspector runs the dataset's INFORMATION_SCHEMA queries
+	dialect.Introspector
+	cfg  Config
+	http *http.Client
+}
+
+// New creates a client and checks that the dataset can be read.
+func New(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.Project == "" || cfg.Dataset == "" {
+		return nil, fmt.Errorf("bigquery: project and dataset are required")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = Endpoint
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+			Scopes:          []string{Scope},
+			CredentialsFile: cfg.CredentialsFile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load BigQuery credentials: %w", err)
+		}
+		client, err = httptransport.NewClient(&httptransport.Options{Credentials: creds})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create BigQuery client: %w", err)
+		}
+	}
+
+	c := &Client{cfg: cfg, http: client}
+	c.Introspector = dialect.Introspector{Dialect: dialect.BigQuery{Project: cfg.Project, Dataset: cfg.Dataset}, Query: c.metadata}
+	if err := c.call(ctx, http.MethodGet, c.datasetPath(), nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to open dataset %s.%s: %w", cfg.Project, cfg.Dataset, err)
+	}
+	return c, nil
+}
+
+// SetResultStorage sets the memory budget and spill directory used while
+// reading query results.
+func (c *Client) SetResultStorage(cfg dataset.Config) {
+	c.cfg.Results = cfg
+}
+
+// DryRun returns the number of bytes query would process, without running
+// it.
+func (c *Client) DryRun(ctx context.Context, query string) (int64, error) {
+	job, err := c.insertJob(ctx, query, true, sqlagent.ArgsFrom(ctx)...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(job.Statistics.TotalBytesProcessed, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bigquery: dry run returned no byte estimate")
+	}
+	return n, nil
+}
+
+// Query runs a query as a job and returns its rows as JSON.
+func (c *Client) Query(ctx context.Context, query string, limit int) (string, error) {
+	// Add LIMIT if not present and it's a SELECT query
+	query = c.Dialect.Limit(query, limit)
+
+	if c.cfg.MaxBytes > 0 {
+		n, err := c.DryRun(ctx, query)
+		if err != nil {
+			return "", fmt.Errorf("query error: %w", err)
+		}
+		if n > c.cfg.MaxBytes {
+			return "", fmt.Errorf("query would process %s, over the %s limit; filter on partition or clustering columns or select fewer columns", formatBytes(n), formatBytes(c.cfg.MaxBytes))
+		}
+	}
+
+	job, err := c.insertJob(ctx, query, false, sqlagent.ArgsFrom(ctx)...)
+	if err != nil {
+		return "", fmt.Errorf("query error: %w", err)
+	}
+
+	var result *sqlagent.Results
+	defer func() {
+		if result != nil {
+			result.Close()
+		}
+	}()
+	err = c.readResults(ctx, job.JobReference, func(page *queryResults) error {
+		if result == nil {
+			result = sqlagent.NewResults(ctx, query, page.Schema.columns(), c.cfg.Results)
+		}
+		for _, row := range page.Rows {
+			values, err := page.Schema.convert(row)
+			if err != nil {
+				return err
+			}
+			if err := result.Append(values); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("query error: %w", err)
+	}
+	if result == nil {
+		return "[]", nil
+	}
+	return result.JSON()
+}
+
+// GetSchema returns the columns of a table as JSON. It reads the table's
+// metadata, which unlike an INFORMATION_SCHEMA query runs no job.
+func (c *Client) GetSchema(ctx context.Context, tableName string) (string, error) {
+	var table struct {
+		Schema schema `json:"schema"`
+	}
+	if err := c.call(ctx, http.MethodGet, c.datasetPath()+"/tables/"+url.PathEscape(tableName), nil, &table); err != nil {
+		return "", fmt.Errorf("query error: %w", err)
+	}
+
+	schema := []map[string]interface{}{}
+	for _, f := range table.Schema.Fields {
+		col := map[string]interface{}{
+			"column_name": f.Name,
+			"data_type":   f.dataType(),
+			"nullable":    f.Mode != "REQUIRED",
+		}
+		if f.Description != "" {
+			col["description"] = f.Description
+		}
+		schema = append(schema, col)
+	}
+	jsonResult, err := json.Marshal(schema)
+	if err != nil {
+		return "", fmt.Errorf("json error: %w", err)
+	}
+	return string(jsonResult), nil
+}
+
+// ListTables returns the tables and views of the dataset as JSON, listed
+// through the API rather than INFORMATION_SCHEMA for the same reason.
+func (c *Client) ListTables(ctx context.Context) (string, error) {
+	tables := []string{}
+	token := ""
+	for {
+		var page struct {
+			Tables []struct {
+				TableReference struct {
+					TableID string `json:"tableId"`
+				} `json:"tableReference"`
+			} `json:"tables"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		path := c.datasetPath() + "/tables?maxResults=1000"
+		if token != "" {
+			path += "&pageToken=" + url.QueryEscape(token)
+		}
+		if err := c.call(ctx, http.MethodGet, path, nil, &page); err != nil {
+			return "", fmt.Errorf("query error: %w", err)
+		}
+		for _, t := range page.Tables {
+			tables = append(tables, t.TableReference.TableID)
+		}
+		if token = page.NextPageToken; token == "" {
+			break
+		}
+	}
+
+	jsonResult, err := json.Marshal(tables)
+	if err != nil {
+		return "", fmt.Errorf("json error: %w", err)
+	}
+	return string(jsonResult), nil
+}
+
+// Close releases idle connections.
+func (c *Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// metadata runs an INFORMATION_SCHEMA query, binding args as the named
+// parameters @p1, @p2... of the BigQuery dialect.
+func (c *Client) metadata(ctx context.Context, query string, args ...interface{}) ([][]interface{}, error) {
+	job, err := c.insertJob(ctx, query, false, args...)
+	if err != nil {
+		return nil, err
+	}
+	var out [][]interface{}
+	err = c.readResults(ctx, job.JobReference, func(page *queryResults) error {
+		for _, row := range page.Rows {
+			values, err := page.Schema.convert(row)
+			if err != nil {
+				return err
+			}
+			out = append(out, values)
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (c *Client) datasetPath() string {
+	return "/projects/" + url.PathEscape(c.cfg.Project) + "/datasets/" + url.PathEscape(c.cfg.Dataset)
+}
+
+type jobReference struct {
+	ProjectID string `json:"projectId"`
+	JobID     string `json:"jobId"`
+	Location  string `json:"location,omitempty"`
+}
+
+type job struct {
+	JobReference jobReference `json:"jobReference"`
+	Statistics   struct {
+		TotalBytesProcessed string `json:"totalBytesProcessed"`
+	} `json:"statistics"`
+}
+
+// insertJob starts a query job, or dry-runs it. args are bound as the
+// named parameters @p1, @p2...
+func (c *Client) insertJob(ctx context.Context, query string, dryRun bool, args ...interface{}) (*job, error) {
+	id := make([]byte, 12)
+	rand.Read(id)
+	queryConfig := map[string]interface{}{
+		"query":          query,
+		"useLegacySql":   false,
+		"defaultDataset": map[string]string{"projectId": c.cfg.Project, "datasetId": c.cfg.Dataset},
+	}
+	if len(args) > 0 {
+		params := make([]map[string]interface{}, len(args))
+		for i, arg := range args {
+			params[i] = queryParameter(fmt.Sprintf("p%d", i+1), arg)
+		}
+		queryConfig["parameterMode"] = "NAMED"
+		queryConfig["queryParameters"] = params
+	}
+	if c.cfg.MaxBytes > 0 {
+		queryConfig["maximumBytesBilled"] = strconv.FormatInt(c.cfg.MaxBytes, 10)
+	}
+	body := map[string]interface{}{
+		"jobReference":  jobReference{ProjectID: c.cfg.Project, JobID: "multiagent_" + hex.EncodeToString(id), Location: c.cfg.Location},
+		"configuration": map[string]interface{}{"dryRun": dryRun, "query": queryConfig},
+	}
+
+	var j job
+	if err := c.call(ctx, http.MethodPost, "/projects/"+url.PathEscape(c.cfg.Project)+"/jobs", body, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// queryParameter is a named query parameter typed after the JSON value
+// given: numbers are INT64 if whole, else FLOAT64; NULL is a NULL STRING.
+func queryParameter(name string, v interface{}) map[string]interface{} {
+	typ, value := "STRING", map[string]interface{}{}
+	switch v := v.(type) {
+	case nil:
+	case bool:
+		typ, value["value"] = "BOOL", strconv.FormatBool(v)
+	case float64:
+		typ, value["value"] = "FLOAT64", strconv.FormatFloat(v, 'f', -1, 64)
+		if v == float64(int64(v)) {
+			typ = "INT64"
+		}
+	default:
+		value["value"] = fmt.Sprint(v)
+	}
+	return map[string]interface{}{
+		"name":           name,
+		"parameterType":  map[string]string{"type": typ},
+		"parameterValue": value,
+	}
+}
+
+type queryResults struct {
+	JobComplete bool     `json:"jobComplete"`
+	Schema      schema   `json:"schema"`
+	Rows        []record `json:"rows"`
+	PageToken   string   `json:"pageToken"`
+}
+
+// readResults waits for a job and passes each page of its results to fn.
+// If ctx ends first, the job is cancelled.
+func (c *Client) readResults(ctx context.Context, ref jobReference, fn func(*queryResults) error) error {
+	token := ""
+	for {
+		params := url.Values{"maxResults": {strconv.Itoa(pageSize)}, "timeoutMs": {"10000"}}
+		if ref.Location != "" {
+			params.Set("location", ref.Location)
+		}
+		if token != "" {
+			params.Set("pageToken", token)
+		}
+
+		var page queryResults
+		path := "/projects/" + url.PathEscape(ref.ProjectID) + "/queries/" + url.PathEscape(ref.JobID) + "?" + params.Encode()
+		if err := c.call(ctx, http.MethodGet, path, nil, &page); err != nil {
+			if ctx.Err() != nil {
+				c.cancel(ref)
+			}
+			return err
+		}
+		if !page.JobComplete {
+			continue
+		}
+		if err := fn(&page); err != nil {
+			return err
+		}
+		if token = page.PageToken; token == "" {
+			return nil
+		}
+	}
+}
+
+// cancel asks BigQuery to stop a job whose results are no longer wanted.
+func (c *Client) cancel(ref jobReference) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	path := "/projects/" + url.PathEscape(ref.ProjectID) + "/jobs/" + url.PathEscape(ref.JobID) + "/cancel"
+	if ref.Location != "" {
+		path += "?location=" + url.QueryEscape(ref.Location)
+	}
+	c.call(ctx, http.MethodPost, path, nil, nil)
+}
+
+// call sends a request to the API and decodes the response into out.
+func (c *Client) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("json error: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.Endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error.Message != "" {
+			return errors.New(e.Error.Message)
+		}
+		return fmt.Errorf("bigquery returned %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode BigQuery response: %w", err)
+	}
+	return nil
+}
+
+// formatBytes formats n in the largest binary unit that keeps it >= 1.
+func formatBytes(n int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB", "PB"}
+	v := float64(n)
+	i := 0
+	for v >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", n)
+	}
+	return fmt.Sprintf("%.1f %s", v, units[i])
+}
@@ -0,0 +1,184 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeAPI serves the parts of the BigQuery API the client uses, for a
+// dataset with one table.
+type fakeAPI struct {
+	bytesProcessed string
+	queries        []string
+	polls          int
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(v interface{}) { json.NewEncoder(w).Encode(v) }
+	switch {
+	case r.URL.Path == "/projects/p/datasets/d":
+		reply(map[string]string{"id": "p:d"})
+	case r.URL.Path == "/projects/p/datasets/d/tables/orders":
+		reply(map[string]interface{}{"schema": map[string]interface{}{"fields": []map[string]string{
+			{"name": "id", "type": "INTEGER", "mode": "REQUIRED"},
+			{"name": "tags", "type": "STRING", "mode": "REPEATED"},
+		}}})
+	case r.URL.Path == "/projects/p/datasets/d/tables":
+		if r.URL.Query().Get("pageToken") == "" {
+			reply(map[string]interface{}{"tables": []interface{}{map[string]interface{}{"tableReference": map[string]string{"tableId": "orders"}}}, "nextPageToken": "2"})
+			return
+		}
+		reply(map[string]interface{}{"tables": []interface{}{map[string]interface{}{"tableReference": map[string]string{"tableId": "customers"}}}})
+	case r.URL.Path == "/projects/p/jobs" && r.Method == http.MethodPost:
+		var body struct {
+			JobReference  jobReference `json:"jobReference"`
+			Configuration struct {
+				DryRun bool `json:"dryRun"`
+				Query  struct {
+					Query string `json:"query"`
+				} `json:"query"`
+			} `json:"configuration"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !body.Configuration.DryRun {
+			f.queries = append(f.queries, body.Configuration.Query.Query)
+		}
+		reply(map[string]interface{}{"jobReference": body.JobReference, "statistics": map[string]string{"totalBytesProcessed": f.bytesProcessed}})
+	case strings.HasPrefix(r.URL.Path, "/projects/p/queries/"):
+		query := f.queries[len(f.queries)-1]
+		if strings.Contains(query, "INFORMATION_SCHEMA.COLUMNS") {
+			reply(map[string]interface{}{
+				"jobComplete": true,
+				"schema":      map[string]interface{}{"fields": []map[string]string{{"name": "table_name", "type": "STRING"}, {"name": "column_name", "type": "STRING"}, {"name": "data_type", "type": "STRING"}}},
+				"rows": []interface{}{
+					row("orders", "id", "INT64"),
+					row("orders", "total", "NUMERIC"),
+					row("customers", "name", "STRING"),
+				},
+			})
+			return
+		}
+		// The first poll finds the job still running
+		if f.polls++; f.polls == 1 {
+			reply(map[string]interface{}{"jobComplete": false})
+			return
+		}
+		reply(map[string]interface{}{
+			"jobComplete": true,
+			"schema": map[string]interface{}{"fields": []map[string]string{
+				{"name": "id", "type": "INTEGER"},
+				{"name": "total", "type": "NUMERIC"},
+				{"name": "paid", "type": "BOOLEAN"},
+				{"name": "at", "type": "TIMESTAMP"},
+			}},
+			"rows": []interface{}{row("1", "9.5", "true", "1.7e9"), row("2", nil, "false", nil)},
+		})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		reply(map[string]interface{}{"error": map[string]string{"message": "Not found: " + r.URL.Path}})
+	}
+}
+
+func row(values ...interface{}) map[string]interface{} {
+	f := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		f[i] = map[string]interface{}{"v": v}
+	}
+	return map[string]interface{}{"f": f}
+}
+
+func newClient(t *testing.T, api *fakeAPI, maxBytes int64) *Client {
+	t.Helper()
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	c, err := New(context.Background(), Config{Project: "p", Dataset: "d", MaxBytes: maxBytes, HTTPClient: srv.Client(), Endpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes int64
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "no limit",
+			want: `[{"id":1,"total":9.5,"paid":true,"at":"2023-11-14T22:13:20Z"},{"id":2,"total":null,"paid":false,"at":null}]`,
+		},
+		{
+			name:     "under limit",
+			maxBytes: 1 << 30,
+			want:     `[{"id":1,"total":9.5,"paid":true,"at":"2023-11-14T22:13:20Z"},{"id":2,"total":null,"paid":false,"at":null}]`,
+		},
+		{
+			name:     "over limit",
+			maxBytes: 1 << 20,
+			wantErr:  "would process 2.0 MB, over the 1.0 MB limit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeAPI{bytesProcessed: "2097152"}
+			c := newClient(t, api, tt.maxBytes)
+
+			got, err := c.Query(context.Background(), "SELECT * FROM orders", 10)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if len(api.queries) != 0 {
+					t.Errorf("ran %v after a failed dry run", api.queries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if api.queries[0] != "SELECT * FROM orders LIMIT 10" {
+				t.Errorf("query = %q", api.queries[0])
+			}
+		})
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	c := newClient(t, &fakeAPI{}, 0)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() (string, error)
+		want string
+	}{
+		{"GetSchema", func() (string, error) { return c.GetSchema(ctx, "orders") }, `[{"column_name":"id","data_type":"INT64","nullable":false},{"column_name":"tags","data_type":"ARRAY\u003cSTRING\u003e","nullable":true}]`},
+		{"ListTables", func() (string, error) { return c.ListTables(ctx) }, `["orders","customers"]`},
+		{"DescribeDatabase", func() (string, error) { return c.DescribeDatabase(ctx) }, `[{"columns":["id INT64","total NUMERIC"],"table":"orders"},{"columns":["name STRING"],"table":"customers"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := c.GetSchema(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "Not found") {
+		t.Errorf("GetSchema(missing) err = %v", err)
+	}
+}
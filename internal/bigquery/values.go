@@ -0,0 +1,136 @@
+package bigquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// field is a column of a result or table schema.
+type field struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Mode        string  `json:"mode"`
+	Description string  `json:"description"`
+	Fields      []field `json:"fields"`
+}
+
+// dataType returns the column's type in standard SQL spelling.
+func (f field) dataType() string {
+	t := f.Type
+	switch t {
+	case "INTEGER":
+		t = "INT64"
+	case "FLOAT":
+		t = "FLOAT64"
+	case "BOOLEAN":
+		t = "BOOL"
+	case "RECORD":
+		t = "STRUCT"
+	}
+	if f.Mode == "REPEATED" {
+		return "ARRAY<" + t + ">"
+	}
+	return t
+}
+
+type schema struct {
+	Fields []field `json:"fields"`
+}
+
+func (s schema) columns() []string {
+	columns := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		columns[i] = f.Name
+	}
+	return columns
+}
+
+// record is a row in the API's encoding: {"f": [{"v": value}, ...]}.
+type record struct {
+	F []struct {
+		V json.RawMessage `json:"v"`
+	} `json:"f"`
+}
+
+// convert decodes a row into Go values in column order.
+func (s schema) convert(r record) ([]interface{}, error) {
+	if len(r.F) != len(s.Fields) {
+		return nil, fmt.Errorf("row has %d values, want %d", len(r.F), len(s.Fields))
+	}
+	values := make([]interface{}, len(s.Fields))
+	for i, f := range s.Fields {
+		v, err := f.convert(r.F[i].V)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", f.Name, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// convert decodes a value of the field. The API sends scalars as strings,
+// arrays as [{"v": ...}] and structs as records.
+func (f field) convert(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if f.Mode == "REPEATED" {
+		var items []struct {
+			V json.RawMessage `json:"v"`
+		}
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		elem := f
+		elem.Mode = ""
+		out := make([]interface{}, len(items))
+		for i, item := range items {
+			v, err := elem.convert(item.V)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	if f.Type == "RECORD" || f.Type == "STRUCT" {
+		var r record
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, err
+		}
+		values, err := schema{Fields: f.Fields}.convert(r)
+		if err != nil {
+			return nil, err
+		}
+		out := make(map[string]interface{}, len(values))
+		for i, sub := range f.Fields {
+			out[sub.Name] = values[i]
+		}
+		return out, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	switch f.Type {
+	case "INTEGER", "INT64":
+		return strconv.ParseInt(s, 10, 64)
+	case "FLOAT", "FLOAT64", "NUMERIC", "BIGNUMERIC":
+		return strconv.ParseFloat(s, 64)
+	case "BOOLEAN", "BOOL":
+		return strconv.ParseBool(s)
+	case "TIMESTAMP":
+		// Seconds since the epoch, with microseconds as the fraction
+		secs, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		whole, frac := math.Modf(secs)
+		return time.Unix(int64(whole), int64(math.Round(frac*1e6))*1000).UTC().Format(time.RFC3339Nano), nil
+	}
+	return s, nil
+}
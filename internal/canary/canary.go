@@ -0,0 +1,311 @@
+// Package canary checks a candidate model or prompt set before it becomes
+// the default: it replays recent successful questions from the event log
+// and compares the candidate's SQL and answers with the recorded ones.
+package canary
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/semcache"
+	"github.com/anuvratrastogi/multi-agent/internal/sqlcompare"
+)
+
+// UserID identifies replayed turns, which are never sampled themselves.
+const UserID = "canary"
+
+// DefaultMinSimilarity is the answer similarity below which a replay counts
+// as a regression.
+const DefaultMinSimilarity = 0.5
+
+// Verdicts comparing the candidate's SQL with the recorded SQL.
+const (
+	// SQLIdentical means the statements match after normalizing whitespace
+	// and case
+	SQLIdentical = "identical"
+	// SQLEquivalent means the statements differ but return the same rows
+	SQLEquivalent = "equivalent"
+	// SQLDifferent means the statements return different rows
+	SQLDifferent = "different"
+	// SQLMissing means the candidate ran no successful query
+	SQLMissing = "missing"
+	// SQLFailed means the candidate's turn failed
+	SQLFailed = "failed"
+)
+
+// Turn is a successful turn recorded in the event log.
+type Turn struct {
+	Time     time.Time `json:"time"`
+	Question string    `json:"question"`
+	// SQL is the last query the turn ran successfully
+	SQL    string `json:"sql"`
+	Answer string `json:"answer"`
+}
+
+// LoadTurns reads the most recent successful turns from an event log, at
+// most n and one per question, oldest first. Turns that were cached,
+// stopped early, dry runs or ran no query are skipped, as are earlier
+// replays.
+func LoadTurns(path string, n int) ([]Turn, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	defer f.Close()
+
+	type pending struct {
+		turn   Turn
+		dryRun bool
+	}
+	open := make(map[string]*pending)
+	var turns []Turn
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var e struct {
+			Kind string `json:"kind"`
+			events.Header
+			Question string `json:"question"`
+			Resumed  bool   `json:"resumed"`
+			SQL      string `json:"sql"`
+			DryRun   bool   `json:"dry_run"`
+			Rejected bool   `json:"rejected"`
+			Error    string `json:"error"`
+			Cached   bool   `json:"cached"`
+			Stopped  string `json:"stopped"`
+			Answer   string `json:"answer"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.UserID == UserID {
+			continue
+		}
+		key := e.UserID + "/" + e.SessionID
+
+		switch e.Kind {
+		case events.KindTurnStarted:
+			if e.Resumed {
+				delete(open, key)
+				continue
+			}
+			open[key] = &pending{turn: Turn{Time: e.Time, Question: e.Question}}
+		case events.KindSQLExecuted:
+			if p := open[key]; p != nil && e.Error == "" && !e.Rejected {
+				p.turn.SQL = e.SQL
+				p.dryRun = p.dryRun || e.DryRun
+			}
+		case events.KindTurnCompleted:
+			p := open[key]
+			delete(open, key)
+			if p == nil || e.Error != "" || e.Cached || e.Stopped != "" || p.dryRun || p.turn.SQL == "" {
+				continue
+			}
+			p.turn.Answer = e.Answer
+			turns = append(turns, p.turn)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	// Keep the latest turn of each question
+	seen := make(map[string]bool)
+	var recent []Turn
+	for i := len(turns) - 1; i >= 0 && len(recent) < n; i-- {
+		q := strings.ToLower(strings.TrimSpace(turns[i].Question))
+		if !seen[q] {
+			seen[q] = true
+			recent = append(recent, turns[i])
+		}
+	}
+	slices.Reverse(recent)
+	return recent, nil
+}
+
+// Config holds configuration for a canary run.
+type Config struct {
+	// App answers the questions with the candidate model or prompts
+	App *app.App
+	// DB re-runs the recorded SQL to compare results
+	DB sqlagent.MCPClient
+	// MinSimilarity is the answer similarity below which a replay is a
+	// regression (defaults to DefaultMinSimilarity)
+	MinSimilarity float64
+	// Embedder compares answers (defaults to semcache.HashEmbedder)
+	Embedder semcache.Embedder
+}
+
+// Result compares the candidate's replay of a turn with the recording.
+type Result struct {
+	Question     string `json:"question"`
+	BaselineSQL  string `json:"baseline_sql"`
+	CandidateSQL string `json:"candidate_sql,omitempty"`
+	SQL          string `json:"sql"`
+	// Similarity compares the answers, if the recording has one
+	Similarity *float64 `json:"answer_similarity,omitempty"`
+	Regression bool     `json:"regression"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// Report summarizes a canary run.
+type Report struct {
+	Results     []Result `json:"results"`
+	Regressions int      `json:"regressions"`
+}
+
+// ErrRegressions is returned by Run when at least one replay regressed.
+var ErrRegressions = errors.New("the candidate regressed on one or more questions")
+
+// Run replays each turn in a fresh session and compares the outcome with
+// the recording.
+func Run(ctx context.Context, cfg Config, turns []Turn) (*Report, error) {
+	if cfg.App == nil || cfg.DB == nil {
+		return nil, fmt.Errorf("canary requires an app and a database")
+	}
+	if cfg.MinSimilarity <= 0 {
+		cfg.MinSimilarity = DefaultMinSimilarity
+	}
+	if cfg.Embedder == nil {
+		cfg.Embedder = semcache.HashEmbedder{}
+	}
+
+	r := &Report{}
+	run := time.Now().UTC().Format("20060102T150405")
+	for i, t := range turns {
+		console.Printf("⏳ [CANARY] %d/%d: %s\n", i+1, len(turns), t.Question)
+
+		sessionID := fmt.Sprintf("canary-%s-%d", run, i+1)
+		turn, err := cfg.App.Ask(app.WithFresh(ctx), UserID, sessionID, t.Question, nil)
+		res := compare(ctx, cfg, t, turn, err)
+		if res.Regression {
+			r.Regressions++
+		}
+		r.Results = append(r.Results, res)
+		if ctx.Err() != nil {
+			return r, ctx.Err()
+		}
+	}
+
+	if r.Regressions > 0 {
+		return r, ErrRegressions
+	}
+	return r, nil
+}
+
+// compare judges the candidate's turn against the recorded one.
+func compare(ctx context.Context, cfg Config, t Turn, turn *app.Turn, err error) Result {
+	res := Result{Question: t.Question, BaselineSQL: t.SQL}
+	switch {
+	case err != nil:
+		res.SQL, res.Error, res.Regression = SQLFailed, err.Error(), true
+		return res
+	case turn.Stopped != "":
+		res.SQL, res.Error, res.Regression = SQLFailed, turn.Stopped, true
+		return res
+	}
+
+	for _, q := range turn.Queries {
+		if q.Error == "" {
+			res.CandidateSQL = q.SQL
+		}
+	}
+	switch {
+	case res.CandidateSQL == "":
+		res.SQL = SQLMissing
+	case sqlcompare.Normalize(res.CandidateSQL) == sqlcompare.Normalize(t.SQL):
+		res.SQL = SQLIdentical
+	default:
+		res.SQL = SQLDifferent
+		same, err := sameRows(ctx, cfg.DB, t.SQL, res.CandidateSQL)
+		if err != nil {
+			res.Error = err.Error()
+		} else if same {
+			res.SQL = SQLEquivalent
+		}
+	}
+
+	res.Regression = res.SQL == SQLMissing || res.SQL == SQLDifferent
+	// Event logs written before answers were recorded have none to compare
+	if t.Answer != "" {
+		sim := similarity(ctx, cfg.Embedder, t.Answer, turn.Text)
+		res.Similarity = &sim
+		res.Regression = res.Regression || sim < cfg.MinSimilarity
+	}
+	return res
+}
+
+// sameRows re-runs the recorded and the candidate's SQL, with the same row
+// limit, and reports whether they return the same rows in any order and
+// under any column names.
+func sameRows(ctx context.Context, db sqlagent.MCPClient, baselineSQL, candidateSQL string) (bool, error) {
+	a, err := sqlcompare.Rows(sqlagent.WithSession(ctx, UserID, "baseline"), db, baselineSQL)
+	if err != nil {
+		return false, fmt.Errorf("recorded SQL no longer runs: %w", err)
+	}
+	b, err := sqlcompare.Rows(sqlagent.WithSession(ctx, UserID, "candidate"), db, candidateSQL)
+	if err != nil {
+		return false, err
+	}
+	return slices.Equal(a, b), nil
+}
+
+// similarity returns the cosine similarity of two answers.
+func similarity(ctx context.Context, e semcache.Embedder, a, b string) float64 {
+	va, err := e.Embed(ctx, a)
+	if err != nil {
+		return 0
+	}
+	vb, err := e.Embed(ctx, b)
+	if err != nil {
+		return 0
+	}
+	return semcache.Cosine(va, vb)
+}
+
+// WriteText writes the report as a plain-text table followed by the SQL of
+// each regression.
+func WriteText(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tSQL\tANSWER\tQUESTION")
+	for _, res := range r.Results {
+		result := "ok"
+		if res.Regression {
+			result = "REGRESSION"
+		}
+		answer := "-"
+		if res.Similarity != nil {
+			answer = fmt.Sprintf("%.2f", *res.Similarity)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", result, res.SQL, answer, res.Question)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, res := range r.Results {
+		if !res.Regression {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\n  baseline:  %s\n", res.Question, sqlcompare.OneLine(res.BaselineSQL))
+		if res.CandidateSQL != "" {
+			fmt.Fprintf(w, "  candidate: %s\n", sqlcompare.OneLine(res.CandidateSQL))
+		}
+		if res.Error != "" {
+			fmt.Fprintf(w, "  error:     %s\n", res.Error)
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d of %d questions regressed\n", r.Regressions, len(r.Results))
+	return err
+}
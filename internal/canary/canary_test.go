@@ -0,0 +1,129 @@
+package canary
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestLoadTurns(t *testing.T) {
+	at := func(user, session string, e events.Event) events.Event {
+		switch e := e.(type) {
+		case *events.TurnStarted:
+			e.UserID, e.SessionID, e.Time = user, session, time.Now()
+		case *events.SQLExecuted:
+			e.UserID, e.SessionID = user, session
+		case *events.TurnCompleted:
+			e.UserID, e.SessionID = user, session
+		}
+		return e
+	}
+	log := []events.Event{
+		at("alice", "s1", &events.TurnStarted{Question: "How many orders?"}),
+		at("bob", "s2", &events.TurnStarted{Question: "Revenue by month"}),
+		at("alice", "s1", &events.SQLExecuted{SQL: "SELECT count(*) FROM order"}),
+		at("alice", "s1", &events.SQLExecuted{SQL: "SELECT count(*) FROM orders"}),
+		at("bob", "s2", &events.SQLExecuted{SQL: "SELECT 1", Error: "boom"}),
+		at("alice", "s1", &events.TurnCompleted{Answer: "There are 4 orders."}),
+		at("bob", "s2", &events.TurnCompleted{}),
+		at("alice", "s3", &events.TurnStarted{Question: "Hello"}),
+		at("alice", "s3", &events.TurnCompleted{Answer: "Hi!"}),
+		at("alice", "s4", &events.TurnStarted{Question: "how many orders?"}),
+		at("alice", "s4", &events.SQLExecuted{SQL: "SELECT count(id) FROM orders"}),
+		at("alice", "s4", &events.TurnCompleted{Answer: "4 orders."}),
+		at(UserID, "c1", &events.TurnStarted{Question: "Top customers"}),
+		at(UserID, "c1", &events.SQLExecuted{SQL: "SELECT name FROM customers"}),
+		at(UserID, "c1", &events.TurnCompleted{Answer: "Alice."}),
+	}
+	var lines []string
+	for _, e := range log {
+		line, err := events.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, string(line))
+	}
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	turns, err := LoadTurns(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Bob's query failed, "Hello" ran no query, the repeated question keeps
+	// its latest turn and replays are skipped
+	if len(turns) != 1 || turns[0].SQL != "SELECT count(id) FROM orders" || turns[0].Answer != "4 orders." {
+		t.Errorf("LoadTurns() = %+v", turns)
+	}
+}
+
+// ordersDB returns the same count for any count query and names otherwise.
+type ordersDB struct {
+	sqlagent.MCPClient
+}
+
+func (ordersDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	if strings.Contains(strings.ToLower(query), "count") {
+		return `[{"count":4}]`, nil
+	}
+	return `[{"name":"Alice"},{"name":"Bob"}]`, nil
+}
+
+func TestRun(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 4 orders."}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT COUNT(*) AS n FROM orders"}}}}},
+	)
+	a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, ordersDB{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	turns := []Turn{
+		{Question: "How many orders?", SQL: "select count(*) as n\nfrom orders;", Answer: "There are 4 orders."},
+		{Question: "Count the orders", SQL: "SELECT count(id) FROM orders", Answer: "There are 4 orders."},
+		{Question: "Who are the customers?", SQL: "SELECT name FROM customers", Answer: "Alice and Bob."},
+	}
+	r, err := Run(context.Background(), Config{App: a, DB: ordersDB{}}, turns)
+	if err != ErrRegressions {
+		t.Fatalf("Run() error = %v, want ErrRegressions", err)
+	}
+
+	want := []struct {
+		sql        string
+		regression bool
+	}{
+		{SQLIdentical, false},
+		{SQLEquivalent, false},
+		{SQLDifferent, true},
+	}
+	for i, w := range want {
+		got := r.Results[i]
+		if got.SQL != w.sql || got.Regression != w.regression {
+			t.Errorf("%s: sql = %s, regression = %v; want %s, %v", got.Question, got.SQL, got.Regression, w.sql, w.regression)
+		}
+	}
+	if r.Regressions != 1 {
+		t.Errorf("Regressions = %d, want 1", r.Regressions)
+	}
+
+	var out strings.Builder
+	if err := WriteText(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "1 of 3 questions regressed") || !strings.Contains(out.String(), "baseline:  SELECT name FROM customers") {
+		t.Errorf("WriteText() =\n%s", out.String())
+	}
+}
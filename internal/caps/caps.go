@@ -0,0 +1,200 @@
+// Package caps enforces hard caps on query results in the database client:
+// the rows returned, whatever limit the model asked for, the columns of
+// each row and the size of the encoded result. Results over a cap are cut
+// and the cut is reported to the query tool, which returns a truncation
+// notice to the agent along with the rows.
+package caps
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/frame"
+)
+
+// Config holds the caps. Zero means no cap.
+type Config struct {
+	// MaxRows is the maximum number of rows a query returns
+	MaxRows int
+	// MaxColumns is the maximum number of columns of each row; the first
+	// columns are kept
+	MaxColumns int
+	// MaxBytes is the maximum size of the encoded rows
+	MaxBytes int
+}
+
+// Client wraps an MCPClient and cuts query results to the caps.
+type Client struct {
+	sqlagent.MCPClient
+	cfg Config
+}
+
+// NewClient creates a client that caps the results of inner.
+func NewClient(inner sqlagent.MCPClient, cfg Config) *Client {
+	return &Client{MCPClient: inner, cfg: cfg}
+}
+
+// Query executes the query with a limit of at most MaxRows and cuts the
+// result to the caps. Results that are not rows pass unchanged.
+func (c *Client) Query(ctx context.Context, query string, limit int) (string, error) {
+	if c.cfg.MaxRows > 0 && (limit <= 0 || limit > c.cfg.MaxRows) {
+		// One row more tells whether there were rows past the cap
+		limit = c.cfg.MaxRows + 1
+	}
+	data, err := c.MCPClient.Query(ctx, query, limit)
+	if err != nil {
+		return "", err
+	}
+	capped, t, ok := c.cut(data)
+	if !ok {
+		return data, nil
+	}
+	if s := sqlagent.SpillFrom(ctx); s != nil {
+		// data holds the leading rows only; the caps count all of them
+		if t, err = c.cutSpill(s); err != nil {
+			return "", err
+		}
+	}
+	if t != nil {
+		sqlagent.ReportTruncation(ctx, t)
+	}
+	return capped, nil
+}
+
+// cut returns data cut to the caps and how, or nil if it fits. It returns
+// false if data is not a JSON array of rows.
+func (c *Client) cut(data string) (string, *sqlagent.Truncation, bool) {
+	if c.cfg == (Config{}) {
+		return data, nil, true
+	}
+	f, err := frame.Parse(data)
+	if err != nil {
+		return "", nil, false
+	}
+
+	t := &sqlagent.Truncation{}
+	if c.cfg.MaxRows > 0 && len(f.Rows) > c.cfg.MaxRows {
+		f.Rows = f.Rows[:c.cfg.MaxRows]
+		t.Reasons = append(t.Reasons, sqlagent.CapRows)
+	}
+	if c.cfg.MaxColumns > 0 && len(f.Columns) > c.cfg.MaxColumns {
+		t.DroppedColumns = f.Columns[c.cfg.MaxColumns:]
+		f.Columns = f.Columns[:c.cfg.MaxColumns]
+		for i, row := range f.Rows {
+			f.Rows[i] = row[:c.cfg.MaxColumns]
+		}
+		t.Reasons = append(t.Reasons, sqlagent.CapColumns)
+	}
+	if len(t.Reasons) > 0 {
+		if data, err = f.JSON(); err != nil {
+			return "", nil, false
+		}
+	}
+	if c.cfg.MaxBytes > 0 && len(data) > c.cfg.MaxBytes {
+		if data, err = fit(data, c.cfg.MaxBytes); err != nil {
+			return "", nil, false
+		}
+		t.Reasons = append(t.Reasons, sqlagent.CapBytes)
+	}
+	if len(t.Reasons) == 0 {
+		return data, nil, true
+	}
+
+	var rows []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &rows); err != nil {
+		return "", nil, false
+	}
+	t.ReturnedRows = len(rows)
+	t.Notice = c.notice(t)
+	return data, t, true
+}
+
+// cutSpill cuts a spilled result to the caps and returns how, or nil if it
+// fits.
+func (c *Client) cutSpill(s *sqlagent.Spill) (*sqlagent.Truncation, error) {
+	t := &sqlagent.Truncation{}
+	if c.cfg.MaxRows > 0 && s.Len() > c.cfg.MaxRows {
+		s.Truncate(c.cfg.MaxRows)
+		t.Reasons = append(t.Reasons, sqlagent.CapRows)
+	}
+	if columns := s.Columns(); c.cfg.MaxColumns > 0 && len(columns) > c.cfg.MaxColumns {
+		t.DroppedColumns = columns[c.cfg.MaxColumns:]
+		s.Edit(func(row map[string]interface{}) {
+			for _, column := range t.DroppedColumns {
+				delete(row, column)
+			}
+		})
+		t.Reasons = append(t.Reasons, sqlagent.CapColumns)
+	}
+	if c.cfg.MaxBytes > 0 {
+		size, n := 1, 0
+		err := s.Each(func(row json.RawMessage) error {
+			// The row, its separator and the closing bracket must fit
+			if size+min(n, 1)+len(row)+1 > c.cfg.MaxBytes {
+				return errFull
+			}
+			size += min(n, 1) + len(row)
+			n++
+			return nil
+		})
+		switch {
+		case errors.Is(err, errFull):
+			s.Truncate(n)
+			t.Reasons = append(t.Reasons, sqlagent.CapBytes)
+		case err != nil:
+			return nil, err
+		}
+	}
+	if len(t.Reasons) == 0 {
+		return nil, nil
+	}
+	t.ReturnedRows = s.Len()
+	t.Notice = c.notice(t)
+	return t, nil
+}
+
+// errFull ends reading a spilled result at the byte cap.
+var errFull = errors.New("byte cap reached")
+
+// fit returns the leading rows of data that fit in maxBytes, encoded.
+func fit(data string, maxBytes int) (string, error) {
+	var rows []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &rows); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, r := range rows {
+		// The row, its separator and the closing bracket must fit
+		sep := min(i, 1)
+		if b.Len()+sep+len(r)+1 > maxBytes {
+			break
+		}
+		if sep > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(r)
+	}
+	b.WriteByte(']')
+	return b.String(), nil
+}
+
+// notice explains the cut to the agent.
+func (c *Client) notice(t *sqlagent.Truncation) string {
+	var cuts []string
+	for _, reason := range t.Reasons {
+		switch reason {
+		case sqlagent.CapRows:
+			cuts = append(cuts, fmt.Sprintf("the query returned more than %d rows", c.cfg.MaxRows))
+		case sqlagent.CapColumns:
+			cuts = append(cuts, fmt.Sprintf("only the first %d columns are kept; %s were left out", c.cfg.MaxColumns, strings.Join(t.DroppedColumns, ", ")))
+		case sqlagent.CapBytes:
+			cuts = append(cuts, fmt.Sprintf("the rows exceeded %d bytes", c.cfg.MaxBytes))
+		}
+	}
+	return fmt.Sprintf("The result was truncated to %d rows (%s). Tell the user the data is incomplete; aggregate or filter in SQL, or select fewer columns, to get a complete result.", t.ReturnedRows, strings.Join(cuts, "; "))
+}
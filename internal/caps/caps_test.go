@@ -0,0 +1,175 @@
+package caps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/dataset"
+	"google.golang.org/adk/tool"
+)
+
+// rowsClient returns up to limit rows of rows, each with columns a, b, c,
+// and records the limit.
+type rowsClient struct {
+	sqlagent.MCPClient
+	rows  int
+	limit int
+}
+
+func (c *rowsClient) Query(ctx context.Context, query string, limit int) (string, error) {
+	c.limit = limit
+	var rows []string
+	for i := 0; i < c.rows && (limit <= 0 || i < limit); i++ {
+		rows = append(rows, fmt.Sprintf(`{"a":%d,"b":"x","c":null}`, i))
+	}
+	return "[" + strings.Join(rows, ",") + "]", nil
+}
+
+func TestClientCaps(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		rows        int
+		limit       int
+		wantLimit   int
+		wantRows    int
+		wantReasons []string
+		wantDropped []string
+	}{
+		{"no caps", Config{}, 5, 100, 100, 5, nil, nil},
+		{"within the caps", Config{MaxRows: 10, MaxColumns: 3, MaxBytes: 1000}, 5, 100, 11, 5, nil, nil},
+		{"model limit below the cap", Config{MaxRows: 10}, 50, 5, 5, 5, nil, nil},
+		{"rows capped", Config{MaxRows: 10}, 50, 100, 11, 10, []string{sqlagent.CapRows}, nil},
+		{"columns capped", Config{MaxColumns: 2}, 3, 100, 100, 3, []string{sqlagent.CapColumns}, []string{"c"}},
+		// Each row is 24 bytes, with its separator 25
+		{"bytes capped", Config{MaxBytes: 80}, 5, 100, 100, 3, []string{sqlagent.CapBytes}, nil},
+		{"all caps", Config{MaxRows: 4, MaxColumns: 1, MaxBytes: 20}, 10, 0, 5, 2, []string{sqlagent.CapRows, sqlagent.CapColumns, sqlagent.CapBytes}, []string{"b", "c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &rowsClient{rows: tt.rows}
+			c := NewClient(inner, tt.cfg)
+
+			var got *sqlagent.Truncation
+			ctx := sqlagent.WithTruncation(context.Background(), &got)
+			data, err := c.Query(ctx, "SELECT * FROM t", tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if inner.limit != tt.wantLimit {
+				t.Errorf("limit = %d, want %d", inner.limit, tt.wantLimit)
+			}
+			var rows []map[string]any
+			if err := json.Unmarshal([]byte(data), &rows); err != nil {
+				t.Fatalf("result %s is not rows: %v", data, err)
+			}
+			if len(rows) != tt.wantRows {
+				t.Errorf("rows = %d, want %d", len(rows), tt.wantRows)
+			}
+			if tt.cfg.MaxBytes > 0 && len(data) > tt.cfg.MaxBytes {
+				t.Errorf("result is %d bytes, over the cap of %d", len(data), tt.cfg.MaxBytes)
+			}
+			if tt.wantReasons == nil {
+				if got != nil {
+					t.Errorf("truncation = %+v, want none", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("no truncation reported")
+			}
+			if !reflect.DeepEqual(got.Reasons, tt.wantReasons) || !reflect.DeepEqual(got.DroppedColumns, tt.wantDropped) || got.ReturnedRows != tt.wantRows {
+				t.Errorf("truncation = %+v, want reasons %v, dropped %v, %d rows", got, tt.wantReasons, tt.wantDropped, tt.wantRows)
+			}
+			if !strings.Contains(got.Notice, fmt.Sprintf("truncated to %d rows", tt.wantRows)) {
+				t.Errorf("notice = %q", got.Notice)
+			}
+		})
+	}
+}
+
+// spillClient returns up to limit rows of rows like rowsClient, collected
+// as the database clients do and spilling to disk past the first rows.
+type spillClient struct {
+	sqlagent.MCPClient
+	rows int
+	dir  string
+}
+
+func (c *spillClient) Query(ctx context.Context, query string, limit int) (string, error) {
+	results := sqlagent.NewResults(ctx, query, []string{"a", "b", "c"}, dataset.Config{Budget: dataset.NewBudget(100), Dir: c.dir})
+	defer results.Close()
+	for i := 0; i < c.rows && (limit <= 0 || i < limit); i++ {
+		if err := results.Append([]interface{}{int64(i), "x", nil}); err != nil {
+			return "", err
+		}
+	}
+	return results.JSON()
+}
+
+// toolContext is the part of a tool.Context the results use.
+type toolContext struct {
+	tool.Context
+}
+
+func (c toolContext) Value(key any) any { return context.Background().Value(key) }
+
+func TestClientCapsSpill(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		wantRows    int
+		wantReasons []string
+	}{
+		{"within the caps", Config{MaxRows: 1000}, 500, nil},
+		{"rows and columns capped", Config{MaxRows: 300, MaxColumns: 2}, 300, []string{sqlagent.CapRows, sqlagent.CapColumns}},
+		// Rows without c are 15 bytes up to a = 9, 16 up to 99 and 17 after,
+		// with their separator one more
+		{"bytes capped", Config{MaxColumns: 2, MaxBytes: 2000}, 117, []string{sqlagent.CapColumns, sqlagent.CapBytes}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient(&spillClient{rows: 500, dir: t.TempDir()}, tt.cfg)
+			var got *sqlagent.Truncation
+			ctx, capture := sqlagent.CaptureSpill(toolContext{})
+			defer capture.Close()
+			if _, err := c.Query(sqlagent.WithTruncation(ctx, &got), "SELECT * FROM t", 0); err != nil {
+				t.Fatal(err)
+			}
+
+			spill := capture.Spill()
+			size, rows := 1, 0
+			spill.Each(func(row json.RawMessage) error {
+				if strings.Contains(string(row), `"c"`) && tt.cfg.MaxColumns > 0 {
+					t.Errorf("row %s kept a dropped column", row)
+				}
+				size += len(row) + 1
+				rows++
+				return nil
+			})
+			if rows != tt.wantRows || spill.Len() != tt.wantRows {
+				t.Errorf("spilled rows = %d (Len %d), want %d", rows, spill.Len(), tt.wantRows)
+			}
+			if tt.cfg.MaxBytes > 0 && size > tt.cfg.MaxBytes {
+				t.Errorf("spilled rows are %d bytes, over the cap of %d", size, tt.cfg.MaxBytes)
+			}
+			if tt.wantReasons == nil {
+				if got != nil {
+					t.Errorf("truncation = %+v, want none", got)
+				}
+				return
+			}
+			if got == nil || !reflect.DeepEqual(got.Reasons, tt.wantReasons) || got.ReturnedRows != tt.wantRows {
+				t.Errorf("truncation = %+v, want reasons %v, %d rows", got, tt.wantReasons, tt.wantRows)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+// Package casts infers the types of values stored in text columns, so the
+// SQL agent can cast them before aggregating or comparing.
+package casts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the type of the values held by a text column.
+type Kind string
+
+const (
+	KindInteger   Kind = "integer"
+	KindNumeric   Kind = "numeric"
+	KindDate      Kind = "date"
+	KindTimestamp Kind = "timestamp"
+	KindBoolean   Kind = "boolean"
+)
+
+// MinSamples is the number of non-empty values needed to infer a type.
+const MinSamples = 3
+
+// Suggestion is the cast suggested for a text column.
+type Suggestion struct {
+	Table    string `json:"table"`
+	Column   string `json:"column"`
+	DataType string `json:"data_type"`
+	Kind     Kind   `json:"kind"`
+	// Cast is a PostgreSQL expression converting the column to Kind
+	Cast string `json:"cast"`
+	// Sampled is the number of values the suggestion is based on
+	Sampled int `json:"sampled"`
+}
+
+var (
+	integer   = regexp.MustCompile(`^[+-]?\d+$`)
+	decimal   = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+	money     = regexp.MustCompile(`^[+-]?[$€£]?\s?(\d{1,3}(,\d{3})+|\d+)(\.\d+)?$`)
+	isoDate   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	isoTime   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[ T]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}(:?\d{2})?)?$`)
+	slashDate = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/\d{4}$`)
+	booleans  = map[string]bool{"true": true, "false": true, "t": true, "f": true, "yes": true, "no": true, "y": true, "n": true}
+)
+
+// Infer returns the type of the sampled values of a text column and the
+// expression casting column (a quoted identifier) to it. ok is false unless
+// at least MinSamples values are non-empty and all share a castable type.
+// Integers with leading zeros are codes such as ZIP codes and are not cast.
+func Infer(column string, values []string) (kind Kind, cast string, ok bool) {
+	var sample []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			sample = append(sample, v)
+		}
+	}
+	if len(sample) < MinSamples {
+		return "", "", false
+	}
+
+	trimmed := fmt.Sprintf("NULLIF(trim(%s), '')", column)
+	switch {
+	case all(sample, func(v string) bool { return booleans[strings.ToLower(v)] }):
+		return KindBoolean, fmt.Sprintf("NULLIF(lower(trim(%s)), '') IN ('true', 't', 'yes', 'y')", column), true
+	case all(sample, integer.MatchString):
+		if !all(sample, noLeadingZero) {
+			return "", "", false
+		}
+		return KindInteger, trimmed + "::bigint", true
+	case all(sample, decimal.MatchString):
+		return KindNumeric, trimmed + "::numeric", true
+	case all(sample, money.MatchString):
+		return KindNumeric, fmt.Sprintf("NULLIF(regexp_replace(%s, '[^0-9.+-]', '', 'g'), '')::numeric", column), true
+	case all(sample, validDate):
+		return KindDate, trimmed + "::date", true
+	case all(sample, isoTime.MatchString):
+		return KindTimestamp, trimmed + "::timestamptz", true
+	case all(sample, slashDate.MatchString):
+		layout := slashLayout(sample)
+		if layout == "" {
+			return "", "", false
+		}
+		return KindDate, fmt.Sprintf("to_date(%s, '%s')", trimmed, layout), true
+	}
+	return "", "", false
+}
+
+func all(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if !match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func noLeadingZero(v string) bool {
+	v = strings.TrimLeft(v, "+-")
+	return len(v) == 1 || v[0] != '0'
+}
+
+func validDate(v string) bool {
+	if !isoDate.MatchString(v) {
+		return false
+	}
+	_, err := time.Parse(time.DateOnly, v)
+	return err == nil
+}
+
+// slashLayout tells DD/MM/YYYY from MM/DD/YYYY by the field that exceeds 12,
+// returning "" if the sample does not settle it either way.
+func slashLayout(values []string) string {
+	var dayFirst, monthFirst bool
+	for _, v := range values {
+		m := slashDate.FindStringSubmatch(v)
+		first, _ := strconv.Atoi(m[1])
+		second, _ := strconv.Atoi(m[2])
+		if first > 31 || second > 31 || first == 0 || second == 0 {
+			return ""
+		}
+		if first > 12 {
+			dayFirst = true
+		}
+		if second > 12 {
+			monthFirst = true
+		}
+	}
+	switch {
+	case dayFirst && !monthFirst:
+		return "DD/MM/YYYY"
+	case monthFirst && !dayFirst:
+		return "MM/DD/YYYY"
+	}
+	return ""
+}
+
+// Guidance describes the suggested casts for the SQL agent's instruction.
+func Guidance(suggestions []Suggestion) string {
+	if len(suggestions) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## Text Columns Holding Typed Values\n")
+	b.WriteString("These columns are stored as text. Cast them with the expression shown before aggregating, comparing or sorting them, ")
+	b.WriteString("qualifying the column with a table alias where needed:\n")
+	for _, s := range suggestions {
+		fmt.Fprintf(&b, "- %s.%s (%s holding %s values): %s\n", s.Table, s.Column, s.DataType, s.Kind, s.Cast)
+	}
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package casts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []string
+		wantKind Kind
+		wantCast string
+	}{
+		{
+			name:     "integers",
+			values:   []string{"12", " 7", "-3", ""},
+			wantKind: KindInteger,
+			wantCast: `NULLIF(trim("qty"), '')::bigint`,
+		},
+		{
+			name:   "codes with leading zeros",
+			values: []string{"02134", "10001", "00501"},
+		},
+		{
+			name:     "decimals",
+			values:   []string{"19.99", "5", ".5", "1e3"},
+			wantKind: KindNumeric,
+			wantCast: `NULLIF(trim("qty"), '')::numeric`,
+		},
+		{
+			name:     "currency with thousands separators",
+			values:   []string{"$1,200.50", "$35.00", "-$4"},
+			wantKind: KindNumeric,
+			wantCast: `NULLIF(regexp_replace("qty", '[^0-9.+-]', '', 'g'), '')::numeric`,
+		},
+		{
+			name:     "iso dates",
+			values:   []string{"2024-01-31", "2024-02-29", "2023-12-01"},
+			wantKind: KindDate,
+			wantCast: `NULLIF(trim("qty"), '')::date`,
+		},
+		{
+			name:     "iso timestamps",
+			values:   []string{"2024-01-31 10:00:00", "2024-02-01T08:30:00Z", "2024-02-02 09:15"},
+			wantKind: KindTimestamp,
+			wantCast: `NULLIF(trim("qty"), '')::timestamptz`,
+		},
+		{
+			name:     "day first dates",
+			values:   []string{"31/01/2024", "05/02/2024", "1/3/2024"},
+			wantKind: KindDate,
+			wantCast: `to_date(NULLIF(trim("qty"), ''), 'DD/MM/YYYY')`,
+		},
+		{
+			name:     "month first dates",
+			values:   []string{"01/31/2024", "02/05/2024", "3/1/2024"},
+			wantKind: KindDate,
+			wantCast: `to_date(NULLIF(trim("qty"), ''), 'MM/DD/YYYY')`,
+		},
+		{
+			name:   "ambiguous slash dates",
+			values: []string{"01/02/2024", "03/04/2024", "05/06/2024"},
+		},
+		{
+			name:     "booleans",
+			values:   []string{"Yes", "no", "Y", "true"},
+			wantKind: KindBoolean,
+			wantCast: `NULLIF(lower(trim("qty")), '') IN ('true', 't', 'yes', 'y')`,
+		},
+		{
+			name:   "mixed values",
+			values: []string{"12", "n/a", "7"},
+		},
+		{
+			name:   "invalid dates",
+			values: []string{"2024-13-01", "2024-01-01", "2024-01-02"},
+		},
+		{
+			name:   "too few values",
+			values: []string{"1", "2", " "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, cast, ok := Infer(`"qty"`, tt.values)
+			if ok != (tt.wantKind != "") || kind != tt.wantKind || cast != tt.wantCast {
+				t.Errorf("Infer() = %q, %q, %v, want %q, %q", kind, cast, ok, tt.wantKind, tt.wantCast)
+			}
+		})
+	}
+}
+
+func TestGuidance(t *testing.T) {
+	if got := Guidance(nil); got != "" {
+		t.Errorf("Guidance(nil) = %q, want empty", got)
+	}
+
+	got := Guidance([]Suggestion{{
+		Table:    "orders",
+		Column:   "amount",
+		DataType: "character varying",
+		Kind:     KindNumeric,
+		Cast:     `NULLIF(trim("amount"), '')::numeric`,
+	}})
+	want := `- orders.amount (character varying holding numeric values): NULLIF(trim("amount"), '')::numeric`
+	if !strings.Contains(got, want) {
+		t.Errorf("Guidance() = %q, want line %q", got, want)
+	}
+}
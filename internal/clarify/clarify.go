@@ -0,0 +1,110 @@
+// Package clarify lets the agents ask the user a question before running a
+// query, instead of guessing what an ambiguous request means.
+package clarify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/toolschema"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ToolName is the tool that asks the user a clarifying question.
+const ToolName = "ask_clarification"
+
+// StatusAsked is returned by ask_clarification; the answer arrives as the
+// user's next message.
+const StatusAsked = "asked"
+
+// maxOptions bounds the answers offered with a question.
+const maxOptions = 5
+
+// Args are the arguments of ask_clarification.
+type Args struct {
+	Question string   `json:"question" description:"One short, targeted question, e.g. 'By revenue, do you mean order totals or payments received?'"`
+	Options  []string `json:"options,omitempty" description:"2 to 5 likely answers the user can pick from, e.g. table or column names in plain words"`
+}
+
+// Result is returned by ask_clarification.
+type Result struct {
+	Status string `json:"status"`
+}
+
+// Tools returns the ask_clarification tool. The tool ends the turn: the
+// question is shown to the user instead of a summary by the model.
+func Tools() ([]tool.Tool, error) {
+	schema, err := toolschema.For[Args]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s schema: %w", ToolName, err)
+	}
+	askTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        ToolName,
+			Description: "Ask the user one question to resolve an ambiguous request before querying. The turn ends and the user's answer arrives as the next message.",
+			InputSchema: schema,
+		},
+		func(ctx tool.Context, args Args) (Result, error) {
+			ctx.Actions().SkipSummarization = true
+			return Result{Status: StatusAsked}, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", ToolName, err)
+	}
+	return []tool.Tool{askTool}, nil
+}
+
+// Note is added to a question whose intent was classified with confidence
+// below the App's threshold.
+func Note(confidence float64) string {
+	return fmt.Sprintf("[The intent of this question is unclear (classifier confidence %.2f). If it could mean different things, call %s with a targeted question before delegating; otherwise answer it as usual.]", confidence, ToolName)
+}
+
+// Question is a clarifying question asked by an agent.
+type Question struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// FromArgs reads the question from the arguments of an ask_clarification
+// call, dropping blank and duplicate options.
+func FromArgs(args map[string]any) Question {
+	q := Question{}
+	q.Question, _ = args["question"].(string)
+	q.Question = strings.TrimSpace(q.Question)
+	options, _ := args["options"].([]any)
+	seen := make(map[string]bool)
+	for _, o := range options {
+		s, _ := o.(string)
+		s = strings.TrimSpace(s)
+		if s == "" || seen[strings.ToLower(s)] || len(q.Options) == maxOptions {
+			continue
+		}
+		seen[strings.ToLower(s)] = true
+		q.Options = append(q.Options, s)
+	}
+	return q
+}
+
+// String formats the question with its options numbered from 1.
+func (q Question) String() string {
+	var b strings.Builder
+	b.WriteString("❓ " + q.Question)
+	for i, option := range q.Options {
+		fmt.Fprintf(&b, "\n   %d. %s", i+1, option)
+	}
+	return b.String()
+}
+
+// Answer maps a reply to a question with options: the number of an option
+// is replaced by its text, anything else is returned as typed.
+func (q Question) Answer(reply string) string {
+	reply = strings.TrimSpace(reply)
+	if n, err := strconv.Atoi(reply); err == nil && n >= 1 && n <= len(q.Options) {
+		return q.Options[n-1]
+	}
+	return reply
+}
@@ -0,0 +1,47 @@
+package clarify
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFromArgs(t *testing.T) {
+	q := FromArgs(map[string]any{
+		"question": " Which revenue? ",
+		"options":  []any{"Order totals", " ", "order totals", "Payments received", 3, "a", "b", "c", "d"},
+	})
+	if q.Question != "Which revenue?" {
+		t.Errorf("question = %q", q.Question)
+	}
+	if want := []string{"Order totals", "Payments received", "a", "b", "c"}; !slices.Equal(q.Options, want) {
+		t.Errorf("options = %q, want %q", q.Options, want)
+	}
+}
+
+func TestAnswer(t *testing.T) {
+	q := Question{Question: "Which revenue?", Options: []string{"Order totals", "Payments received"}}
+	tests := []struct {
+		reply string
+		want  string
+	}{
+		{reply: "2", want: "Payments received"},
+		{reply: " 1 ", want: "Order totals"},
+		{reply: "3", want: "3"},
+		{reply: "0", want: "0"},
+		{reply: "refunds included", want: "refunds included"},
+		{reply: "", want: ""},
+	}
+	for _, tt := range tests {
+		if got := q.Answer(tt.reply); got != tt.want {
+			t.Errorf("Answer(%q) = %q, want %q", tt.reply, got, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	q := Question{Question: "Which revenue?", Options: []string{"Order totals", "Payments received"}}
+	want := "❓ Which revenue?\n   1. Order totals\n   2. Payments received"
+	if got := q.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package clickhouse
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+)
+
+// fakeServer answers queries like the ClickHouse HTTP interface, with
+// canned output by query.
+func fakeServer(t *testing.T, got *[]string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		query := string(body)
+		*got = append(*got, query)
+		if r.Header.Get("X-ClickHouse-User") != "analyst" || r.URL.Query().Get("database") != "events" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, "Code: 516. DB::Exception: analyst: Authentication failed")
+			return
+		}
+		switch {
+		case strings.HasPrefix(query, "INSERT") && r.URL.Query().Get("readonly") != "":
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, "Code: 164. DB::Exception: analyst: Cannot execute query in readonly mode")
+		case strings.HasPrefix(query, "INSERT"):
+			io.WriteString(w, "[]\n[]\n")
+		case query == "SELECT 1":
+			io.WriteString(w, "[\"1\"]\n[\"UInt8\"]\n[1]\n")
+		case strings.Contains(query, "FROM system.columns") && strings.Contains(query, "{p1:String}"):
+			header := "[\"name\",\"type\",\"nullable\",\"default_expression\",\"comment\"]\n[\"String\",\"String\",\"UInt8\",\"String\",\"String\"]\n"
+			if r.URL.Query().Get("param_p1") != "clicks" {
+				io.WriteString(w, header)
+				return
+			}
+			io.WriteString(w, header+"[\"id\",\"UInt64\",0,\"\",\"\"]\n[\"referrer\",\"Nullable(String)\",1,\"\",\"HTTP referrer\"]\n")
+		case strings.Contains(query, "is_in_primary_key"):
+			io.WriteString(w, "[\"table\",\"name\"]\n[\"String\",\"String\"]\n[\"clicks\",\"id\"]\n")
+		case strings.Contains(query, "FROM system.data_skipping_indices"):
+			io.WriteString(w, "[\"table\",\"name\",\"false\",\"expr\"]\n[\"String\",\"String\",\"Bool\",\"String\"]\n[\"clicks\",\"referrer_idx\",false,\"referrer\"]\n")
+		case strings.Contains(query, "FROM system.columns"):
+			io.WriteString(w, "[\"table\",\"name\",\"type\"]\n[\"String\",\"String\",\"String\"]\n"+
+				"[\"clicks\",\"id\",\"UInt64\"]\n[\"clicks\",\"referrer\",\"Nullable(String)\"]\n[\"sessions\",\"user_id\",\"UInt32\"]\n")
+		case strings.Contains(query, "FROM system.tables"):
+			io.WriteString(w, "[\"name\"]\n[\"String\"]\n[\"clicks\"]\n[\"sessions\"]\n")
+		case strings.Contains(query, "broken"):
+			io.WriteString(w, "[\"n\"]\n[\"UInt8\"]\n[1]\nCode: 395. DB::Exception: Value passed to 'throwIf' function is non-zero\n")
+		default:
+			io.WriteString(w, "[\"id\",\"score\",\"tags\",\"referrer\"]\n[\"UInt64\",\"Float64\",\"Array(Int32)\",\"Nullable(String)\"]\n"+
+				"[9007199254740993,0.5,[1,2],null]\n[2,1,[],\"ads\"]\n")
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "clickhouse://analyst:secret@" + strings.TrimPrefix(srv.URL, "http://") + "/events"
+}
+
+func TestQuery(t *testing.T) {
+	var queries []string
+	c, err := New(context.Background(), Config{URL: fakeServer(t, &queries)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.Query(context.Background(), "SELECT * FROM clicks;", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"id":9007199254740993,"score":0.5,"tags":[1,2],"referrer":null},{"id":2,"score":1,"tags":[],"referrer":"ads"}]`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if q := queries[len(queries)-1]; q != "SELECT * FROM clicks LIMIT 10" {
+		t.Errorf("query = %q", q)
+	}
+
+	if _, err := c.Query(context.Background(), "SELECT throwIf(1) AS broken", 10); err == nil || !strings.Contains(err.Error(), "Code: 395") {
+		t.Errorf("err = %v, want the exception sent mid-stream", err)
+	}
+
+	insert := "INSERT INTO clicks VALUES (3, 0.1, [], NULL)"
+	if _, err := c.Query(context.Background(), insert, 0); err == nil || !strings.Contains(err.Error(), "readonly mode") {
+		t.Errorf("err = %v, want the insert refused read-only", err)
+	}
+	if _, err := c.Query(sqlagent.WithApprovedWrite(context.Background()), insert, 0); err != nil {
+		t.Errorf("approved insert: err = %v", err)
+	}
+}
+
+func TestNewAuthFailure(t *testing.T) {
+	var queries []string
+	url := strings.Replace(fakeServer(t, &queries), "analyst", "guest", 1)
+	if _, err := New(context.Background(), Config{URL: url}); err == nil || !strings.Contains(err.Error(), "Authentication failed") {
+		t.Errorf("err = %v, want the server's message", err)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	var queries []string
+	c, err := New(context.Background(), Config{URL: fakeServer(t, &queries)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() (string, error)
+		want string
+	}{
+		{"GetSchema", func() (string, error) { return c.GetSchema(ctx, "clicks") }, `[{"column_name":"id","data_type":"UInt64","nullable":false,"primary_key":true},{"column_name":"referrer","data_type":"Nullable(String)","description":"HTTP referrer","indexed":true,"nullable":true}]`},
+		{"GetSchema missing", func() (string, error) { return c.GetSchema(ctx, "nope") }, `[]`},
+		{"ListTables", func() (string, error) { return c.ListTables(ctx) }, `["clicks","sessions"]`},
+		{"DescribeDatabase", func() (string, error) { return c.DescribeDatabase(ctx) }, `[{"columns":["id UInt64","referrer Nullable(String)"],"indexes":["referrer_idx (referrer)"],"primary_key":["id"],"table":"clicks"},{"columns":["user_id UInt32"],"table":"sessions"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package clickhouse
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// decodeRow decodes a JSON array of values, converting numbers by their
+// ClickHouse type so integers stay exact.
+func decodeRow(line []byte, types []string) ([]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var row []interface{}
+	if err := dec.Decode(&row); err != nil || len(row) != len(types) {
+		return nil, streamError(line)
+	}
+	for i, v := range row {
+		row[i] = convert(v, types[i])
+	}
+	return row, nil
+}
+
+// convert turns the numbers in v into int64 or float64 according to the
+// column type, inside arrays, tuples and maps as well.
+func convert(v interface{}, typ string) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if isInteger(typ) {
+			if n, err := v.Int64(); err == nil {
+				return n
+			}
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []interface{}:
+		for i := range v {
+			v[i] = convert(v[i], elementType(typ))
+		}
+		return v
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = convert(v[k], "")
+		}
+		return v
+	}
+	return v
+}
+
+// isInteger reports whether typ is an integer type, e.g. "Nullable(UInt32)".
+func isInteger(typ string) bool {
+	typ = unwrap(typ)
+	return strings.HasPrefix(typ, "Int") || strings.HasPrefix(typ, "UInt")
+}
+
+// elementType returns the element type of an Array type, or "" if unknown.
+func elementType(typ string) string {
+	typ = unwrap(typ)
+	if strings.HasPrefix(typ, "Array(") {
+		return typ[len("Array(") : len(typ)-1]
+	}
+	return ""
+}
+
+// unwrap strips Nullable and LowCardinality wrappers.
+func unwrap(typ string) string {
+	for _, w := range []string{"Nullable(", "LowCardinality("} {
+		if strings.HasPrefix(typ, w) && strings.HasSuffix(typ, ")") {
+			typ = unwrap(typ[len(w) : len(typ)-1])
+		}
+	}
+	return typ
+}
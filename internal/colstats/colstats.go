@@ -0,0 +1,81 @@
+// Package colstats lists the values of low-cardinality columns, such as
+// status or type columns, so the SQL agent filters them on values that
+// exist without first sampling the table.
+package colstats
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+)
+
+// MaxValueLength is the length beyond which a column's values are taken for
+// free text rather than codes, and the column is not listed.
+const MaxValueLength = 40
+
+// Column is a low-cardinality column with its most common values.
+type Column struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	// Distinct is the estimated number of distinct values
+	Distinct int `json:"distinct"`
+	// Values are the most common values, most frequent first
+	Values []string `json:"values"`
+}
+
+// Complete reports whether Values holds every distinct value of the column.
+func (c Column) Complete() bool {
+	return len(c.Values) >= c.Distinct
+}
+
+// Distinct converts PostgreSQL's n_distinct statistic to a count. A
+// negative n_distinct is minus the fraction of the table's rows that are
+// distinct, so the count grows with the table.
+func Distinct(nDistinct float64, rows float64) int {
+	if nDistinct < 0 {
+		nDistinct = -nDistinct * rows
+	}
+	return int(math.Round(nDistinct))
+}
+
+// Listable reports whether a column with the given distinct count and most
+// common values is enum-like: at most maxValues distinct values, all of
+// them short.
+func Listable(distinct int, values []string, maxValues int) bool {
+	if distinct < 1 || distinct > maxValues || len(values) == 0 {
+		return false
+	}
+	for _, v := range values {
+		if len(v) > MaxValueLength || strings.ContainsAny(v, "\n\r") {
+			return false
+		}
+	}
+	return true
+}
+
+// Guidance lists columns and their values as a section of the SQL agent's
+// instruction, or returns "" if there are none.
+func Guidance(columns []Column) string {
+	if len(columns) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("## Column Values\n")
+	b.WriteString("These columns hold few distinct values. Filter them on the values listed, matching case and spelling exactly, ")
+	b.WriteString("instead of guessing or sampling the table first:\n")
+	for _, c := range columns {
+		quoted := make([]string, len(c.Values))
+		for i, v := range c.Values {
+			quoted[i] = dialect.QuoteLiteral(v)
+		}
+		if c.Complete() {
+			fmt.Fprintf(&b, "- %s.%s: %s\n", c.Table, c.Column, strings.Join(quoted, ", "))
+		} else {
+			fmt.Fprintf(&b, "- %s.%s (about %d values, most common first): %s\n", c.Table, c.Column, c.Distinct, strings.Join(quoted, ", "))
+		}
+	}
+	return b.String()
+}
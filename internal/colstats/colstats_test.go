@@ -0,0 +1,69 @@
+package colstats
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDistinct(t *testing.T) {
+	tests := []struct {
+		name      string
+		nDistinct float64
+		rows      float64
+		want      int
+	}{
+		{name: "count", nDistinct: 4, rows: 1000, want: 4},
+		{name: "fraction of rows", nDistinct: -0.25, rows: 1000, want: 250},
+		{name: "unique", nDistinct: -1, rows: 12, want: 12},
+		{name: "unknown", nDistinct: 0, rows: 1000, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Distinct(tt.nDistinct, tt.rows); got != tt.want {
+				t.Errorf("Distinct(%v, %v) = %d, want %d", tt.nDistinct, tt.rows, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListable(t *testing.T) {
+	tests := []struct {
+		name     string
+		distinct int
+		values   []string
+		want     bool
+	}{
+		{name: "status codes", distinct: 3, values: []string{"paid", "pending", "shipped"}, want: true},
+		{name: "too many values", distinct: 21, values: []string{"a", "b"}, want: false},
+		{name: "no values", distinct: 2, want: false},
+		{name: "free text", distinct: 2, values: []string{"ok", strings.Repeat("x", MaxValueLength+1)}, want: false},
+		{name: "multiline", distinct: 2, values: []string{"a\nb", "c"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Listable(tt.distinct, tt.values, 20); got != tt.want {
+				t.Errorf("Listable(%d, %q) = %v, want %v", tt.distinct, tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGuidance(t *testing.T) {
+	if got := Guidance(nil); got != "" {
+		t.Errorf("Guidance(nil) = %q, want none", got)
+	}
+
+	got := Guidance([]Column{
+		{Table: "orders", Column: "status", Distinct: 3, Values: []string{"paid", "pending", "shipped"}},
+		{Table: "sales.leads", Column: "source", Distinct: 12, Values: []string{"web", "O'Hare expo"}},
+	})
+	for _, want := range []string{
+		"## Column Values\n",
+		"\n- orders.status: 'paid', 'pending', 'shipped'\n",
+		"\n- sales.leads.source (about 12 values, most common first): 'web', 'O''Hare expo'\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Guidance() = %q, lacks %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package console adapts terminal output to the capabilities of the host:
+// emoji and ANSI styling are used only where they render, and can be turned
+// off for logging-friendly plain output.
+package console
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/anuvratrastogi/multi-agent/internal/secrets"
+)
+
+// Options controls console output.
+type Options struct {
+	// NoEmoji strips emoji from all output
+	NoEmoji bool
+	// Stderr writes console output to stderr, leaving stdout for results
+	Stderr bool
+}
+
+var (
+	emoji = true
+	ansi  = false
+	// Out is the writer used by Printf, Println and Print.
+	Out io.Writer = os.Stdout
+)
+
+// Init detects terminal capabilities and configures output. It should be
+// called once at startup before anything is printed.
+func Init(opts Options) {
+	out := os.Stdout
+	if opts.Stderr {
+		out = os.Stderr
+	}
+
+	vt := enableVirtualTerminal()
+	terminal := isTerminal(out)
+	dumb := os.Getenv("TERM") == "dumb"
+
+	ansi = vt && terminal && !dumb && os.Getenv("NO_COLOR") == ""
+	// Consoles that cannot process escape sequences predate emoji support.
+	emoji = !opts.NoEmoji && !dumb && (vt || !terminal)
+
+	Out = Writer(out)
+	log.SetOutput(LogWriter(os.Stderr))
+}
+
+// EmojiEnabled reports whether emoji are written to the console.
+func EmojiEnabled() bool {
+	return emoji
+}
+
+// Printf formats according to a format specifier and writes to Out.
+func Printf(format string, a ...interface{}) {
+	fmt.Fprintf(Out, format, a...)
+}
+
+// Println formats using the default formats and writes to Out.
+func Println(a ...interface{}) {
+	fmt.Fprintln(Out, a...)
+}
+
+// Print formats using the default formats and writes to Out.
+func Print(a ...interface{}) {
+	fmt.Fprint(Out, a...)
+}
+
+// Bold styles s as bold when the terminal supports ANSI escapes.
+func Bold(s string) string {
+	return style("1", s)
+}
+
+// Red styles s in red when the terminal supports ANSI escapes.
+func Red(s string) string {
+	return style("31", s)
+}
+
+// Dim styles s as faint when the terminal supports ANSI escapes.
+func Dim(s string) string {
+	return style("2", s)
+}
+
+func style(code, s string) string {
+	if !ansi {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// Writer wraps w so that emoji are stripped when they are disabled. It
+// leaves secrets alone: it is meant for what users read, such as their
+// own query results and the SQL they are asked to approve.
+func Writer(w io.Writer) io.Writer {
+	return &filterWriter{w: w}
+}
+
+// LogWriter wraps w like Writer, and also redacts secrets, for log output.
+func LogWriter(w io.Writer) io.Writer {
+	return &filterWriter{w: w, redact: true}
+}
+
+type filterWriter struct {
+	w      io.Writer
+	redact bool
+}
+
+func (f *filterWriter) Write(p []byte) (int, error) {
+	s := string(p)
+	if f.redact {
+		s = secrets.Redact(s)
+	}
+	if !emoji {
+		s = StripEmoji(s)
+	}
+	if _, err := io.WriteString(f.w, s); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// StripEmoji removes emoji (and the spacing that follows them) from s and
+// replaces arrows with ASCII equivalents.
+func StripEmoji(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	skipSpace := false
+	for _, r := range s {
+		switch {
+		case isEmoji(r):
+			skipSpace = true
+			continue
+		case skipSpace && r == ' ':
+			continue
+		case r == '→':
+			b.WriteString("->")
+		default:
+			b.WriteRune(r)
+		}
+		skipSpace = false
+	}
+	return b.String()
+}
+
+func isEmoji(r rune) bool {
+	switch {
+	case r == 0x200D, r == 0xFE0F: // zero-width joiner, emoji presentation selector
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // miscellaneous technical (⏳)
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // symbols and dingbats (✅ ❌ ⚠)
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // arrows and stars (⭐)
+		return true
+	case r >= 0x1F000 && r <= 0x1FAFF: // emoji blocks
+		return true
+	}
+	return unicode.Is(unicode.Variation_Selector, r)
+}
+
+// isTerminal reports whether f is attached to a character device.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
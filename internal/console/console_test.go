@@ -0,0 +1,41 @@
+package console
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripEmoji(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "leading emoji", input: "✅ Database connected", expected: "Database connected"},
+		{name: "emoji with variation selector", input: "⚠️  Warning: no schema", expected: "Warning: no schema"},
+		{name: "indented tool log", input: "  📝 [SQL] Executing query", expected: "  [SQL] Executing query"},
+		{name: "arrow", input: "SQLAgent → ChartAgent", expected: "SQLAgent -> ChartAgent"},
+		{name: "plain text", input: "You: ", expected: "You: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripEmoji(tt.input); got != tt.expected {
+				t.Errorf("StripEmoji(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriters(t *testing.T) {
+	line := "password=s3cr3t host=db\n"
+	var out, logs strings.Builder
+	Writer(&out).Write([]byte(line))
+	LogWriter(&logs).Write([]byte(line))
+	if out.String() != line {
+		t.Errorf("Writer() wrote %q, want the user's output unchanged", out.String())
+	}
+	if logs.String() != "password=[REDACTED] host=db\n" {
+		t.Errorf("LogWriter() wrote %q, want the password redacted", logs.String())
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package console
+
+import (
+	"os"
+	"syscall"
+)
+
+// enableVirtualTerminal reports whether the terminal processes escape
+// sequences. Unix terminals do unless TERM says otherwise.
+func enableVirtualTerminal() bool {
+	return os.Getenv("TERM") != "dumb"
+}
+
+// ShutdownSignals returns the signals that trigger a graceful shutdown.
+func ShutdownSignals() []os.Signal {
+	return []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+}
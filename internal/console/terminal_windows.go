@@ -0,0 +1,38 @@
+//go:build windows
+
+package console
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+const cpUTF8 = 65001
+
+// enableVirtualTerminal switches the Windows console to UTF-8 and turns on
+// escape-sequence processing. It reports whether VT processing is available,
+// which is false on legacy consoles (pre Windows 10).
+func enableVirtualTerminal() bool {
+	handle := windows.Handle(os.Stdout.Fd())
+
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		// Not a console (redirected to a file or pipe).
+		return false
+	}
+
+	windows.SetConsoleOutputCP(cpUTF8)
+	if err := windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+		return false
+	}
+	return true
+}
+
+// ShutdownSignals returns the signals that trigger a graceful shutdown. The Go
+// runtime delivers both Ctrl+C and Ctrl+Break as os.Interrupt, and console
+// close, logoff and shutdown events as SIGTERM.
+func ShutdownSignals() []os.Signal {
+	return []os.Signal{os.Interrupt, syscall.SIGTERM}
+}
@@ -0,0 +1,402 @@
+// Package dashboard refreshes saved dashboards, re-querying only the
+// partitions of a table that changed since the last run when the table
+// tracks changes in an updated_at column.
+package dashboard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"gopkg.in/yaml.v3"
+)
+
+// Placeholder marks where a panel's SQL is restricted to changed partitions.
+// It is replaced with TRUE on a full refresh.
+const Placeholder = "{{changed}}"
+
+// DefaultLimit is the row limit of panel queries when none is set.
+const DefaultLimit = 10000
+
+// Refresh modes reported in a Result.
+const (
+	ModeFull        = "full"
+	ModeIncremental = "incremental"
+	ModeUnchanged   = "unchanged"
+)
+
+// Querier runs read-only SQL and returns the rows as a JSON array of
+// objects. sqlagent.MCPClient satisfies it.
+type Querier interface {
+	Query(ctx context.Context, query string, limit int) (string, error)
+}
+
+// Dashboard is a set of panels refreshed together.
+//
+// Example YAML:
+//
+//	name: sales
+//	panels:
+//	  - name: revenue_by_day
+//	    sql: |
+//	      SELECT created_at::date AS day, sum(amount) AS revenue
+//	      FROM orders WHERE {{changed}} GROUP BY 1 ORDER BY 1
+//	    table: orders
+//	    updated_at: updated_at
+//	    partition: created_at::date
+//	    key: day
+type Dashboard struct {
+	Name string `yaml:"name"`
+	// Snapshot is the file holding the panels' results (defaults to the
+	// dashboard file with a .snapshot.json extension)
+	Snapshot string  `yaml:"snapshot"`
+	Panels   []Panel `yaml:"panels"`
+}
+
+// Panel is one query of a dashboard. Setting UpdatedAt makes the panel
+// incremental: Table, Partition and Key are then required and SQL must
+// contain Placeholder.
+type Panel struct {
+	Name string `yaml:"name"`
+	SQL  string `yaml:"sql"`
+	// Table is the table whose changes are tracked
+	Table string `yaml:"table"`
+	// UpdatedAt is the column of Table set whenever a row changes
+	UpdatedAt string `yaml:"updated_at"`
+	// Partition is the SQL expression over Table grouping rows into the
+	// partitions that are re-queried, e.g. created_at::date
+	Partition string `yaml:"partition"`
+	// Key is the result column holding each row's Partition value
+	Key string `yaml:"key"`
+	// Limit caps the rows of the panel (defaults to DefaultLimit)
+	Limit int `yaml:"limit"`
+}
+
+// Incremental reports whether the panel can be refreshed incrementally.
+func (p Panel) Incremental() bool {
+	return p.UpdatedAt != ""
+}
+
+// Load reads a dashboard from a YAML file.
+func Load(path string) (*Dashboard, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dashboard: %w", err)
+	}
+
+	var d Dashboard
+	if err := yaml.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse dashboard %s: %w", path, err)
+	}
+	if d.Name == "" {
+		d.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if d.Snapshot == "" {
+		d.Snapshot = strings.TrimSuffix(path, filepath.Ext(path)) + ".snapshot.json"
+	}
+	if len(d.Panels) == 0 {
+		return nil, fmt.Errorf("dashboard %s has no panels", d.Name)
+	}
+
+	seen := make(map[string]bool)
+	for i := range d.Panels {
+		p := &d.Panels[i]
+		switch {
+		case p.Name == "":
+			return nil, fmt.Errorf("panel %d of dashboard %s has no name", i+1, d.Name)
+		case seen[p.Name]:
+			return nil, fmt.Errorf("dashboard %s has two panels named %s", d.Name, p.Name)
+		case strings.TrimSpace(p.SQL) == "":
+			return nil, fmt.Errorf("panel %s has no sql", p.Name)
+		case p.Incremental() && (p.Table == "" || p.Partition == "" || p.Key == ""):
+			return nil, fmt.Errorf("panel %s sets updated_at but not table, partition and key", p.Name)
+		case p.Incremental() && !strings.Contains(p.SQL, Placeholder):
+			return nil, fmt.Errorf("panel %s sets updated_at but its sql has no %s condition", p.Name, Placeholder)
+		}
+		seen[p.Name] = true
+		if p.Limit <= 0 {
+			p.Limit = DefaultLimit
+		}
+	}
+	return &d, nil
+}
+
+// Snapshot is the stored result of a panel.
+type Snapshot struct {
+	// Definition identifies the panel definition the rows were computed
+	// with; a changed panel is refreshed in full
+	Definition  string            `json:"definition"`
+	Watermark   string            `json:"watermark,omitempty"`
+	RefreshedAt time.Time         `json:"refreshed_at"`
+	Rows        []json.RawMessage `json:"rows"`
+}
+
+// Result reports the refresh of one panel.
+type Result struct {
+	Panel string `json:"panel"`
+	Mode  string `json:"mode,omitempty"`
+	// Partitions is the number of changed partitions re-queried
+	Partitions int `json:"partitions,omitempty"`
+	// Fetched is the number of rows read from the database
+	Fetched int `json:"fetched"`
+	// Rows is the number of rows in the snapshot after the refresh
+	Rows       int    `json:"rows"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ErrFailed is returned by Refresh when at least one panel failed.
+var ErrFailed = errors.New("one or more panels failed to refresh")
+
+// Refresh brings every panel of d up to date and saves the snapshots.
+// Incremental panels re-query only the partitions changed since their last
+// refresh unless full is set. A failed panel keeps its previous snapshot.
+func Refresh(ctx context.Context, db Querier, d *Dashboard, full bool) ([]Result, error) {
+	snapshots, err := loadSnapshots(d.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	failed := false
+	for _, p := range d.Panels {
+		start := time.Now()
+		snap, res, err := refreshPanel(ctx, db, p, snapshots[p.Name], full)
+		res.Panel = p.Name
+		res.DurationMS = time.Since(start).Milliseconds()
+		if err != nil {
+			res.Error = err.Error()
+			failed = true
+		} else {
+			snapshots[p.Name] = snap
+		}
+		results = append(results, res)
+	}
+
+	if err := saveSnapshots(d.Snapshot, snapshots); err != nil {
+		return results, err
+	}
+	if failed {
+		return results, ErrFailed
+	}
+	return results, nil
+}
+
+// refreshPanel computes the panel's new snapshot from the previous one.
+func refreshPanel(ctx context.Context, db Querier, p Panel, prev *Snapshot, full bool) (*Snapshot, Result, error) {
+	snap := &Snapshot{Definition: definition(p), RefreshedAt: time.Now().UTC()}
+
+	if p.Incremental() {
+		// Read the watermark first: rows changed while the panel is queried
+		// are picked up again next time, and re-querying them is harmless
+		wm, err := watermark(ctx, db, p)
+		if err != nil {
+			return nil, Result{}, err
+		}
+		snap.Watermark = wm
+
+		if !full && prev != nil && prev.Definition == snap.Definition && prev.Watermark != "" {
+			if wm == prev.Watermark {
+				snap.Rows = prev.Rows
+				return snap, Result{Mode: ModeUnchanged, Rows: len(snap.Rows)}, nil
+			}
+			keys, err := changedKeys(ctx, db, p, prev.Watermark)
+			if err != nil {
+				return nil, Result{}, err
+			}
+			// A list of changed partitions at the limit may be cut short, so
+			// the panel is refreshed in full instead
+			if len(keys) < p.Limit {
+				rows, err := query(ctx, db, strings.ReplaceAll(p.SQL, Placeholder, changedCondition(p, prev.Watermark)), p.Limit)
+				if err != nil {
+					return nil, Result{}, err
+				}
+				snap.Rows, err = merge(prev.Rows, rows, p.Key, keys)
+				if err != nil {
+					return nil, Result{}, err
+				}
+				return snap, Result{Mode: ModeIncremental, Partitions: len(keys), Fetched: len(rows), Rows: len(snap.Rows)}, nil
+			}
+		}
+	}
+
+	rows, err := query(ctx, db, strings.ReplaceAll(p.SQL, Placeholder, "TRUE"), p.Limit)
+	if err != nil {
+		return nil, Result{}, err
+	}
+	snap.Rows = rows
+	return snap, Result{Mode: ModeFull, Fetched: len(rows), Rows: len(rows)}, nil
+}
+
+// watermark returns the latest change time of the panel's table as text,
+// or "" if the table is empty.
+func watermark(ctx context.Context, db Querier, p Panel) (string, error) {
+	rows, err := query(ctx, db, fmt.Sprintf("SELECT max(%s)::text AS watermark FROM %s", p.UpdatedAt, p.Table), 1)
+	if err != nil {
+		return "", fmt.Errorf("failed to read watermark: %w", err)
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	var row struct {
+		Watermark *string `json:"watermark"`
+	}
+	if err := json.Unmarshal(rows[0], &row); err != nil {
+		return "", fmt.Errorf("failed to read watermark: %w", err)
+	}
+	if row.Watermark == nil {
+		return "", nil
+	}
+	return *row.Watermark, nil
+}
+
+// changedKeys returns the partitions with rows changed after wm, as the
+// JSON encoding of their partition values.
+func changedKeys(ctx context.Context, db Querier, p Panel, wm string) (map[string]bool, error) {
+	rows, err := query(ctx, db, fmt.Sprintf("SELECT DISTINCT %s AS key FROM %s WHERE %s > %s",
+		p.Partition, p.Table, p.UpdatedAt, pq.QuoteLiteral(wm)), p.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find changed partitions: %w", err)
+	}
+	keys := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		k, err := field(r, "key")
+		if err != nil {
+			return nil, err
+		}
+		keys[k] = true
+	}
+	return keys, nil
+}
+
+// changedCondition restricts the panel's rows to partitions changed after wm.
+func changedCondition(p Panel, wm string) string {
+	return fmt.Sprintf("(%s) IN (SELECT %s FROM %s WHERE %s > %s)",
+		p.Partition, p.Partition, p.Table, p.UpdatedAt, pq.QuoteLiteral(wm))
+}
+
+// merge replaces the rows of the changed partitions in prev with fresh.
+// Replaced partitions keep their place; new partitions are appended.
+func merge(prev, fresh []json.RawMessage, key string, changed map[string]bool) ([]json.RawMessage, error) {
+	byKey := make(map[string][]json.RawMessage)
+	var order []string
+	for _, r := range fresh {
+		k, err := field(r, key)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := byKey[k]; !ok {
+			order = append(order, k)
+		}
+		byKey[k] = append(byKey[k], r)
+	}
+
+	merged := make([]json.RawMessage, 0, len(prev)+len(fresh))
+	placed := make(map[string]bool)
+	for _, r := range prev {
+		k, err := field(r, key)
+		if err != nil {
+			return nil, err
+		}
+		if !changed[k] && byKey[k] == nil {
+			merged = append(merged, r)
+			continue
+		}
+		// A changed partition with no fresh rows was emptied
+		if !placed[k] {
+			merged = append(merged, byKey[k]...)
+			placed[k] = true
+		}
+	}
+	for _, k := range order {
+		if !placed[k] {
+			merged = append(merged, byKey[k]...)
+		}
+	}
+	return merged, nil
+}
+
+// field returns the JSON encoding of a column of a row.
+func field(row json.RawMessage, column string) (string, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(row, &m); err != nil {
+		return "", fmt.Errorf("json error: %w", err)
+	}
+	v, ok := m[column]
+	if !ok {
+		return "", fmt.Errorf("result has no column %q", column)
+	}
+	return string(v), nil
+}
+
+// query runs a query and splits its result into rows.
+func query(ctx context.Context, db Querier, sql string, limit int) ([]json.RawMessage, error) {
+	out, err := db.Query(ctx, sql, limit)
+	if err != nil {
+		return nil, err
+	}
+	var rows []json.RawMessage
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+	return rows, nil
+}
+
+// definition hashes the parts of a panel that determine its rows.
+func definition(p Panel) string {
+	h := sha256.New()
+	for _, s := range []string{p.SQL, p.Table, p.UpdatedAt, p.Partition, p.Key, fmt.Sprint(p.Limit)} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// loadSnapshots reads the stored panel snapshots; a missing file is empty.
+func loadSnapshots(path string) (map[string]*Snapshot, error) {
+	snapshots := make(map[string]*Snapshot)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshots, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshots %s: %w", path, err)
+	}
+	return snapshots, nil
+}
+
+// saveSnapshots atomically replaces the snapshot file.
+func saveSnapshots(path string, snapshots map[string]*Snapshot) error {
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return fmt.Errorf("json error: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".dashboard-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write snapshots: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshots: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshots: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write snapshots: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package dashboard
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDB answers the dashboard's queries from canned results.
+type fakeDB struct {
+	watermark string
+	changed   string
+	full      string
+	partial   string
+	queries   []string
+}
+
+func (f *fakeDB) Query(_ context.Context, query string, _ int) (string, error) {
+	f.queries = append(f.queries, query)
+	switch {
+	case strings.Contains(query, "AS watermark"):
+		return `[{"watermark":` + f.watermark + `}]`, nil
+	case strings.HasPrefix(query, "SELECT DISTINCT"):
+		return f.changed, nil
+	case strings.Contains(query, "IN (SELECT"):
+		return f.partial, nil
+	case strings.Contains(query, "WHERE TRUE"):
+		return f.full, nil
+	}
+	return "", errors.New("unexpected query: " + query)
+}
+
+func writeDashboard(t *testing.T, yaml string) *Dashboard {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sales.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return d
+}
+
+const salesYAML = `
+panels:
+  - name: revenue_by_day
+    sql: SELECT created_at::date AS day, sum(amount) AS revenue FROM orders WHERE {{changed}} GROUP BY 1
+    table: orders
+    updated_at: updated_at
+    partition: created_at::date
+    key: day
+`
+
+func TestRefresh(t *testing.T) {
+	d := writeDashboard(t, salesYAML)
+	if d.Name != "sales" || !strings.HasSuffix(d.Snapshot, "sales.snapshot.json") {
+		t.Fatalf("Load() = name %q, snapshot %q", d.Name, d.Snapshot)
+	}
+
+	db := &fakeDB{
+		watermark: `"2024-01-03 10:00:00+00"`,
+		full:      `[{"day":"2024-01-01","revenue":10},{"day":"2024-01-02","revenue":20},{"day":"2024-01-03","revenue":30}]`,
+	}
+	steps := []struct {
+		name      string
+		watermark string
+		changed   string
+		partial   string
+		full      bool
+		wantMode  string
+		wantRows  string
+	}{
+		{
+			name:     "first run is full",
+			wantMode: ModeFull,
+			wantRows: `{"day":"2024-01-01","revenue":10}|{"day":"2024-01-02","revenue":20}|{"day":"2024-01-03","revenue":30}`,
+		},
+		{
+			name:     "no changes",
+			wantMode: ModeUnchanged,
+			wantRows: `{"day":"2024-01-01","revenue":10}|{"day":"2024-01-02","revenue":20}|{"day":"2024-01-03","revenue":30}`,
+		},
+		{
+			name:      "changed and new partitions",
+			watermark: `"2024-01-04 09:00:00+00"`,
+			changed:   `[{"key":"2024-01-02"},{"key":"2024-01-04"}]`,
+			partial:   `[{"day":"2024-01-02","revenue":25},{"day":"2024-01-04","revenue":40}]`,
+			wantMode:  ModeIncremental,
+			wantRows:  `{"day":"2024-01-01","revenue":10}|{"day":"2024-01-02","revenue":25}|{"day":"2024-01-03","revenue":30}|{"day":"2024-01-04","revenue":40}`,
+		},
+		{
+			name:      "emptied partition",
+			watermark: `"2024-01-05 09:00:00+00"`,
+			changed:   `[{"key":"2024-01-01"}]`,
+			partial:   `[]`,
+			wantMode:  ModeIncremental,
+			wantRows:  `{"day":"2024-01-02","revenue":25}|{"day":"2024-01-03","revenue":30}|{"day":"2024-01-04","revenue":40}`,
+		},
+		{
+			name:     "forced full refresh",
+			full:     true,
+			wantMode: ModeFull,
+			wantRows: `{"day":"2024-01-01","revenue":10}|{"day":"2024-01-02","revenue":20}|{"day":"2024-01-03","revenue":30}`,
+		},
+	}
+
+	for _, s := range steps {
+		if s.watermark != "" {
+			db.watermark = s.watermark
+		}
+		db.changed, db.partial = s.changed, s.partial
+
+		// Reload so every step reads the snapshots from disk
+		d, err := Load(strings.TrimSuffix(d.Snapshot, ".snapshot.json") + ".yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := Refresh(context.Background(), db, d, s.full)
+		if err != nil {
+			t.Fatalf("%s: Refresh() error = %v", s.name, err)
+		}
+		if got := results[0].Mode; got != s.wantMode {
+			t.Errorf("%s: mode = %s, want %s", s.name, got, s.wantMode)
+		}
+
+		snapshots, err := loadSnapshots(d.Snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rows []string
+		for _, r := range snapshots["revenue_by_day"].Rows {
+			rows = append(rows, string(r))
+		}
+		if got := strings.Join(rows, "|"); got != s.wantRows {
+			t.Errorf("%s: rows =\n%s\nwant\n%s", s.name, got, s.wantRows)
+		}
+	}
+
+	last := db.queries[len(db.queries)-3]
+	if !strings.Contains(last, "updated_at > '2024-01-04 09:00:00+00'") {
+		t.Errorf("changed partitions query = %s, want it after the stored watermark", last)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "no panels", yaml: "name: empty\n", wantErr: "has no panels"},
+		{name: "missing key", yaml: "panels:\n  - name: p\n    sql: SELECT 1 WHERE {{changed}}\n    updated_at: updated_at\n", wantErr: "not table, partition and key"},
+		{name: "missing placeholder", yaml: "panels:\n  - name: p\n    sql: SELECT 1\n    table: t\n    updated_at: u\n    partition: d\n    key: d\n", wantErr: "no {{changed}} condition"},
+		{name: "duplicate panel", yaml: "panels:\n  - name: p\n    sql: SELECT 1\n  - name: p\n    sql: SELECT 2\n", wantErr: "two panels named p"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "d.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+// Package dataset stores query results column by column within a shared
+// memory budget, spilling to temporary files when the budget is exhausted.
+package dataset
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Budget is a memory limit shared by every Dataset created with it.
+type Budget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// NewBudget creates a budget of limit bytes. A limit <= 0 means unlimited.
+func NewBudget(limit int64) *Budget {
+	return &Budget{limit: limit}
+}
+
+// Used returns the number of bytes currently held in memory.
+func (b *Budget) Used() int64 {
+	return b.used.Load()
+}
+
+func (b *Budget) reserve(n int64) bool {
+	if b == nil {
+		return true
+	}
+	if b.used.Add(n) > b.limit && b.limit > 0 {
+		b.used.Add(-n)
+		return false
+	}
+	return true
+}
+
+func (b *Budget) release(n int64) {
+	if b != nil {
+		b.used.Add(-n)
+	}
+}
+
+// Config holds configuration for a Dataset.
+type Config struct {
+	// Budget limits the memory held by in-memory columns (nil means unlimited)
+	Budget *Budget
+	// Dir is where spill files are created (defaults to os.TempDir)
+	Dir string
+}
+
+// Dataset is an append-only table. Rows are kept in memory as columns until
+// the budget is exhausted; from then on all rows live in a temporary file
+// that is memory-mapped for reading where the platform allows it.
+type Dataset struct {
+	cfg     Config
+	columns []string
+	data    [][]interface{}
+	rows    int
+	held    int64
+
+	spill  *os.File
+	writer *bufio.Writer
+}
+
+// New creates an empty dataset with the given columns.
+func New(columns []string, cfg Config) *Dataset {
+	return &Dataset{
+		cfg:     cfg,
+		columns: columns,
+		data:    make([][]interface{}, len(columns)),
+	}
+}
+
+// Columns returns the column names in order.
+func (d *Dataset) Columns() []string {
+	return d.columns
+}
+
+// Len returns the number of rows.
+func (d *Dataset) Len() int {
+	return d.rows
+}
+
+// Spilled reports whether the rows have been moved to disk.
+func (d *Dataset) Spilled() bool {
+	return d.spill != nil
+}
+
+// Append adds a row. Values must be in column order.
+func (d *Dataset) Append(row []interface{}) error {
+	if len(row) != len(d.columns) {
+		return fmt.Errorf("row has %d values, want %d", len(row), len(d.columns))
+	}
+
+	if d.spill == nil {
+		size := rowSize(row)
+		if d.cfg.Budget.reserve(size) {
+			for i, v := range row {
+				d.data[i] = append(d.data[i], v)
+			}
+			d.held += size
+			d.rows++
+			return nil
+		}
+		if err := d.spillToDisk(); err != nil {
+			return err
+		}
+	}
+
+	if err := d.writeRow(row); err != nil {
+		return err
+	}
+	d.rows++
+	return nil
+}
+
+// spillToDisk moves the in-memory rows to a temporary file and releases
+// their memory back to the budget.
+func (d *Dataset) spillToDisk() error {
+	f, err := os.CreateTemp(d.cfg.Dir, "multi-agent-spill-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %w", err)
+	}
+	d.spill = f
+	d.writer = bufio.NewWriter(f)
+
+	row := make([]interface{}, len(d.columns))
+	for r := 0; r < d.rows; r++ {
+		for c := range d.columns {
+			row[c] = d.data[c][r]
+		}
+		if err := d.writeRow(row); err != nil {
+			return err
+		}
+	}
+
+	d.data = nil
+	d.cfg.Budget.release(d.held)
+	d.held = 0
+	return nil
+}
+
+func (d *Dataset) writeRow(row []interface{}) error {
+	line, err := json.Marshal(row)
+	if err != nil {
+		return fmt.Errorf("json error: %w", err)
+	}
+	if _, err := d.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	return nil
+}
+
+// Each calls fn for every row in order. The row slice is reused between
+// calls. Rows read back from disk hold JSON-decoded values, with numbers as
+// json.Number.
+func (d *Dataset) Each(fn func(row []interface{}) error) error {
+	row := make([]interface{}, len(d.columns))
+
+	if d.spill == nil {
+		for r := 0; r < d.rows; r++ {
+			for c := range d.columns {
+				row[c] = d.data[c][r]
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := d.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file: %w", err)
+	}
+	decode := func(line []byte) error {
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&row); err != nil {
+			return fmt.Errorf("failed to read spill file: %w", err)
+		}
+		return fn(row)
+	}
+
+	data, unmap, err := mapFile(d.spill)
+	if err == nil {
+		defer unmap()
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				i = len(data)
+			}
+			if err := decode(data[:i]); err != nil {
+				return err
+			}
+			data = data[min(i+1, len(data)):]
+		}
+		return nil
+	}
+	if !errors.Is(err, errNoMmap) {
+		return err
+	}
+
+	// Platforms without mmap stream the file instead
+	if _, err := d.spill.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read spill file: %w", err)
+	}
+	scanner := bufio.NewScanner(d.spill)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if err := decode(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read spill file: %w", err)
+	}
+	_, err = d.spill.Seek(0, io.SeekEnd)
+	return err
+}
+
+// WriteJSON writes the rows as a JSON array of objects whose keys follow the
+// column order.
+func (d *Dataset) WriteJSON(w io.Writer) error {
+	keys := make([][]byte, len(d.columns))
+	for i, c := range d.columns {
+		k, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("json error: %w", err)
+		}
+		keys[i] = append(k, ':')
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteByte('[')
+	first := true
+	err := d.Each(func(row []interface{}) error {
+		if !first {
+			bw.WriteByte(',')
+		}
+		first = false
+		bw.WriteByte('{')
+		for i, v := range row {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			val, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("json error: %w", err)
+			}
+			bw.Write(keys[i])
+			bw.Write(val)
+		}
+		bw.WriteByte('}')
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteByte(']')
+	return bw.Flush()
+}
+
+// Close releases the dataset's memory and removes its spill file.
+func (d *Dataset) Close() error {
+	d.cfg.Budget.release(d.held)
+	d.held = 0
+	d.data = nil
+
+	if d.spill == nil {
+		return nil
+	}
+	name := d.spill.Name()
+	err := d.spill.Close()
+	d.spill = nil
+	if rmErr := os.Remove(name); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// rowSize estimates the memory held by a row.
+func rowSize(row []interface{}) int64 {
+	// interface header per value
+	size := int64(16 * len(row))
+	for _, v := range row {
+		switch x := v.(type) {
+		case string:
+			size += int64(len(x))
+		case []byte:
+			size += int64(len(x)) + 24
+		case time.Time:
+			size += 24
+		}
+	}
+	return size
+}
+
+// ParseSize parses a byte size such as "512MB", "2G" or "1048576".
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"GB", 1 << 30}, {"G", 1 << 30},
+		{"MB", 1 << 20}, {"M", 1 << 20},
+		{"KB", 1 << 10}, {"K", 1 << 10},
+		{"B", 1},
+	}
+
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,84 @@
+package dataset
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDatasetSpill(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       int64
+		wantSpilled bool
+	}{
+		{"fits in memory", 0, false},
+		{"spills past budget", 100, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := NewBudget(tt.limit)
+			d := New([]string{"name", "total"}, Config{Budget: budget, Dir: t.TempDir()})
+
+			for _, row := range [][]interface{}{
+				{"alice", int64(3)},
+				{"bob", nil},
+				{"carol", 2.5},
+				{"dave", int64(7)},
+			} {
+				if err := d.Append(row); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+			}
+
+			if d.Spilled() != tt.wantSpilled {
+				t.Errorf("Spilled() = %v, want %v", d.Spilled(), tt.wantSpilled)
+			}
+			if d.Len() != 4 {
+				t.Errorf("Len() = %d, want 4", d.Len())
+			}
+
+			var out strings.Builder
+			if err := d.WriteJSON(&out); err != nil {
+				t.Fatalf("WriteJSON() error = %v", err)
+			}
+			want := `[{"name":"alice","total":3},{"name":"bob","total":null},{"name":"carol","total":2.5},{"name":"dave","total":7}]`
+			if out.String() != want {
+				t.Errorf("WriteJSON() = %s, want %s", out.String(), want)
+			}
+
+			if err := d.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if budget.Used() != 0 {
+				t.Errorf("Used() after Close = %d, want 0", budget.Used())
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"1024", 1024, false},
+		{"256MB", 256 << 20, false},
+		{"2g", 2 << 30, false},
+		{"64 KB", 64 << 10, false},
+		{"lots", 0, true},
+		{"-1MB", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
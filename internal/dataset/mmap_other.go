@@ -0,0 +1,16 @@
+//go:build !unix
+
+package dataset
+
+import (
+	"errors"
+	"os"
+)
+
+// errNoMmap is returned by mapFile where memory mapping is unavailable.
+var errNoMmap = errors.New("mmap not supported")
+
+// mapFile is not supported on this platform; spill files are streamed instead.
+func mapFile(f *os.File) ([]byte, func() error, error) {
+	return nil, nil, errNoMmap
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// errNoMmap is returned by mapFile where memory mapping is unavailable.
+var errNoMmap = errors.New("mmap not supported")
+
+// mapFile maps f read-only into memory.
+func mapFile(f *os.File) ([]byte, func() error, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat spill file: %w", err)
+	}
+	if info.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+
+	data, err := unix.Mmap(int(f.Fd()), 0, int(info.Size()), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to map spill file: %w", err)
+	}
+	return data, func() error { return unix.Munmap(data) }, nil
+}
@@ -0,0 +1,100 @@
+package dialect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// BigQuery is the dialect of GoogleSQL on BigQuery, introspecting one
+// dataset.
+type BigQuery struct {
+	Project string
+	Dataset string
+}
+
+func (BigQuery) Name() string { return "BigQuery (GoogleSQL)" }
+
+func (BigQuery) Hints() []string {
+	return []string{
+		"Use FORMAT_DATE('%Y-%m', date) or DATE_TRUNC(date, MONTH) for date formatting",
+		"Use 'LIMIT n' for limiting results",
+		"Quote names with backticks (`name`) if needed; SAFE_DIVIDE avoids division-by-zero errors",
+		"Select only the columns you need: BigQuery bills by the bytes of the columns read",
+	}
+}
+
+func (BigQuery) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+func (BigQuery) Limit(query string, limit int) string { return LimitQuery(query, limit) }
+
+// Explain is not supported: BigQuery has no EXPLAIN. A dry run estimates
+// the bytes a query reads instead.
+func (BigQuery) Explain(query string) (string, bool) { return "", false }
+
+func (BigQuery) Placeholder(n int) string { return "@p" + strconv.Itoa(n) }
+
+// schema is the dataset's INFORMATION_SCHEMA.
+func (d BigQuery) schema() string {
+	return d.QuoteIdent(d.Project+"."+d.Dataset) + ".INFORMATION_SCHEMA"
+}
+
+func (d BigQuery) ColumnsQuery() string {
+	return fmt.Sprintf(`
+		SELECT c.column_name, c.data_type, c.is_nullable, c.column_default, f.description
+		FROM %[1]s.COLUMNS c
+		LEFT JOIN %[1]s.COLUMN_FIELD_PATHS f
+			ON f.table_name = c.table_name AND f.column_name = c.column_name AND f.field_path = c.column_name
+		WHERE c.table_name = @p1
+		ORDER BY c.ordinal_position
+	`, d.schema())
+}
+
+func (d BigQuery) TablesQuery() string {
+	return fmt.Sprintf(`
+		SELECT table_name
+		FROM %s.TABLES
+		ORDER BY table_name
+	`, d.schema())
+}
+
+func (d BigQuery) DescribeQuery() string {
+	return fmt.Sprintf(`
+		SELECT table_name, column_name, data_type
+		FROM %s.COLUMNS
+		ORDER BY table_name, ordinal_position
+	`, d.schema())
+}
+
+// ForeignKeysQuery reads the foreign keys declared in the dataset. BigQuery
+// does not enforce them, but they document how tables join. A foreign key
+// references a primary key, whose columns are matched by position.
+func (d BigQuery) ForeignKeysQuery() string {
+	return fmt.Sprintf(`
+		SELECT k.constraint_name, k.table_name, k.column_name, p.table_name, p.column_name
+		FROM %[1]s.TABLE_CONSTRAINTS t
+		JOIN %[1]s.KEY_COLUMN_USAGE k ON k.constraint_name = t.constraint_name
+		JOIN (SELECT DISTINCT constraint_name, table_name FROM %[1]s.CONSTRAINT_COLUMN_USAGE) u ON u.constraint_name = t.constraint_name
+		JOIN %[1]s.TABLE_CONSTRAINTS pk ON pk.table_name = u.table_name AND pk.constraint_type = 'PRIMARY KEY'
+		JOIN %[1]s.KEY_COLUMN_USAGE p ON p.constraint_name = pk.constraint_name AND p.ordinal_position = k.position_in_unique_constraint
+		WHERE t.constraint_type = 'FOREIGN KEY'
+		ORDER BY k.table_name, k.constraint_name, k.ordinal_position
+	`, d.schema())
+}
+
+// PrimaryKeysQuery reads the primary keys declared in the dataset, which
+// BigQuery does not enforce either.
+func (d BigQuery) PrimaryKeysQuery() string {
+	return fmt.Sprintf(`
+		SELECT k.table_name, k.column_name
+		FROM %[1]s.TABLE_CONSTRAINTS t
+		JOIN %[1]s.KEY_COLUMN_USAGE k ON k.constraint_name = t.constraint_name
+		WHERE t.constraint_type = 'PRIMARY KEY'
+		ORDER BY k.table_name, k.ordinal_position
+	`, d.schema())
+}
+
+// IndexesQuery is empty: BigQuery has no indexes.
+func (BigQuery) IndexesQuery() string { return "" }
@@ -0,0 +1,101 @@
+package dialect
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ClickHouse is the dialect of ClickHouse, introspecting the current
+// database.
+type ClickHouse struct{}
+
+func (ClickHouse) Name() string { return "ClickHouse" }
+
+func (ClickHouse) Hints() []string {
+	return []string{
+		"Use formatDateTime(ts, '%Y-%m') or toStartOfMonth(ts) for date formatting",
+		"Use 'LIMIT n' for limiting results; 'LIMIT n BY col' keeps n rows per group",
+		"Prefer uniq(col) over COUNT(DISTINCT col) on large tables",
+	}
+}
+
+func (ClickHouse) QuoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+// trailingClause matches a SETTINGS or FORMAT clause ending a query.
+var trailingClause = regexp.MustCompile(`(?i)\s+(SETTINGS|FORMAT)\s+[^()]*$`)
+
+// Limit adds a LIMIT to a SELECT without one. A trailing SETTINGS or
+// FORMAT clause must come after the LIMIT, so the LIMIT goes before it.
+func (ClickHouse) Limit(query string, limit int) string {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \n\t")
+	queryUpper := strings.ToUpper(query)
+	if !(strings.HasPrefix(queryUpper, "SELECT") || strings.HasPrefix(queryUpper, "WITH")) || strings.Contains(queryUpper, "LIMIT") {
+		return query
+	}
+	tail := len(query)
+	if loc := trailingClause.FindStringIndex(query); loc != nil {
+		tail = loc[0]
+	}
+	return fmt.Sprintf("%s LIMIT %d%s", query[:tail], limit, query[tail:])
+}
+
+// Placeholder is a String query parameter, bound as param_pN.
+// Explain asks for the query plan in JSON, with step descriptions.
+func (ClickHouse) Explain(query string) (string, bool) {
+	return "EXPLAIN PLAN json = 1, description = 1 " + strings.TrimRight(strings.TrimSpace(query), "; \n\t"), true
+}
+
+func (ClickHouse) Placeholder(n int) string { return "{p" + strconv.Itoa(n) + ":String}" }
+
+func (ClickHouse) ColumnsQuery() string {
+	return `
+		SELECT name, type, startsWith(type, 'Nullable('), default_expression, comment
+		FROM system.columns
+		WHERE database = currentDatabase() AND table = {p1:String}
+		ORDER BY position
+	`
+}
+
+func (ClickHouse) TablesQuery() string {
+	return `
+		SELECT name
+		FROM system.tables
+		WHERE database = currentDatabase() AND NOT is_temporary
+		ORDER BY name
+	`
+}
+
+func (ClickHouse) DescribeQuery() string {
+	return `
+		SELECT table, name, type
+		FROM system.columns
+		WHERE database = currentDatabase()
+		ORDER BY table, position
+	`
+}
+
+// ForeignKeysQuery is empty: ClickHouse has no foreign key constraints.
+func (ClickHouse) ForeignKeysQuery() string { return "" }
+
+func (ClickHouse) PrimaryKeysQuery() string {
+	return `
+		SELECT table, name
+		FROM system.columns
+		WHERE database = currentDatabase() AND is_in_primary_key
+		ORDER BY table, position
+	`
+}
+
+// IndexesQuery lists the data skipping indexes, which are never unique.
+func (ClickHouse) IndexesQuery() string {
+	return `
+		SELECT table, name, false, expr
+		FROM system.data_skipping_indices
+		WHERE database = currentDatabase()
+		ORDER BY table, name
+	`
+}
@@ -0,0 +1,397 @@
+// Package dialect describes how each supported database spells the SQL the
+// connectors generate: identifier quoting, the limit clause, bind
+// parameters and the queries that introspect tables, columns and foreign
+// keys. An Introspector runs those queries through any connector, so a new
+// database only implements a Dialect and a way to run a query.
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/joins"
+)
+
+// Dialect is the SQL spoken by a database.
+type Dialect interface {
+	// Name is the database as named to the agents, e.g. "PostgreSQL".
+	Name() string
+	// Hints are syntax reminders for the SQL agent's instruction.
+	Hints() []string
+	// QuoteIdent quotes a table or column name.
+	QuoteIdent(name string) string
+	// Limit adds a limit clause to a SELECT without one.
+	Limit(query string, limit int) string
+	// Placeholder is the n-th bind parameter, counting from 1.
+	Placeholder(n int) string
+	// Explain is the statement returning the plan of query as JSON, or
+	// false if the database cannot explain queries.
+	Explain(query string) (string, bool)
+
+	// ColumnsQuery selects column_name, data_type, nullable, default and
+	// description of the columns of the table bound to the first
+	// placeholder, in order. nullable is a boolean or "YES"/"NO"; default
+	// and description may be NULL.
+	ColumnsQuery() string
+	// TablesQuery selects the names of the tables, in order.
+	TablesQuery() string
+	// DescribeQuery selects table_name, column_name and data_type of every
+	// column, ordered by table and position.
+	DescribeQuery() string
+	// ForeignKeysQuery selects constraint_name, table_name, column_name,
+	// ref_table and ref_column, one row per column pair, ordered by table,
+	// constraint and position; "" if the database has no foreign keys.
+	ForeignKeysQuery() string
+	// PrimaryKeysQuery selects table_name and column_name of every primary
+	// key column, ordered by table and position in the key; "" if the
+	// database has no primary keys.
+	PrimaryKeysQuery() string
+	// IndexesQuery selects table_name, index_name, unique and column of the
+	// indexes other than primary keys, one row per column or expression,
+	// ordered by table, index and position; "" if the database has none.
+	IndexesQuery() string
+}
+
+// Index is an index of a table over Columns, which may be expressions.
+type Index struct {
+	Table   string
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// String describes the index, e.g. "orders_number_key UNIQUE (number)".
+func (ix Index) String() string {
+	unique := ""
+	if ix.Unique {
+		unique = " UNIQUE"
+	}
+	return fmt.Sprintf("%s%s (%s)", ix.Name, unique, strings.Join(ix.Columns, ", "))
+}
+
+// keys are the primary key, foreign keys and indexes of a table.
+type keys struct {
+	primary []string
+	foreign []joins.ForeignKey
+	indexes []Index
+}
+
+// QueryFunc runs a metadata query with bind parameters and returns its
+// rows, with values in column order.
+type QueryFunc func(ctx context.Context, query string, args ...interface{}) ([][]interface{}, error)
+
+// Introspector implements the introspection methods of the SQL agent's
+// database client from a dialect's queries. Connectors embed it.
+type Introspector struct {
+	Dialect Dialect
+	Query   QueryFunc
+	// Annotate adds fields to a column of GetSchema (optional)
+	Annotate func(table string, column map[string]interface{})
+}
+
+// GetSchema returns the columns of a table as JSON, marking the columns of
+// its primary key, the ones referencing other tables and indexed ones.
+func (in Introspector) GetSchema(ctx context.Context, tableName string) (string, error) {
+	rows, err := in.Query(ctx, in.Dialect.ColumnsQuery(), tableName)
+	if err != nil {
+		return "", fmt.Errorf("query error: %w", err)
+	}
+	k := in.keys(ctx)[tableName]
+
+	schema := []map[string]interface{}{}
+	for _, row := range rows {
+		if len(row) < 5 {
+			return "", fmt.Errorf("columns query returned %d values, want 5", len(row))
+		}
+		col := map[string]interface{}{
+			"column_name": text(row[0]),
+			"data_type":   text(row[1]),
+			"nullable":    truth(row[2]),
+		}
+		if def := text(row[3]); def != "" {
+			col["default"] = def
+		}
+		if desc := text(row[4]); desc != "" {
+			col["description"] = desc
+		}
+		name := col["column_name"].(string)
+		if slices.Contains(k.primary, name) {
+			col["primary_key"] = true
+		}
+		for _, fk := range k.foreign {
+			if i := slices.Index(fk.Columns, name); i >= 0 {
+				col["references"] = fk.RefTable + "." + fk.RefColumns[i]
+				break
+			}
+		}
+		if slices.ContainsFunc(k.indexes, func(ix Index) bool { return slices.Contains(ix.Columns, name) }) {
+			col["indexed"] = true
+		}
+		if in.Annotate != nil {
+			in.Annotate(tableName, col)
+		}
+		schema = append(schema, col)
+	}
+	return marshal(schema)
+}
+
+// ListTables returns the names of the tables as JSON.
+func (in Introspector) ListTables(ctx context.Context) (string, error) {
+	rows, err := in.Query(ctx, in.Dialect.TablesQuery())
+	if err != nil {
+		return "", fmt.Errorf("query error: %w", err)
+	}
+	tables := []string{}
+	for _, row := range rows {
+		tables = append(tables, text(row[0]))
+	}
+	return marshal(tables)
+}
+
+// DescribeDatabase returns every table with its columns, primary key,
+// foreign keys and indexes as JSON.
+func (in Introspector) DescribeDatabase(ctx context.Context) (string, error) {
+	rows, err := in.Query(ctx, in.Dialect.DescribeQuery())
+	if err != nil {
+		return "", fmt.Errorf("query error: %w", err)
+	}
+
+	tables := []map[string]interface{}{}
+	var columns []string
+	for _, row := range rows {
+		if len(row) < 3 {
+			return "", fmt.Errorf("describe query returned %d values, want 3", len(row))
+		}
+		name := text(row[0])
+		if n := len(tables); n == 0 || tables[n-1]["table"] != name {
+			columns = []string{}
+			tables = append(tables, map[string]interface{}{"table": name})
+		}
+		columns = append(columns, text(row[1])+" "+text(row[2]))
+		tables[len(tables)-1]["columns"] = columns
+	}
+
+	all := in.keys(ctx)
+	for _, t := range tables {
+		k := all[t["table"].(string)]
+		if len(k.primary) > 0 {
+			t["primary_key"] = k.primary
+		}
+		if len(k.foreign) > 0 {
+			fks := make([]string, len(k.foreign))
+			for i, fk := range k.foreign {
+				fks[i] = fk.String()
+			}
+			t["foreign_keys"] = fks
+		}
+		if len(k.indexes) > 0 {
+			indexes := make([]string, len(k.indexes))
+			for i, ix := range k.indexes {
+				indexes[i] = ix.String()
+			}
+			t["indexes"] = indexes
+		}
+	}
+	return marshal(tables)
+}
+
+// keys returns the primary key, foreign keys and indexes of every table.
+// Those the database does not have or the role cannot read are left out,
+// as the columns alone still describe the tables.
+func (in Introspector) keys(ctx context.Context) map[string]keys {
+	all := make(map[string]keys)
+	if primary, err := in.PrimaryKeys(ctx); err == nil {
+		for table, columns := range primary {
+			k := all[table]
+			k.primary = columns
+			all[table] = k
+		}
+	}
+	if foreign, err := in.ForeignKeys(ctx); err == nil {
+		for _, fk := range foreign {
+			k := all[fk.Table]
+			k.foreign = append(k.foreign, fk)
+			all[fk.Table] = k
+		}
+	}
+	if indexes, err := in.Indexes(ctx); err == nil {
+		for _, ix := range indexes {
+			k := all[ix.Table]
+			k.indexes = append(k.indexes, ix)
+			all[ix.Table] = k
+		}
+	}
+	return all
+}
+
+// PrimaryKeys returns the primary key columns of each table with one.
+func (in Introspector) PrimaryKeys(ctx context.Context) (map[string][]string, error) {
+	query := in.Dialect.PrimaryKeysQuery()
+	if query == "" {
+		return nil, nil
+	}
+	rows, err := in.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+
+	primary := make(map[string][]string)
+	for _, row := range rows {
+		if len(row) < 2 {
+			return nil, fmt.Errorf("primary keys query returned %d values, want 2", len(row))
+		}
+		table := text(row[0])
+		primary[table] = append(primary[table], text(row[1]))
+	}
+	return primary, nil
+}
+
+// Indexes returns the indexes of the tables other than primary keys.
+func (in Introspector) Indexes(ctx context.Context) ([]Index, error) {
+	query := in.Dialect.IndexesQuery()
+	if query == "" {
+		return nil, nil
+	}
+	rows, err := in.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+
+	var indexes []Index
+	for _, row := range rows {
+		if len(row) < 4 {
+			return nil, fmt.Errorf("indexes query returned %d values, want 4", len(row))
+		}
+		table, name := text(row[0]), text(row[1])
+		if n := len(indexes); n == 0 || indexes[n-1].Name != name || indexes[n-1].Table != table {
+			indexes = append(indexes, Index{Table: table, Name: name, Unique: truth(row[2])})
+		}
+		ix := &indexes[len(indexes)-1]
+		ix.Columns = append(ix.Columns, text(row[3]))
+	}
+	return indexes, nil
+}
+
+// ForeignKeys returns the foreign keys between tables.
+func (in Introspector) ForeignKeys(ctx context.Context) ([]joins.ForeignKey, error) {
+	query := in.Dialect.ForeignKeysQuery()
+	if query == "" {
+		return nil, nil
+	}
+	rows, err := in.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+
+	var keys []joins.ForeignKey
+	for _, row := range rows {
+		if len(row) < 5 {
+			return nil, fmt.Errorf("foreign keys query returned %d values, want 5", len(row))
+		}
+		name, table := text(row[0]), text(row[1])
+		if n := len(keys); n == 0 || keys[n-1].Name != name || keys[n-1].Table != table {
+			keys = append(keys, joins.ForeignKey{Name: name, Table: table, RefTable: text(row[3])})
+		}
+		k := &keys[len(keys)-1]
+		k.Columns = append(k.Columns, text(row[2]))
+		k.RefColumns = append(k.RefColumns, text(row[4]))
+	}
+	return keys, nil
+}
+
+// SQLDialect returns the dialect, so callers can tell which SQL a client
+// speaks.
+func (in Introspector) SQLDialect() Dialect {
+	return in.Dialect
+}
+
+// SQLQuery returns a QueryFunc running queries on db.
+func SQLQuery(db *sql.DB) QueryFunc {
+	return func(ctx context.Context, query string, args ...interface{}) ([][]interface{}, error) {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			return nil, err
+		}
+
+		var out [][]interface{}
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			valuePtrs := make([]interface{}, len(columns))
+			for i := range values {
+				valuePtrs[i] = &values[i]
+			}
+			if err := rows.Scan(valuePtrs...); err != nil {
+				return nil, fmt.Errorf("scan error: %w", err)
+			}
+			out = append(out, values)
+		}
+		return out, rows.Err()
+	}
+}
+
+// LimitQuery adds "LIMIT n" to a SELECT without a LIMIT, dropping a
+// trailing semicolon. It is the limit clause of most dialects.
+func LimitQuery(query string, limit int) string {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \n\t")
+	queryUpper := strings.ToUpper(query)
+	if strings.HasPrefix(queryUpper, "SELECT") && !strings.Contains(queryUpper, "LIMIT") {
+		query = fmt.Sprintf("%s LIMIT %d", query, limit)
+	}
+	return query
+}
+
+// QuoteLiteral quotes a string literal the standard way.
+func QuoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quoteIdent quotes an identifier with double quotes.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// text converts a value read from a database to a string; NULL is "".
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// truth converts a boolean or "YES"/"NO" value.
+func truth(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	}
+	s := strings.ToUpper(text(v))
+	return s == "YES" || s == "TRUE" || s == "1"
+}
+
+// marshal encodes v as JSON, leaving characters such as ">" in foreign
+// keys readable for the model.
+func marshal(v interface{}) (string, error) {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", fmt.Errorf("json error: %w", err)
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
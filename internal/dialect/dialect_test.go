@@ -0,0 +1,195 @@
+package dialect
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/joins"
+)
+
+func TestLimit(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		{Postgres{}, "SELECT * FROM t", "SELECT * FROM t LIMIT 100"},
+		{Postgres{}, "select * from t;\n", "select * from t LIMIT 100"},
+		{Postgres{}, "SELECT * FROM t LIMIT 5", "SELECT * FROM t LIMIT 5"},
+		{Postgres{}, "UPDATE t SET x = 1", "UPDATE t SET x = 1"},
+		{BigQuery{}, "SELECT * FROM t", "SELECT * FROM t LIMIT 100"},
+		{DuckDB{}, "SELECT * FROM t;", "SELECT * FROM t LIMIT 100"},
+		{ClickHouse{}, "SELECT * FROM t", "SELECT * FROM t LIMIT 100"},
+		{ClickHouse{}, "select * from t;\n", "select * from t LIMIT 100"},
+		{ClickHouse{}, "WITH x AS (SELECT 1) SELECT * FROM x", "WITH x AS (SELECT 1) SELECT * FROM x LIMIT 100"},
+		{ClickHouse{}, "SELECT * FROM t SETTINGS max_threads = 2", "SELECT * FROM t LIMIT 100 SETTINGS max_threads = 2"},
+		{ClickHouse{}, "SELECT * FROM t\nFORMAT CSV", "SELECT * FROM t LIMIT 100\nFORMAT CSV"},
+		{ClickHouse{}, "SELECT * FROM t LIMIT 5 BY user_id", "SELECT * FROM t LIMIT 5 BY user_id"},
+		{ClickHouse{}, "SHOW TABLES", "SHOW TABLES"},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.Limit(tt.query, 100); got != tt.want {
+			t.Errorf("%s: Limit(%q) = %q, want %q", tt.dialect.Name(), tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{Postgres{}.QuoteIdent(`Order "Items"`), `"Order ""Items"""`},
+		{ClickHouse{}.QuoteIdent("my`table"), "`my\\`table`"},
+		{BigQuery{}.QuoteIdent("p.d"), "`p.d`"},
+		{QuoteLiteral("it's"), "'it''s'"},
+		{Postgres{}.Placeholder(2), "$2"},
+		{BigQuery{}.Placeholder(1), "@p1"},
+		{ClickHouse{}.Placeholder(1), "{p1:String}"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %s, want %s", tt.got, tt.want)
+		}
+	}
+}
+
+// fakeQuery answers the Postgres dialect's queries with canned rows, as a
+// driver would return them.
+func fakeQuery(ctx context.Context, query string, args ...interface{}) ([][]interface{}, error) {
+	d := Postgres{}
+	switch query {
+	case d.ColumnsQuery():
+		if args[0] != "orders" {
+			return nil, nil
+		}
+		return [][]interface{}{
+			{"id", "integer", "NO", []byte("nextval('orders_id_seq'::regclass)"), nil},
+			{"customer_id", "integer", "NO", nil, nil},
+			{"amount", "text", "YES", nil, nil},
+		}, nil
+	case d.TablesQuery():
+		return [][]interface{}{{"customers"}, {"orders"}}, nil
+	case d.DescribeQuery():
+		return [][]interface{}{
+			{"customers", "id", "integer"},
+			{"orders", "id", "integer"},
+			{"orders", "customer_id", "integer"},
+		}, nil
+	case d.PrimaryKeysQuery():
+		return [][]interface{}{{"customers", "id"}, {"orders", "id"}}, nil
+	case d.IndexesQuery():
+		return [][]interface{}{
+			{"orders", "orders_customer_idx", false, "customer_id"},
+			{"orders", "orders_number_key", true, "number"},
+			{"orders", "orders_number_key", true, "lower(region)"},
+		}, nil
+	case d.ForeignKeysQuery():
+		return [][]interface{}{
+			{"lines_order_fk", "lines", "order_id", "orders", "id"},
+			{"lines_order_fk", "lines", "order_rev", "orders", "rev"},
+			{"orders_customer_fk", "orders", "customer_id", "customers", "id"},
+		}, nil
+	}
+	return nil, fmt.Errorf("unexpected query %q", query)
+}
+
+func TestIntrospector(t *testing.T) {
+	in := Introspector{
+		Dialect: Postgres{},
+		Query:   fakeQuery,
+		Annotate: func(table string, col map[string]interface{}) {
+			if col["column_name"] == "amount" {
+				col["suggested_cast"] = "amount::numeric"
+			}
+		},
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() (string, error)
+		want string
+	}{
+		{"GetSchema", func() (string, error) { return in.GetSchema(ctx, "orders") }, `[{"column_name":"id","data_type":"integer","default":"nextval('orders_id_seq'::regclass)","nullable":false,"primary_key":true},{"column_name":"customer_id","data_type":"integer","indexed":true,"nullable":false,"references":"customers.id"},{"column_name":"amount","data_type":"text","nullable":true,"suggested_cast":"amount::numeric"}]`},
+		{"GetSchema missing", func() (string, error) { return in.GetSchema(ctx, "nope") }, `[]`},
+		{"ListTables", func() (string, error) { return in.ListTables(ctx) }, `["customers","orders"]`},
+		{"DescribeDatabase", func() (string, error) { return in.DescribeDatabase(ctx) }, `[{"columns":["id integer"],"primary_key":["id"],"table":"customers"},{"columns":["id integer","customer_id integer"],"foreign_keys":["orders(customer_id) -> customers(id)"],"indexes":["orders_customer_idx (customer_id)","orders_number_key UNIQUE (number, lower(region))"],"primary_key":["id"],"table":"orders"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("ForeignKeys", func(t *testing.T) {
+		got, err := in.ForeignKeys(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []joins.ForeignKey{
+			{Name: "lines_order_fk", Table: "lines", Columns: []string{"order_id", "order_rev"}, RefTable: "orders", RefColumns: []string{"id", "rev"}},
+			{Name: "orders_customer_fk", Table: "orders", Columns: []string{"customer_id"}, RefTable: "customers", RefColumns: []string{"id"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("no foreign keys", func(t *testing.T) {
+		in := Introspector{Dialect: ClickHouse{}, Query: fakeQuery}
+		if got, err := in.ForeignKeys(ctx); got != nil || err != nil {
+			t.Errorf("got %v, %v; want nil", got, err)
+		}
+	})
+}
+
+func TestPostgresSearchPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		schemas   []string
+		wantPath  string
+		wantOrder string
+		wantHint  bool
+	}{
+		{"default", nil, "table_schema IN ('public')", "CASE table_schema WHEN 'public' THEN 0 END", false},
+		{"several schemas", []string{"sales", "public"}, "table_schema IN ('sales', 'public')", "CASE table_schema WHEN 'sales' THEN 0 WHEN 'public' THEN 1 END", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Postgres{Schemas: tt.schemas}
+			for _, query := range []string{d.ColumnsQuery(), d.TablesQuery(), d.DescribeQuery()} {
+				if !strings.Contains(query, tt.wantPath) {
+					t.Errorf("query %s does not filter on %s", query, tt.wantPath)
+				}
+			}
+			if q := d.TablesQuery(); !strings.Contains(q, tt.wantOrder) {
+				t.Errorf("tables query %s does not order by %s", q, tt.wantOrder)
+			}
+			hint := slices.ContainsFunc(d.Hints(), func(h string) bool { return strings.Contains(h, "schema-qualified") })
+			if hint != tt.wantHint {
+				t.Errorf("qualified names hint = %v, want %v", hint, tt.wantHint)
+			}
+		})
+	}
+
+	d := Postgres{}
+	for _, tt := range []struct{ schema, table, want string }{
+		{"public", "orders", "orders"},
+		{"sales", "orders", "sales.orders"},
+	} {
+		if got := d.TableName(tt.schema, tt.table); got != tt.want {
+			t.Errorf("TableName(%q, %q) = %q, want %q", tt.schema, tt.table, got, tt.want)
+		}
+	}
+}
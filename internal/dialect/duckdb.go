@@ -0,0 +1,86 @@
+package dialect
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DuckDB is the dialect of DuckDB, introspecting the main schema of the
+// database and of its temporary views.
+type DuckDB struct{}
+
+func (DuckDB) Name() string { return "DuckDB" }
+
+func (DuckDB) Hints() []string {
+	return []string{
+		"Use strftime(date, '%Y-%m') or date_trunc('month', date) for date formatting",
+		"Use 'LIMIT n' for limiting results",
+		`Quote column names that contain spaces or capitals with double quotes "Column Name"`,
+	}
+}
+
+func (DuckDB) QuoteIdent(name string) string { return quoteIdent(name) }
+
+func (DuckDB) Limit(query string, limit int) string { return LimitQuery(query, limit) }
+
+// Explain asks for the plan in JSON without running the query.
+func (DuckDB) Explain(query string) (string, bool) {
+	return "EXPLAIN (FORMAT JSON) " + strings.TrimRight(strings.TrimSpace(query), "; \n\t"), true
+}
+
+func (DuckDB) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (DuckDB) ColumnsQuery() string {
+	return `
+		SELECT column_name, data_type, is_nullable, column_default, NULL
+		FROM information_schema.columns
+		WHERE table_schema = 'main' AND table_name = $1
+		ORDER BY ordinal_position
+	`
+}
+
+func (DuckDB) TablesQuery() string {
+	return `
+		SELECT table_name
+		FROM information_schema.tables
+		WHERE table_schema = 'main'
+		ORDER BY table_name
+	`
+}
+
+func (DuckDB) DescribeQuery() string {
+	return `
+		SELECT table_name, column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = 'main'
+		ORDER BY table_name, ordinal_position
+	`
+}
+
+func (DuckDB) ForeignKeysQuery() string {
+	return `
+		SELECT constraint_name, table_name, unnest(constraint_column_names), referenced_table, unnest(referenced_column_names)
+		FROM duckdb_constraints()
+		WHERE constraint_type = 'FOREIGN KEY' AND schema_name = 'main'
+		ORDER BY table_name, constraint_name
+	`
+}
+
+func (DuckDB) PrimaryKeysQuery() string {
+	return `
+		SELECT table_name, unnest(constraint_column_names)
+		FROM duckdb_constraints()
+		WHERE constraint_type = 'PRIMARY KEY' AND schema_name = 'main'
+		ORDER BY table_name
+	`
+}
+
+// IndexesQuery lists each index with its expressions as one column.
+func (DuckDB) IndexesQuery() string {
+	return `
+		SELECT table_name, index_name, is_unique, expressions
+		FROM duckdb_indexes()
+		WHERE schema_name = 'main'
+		ORDER BY table_name, index_name
+	`
+}
@@ -0,0 +1,164 @@
+package dialect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PublicSchema is the schema whose tables are named without a schema.
+const PublicSchema = "public"
+
+// Postgres is the dialect of PostgreSQL, introspecting the schemas of its
+// search path. Tables outside the public schema are named schema.table,
+// so the names work whatever the connection's search_path.
+type Postgres struct {
+	// Schemas are the schemas introspected, in order (defaults to public)
+	Schemas []string
+}
+
+func (Postgres) Name() string { return "PostgreSQL" }
+
+func (d Postgres) Hints() []string {
+	hints := []string{
+		"Use TO_CHAR(date, 'YYYY-MM') for date formatting (not DATE_FORMAT)",
+		"Use 'LIMIT n' for limiting results",
+		`Use double quotes "Identifier" for mixed-case table/column names if needed (but usually lowercase is fine)`,
+	}
+	for _, schema := range d.SearchPath() {
+		if schema != PublicSchema {
+			hints = append(hints, fmt.Sprintf("Tables outside the public schema are listed schema-qualified, e.g. %s.orders: always write them with their schema, exactly as listed, and never drop it, even in joins and subqueries", schema))
+			break
+		}
+	}
+	return hints
+}
+
+// SearchPath returns the schemas introspected, in order.
+func (d Postgres) SearchPath() []string {
+	if len(d.Schemas) == 0 {
+		return []string{PublicSchema}
+	}
+	return d.Schemas
+}
+
+// TableName is the name the agents know the table of a schema by.
+func (Postgres) TableName(schema, table string) string {
+	if schema == PublicSchema {
+		return table
+	}
+	return schema + "." + table
+}
+
+// nameExpr is the SQL for TableName of the schema and table columns.
+func (Postgres) nameExpr(schema, table string) string {
+	return fmt.Sprintf("CASE WHEN %[1]s = '%[3]s' THEN %[2]s::text ELSE %[1]s || '.' || %[2]s END", schema, table, PublicSchema)
+}
+
+// inPath is the SQL condition that column names a schema of the search
+// path.
+func (d Postgres) inPath(column string) string {
+	schemas := make([]string, len(d.SearchPath()))
+	for i, s := range d.SearchPath() {
+		schemas[i] = QuoteLiteral(s)
+	}
+	return column + " IN (" + strings.Join(schemas, ", ") + ")"
+}
+
+// pathOrder is the SQL ordering schemas by their place in the search path.
+func (d Postgres) pathOrder(column string) string {
+	var b strings.Builder
+	b.WriteString("CASE " + column)
+	for i, s := range d.SearchPath() {
+		fmt.Fprintf(&b, " WHEN %s THEN %d", QuoteLiteral(s), i)
+	}
+	b.WriteString(" END")
+	return b.String()
+}
+
+func (Postgres) QuoteIdent(name string) string { return quoteIdent(name) }
+
+func (Postgres) Limit(query string, limit int) string { return LimitQuery(query, limit) }
+
+// Explain asks for the plan in JSON without running the query.
+func (Postgres) Explain(query string) (string, bool) {
+	return "EXPLAIN (FORMAT JSON) " + strings.TrimRight(strings.TrimSpace(query), "; \n\t"), true
+}
+
+func (Postgres) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+// ColumnsQuery finds the table by the name TableName gives it, or by
+// schema.table for a table of the public schema.
+func (d Postgres) ColumnsQuery() string {
+	return `
+		SELECT column_name, data_type, is_nullable, column_default,
+			col_description(format('%I.%I', table_schema, table_name)::regclass, ordinal_position::int)
+		FROM information_schema.columns
+		WHERE ` + d.inPath("table_schema") + `
+			AND (` + d.nameExpr("table_schema", "table_name") + ` = $1 OR table_schema || '.' || table_name = $1)
+		ORDER BY ordinal_position
+	`
+}
+
+func (d Postgres) TablesQuery() string {
+	return `
+		SELECT ` + d.nameExpr("table_schema", "table_name") + `
+		FROM information_schema.tables
+		WHERE ` + d.inPath("table_schema") + `
+		ORDER BY ` + d.pathOrder("table_schema") + `, table_name
+	`
+}
+
+func (d Postgres) DescribeQuery() string {
+	return `
+		SELECT ` + d.nameExpr("c.table_schema", "c.table_name") + `, c.column_name, c.data_type
+		FROM information_schema.tables t
+		JOIN information_schema.columns c ON t.table_name = c.table_name AND t.table_schema = c.table_schema
+		WHERE ` + d.inPath("t.table_schema") + `
+		ORDER BY ` + d.pathOrder("c.table_schema") + `, c.table_name, c.ordinal_position
+	`
+}
+
+func (d Postgres) ForeignKeysQuery() string {
+	return `
+		SELECT con.conname, ` + d.nameExpr("n.nspname", "src.relname") + `, sa.attname::text, ` + d.nameExpr("dn.nspname", "dst.relname") + `, da.attname::text
+		FROM pg_constraint con
+		JOIN pg_class src ON src.oid = con.conrelid
+		JOIN pg_class dst ON dst.oid = con.confrelid
+		JOIN pg_namespace n ON n.oid = src.relnamespace
+		JOIN pg_namespace dn ON dn.oid = dst.relnamespace
+		CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(src_att, dst_att, ord)
+		JOIN pg_attribute sa ON sa.attrelid = con.conrelid AND sa.attnum = k.src_att
+		JOIN pg_attribute da ON da.attrelid = con.confrelid AND da.attnum = k.dst_att
+		WHERE con.contype = 'f' AND ` + d.inPath("n.nspname") + `
+		ORDER BY ` + d.pathOrder("n.nspname") + `, src.relname, con.conname, k.ord
+	`
+}
+
+func (d Postgres) PrimaryKeysQuery() string {
+	return `
+		SELECT ` + d.nameExpr("n.nspname", "t.relname") + `, a.attname::text
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord)
+		JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+		WHERE ix.indisprimary AND ` + d.inPath("n.nspname") + `
+		ORDER BY ` + d.pathOrder("n.nspname") + `, t.relname, k.ord
+	`
+}
+
+// IndexesQuery lists each key column or expression of an index as
+// pg_get_indexdef spells it, leaving out included columns.
+func (d Postgres) IndexesQuery() string {
+	return `
+		SELECT ` + d.nameExpr("n.nspname", "t.relname") + `, i.relname::text, ix.indisunique, pg_get_indexdef(ix.indexrelid, k.ord, true)
+		FROM pg_index ix
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		CROSS JOIN LATERAL generate_series(1, ix.indnkeyatts) AS k(ord)
+		WHERE NOT ix.indisprimary AND ` + d.inPath("n.nspname") + `
+		ORDER BY ` + d.pathOrder("n.nspname") + `, t.relname, i.relname, k.ord
+	`
+}
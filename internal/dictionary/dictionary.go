@@ -0,0 +1,267 @@
+// Package dictionary merges human-written descriptions of tables and
+// columns, and a glossary of business terms, into the schema the SQL agent
+// is prompted with, so terms like "MRR" or "churned customer" map to the
+// right columns. Descriptions come from a YAML file, a comments table or
+// the database's own comments (COMMENT ON in PostgreSQL).
+package dictionary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Dictionary describes tables and columns and defines business terms.
+// Table names are matched case-insensitively against the names the
+// database description gives them.
+//
+// Example YAML:
+//
+//	tables:
+//	  subscriptions:
+//	    description: One row per customer plan, kept after cancellation
+//	    columns:
+//	      amount: Monthly price in USD, excluding tax
+//	      canceled_at: Set when the customer churned
+//	terms:
+//	  MRR: sum(subscriptions.amount) of subscriptions with canceled_at IS NULL
+//	  churned customer: a customer whose subscriptions all have canceled_at set
+type Dictionary struct {
+	Tables map[string]Table  `yaml:"tables"`
+	Terms  map[string]string `yaml:"terms"`
+}
+
+// Table describes a table and its columns.
+type Table struct {
+	Description string            `yaml:"description"`
+	Columns     map[string]string `yaml:"columns"`
+}
+
+// Load reads a dictionary from a YAML file.
+func Load(path string) (*Dictionary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data dictionary: %w", err)
+	}
+
+	var d Dictionary
+	if err := yaml.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse data dictionary: %w", err)
+	}
+	d.normalize()
+	return &d, nil
+}
+
+// normalize lowercases table and column names and drops empty entries.
+func (d *Dictionary) normalize() {
+	tables := make(map[string]Table, len(d.Tables))
+	for name, t := range d.Tables {
+		columns := make(map[string]string, len(t.Columns))
+		for column, desc := range t.Columns {
+			if desc = strings.TrimSpace(desc); desc != "" {
+				columns[strings.ToLower(strings.TrimSpace(column))] = desc
+			}
+		}
+		tables[strings.ToLower(strings.TrimSpace(name))] = Table{Description: strings.TrimSpace(t.Description), Columns: columns}
+	}
+	d.Tables = tables
+}
+
+// Describe sets the description of table, or of its column if column is
+// not empty. Empty descriptions are ignored.
+func (d *Dictionary) Describe(table, column, description string) {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return
+	}
+	if d.Tables == nil {
+		d.Tables = make(map[string]Table)
+	}
+	table = strings.ToLower(strings.TrimSpace(table))
+	t := d.Tables[table]
+	if column = strings.ToLower(strings.TrimSpace(column)); column == "" {
+		t.Description = description
+	} else {
+		if t.Columns == nil {
+			t.Columns = make(map[string]string)
+		}
+		t.Columns[column] = description
+	}
+	d.Tables[table] = t
+}
+
+// Merge adds the descriptions and terms of other that d lacks, so d takes
+// precedence.
+func (d *Dictionary) Merge(other *Dictionary) {
+	if other == nil {
+		return
+	}
+	for name, t := range other.Tables {
+		if d.Tables[name].Description == "" {
+			d.Describe(name, "", t.Description)
+		}
+		for column, desc := range t.Columns {
+			if _, ok := d.Tables[name].Columns[column]; !ok {
+				d.Describe(name, column, desc)
+			}
+		}
+	}
+	for term, def := range other.Terms {
+		if _, ok := d.Terms[term]; !ok {
+			if d.Terms == nil {
+				d.Terms = make(map[string]string)
+			}
+			d.Terms[term] = def
+		}
+	}
+}
+
+// Apply adds the descriptions to a DescribeDatabase description: a table's
+// own as "description" and its columns' as "column_descriptions".
+func (d *Dictionary) Apply(description string) (string, error) {
+	if d == nil || len(d.Tables) == 0 {
+		return description, nil
+	}
+	var tables []map[string]interface{}
+	if err := json.Unmarshal([]byte(description), &tables); err != nil {
+		return "", fmt.Errorf("failed to parse database description: %w", err)
+	}
+
+	for _, entry := range tables {
+		name, _ := entry["table"].(string)
+		t, ok := d.Tables[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		if t.Description != "" {
+			entry["description"] = t.Description
+		}
+		columns := make(map[string]string)
+		listed, _ := entry["columns"].([]interface{})
+		for _, c := range listed {
+			column, _, _ := strings.Cut(fmt.Sprint(c), " ")
+			if desc, ok := t.Columns[strings.ToLower(column)]; ok {
+				columns[column] = desc
+			}
+		}
+		if len(columns) > 0 {
+			entry["column_descriptions"] = columns
+		}
+	}
+
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(tables); err != nil {
+		return "", fmt.Errorf("json error: %w", err)
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// Glossary lists business terms and their definitions as a section of the
+// SQL agent's instruction, or returns "" if there are none.
+func Glossary(terms map[string]string) string {
+	if len(terms) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(terms))
+	for term := range terms {
+		names = append(names, term)
+	}
+	slices.SortFunc(names, func(a, b string) int { return strings.Compare(strings.ToLower(a), strings.ToLower(b)) })
+
+	var b strings.Builder
+	b.WriteString("## Business Terms\n")
+	b.WriteString("When a question uses one of these terms, compute it exactly as defined here, using the columns named:\n")
+	for _, term := range names {
+		fmt.Fprintf(&b, "- %s: %s\n", term, strings.TrimSpace(terms[term]))
+	}
+	return b.String()
+}
+
+// Querier runs queries, as the SQL agent's database client does.
+type Querier interface {
+	Query(ctx context.Context, query string, limit int) (string, error)
+}
+
+// maxComments bounds the rows read from a comments table.
+const maxComments = 100000
+
+// FromTable reads descriptions from a comments table with the columns
+// table_name, column_name and description. Rows whose column_name is NULL
+// or empty describe the table itself.
+func FromTable(db Querier, table string) Source {
+	return func(ctx context.Context) (*Dictionary, error) {
+		query := "SELECT table_name, column_name, description FROM " + table
+		result, err := db.Query(ctx, query, maxComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read comments table %s: %w", table, err)
+		}
+		var rows []map[string]interface{}
+		if err := json.Unmarshal([]byte(result), &rows); err != nil {
+			return nil, fmt.Errorf("failed to parse comments table %s: %w", table, err)
+		}
+
+		d := &Dictionary{}
+		for _, row := range rows {
+			d.Describe(text(row["table_name"]), text(row["column_name"]), text(row["description"]))
+		}
+		return d, nil
+	}
+}
+
+// text returns a value of a result row as a string, or "" for NULL.
+func text(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// Source reads a dictionary, e.g. from a comments table, each time the
+// database is described.
+type Source func(ctx context.Context) (*Dictionary, error)
+
+// Database describes every table of a database, as the SQL agent's
+// database client does.
+type Database interface {
+	DescribeDatabase(ctx context.Context) (string, error)
+}
+
+// Describer adds the descriptions of a dictionary and its sources to the
+// description of a database.
+type Describer struct {
+	DB Database
+	// Dictionary takes precedence over Sources (optional)
+	Dictionary *Dictionary
+	// Sources are read in order, earlier ones taking precedence
+	Sources []Source
+}
+
+// DescribeDatabase describes the database with the descriptions merged in.
+// A source that fails is logged and skipped, as the schema is still usable
+// without it.
+func (d Describer) DescribeDatabase(ctx context.Context) (string, error) {
+	description, err := d.DB.DescribeDatabase(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	merged := &Dictionary{}
+	merged.Merge(d.Dictionary)
+	for _, source := range d.Sources {
+		more, err := source(ctx)
+		if err != nil {
+			log.Printf("⚠️  Warning: Could not read data dictionary: %v", err)
+			continue
+		}
+		merged.Merge(more)
+	}
+	return merged.Apply(description)
+}
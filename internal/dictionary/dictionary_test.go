@@ -0,0 +1,86 @@
+package dictionary
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const description = `[{"table":"customers","columns":["id integer","Segment text"]},{"table":"sales.subscriptions","columns":["id integer","amount numeric","canceled_at timestamp"]}]`
+
+// fakeDB describes the database and serves a comments table.
+type fakeDB struct {
+	comments string
+	err      error
+}
+
+func (f fakeDB) DescribeDatabase(ctx context.Context) (string, error) {
+	return description, nil
+}
+
+func (f fakeDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	return f.comments, f.err
+}
+
+func TestDescriber(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dictionary.yaml")
+	yaml := `
+tables:
+  Sales.Subscriptions:
+    description: One row per customer plan
+    columns:
+      amount: Monthly price in USD
+terms:
+  MRR: sum(sales.subscriptions.amount) where canceled_at IS NULL
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dict, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	db := fakeDB{comments: `[
+		{"table_name":"sales.subscriptions","column_name":"amount","description":"Price"},
+		{"table_name":"sales.subscriptions","column_name":"canceled_at","description":"Set when the customer churned"},
+		{"table_name":"customers","column_name":null,"description":"People and companies that bought"},
+		{"table_name":"customers","column_name":"segment","description":"smb, mid or enterprise"}
+	]`}
+	failing := func(ctx context.Context) (*Dictionary, error) { return nil, errors.New("no such table") }
+	d := Describer{DB: db, Dictionary: dict, Sources: []Source{FromTable(db, "meta.comments"), failing}}
+
+	got, err := d.DescribeDatabase(context.Background())
+	if err != nil {
+		t.Fatalf("DescribeDatabase() error = %v", err)
+	}
+	for _, want := range []string{
+		`"column_descriptions":{"Segment":"smb, mid or enterprise"},"columns":["id integer","Segment text"],"description":"People and companies that bought","table":"customers"`,
+		`"column_descriptions":{"amount":"Monthly price in USD","canceled_at":"Set when the customer churned"}`,
+		`"description":"One row per customer plan","table":"sales.subscriptions"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("DescribeDatabase() = %s, lacks %s", got, want)
+		}
+	}
+
+	if got := Glossary(dict.Terms); got != "## Business Terms\nWhen a question uses one of these terms, compute it exactly as defined here, using the columns named:\n- MRR: sum(sales.subscriptions.amount) where canceled_at IS NULL\n" {
+		t.Errorf("Glossary() = %q", got)
+	}
+	if got := Glossary(nil); got != "" {
+		t.Errorf("Glossary(nil) = %q, want none", got)
+	}
+}
+
+func TestApplyWithoutDescriptions(t *testing.T) {
+	var d *Dictionary
+	if got, err := d.Apply(description); err != nil || got != description {
+		t.Errorf("Apply() = %s, %v; want the description unchanged", got, err)
+	}
+	if _, err := (&Dictionary{Tables: map[string]Table{"customers": {Description: "x"}}}).Apply("not json"); err == nil {
+		t.Error("Apply() accepted a description that is not JSON")
+	}
+}
@@ -0,0 +1,217 @@
+// Package duckdb implements the SQL agent's database client on DuckDB, so
+// CSV, Parquet and JSON files can be queried without a database server.
+// Each data file in the configured directory is attached as a view named
+// after the file, and every call runs the duckdb command-line tool on a
+// fresh in-memory database (or the configured database file, read-only),
+// so files added or changed while the agent runs are picked up.
+package duckdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/dataset"
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+	"github.com/anuvratrastogi/multi-agent/internal/joins"
+)
+
+// Config holds configuration for the DuckDB client.
+type Config struct {
+	// Binary is the duckdb command-line tool (default: "duckdb" on the PATH)
+	Binary string
+	// Database is a DuckDB database file opened read-only (optional)
+	Database string
+	// Dir holds the data files attached as views (optional)
+	Dir string
+	// Results sets the memory budget and spill directory for query results
+	Results dataset.Config
+}
+
+// Client queries DuckDB. It implements sqlagent.MCPClient.
+type Client struct {
+	// Introspector reads tables and views from information_schema
+	dialect.Introspector
+	cfg Config
+}
+
+// New creates a client and checks that the duckdb tool runs.
+func New(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.Binary == "" {
+		cfg.Binary = "duckdb"
+	}
+	if cfg.Dir != "" {
+		dir, err := filepath.Abs(cfg.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data directory: %w", err)
+		}
+		cfg.Dir = dir
+	}
+	c := &Client{cfg: cfg}
+	c.Introspector = dialect.Introspector{Dialect: dialect.DuckDB{}, Query: c.metadata}
+	if _, err := c.Query(ctx, "SELECT 1", 1); err != nil {
+		return nil, fmt.Errorf("failed to start duckdb: %w", err)
+	}
+	return c, nil
+}
+
+// SetResultStorage sets the memory budget and spill directory used while
+// reading query results.
+func (c *Client) SetResultStorage(cfg dataset.Config) {
+	c.cfg.Results = cfg
+}
+
+// Query executes a SQL query over the data files and returns results as
+// JSON.
+func (c *Client) Query(ctx context.Context, query string, limit int) (string, error) {
+	files, err := c.Files()
+	if err != nil {
+		return "", err
+	}
+	return c.query(ctx, files, "", prepare(c.Dialect.Limit(query, limit), sqlagent.ArgsFrom(ctx)))
+}
+
+// QueryFile executes a SQL query in which the table data is the given data
+// file, and returns results as JSON. The file is a path relative to the
+// data directory, as listed by Files, or its view name.
+func (c *Client) QueryFile(ctx context.Context, file, query string, limit int) (string, error) {
+	files, err := c.Files()
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if f.Path == file || f.Table == file {
+			setup := fmt.Sprintf("CREATE OR REPLACE TEMP VIEW data AS SELECT * FROM %s;\n", f.reader())
+			return c.query(ctx, files, setup, c.Dialect.Limit(query, limit))
+		}
+	}
+	return "", fmt.Errorf("no data file %q; use list_files to see the available files", file)
+}
+
+// query runs setup statements followed by query.
+func (c *Client) query(ctx context.Context, files []File, setup, query string) (string, error) {
+	var result *sqlagent.Results
+	defer func() {
+		if result != nil {
+			result.Close()
+		}
+	}()
+	err := c.run(ctx, files, setup+query, func(columns []string, values []interface{}) error {
+		if result == nil {
+			result = sqlagent.NewResults(ctx, query, columns, c.cfg.Results)
+		}
+		return result.Append(values)
+	})
+	if err != nil {
+		return "", fmt.Errorf("query error: %w", err)
+	}
+	if result == nil {
+		return "[]", nil
+	}
+	return result.JSON()
+}
+
+// ForeignKeys returns the foreign keys declared in the database file. Data
+// files have none.
+func (c *Client) ForeignKeys(ctx context.Context) ([]joins.ForeignKey, error) {
+	if c.cfg.Database == "" {
+		return nil, nil
+	}
+	return c.Introspector.ForeignKeys(ctx)
+}
+
+// Close does nothing: no process outlives a call.
+func (c *Client) Close() error {
+	return nil
+}
+
+// metadata runs an introspection query.
+func (c *Client) metadata(ctx context.Context, query string, args ...interface{}) ([][]interface{}, error) {
+	files, err := c.Files()
+	if err != nil {
+		return nil, err
+	}
+	var out [][]interface{}
+	err = c.run(ctx, files, prepare(query, args), func(_ []string, row []interface{}) error {
+		out = append(out, row)
+		return nil
+	})
+	return out, err
+}
+
+// prepare binds args to the $1, $2... placeholders of query. The duckdb tool
+// takes no bind parameters, so the query is prepared and executed with
+// them as literals; a query without args is returned unchanged.
+func prepare(query string, args []interface{}) string {
+	if len(args) == 0 {
+		return query
+	}
+	literals := make([]string, len(args))
+	for i, arg := range args {
+		switch arg := arg.(type) {
+		case nil:
+			literals[i] = "NULL"
+		case bool:
+			literals[i] = strings.ToUpper(strconv.FormatBool(arg))
+		case float64:
+			literals[i] = strconv.FormatFloat(arg, 'f', -1, 64)
+		default:
+			literals[i] = dialect.QuoteLiteral(fmt.Sprint(arg))
+		}
+	}
+	return fmt.Sprintf("PREPARE stmt AS %s;\nEXECUTE stmt(%s)", strings.TrimSpace(query), strings.Join(literals, ", "))
+}
+
+// run executes a script that attaches the data files and then runs query,
+// passing each result row to fn. The views are temporary so they can be
+// created over a read-only database file.
+func (c *Client) run(ctx context.Context, files []File, query string, fn func(columns []string, row []interface{}) error) error {
+	var script strings.Builder
+	for _, f := range files {
+		fmt.Fprintf(&script, "CREATE TEMP VIEW %s AS SELECT * FROM %s;\n", dialect.DuckDB{}.QuoteIdent(f.Table), f.reader())
+	}
+	if c.cfg.Dir != "" {
+		// Keep queries to the data directory and away from the network
+		fmt.Fprintf(&script, "SET allowed_directories = [%s];\n", dialect.QuoteLiteral(c.cfg.Dir))
+	}
+	script.WriteString("SET enable_external_access = false;\nSET lock_configuration = true;\n")
+	script.WriteString(query)
+	script.WriteString(";\n")
+
+	args := []string{"-json", "-bail", "-batch"}
+	if c.cfg.Database != "" {
+		args = append(args, "-readonly", c.cfg.Database)
+	}
+	cmd := exec.CommandContext(ctx, c.cfg.Binary, args...)
+	cmd.Stdin = strings.NewReader(script.String())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	readErr := decodeRows(stdout, fn)
+	if readErr != nil {
+		// Stop the process so Wait does not block on a full pipe
+		io.Copy(io.Discard, stdout)
+	}
+	waitErr := cmd.Wait()
+	if msg := strings.TrimSpace(stderr.String()); msg != "" {
+		return errors.New(msg)
+	}
+	if readErr != nil {
+		return readErr
+	}
+	return waitErr
+}
@@ -0,0 +1,127 @@
+package duckdb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+)
+
+// fakeDuckDB writes a stand-in for the duckdb tool that saves the script it
+// reads and prints canned output chosen by the query it ends with.
+func fakeDuckDB(t *testing.T) (binary, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake duckdb is a shell script")
+	}
+	dir := t.TempDir()
+	script = filepath.Join(dir, "script.sql")
+	binary = filepath.Join(dir, "duckdb")
+	src := `#!/bin/sh
+cat > ` + script + `
+case "$(tail -n 1 ` + script + `)" in
+*broken*) echo 'Binder Error: Referenced column "nope" not found' >&2; exit 1 ;;
+*"SELECT 1"*) echo '[{"1":1}]' ;;
+*empty*) ;;
+*) printf '[{"region":"EU","total":12.5,"orders":9007199254740993},\n{"region":"US","total":3,"orders":2}]\n' ;;
+esac
+`
+	if err := os.WriteFile(binary, []byte(src), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return binary, script
+}
+
+func newClient(t *testing.T) (*Client, string) {
+	t.Helper()
+	binary, script := fakeDuckDB(t)
+	dir := t.TempDir()
+	for _, name := range []string{"sales.csv", "2024 Q1.parquet", "logs/app.jsonl.gz", "notes.txt", ".hidden/x.csv"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := New(context.Background(), Config{Binary: binary, Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, script
+}
+
+func TestFiles(t *testing.T) {
+	c, _ := newClient(t)
+	files, err := c.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Path+"="+f.Table+":"+f.Format)
+	}
+	want := "2024 Q1.parquet=t_2024_q1:parquet logs/app.jsonl.gz=logs_app:json sales.csv=sales:csv"
+	if strings.Join(got, " ") != want {
+		t.Errorf("files = %v, want %s", got, want)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	c, script := newClient(t)
+	ctx := context.Background()
+
+	got, err := c.Query(ctx, "SELECT region, sum(amount) AS total FROM sales GROUP BY 1;", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"region":"EU","total":12.5,"orders":9007199254740993},{"region":"US","total":3,"orders":2}]`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	sent, _ := os.ReadFile(script)
+	for _, want := range []string{
+		`CREATE TEMP VIEW "sales" AS SELECT * FROM read_csv_auto('` + c.cfg.Dir + `/sales.csv');`,
+		`CREATE TEMP VIEW "logs_app" AS SELECT * FROM read_json_auto(`,
+		`SET enable_external_access = false;`,
+		"FROM sales GROUP BY 1 LIMIT 10;\n",
+	} {
+		if !strings.Contains(string(sent), want) {
+			t.Errorf("script missing %q:\n%s", want, sent)
+		}
+	}
+
+	if _, err := c.Query(sqlagent.WithArgs(ctx, []interface{}{"O'Brien", float64(3), nil}), "SELECT * FROM sales WHERE name = $1 AND qty > $2 OR note = $3", 10); err != nil {
+		t.Fatal(err)
+	}
+	sent, _ = os.ReadFile(script)
+	if want := "PREPARE stmt AS SELECT * FROM sales WHERE name = $1 AND qty > $2 OR note = $3 LIMIT 10;\nEXECUTE stmt('O''Brien', 3, NULL);\n"; !strings.HasSuffix(string(sent), want) {
+		t.Errorf("script does not bind the args:\n%s", sent)
+	}
+
+	if got, err := c.Query(ctx, "SELECT * FROM sales WHERE empty", 10); err != nil || got != "[]" {
+		t.Errorf("empty result = %s, %v", got, err)
+	}
+	if _, err := c.Query(ctx, "SELECT nope FROM broken", 10); err == nil || !strings.Contains(err.Error(), "Binder Error") {
+		t.Errorf("err = %v, want the duckdb error", err)
+	}
+}
+
+func TestQueryFile(t *testing.T) {
+	c, script := newClient(t)
+	ctx := context.Background()
+
+	if _, err := c.QueryFile(ctx, "2024 Q1.parquet", "SELECT count(*) FROM data", 5); err != nil {
+		t.Fatal(err)
+	}
+	sent, _ := os.ReadFile(script)
+	if !strings.Contains(string(sent), "CREATE OR REPLACE TEMP VIEW data AS SELECT * FROM read_parquet(") {
+		t.Errorf("script does not alias the file as data:\n%s", sent)
+	}
+
+	if _, err := c.QueryFile(ctx, "../etc/passwd", "SELECT * FROM data", 5); err == nil || !strings.Contains(err.Error(), "list_files") {
+		t.Errorf("err = %v, want unknown file", err)
+	}
+}
@@ -0,0 +1,132 @@
+package duckdb
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+)
+
+// File is a data file attached as a view.
+type File struct {
+	// Path is relative to the data directory, with forward slashes
+	Path string `json:"path"`
+	// Table is the view the file is queried through
+	Table    string    `json:"table"`
+	Format   string    `json:"format"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+
+	abs      string
+	function string
+}
+
+// readers are the table functions reading each format, by extension.
+var readers = map[string]struct{ format, function string }{
+	".csv":     {"csv", "read_csv_auto"},
+	".tsv":     {"csv", "read_csv_auto"},
+	".parquet": {"parquet", "read_parquet"},
+	".json":    {"json", "read_json_auto"},
+	".jsonl":   {"json", "read_json_auto"},
+	".ndjson":  {"json", "read_json_auto"},
+}
+
+// Files returns the data files in the data directory and its
+// subdirectories, sorted by path. Hidden files and directories are skipped.
+func (c *Client) Files() ([]File, error) {
+	if c.cfg.Dir == "" {
+		return nil, nil
+	}
+	var files []File
+	err := filepath.WalkDir(c.cfg.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != c.cfg.Dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		// Compressed files are read transparently
+		name := strings.TrimSuffix(strings.ToLower(d.Name()), ".gz")
+		r, ok := readers[filepath.Ext(name)]
+		if !ok {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(c.cfg.Dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, File{
+			Path:     filepath.ToSlash(rel),
+			Format:   r.format,
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+			abs:      path,
+			function: r.function,
+		})
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("data directory %s does not exist", c.cfg.Dir)
+		}
+		return nil, fmt.Errorf("failed to list data files: %w", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	taken := map[string]bool{}
+	for i := range files {
+		files[i].Table = uniqueName(tableName(files[i].Path), taken)
+	}
+	return files, nil
+}
+
+// reader returns the table function call reading the file.
+func (f File) reader() string {
+	return fmt.Sprintf("%s(%s)", f.function, dialect.QuoteLiteral(f.abs))
+}
+
+// tableName derives a view name from a file path: "sales/2024 Q1.csv"
+// becomes "sales_2024_q1".
+func tableName(path string) string {
+	path = strings.TrimSuffix(strings.ToLower(path), ".gz")
+	path = strings.TrimSuffix(path, filepath.Ext(path))
+	var b strings.Builder
+	for _, r := range path {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+			b.WriteByte('_')
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "_")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "t_" + name
+	}
+	return name
+}
+
+// uniqueName returns name, or name with a numeric suffix if it is taken.
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	taken[unique] = true
+	return unique
+}
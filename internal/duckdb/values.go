@@ -0,0 +1,95 @@
+package duckdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// decodeRows reads the output of duckdb -json, an array of objects with
+// keys in column order, passing each row to fn with values in that order.
+// Queries without rows print nothing.
+func decodeRows(r io.Reader, fn func(columns []string, row []interface{}) error) error {
+	br := bufio.NewReader(r)
+	if _, err := br.Peek(1); err == io.EOF {
+		return nil
+	}
+	dec := json.NewDecoder(br)
+	dec.UseNumber()
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+
+	var columns []string
+	for dec.More() {
+		if err := expectDelim(dec, '{'); err != nil {
+			return err
+		}
+		// The first row names the columns; the others must match it
+		first := columns == nil
+		var row []interface{}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return err
+			}
+			if first {
+				columns = append(columns, key)
+			} else if len(row) >= len(columns) || columns[len(row)] != key {
+				return fmt.Errorf("duckdb returned rows with different columns")
+			}
+			row = append(row, number(v))
+		}
+		if err := expectDelim(dec, '}'); err != nil {
+			return err
+		}
+		if len(row) != len(columns) {
+			return fmt.Errorf("duckdb returned rows with different columns")
+		}
+		if err := fn(columns, row); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("failed to read duckdb output: %w", err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("failed to read duckdb output: unexpected %v", tok)
+	}
+	return nil
+}
+
+// number turns the numbers in v into int64 where exact and float64
+// otherwise, inside lists and structs as well.
+func number(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []interface{}:
+		for i := range v {
+			v[i] = number(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = number(v[k])
+		}
+	}
+	return v
+}
@@ -0,0 +1,363 @@
+// Package eval measures the intent classifier and the text-to-SQL agents
+// against a labeled dataset, so prompt, model and classifier changes can be
+// compared by numbers: intent accuracy, SQL exact match and execution
+// match.
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/sqlcompare"
+	"gopkg.in/yaml.v3"
+)
+
+// UserID identifies the turns of an evaluation.
+const UserID = "eval"
+
+// Dataset is a set of labeled examples.
+type Dataset struct {
+	// Intents are questions labeled with the intent they should route to
+	Intents []IntentCase `yaml:"intents"`
+	// SQL are questions labeled with the SQL, or the rows, that answer them
+	SQL []SQLCase `yaml:"sql"`
+}
+
+// IntentCase is a question and its expected intent.
+type IntentCase struct {
+	Query  string `yaml:"query"`
+	Intent string `yaml:"intent"`
+}
+
+// SQLCase is a question and the query that answers it. Rows, if given, are
+// the expected result instead of the result of SQL.
+type SQLCase struct {
+	Question string     `yaml:"question"`
+	SQL      string     `yaml:"sql"`
+	Rows     [][]string `yaml:"rows"`
+}
+
+// Load reads a dataset from a YAML file.
+func Load(path string) (*Dataset, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset: %w", err)
+	}
+	var ds Dataset
+	if err := yaml.Unmarshal(data, &ds); err != nil {
+		return nil, fmt.Errorf("failed to parse dataset: %w", err)
+	}
+	for i, c := range ds.Intents {
+		if strings.TrimSpace(c.Query) == "" || strings.TrimSpace(c.Intent) == "" {
+			return nil, fmt.Errorf("intent case %d needs a query and an intent", i+1)
+		}
+	}
+	for i, c := range ds.SQL {
+		if strings.TrimSpace(c.Question) == "" || (strings.TrimSpace(c.SQL) == "" && c.Rows == nil) {
+			return nil, fmt.Errorf("SQL case %d needs a question and the expected sql or rows", i+1)
+		}
+	}
+	if len(ds.Intents) == 0 && len(ds.SQL) == 0 {
+		return nil, fmt.Errorf("dataset %s has no intents or sql cases", path)
+	}
+	return &ds, nil
+}
+
+// Config holds configuration for an evaluation.
+type Config struct {
+	// App classifies and answers the questions
+	App *app.App
+	// DB runs the expected SQL of cases without expected rows
+	DB sqlagent.MCPClient
+}
+
+// IntentResult is the classification of one question.
+type IntentResult struct {
+	Query    string `json:"query"`
+	Expected string `json:"expected"`
+	Got      string `json:"got"`
+	Correct  bool   `json:"correct"`
+	// Classifier decided the intent; Keyword is the keyword classifier's
+	// intent when the LLM decided
+	Classifier string `json:"classifier,omitempty"`
+	Keyword    string `json:"keyword,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// IntentStats counts the questions of one expected intent.
+type IntentStats struct {
+	Total   int `json:"total"`
+	Correct int `json:"correct"`
+}
+
+// IntentReport summarizes the classification of every intent case.
+type IntentReport struct {
+	Total    int     `json:"total"`
+	Correct  int     `json:"correct"`
+	Accuracy float64 `json:"accuracy"`
+	// KeywordAccuracy is the accuracy of the keyword classifier alone,
+	// which differs from Accuracy when the LLM decided some intents
+	KeywordAccuracy float64                `json:"keyword_accuracy"`
+	ByIntent        map[string]IntentStats `json:"by_intent"`
+	Results         []IntentResult         `json:"results"`
+}
+
+// SQLResult is the agents' answer to one question.
+type SQLResult struct {
+	Question    string `json:"question"`
+	ExpectedSQL string `json:"expected_sql,omitempty"`
+	SQL         string `json:"sql,omitempty"`
+	// ExactMatch means the SQL equals the expected SQL after normalizing
+	// whitespace and case
+	ExactMatch bool `json:"exact_match"`
+	// ExecutionMatch means the SQL returned the expected rows, in any
+	// order and under any column names
+	ExecutionMatch bool   `json:"execution_match"`
+	DurationMS     int64  `json:"duration_ms"`
+	Error          string `json:"error,omitempty"`
+}
+
+// SQLReport summarizes the answers to every SQL case.
+type SQLReport struct {
+	Total          int         `json:"total"`
+	ExactMatch     float64     `json:"exact_match"`
+	ExecutionMatch float64     `json:"execution_match"`
+	Failed         int         `json:"failed"`
+	AvgMS          int64       `json:"avg_ms"`
+	Results        []SQLResult `json:"results"`
+}
+
+// Report is the outcome of an evaluation.
+type Report struct {
+	Intents *IntentReport `json:"intents,omitempty"`
+	SQL     *SQLReport    `json:"sql,omitempty"`
+}
+
+// Run evaluates the dataset. Each SQL case is asked in a fresh session,
+// bypassing the answer cache.
+func Run(ctx context.Context, cfg Config, ds *Dataset) (*Report, error) {
+	if cfg.App == nil {
+		return nil, fmt.Errorf("eval requires an app")
+	}
+	r := &Report{}
+	if len(ds.Intents) > 0 {
+		r.Intents = runIntents(ctx, cfg.App, ds.Intents)
+	}
+	if len(ds.SQL) > 0 {
+		if cfg.DB == nil && slices.ContainsFunc(ds.SQL, func(c SQLCase) bool { return c.Rows == nil }) {
+			return nil, fmt.Errorf("eval requires a database to run the expected SQL")
+		}
+		r.SQL = runSQL(ctx, cfg, ds.SQL)
+	}
+	return r, ctx.Err()
+}
+
+func runIntents(ctx context.Context, a *app.App, cases []IntentCase) *IntentReport {
+	r := &IntentReport{ByIntent: make(map[string]IntentStats)}
+	keywordCorrect := 0
+	for _, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+		res := IntentResult{Query: c.Query, Expected: c.Intent}
+		routing, err := a.Route(ctx, c.Query)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Got, res.Classifier = routing.ClassifiedIntent, routing.Classifier
+			res.Correct = strings.EqualFold(res.Got, c.Intent)
+		}
+		keyword := res.Got
+		if routing != nil && routing.Classifier == manager.ClassifierLLM {
+			for _, cl := range routing.Classifications {
+				if cl.Classifier != manager.ClassifierLLM {
+					keyword, res.Keyword = cl.Intent, cl.Intent
+				}
+			}
+		}
+		if strings.EqualFold(keyword, c.Intent) {
+			keywordCorrect++
+		}
+
+		stats := r.ByIntent[c.Intent]
+		stats.Total++
+		r.Total++
+		if res.Correct {
+			stats.Correct++
+			r.Correct++
+		}
+		r.ByIntent[c.Intent] = stats
+		r.Results = append(r.Results, res)
+	}
+	r.Accuracy = ratio(r.Correct, r.Total)
+	r.KeywordAccuracy = ratio(keywordCorrect, r.Total)
+	return r
+}
+
+func runSQL(ctx context.Context, cfg Config, cases []SQLCase) *SQLReport {
+	r := &SQLReport{}
+	run := time.Now().UTC().Format("20060102T150405")
+	exact, executed := 0, 0
+	var totalMS int64
+	for i, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+		console.Printf("⏳ [EVAL] %d/%d: %s\n", i+1, len(cases), c.Question)
+		start := time.Now()
+		sessionID := fmt.Sprintf("eval-%s-%d", run, i+1)
+		turn, err := cfg.App.Ask(app.WithFresh(ctx), UserID, sessionID, c.Question, nil)
+		res := judge(ctx, cfg.DB, c, turn, err)
+		res.DurationMS = time.Since(start).Milliseconds()
+
+		totalMS += res.DurationMS
+		if res.ExactMatch {
+			exact++
+		}
+		if res.ExecutionMatch {
+			executed++
+		}
+		if res.Error != "" && res.SQL == "" {
+			r.Failed++
+		}
+		r.Results = append(r.Results, res)
+	}
+	r.Total = len(r.Results)
+	r.ExactMatch = ratio(exact, r.Total)
+	r.ExecutionMatch = ratio(executed, r.Total)
+	if r.Total > 0 {
+		r.AvgMS = totalMS / int64(r.Total)
+	}
+	return r
+}
+
+// judge compares the last successful query of a turn with the expected SQL
+// or rows.
+func judge(ctx context.Context, db sqlagent.MCPClient, c SQLCase, turn *app.Turn, err error) SQLResult {
+	res := SQLResult{Question: c.Question, ExpectedSQL: c.SQL}
+	switch {
+	case err != nil:
+		res.Error = err.Error()
+		return res
+	case turn.Stopped != "":
+		res.Error = turn.Stopped
+	}
+
+	var data string
+	for _, q := range turn.Queries {
+		if q.Error == "" {
+			res.SQL, data = q.SQL, q.Data
+		}
+	}
+	if res.SQL == "" {
+		if res.Error == "" {
+			res.Error = "no successful query"
+		}
+		return res
+	}
+	res.ExactMatch = c.SQL != "" && sqlcompare.Normalize(res.SQL) == sqlcompare.Normalize(c.SQL)
+
+	var got, want []string
+	if c.Rows != nil {
+		if got, err = sqlcompare.RowSet(data); err != nil {
+			res.Error = err.Error()
+			return res
+		}
+		want = make([]string, len(c.Rows))
+		for i, row := range c.Rows {
+			want[i] = strings.Join(row, "\x1f")
+		}
+		slices.Sort(want)
+	} else {
+		// Both statements are run again with the same row limit, so long
+		// results are cut alike
+		if want, err = sqlcompare.Rows(sqlagent.WithSession(ctx, UserID, "expected"), db, c.SQL); err != nil {
+			res.Error = fmt.Sprintf("expected SQL failed: %v", err)
+			return res
+		}
+		if got, err = sqlcompare.Rows(sqlagent.WithSession(ctx, UserID, "candidate"), db, res.SQL); err != nil {
+			res.Error = err.Error()
+			return res
+		}
+	}
+	res.ExecutionMatch = res.ExactMatch || slices.Equal(got, want)
+	return res
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// WriteText writes the report as plain-text tables: the metrics, the
+// accuracy per intent and every miss.
+func WriteText(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if ir := r.Intents; ir != nil {
+		fmt.Fprintf(tw, "Intent accuracy: %.1f%% (%d/%d), keywords alone %.1f%%\n\n", ir.Accuracy*100, ir.Correct, ir.Total, ir.KeywordAccuracy*100)
+		fmt.Fprintln(tw, "INTENT\tCORRECT\tTOTAL\tACCURACY")
+		intents := make([]string, 0, len(ir.ByIntent))
+		for intent := range ir.ByIntent {
+			intents = append(intents, intent)
+		}
+		sort.Strings(intents)
+		for _, intent := range intents {
+			s := ir.ByIntent[intent]
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\n", intent, s.Correct, s.Total, ratio(s.Correct, s.Total)*100)
+		}
+		for _, res := range ir.Results {
+			if !res.Correct {
+				fmt.Fprintf(tw, "\nMISS  %q: expected %s, got %s", res.Query, res.Expected, orDash(res.Got))
+				if res.Error != "" {
+					fmt.Fprintf(tw, " (%s)", res.Error)
+				}
+			}
+		}
+		fmt.Fprint(tw, "\n\n")
+	}
+	if sr := r.SQL; sr != nil {
+		fmt.Fprintf(tw, "SQL: %d questions, exact match %.1f%%, execution match %.1f%%, %d failed, %d ms average\n\n",
+			sr.Total, sr.ExactMatch*100, sr.ExecutionMatch*100, sr.Failed, sr.AvgMS)
+		fmt.Fprintln(tw, "EXACT\tEXECUTION\tMS\tQUESTION")
+		for _, res := range sr.Results {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", yesNo(res.ExactMatch), yesNo(res.ExecutionMatch), res.DurationMS, res.Question)
+		}
+		for _, res := range sr.Results {
+			if res.ExecutionMatch {
+				continue
+			}
+			fmt.Fprintf(tw, "\n%s\n  expected: %s\n  got:      %s\n", res.Question, orDash(sqlcompare.OneLine(res.ExpectedSQL)), orDash(sqlcompare.OneLine(res.SQL)))
+			if res.Error != "" {
+				fmt.Fprintf(tw, "  error:    %s\n", res.Error)
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+func yesNo(ok bool) string {
+	if ok {
+		return "yes"
+	}
+	return "no"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+package eval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+const dataset = `
+intents:
+  - query: Select all records where status is active
+    intent: sql_query
+  - query: Plot a bar chart of sales
+    intent: visualization
+  - query: hello there
+    intent: sql_query
+sql:
+  - question: How many orders?
+    sql: select count(*) as n from orders;
+  - question: Count the orders
+    sql: SELECT count(id) FROM orders
+  - question: Who are the customers?
+    rows: [[Carol]]
+`
+
+// ordersDB returns the same count for any count query and names otherwise.
+type ordersDB struct {
+	sqlagent.MCPClient
+}
+
+func (ordersDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	if strings.Contains(strings.ToLower(query), "count") {
+		return `[{"count":4}]`, nil
+	}
+	return `[{"name":"Alice"},{"name":"Bob"}]`, nil
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eval.yaml")
+	if err := os.WriteFile(path, []byte(dataset), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "Done."}},
+		mockllm.Rule{User: "customers", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT name FROM customers"}}}}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT COUNT(*) AS n FROM orders"}}}}},
+	)
+	a, err := app.New(app.Config{Manager: testutil.NewManager(t, llm, ordersDB{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Run(context.Background(), Config{App: a, DB: ordersDB{}}, ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Intents.Total != 3 || r.Intents.Correct != 2 || r.Intents.ByIntent["sql_query"].Correct != 1 {
+		t.Errorf("intents = %+v, want 2 of 3 correct", r.Intents)
+	}
+
+	want := []struct{ exact, execution bool }{
+		{exact: true, execution: true},   // same SQL, formatted differently
+		{exact: false, execution: true},  // different SQL, same rows
+		{exact: false, execution: false}, // wrong rows
+	}
+	for i, w := range want {
+		got := r.SQL.Results[i]
+		if got.ExactMatch != w.exact || got.ExecutionMatch != w.execution {
+			t.Errorf("%s: exact = %v, execution = %v; want %v, %v (%s)", got.Question, got.ExactMatch, got.ExecutionMatch, w.exact, w.execution, got.Error)
+		}
+	}
+	if r.SQL.Total != 3 || r.SQL.Failed != 0 {
+		t.Errorf("sql = %+v", r.SQL)
+	}
+
+	var out strings.Builder
+	if err := WriteText(&out, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"Intent accuracy: 66.7% (2/3)", `MISS  "hello there": expected sql_query, got general`, "exact match 33.3%, execution match 66.7%"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("WriteText() is missing %q:\n%s", s, out.String())
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "empty", data: "intents: []", wantErr: "no intents or sql cases"},
+		{name: "no intent", data: "intents:\n  - query: hi", wantErr: "needs a query and an intent"},
+		{name: "no expectation", data: "sql:\n  - question: How many orders?", wantErr: "needs a question and the expected sql or rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "eval.yaml")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+// Package events publishes typed agent lifecycle events, such as a turn
+// starting or a query executing, to subscribers like the console printer,
+// the event log and metrics, so packages report what happened without
+// printing it themselves.
+package events
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Event kinds, as written to the event log.
+const (
+	KindTurnStarted      = "turn_started"
+	KindIntentClassified = "intent_classified"
+	KindPlanMade         = "plan_made"
+	KindStepStarted      = "step_started"
+	KindStepCompleted    = "step_completed"
+	KindAgentInvoked     = "agent_invoked"
+	KindToolCalled       = "tool_called"
+	KindToolCompleted    = "tool_completed"
+	KindSQLExecuted      = "sql_executed"
+	KindChartGenerated   = "chart_generated"
+	KindHistoryCompacted = "history_compacted"
+	KindRateLimited      = "rate_limited"
+	KindGuardrail        = "guardrail_triggered"
+	KindTurnCompleted    = "turn_completed"
+)
+
+// Event is one of the event types in this package.
+type Event interface {
+	// Kind names the event type.
+	Kind() string
+	header() *Header
+}
+
+// Header records when and for whom an event happened. Publish fills it in.
+type Header struct {
+	Time      time.Time `json:"time"`
+	UserID    string    `json:"user_id,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+}
+
+func (h *Header) header() *Header { return h }
+
+// TurnStarted is published when a question, or the user's decision on a
+// proposed write, starts a turn.
+type TurnStarted struct {
+	Header
+	Question string `json:"question"`
+	// Resumed is true for turns that resume a session, such as approvals
+	Resumed bool `json:"resumed,omitempty"`
+}
+
+// IntentClassified is published once the question's intent is known.
+type IntentClassified struct {
+	Header
+	Intent     string   `json:"intent"`
+	Confidence float64  `json:"confidence"`
+	Workflow   string   `json:"workflow"`
+	Agents     []string `json:"agents"`
+	// Classifier is "keyword" or "llm", whichever decided the intent
+	Classifier string `json:"classifier,omitempty"`
+	// KeywordIntent and KeywordConfidence are the keyword classifier's
+	// reading when the LLM was also asked, to evaluate the keywords against
+	KeywordIntent     string  `json:"keyword_intent,omitempty"`
+	KeywordConfidence float64 `json:"keyword_confidence,omitempty"`
+	// LLMIntent and LLMReasoning are the LLM's reading, or LLMError why
+	// there is none
+	LLMIntent    string `json:"llm_intent,omitempty"`
+	LLMReasoning string `json:"llm_reasoning,omitempty"`
+	LLMError     string `json:"llm_error,omitempty"`
+	// Steps are the tasks of a compound request, one per agent of Agents
+	Steps []string `json:"steps,omitempty"`
+}
+
+// PlanMade is published when a question is planned as steps to carry out
+// one at a time, and again when the steps after a failed one are planned
+// anew.
+type PlanMade struct {
+	Header
+	// Agents and Tasks describe each step
+	Agents []string `json:"agents"`
+	Tasks  []string `json:"tasks"`
+	// Replanned is true for a plan replacing the steps after a failed one;
+	// its steps are numbered from First
+	Replanned bool `json:"replanned,omitempty"`
+	First     int  `json:"first,omitempty"`
+}
+
+// StepStarted is published when a step of a plan starts.
+type StepStarted struct {
+	Header
+	// Step numbers the step from 1
+	Step  int    `json:"step"`
+	Steps int    `json:"steps"`
+	Agent string `json:"agent"`
+	Task  string `json:"task"`
+	// Attempt numbers the runs of the step from 1
+	Attempt int `json:"attempt"`
+}
+
+// StepCompleted is published when a step of a plan ends.
+type StepCompleted struct {
+	Header
+	Step       int    `json:"step"`
+	Agent      string `json:"agent"`
+	Attempt    int    `json:"attempt"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+	// Recovery is what follows a failed step: "retry", "replan", "skip"
+	// or "abort"
+	Recovery string `json:"recovery,omitempty"`
+}
+
+// AgentInvoked is published when an agent starts responding in a turn.
+type AgentInvoked struct {
+	Header
+	Agent string `json:"agent"`
+}
+
+// ToolCalled is published when an agent calls a tool.
+type ToolCalled struct {
+	Header
+	Agent string         `json:"agent"`
+	Tool  string         `json:"tool"`
+	Args  map[string]any `json:"args,omitempty"`
+}
+
+// ToolCompleted is published when a tool call returns.
+type ToolCompleted struct {
+	Header
+	Agent      string `json:"agent"`
+	Tool       string `json:"tool"`
+	DurationMS int64  `json:"duration_ms"`
+	// Error is the error the call failed with or reported in its result
+	Error string `json:"error,omitempty"`
+}
+
+// SQLExecuted is published after a query tool handles a statement,
+// including statements it refused to run.
+type SQLExecuted struct {
+	Header
+	SQL string `json:"sql"`
+	// Args are the values bound to the statement's placeholders, if any
+	Args       []interface{} `json:"args,omitempty"`
+	RowCount   int           `json:"row_count"`
+	DurationMS int64         `json:"duration_ms"`
+	Error      string        `json:"error,omitempty"`
+	// Rejected is true for data-modifying statements, which are not run
+	Rejected bool `json:"rejected,omitempty"`
+	// DryRun is true if the statement was generated but not run
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// ChartGenerated is published for every chart in a turn's answer.
+type ChartGenerated struct {
+	Header
+	// Mermaid is the chart definition
+	Mermaid string `json:"mermaid"`
+}
+
+// HistoryCompacted is published when older messages of a conversation are
+// replaced by a summary to fit a model's context window.
+type HistoryCompacted struct {
+	Header
+	Model string `json:"model"`
+	// Messages is the number of messages summarized
+	Messages int `json:"messages"`
+	// TokensBefore and TokensAfter estimate the size of the prompt
+	TokensBefore int `json:"tokens_before"`
+	TokensAfter  int `json:"tokens_after"`
+}
+
+// Resources reported by RateLimited.
+const (
+	ResourceLLM      = "llm"
+	ResourceDatabase = "database"
+)
+
+// RateLimited is published when a call waits for a client-side rate limit,
+// or is retried after the service refused it for exceeding its own.
+type RateLimited struct {
+	Header
+	// Resource is ResourceLLM or ResourceDatabase
+	Resource string `json:"resource"`
+	// Limit names the limit reached, e.g. "requests per minute", or is
+	// "server" when the service refused the call
+	Limit  string `json:"limit"`
+	WaitMS int64  `json:"wait_ms"`
+	// Attempt numbers the retries after a refusal
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// GuardrailTriggered is published when a content rule redacts or blocks an
+// answer or a tool call.
+type GuardrailTriggered struct {
+	Header
+	Rule string `json:"rule"`
+	// Target is "response" or "tool_args"
+	Target string `json:"target"`
+	Tool   string `json:"tool,omitempty"`
+	// Action is "block" or "redact"
+	Action string `json:"action"`
+	// Reason is the model's explanation for rules it judged
+	Reason string `json:"reason,omitempty"`
+	// Error is set if the output was blocked because it could not be
+	// checked
+	Error string `json:"error,omitempty"`
+}
+
+// TurnCompleted is published when a turn ends, successfully or not.
+type TurnCompleted struct {
+	Header
+	DurationMS       int64 `json:"duration_ms"`
+	PromptTokens     int   `json:"prompt_tokens"`
+	CompletionTokens int   `json:"completion_tokens"`
+	// Cached is true if the answer came from the answer cache
+	Cached bool `json:"cached,omitempty"`
+	// Stopped is why the turn was cut short by its budget, if it was
+	Stopped string `json:"stopped,omitempty"`
+	// Answer is the text of the agents' answer
+	Answer string `json:"answer,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Queries and FailedQueries count the turn's queries and those that
+	// failed
+	Queries       int `json:"queries,omitempty"`
+	FailedQueries int `json:"failed_queries,omitempty"`
+	// PromptVariant is the variant of the agents' prompts, set when
+	// variants are being compared
+	PromptVariant string `json:"prompt_variant,omitempty"`
+}
+
+func (*TurnStarted) Kind() string        { return KindTurnStarted }
+func (*IntentClassified) Kind() string   { return KindIntentClassified }
+func (*PlanMade) Kind() string           { return KindPlanMade }
+func (*StepStarted) Kind() string        { return KindStepStarted }
+func (*StepCompleted) Kind() string      { return KindStepCompleted }
+func (*AgentInvoked) Kind() string       { return KindAgentInvoked }
+func (*ToolCalled) Kind() string         { return KindToolCalled }
+func (*ToolCompleted) Kind() string      { return KindToolCompleted }
+func (*SQLExecuted) Kind() string        { return KindSQLExecuted }
+func (*ChartGenerated) Kind() string     { return KindChartGenerated }
+func (*HistoryCompacted) Kind() string   { return KindHistoryCompacted }
+func (*RateLimited) Kind() string        { return KindRateLimited }
+func (*GuardrailTriggered) Kind() string { return KindGuardrail }
+func (*TurnCompleted) Kind() string      { return KindTurnCompleted }
+
+// Handler receives published events. Handlers run synchronously on the
+// publishing goroutine, so they must be quick and safe for concurrent use.
+type Handler func(Event)
+
+// Bus delivers events to its subscribers. A nil *Bus discards events.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers []subscriber
+	next        int
+}
+
+type subscriber struct {
+	id      int
+	handler Handler
+}
+
+// New creates an empty Bus.
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers h for every subsequent event and returns a function
+// that unregisters it.
+func (b *Bus) Subscribe(h Handler) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.subscribers = append(b.subscribers, subscriber{id: id, handler: h})
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.subscribers = slices.DeleteFunc(slices.Clone(b.subscribers), func(s subscriber) bool { return s.id == id })
+	}
+}
+
+// Publish stamps e with the current time if unset and calls every
+// subscriber in the order they subscribed.
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if h := e.header(); h.Time.IsZero() {
+		h.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, s := range subscribers {
+		s.handler(e)
+	}
+}
+
+type contextKey struct{}
+
+type scope struct {
+	bus       *Bus
+	userID    string
+	sessionID string
+}
+
+// NewContext returns a context that publishes to bus on behalf of the user
+// and session, for code that only has the context, such as tools.
+func NewContext(ctx context.Context, bus *Bus, userID, sessionID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, scope{bus: bus, userID: userID, sessionID: sessionID})
+}
+
+// Publish publishes e to the bus of a context from NewContext, filling in
+// the user and session. It does nothing for other contexts.
+func Publish(ctx context.Context, e Event) {
+	s, ok := ctx.Value(contextKey{}).(scope)
+	if !ok {
+		return
+	}
+	h := e.header()
+	if h.UserID == "" && h.SessionID == "" {
+		h.UserID, h.SessionID = s.userID, s.sessionID
+	}
+	s.bus.Publish(e)
+}
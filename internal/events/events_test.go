@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBus(t *testing.T) {
+	bus := New()
+	var got []string
+	bus.Subscribe(func(e Event) { got = append(got, "first "+e.Kind()) })
+	unsubscribe := bus.Subscribe(func(e Event) { got = append(got, "second "+e.Kind()) })
+
+	ctx := NewContext(context.Background(), bus, "u", "s")
+	started := &TurnStarted{Question: "How many orders?"}
+	Publish(ctx, started)
+	unsubscribe()
+	Publish(ctx, &TurnCompleted{})
+	Publish(context.Background(), &TurnCompleted{})
+	(*Bus)(nil).Publish(&TurnCompleted{})
+
+	want := []string{"first turn_started", "second turn_started", "first turn_completed"}
+	if len(got) != len(want) {
+		t.Fatalf("delivered %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("delivered %v, want %v", got, want)
+			break
+		}
+	}
+	if started.UserID != "u" || started.SessionID != "s" || started.Time.IsZero() {
+		t.Errorf("header = %+v, want user, session and time filled in", started.Header)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  string
+	}{
+		{
+			name:  "fields",
+			event: &AgentInvoked{Agent: "SQLAgent"},
+			want:  `{"kind":"agent_invoked","time":"0001-01-01T00:00:00Z","agent":"SQLAgent"}`,
+		},
+		{
+			name:  "omitted fields",
+			event: &SQLExecuted{SQL: "SELECT 1", RowCount: 1, DurationMS: 3},
+			want:  `{"kind":"sql_executed","time":"0001-01-01T00:00:00Z","sql":"SELECT 1","row_count":1,"duration_ms":3}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Marshal(tt.event)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("Marshal() = %s, %v, want %s", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/secrets"
+)
+
+// Log is an append-only JSONL file of events, each with its kind.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenLog opens (or creates) the event log at path for appending.
+func OpenLog(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return &Log{file: f}, nil
+}
+
+// Handle appends e to the log, with secrets redacted. Subscribe it to a Bus.
+func (l *Log) Handle(e Event) {
+	if err := l.write(e); err != nil {
+		console.Printf("  ⚠️  [EVENTS] %v\n", err)
+	}
+}
+
+func (l *Log) write(e Event) error {
+	line, err := Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = []byte(secrets.Redact(string(line)))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	return nil
+}
+
+// Close flushes the log file to disk and closes it, after any event being
+// written.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.file.Sync(); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to flush event log: %w", err)
+	}
+	return l.file.Close()
+}
+
+// Marshal encodes e as a JSON object with its fields and a "kind" field.
+func Marshal(e Event) ([]byte, error) {
+	fields, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("json error: %w", err)
+	}
+	kind, _ := json.Marshal(e.Kind())
+
+	out := append([]byte(`{"kind":`), kind...)
+	if len(fields) > 2 {
+		out = append(out, ',')
+	}
+	return append(out, fields[1:]...), nil
+}
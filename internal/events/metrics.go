@@ -0,0 +1,197 @@
+package events
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// Metrics counts turns, intents, agents, tools, queries and charts since it
+// was created.
+type Metrics struct {
+	mu   sync.Mutex
+	snap MetricsSnapshot
+	// turnMS and queryMS total the durations behind the averages
+	turnMS  int64
+	queryMS int64
+	// toolMS and toolCalls total the durations of completed calls per tool
+	toolMS    map[string]int64
+	toolCalls map[string]int64
+	// variantMS totals the durations of turns per prompt variant
+	variantMS map[string]int64
+}
+
+// MetricsSnapshot is a copy of the counters of Metrics.
+type MetricsSnapshot struct {
+	Since            time.Time      `json:"since"`
+	Turns            int            `json:"turns"`
+	FailedTurns      int            `json:"failed_turns"`
+	CachedTurns      int            `json:"cached_turns"`
+	StoppedTurns     int            `json:"stopped_turns"`
+	AvgTurnMS        int64          `json:"avg_turn_ms"`
+	PromptTokens     int            `json:"prompt_tokens"`
+	CompletionTokens int            `json:"completion_tokens"`
+	Intents          map[string]int `json:"intents"`
+	Agents           map[string]int `json:"agents"`
+	Tools            map[string]int `json:"tools"`
+	// AvgToolMS is the average duration of each tool's completed calls
+	AvgToolMS     map[string]int64 `json:"avg_tool_ms"`
+	FailedTools   map[string]int   `json:"failed_tools"`
+	Queries       int              `json:"queries"`
+	FailedQueries int              `json:"failed_queries"`
+	AvgQueryMS    int64            `json:"avg_query_ms"`
+	Charts        int              `json:"charts"`
+	// Compactions counts conversations summarized to fit a context window
+	Compactions int `json:"compactions"`
+	// RateLimited counts LLM and database calls that waited for a rate
+	// limit or were retried after the service refused them
+	RateLimited map[string]int `json:"rate_limited"`
+	// Guardrails counts the answers and tool calls each content rule
+	// redacted or blocked
+	Guardrails map[string]int `json:"guardrails"`
+	// LLMClassifications counts questions whose intent the LLM was asked
+	// for, and ClassifierDisagreements those it classified differently
+	// from the keywords
+	LLMClassifications      int `json:"llm_classifications"`
+	ClassifierDisagreements int `json:"classifier_disagreements"`
+	// Variants counts the turns of each prompt variant, while variants
+	// are being compared
+	Variants map[string]VariantStats `json:"variants,omitempty"`
+}
+
+// VariantStats are the counters of the turns run with a prompt variant.
+type VariantStats struct {
+	Turns            int   `json:"turns"`
+	FailedTurns      int   `json:"failed_turns"`
+	StoppedTurns     int   `json:"stopped_turns"`
+	AvgTurnMS        int64 `json:"avg_turn_ms"`
+	PromptTokens     int   `json:"prompt_tokens"`
+	CompletionTokens int   `json:"completion_tokens"`
+	Queries          int   `json:"queries"`
+	FailedQueries    int   `json:"failed_queries"`
+}
+
+// NewMetrics creates Metrics with every counter at zero.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		snap: MetricsSnapshot{
+			Since:       time.Now().UTC(),
+			Intents:     make(map[string]int),
+			Agents:      make(map[string]int),
+			Tools:       make(map[string]int),
+			FailedTools: make(map[string]int),
+			RateLimited: make(map[string]int),
+			Guardrails:  make(map[string]int),
+			Variants:    make(map[string]VariantStats),
+		},
+		toolMS:    make(map[string]int64),
+		toolCalls: make(map[string]int64),
+		variantMS: make(map[string]int64),
+	}
+}
+
+// Handle counts e. Subscribe it to a Bus.
+func (m *Metrics) Handle(e Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := &m.snap
+	switch e := e.(type) {
+	case *IntentClassified:
+		s.Intents[e.Intent]++
+		if e.LLMIntent != "" {
+			s.LLMClassifications++
+			if e.LLMIntent != e.KeywordIntent {
+				s.ClassifierDisagreements++
+			}
+		}
+	case *AgentInvoked:
+		s.Agents[e.Agent]++
+	case *ToolCalled:
+		s.Tools[e.Tool]++
+	case *ToolCompleted:
+		m.toolCalls[e.Tool]++
+		m.toolMS[e.Tool] += e.DurationMS
+		if e.Error != "" {
+			s.FailedTools[e.Tool]++
+		}
+	case *SQLExecuted:
+		if e.Rejected || e.DryRun {
+			return
+		}
+		s.Queries++
+		m.queryMS += e.DurationMS
+		if e.Error != "" {
+			s.FailedQueries++
+		}
+	case *ChartGenerated:
+		s.Charts++
+	case *HistoryCompacted:
+		s.Compactions++
+	case *RateLimited:
+		s.RateLimited[e.Resource]++
+	case *GuardrailTriggered:
+		s.Guardrails[e.Rule]++
+	case *TurnCompleted:
+		s.Turns++
+		m.turnMS += e.DurationMS
+		s.PromptTokens += e.PromptTokens
+		s.CompletionTokens += e.CompletionTokens
+		if e.Error != "" {
+			s.FailedTurns++
+		}
+		if e.Cached {
+			s.CachedTurns++
+		}
+		if e.Stopped != "" {
+			s.StoppedTurns++
+		}
+		// Cached answers say nothing about the variant's prompts
+		if e.PromptVariant != "" && !e.Cached {
+			v := s.Variants[e.PromptVariant]
+			v.Turns++
+			m.variantMS[e.PromptVariant] += e.DurationMS
+			v.PromptTokens += e.PromptTokens
+			v.CompletionTokens += e.CompletionTokens
+			v.Queries += e.Queries
+			v.FailedQueries += e.FailedQueries
+			if e.Error != "" {
+				v.FailedTurns++
+			}
+			if e.Stopped != "" {
+				v.StoppedTurns++
+			}
+			s.Variants[e.PromptVariant] = v
+		}
+	}
+}
+
+// Snapshot returns the current counters.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snap := m.snap
+	snap.Intents = maps.Clone(m.snap.Intents)
+	snap.Agents = maps.Clone(m.snap.Agents)
+	snap.Tools = maps.Clone(m.snap.Tools)
+	snap.FailedTools = maps.Clone(m.snap.FailedTools)
+	snap.RateLimited = maps.Clone(m.snap.RateLimited)
+	snap.Guardrails = maps.Clone(m.snap.Guardrails)
+	snap.Variants = make(map[string]VariantStats, len(m.snap.Variants))
+	for variant, v := range m.snap.Variants {
+		v.AvgTurnMS = m.variantMS[variant] / int64(v.Turns)
+		snap.Variants[variant] = v
+	}
+	snap.AvgToolMS = make(map[string]int64, len(m.toolCalls))
+	for tool, n := range m.toolCalls {
+		snap.AvgToolMS[tool] = m.toolMS[tool] / n
+	}
+	if snap.Turns > 0 {
+		snap.AvgTurnMS = m.turnMS / int64(snap.Turns)
+	}
+	if snap.Queries > 0 {
+		snap.AvgQueryMS = m.queryMS / int64(snap.Queries)
+	}
+	return snap
+}
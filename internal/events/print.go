@@ -0,0 +1,95 @@
+package events
+
+import (
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+)
+
+// Print shows plans, tool calls, query outcomes, history compaction, rate
+// limit waits and guardrails on the console as they happen.
+// Subscribe it in every front-end; the REPL adds the intent of its own
+// questions.
+func Print(e Event) {
+	switch e := e.(type) {
+	case *PlanMade:
+		if e.Replanned {
+			console.Printf("  🗺️  [PLAN] Planned again from step %d:\n", e.First)
+		} else {
+			console.Printf("  🗺️  [PLAN] %d steps:\n", len(e.Tasks))
+		}
+		for i, task := range e.Tasks {
+			console.Printf("     %d. %s: %s\n", max(e.First, 1)+i, e.Agents[i], task)
+		}
+	case *StepStarted:
+		if e.Attempt > 1 {
+			console.Printf("  🔁 [PLAN] Step %d of %d: %s, attempt %d\n", e.Step, e.Steps, e.Agent, e.Attempt)
+		} else {
+			console.Printf("  ▶️  [PLAN] Step %d of %d: %s\n", e.Step, e.Steps, e.Agent)
+		}
+	case *StepCompleted:
+		if e.Error == "" {
+			console.Printf("  ✅ [PLAN] Step %d done in %d ms\n", e.Step, e.DurationMS)
+			break
+		}
+		console.Printf("  ❌ [PLAN] Step %d failed: %s\n", e.Step, e.Error)
+		switch e.Recovery {
+		case "skip":
+			console.Printf("  ⏭️  [PLAN] Skipping step %d\n", e.Step)
+		case "abort":
+			console.Printf("  ⛔ [PLAN] Stopping the plan at step %d\n", e.Step)
+		}
+	case *ToolCalled:
+		sql, _ := e.Args["sql"].(string)
+		switch e.Tool {
+		case "query_database", "query_database_params":
+			console.Printf("  📝 [SQL] Executing query: %s\n", sql)
+		case "propose_write":
+			console.Printf("  ✋ [SQL] Write proposed: %s\n", sql)
+		default:
+			console.Printf("  🔧 [AGENT] Calling tool: %s\n", e.Tool)
+		}
+	case *ToolCompleted:
+		// Query failures are reported with the query
+		if e.Error != "" && e.Tool != "query_database" && e.Tool != "query_database_params" {
+			console.Printf("  ❌ [AGENT] Tool %s failed: %s\n", e.Tool, e.Error)
+		}
+	case *HistoryCompacted:
+		console.Printf("  🗜️  [MEMORY] Summarized %d earlier messages (~%d → ~%d tokens)\n", e.Messages, e.TokensBefore, e.TokensAfter)
+	case *RateLimited:
+		tag := "LLM"
+		if e.Resource == ResourceDatabase {
+			tag = "SQL"
+		}
+		wait := (time.Duration(e.WaitMS) * time.Millisecond).Round(100 * time.Millisecond)
+		if e.Attempt > 0 {
+			console.Printf("  🚦 [%s] Rate limited by the server, retrying in %s (attempt %d)\n", tag, wait, e.Attempt)
+		} else {
+			console.Printf("  🚦 [%s] Rate limited (%s), retrying in %s\n", tag, e.Limit, wait)
+		}
+	case *GuardrailTriggered:
+		what := "answer"
+		if e.Tool != "" {
+			what = "call of " + e.Tool
+		}
+		switch {
+		case e.Error != "":
+			console.Printf("  🛡️  [GUARDRAIL] Blocked the %s, %s could not be checked: %s\n", what, e.Rule, e.Error)
+		case e.Action == "redact":
+			console.Printf("  🛡️  [GUARDRAIL] %s redacted the %s\n", e.Rule, what)
+		default:
+			console.Printf("  🛡️  [GUARDRAIL] %s blocked the %s\n", e.Rule, what)
+		}
+	case *SQLExecuted:
+		switch {
+		case e.Rejected:
+			console.Printf("  ❌ [SQL] Rejected data-modifying statement\n")
+		case e.DryRun:
+			console.Printf("  🧪 [SQL] Dry run, query not executed\n")
+		case e.Error != "":
+			console.Printf("  ❌ [SQL] Query error: %s\n", e.Error)
+		default:
+			console.Printf("  ✅ [SQL] Query completed successfully (%d rows in %d ms)\n", e.RowCount, e.DurationMS)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Package filters tracks the row filters a conversation has applied, so the
+// user can see what follow-up questions are scoped to.
+package filters
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Filter is a condition on a column taken from a query's WHERE clause.
+type Filter struct {
+	Column string `json:"column"`
+	Op     string `json:"op"`
+	Value  string `json:"value"`
+}
+
+// String describes the filter for the user, e.g. "region = EMEA" or
+// "order_date: last 90 days".
+func (f Filter) String() string {
+	if m := relativeDate.FindStringSubmatch(f.Value); m != nil && (f.Op == ">=" || f.Op == ">") {
+		return fmt.Sprintf("%s: last %s", f.Column, strings.TrimSpace(m[1]))
+	}
+	return fmt.Sprintf("%s %s %s", f.Column, f.Op, unquote.Replace(f.Value))
+}
+
+// Format joins filters for a status line.
+func Format(filters []Filter) string {
+	parts := make([]string, len(filters))
+	for i, f := range filters {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+var (
+	// clauseEnd ends a WHERE clause at the top level of the query.
+	clauseEnd = regexp.MustCompile(`(?i)^\s*(group\s+by|order\s+by|having|limit|offset|union|intersect|except|window|fetch|for)\b`)
+	// predicate matches "[table.]column op value".
+	predicate = regexp.MustCompile(`(?is)^(?:"?\w+"?\.)?"?(\w+)"?\s*(=|<>|!=|>=|<=|>|<|not\s+in|in|not\s+like|not\s+ilike|like|ilike|between|is\s+not|is)\s*(.+)$`)
+	// columnRef matches join conditions such as "c.id", which are not filters.
+	columnRef    = regexp.MustCompile(`^"?\w+"?\."?\w+"?$`)
+	relativeDate = regexp.MustCompile(`(?i)^(?:current_date|current_timestamp|now\(\))\s*-\s*interval\s*'([^']+)'$`)
+	whitespace   = regexp.MustCompile(`\s+`)
+	unquote      = strings.NewReplacer("'", "")
+)
+
+// Extract returns the column filters in the WHERE clause of query. Only
+// conditions joined by AND at the top level are understood; OR groups,
+// subqueries and join conditions are ignored.
+func Extract(query string) []Filter {
+	where := whereClause(query)
+	if where == "" {
+		return nil
+	}
+
+	var filters []Filter
+	for _, cond := range splitAnd(where) {
+		m := predicate.FindStringSubmatch(strings.TrimSpace(cond))
+		if m == nil {
+			continue
+		}
+		value := whitespace.ReplaceAllString(strings.TrimSpace(m[3]), " ")
+		if columnRef.MatchString(value) || strings.Contains(strings.ToLower(value), "select ") {
+			continue
+		}
+		op := strings.ToUpper(whitespace.ReplaceAllString(m[2], " "))
+		filters = append(filters, Filter{Column: strings.ToLower(m[1]), Op: op, Value: value})
+	}
+	return filters
+}
+
+// Merge adds filters to scope. A filter replaces one on the same column
+// with the same operator, so a follow-up that switches region from EMEA to
+// AMER leaves one region filter.
+func Merge(scope, filters []Filter) []Filter {
+	out := append([]Filter(nil), scope...)
+	for _, f := range filters {
+		replaced := false
+		for i := range out {
+			if out[i].Column == f.Column && out[i].Op == f.Op {
+				out[i] = f
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// whereClause returns the top-level WHERE clause of query, or "".
+func whereClause(query string) string {
+	depth, start := 0, -1
+	inString := false
+	lower := strings.ToLower(query)
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inString = !inString
+		case inString:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && start < 0 && isWord(lower, i, "where"):
+			start = i + len("where")
+		case depth == 0 && start >= 0 && (i == 0 || !isIdent(query[i-1])) && clauseEnd.MatchString(query[i:]):
+			return query[start:i]
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	return strings.TrimRight(query[start:], "; \n\t")
+}
+
+// splitAnd splits a condition on the ANDs outside parentheses and strings.
+// The AND of BETWEEN ... AND ... is kept with its condition.
+func splitAnd(cond string) []string {
+	var parts []string
+	lower := strings.ToLower(cond)
+	depth, last := 0, 0
+	inString, between := false, false
+	for i := 0; i < len(cond); i++ {
+		c := cond[i]
+		switch {
+		case c == '\'':
+			inString = !inString
+		case inString:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && isWord(lower, i, "or"):
+			// Alternatives cannot be shown as a single scope
+			return nil
+		case depth == 0 && isWord(lower, i, "between"):
+			between = true
+		case depth == 0 && isWord(lower, i, "and"):
+			if between {
+				between = false
+				continue
+			}
+			parts = append(parts, cond[last:i])
+			last = i + len("and")
+		}
+	}
+	return append(parts, cond[last:])
+}
+
+// isWord reports whether the keyword word starts at s[i] as a whole word.
+func isWord(s string, i int, word string) bool {
+	if !strings.HasPrefix(s[i:], word) {
+		return false
+	}
+	if i > 0 && isIdent(s[i-1]) {
+		return false
+	}
+	end := i + len(word)
+	return end == len(s) || !isIdent(s[end])
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c == '.' || c == '"' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
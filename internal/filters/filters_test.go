@@ -0,0 +1,67 @@
+package filters
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "no where clause",
+			query: "SELECT count(*) FROM orders",
+			want:  "",
+		},
+		{
+			name:  "equality and relative date",
+			query: "SELECT SUM(amount) FROM orders o JOIN customers c ON c.id = o.customer_id WHERE c.region = 'EMEA' AND o.order_date >= CURRENT_DATE - INTERVAL '90 days' GROUP BY 1 ORDER BY 1 LIMIT 10",
+			want:  "region = EMEA, order_date: last 90 days",
+		},
+		{
+			name:  "between and in",
+			query: "select * from orders where order_date between '2024-01-01' and '2024-03-31' and status in ('paid', 'shipped');",
+			want:  "order_date BETWEEN 2024-01-01 and 2024-03-31, status IN (paid, shipped)",
+		},
+		{
+			name:  "join condition and subquery are not filters",
+			query: "SELECT * FROM orders o, customers c WHERE o.customer_id = c.id AND o.product_id IN (SELECT id FROM products) AND o.quantity > 2",
+			want:  "quantity > 2",
+		},
+		{
+			name:  "or is not a scope",
+			query: "SELECT * FROM orders WHERE region = 'EMEA' OR region = 'APAC'",
+			want:  "",
+		},
+		{
+			name:  "where inside a subquery",
+			query: "SELECT * FROM (SELECT * FROM orders WHERE region = 'EMEA') t",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(Extract(tt.query)); got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	scope := Merge(nil, []Filter{{Column: "region", Op: "=", Value: "'EMEA'"}, {Column: "year", Op: ">=", Value: "2023"}})
+	scope = Merge(scope, []Filter{{Column: "region", Op: "=", Value: "'AMER'"}, {Column: "year", Op: "<", Value: "2025"}})
+
+	want := []Filter{
+		{Column: "region", Op: "=", Value: "'AMER'"},
+		{Column: "year", Op: ">=", Value: "2023"},
+		{Column: "year", Op: "<", Value: "2025"},
+	}
+	if !reflect.DeepEqual(scope, want) {
+		t.Errorf("Merge() = %+v, want %+v", scope, want)
+	}
+}
@@ -0,0 +1,428 @@
+// Package frame reshapes query results in memory: sorting, top-N,
+// group-by, pivots and percentages of a total. Steps are written as a small
+// pipeline language, e.g. "group region: sum(revenue) | sort sum_revenue
+// desc | top 5", so simple reshaping needs no second query.
+package frame
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Frame is a query result: named columns and rows of values in column
+// order. Values are as decoded from JSON, with numbers as json.Number.
+type Frame struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// Parse reads a query result encoded as a JSON array of row objects. The
+// columns are the keys of the first row, in order, followed by keys first
+// seen in later rows.
+func Parse(data string) (*Frame, error) {
+	var raws []json.RawMessage
+	if err := json.Unmarshal([]byte(data), &raws); err != nil {
+		return nil, fmt.Errorf("failed to decode rows: %w", err)
+	}
+	return Read(func(fn func(raw json.RawMessage) error) error {
+		for _, raw := range raws {
+			if err := fn(raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Read reads a query result from the JSON row objects each passes to its
+// function in order, as Parse does.
+func Read(each func(fn func(raw json.RawMessage) error) error) (*Frame, error) {
+	f := &Frame{}
+	index := make(map[string]int)
+	var records []map[string]interface{}
+	err := each(func(raw json.RawMessage) error {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return fmt.Errorf("failed to decode rows: row is not a JSON object")
+		}
+		rec := make(map[string]interface{})
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return fmt.Errorf("failed to decode rows: %w", err)
+			}
+			key, _ := tok.(string)
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return fmt.Errorf("failed to decode rows: %w", err)
+			}
+			if _, ok := index[key]; !ok {
+				index[key] = len(f.Columns)
+				f.Columns = append(f.Columns, key)
+			}
+			rec[key] = v
+		}
+		records = append(records, rec)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rec := range records {
+		row := make([]interface{}, len(f.Columns))
+		for k, v := range rec {
+			row[index[k]] = v
+		}
+		f.Rows = append(f.Rows, row)
+	}
+	return f, nil
+}
+
+// JSON encodes the frame as a JSON array of row objects with keys in
+// column order.
+func (f *Frame) JSON() (string, error) {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, row := range f.Rows {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('{')
+		for j, col := range f.Columns {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			k, _ := json.Marshal(col)
+			v, err := json.Marshal(row[j])
+			if err != nil {
+				return "", fmt.Errorf("json error: %w", err)
+			}
+			b.Write(k)
+			b.WriteByte(':')
+			b.Write(v)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(']')
+	return b.String(), nil
+}
+
+// column returns the index of a column, matching its name exactly or else
+// ignoring case.
+func (f *Frame) column(name string) (int, error) {
+	for i, c := range f.Columns {
+		if c == name {
+			return i, nil
+		}
+	}
+	for i, c := range f.Columns {
+		if strings.EqualFold(c, name) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("unknown column %q; columns are %s", name, strings.Join(f.Columns, ", "))
+}
+
+// SortKey orders rows by a column.
+type SortKey struct {
+	Column string
+	Desc   bool
+}
+
+// Sort orders the rows by keys, numbers numerically and other values as
+// text. NULLs come last either way; ties keep their order.
+func (f *Frame) Sort(keys ...SortKey) (*Frame, error) {
+	idx := make([]int, len(keys))
+	for i, k := range keys {
+		c, err := f.column(k.Column)
+		if err != nil {
+			return nil, err
+		}
+		idx[i] = c
+	}
+	rows := append([][]interface{}(nil), f.Rows...)
+	sort.SliceStable(rows, func(a, b int) bool {
+		for i, k := range keys {
+			va, vb := rows[a][idx[i]], rows[b][idx[i]]
+			if va == nil || vb == nil {
+				if (va == nil) != (vb == nil) {
+					return vb == nil
+				}
+				continue
+			}
+			c := compare(va, vb)
+			if c == 0 {
+				continue
+			}
+			if k.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return &Frame{Columns: f.Columns, Rows: rows}, nil
+}
+
+// Top keeps the first n rows.
+func (f *Frame) Top(n int) *Frame {
+	if n < 0 {
+		n = 0
+	}
+	return &Frame{Columns: f.Columns, Rows: f.Rows[:min(n, len(f.Rows))]}
+}
+
+// Agg is an aggregate of a column: sum, avg, min, max or count. Count
+// counts rows if Column is "*" or empty, and non-NULL values otherwise.
+type Agg struct {
+	Func   string
+	Column string
+	// As names the result column (default: func_column, or count)
+	As string
+}
+
+// name is the result column of the aggregate.
+func (a Agg) name() string {
+	if a.As != "" {
+		return a.As
+	}
+	if a.Column == "" || a.Column == "*" {
+		return a.Func
+	}
+	return a.Func + "_" + a.Column
+}
+
+// GroupBy returns a row per distinct combination of the keys, in order of
+// first appearance, with the keys followed by the aggregates.
+func (f *Frame) GroupBy(keys []string, aggs []Agg) (*Frame, error) {
+	keyIdx, err := f.columns(keys)
+	if err != nil {
+		return nil, err
+	}
+	groups, order := f.group(keyIdx)
+
+	out := &Frame{}
+	for _, k := range keyIdx {
+		out.Columns = append(out.Columns, f.Columns[k])
+	}
+	for _, a := range aggs {
+		out.Columns = append(out.Columns, a.name())
+	}
+	for _, g := range order {
+		rows := groups[g]
+		row := make([]interface{}, 0, len(out.Columns))
+		for _, k := range keyIdx {
+			row = append(row, rows[0][k])
+		}
+		for _, a := range aggs {
+			v, err := f.aggregate(a, rows)
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, v)
+		}
+		out.Rows = append(out.Rows, row)
+	}
+	return out, nil
+}
+
+// Pivot returns a row per distinct value of index and a column per
+// distinct value of columns, holding agg over the rows with both values.
+// Combinations without rows are NULL.
+func (f *Frame) Pivot(index, columns string, agg Agg) (*Frame, error) {
+	idx, err := f.columns([]string{index, columns})
+	if err != nil {
+		return nil, err
+	}
+	rowGroups, rowOrder := f.group(idx[:1])
+	_, colOrder := f.group(idx[1:])
+	cells, _ := f.group(idx)
+
+	out := &Frame{Columns: []string{f.Columns[idx[0]]}}
+	for _, c := range colOrder {
+		if c == "" {
+			c = "null"
+		}
+		out.Columns = append(out.Columns, c)
+	}
+	for _, r := range rowOrder {
+		row := []interface{}{rowGroups[r][0][idx[0]]}
+		for _, c := range colOrder {
+			rows, ok := cells[groupKey([]interface{}{rowGroups[r][0][idx[0]], c})]
+			if !ok {
+				row = append(row, nil)
+				continue
+			}
+			v, err := f.aggregate(agg, rows)
+			if err != nil {
+				return nil, err
+			}
+			row = append(row, v)
+		}
+		out.Rows = append(out.Rows, row)
+	}
+	return out, nil
+}
+
+// Percent adds a column, named after column with a _pct suffix, holding
+// each row's share of the column's total in percent, rounded to two
+// decimals.
+func (f *Frame) Percent(column string) (*Frame, error) {
+	c, err := f.column(column)
+	if err != nil {
+		return nil, err
+	}
+	total := 0.0
+	for _, row := range f.Rows {
+		if v, ok := number(row[c]); ok {
+			total += v
+		}
+	}
+
+	out := &Frame{Columns: append(append([]string(nil), f.Columns...), f.Columns[c]+"_pct")}
+	for _, row := range f.Rows {
+		var pct interface{}
+		if v, ok := number(row[c]); ok && total != 0 {
+			pct = math.Round(v/total*10000) / 100
+		}
+		out.Rows = append(out.Rows, append(append([]interface{}(nil), row...), pct))
+	}
+	return out, nil
+}
+
+func (f *Frame) columns(names []string) ([]int, error) {
+	idx := make([]int, len(names))
+	for i, n := range names {
+		c, err := f.column(n)
+		if err != nil {
+			return nil, err
+		}
+		idx[i] = c
+	}
+	return idx, nil
+}
+
+// group splits the rows by the values of the key columns and returns the
+// groups with their keys in order of first appearance.
+func (f *Frame) group(keys []int) (map[string][][]interface{}, []string) {
+	groups := make(map[string][][]interface{})
+	var order []string
+	for _, row := range f.Rows {
+		values := make([]interface{}, len(keys))
+		for i, k := range keys {
+			values[i] = row[k]
+		}
+		g := groupKey(values)
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], row)
+	}
+	return groups, order
+}
+
+// groupKey is the text of the values; a single value is used as is, so
+// pivot columns are named after the values.
+func groupKey(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		if v != nil {
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func (f *Frame) aggregate(a Agg, rows [][]interface{}) (interface{}, error) {
+	if a.Func == "count" && (a.Column == "" || a.Column == "*") {
+		return len(rows), nil
+	}
+	c, err := f.column(a.Column)
+	if err != nil {
+		return nil, err
+	}
+
+	n, sum := 0, 0.0
+	var best interface{}
+	for _, row := range rows {
+		v := row[c]
+		if v == nil {
+			continue
+		}
+		n++
+		switch a.Func {
+		case "sum", "avg":
+			x, ok := number(v)
+			if !ok {
+				return nil, fmt.Errorf("%s(%s): %v is not a number", a.Func, a.Column, v)
+			}
+			sum += x
+		case "min":
+			if best == nil || compare(v, best) < 0 {
+				best = v
+			}
+		case "max":
+			if best == nil || compare(v, best) > 0 {
+				best = v
+			}
+		}
+	}
+
+	switch a.Func {
+	case "count":
+		return n, nil
+	case "sum", "avg":
+		// Like SQL, the sum of no values is NULL
+		if n == 0 {
+			return nil, nil
+		}
+		if a.Func == "avg" {
+			return sum / float64(n), nil
+		}
+		return sum, nil
+	case "min", "max":
+		return best, nil
+	}
+	return nil, fmt.Errorf("unknown aggregate %q; use sum, avg, min, max or count", a.Func)
+}
+
+// compare orders two non-NULL values: numerically if both are numbers,
+// as text otherwise.
+func compare(a, b interface{}) int {
+	x, okA := number(a)
+	y, okB := number(b)
+	if okA && okB {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// number converts a numeric value; NUMERIC columns arrive as strings.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package frame
+
+import "testing"
+
+const sales = `[
+	{"region":"north","month":"2026-01","revenue":100,"note":null},
+	{"region":"south","month":"2026-01","revenue":"50.5"},
+	{"region":"north","month":"2026-02","revenue":300},
+	{"region":"east","month":"2026-02","revenue":null}
+]`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		pipeline string
+		want     string
+		wantErr  bool
+	}{
+		{
+			pipeline: "sort revenue desc",
+			want:     `[{"region":"north","month":"2026-02","revenue":300,"note":null},{"region":"north","month":"2026-01","revenue":100,"note":null},{"region":"south","month":"2026-01","revenue":"50.5","note":null},{"region":"east","month":"2026-02","revenue":null,"note":null}]`,
+		},
+		{
+			pipeline: "sort region, month DESC | top 2",
+			want:     `[{"region":"east","month":"2026-02","revenue":null,"note":null},{"region":"north","month":"2026-02","revenue":300,"note":null}]`,
+		},
+		{
+			pipeline: "top 1 by Revenue",
+			want:     `[{"region":"north","month":"2026-02","revenue":300,"note":null}]`,
+		},
+		{
+			pipeline: "group region: sum(revenue), count(*), count(revenue) as priced, max(month)",
+			want:     `[{"region":"north","sum_revenue":400,"count":2,"priced":2,"max_month":"2026-02"},{"region":"south","sum_revenue":50.5,"count":1,"priced":1,"max_month":"2026-01"},{"region":"east","sum_revenue":null,"count":1,"priced":0,"max_month":"2026-02"}]`,
+		},
+		{
+			pipeline: "group region: avg(revenue) | pct avg_revenue | sort avg_revenue desc",
+			want:     `[{"region":"north","avg_revenue":200,"avg_revenue_pct":79.84},{"region":"south","avg_revenue":50.5,"avg_revenue_pct":20.16},{"region":"east","avg_revenue":null,"avg_revenue_pct":null}]`,
+		},
+		{
+			pipeline: "pivot month by region: sum(revenue)",
+			want:     `[{"month":"2026-01","north":100,"south":50.5,"east":null},{"month":"2026-02","north":300,"south":null,"east":null}]`,
+		},
+		{pipeline: "sort nope", wantErr: true},
+		{pipeline: "group region", wantErr: true},
+		{pipeline: "group region: median(revenue)", wantErr: true},
+		{pipeline: "group month: sum(region)", wantErr: true},
+		{pipeline: "pivot month: sum(revenue)", wantErr: true},
+		{pipeline: "filter x", wantErr: true},
+		{pipeline: " ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pipeline, func(t *testing.T) {
+			f, err := Parse(sales)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Run(f, tt.pipeline)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			data, err := got.JSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data != tt.want {
+				t.Errorf("got  %s\nwant %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransform(t *testing.T) {
+	got := Transform(Result{SQL: "SELECT * FROM sales", Data: sales}, "sort revenue", 2)
+	if got.Error != "" {
+		t.Fatal(got.Error)
+	}
+	if got.SQL != "SELECT * FROM sales\n-- | sort revenue" || got.RowCount != 4 || got.Notice == "" {
+		t.Errorf("got %+v, want 2 of 4 rows of the annotated query", got)
+	}
+	if want := `[{"region":"south","month":"2026-01","revenue":"50.5","note":null},{"region":"north","month":"2026-01","revenue":100,"note":null}]`; got.Data != want {
+		t.Errorf("data = %s, want %s", got.Data, want)
+	}
+}
@@ -0,0 +1,144 @@
+package frame
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Syntax describes the pipeline language for the agents and /reshape.
+const Syntax = `Steps separated by "|", applied in order:
+- sort <col> [desc], <col> [desc], ...
+- top <n> [by <col>]   (by sorts descending on col first)
+- group <col>, <col>, ...: <agg>, <agg>, ...   (agg: sum(col), avg(col), min(col), max(col), count(*) or count(col), optionally "as name"; results are named e.g. sum_revenue)
+- pivot <row col> by <column col>: <agg>
+- pct <col>   (adds <col>_pct, the share of the column total in percent)
+Example: group region: sum(revenue) | pct sum_revenue | sort sum_revenue desc | top 5`
+
+var (
+	topRe = regexp.MustCompile(`(?i)^top\s+(\d+)(?:\s+by\s+(.+))?$`)
+	aggRe = regexp.MustCompile(`(?i)^(sum|avg|min|max|count)\s*\(\s*([^)]*?)\s*\)(?:\s+as\s+(\S+))?$`)
+)
+
+// Run applies a pipeline of steps to f, e.g. "sort revenue desc | top 5".
+func Run(f *Frame, pipeline string) (*Frame, error) {
+	if strings.TrimSpace(pipeline) == "" {
+		return nil, fmt.Errorf("empty pipeline")
+	}
+	for _, step := range strings.Split(pipeline, "|") {
+		var err error
+		if f, err = apply(f, strings.TrimSpace(step)); err != nil {
+			return nil, fmt.Errorf("%q: %w", strings.TrimSpace(step), err)
+		}
+	}
+	return f, nil
+}
+
+// apply runs one step of a pipeline.
+func apply(f *Frame, step string) (*Frame, error) {
+	verb, rest, _ := strings.Cut(step, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(verb) {
+	case "sort":
+		var keys []SortKey
+		for _, k := range splitList(rest) {
+			fields := strings.Fields(k)
+			key := SortKey{Column: k}
+			if n := len(fields); n > 1 && (strings.EqualFold(fields[n-1], "desc") || strings.EqualFold(fields[n-1], "asc")) {
+				key = SortKey{Column: strings.Join(fields[:n-1], " "), Desc: strings.EqualFold(fields[n-1], "desc")}
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("sort needs a column")
+		}
+		return f.Sort(keys...)
+
+	case "top":
+		m := topRe.FindStringSubmatch(step)
+		if m == nil {
+			return nil, fmt.Errorf("expected top <n> [by <col>]")
+		}
+		n, _ := strconv.Atoi(m[1])
+		if m[2] != "" {
+			sorted, err := f.Sort(SortKey{Column: strings.TrimSpace(m[2]), Desc: true})
+			if err != nil {
+				return nil, err
+			}
+			f = sorted
+		}
+		return f.Top(n), nil
+
+	case "group":
+		keys, aggList, ok := strings.Cut(rest, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected group <cols>: <aggregates>")
+		}
+		aggs, err := parseAggs(aggList)
+		if err != nil {
+			return nil, err
+		}
+		return f.GroupBy(splitList(keys), aggs)
+
+	case "pivot":
+		spec, aggText, ok := strings.Cut(rest, ":")
+		index, columns, okBy := cutWord(spec, "by")
+		if !ok || !okBy {
+			return nil, fmt.Errorf("expected pivot <row col> by <column col>: <aggregate>")
+		}
+		aggs, err := parseAggs(aggText)
+		if err != nil {
+			return nil, err
+		}
+		if len(aggs) != 1 {
+			return nil, fmt.Errorf("pivot takes one aggregate")
+		}
+		return f.Pivot(index, columns, aggs[0])
+
+	case "pct":
+		if rest == "" {
+			return nil, fmt.Errorf("pct needs a column")
+		}
+		return f.Percent(rest)
+	}
+	return nil, fmt.Errorf("unknown step %q; use sort, top, group, pivot or pct", verb)
+}
+
+func parseAggs(s string) ([]Agg, error) {
+	var aggs []Agg
+	for _, a := range splitList(s) {
+		m := aggRe.FindStringSubmatch(a)
+		if m == nil {
+			return nil, fmt.Errorf("invalid aggregate %q; use e.g. sum(revenue) or count(*)", a)
+		}
+		aggs = append(aggs, Agg{Func: strings.ToLower(m[1]), Column: m[2], As: m[3]})
+	}
+	if len(aggs) == 0 {
+		return nil, fmt.Errorf("no aggregates")
+	}
+	return aggs, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// cutWord splits s around the first standalone, case-insensitive word.
+func cutWord(s, word string) (before, after string, found bool) {
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if strings.EqualFold(f, word) && i > 0 && i < len(fields)-1 {
+			return strings.Join(fields[:i], " "), strings.Join(fields[i+1:], " "), true
+		}
+	}
+	return s, "", false
+}
@@ -0,0 +1,171 @@
+package frame
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/toolmw"
+	"github.com/anuvratrastogi/multi-agent/internal/toolschema"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// ToolName is the tool reshaping the latest query result.
+const ToolName = "transform_result"
+
+// maxRows bounds the rows of a transformed result returned to the model.
+const maxRows = 200
+
+// queryTools are the tools whose results can be transformed.
+var queryTools = map[string]bool{"query_database": true, "query_database_params": true}
+
+// Result is a query result kept for transform_result.
+type Result struct {
+	SQL  string
+	Data string
+	// Spill holds the rows of a result that spilled to disk, of which Data
+	// holds the leading ones only
+	Spill *sqlagent.Spill
+}
+
+// Frame returns the rows of the result, read back from disk if it spilled.
+func (r Result) Frame() (*Frame, error) {
+	if r.Spill != nil {
+		return Read(r.Spill.Each)
+	}
+	return Parse(r.Data)
+}
+
+// Close releases the rows of a result returned by Last.
+func (r Result) Close() error {
+	if r.Spill != nil {
+		return r.Spill.Close()
+	}
+	return nil
+}
+
+// Store remembers the latest query result of each session. It is safe for
+// concurrent use.
+type Store struct {
+	mu   sync.Mutex
+	last map[string]Result
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{last: make(map[string]Result)}
+}
+
+// Middleware records the complete result of every successful query. Place
+// it after the preview middleware, so it sees the rows before they are cut.
+func (s *Store) Middleware() toolmw.Middleware {
+	return func(t tool.Tool, next toolmw.Handler) toolmw.Handler {
+		if !queryTools[t.Name()] {
+			return next
+		}
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			ctx, capture := sqlagent.CaptureSpill(ctx)
+			defer capture.Close()
+			res, err := next(ctx, args)
+			if data, _ := res["data"].(string); err == nil && data != "" {
+				sql, _ := args["sql"].(string)
+				r := Result{SQL: sql, Data: data}
+				if spill := capture.Spill(); spill != nil {
+					r.Spill = spill.Retain()
+				}
+				s.Put(ctx.UserID()+"/"+ctx.SessionID(), r)
+			}
+			return res, err
+		}
+	}
+}
+
+// Put records the latest result of a session, taking over its Spill.
+func (s *Store) Put(session string, r Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last[session].Close()
+	s.last[session] = r
+}
+
+// Last returns the latest result of a session. The caller must Close it.
+func (s *Store) Last(session string) (Result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.last[session]
+	if r.Spill != nil {
+		r.Spill.Retain()
+	}
+	return r, ok
+}
+
+// TransformArgs are the arguments of transform_result.
+type TransformArgs struct {
+	Pipeline string `json:"pipeline" description:"The steps to apply, separated by |, e.g. 'group region: sum(revenue) | sort sum_revenue desc | top 5'"`
+}
+
+// TransformResult is a transformed query result.
+type TransformResult struct {
+	// SQL is the query whose result was transformed, followed by the
+	// pipeline as a comment
+	SQL      string `json:"sql,omitempty"`
+	Data     string `json:"data,omitempty"`
+	RowCount int    `json:"row_count,omitempty"`
+	Notice   string `json:"notice,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Tools returns the transform_result tool.
+func (s *Store) Tools() ([]tool.Tool, error) {
+	schema, err := toolschema.For[TransformArgs]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s schema: %w", ToolName, err)
+	}
+	transformTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        ToolName,
+			Description: "Sort, pick the top rows of, group, pivot or add percentages of total to the latest query result in memory, without another query. " + Syntax,
+			InputSchema: schema,
+		},
+		func(ctx tool.Context, args TransformArgs) (TransformResult, error) {
+			r, ok := s.Last(ctx.UserID() + "/" + ctx.SessionID())
+			if !ok {
+				return TransformResult{Error: "no query result to transform yet; run a query first"}, nil
+			}
+			defer r.Close()
+			return Transform(r, args.Pipeline, maxRows), nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", ToolName, err)
+	}
+	return []tool.Tool{transformTool}, nil
+}
+
+// Transform applies a pipeline to a result, returning at most limit rows
+// (0 = all).
+func Transform(r Result, pipeline string, limit int) TransformResult {
+	f, err := r.Frame()
+	if err != nil {
+		return TransformResult{Error: err.Error()}
+	}
+	f, err = Run(f, pipeline)
+	if err != nil {
+		return TransformResult{Error: err.Error()}
+	}
+
+	res := TransformResult{
+		SQL:      strings.TrimSpace(r.SQL) + "\n-- | " + strings.TrimSpace(pipeline),
+		RowCount: len(f.Rows),
+	}
+	if limit > 0 && len(f.Rows) > limit {
+		f = f.Top(limit)
+		res.Notice = fmt.Sprintf("Showing the first %d of %d rows; add a top step to keep fewer.", limit, res.RowCount)
+	}
+	if res.Data, err = f.JSON(); err != nil {
+		return TransformResult{Error: err.Error()}
+	}
+	return res
+}
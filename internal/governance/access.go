@@ -0,0 +1,246 @@
+package governance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"gopkg.in/yaml.v3"
+)
+
+// AnyUser is the users key whose roles apply to users not listed by name.
+const AnyUser = "*"
+
+// DefaultSchema is the schema of tables named without one.
+const DefaultSchema = "public"
+
+// systemSchemas may always be read: they describe the database, not its data.
+var systemSchemas = []string{"information_schema", "pg_catalog"}
+
+// AccessPolicy maps users to roles, and roles to the schemas and tables they
+// may query. Tables are written as schema.table, schema.* or "*"; a table
+// without a schema is in DefaultSchema.
+//
+// Example YAML:
+//
+//	roles:
+//	  analyst: [orders, customers, "sales.*"]
+//	  finance: ["finance.*"]
+//	users:
+//	  alice: [analyst, finance]
+//	  "*": [analyst]
+type AccessPolicy struct {
+	Roles map[string][]string `yaml:"roles"`
+	Users map[string][]string `yaml:"users"`
+
+	// searchPath are the schemas the database looks up tables named
+	// without a schema in
+	searchPath []string
+}
+
+// SetSearchPath sets the schemas the database looks up tables named without
+// a schema in, as read by SearchPath. A query may only name a table without
+// a schema if every schema of the path permits it, as the table it reads may
+// be in any of them. The default is DefaultSchema. Call it before the policy
+// is shared.
+func (p *AccessPolicy) SetSearchPath(schemas []string) {
+	p.searchPath = nil
+	for _, schema := range schemas {
+		p.searchPath = append(p.searchPath, strings.ToLower(schema))
+	}
+}
+
+// SearchPath returns the schemas the connections of db look up tables named
+// without a schema in.
+func SearchPath(ctx context.Context, db sqlagent.MCPClient) ([]string, error) {
+	data, err := db.Query(ctx, "SELECT unnest(current_schemas(false)) AS schema", 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the search path: %w", err)
+	}
+	var rows []struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.Unmarshal([]byte(data), &rows); err != nil {
+		return nil, fmt.Errorf("failed to read the search path: %w", err)
+	}
+	var schemas []string
+	for _, row := range rows {
+		schemas = append(schemas, row.Schema)
+	}
+	return schemas, nil
+}
+
+// resolve returns the schema.table names table may refer to: itself if it
+// has a schema, or else the table in each schema of the search path.
+func (p *AccessPolicy) resolve(table string) []string {
+	table = strings.ToLower(strings.TrimSpace(table))
+	if strings.Contains(table, ".") {
+		return []string{table}
+	}
+	path := p.searchPath
+	if len(path) == 0 {
+		path = []string{DefaultSchema}
+	}
+	names := make([]string, len(path))
+	for i, schema := range path {
+		names[i] = schema + "." + table
+	}
+	return names
+}
+
+// LoadAccessPolicy reads an access policy from a YAML file.
+func LoadAccessPolicy(path string) (*AccessPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access policy: %w", err)
+	}
+
+	var policy AccessPolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse access policy: %w", err)
+	}
+
+	roles := make(map[string][]string, len(policy.Roles))
+	for role, tables := range policy.Roles {
+		normalized := make([]string, len(tables))
+		for i, table := range tables {
+			normalized[i] = qualify(table)
+		}
+		roles[role] = normalized
+	}
+	for user, names := range policy.Users {
+		for _, role := range names {
+			if _, ok := roles[role]; !ok {
+				return nil, fmt.Errorf("user %s has unknown role %q", user, role)
+			}
+		}
+	}
+	policy.Roles = roles
+	return &policy, nil
+}
+
+// Allowed returns the tables userID may query, as schema.table or schema.*
+// patterns, in the order they are granted.
+func (p *AccessPolicy) Allowed(userID string) []string {
+	roles, ok := p.Users[userID]
+	if !ok {
+		roles = p.Users[AnyUser]
+	}
+	var allowed []string
+	for _, role := range roles {
+		for _, table := range p.Roles[role] {
+			if !slices.Contains(allowed, table) {
+				allowed = append(allowed, table)
+			}
+		}
+	}
+	return allowed
+}
+
+// Scope identifies the tables userID may query, so that users granted the
+// same tables have the same scope. Answers computed for one user may be
+// reused for another of the same scope.
+func (p *AccessPolicy) Scope(userID string) string {
+	return strings.Join(slices.Sorted(slices.Values(p.Allowed(userID))), ",")
+}
+
+// Denied returns the tables query references that userID may not query,
+// the functions it calls that run SQL passed as text, which cannot be
+// checked, as name(), and search_path if it names the setting, which
+// changes the tables names without a schema refer to.
+func (p *AccessPolicy) Denied(userID, query string) []string {
+	allowed := p.Allowed(userID)
+	var denied []string
+	for _, fn := range TextQueryFunctions(query) {
+		denied = append(denied, fn+"()")
+	}
+	if slices.ContainsFunc(tokenize(query), func(t sqlToken) bool { return t.is("search_path") }) {
+		denied = append(denied, "search_path")
+	}
+	for _, table := range queryTables(query) {
+		for _, name := range p.resolve(table) {
+			if !permits(allowed, name) && !slices.Contains(denied, name) {
+				denied = append(denied, name)
+			}
+		}
+	}
+	return denied
+}
+
+// Permits reports whether userID may query table, which may be
+// schema-qualified.
+func (p *AccessPolicy) Permits(userID, table string) bool {
+	allowed := p.Allowed(userID)
+	for _, name := range p.resolve(table) {
+		if !permits(allowed, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// permits reports whether the schema.table name is matched by allowed.
+func permits(allowed []string, table string) bool {
+	schema, _, _ := strings.Cut(table, ".")
+	if slices.Contains(systemSchemas, schema) {
+		return true
+	}
+	for _, pattern := range allowed {
+		if pattern == "*" || pattern == table || pattern == schema+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// qualify lowercases a table name and adds DefaultSchema if it has none.
+func qualify(table string) string {
+	table = strings.ToLower(strings.TrimSpace(table))
+	if table == "*" || strings.Contains(table, ".") {
+		return table
+	}
+	return DefaultSchema + "." + table
+}
+
+// denialMessage tells the agent which tables it may use instead.
+func denialMessage(userID string, denied, allowed []string) string {
+	if len(allowed) == 0 {
+		return fmt.Sprintf("access denied: user %s may not query any tables (%s). Tell the user they lack access instead of retrying.", userID, strings.Join(denied, ", "))
+	}
+	return fmt.Sprintf("access denied: user %s may not query %s. Reformulate the query using only these tables: %s. If the question cannot be answered from them, tell the user they lack access.",
+		userID, strings.Join(denied, ", "), strings.Join(allowed, ", "))
+}
+
+// AccessClient wraps an MCPClient and rejects queries that reference tables
+// the session's user may not read, before they are executed.
+type AccessClient struct {
+	sqlagent.MCPClient
+	policy *AccessPolicy
+}
+
+// NewAccessClient creates a client that enforces policy on queries to inner.
+func NewAccessClient(inner sqlagent.MCPClient, policy *AccessPolicy) *AccessClient {
+	return &AccessClient{MCPClient: inner, policy: policy}
+}
+
+// Query executes the query if the user may read every table it references.
+func (c *AccessClient) Query(ctx context.Context, query string, limit int) (string, error) {
+	userID, _, _ := sqlagent.SessionFrom(ctx)
+	if denied := c.policy.Denied(userID, query); len(denied) > 0 {
+		return "", fmt.Errorf("%s", denialMessage(userID, denied, c.policy.Allowed(userID)))
+	}
+	return c.MCPClient.Query(ctx, query, limit)
+}
+
+// GetSchema describes the table if the user may read it.
+func (c *AccessClient) GetSchema(ctx context.Context, tableName string) (string, error) {
+	userID, _, _ := sqlagent.SessionFrom(ctx)
+	if !c.policy.Permits(userID, tableName) {
+		return "", fmt.Errorf("%s", denialMessage(userID, c.policy.resolve(tableName), c.policy.Allowed(userID)))
+	}
+	return c.MCPClient.GetSchema(ctx, tableName)
+}
@@ -0,0 +1,186 @@
+package governance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/semcache"
+)
+
+func TestQualifiedTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "single table", query: "SELECT * FROM orders", want: []string{"public.orders"}},
+		{name: "schema and quotes", query: `SELECT * FROM Sales."Targets" t JOIN orders o ON o.id = t.order_id`, want: []string{"sales.targets", "public.orders"}},
+		{name: "comma list", query: "SELECT * FROM orders o, finance.salaries s WHERE o.id = s.id", want: []string{"public.orders", "finance.salaries"}},
+		{name: "subquery then list", query: "SELECT * FROM (SELECT id FROM orders) o, secrets s", want: []string{"public.orders", "public.secrets"}},
+		{name: "parenthesized join", query: "SELECT * FROM (orders JOIN secrets USING (id))", want: []string{"public.orders", "public.secrets"}},
+		{name: "cte", query: "WITH recent AS (SELECT * FROM orders) SELECT * FROM recent", want: []string{"public.orders"}},
+		{name: "cte in another statement", query: "WITH secrets AS (SELECT 1) SELECT 1; SELECT * FROM secrets", want: []string{"public.secrets"}},
+		{name: "cte body reads the table it shadows", query: "WITH secrets AS (SELECT * FROM secrets) SELECT * FROM secrets", want: []string{"public.secrets"}},
+		{name: "cte of a nested with", query: "WITH a AS (WITH secrets AS (SELECT 1) SELECT 1) SELECT * FROM secrets", want: []string{"public.secrets"}},
+		{name: "cte before its definition", query: "WITH a AS (SELECT * FROM b), b AS (SELECT 1) SELECT * FROM a", want: []string{"public.b"}},
+		{name: "cte after its definition", query: "WITH a AS (SELECT * FROM orders), b AS (SELECT * FROM a) SELECT * FROM b", want: []string{"public.orders"}},
+		{name: "recursive cte", query: "WITH RECURSIVE t (n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM t) SELECT * FROM t", want: nil},
+		{name: "column alias is not a cte", query: "SELECT 1, secrets AS x FROM secrets", want: []string{"public.secrets"}},
+		{name: "functions", query: "SELECT EXTRACT(YEAR FROM created_at), x IS DISTINCT FROM y FROM generate_series(1, 3) g, orders", want: []string{"public.orders"}},
+		{name: "zero-column subquery", query: "SELECT count(*) FROM (SELECT FROM secrets) s", want: []string{"public.secrets"}},
+		{name: "comments and strings", query: "SELECT 'FROM secrets' /* FROM secrets */ FROM orders -- JOIN secrets", want: []string{"public.orders"}},
+		{name: "writes", query: "INSERT INTO audit.log (id) SELECT id FROM orders ON CONFLICT (id) DO UPDATE SET id = 1", want: []string{"audit.log", "public.orders"}},
+		{name: "lock clause", query: "SELECT * FROM orders FOR UPDATE OF orders", want: []string{"public.orders"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QualifiedTables(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("QualifiedTables(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.yaml")
+	policy := `
+roles:
+  analyst: [orders, Customers, "sales.*"]
+  finance: ["finance.salaries"]
+users:
+  alice: [analyst, finance]
+  "*": [analyst]
+`
+	if err := os.WriteFile(path, []byte(policy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadAccessPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadAccessPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		user  string
+		query string
+		want  []string
+	}{
+		{name: "allowed tables", user: "bob", query: "SELECT * FROM orders JOIN customers c ON true", want: nil},
+		{name: "allowed schema", user: "bob", query: "SELECT * FROM sales.targets", want: nil},
+		{name: "denied table", user: "bob", query: "SELECT * FROM orders, finance.salaries", want: []string{"finance.salaries"}},
+		{name: "role grants table", user: "alice", query: "SELECT * FROM finance.salaries", want: nil},
+		{name: "system catalogs", user: "bob", query: "SELECT * FROM information_schema.columns", want: nil},
+		{name: "same name, other schema", user: "bob", query: "SELECT * FROM archive.orders", want: []string{"archive.orders"}},
+		{name: "sql in a string", user: "bob", query: "SELECT query_to_xml('SELECT * FROM finance.salaries', true, false, '') FROM orders", want: []string{"query_to_xml()"}},
+		{name: "qualified function", user: "alice", query: "SELECT * FROM pg_catalog.cursor_to_xml('c', 10, true, false, '')", want: []string{"cursor_to_xml()"}},
+		{name: "dblink", user: "bob", query: "SELECT * FROM dblink('dbname=hr', 'SELECT name FROM salaries') AS t(name text)", want: []string{"dblink()"}},
+		{name: "table named by a string", user: "bob", query: "SELECT Table_To_XML('finance.salaries', true, false, '')", want: []string{"table_to_xml()"}},
+		{name: "function name in a string", user: "bob", query: "SELECT 'query_to_xml(' FROM orders", want: nil},
+		{name: "cte shadowing a denied table", user: "bob", query: "WITH salaries AS (SELECT * FROM salaries) SELECT * FROM salaries", want: []string{"public.salaries"}},
+		{name: "search path changed", user: "bob", query: "SELECT set_config('search_path', 'finance', false); SET search_path TO finance", want: []string{"set_config()", "search_path"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Denied(tt.user, tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Denied(%s, %q) = %v, want %v", tt.user, tt.query, got, tt.want)
+			}
+		})
+	}
+
+	if !p.Permits(AnyUser, "Customers") || !p.Permits(AnyUser, "sales.targets") || p.Permits(AnyUser, "finance.salaries") {
+		t.Error("Permits() disagrees with the roles of users not listed")
+	}
+
+	// Cached answers are only shared by users granted the same tables
+	if p.Scope("bob") != p.Scope("carol") || p.Scope("bob") == p.Scope("alice") {
+		t.Errorf("Scope() = %q for bob, %q for carol and %q for alice", p.Scope("bob"), p.Scope("carol"), p.Scope("alice"))
+	}
+	ctx := context.Background()
+	cache, err := semcache.Open(semcache.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Store(ctx, p.Scope("alice"), "What is the total salary?", &app.Turn{Text: "1,200,000."}); err != nil {
+		t.Fatal(err)
+	}
+	if turn, _ := cache.Lookup(ctx, p.Scope("bob"), "What is the total salary?"); turn != nil {
+		t.Errorf("Lookup() for bob = %+v, want alice's answer withheld", turn)
+	}
+	if turn, _ := cache.Lookup(ctx, p.Scope("alice"), "what is the total salary"); turn == nil {
+		t.Error("Lookup() for alice missed her own answer")
+	}
+
+	if err := os.WriteFile(path, []byte("roles: {}\nusers:\n  bob: [admin]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAccessPolicy(path); err == nil {
+		t.Error("LoadAccessPolicy() accepted an unknown role")
+	}
+}
+
+// countingClient counts the queries that reach the database.
+type countingClient struct {
+	sqlagent.MCPClient
+	queries int
+}
+
+func (c *countingClient) Query(ctx context.Context, query string, limit int) (string, error) {
+	c.queries++
+	return "[]", nil
+}
+
+func TestAccessClient(t *testing.T) {
+	inner := &countingClient{}
+	policy := &AccessPolicy{
+		Roles: map[string][]string{"analyst": {"public.orders"}},
+		Users: map[string][]string{"bob": {"analyst"}},
+	}
+	c := NewAccessClient(inner, policy)
+
+	ctx := sqlagent.WithSession(context.Background(), "bob", "s1")
+	if _, err := c.Query(ctx, "SELECT * FROM orders", 0); err != nil {
+		t.Fatalf("Query(orders) error = %v", err)
+	}
+	_, err := c.Query(ctx, "SELECT * FROM salaries", 0)
+	if err == nil || !strings.Contains(err.Error(), "public.salaries") || !strings.Contains(err.Error(), "Reformulate the query using only these tables: public.orders") {
+		t.Errorf("Query(salaries) error = %v, want a denial naming the allowed tables", err)
+	}
+	if _, err := c.Query(sqlagent.WithSession(context.Background(), "carol", "s1"), "SELECT * FROM orders", 0); err == nil {
+		t.Error("Query() by a user without roles succeeded")
+	}
+	if inner.queries != 1 {
+		t.Errorf("%d queries reached the database, want 1", inner.queries)
+	}
+}
+
+func TestAccessSearchPath(t *testing.T) {
+	policy := &AccessPolicy{
+		Roles: map[string][]string{"analyst": {"public.orders", "sales.*"}},
+		Users: map[string][]string{"bob": {"analyst"}},
+	}
+	policy.SetSearchPath([]string{"Sales", "public"})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "SELECT * FROM targets", want: []string{"public.targets"}},
+		// orders may be sales.orders, which is granted, or public.orders
+		{query: "SELECT * FROM orders", want: nil},
+		{query: "SELECT * FROM public.targets", want: []string{"public.targets"}},
+	}
+	for _, tt := range tests {
+		if got := policy.Denied("bob", tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Denied(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if policy.Permits("bob", "targets") {
+		t.Error("Permits(targets) = true, but public.targets is not granted")
+	}
+}
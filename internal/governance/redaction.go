@@ -0,0 +1,476 @@
+// Package governance applies data-governance policies to query results before
+// they are returned to the agents (and therefore the LLM).
+package governance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"gopkg.in/yaml.v3"
+)
+
+// Action is what happens to a governed column.
+type Action string
+
+const (
+	// ActionMask replaces the column value with MaskValue.
+	ActionMask Action = "mask"
+	// ActionDrop removes the column from the result entirely.
+	ActionDrop Action = "drop"
+)
+
+// MaskValue is substituted for masked column values.
+const MaskValue = "[REDACTED]"
+
+// AnyTable is the table key whose rules apply to every query.
+const AnyTable = "*"
+
+// RedactionPolicy maps table names to column rules.
+//
+// Example YAML:
+//
+//	tables:
+//	  customers:
+//	    email: mask
+//	    phone: drop
+//	  "*":
+//	    ssn: drop
+type RedactionPolicy struct {
+	Tables map[string]map[string]Action `yaml:"tables"`
+}
+
+// LoadRedactionPolicy reads a redaction policy from a YAML file.
+func LoadRedactionPolicy(path string) (*RedactionPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction policy: %w", err)
+	}
+
+	var policy RedactionPolicy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction policy: %w", err)
+	}
+
+	normalized := make(map[string]map[string]Action, len(policy.Tables))
+	for table, columns := range policy.Tables {
+		rules := make(map[string]Action, len(columns))
+		for column, action := range columns {
+			action = Action(strings.ToLower(string(action)))
+			if action != ActionMask && action != ActionDrop {
+				return nil, fmt.Errorf("invalid action %q for %s.%s", action, table, column)
+			}
+			rules[strings.ToLower(column)] = action
+		}
+		normalized[strings.ToLower(table)] = rules
+	}
+	policy.Tables = normalized
+
+	return &policy, nil
+}
+
+// ReferencedTables extracts the (unqualified, lowercased) table names that a
+// query reads from.
+func ReferencedTables(query string) []string {
+	var tables []string
+	for _, table := range queryTables(query) {
+		parts := strings.Split(table, ".")
+		if name := parts[len(parts)-1]; !slices.Contains(tables, name) {
+			tables = append(tables, name)
+		}
+	}
+	return tables
+}
+
+// rulesFor merges the column rules of every table referenced by query. When
+// two tables disagree, dropping wins over masking.
+func (p *RedactionPolicy) rulesFor(query string) map[string]Action {
+	rules := make(map[string]Action)
+	merge := func(table string) {
+		for column, action := range p.Tables[table] {
+			if rules[column] != ActionDrop {
+				rules[column] = action
+			}
+		}
+	}
+
+	merge(AnyTable)
+	for _, table := range ReferencedTables(query) {
+		merge(table)
+	}
+	return rules
+}
+
+// Redacts reports whether a rule masks or drops column of table, which may
+// be schema-qualified.
+func (p *RedactionPolicy) Redacts(table, column string) bool {
+	parts := strings.Split(strings.ToLower(table), ".")
+	column = strings.ToLower(column)
+	_, global := p.Tables[AnyTable][column]
+	_, own := p.Tables[parts[len(parts)-1]][column]
+	return global || own
+}
+
+// Unredactable returns the governed columns, and the tables used as whole
+// rows, that query could return under a name other than their own: renamed
+// with an alias, inside an expression such as concat(email, ”), as a row
+// such as row_to_json(c), or through UNION or a column alias list. Rules
+// match result columns by name, so such queries cannot be redacted.
+func (p *RedactionPolicy) Unredactable(query string) []string {
+	rules := p.rulesFor(query)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	tokens := tokenize(query)
+	call := func(i int) bool { return i+1 < len(tokens) && tokens[i+1].text == "(" && !tokens[i+1].quoted }
+	dot := func(i int) bool { return i >= 0 && i < len(tokens) && tokens[i].text == "." && !tokens[i].quoted }
+
+	// Tables and their aliases, which name the table's row in an expression;
+	// the alias of a function's result names only what its arguments do
+	var relations []string
+	walkClauses(tokens, func(i int, frames []clauseFrame) {
+		top := frames[len(frames)-1]
+		if top.clause != "from" || !top.own || !tokens[i].ident() || call(i) {
+			return
+		}
+		k := i - 1
+		if k >= 0 && tokens[k].is("as") {
+			k--
+		}
+		if k >= 0 && tokens[k].text == ")" && !tokens[k].quoted {
+			if o := opening(tokens, k); o > 0 && tokens[o-1].ident() && !slices.ContainsFunc(sourceAfter, tokens[o-1].is) {
+				return
+			}
+		}
+		relations = append(relations, strings.ToLower(tokens[i].text))
+	})
+
+	var hidden, selected []string
+	add := func(names *[]string, name string) {
+		if !slices.Contains(*names, name) {
+			*names = append(*names, name)
+		}
+	}
+	renamed := false
+	walkClauses(tokens, func(i int, frames []clauseFrame) {
+		t := tokens[i]
+		top := frames[len(frames)-1]
+		switch {
+		case t.is("union") || t.is("intersect") || t.is("except"):
+			renamed = true
+		case t.text == "(" && !t.quoted && aliasList(tokens, i):
+			renamed = true
+		case t.text == "*" && !t.quoted && i > 1 && dot(i-1) && top.clause == "select" && !top.own:
+			add(&hidden, strings.ToLower(tokens[i-2].text)+".*")
+		}
+		if !t.ident() || call(i) || i > 0 && tokens[i-1].is("as") {
+			return
+		}
+		name := strings.ToLower(t.text)
+		switch {
+		case rules[name] != "":
+			if top.clause == "select" {
+				add(&selected, name)
+			}
+			if !plainlyOutput(tokens, i, frames) {
+				add(&hidden, name)
+			}
+		case !dot(i-1) && !dot(i+1) && slices.Contains(relations, name) && (top.clause == "select" || top.clause == "from" && !top.own):
+			add(&hidden, name)
+		}
+	})
+	if renamed {
+		for _, name := range selected {
+			add(&hidden, name)
+		}
+	}
+	return hidden
+}
+
+// clauseFrame is a parenthesis of a query, or the query itself, and the
+// clause its tokens are in.
+type clauseFrame struct {
+	clause string
+	// own marks a clause begun inside the parenthesis rather than around it
+	own bool
+	// call is the word before the parenthesis, such as a function name
+	call string
+	// source marks a subquery whose rows the enclosing query reads as a
+	// table: a derived table or common table expression
+	source bool
+}
+
+// predicateClauses filter, join, group or order rows; the columns they name
+// are not output.
+var predicateClauses = []string{"where", "having", "window", "on", "using", "group", "order", "limit", "offset", "fetch"}
+
+// selectListEnd are the keywords that end a select list.
+var selectListEnd = []string{"from", "into", "where", "group", "having", "window", "order", "limit", "offset", "union", "intersect", "except", "fetch", "for"}
+
+// sourceAfter are the tokens a derived table or common table expression
+// follows.
+var sourceAfter = []string{"from", "join", "lateral", "as", "materialized", ",", "("}
+
+// walkClauses calls fn with each token of a statement and the frames
+// enclosing it, outermost first.
+func walkClauses(tokens []sqlToken, fn func(i int, frames []clauseFrame)) {
+	frames := []clauseFrame{{}}
+	for i, t := range tokens {
+		top := &frames[len(frames)-1]
+		switch {
+		case t.quoted:
+		case t.text == "(":
+			fn(i, frames)
+			f := clauseFrame{clause: top.clause}
+			if i > 0 && tokens[i-1].ident() {
+				f.call = strings.ToLower(tokens[i-1].text)
+			}
+			if i > 1 && tokens[i-1].is("on") && tokens[i-2].is("distinct") {
+				f.clause = "on"
+			}
+			if i+1 < len(tokens) && (tokens[i+1].is("select") || tokens[i+1].is("with") || tokens[i+1].is("values")) {
+				f.source = i == 0 || slices.ContainsFunc(sourceAfter, tokens[i-1].is)
+			}
+			frames = append(frames, f)
+			continue
+		case t.text == ")":
+			if len(frames) > 1 {
+				frames = frames[:len(frames)-1]
+			}
+		case t.text == ";":
+			frames = []clauseFrame{{}}
+		default:
+			if clause := clauseAt(tokens, i); clause != "" {
+				top.clause, top.own = clause, true
+			}
+		}
+		fn(i, frames)
+	}
+}
+
+// clauseAt returns the clause that tokens[i] begins, if any.
+func clauseAt(tokens []sqlToken, i int) string {
+	t := tokens[i]
+	after := func(keyword string) bool { return i > 0 && tokens[i-1].is(keyword) }
+	switch {
+	case t.is("from"):
+		// IS DISTINCT FROM compares values
+		if after("distinct") {
+			return ""
+		}
+		return "from"
+	case t.is("join"):
+		return "from"
+	case t.is("on"):
+		// DISTINCT ON (expressions) is part of the select list
+		if after("distinct") {
+			return ""
+		}
+		return "on"
+	case t.is("group") || t.is("order"):
+		// WITHIN GROUP (ORDER BY ...) is part of an aggregate
+		if i+1 >= len(tokens) || !tokens[i+1].is("by") || after("within") {
+			return ""
+		}
+		return strings.ToLower(t.text)
+	}
+	for _, keyword := range []string{"with", "select", "values", "returning", "where", "having", "window", "using", "limit", "offset", "fetch", "union", "intersect", "except"} {
+		if t.is(keyword) {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// plainlyOutput reports whether the governed column at tokens[i] reaches
+// the result, if at all, under its own name: because it is only filtered
+// or counted on, or selected plainly by every query between it and the
+// result.
+func plainlyOutput(tokens []sqlToken, i int, frames []clauseFrame) bool {
+	for level := len(frames) - 1; level >= 0; level-- {
+		f := frames[level]
+		inner := level == len(frames)-1
+		switch {
+		case slices.Contains(predicateClauses, f.clause) || f.call == "count":
+			return true
+		case !inner && !frames[level+1].source:
+			return false
+		case f.clause == "select" && inner && f.own:
+			if !plainItem(tokens, i) {
+				return false
+			}
+		case (f.clause == "from" || f.clause == "with" || f.clause == "") && !inner:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// plainItem reports whether the select list item of the column at
+// tokens[i] is that column, possibly qualified, alone or aliased to its
+// own name.
+func plainItem(tokens []sqlToken, i int) bool {
+	start := i
+	for start > 1 && tokens[start-1].text == "." && !tokens[start-1].quoted && tokens[start-2].ident() {
+		start -= 2
+	}
+	if start == 0 {
+		return false
+	}
+	before := tokens[start-1]
+	if !(before.text == "," && !before.quoted || before.is("select") || before.is("distinct") || before.is("all") ||
+		before.text == ")" && !before.quoted && distinctOn(tokens, start-1)) {
+		return false
+	}
+
+	end := func(j int) bool {
+		return j == len(tokens) || !tokens[j].quoted && slices.Contains([]string{",", ")", ";"}, tokens[j].text) ||
+			slices.ContainsFunc(selectListEnd, tokens[j].is)
+	}
+	j := i + 1
+	if end(j) {
+		return true
+	}
+	if tokens[j].is("as") {
+		j++
+	}
+	return j < len(tokens) && tokens[j].ident() && strings.EqualFold(tokens[j].text, tokens[i].text) && end(j+1)
+}
+
+// distinctOn reports whether the ")" at tokens[i] closes DISTINCT ON (...).
+func distinctOn(tokens []sqlToken, i int) bool {
+	j := opening(tokens, i)
+	return j > 1 && tokens[j-1].is("on") && tokens[j-2].is("distinct")
+}
+
+// aliasList reports whether the "(" at tokens[i] opens a list of column
+// aliases, which rename the columns of a derived table, as in
+// (SELECT ...) AS t (a, b), or of a common table expression, as in
+// WITH t (a, b) AS (...).
+func aliasList(tokens []sqlToken, i int) bool {
+	if i == 0 || !tokens[i-1].ident() {
+		return false
+	}
+	if c := closing(tokens, i+1); c+2 < len(tokens) && tokens[c+1].is("as") &&
+		(tokens[c+2].text == "(" && !tokens[c+2].quoted || tokens[c+2].is("not") || tokens[c+2].is("materialized")) {
+		return true
+	}
+	k := i - 2
+	if k >= 0 && tokens[k].is("as") {
+		k--
+	}
+	if k < 0 || tokens[k].text != ")" || tokens[k].quoted {
+		return false
+	}
+	j := opening(tokens, k)
+	return j >= 0 && (tokens[j+1].is("select") || tokens[j+1].is("with") || tokens[j+1].is("values"))
+}
+
+// Apply redacts a query_database JSON payload produced by query.
+func (p *RedactionPolicy) Apply(query, data string) (string, error) {
+	rules := p.rulesFor(query)
+	if len(rules) == 0 {
+		return data, nil
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &rows); err != nil {
+		return "", fmt.Errorf("failed to decode result for redaction: %w", err)
+	}
+
+	redactRows(rules, rows)
+
+	out, err := json.Marshal(rows)
+	if err != nil {
+		return "", fmt.Errorf("json error: %w", err)
+	}
+	return string(out), nil
+}
+
+// redactRows applies rules to rows in place.
+func redactRows(rules map[string]Action, rows []map[string]interface{}) {
+	for _, row := range rows {
+		for column := range row {
+			switch rules[strings.ToLower(column)] {
+			case ActionDrop:
+				delete(row, column)
+			case ActionMask:
+				if row[column] != nil {
+					row[column] = MaskValue
+				}
+			}
+		}
+	}
+}
+
+// RedactingClient wraps an MCPClient and applies a RedactionPolicy to every
+// query result.
+type RedactingClient struct {
+	sqlagent.MCPClient
+	policy *RedactionPolicy
+}
+
+// NewRedactingClient creates a client that redacts results from inner.
+func NewRedactingClient(inner sqlagent.MCPClient, policy *RedactionPolicy) *RedactingClient {
+	return &RedactingClient{MCPClient: inner, policy: policy}
+}
+
+// Query executes the query and redacts governed columns from the result.
+// Results that cannot be redacted are withheld rather than passed through.
+func (c *RedactingClient) Query(ctx context.Context, query string, limit int) (string, error) {
+	// Rules match result columns by name, so governed values must keep theirs
+	if hidden := c.policy.Unredactable(query); len(hidden) > 0 {
+		return "", fmt.Errorf("redaction: %s may only be selected as plain columns under their own names, not renamed, inside expressions or as whole rows. Select them plainly or leave them out.",
+			strings.Join(hidden, ", "))
+	}
+
+	// Partial results streamed while the query runs must be redacted too
+	if onBatch := sqlagent.RowHandlerFrom(ctx); onBatch != nil {
+		rules := c.policy.rulesFor(query)
+		ctx = sqlagent.WithRowHandler(ctx, func(batch sqlagent.RowBatch) {
+			rows := make([]map[string]interface{}, len(batch.Rows))
+			for i, row := range batch.Rows {
+				rows[i] = make(map[string]interface{}, len(row))
+				for k, v := range row {
+					rows[i][k] = v
+				}
+			}
+			redactRows(rules, rows)
+			batch.Rows = rows
+
+			var columns []string
+			for _, column := range batch.Columns {
+				if rules[strings.ToLower(column)] != ActionDrop {
+					columns = append(columns, column)
+				}
+			}
+			batch.Columns = columns
+			onBatch(batch)
+		})
+	}
+
+	data, err := c.MCPClient.Query(ctx, query, limit)
+	if err != nil {
+		return "", err
+	}
+	// So must the rows of a result kept on disk
+	if s := sqlagent.SpillFrom(ctx); s != nil {
+		if rules := c.policy.rulesFor(query); len(rules) > 0 {
+			s.Edit(func(row map[string]interface{}) {
+				redactRows(rules, []map[string]interface{}{row})
+			})
+		}
+	}
+
+	redacted, err := c.policy.Apply(query, data)
+	if err != nil {
+		return "", err
+	}
+	return redacted, nil
+}
@@ -0,0 +1,179 @@
+package governance
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReferencedTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			name:     "single table",
+			query:    "SELECT * FROM customers",
+			expected: []string{"customers"},
+		},
+		{
+			name:     "schema qualified and quoted",
+			query:    `SELECT * FROM public."Customers" c JOIN orders o ON o.customer_id = c.id`,
+			expected: []string{"customers", "orders"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ReferencedTables(tt.query)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("ReferencedTables(%q) = %v, want %v", tt.query, got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("ReferencedTables(%q) = %v, want %v", tt.query, got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactionPolicy_Apply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	policy := `
+tables:
+  customers:
+    email: mask
+    phone: drop
+  "*":
+    ssn: drop
+`
+	if err := os.WriteFile(path, []byte(policy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadRedactionPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadRedactionPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		data     string
+		expected string
+	}{
+		{
+			name:     "mask and drop customer columns",
+			query:    "SELECT name, email, phone FROM customers",
+			data:     `[{"email":"a@b.com","name":"Ann","phone":"123"}]`,
+			expected: `[{"email":"[REDACTED]","name":"Ann"}]`,
+		},
+		{
+			name:     "wildcard rule applies to any table",
+			query:    "SELECT name, ssn FROM employees",
+			data:     `[{"name":"Bob","ssn":"000-00-0000"}]`,
+			expected: `[{"name":"Bob"}]`,
+		},
+		{
+			name:     "unrelated table is untouched",
+			query:    "SELECT email FROM suppliers",
+			data:     `[{"email":"s@x.com"}]`,
+			expected: `[{"email":"s@x.com"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Apply(tt.query, tt.data)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Apply() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+
+	if !p.Redacts("crm.Customers", "EMAIL") || !p.Redacts("employees", "ssn") || p.Redacts("suppliers", "email") {
+		t.Error("Redacts() disagrees with the policy")
+	}
+}
+
+func TestLoadRedactionPolicy_InvalidAction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(path, []byte("tables:\n  t:\n    c: hide\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadRedactionPolicy(path); err == nil {
+		t.Error("expected error for invalid action")
+	}
+}
+
+func TestRedactionPolicy_Unredactable(t *testing.T) {
+	p := &RedactionPolicy{Tables: map[string]map[string]Action{
+		"customers": {"email": ActionMask, "phone": ActionDrop},
+		AnyTable:    {"ssn": ActionDrop},
+	}}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "plain columns", query: "SELECT name, email, c.phone FROM customers c"},
+		{name: "aliased to its own name", query: `SELECT DISTINCT c.email AS "Email" FROM customers c`},
+		{name: "filtered, grouped and ordered", query: "SELECT name FROM customers WHERE lower(email) LIKE '%@x.com' GROUP BY name, email ORDER BY email"},
+		{name: "counted", query: "SELECT count(DISTINCT email) AS n FROM customers"},
+		{name: "distinct on", query: "SELECT DISTINCT ON (email) email, name FROM customers ORDER BY email"},
+		{name: "derived table", query: "SELECT s.email FROM (SELECT email FROM customers) s"},
+		{name: "in a subquery condition", query: "SELECT * FROM orders WHERE customer IN (SELECT email FROM customers)"},
+		{name: "whole table", query: "SELECT c.* FROM customers c"},
+		{name: "ungoverned table", query: "SELECT email AS e FROM suppliers"},
+		{name: "alias", query: "SELECT email AS e FROM customers", want: []string{"email"}},
+		{name: "alias without as", query: "SELECT c.email e, phone FROM customers c", want: []string{"email"}},
+		{name: "expression", query: "SELECT concat(email, '') FROM customers", want: []string{"email"}},
+		{name: "cast", query: "SELECT email::text AS email FROM customers", want: []string{"email"}},
+		{name: "row", query: "SELECT row_to_json(c) FROM customers c", want: []string{"c"}},
+		{name: "row by table name", query: "SELECT customers FROM customers", want: []string{"customers"}},
+		{name: "row of columns", query: "SELECT to_jsonb(c.*) FROM customers c", want: []string{"c.*"}},
+		{name: "alias in a derived table", query: "SELECT * FROM (SELECT email AS e FROM customers) s", want: []string{"email"}},
+		{name: "scalar subquery", query: "SELECT (SELECT email FROM customers LIMIT 1) AS e", want: []string{"email"}},
+		{name: "column alias list", query: "SELECT x FROM (SELECT email FROM customers) s (x)", want: []string{"email"}},
+		{name: "cte column list", query: "WITH t (x) AS (SELECT email FROM customers) SELECT x FROM t", want: []string{"email"}},
+		{name: "union", query: "SELECT name FROM suppliers UNION SELECT email FROM customers", want: []string{"email"}},
+		{name: "function in from", query: "SELECT x FROM customers, unnest(ARRAY[email]) x", want: []string{"email"}},
+		{name: "table after a comma", query: "SELECT email AS e FROM suppliers, customers", want: []string{"email"}},
+		{name: "rule for every table", query: "SELECT upper(ssn) FROM employees", want: []string{"ssn"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Unredactable(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Unredactable(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactingClient(t *testing.T) {
+	inner := &countingClient{}
+	c := NewRedactingClient(inner, &RedactionPolicy{Tables: map[string]map[string]Action{
+		"customers": {"email": ActionMask},
+	}})
+
+	if _, err := c.Query(context.Background(), "SELECT email FROM customers", 0); err != nil {
+		t.Fatalf("Query(email) error = %v", err)
+	}
+	_, err := c.Query(context.Background(), "SELECT email AS e FROM customers", 0)
+	if err == nil || !strings.Contains(err.Error(), "email may only be selected as plain columns") {
+		t.Errorf("Query(email AS e) error = %v, want a refusal naming email", err)
+	}
+	if inner.queries != 1 {
+		t.Errorf("%d queries reached the database, want 1", inner.queries)
+	}
+}
@@ -0,0 +1,335 @@
+package governance
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// sqlToken is a word, quoted identifier or punctuation character of a query.
+type sqlToken struct {
+	text string
+	// quoted marks "identifiers", which are never keywords
+	quoted bool
+}
+
+func (t sqlToken) is(keyword string) bool {
+	return !t.quoted && strings.EqualFold(t.text, keyword)
+}
+
+func (t sqlToken) ident() bool {
+	if t.quoted {
+		return true
+	}
+	r := []rune(t.text)
+	return len(r) > 0 && (unicode.IsLetter(r[0]) || r[0] == '_')
+}
+
+// tokenize splits query into tokens, skipping comments, string literals and
+// numbers.
+func tokenize(query string) []sqlToken {
+	var tokens []sqlToken
+	r := []rune(query)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '-' && i+1 < len(r) && r[i+1] == '-':
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(r) && r[i+1] == '*':
+			i += 2
+			for i < len(r) && !(r[i] == '*' && i+1 < len(r) && r[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < len(r) {
+				if r[j] == c {
+					// A doubled quote is an escaped quote
+					if j+1 < len(r) && r[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if c == '"' {
+				tokens = append(tokens, sqlToken{text: strings.ReplaceAll(string(r[i+1:min(j, len(r))]), `""`, `"`), quoted: true})
+			}
+			i = j + 1
+		case unicode.IsLetter(c) || c == '_' || unicode.IsDigit(c):
+			j := i
+			for j < len(r) && (unicode.IsLetter(r[j]) || unicode.IsDigit(r[j]) || r[j] == '_' || r[j] == '$') {
+				j++
+			}
+			if !unicode.IsDigit(c) {
+				tokens = append(tokens, sqlToken{text: string(r[i:j])})
+			}
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{text: string(c)})
+			i++
+		}
+	}
+	return tokens
+}
+
+// tableListEnd are the keywords that end a FROM list.
+var tableListEnd = []string{"where", "group", "order", "limit", "offset", "having", "window", "union", "intersect", "except", "returning", "set", "values", "select", "fetch", "for"}
+
+// QualifiedTables returns the schema.table names, lowercased, of the tables
+// and views a query reads or writes: after FROM (including comma-separated
+// lists), JOIN, INTO, UPDATE, USING and TABLE. Names without a schema are in
+// DefaultSchema; common table expressions and set-returning functions are
+// skipped.
+func QualifiedTables(query string) []string {
+	var tables []string
+	for _, table := range queryTables(query) {
+		if !strings.Contains(table, ".") {
+			table = DefaultSchema + "." + table
+		}
+		if !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// queryTables returns the tables of QualifiedTables, leaving names written
+// without a schema unqualified.
+func queryTables(query string) []string {
+	var tables []string
+	tokens := tokenize(query)
+	for len(tokens) > 0 {
+		end := slices.IndexFunc(tokens, func(t sqlToken) bool { return t.text == ";" && !t.quoted })
+		if end < 0 {
+			end = len(tokens)
+		}
+		for _, table := range statementTables(tokens[:end]) {
+			if !slices.Contains(tables, table) {
+				tables = append(tables, table)
+			}
+		}
+		tokens = tokens[min(end+1, len(tokens)):]
+	}
+	return tables
+}
+
+// statementTables returns the tables of a single statement.
+func statementTables(tokens []sqlToken) []string {
+	ctes := cteScopes(tokens)
+	var tables []string
+	// inList marks the parenthesis depths with an open FROM list, and
+	// subquery those opened by a subquery rather than an expression
+	inList := map[int]bool{}
+	subquery := map[int]bool{0: true}
+	depth := 0
+	// expect is set when the next name is a table
+	expect := false
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		punct := !t.quoted
+		switch {
+		case punct && t.text == "(":
+			depth++
+			next := sqlToken{}
+			if i+1 < len(tokens) {
+				next = tokens[i+1]
+			}
+			subquery[depth] = next.is("select") || next.is("with") || next.is("values") || next.is("table") || next.text == "("
+			// FROM (a JOIN b) lists tables inside the parentheses
+			inList[depth] = expect && !subquery[depth]
+			expect = inList[depth]
+			continue
+		case punct && t.text == ")":
+			inList[depth] = false
+			depth--
+			expect = false
+			continue
+		case expect && (t.is("only") || t.is("lateral")):
+			continue
+		case expect && t.ident():
+			expect = false
+			var parts []string
+			j := i
+			for j < len(tokens) && tokens[j].ident() {
+				parts = append(parts, strings.ToLower(tokens[j].text))
+				j++
+				if j+1 < len(tokens) && tokens[j].text == "." && !tokens[j].quoted {
+					j++
+					continue
+				}
+				break
+			}
+			if j < len(tokens) && tokens[j].text == "(" && !tokens[j].quoted && !tokens[i-1].is("into") {
+				// A set-returning function; INSERT INTO t (columns) is a table
+				continue
+			}
+			if len(parts) == 1 && slices.ContainsFunc(ctes, func(c cteScope) bool { return c.name == parts[0] && c.start <= i && i < c.end }) {
+				i = j - 1
+				continue
+			}
+			i = j - 1
+			// database.schema.table names the table by its last two parts
+			name := strings.Join(parts[max(len(parts)-2, 0):], ".")
+			if !slices.Contains(tables, name) {
+				tables = append(tables, name)
+			}
+			continue
+		}
+
+		expect = false
+		switch {
+		case t.is("from"):
+			// FROM in EXTRACT(x FROM y), SUBSTRING(...) and IS DISTINCT FROM
+			// names no table
+			distinct := i > 1 && tokens[i-1].is("distinct") && (tokens[i-2].is("is") || tokens[i-2].is("not"))
+			if !subquery[depth] || distinct {
+				continue
+			}
+			expect, inList[depth] = true, true
+		case t.is("update"):
+			// FOR [NO KEY] UPDATE and DO UPDATE name no table
+			expect = i == 0 || !(tokens[i-1].is("for") || tokens[i-1].is("key") || tokens[i-1].is("do"))
+		case t.is("join"), t.is("into"), t.is("table"):
+			expect = true
+		case t.is("using"):
+			// USING (columns) of a join names no table
+			expect = i+1 < len(tokens) && tokens[i+1].text != "("
+		case punct && t.text == ",":
+			expect = inList[depth]
+		case inList[depth] && slices.ContainsFunc(tableListEnd, t.is):
+			inList[depth] = false
+		}
+	}
+	return tables
+}
+
+// textQueryFunctions run SQL, or read a table, named by a string rather
+// than written in the query, which hides the tables from QualifiedTables;
+// set_config can change the search path names without a schema resolve
+// in. Names ending in "*" are prefixes.
+var textQueryFunctions = []string{
+	"query_to_*", "cursor_to_*", "table_to_*", "schema_to_*", "database_to_*",
+	"dblink*", "ts_stat", "xpath_table", "crosstab*", "connectby", "set_config",
+}
+
+// TextQueryFunctions returns the functions, lowercased and without a
+// schema, that query calls to run SQL passed as text, such as
+// query_to_xml('SELECT * FROM secrets', ...) or dblink.
+func TextQueryFunctions(query string) []string {
+	var names []string
+	tokens := tokenize(query)
+	for i := 0; i+1 < len(tokens); i++ {
+		if !tokens[i].ident() || tokens[i+1].text != "(" || tokens[i+1].quoted {
+			continue
+		}
+		name := strings.ToLower(tokens[i].text)
+		if slices.ContainsFunc(textQueryFunctions, func(pattern string) bool {
+			prefix, wildcard := strings.CutSuffix(pattern, "*")
+			return name == pattern || wildcard && strings.HasPrefix(name, prefix)
+		}) && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// cteScope is a common table expression and the tokens, from start up to
+// end, in which its name refers to it rather than to a table.
+type cteScope struct {
+	name       string
+	start, end int
+}
+
+// cteScopes returns the common table expressions defined by the WITH
+// clauses of a statement: each name, with optional columns, followed by
+// AS [NOT] [MATERIALIZED] (body). A name is in scope in the bodies of the
+// expressions after it and in the rest of the query the WITH belongs to,
+// up to the parenthesis enclosing it; under WITH RECURSIVE also in its own
+// body.
+func cteScopes(tokens []sqlToken) []cteScope {
+	var scopes []cteScope
+	for w, t := range tokens {
+		if !t.is("with") {
+			continue
+		}
+		end := closing(tokens, w)
+		i := w + 1
+		recursive := i < len(tokens) && tokens[i].is("recursive")
+		if recursive {
+			i++
+		}
+		for i < end && tokens[i].ident() {
+			name := strings.ToLower(tokens[i].text)
+			j := i + 1
+			if j < end && tokens[j].text == "(" && !tokens[j].quoted {
+				j = closing(tokens, j+1) + 1
+			}
+			if j >= end || !tokens[j].is("as") {
+				break
+			}
+			j++
+			for j < end && (tokens[j].is("not") || tokens[j].is("materialized")) {
+				j++
+			}
+			if j >= end || tokens[j].text != "(" || tokens[j].quoted {
+				break
+			}
+			body := closing(tokens, j+1)
+			start := body + 1
+			if recursive {
+				start = j
+			}
+			scopes = append(scopes, cteScope{name: name, start: start, end: end})
+			i = body + 1
+			if i >= end || tokens[i].text != "," || tokens[i].quoted {
+				break
+			}
+			i++
+		}
+	}
+	return scopes
+}
+
+// closing returns the index of the ")" closing the parenthesis that
+// tokens[from] is in, or len(tokens) if it is at the top level.
+func closing(tokens []sqlToken, from int) int {
+	depth := 0
+	for i := from; i < len(tokens); i++ {
+		switch {
+		case tokens[i].quoted:
+		case tokens[i].text == "(":
+			depth++
+		case tokens[i].text == ")":
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return len(tokens)
+}
+
+// opening returns the index of the "(" that the ")" at tokens[i] closes,
+// or -1 if there is none.
+func opening(tokens []sqlToken, i int) int {
+	depth := 0
+	for j := i; j >= 0; j-- {
+		switch {
+		case tokens[j].quoted:
+		case tokens[j].text == ")":
+			depth++
+		case tokens[j].text == "(":
+			if depth--; depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
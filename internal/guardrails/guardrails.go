@@ -0,0 +1,403 @@
+// Package guardrails checks the final answers of the agents and the
+// arguments of their tool calls against an organization's content rules,
+// before answers are shown to the user and before tools run. Rules are
+// regular expressions, whose matches are redacted or block the output, or
+// policies in plain words that a model judges.
+package guardrails
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+	"gopkg.in/yaml.v3"
+)
+
+// Target is what a rule checks.
+type Target string
+
+const (
+	// TargetResponse is the answer of a turn: its text, charts and query
+	// results.
+	TargetResponse Target = "response"
+	// TargetToolArgs is the arguments of a tool call, such as the SQL of a
+	// query.
+	TargetToolArgs Target = "tool_args"
+)
+
+// Action is what happens to output a rule matches.
+type Action string
+
+const (
+	// ActionBlock withholds the whole answer, or refuses the tool call.
+	ActionBlock Action = "block"
+	// ActionRedact replaces the matches of a pattern with RedactedValue.
+	ActionRedact Action = "redact"
+)
+
+// RedactedValue is substituted for redacted matches.
+const RedactedValue = "[REDACTED]"
+
+// Rule is a content rule. It has a Pattern or a Policy, not both.
+type Rule struct {
+	Name string `yaml:"name"`
+	// Pattern is a regular expression that output must not match
+	Pattern string `yaml:"pattern"`
+	// Policy describes in plain words what output must not contain; a
+	// model judges each output against it
+	Policy string `yaml:"policy"`
+	// AppliesTo lists what the rule checks (default: both targets)
+	AppliesTo []Target `yaml:"applies_to"`
+	// Tools limits the rule's tool_args checks to these tools (default:
+	// every tool)
+	Tools []string `yaml:"tools"`
+	// Action is block or redact (default: block); policies can only block
+	Action Action `yaml:"action"`
+	// Message is shown instead of blocked output
+	Message string `yaml:"message"`
+
+	re *regexp.Regexp
+}
+
+// Policy is a list of content rules, applied in order.
+//
+// Example YAML:
+//
+//	rules:
+//	  - name: ssn
+//	    pattern: '\b\d{3}-\d{2}-\d{4}\b'
+//	    action: redact
+//	  - name: no-drop
+//	    pattern: '(?i)\bdrop\s+table\b'
+//	    applies_to: [tool_args]
+//	    tools: [query_database]
+//	  - name: customer-lists
+//	    policy: Lists of individual customers with their email addresses or phone numbers.
+//	    applies_to: [response]
+//	    message: Customer contact lists cannot be shared here.
+type Policy struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// LoadPolicy reads a guardrails policy from a YAML file.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guardrails policy: %w", err)
+	}
+
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse guardrails policy: %w", err)
+	}
+	if err := policy.compile(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// compile validates the rules, fills in their defaults and compiles their
+// patterns.
+func (p *Policy) compile() error {
+	names := make(map[string]bool, len(p.Rules))
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("duplicate guardrail %q", r.Name)
+		}
+		names[r.Name] = true
+
+		if (r.Pattern == "") == (r.Policy == "") {
+			return fmt.Errorf("guardrail %s needs either a pattern or a policy", r.Name)
+		}
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern for guardrail %s: %w", r.Name, err)
+			}
+			r.re = re
+		}
+
+		r.Action = Action(strings.ToLower(string(r.Action)))
+		switch r.Action {
+		case "":
+			r.Action = ActionBlock
+		case ActionBlock:
+		case ActionRedact:
+			if r.Policy != "" {
+				return fmt.Errorf("guardrail %s: policies can only block", r.Name)
+			}
+		default:
+			return fmt.Errorf("invalid action %q for guardrail %s", r.Action, r.Name)
+		}
+
+		if len(r.AppliesTo) == 0 {
+			r.AppliesTo = []Target{TargetResponse, TargetToolArgs}
+		}
+		for _, t := range r.AppliesTo {
+			if t != TargetResponse && t != TargetToolArgs {
+				return fmt.Errorf("invalid target %q for guardrail %s", t, r.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// applies reports whether the rule checks target, and for tool arguments
+// the calls of toolName.
+func (r *Rule) applies(target Target, toolName string) bool {
+	if !slices.Contains(r.AppliesTo, target) {
+		return false
+	}
+	return target != TargetToolArgs || len(r.Tools) == 0 || slices.Contains(r.Tools, toolName)
+}
+
+// blockedMessage is shown instead of output blocked by r.
+func (r *Rule) blockedMessage(target Target) string {
+	switch {
+	case r.Message != "":
+		return r.Message
+	case target == TargetToolArgs:
+		return "the call is not allowed by the content policy"
+	}
+	return fmt.Sprintf("This answer was withheld by the content policy (%s).", r.Name)
+}
+
+// Violation records a rule that matched.
+type Violation struct {
+	Rule   string `json:"rule"`
+	Target Target `json:"target"`
+	Tool   string `json:"tool,omitempty"`
+	Action Action `json:"action"`
+	// Reason is the model's explanation for policies
+	Reason string `json:"reason,omitempty"`
+	// Error is set if the output was blocked because the model could not
+	// judge it
+	Error string `json:"error,omitempty"`
+}
+
+// Result is the outcome of a check.
+type Result struct {
+	// Text is the checked text with matches redacted, or the rule's
+	// message if it was blocked
+	Text string
+	// Blocked is set if a rule withheld the text
+	Blocked bool
+	// Violations lists the rules that matched, in order
+	Violations []Violation
+}
+
+// Config holds configuration for a Guard.
+type Config struct {
+	Policy *Policy
+	// Model judges the policies of the rules; required if any rule has one
+	Model model.LLM
+}
+
+// Guard applies a guardrails policy.
+type Guard struct {
+	rules []Rule
+	model model.LLM
+}
+
+// New creates a Guard.
+func New(cfg Config) (*Guard, error) {
+	if cfg.Policy == nil {
+		return nil, fmt.Errorf("guardrails policy is required")
+	}
+	if err := cfg.Policy.compile(); err != nil {
+		return nil, err
+	}
+	for _, r := range cfg.Policy.Rules {
+		if r.Policy != "" && cfg.Model == nil {
+			return nil, fmt.Errorf("guardrail %s has a policy but no model is configured to judge it", r.Name)
+		}
+	}
+	return &Guard{rules: cfg.Policy.Rules, model: cfg.Model}, nil
+}
+
+// Rules returns the number of rules of the policy.
+func (g *Guard) Rules() int {
+	return len(g.rules)
+}
+
+// CheckResponse checks the text of an answer. Patterns are applied first,
+// so policies judge the redacted text.
+func (g *Guard) CheckResponse(ctx context.Context, text string) (Result, error) {
+	res := Result{Text: text}
+	for i := range g.rules {
+		r := &g.rules[i]
+		if r.re == nil || !r.applies(TargetResponse, "") || !r.re.MatchString(res.Text) {
+			continue
+		}
+		res.Violations = append(res.Violations, Violation{Rule: r.Name, Target: TargetResponse, Action: r.Action})
+		if r.Action == ActionBlock {
+			res.Blocked, res.Text = true, r.blockedMessage(TargetResponse)
+			return res, nil
+		}
+		res.Text = r.re.ReplaceAllLiteralString(res.Text, RedactedValue)
+	}
+
+	judged, err := g.judge(ctx, TargetResponse, "", res.Text)
+	judged.Violations = append(res.Violations, judged.Violations...)
+	return judged, err
+}
+
+// CheckArgs checks the arguments of a call of toolName, and returns them
+// with the string values redacted.
+func (g *Guard) CheckArgs(ctx context.Context, toolName string, args map[string]any) (map[string]any, Result, error) {
+	var res Result
+	for i := range g.rules {
+		r := &g.rules[i]
+		if r.re == nil || !r.applies(TargetToolArgs, toolName) {
+			continue
+		}
+		redacted, matched := redactValue(r.re, args)
+		if !matched {
+			continue
+		}
+		res.Violations = append(res.Violations, Violation{Rule: r.Name, Target: TargetToolArgs, Tool: toolName, Action: r.Action})
+		if r.Action == ActionBlock {
+			res.Blocked, res.Text = true, r.blockedMessage(TargetToolArgs)
+			return args, res, nil
+		}
+		args = redacted.(map[string]any)
+	}
+
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return args, res, fmt.Errorf("failed to encode arguments of %s: %w", toolName, err)
+	}
+	judged, err := g.judge(ctx, TargetToolArgs, toolName, string(encoded))
+	judged.Violations = append(res.Violations, judged.Violations...)
+	return args, judged, err
+}
+
+// judge asks the model whether text breaks the policies for target. Text
+// that could not be judged is blocked along with the error.
+func (g *Guard) judge(ctx context.Context, target Target, toolName, text string) (Result, error) {
+	res := Result{Text: text}
+	for i := range g.rules {
+		r := &g.rules[i]
+		if r.Policy == "" || !r.applies(target, toolName) {
+			continue
+		}
+		violates, reason, err := ask(ctx, g.model, r.Policy, target, text)
+		if err != nil {
+			err = fmt.Errorf("failed to check guardrail %s: %w", r.Name, err)
+			res.Violations = append(res.Violations, Violation{Rule: r.Name, Target: target, Tool: toolName, Action: ActionBlock, Error: err.Error()})
+			res.Blocked, res.Text = true, r.blockedMessage(target)
+			return res, err
+		}
+		if violates {
+			res.Violations = append(res.Violations, Violation{Rule: r.Name, Target: target, Tool: toolName, Action: ActionBlock, Reason: reason})
+			res.Blocked, res.Text = true, r.blockedMessage(target)
+			return res, nil
+		}
+	}
+	return res, nil
+}
+
+// redactValue replaces the matches of re in the strings of a decoded JSON
+// value, and reports whether there were any.
+func redactValue(re *regexp.Regexp, v any) (any, bool) {
+	switch v := v.(type) {
+	case string:
+		if !re.MatchString(v) {
+			return v, false
+		}
+		return re.ReplaceAllLiteralString(v, RedactedValue), true
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		matched := false
+		for k, item := range v {
+			redacted, ok := redactValue(re, item)
+			out[k], matched = redacted, matched || ok
+		}
+		return out, matched
+	case []any:
+		out := make([]any, len(v))
+		matched := false
+		for i, item := range v {
+			redacted, ok := redactValue(re, item)
+			out[i], matched = redacted, matched || ok
+		}
+		return out, matched
+	}
+	return v, false
+}
+
+// judgeInstruction asks the model whether output breaks a policy.
+const judgeInstruction = `You enforce a content policy on the output of a data analysis assistant. The policy forbids:
+%s
+
+Decide whether the %s below contains anything the policy forbids. Judge only the content itself, not whether it is accurate.
+Reply with JSON only: {"violates": <true or false>, "reason": "<one short sentence>"}`
+
+// describeTarget names a target in the judge's instruction.
+var describeTarget = map[Target]string{
+	TargetResponse: "answer to the user",
+	TargetToolArgs: "tool call arguments",
+}
+
+// ask has the model judge text against policy.
+func ask(ctx context.Context, llm model.LLM, policy string, target Target, text string) (bool, string, error) {
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText(text, genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(fmt.Sprintf(judgeInstruction, policy, describeTarget[target]), genai.RoleUser),
+			ResponseMIMEType:  "application/json",
+			ResponseSchema: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"violates": {Type: genai.TypeBoolean},
+					"reason":   {Type: genai.TypeString, Description: "One short sentence"},
+				},
+				Required:         []string{"violates", "reason"},
+				PropertyOrdering: []string{"violates", "reason"},
+			},
+			Temperature: genai.Ptr[float32](0),
+		},
+	}
+	var reply strings.Builder
+	for resp, err := range llm.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return false, "", err
+		}
+		if resp == nil || resp.Content == nil {
+			continue
+		}
+		for _, p := range resp.Content.Parts {
+			if !p.Thought {
+				reply.WriteString(p.Text)
+			}
+		}
+	}
+	return parseVerdict(reply.String())
+}
+
+// parseVerdict reads the model's JSON reply, tolerating text or code fences
+// around it.
+func parseVerdict(reply string) (bool, string, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return false, "", fmt.Errorf("no JSON in reply %q", reply)
+	}
+	var out struct {
+		Violates bool   `json:"violates"`
+		Reason   string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &out); err != nil {
+		return false, "", fmt.Errorf("failed to decode reply: %w", err)
+	}
+	return out.Violates, strings.TrimSpace(out.Reason), nil
+}
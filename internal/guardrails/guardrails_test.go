@@ -0,0 +1,160 @@
+package guardrails
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// judgingLLM flags any text containing forbidden, and fails if fail is set.
+type judgingLLM struct {
+	forbidden string
+	fail      bool
+	calls     int
+}
+
+func (m *judgingLLM) Name() string { return "judging" }
+
+func (m *judgingLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		m.calls++
+		if m.fail {
+			yield(nil, errors.New("model unavailable"))
+			return
+		}
+		reply := `{"violates": false, "reason": "fine"}`
+		if strings.Contains(req.Contents[0].Parts[0].Text, m.forbidden) {
+			reply = "```json\n{\"violates\": true, \"reason\": \"lists contacts\"}\n```"
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(reply, genai.RoleModel)}, nil)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "valid", yaml: "rules:\n  - name: ssn\n    pattern: '\\d{3}-\\d{2}-\\d{4}'\n    action: REDACT\n"},
+		{name: "pattern and policy", yaml: "rules:\n  - pattern: x\n    policy: y\n", wantErr: "either a pattern or a policy"},
+		{name: "bad pattern", yaml: "rules:\n  - pattern: '('\n", wantErr: "invalid pattern"},
+		{name: "redacting policy", yaml: "rules:\n  - policy: y\n    action: redact\n", wantErr: "can only block"},
+		{name: "unknown action", yaml: "rules:\n  - pattern: x\n    action: warn\n", wantErr: "invalid action"},
+		{name: "unknown target", yaml: "rules:\n  - pattern: x\n    applies_to: [prompt]\n", wantErr: "invalid target"},
+		{name: "duplicate", yaml: "rules:\n  - {name: a, pattern: x}\n  - {name: a, pattern: y}\n", wantErr: "duplicate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "guardrails.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadPolicy(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("LoadPolicy() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("LoadPolicy() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	llm := &judgingLLM{forbidden: "@example.com"}
+	g, err := New(Config{Model: llm, Policy: &Policy{Rules: []Rule{
+		{Name: "ssn", Pattern: `\b\d{3}-\d{2}-\d{4}\b`, Action: ActionRedact},
+		{Name: "secret", Pattern: `(?i)top secret`, Message: "Not here."},
+		{Name: "no-drop", Pattern: `(?i)drop table`, AppliesTo: []Target{TargetToolArgs}},
+		{Name: "contacts", Policy: "Customer contact lists.", AppliesTo: []Target{TargetResponse}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		text        string
+		want        string
+		wantBlocked bool
+		wantRules   []string
+	}{
+		{name: "clean", text: "42 orders", want: "42 orders"},
+		{name: "redacted", text: "SSNs 123-45-6789 and 987-65-4321", want: "SSNs [REDACTED] and [REDACTED]", wantRules: []string{"ssn"}},
+		{name: "blocked with message", text: "the top secret plan", want: "Not here.", wantBlocked: true, wantRules: []string{"secret"}},
+		{name: "tool rule ignored", text: "how to DROP TABLE", want: "how to DROP TABLE"},
+		{name: "policy", text: "ann@example.com 123-45-6789", want: "This answer was withheld by the content policy (contacts).", wantBlocked: true, wantRules: []string{"ssn", "contacts"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := g.CheckResponse(context.Background(), tt.text)
+			if err != nil {
+				t.Fatalf("CheckResponse() error = %v", err)
+			}
+			if res.Text != tt.want || res.Blocked != tt.wantBlocked {
+				t.Errorf("CheckResponse() = %q blocked %v, want %q blocked %v", res.Text, res.Blocked, tt.want, tt.wantBlocked)
+			}
+			var rules []string
+			for _, v := range res.Violations {
+				rules = append(rules, v.Rule)
+			}
+			if strings.Join(rules, ",") != strings.Join(tt.wantRules, ",") {
+				t.Errorf("CheckResponse() violations = %v, want %v", rules, tt.wantRules)
+			}
+		})
+	}
+
+	llm.fail = true
+	res, err := g.CheckResponse(context.Background(), "42 orders")
+	if err == nil || !res.Blocked || res.Violations[0].Error == "" {
+		t.Errorf("CheckResponse() with a failing model = %+v, %v; want it blocked with an error", res, err)
+	}
+}
+
+func TestCheckArgs(t *testing.T) {
+	g, err := New(Config{Policy: &Policy{Rules: []Rule{
+		{Name: "ssn", Pattern: `\d{3}-\d{2}-\d{4}`, Action: ActionRedact},
+		{Name: "no-drop", Pattern: `(?i)drop\s+table`, Tools: []string{"query_database"}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := map[string]any{"sql": "SELECT * FROM people WHERE ssn = '123-45-6789'", "params": []any{"987-65-4321", 3.0}}
+	checked, res, err := g.CheckArgs(context.Background(), "query_database", args)
+	if err != nil || res.Blocked {
+		t.Fatalf("CheckArgs() = %+v, %v", res, err)
+	}
+	if checked["sql"] != "SELECT * FROM people WHERE ssn = '[REDACTED]'" || checked["params"].([]any)[0] != RedactedValue || checked["params"].([]any)[1] != 3.0 {
+		t.Errorf("CheckArgs() = %v, want the SSNs redacted", checked)
+	}
+	if args["sql"] == checked["sql"] {
+		t.Error("CheckArgs() changed the caller's arguments")
+	}
+
+	_, res, _ = g.CheckArgs(context.Background(), "query_database", map[string]any{"sql": "DROP  TABLE orders"})
+	if !res.Blocked || res.Violations[0].Tool != "query_database" {
+		t.Errorf("CheckArgs() = %+v, want the call blocked", res)
+	}
+	_, res, _ = g.CheckArgs(context.Background(), "get_schema", map[string]any{"table": "drop table"})
+	if res.Blocked {
+		t.Error("CheckArgs() blocked a tool the rule does not apply to")
+	}
+}
+
+func TestNewRequiresModelForPolicies(t *testing.T) {
+	_, err := New(Config{Policy: &Policy{Rules: []Rule{{Name: "contacts", Policy: "Customer contact lists."}}}})
+	if err == nil {
+		t.Error("New() without a model should fail for policies")
+	}
+}
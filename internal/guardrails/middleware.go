@@ -0,0 +1,45 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/toolmw"
+	"google.golang.org/adk/tool"
+)
+
+// Middleware checks the arguments of every tool call before the tool runs.
+// Redacted arguments are passed on; blocked calls, and calls that could not
+// be checked, are not run and the model is told why instead.
+func (g *Guard) Middleware() toolmw.Middleware {
+	return func(t tool.Tool, next toolmw.Handler) toolmw.Handler {
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			checked, res, err := g.CheckArgs(ctx, t.Name(), args)
+			Publish(ctx, res)
+			if err != nil && !res.Blocked {
+				return nil, err
+			}
+			if res.Blocked {
+				v := res.Violations[len(res.Violations)-1]
+				return map[string]any{"error": fmt.Sprintf("blocked by guardrail %s: %s", v.Rule, res.Text)}, nil
+			}
+			return next(ctx, checked)
+		}
+	}
+}
+
+// Publish publishes a GuardrailTriggered event for each violation of a
+// check.
+func Publish(ctx context.Context, res Result) {
+	for _, v := range res.Violations {
+		events.Publish(ctx, &events.GuardrailTriggered{
+			Rule:   v.Rule,
+			Target: string(v.Target),
+			Tool:   v.Tool,
+			Action: string(v.Action),
+			Reason: v.Reason,
+			Error:  v.Error,
+		})
+	}
+}
@@ -0,0 +1,305 @@
+// Package i18n normalizes locale-formatted numbers and dates found in user
+// questions so the agents do not have to guess whether "1.234" means one
+// thousand or one point two.
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Slot kinds.
+const (
+	KindNumber = "number"
+	KindDate   = "date"
+)
+
+// Locale describes how numbers and dates are written.
+type Locale struct {
+	// Tag is the BCP 47 language tag the locale was parsed from.
+	Tag string
+	// DecimalComma is true if "," separates the fractional part.
+	DecimalComma bool
+	// DayFirst is true if numeric dates are written day/month/year.
+	DayFirst bool
+}
+
+// DefaultLocale is used when no locale is configured.
+var DefaultLocale = Locale{Tag: "en-US"}
+
+// Languages that write decimals with a comma.
+var decimalCommaLanguages = map[string]bool{
+	"bg": true, "ca": true, "cs": true, "da": true, "de": true, "el": true,
+	"es": true, "fi": true, "fr": true, "hr": true, "hu": true, "id": true,
+	"it": true, "lt": true, "lv": true, "nb": true, "nl": true, "nn": true,
+	"no": true, "pl": true, "pt": true, "ro": true, "ru": true, "sk": true,
+	"sl": true, "sr": true, "sv": true, "tr": true, "uk": true, "vi": true,
+}
+
+// Regions that write month/day/year.
+var monthFirstRegions = map[string]bool{
+	"US": true, "PH": true, "FM": true, "MH": true, "PW": true,
+}
+
+// Regions that use a decimal point even if their language usually does not.
+var decimalPointRegions = map[string]bool{
+	"CH": true, "MX": true, "LI": true,
+}
+
+// ParseLocale parses a tag such as "de-DE", "en_GB" or "fr".
+func ParseLocale(tag string) (Locale, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return DefaultLocale, nil
+	}
+	// Drop encodings such as "de_DE.UTF-8"
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+
+	parts := strings.FieldsFunc(tag, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) == 0 {
+		return Locale{}, fmt.Errorf("invalid locale %q", tag)
+	}
+	lang := strings.ToLower(parts[0])
+	if len(lang) < 2 || len(lang) > 3 || strings.Trim(lang, "abcdefghijklmnopqrstuvwxyz") != "" {
+		return Locale{}, fmt.Errorf("invalid locale %q", tag)
+	}
+
+	var region string
+	for _, p := range parts[1:] {
+		if len(p) == 2 {
+			region = strings.ToUpper(p)
+			break
+		}
+	}
+
+	loc := Locale{
+		Tag:          tag,
+		DecimalComma: decimalCommaLanguages[lang] && !decimalPointRegions[region],
+		DayFirst:     true,
+	}
+	if monthFirstRegions[region] || (lang == "en" && region == "") {
+		loc.DayFirst = false
+	}
+	return loc, nil
+}
+
+// Slot is a number or date found in a question together with its canonical
+// form: a plain decimal ("1234.56") or an ISO 8601 date ("2024-12-31").
+type Slot struct {
+	Text  string `json:"text"`
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+var (
+	datePattern   = regexp.MustCompile(`\b(\d{1,4})([./-])(\d{1,2})([./-])(\d{2,4})\b`)
+	numberPattern = regexp.MustCompile(`\d{1,3}(?:[.,'\x{00A0}\x{202F}]\d{3})+(?:[.,]\d+)?|\d+[.,]\d+`)
+)
+
+// Extract returns the numbers and dates in text whose canonical form differs
+// from how they were written, in order of appearance.
+func (l Locale) Extract(text string) []Slot {
+	type found struct {
+		pos  int
+		slot Slot
+	}
+	var all []found
+	taken := make([]bool, len(text))
+
+	for _, m := range datePattern.FindAllStringSubmatchIndex(text, -1) {
+		if !boundary(text, m[0], m[1]) || text[m[4]:m[5]] != text[m[8]:m[9]] {
+			continue
+		}
+		for i := m[0]; i < m[1]; i++ {
+			taken[i] = true
+		}
+		raw := text[m[0]:m[1]]
+		value, ok := l.parseDate(text[m[2]:m[3]], text[m[6]:m[7]], text[m[10]:m[11]])
+		if ok && value != raw {
+			all = append(all, found{m[0], Slot{Text: raw, Kind: KindDate, Value: value}})
+		}
+	}
+
+	for _, m := range numberPattern.FindAllStringIndex(text, -1) {
+		if taken[m[0]] || !boundary(text, m[0], m[1]) {
+			continue
+		}
+		raw := text[m[0]:m[1]]
+		value, ok := l.parseNumber(raw)
+		if ok && value != raw {
+			all = append(all, found{m[0], Slot{Text: raw, Kind: KindNumber, Value: value}})
+		}
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i].pos < all[j].pos })
+	slots := make([]Slot, len(all))
+	for i, f := range all {
+		slots[i] = f.slot
+	}
+	return slots
+}
+
+// boundary reports whether text[start:end] is not glued to other digits or
+// separators, e.g. part of a version number like "1.2.3.4".
+func boundary(text string, start, end int) bool {
+	if start > 0 && strings.ContainsRune("0123456789.,/", rune(text[start-1])) {
+		return false
+	}
+	if end < len(text) {
+		next := text[end]
+		if next >= '0' && next <= '9' || next == '/' || next == '-' {
+			return false
+		}
+		// Allow sentence punctuation but not another separator group.
+		if (next == '.' || next == ',') && end+1 < len(text) && text[end+1] >= '0' && text[end+1] <= '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (l Locale) parseDate(first, second, third string) (string, bool) {
+	var year, month, day int
+	a, _ := strconv.Atoi(first)
+	b, _ := strconv.Atoi(second)
+	c, _ := strconv.Atoi(third)
+
+	switch {
+	case len(first) == 4:
+		year, month, day = a, b, c
+	case len(first) > 2:
+		return "", false
+	default:
+		switch {
+		case a > 12:
+			day, month = a, b
+		case b > 12:
+			month, day = a, b
+		case l.DayFirst:
+			day, month = a, b
+		default:
+			month, day = a, b
+		}
+		year = c
+		if len(third) == 2 {
+			year += 2000
+		} else if len(third) != 4 {
+			return "", false
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Year() != year || int(t.Month()) != month || t.Day() != day {
+		return "", false
+	}
+	return t.Format("2006-01-02"), true
+}
+
+func (l Locale) parseNumber(raw string) (string, bool) {
+	decimal := byte('.')
+	if l.DecimalComma {
+		decimal = ','
+	}
+
+	lastDot := strings.LastIndexByte(raw, '.')
+	lastComma := strings.LastIndexByte(raw, ',')
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		// Both present: whichever comes last is the decimal separator.
+		if lastComma > lastDot {
+			decimal = ','
+		} else {
+			decimal = '.'
+		}
+	case lastDot >= 0 || lastComma >= 0:
+		sep := byte('.')
+		if lastComma >= 0 {
+			sep = ','
+		}
+		switch {
+		case strings.Count(raw, string(sep)) > 1:
+			// Repeated separators can only be grouping.
+			decimal = other(sep)
+		case len(raw)-strings.LastIndexByte(raw, sep)-1 != 3:
+			// Grouping is always in threes, so anything else is a fraction.
+			decimal = sep
+		case strings.ContainsAny(raw, "'\u00a0\u202f"):
+			// Another separator is already grouping.
+			decimal = sep
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; {
+		case c >= '0' && c <= '9':
+			b.WriteByte(c)
+		case c == decimal:
+			b.WriteByte('.')
+		}
+	}
+	value := b.String()
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// FormatNumber writes v rounded to decimals digits the way the locale
+// does, with its decimal separator and the other one grouping thousands.
+func (l Locale) FormatNumber(v float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', max(decimals, 0), 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	decimal := byte('.')
+	if l.DecimalComma {
+		decimal = ','
+	}
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(other(decimal))
+		}
+		b.WriteByte(whole[i])
+	}
+	if frac != "" {
+		b.WriteByte(decimal)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+func other(sep byte) byte {
+	if sep == '.' {
+		return ','
+	}
+	return '.'
+}
+
+// Annotate appends the canonical slot values to query so the agents use them
+// instead of reinterpreting the original text. It returns query unchanged if
+// there are no slots.
+func Annotate(query string, slots []Slot) string {
+	if len(slots) == 0 {
+		return query
+	}
+
+	var b strings.Builder
+	b.WriteString(query)
+	b.WriteString("\n\n[Parsed values - use these canonical forms in SQL:")
+	for _, s := range slots {
+		fmt.Fprintf(&b, "\n- %q (%s) = %s", s.Text, s.Kind, s.Value)
+	}
+	b.WriteString("]")
+	return b.String()
+}
@@ -0,0 +1,166 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		tag          string
+		decimalComma bool
+		dayFirst     bool
+		wantErr      bool
+	}{
+		{"", false, false, false},
+		{"en-US", false, false, false},
+		{"en", false, false, false},
+		{"en-GB", false, true, false},
+		{"de-DE", true, true, false},
+		{"de_CH.UTF-8", false, true, false},
+		{"pt-BR", true, true, false},
+		{"fr", true, true, false},
+		{"1234", false, false, true},
+		{"-", false, false, true},
+		{"_", false, false, true},
+		{"--", false, false, true},
+		{".UTF-8", false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			loc, err := ParseLocale(tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLocale(%q) error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if loc.DecimalComma != tt.decimalComma || loc.DayFirst != tt.dayFirst {
+				t.Errorf("ParseLocale(%q) = %+v, want DecimalComma=%v DayFirst=%v",
+					tt.tag, loc, tt.decimalComma, tt.dayFirst)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	us := DefaultLocale
+	de, _ := ParseLocale("de-DE")
+
+	tests := []struct {
+		name   string
+		locale Locale
+		text   string
+		want   []Slot
+	}{
+		{
+			name:   "german number and date",
+			locale: de,
+			text:   "orders above 1.234,56 since 31/12/2024",
+			want: []Slot{
+				{Text: "1.234,56", Kind: KindNumber, Value: "1234.56"},
+				{Text: "31/12/2024", Kind: KindDate, Value: "2024-12-31"},
+			},
+		},
+		{
+			name:   "ambiguous grouping follows locale",
+			locale: de,
+			text:   "more than 1.234 items",
+			want:   []Slot{{Text: "1.234", Kind: KindNumber, Value: "1234"}},
+		},
+		{
+			name:   "ambiguous grouping in US locale",
+			locale: us,
+			text:   "more than 1,234 items",
+			want:   []Slot{{Text: "1,234", Kind: KindNumber, Value: "1234"}},
+		},
+		{
+			name:   "decimal comma is unambiguous",
+			locale: us,
+			text:   "price over 3,5",
+			want:   []Slot{{Text: "3,5", Kind: KindNumber, Value: "3.5"}},
+		},
+		{
+			name:   "repeated separators group",
+			locale: de,
+			text:   "revenue of 1,234,567.",
+			want:   []Slot{{Text: "1,234,567", Kind: KindNumber, Value: "1234567"}},
+		},
+		{
+			name:   "day first when unambiguous",
+			locale: us,
+			text:   "on 31/12/2024",
+			want:   []Slot{{Text: "31/12/2024", Kind: KindDate, Value: "2024-12-31"}},
+		},
+		{
+			name:   "ambiguous date follows locale",
+			locale: us,
+			text:   "on 03/04/2024",
+			want:   []Slot{{Text: "03/04/2024", Kind: KindDate, Value: "2024-03-04"}},
+		},
+		{
+			name:   "dotted german date",
+			locale: de,
+			text:   "am 03.04.24",
+			want:   []Slot{{Text: "03.04.24", Kind: KindDate, Value: "2024-04-03"}},
+		},
+		{
+			name:   "canonical values are skipped",
+			locale: de,
+			text:   "top 10 since 2024-12-31 above 3.5",
+			want:   nil,
+		},
+		{
+			name:   "invalid date ignored",
+			locale: us,
+			text:   "on 31/31/2024",
+			want:   nil,
+		},
+		{
+			name:   "version numbers ignored",
+			locale: us,
+			text:   "running 1.2.3.4",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.locale.Extract(tt.text)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		tag      string
+		v        float64
+		decimals int
+		want     string
+	}{
+		{"en-US", 1234567.891, 2, "1,234,567.89"},
+		{"de-DE", 1234567.891, 2, "1.234.567,89"},
+		{"de-DE", 999, 0, "999"},
+		{"fr", -1234.5, 1, "-1.234,5"},
+		{"en-US", -0.004, 2, "0.00"},
+		{"en-US", 1500, 0, "1,500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag+" "+tt.want, func(t *testing.T) {
+			loc, err := ParseLocale(tt.tag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := loc.FormatNumber(tt.v, tt.decimals); got != tt.want {
+				t.Errorf("FormatNumber(%v, %d) = %q, want %q", tt.v, tt.decimals, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package joins finds how tables relate through foreign keys, so the SQL
+// agent joins tables on their real keys instead of guessing them.
+package joins
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ForeignKey is a foreign key constraint from Table's Columns to
+// RefTable's RefColumns.
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+}
+
+// String describes the key, e.g. "orders(customer_id) -> customers(id)".
+func (k ForeignKey) String() string {
+	return fmt.Sprintf("%s(%s) -> %s(%s)", k.Table, strings.Join(k.Columns, ", "), k.RefTable, strings.Join(k.RefColumns, ", "))
+}
+
+// Step joins the table To to the table From along Key, which may point
+// either way. Alternatives lists the other keys between the same tables.
+type Step struct {
+	From         string
+	To           string
+	Key          ForeignKey
+	Alternatives []ForeignKey
+}
+
+// On returns the join condition of the step.
+func (s Step) On() string {
+	conds := make([]string, len(s.Key.Columns))
+	for i := range s.Key.Columns {
+		conds[i] = fmt.Sprintf("%s.%s = %s.%s", s.Key.Table, s.Key.Columns[i], s.Key.RefTable, s.Key.RefColumns[i])
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// Path returns the shortest chain of foreign keys from one table to
+// another, following keys in either direction. Among paths of equal length
+// the one through alphabetically first tables wins, so answers are stable.
+// Table names are matched case-insensitively.
+func Path(keys []ForeignKey, from, to string) ([]Step, error) {
+	from, to = strings.ToLower(from), strings.ToLower(to)
+
+	// edges maps each table to its neighbours and the keys reaching them
+	edges := make(map[string]map[string][]ForeignKey)
+	addEdge := func(a, b string, k ForeignKey) {
+		if edges[a] == nil {
+			edges[a] = make(map[string][]ForeignKey)
+		}
+		edges[a][b] = append(edges[a][b], k)
+	}
+	for _, k := range keys {
+		src, dst := strings.ToLower(k.Table), strings.ToLower(k.RefTable)
+		if src == dst {
+			// Self-references never shorten a path between two tables
+			continue
+		}
+		addEdge(src, dst, k)
+		addEdge(dst, src, k)
+	}
+
+	if edges[from] == nil {
+		return nil, fmt.Errorf("table %s has no foreign keys", from)
+	}
+	if edges[to] == nil {
+		return nil, fmt.Errorf("table %s has no foreign keys", to)
+	}
+	if from == to {
+		return nil, nil
+	}
+
+	// Breadth-first search from the start table
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 && prev[to] == "" {
+		table := queue[0]
+		queue = queue[1:]
+
+		neighbours := make([]string, 0, len(edges[table]))
+		for n := range edges[table] {
+			neighbours = append(neighbours, n)
+		}
+		slices.Sort(neighbours)
+		for _, n := range neighbours {
+			if _, seen := prev[n]; !seen {
+				prev[n] = table
+				queue = append(queue, n)
+			}
+		}
+	}
+	if _, ok := prev[to]; !ok {
+		return nil, fmt.Errorf("no foreign key path joins %s to %s", from, to)
+	}
+
+	var steps []Step
+	for table := to; table != from; table = prev[table] {
+		keys := edges[prev[table]][table]
+		steps = append(steps, Step{From: prev[table], To: table, Key: keys[0], Alternatives: keys[1:]})
+	}
+	slices.Reverse(steps)
+	return steps, nil
+}
+
+// Clause renders a path starting at from as a FROM ... JOIN skeleton.
+func Clause(from string, steps []Step) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "FROM %s", from)
+	for _, s := range steps {
+		fmt.Fprintf(&b, "\nJOIN %s ON %s", s.To, s.On())
+	}
+	return b.String()
+}
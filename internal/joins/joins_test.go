@@ -0,0 +1,95 @@
+package joins
+
+import (
+	"strings"
+	"testing"
+)
+
+// starSchema is a sales fact table with product, date and store
+// dimensions, a snowflaked category and a role-playing date dimension.
+var starSchema = []ForeignKey{
+	{Name: "fact_sales_product_fk", Table: "fact_sales", Columns: []string{"product_id"}, RefTable: "dim_product", RefColumns: []string{"id"}},
+	{Name: "fact_sales_date_fk", Table: "fact_sales", Columns: []string{"date_id"}, RefTable: "dim_date", RefColumns: []string{"id"}},
+	{Name: "fact_sales_ship_date_fk", Table: "fact_sales", Columns: []string{"ship_date_id"}, RefTable: "dim_date", RefColumns: []string{"id"}},
+	{Name: "fact_sales_store_fk", Table: "fact_sales", Columns: []string{"store_id", "region_id"}, RefTable: "dim_store", RefColumns: []string{"id", "region_id"}},
+	{Name: "dim_product_category_fk", Table: "dim_product", Columns: []string{"category_id"}, RefTable: "dim_category", RefColumns: []string{"id"}},
+	{Name: "dim_category_parent_fk", Table: "dim_category", Columns: []string{"parent_id"}, RefTable: "dim_category", RefColumns: []string{"id"}},
+	{Name: "audit_user_fk", Table: "audit_log", Columns: []string{"user_id"}, RefTable: "app_users", RefColumns: []string{"id"}},
+}
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    string
+		to      string
+		want    string
+		alts    int
+		wantErr string
+	}{
+		{
+			name: "direct key",
+			from: "fact_sales",
+			to:   "dim_product",
+			want: "FROM fact_sales\nJOIN dim_product ON fact_sales.product_id = dim_product.id",
+		},
+		{
+			name: "snowflake across the fact table",
+			from: "DIM_CATEGORY",
+			to:   "dim_store",
+			want: "FROM dim_category\n" +
+				"JOIN dim_product ON dim_product.category_id = dim_category.id\n" +
+				"JOIN fact_sales ON fact_sales.product_id = dim_product.id\n" +
+				"JOIN dim_store ON fact_sales.store_id = dim_store.id AND fact_sales.region_id = dim_store.region_id",
+		},
+		{
+			name: "role-playing dimension",
+			from: "fact_sales",
+			to:   "dim_date",
+			want: "FROM fact_sales\nJOIN dim_date ON fact_sales.date_id = dim_date.id",
+			alts: 1,
+		},
+		{
+			name: "same table",
+			from: "dim_date",
+			to:   "dim_date",
+			want: "FROM dim_date",
+		},
+		{
+			name:    "disconnected tables",
+			from:    "audit_log",
+			to:      "dim_date",
+			wantErr: "no foreign key path",
+		},
+		{
+			name:    "unknown table",
+			from:    "fact_sales",
+			to:      "customers",
+			wantErr: "customers has no foreign keys",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := Path(starSchema, tt.from, tt.to)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Path() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Path() error = %v", err)
+			}
+			if got := Clause(strings.ToLower(tt.from), steps); got != tt.want {
+				t.Errorf("Clause() =\n%s\nwant\n%s", got, tt.want)
+			}
+			alts := 0
+			for _, s := range steps {
+				alts += len(s.Alternatives)
+			}
+			if alts != tt.alts {
+				t.Errorf("alternatives = %d, want %d", alts, tt.alts)
+			}
+		})
+	}
+}
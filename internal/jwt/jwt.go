@@ -0,0 +1,272 @@
+// Package jwt verifies JSON Web Tokens signed with RS256 or ES256 by the
+// keys an OpenID provider publishes, discovered from its configuration.
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalid is wrapped by the errors for tokens that fail verification,
+// as opposed to failures to fetch the provider's keys.
+var ErrInvalid = errors.New("invalid token")
+
+// Verifier checks tokens against the signing keys of an OpenID provider.
+// Keys are fetched on first use and again when a token names an unknown
+// one.
+type Verifier struct {
+	// MetadataURL is the provider's OpenID configuration, whose jwks_uri
+	// lists the signing keys
+	MetadataURL string
+	// Algorithms are the accepted signing algorithms (defaults to RS256 and ES256)
+	Algorithms []string
+	// Skew is the leeway given to exp and nbf
+	Skew time.Duration
+	// MaxAge is how long fetched keys are used before being fetched again
+	// (0 = until a token names an unknown key)
+	MaxAge time.Duration
+	// Refresh is the least time between fetches, so tokens with unknown key
+	// IDs cannot hammer the provider
+	Refresh time.Duration
+	// Client fetches the keys (defaults to http.DefaultClient)
+	Client *http.Client
+	// Now returns the current time (defaults to time.Now)
+	Now func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// Claims are the payload of a verified token.
+type Claims map[string]any
+
+// String returns the string claim name, or "".
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// HasAudience reports whether the aud claim, a string or a list of strings,
+// includes audience.
+func (c Claims) HasAudience(audience string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Verify checks the token's signature against the provider's keys and that
+// it has not expired, and returns its claims. Tokens without an exp claim
+// are refused. The issuer, audience and other claims are left to the
+// caller.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalid)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: invalid token header: %v", ErrInvalid, err)
+	}
+	algorithms := v.Algorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"RS256", "ES256"}
+	}
+	if !slices.Contains(algorithms, header.Alg) {
+		return nil, fmt.Errorf("%w: unsupported signing algorithm %q", ErrInvalid, header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid token signature encoding: %v", ErrInvalid, err)
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !verifySignature(header.Alg, key, digest[:], sig) {
+		return nil, fmt.Errorf("%w: invalid token signature", ErrInvalid)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: invalid token claims: %v", ErrInvalid, err)
+	}
+	t := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%w: token has no expiry", ErrInvalid)
+	}
+	if t.After(time.Unix(int64(exp), 0).Add(v.Skew)) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalid)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && t.Add(v.Skew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("%w: token not yet valid", ErrInvalid)
+	}
+	return claims, nil
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+// key returns the provider's key with ID kid, fetching the key set if it is
+// not known yet or older than MaxAge.
+func (v *Verifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	t := v.now()
+	key, ok := v.keys[kid]
+	if ok && (v.MaxAge == 0 || t.Sub(v.fetched) < v.MaxAge) {
+		return key, nil
+	}
+	if !ok && !v.fetched.IsZero() && t.Sub(v.fetched) < v.Refresh {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalid, kid)
+	}
+	keys, err := v.fetchKeys(ctx)
+	v.fetched = t
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalid, kid)
+}
+
+// fetchKeys discovers the provider's JWKS URL and reads its signing keys.
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var metadata struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, v.MetadataURL, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to load OpenID metadata: %w", err)
+	}
+	if metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("OpenID metadata at %s publishes no jwks_uri", v.MetadataURL)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to load signing keys: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		// Keys of unsupported types or for encryption are skipped
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key := k.publicKey(); key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jwk is a JSON Web Key of type RSA or EC (P-256).
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() crypto.PublicKey {
+	switch k.Kty {
+	case "RSA":
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+			return nil
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+		y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+		if k.Crv != "P-256" || err1 != nil || err2 != nil {
+			return nil
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, digest, sig []byte) bool {
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, sig) == nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, digest, r, s)
+	}
+	return false
+}
+
+func decodeSegment(segment string, out any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,179 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// provider serves an OpenID configuration and key set with an RSA key
+// "rsa" and a P-256 key "ec", counting the key set fetches.
+type provider struct {
+	*httptest.Server
+	rsa     *rsa.PrivateKey
+	ec      *ecdsa.PrivateKey
+	fetches int
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+	p := &provider{}
+	var err error
+	if p.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	if p.ec, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": p.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		p.fetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kid": "rsa", "kty": "RSA", "use": "sig", "n": b64(p.rsa.N.Bytes()), "e": b64(big.NewInt(int64(p.rsa.E)).Bytes())},
+			{"kid": "ec", "kty": "EC", "crv": "P-256", "x": b64(p.ec.X.FillBytes(make([]byte, 32))), "y": b64(p.ec.Y.FillBytes(make([]byte, 32)))},
+			{"kid": "enc", "kty": "RSA", "use": "enc", "n": b64(p.rsa.N.Bytes()), "e": b64(big.NewInt(int64(p.rsa.E)).Bytes())},
+		}})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// token signs claims with the key named by kid, using alg.
+func (p *provider) token(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	var sig []byte
+	switch alg {
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, p.ec, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, p.rsa, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + b64(sig)
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestVerify(t *testing.T) {
+	p := newProvider(t)
+	v := &Verifier{MetadataURL: p.URL + "/.well-known/openid-configuration", Skew: time.Minute}
+	exp := float64(time.Now().Add(time.Hour).Unix())
+
+	tests := []struct {
+		name    string
+		alg     string
+		kid     string
+		claims  map[string]any
+		wantErr bool
+	}{
+		{name: "RS256", alg: "RS256", kid: "rsa", claims: map[string]any{"exp": exp, "sub": "alice"}},
+		{name: "ES256", alg: "ES256", kid: "ec", claims: map[string]any{"exp": exp, "sub": "alice"}},
+		{name: "within the skew", alg: "RS256", kid: "rsa", claims: map[string]any{"exp": float64(time.Now().Add(-30 * time.Second).Unix())}},
+		{name: "expired", alg: "RS256", kid: "rsa", claims: map[string]any{"exp": float64(time.Now().Add(-time.Hour).Unix())}, wantErr: true},
+		{name: "no expiry", alg: "RS256", kid: "rsa", claims: map[string]any{"sub": "alice"}, wantErr: true},
+		{name: "not yet valid", alg: "RS256", kid: "rsa", claims: map[string]any{"exp": exp, "nbf": float64(time.Now().Add(time.Hour).Unix())}, wantErr: true},
+		{name: "algorithm of another key", alg: "RS256", kid: "ec", claims: map[string]any{"exp": exp}, wantErr: true},
+		{name: "encryption key", alg: "RS256", kid: "enc", claims: map[string]any{"exp": exp}, wantErr: true},
+		{name: "unsupported algorithm", alg: "HS256", kid: "rsa", claims: map[string]any{"exp": exp}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), p.token(t, tt.alg, tt.kid, tt.claims))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Errorf("Verify() error = %v, want ErrInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims["exp"] != tt.claims["exp"] || claims.String("sub") != Claims(tt.claims).String("sub") {
+				t.Errorf("Verify() = %v, want %v", claims, tt.claims)
+			}
+		})
+	}
+
+	if _, err := v.Verify(context.Background(), "not.a-token"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify(malformed) error = %v, want ErrInvalid", err)
+	}
+	rs := &Verifier{MetadataURL: v.MetadataURL, Algorithms: []string{"RS256"}}
+	if _, err := rs.Verify(context.Background(), p.token(t, "ES256", "ec", map[string]any{"exp": exp})); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify(ES256) with only RS256 accepted: error = %v, want ErrInvalid", err)
+	}
+}
+
+func TestVerifyKeyRefresh(t *testing.T) {
+	p := newProvider(t)
+	clock := time.Now()
+	v := &Verifier{
+		MetadataURL: p.URL + "/.well-known/openid-configuration",
+		MaxAge:      time.Hour,
+		Refresh:     time.Minute,
+		Now:         func() time.Time { return clock },
+	}
+	valid := p.token(t, "RS256", "rsa", map[string]any{"exp": float64(clock.Add(2 * time.Hour).Unix())})
+	unknown := p.token(t, "RS256", "other", map[string]any{"exp": float64(clock.Add(2 * time.Hour).Unix())})
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		token   string
+		fetches int
+	}{
+		{name: "first use", token: valid, fetches: 1},
+		{name: "known key", advance: time.Minute, token: valid, fetches: 1},
+		{name: "unknown key", token: unknown, fetches: 2},
+		{name: "unknown key again", advance: 30 * time.Second, token: unknown, fetches: 2},
+		{name: "unknown key after the refresh interval", advance: time.Minute, token: unknown, fetches: 3},
+		{name: "known key past its age", advance: time.Hour, token: valid, fetches: 4},
+	}
+	for _, s := range steps {
+		clock = clock.Add(s.advance)
+		_, err := v.Verify(context.Background(), s.token)
+		if (err != nil) != (s.token == unknown) {
+			t.Errorf("%s: Verify() error = %v", s.name, err)
+		}
+		if p.fetches != s.fetches {
+			t.Errorf("%s: %d key fetches, want %d", s.name, p.fetches, s.fetches)
+		}
+	}
+}
+
+func TestVerifyProviderDown(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	v := &Verifier{MetadataURL: srv.URL + "/.well-known/openid-configuration"}
+	token := newProvider(t).token(t, "RS256", "rsa", map[string]any{"exp": float64(time.Now().Add(time.Hour).Unix())})
+	if _, err := v.Verify(context.Background(), token); err == nil || errors.Is(err, ErrInvalid) {
+		t.Errorf("Verify() error = %v, want a fetch error that is not ErrInvalid", err)
+	}
+}
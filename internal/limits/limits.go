@@ -0,0 +1,229 @@
+// Package limits bounds how many database queries run at once, globally and
+// per session, and how many start per second, so parallel workflows and
+// many users cannot overwhelm the database. Queries over the limit wait in
+// line for a slot.
+package limits
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/joins"
+	"github.com/anuvratrastogi/multi-agent/internal/ratelimit"
+)
+
+// Scopes reported to a WaitHandler.
+const (
+	ScopeSession = "session"
+	ScopeGlobal  = "global"
+	ScopeRate    = "rate"
+)
+
+// Config holds configuration for the limiter. Zero means unlimited.
+type Config struct {
+	// Global is the maximum number of concurrent queries overall
+	Global int
+	// PerSession is the maximum number of concurrent queries per session
+	PerSession int
+	// QueriesPerSecond is the maximum number of database calls started per
+	// second overall
+	QueriesPerSecond int
+}
+
+// WaitHandler is called when a query has to wait for a slot in scope, or
+// for its turn under the rate limit with ScopeRate.
+type WaitHandler func(scope string)
+
+type waitHandlerKey struct{}
+
+// WithWaitHandler returns a context in which h is told when queries wait.
+func WithWaitHandler(ctx context.Context, h WaitHandler) context.Context {
+	return context.WithValue(ctx, waitHandlerKey{}, h)
+}
+
+// sessionSlots is a session's semaphore, removed once no query holds or
+// awaits it.
+type sessionSlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+// Client wraps an MCPClient and limits concurrent database calls.
+type Client struct {
+	sqlagent.MCPClient
+	global     chan struct{}
+	perSession int
+	rate       *ratelimit.Limiter
+
+	mu       sync.Mutex
+	sessions map[string]*sessionSlots
+}
+
+// NewClient creates a client that limits concurrent calls to inner.
+func NewClient(inner sqlagent.MCPClient, cfg Config) *Client {
+	c := &Client{
+		MCPClient:  inner,
+		perSession: cfg.PerSession,
+		rate:       ratelimit.New(cfg.QueriesPerSecond, time.Second),
+		sessions:   make(map[string]*sessionSlots),
+	}
+	if cfg.Global > 0 {
+		c.global = make(chan struct{}, cfg.Global)
+	}
+	return c
+}
+
+// Query executes the query once a slot is available.
+func (c *Client) Query(ctx context.Context, query string, limit int) (string, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return c.MCPClient.Query(ctx, query, limit)
+}
+
+// GetSchema returns the table schema once a slot is available.
+func (c *Client) GetSchema(ctx context.Context, tableName string) (string, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return c.MCPClient.GetSchema(ctx, tableName)
+}
+
+// ListTables lists tables once a slot is available.
+func (c *Client) ListTables(ctx context.Context) (string, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return c.MCPClient.ListTables(ctx)
+}
+
+// DescribeDatabase describes the database once a slot is available.
+func (c *Client) DescribeDatabase(ctx context.Context) (string, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return c.MCPClient.DescribeDatabase(ctx)
+}
+
+// ForeignKeys lists foreign keys once a slot is available.
+func (c *Client) ForeignKeys(ctx context.Context) ([]joins.ForeignKey, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.MCPClient.ForeignKeys(ctx)
+}
+
+// acquire waits for its turn under the rate limit, then takes a session
+// slot and then a global slot, so a session waiting on its own limit never
+// holds a global slot.
+func (c *Client) acquire(ctx context.Context) (func(), error) {
+	err := c.rate.Wait(ctx, 1, func(wait time.Duration) {
+		events.Publish(ctx, &events.RateLimited{
+			Resource: events.ResourceDatabase,
+			Limit:    "queries per second",
+			WaitMS:   wait.Milliseconds(),
+		})
+		if h, ok := ctx.Value(waitHandlerKey{}).(WaitHandler); ok {
+			h(ScopeRate)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var key string
+	if userID, sessionID, ok := sqlagent.SessionFrom(ctx); ok {
+		key = userID + "/" + sessionID
+	}
+
+	var session *sessionSlots
+	if c.perSession > 0 && key != "" {
+		session = c.session(key)
+		if err := wait(ctx, session.sem, ScopeSession); err != nil {
+			c.releaseSession(key, session, false)
+			return nil, err
+		}
+	}
+
+	if c.global != nil {
+		if err := wait(ctx, c.global, ScopeGlobal); err != nil {
+			if session != nil {
+				c.releaseSession(key, session, true)
+			}
+			return nil, err
+		}
+	}
+
+	return func() {
+		if c.global != nil {
+			<-c.global
+		}
+		if session != nil {
+			c.releaseSession(key, session, true)
+		}
+	}, nil
+}
+
+// session returns the semaphore for key, taking a reference to it.
+func (c *Client) session(key string) *sessionSlots {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.sessions[key]
+	if !ok {
+		s = &sessionSlots{sem: make(chan struct{}, c.perSession)}
+		c.sessions[key] = s
+	}
+	s.refs++
+	return s
+}
+
+// releaseSession drops a reference to a session's semaphore, freeing its
+// slot if held.
+func (c *Client) releaseSession(key string, s *sessionSlots, held bool) {
+	if held {
+		<-s.sem
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.refs--
+	if s.refs == 0 {
+		delete(c.sessions, key)
+	}
+}
+
+// wait takes a slot from sem, reporting the wait if none is free.
+func wait(ctx context.Context, sem chan struct{}, scope string) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	default:
+	}
+
+	console.Printf("  ⏳ [SQL] Waiting for a database slot (%s limit reached)\n", scope)
+	if h, ok := ctx.Value(waitHandlerKey{}).(WaitHandler); ok {
+		h(scope)
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,128 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+)
+
+// slowClient records the peak number of concurrent queries.
+type slowClient struct {
+	sqlagent.MCPClient
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (c *slowClient) Query(ctx context.Context, query string, limit int) (string, error) {
+	n := c.running.Add(1)
+	defer c.running.Add(-1)
+	for {
+		p := c.peak.Load()
+		if n <= p || c.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return "[]", nil
+}
+
+func TestClientLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		sessions int
+		queries  int
+		wantPeak int32
+	}{
+		{"global limit", Config{Global: 3}, 1, 10, 3},
+		{"per-session limit", Config{PerSession: 1}, 2, 10, 2},
+		{"session limit below global", Config{Global: 4, PerSession: 1}, 3, 9, 3},
+		{"global limit below sessions", Config{Global: 2, PerSession: 2}, 3, 9, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &slowClient{}
+			c := NewClient(inner, tt.cfg)
+
+			var wg sync.WaitGroup
+			for i := 0; i < tt.queries; i++ {
+				ctx := sqlagent.WithSession(context.Background(), "u", string(rune('a'+i%tt.sessions)))
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := c.Query(ctx, "SELECT 1", 1); err != nil {
+						t.Errorf("Query() error = %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := inner.peak.Load(); got > tt.wantPeak {
+				t.Errorf("peak concurrency = %d, want at most %d", got, tt.wantPeak)
+			}
+			if len(c.sessions) != 0 {
+				t.Errorf("%d session semaphores leaked", len(c.sessions))
+			}
+		})
+	}
+}
+
+func TestClientWaitCancelled(t *testing.T) {
+	c := NewClient(&slowClient{}, Config{Global: 1})
+	release, err := c.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	defer release()
+
+	var waited string
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ctx = WithWaitHandler(ctx, func(scope string) { waited = scope })
+
+	if _, err := c.Query(ctx, "SELECT 1", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Query() error = %v, want deadline exceeded", err)
+	}
+	if waited != ScopeGlobal {
+		t.Errorf("wait handler scope = %q, want %q", waited, ScopeGlobal)
+	}
+}
+
+// tablesClient lists tables instantly.
+type tablesClient struct {
+	sqlagent.MCPClient
+}
+
+func (tablesClient) ListTables(ctx context.Context) (string, error) {
+	return "orders", nil
+}
+
+func TestClientRate(t *testing.T) {
+	c := NewClient(tablesClient{}, Config{QueriesPerSecond: 20})
+	var waits atomic.Int32
+	ctx := WithWaitHandler(context.Background(), func(scope string) {
+		if scope == ScopeRate {
+			waits.Add(1)
+		}
+	})
+
+	// The first 20 queries start at once, the next 10 one every 50ms
+	start := time.Now()
+	for i := 0; i < 30; i++ {
+		if _, err := c.ListTables(ctx); err != nil {
+			t.Fatalf("ListTables() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("30 queries took %v, want at least 450ms at 20 per second", elapsed)
+	}
+	if got := waits.Load(); got != 10 {
+		t.Errorf("rate waits = %d, want 10", got)
+	}
+}
@@ -0,0 +1,193 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// ErrCacheMiss is returned in replay mode for requests that were not
+// recorded.
+var ErrCacheMiss = errors.New("no recorded response for this request")
+
+// Cache is a model.LLM that stores responses by a hash of the request, so
+// identical requests in CI, tests and demos do not reach the backend again.
+type Cache struct {
+	llm  model.LLM
+	mode config.LLMCacheMode
+	dir  string
+
+	mu sync.Mutex
+	// entries holds the encoded responses when dir is empty
+	entries map[string][]byte
+}
+
+// cacheEntry is the stored form of a request's responses.
+type cacheEntry struct {
+	Model     string               `json:"model"`
+	Stream    bool                 `json:"stream"`
+	Responses []*model.LLMResponse `json:"responses"`
+}
+
+// NewCache wraps llm with a cache in the given mode. Responses are kept in
+// memory, or as one JSON file per request in dir.
+func NewCache(llm model.LLM, mode config.LLMCacheMode, dir string) (*Cache, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+	return &Cache{llm: llm, mode: mode, dir: dir, entries: make(map[string][]byte)}, nil
+}
+
+// Name implements model.LLM.
+func (c *Cache) Name() string {
+	return c.llm.Name()
+}
+
+// GenerateContent implements model.LLM. Failed or abandoned generations are
+// not stored.
+func (c *Cache) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		key, err := CacheKey(c.Name(), req, stream)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		if c.mode != config.LLMCacheRecord {
+			responses, err := c.load(key)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if responses != nil {
+				for _, resp := range responses {
+					if !yield(resp, nil) {
+						return
+					}
+				}
+				return
+			}
+			if c.mode == config.LLMCacheReplay {
+				yield(nil, fmt.Errorf("%w (key %s); record it with LLM_CACHE=record", ErrCacheMiss, key))
+				return
+			}
+		}
+
+		var responses []*model.LLMResponse
+		for resp, err := range c.llm.GenerateContent(ctx, req, stream) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			responses = append(responses, resp)
+			if !yield(resp, nil) {
+				return
+			}
+		}
+		if err := c.store(key, cacheEntry{Model: c.Name(), Stream: stream, Responses: responses}); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// load returns a fresh copy of the responses stored under key, or nil.
+func (c *Cache) load(key string) ([]*model.LLMResponse, error) {
+	var data []byte
+	if c.dir == "" {
+		c.mu.Lock()
+		data = c.entries[key]
+		c.mu.Unlock()
+	} else {
+		var err error
+		data, err = os.ReadFile(filepath.Join(c.dir, key+".json"))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cached response: %w", err)
+		}
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("failed to decode cached response %s: %w", key, err)
+	}
+	return entry.Responses, nil
+}
+
+func (c *Cache) store(key string, entry cacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json error: %w", err)
+	}
+	if c.dir == "" {
+		c.mu.Lock()
+		c.entries[key] = data
+		c.mu.Unlock()
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(c.dir, key+".json"), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cached response: %w", err)
+	}
+	return nil
+}
+
+// CacheKey hashes everything that determines a model's response: the model
+// name, the conversation, the generation config (including the system
+// instruction and tool declarations) and whether the response is streamed.
+// Function call IDs, which are generated afresh on every run, are ignored.
+func CacheKey(modelName string, req *model.LLMRequest, stream bool) (string, error) {
+	contents := make([]*genai.Content, 0, len(req.Contents))
+	for _, content := range req.Contents {
+		if content == nil {
+			continue
+		}
+		normalized := &genai.Content{Role: content.Role}
+		for _, part := range content.Parts {
+			if part == nil {
+				continue
+			}
+			p := *part
+			if p.FunctionCall != nil {
+				call := *p.FunctionCall
+				call.ID = ""
+				p.FunctionCall = &call
+			}
+			if p.FunctionResponse != nil {
+				resp := *p.FunctionResponse
+				resp.ID = ""
+				p.FunctionResponse = &resp
+			}
+			normalized.Parts = append(normalized.Parts, &p)
+		}
+		contents = append(contents, normalized)
+	}
+
+	data, err := json.Marshal(struct {
+		Model    string                       `json:"model"`
+		Contents []*genai.Content             `json:"contents"`
+		Config   *genai.GenerateContentConfig `json:"config"`
+		Stream   bool                         `json:"stream"`
+	}{modelName, contents, req.Config, stream})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// countingLLM answers every request with the same text and counts calls.
+type countingLLM struct {
+	calls int
+}
+
+func (m *countingLLM) Name() string { return "counting" }
+
+func (m *countingLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	m.calls++
+	return func(yield func(*model.LLMResponse, error) bool) {
+		yield(&model.LLMResponse{Content: genai.NewContentFromText("42", genai.RoleModel), TurnComplete: true}, nil)
+	}
+}
+
+func request(callID string) *model.LLMRequest {
+	return &model.LLMRequest{Contents: []*genai.Content{
+		genai.NewContentFromText("How many orders?", genai.RoleUser),
+		{Role: genai.RoleModel, Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{ID: callID, Name: "query_database"}}}},
+	}}
+}
+
+func generate(t *testing.T, llm model.LLM, req *model.LLMRequest) (string, error) {
+	t.Helper()
+	var text string
+	for resp, err := range llm.GenerateContent(context.Background(), req, false) {
+		if err != nil {
+			return "", err
+		}
+		text += resp.Content.Parts[0].Text
+	}
+	return text, nil
+}
+
+func TestCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      config.LLMCacheMode
+		dir       bool
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "memory", mode: config.LLMCacheOn, wantCalls: 1},
+		{name: "disk", mode: config.LLMCacheOn, dir: true, wantCalls: 1},
+		{name: "record always calls the model", mode: config.LLMCacheRecord, dir: true, wantCalls: 2},
+		{name: "replay fails on a miss", mode: config.LLMCacheReplay, dir: true, wantErr: ErrCacheMiss},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dir string
+			if tt.dir {
+				dir = t.TempDir()
+			}
+			inner := &countingLLM{}
+			cache, err := NewCache(inner, tt.mode, dir)
+			if err != nil {
+				t.Fatalf("NewCache() error = %v", err)
+			}
+
+			// Function call IDs differ between runs but must hit the same entry
+			for _, id := range []string{"adk-1", "adk-2"} {
+				text, err := generate(t, cache, request(id))
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GenerateContent() error = %v, want %v", err, tt.wantErr)
+				}
+				if err == nil && text != "42" {
+					t.Errorf("GenerateContent() = %q, want 42", text)
+				}
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("model called %d times, want %d", inner.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCacheReplaysRecording(t *testing.T) {
+	dir := t.TempDir()
+	recorder, _ := NewCache(&countingLLM{}, config.LLMCacheRecord, dir)
+	if _, err := generate(t, recorder, request("a")); err != nil {
+		t.Fatalf("record error = %v", err)
+	}
+
+	inner := &countingLLM{}
+	replayer, _ := NewCache(inner, config.LLMCacheReplay, dir)
+	text, err := generate(t, replayer, request("b"))
+	if err != nil || text != "42" {
+		t.Fatalf("replay = %q, %v; want 42", text, err)
+	}
+	if inner.calls != 0 {
+		t.Errorf("replay called the model %d times", inner.calls)
+	}
+}
@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	"github.com/anuvratrastogi/multi-agent/pkg/localllm"
+)
+
+// Capabilities describe what a model can do.
+type Capabilities struct {
+	// ContextWindow is the context window in tokens. Local servers may be
+	// started with a smaller one.
+	ContextWindow int
+	// ToolCalling is native function calling, which the agents need to
+	// query the database unless tools are described in the prompt
+	ToolCalling bool
+	// JSONMode is output constrained to JSON when a request asks for it
+	JSONMode bool
+	// Vision is images in user messages
+	Vision bool
+	// Streaming is responses streamed as they are generated
+	Streaming bool
+}
+
+// modelCaps are the capabilities of the models whose names start with
+// prefix, compared by squeeze.
+type modelCaps struct {
+	prefix string
+	caps   Capabilities
+}
+
+var (
+	capsAll    = Capabilities{ToolCalling: true, JSONMode: true, Vision: true, Streaming: true}
+	capsTools  = Capabilities{ToolCalling: true, JSONMode: true, Streaming: true}
+	capsVision = Capabilities{JSONMode: true, Vision: true, Streaming: true}
+	capsText   = Capabilities{JSONMode: true, Streaming: true}
+)
+
+// window returns c with a context window of n tokens.
+func (c Capabilities) window(n int) Capabilities {
+	c.ContextWindow = n
+	return c
+}
+
+var geminiModels = []modelCaps{
+	{"gemini-2.5-pro", capsAll.window(1048576)},
+	{"gemini-2.5-flash", capsAll.window(1048576)},
+	{"gemini-2.0-flash", capsAll.window(1048576)},
+	{"gemini-1.5-pro", capsAll.window(2097152)},
+	{"gemini-1.5-flash", capsAll.window(1048576)},
+}
+
+var openAIModels = []modelCaps{
+	{"gpt-4.1", capsAll.window(1047576)},
+	{"gpt-4o", capsAll.window(128000)},
+	{"gpt-4-turbo", capsAll.window(128000)},
+	{"gpt-4-1106", capsTools.window(128000)},
+	{"gpt-4-0125", capsTools.window(128000)},
+	{"gpt-4", Capabilities{ToolCalling: true, Streaming: true, ContextWindow: 8192}},
+	{"gpt-3.5-turbo", capsTools.window(16385)},
+	{"gpt-35-turbo", capsTools.window(16385)},
+	{"o1", capsAll.window(200000)},
+	{"o3-mini", capsTools.window(200000)},
+	{"o3", capsAll.window(200000)},
+	{"o4-mini", capsAll.window(200000)},
+}
+
+// openModels are open-weight models as served by Ollama, vLLM, LM Studio
+// and the like, by their Ollama and Hugging Face names. Tool calling
+// depends on the chat template the server applies.
+var openModels = []modelCaps{
+	{"llama3.3", capsTools.window(131072)},
+	{"llama3.2-vision", capsVision.window(131072)},
+	{"llama-3.2-11b-vision", capsVision.window(131072)},
+	{"llama-3.2-90b-vision", capsVision.window(131072)},
+	{"llama3.2", capsTools.window(131072)},
+	{"llama3.1", capsTools.window(131072)},
+	{"llama3", capsText.window(8192)},
+	{"llama2", Capabilities{Streaming: true, ContextWindow: 4096}},
+	{"codellama", Capabilities{Streaming: true, ContextWindow: 16384}},
+	{"tinyllama", Capabilities{Streaming: true, ContextWindow: 2048}},
+	{"qwen3", capsTools.window(32768)},
+	{"qwen2.5-vl", capsVision.window(32768)},
+	{"qwen2-vl", capsVision.window(32768)},
+	{"qwen2.5", capsTools.window(32768)},
+	{"qwen2", capsTools.window(32768)},
+	{"mistral-nemo", capsTools.window(131072)},
+	{"mistral-small", capsTools.window(32768)},
+	{"mistral", capsTools.window(32768)},
+	{"mixtral", capsTools.window(32768)},
+	{"phi4-mini", capsTools.window(131072)},
+	{"phi4", capsText.window(16384)},
+	{"phi3", capsText.window(4096)},
+	{"gemma3", capsVision.window(131072)},
+	{"gemma2", capsText.window(8192)},
+	{"gemma", capsText.window(8192)},
+	{"deepseek-r1", capsText.window(131072)},
+	{"deepseek-v3", capsTools.window(131072)},
+	{"llava", capsVision.window(4096)},
+	{"hermes3", capsTools.window(131072)},
+	{"command-r", capsTools.window(131072)},
+}
+
+// registry lists the known models of each provider. Servers behind the
+// local provider may also be gateways to OpenAI.
+var registry = map[config.LLMProvider][]modelCaps{
+	config.LLMProviderGemini: geminiModels,
+	config.LLMProviderAzure:  openAIModels,
+	config.LLMProviderLocal:  slices.Concat(openModels, openAIModels),
+}
+
+// LookupCapabilities returns the capabilities of the named model of the
+// provider, and whether the model is known. Names match the longest known
+// prefix, ignoring case, separators, an Ollama tag and any organization or
+// family in front of it, so "llama3.1:8b" and
+// "meta-llama/Meta-Llama-3.1-8B-Instruct" are both llama3.1.
+func LookupCapabilities(provider config.LLMProvider, modelName string) (Capabilities, bool) {
+	name := strings.ToLower(modelName)
+	name, _, _ = strings.Cut(name, ":")
+
+	var best *modelCaps
+	for i := range len(name) {
+		if i > 0 && !strings.ContainsRune("/-_ ", rune(name[i-1])) {
+			continue
+		}
+		rest := squeeze(name[i:])
+		for j, m := range registry[provider] {
+			if strings.HasPrefix(rest, squeeze(m.prefix)) && (best == nil || len(squeeze(m.prefix)) > len(squeeze(best.prefix))) {
+				best = &registry[provider][j]
+			}
+		}
+	}
+	if best == nil {
+		return Capabilities{}, false
+	}
+	return best.caps, true
+}
+
+// squeeze drops the separators of a model name, which are used
+// inconsistently: "llama3.1", "llama-3.1" and "Llama-3_1".
+func squeeze(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune("-_. ", r) {
+			return -1
+		}
+		return r
+	}, name)
+}
+
+// ToolCalling returns how the named model is given tools: as configured by
+// LocalLLMToolCalling, or else in the prompt for known models of an
+// OpenAI-compatible provider that cannot call tools natively.
+func ToolCalling(cfg *config.Config, modelName string) localllm.ToolCalling {
+	if cfg.LocalLLMToolCalling != "" {
+		return localllm.ToolCalling(cfg.LocalLLMToolCalling)
+	}
+	if caps, ok := LookupCapabilities(cfg.LLMProvider, modelName); ok && !caps.ToolCalling && cfg.IsOpenAICompatible() {
+		return localllm.ToolCallingPrompt
+	}
+	return localllm.ToolCallingNative
+}
@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	"github.com/anuvratrastogi/multi-agent/pkg/localllm"
+)
+
+func TestLookupCapabilities(t *testing.T) {
+	tests := []struct {
+		provider   config.LLMProvider
+		model      string
+		wantKnown  bool
+		wantTools  bool
+		wantVision bool
+		wantWindow int
+	}{
+		{provider: config.LLMProviderGemini, model: "gemini-2.0-flash", wantKnown: true, wantTools: true, wantVision: true, wantWindow: 1048576},
+		{provider: config.LLMProviderGemini, model: "gemini-1.5-pro-002", wantKnown: true, wantTools: true, wantVision: true, wantWindow: 2097152},
+		{provider: config.LLMProviderAzure, model: "gpt-4o-mini", wantKnown: true, wantTools: true, wantVision: true, wantWindow: 128000},
+		{provider: config.LLMProviderAzure, model: "gpt-35-turbo", wantKnown: true, wantTools: true, wantWindow: 16385},
+		{provider: config.LLMProviderLocal, model: "llama3.1:8b", wantKnown: true, wantTools: true, wantWindow: 131072},
+		{provider: config.LLMProviderLocal, model: "meta-llama/Meta-Llama-3.1-8B-Instruct", wantKnown: true, wantTools: true, wantWindow: 131072},
+		{provider: config.LLMProviderLocal, model: "llama3.2-vision:11b", wantKnown: true, wantVision: true, wantWindow: 131072},
+		{provider: config.LLMProviderLocal, model: "Qwen/Qwen2.5-7B-Instruct", wantKnown: true, wantTools: true, wantWindow: 32768},
+		{provider: config.LLMProviderLocal, model: "phi3:mini", wantKnown: true, wantWindow: 4096},
+		{provider: config.LLMProviderLocal, model: "gpt-4o", wantKnown: true, wantTools: true, wantVision: true, wantWindow: 128000},
+		{provider: config.LLMProviderLocal, model: "local-model"},
+		{provider: config.LLMProviderAzure, model: "llama3.1"},
+		{provider: config.LLMProviderMock, model: "gemini-2.0-flash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.provider)+"/"+tt.model, func(t *testing.T) {
+			caps, known := LookupCapabilities(tt.provider, tt.model)
+			if known != tt.wantKnown || caps.ToolCalling != tt.wantTools || caps.Vision != tt.wantVision || caps.ContextWindow != tt.wantWindow {
+				t.Errorf("LookupCapabilities() = %+v, %v; want tools %v, vision %v, window %d, known %v",
+					caps, known, tt.wantTools, tt.wantVision, tt.wantWindow, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestToolCalling(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.Config
+		model string
+		want  localllm.ToolCalling
+	}{
+		{name: "capable model", cfg: config.Config{LLMProvider: config.LLMProviderLocal}, model: "qwen2.5:7b", want: localllm.ToolCallingNative},
+		{name: "unknown model", cfg: config.Config{LLMProvider: config.LLMProviderLocal}, model: "my-finetune", want: localllm.ToolCallingNative},
+		{name: "model without tools", cfg: config.Config{LLMProvider: config.LLMProviderLocal}, model: "gemma2:2b", want: localllm.ToolCallingPrompt},
+		{name: "configured", cfg: config.Config{LLMProvider: config.LLMProviderLocal, LocalLLMToolCalling: "native"}, model: "gemma2:2b", want: localllm.ToolCallingNative},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToolCalling(&tt.cfg, tt.model); got != tt.want {
+				t.Errorf("ToolCalling() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// Compaction defaults.
+const (
+	DefaultCompactAt = 0.8
+	DefaultKeepTurns = 4
+)
+
+// CompactConfig holds configuration for Compact.
+type CompactConfig struct {
+	// Limit is the model's context window in tokens
+	Limit int
+	// At is the fraction of Limit at which older messages are summarized
+	// (defaults to DefaultCompactAt)
+	At float64
+	// KeepTurns is the number of recent user messages kept verbatim, with
+	// everything after them (defaults to DefaultKeepTurns)
+	KeepTurns int
+}
+
+// Compact wraps llm so that prompts approaching its context window are
+// shortened: the messages before the last KeepTurns user messages,
+// tool calls and results included, are replaced by a summary the model
+// writes. Summaries are reused by later prompts of the same conversation,
+// which extend them with the messages that have aged out since. If
+// summarizing fails, the prompt is sent unchanged.
+func Compact(llm model.LLM, cfg CompactConfig) model.LLM {
+	if cfg.At <= 0 || cfg.At > 1 {
+		cfg.At = DefaultCompactAt
+	}
+	if cfg.KeepTurns <= 0 {
+		cfg.KeepTurns = DefaultKeepTurns
+	}
+	return &compactor{llm: llm, cfg: cfg, notes: make(map[string]string)}
+}
+
+// maxNotes bounds the summaries a compactor remembers.
+const maxNotes = 256
+
+type compactor struct {
+	llm model.LLM
+	cfg CompactConfig
+
+	mu sync.Mutex
+	// notes holds summaries by the hash of the messages they cover
+	notes map[string]string
+	// order lists the keys of notes, oldest first
+	order []string
+}
+
+// Name implements model.LLM.
+func (c *compactor) Name() string {
+	return c.llm.Name()
+}
+
+// GenerateContent implements model.LLM.
+func (c *compactor) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	compacted, err := c.compact(ctx, req)
+	if err != nil {
+		log.Printf("⚠️  Failed to compact conversation history: %v", err)
+		compacted = req
+	}
+	return c.llm.GenerateContent(ctx, compacted, stream)
+}
+
+// summaryPrefix introduces the summary in place of the older messages.
+const summaryPrefix = "[Summary of the earlier conversation]\n"
+
+// compact returns req with its older messages summarized if its estimated
+// size reaches the compaction threshold, or req itself.
+func (c *compactor) compact(ctx context.Context, req *model.LLMRequest) (*model.LLMRequest, error) {
+	before := estimateTokens(req)
+	if float64(before) < c.cfg.At*float64(c.cfg.Limit) {
+		return req, nil
+	}
+	cut := cutPoint(req.Contents, c.cfg.KeepTurns)
+	if cut == 0 {
+		return req, nil
+	}
+
+	// Extend the summary of the longest prefix summarized before
+	hashes := prefixHashes(req.Contents[:cut])
+	from, note := 0, ""
+	for i := cut; i > 0; i-- {
+		if n, ok := c.note(hashes[i-1]); ok {
+			from, note = i, n
+			break
+		}
+	}
+	if from < cut {
+		var err error
+		note, err = c.summarize(ctx, req, note, req.Contents[from:cut])
+		if err != nil {
+			return nil, err
+		}
+		c.store(hashes[cut-1], note)
+	}
+
+	out := *req
+	out.Contents = append([]*genai.Content{genai.NewContentFromText(summaryPrefix+note, genai.RoleUser)}, req.Contents[cut:]...)
+	if from < cut {
+		events.Publish(ctx, &events.HistoryCompacted{
+			Model:        c.llm.Name(),
+			Messages:     cut,
+			TokensBefore: before,
+			TokensAfter:  estimateTokens(&out),
+		})
+	}
+	return &out, nil
+}
+
+// summaryInstruction asks the model for the summary.
+const summaryInstruction = `You compress conversations between a user and data analysis agents into a memory note the agents will read instead of the original messages.
+Keep: the user's questions and goals, the tables, columns, filters and SQL used, the key numbers in the results, charts made, and any decisions, assumptions or open follow-ups.
+Drop: greetings, raw result rows beyond the key figures, and repetition.
+If an earlier note is given, merge it with the new messages into one note.
+Reply with the note only, as short bullet points.`
+
+// maxToolResultChars truncates tool results in the transcript to summarize.
+const maxToolResultChars = 2000
+
+// summarize asks the model to fold messages into the earlier note.
+func (c *compactor) summarize(ctx context.Context, req *model.LLMRequest, note string, messages []*genai.Content) (string, error) {
+	var b strings.Builder
+	if note != "" {
+		b.WriteString("Earlier note:\n" + note + "\n\n")
+	}
+	b.WriteString("Messages:\n")
+	for _, m := range messages {
+		writeMessage(&b, m)
+	}
+
+	sreq := &model.LLMRequest{
+		Model:    req.Model,
+		Contents: []*genai.Content{genai.NewContentFromText(b.String(), genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(summaryInstruction, genai.RoleUser),
+		},
+	}
+	var summary strings.Builder
+	for resp, err := range c.llm.GenerateContent(ctx, sreq, false) {
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize history: %w", err)
+		}
+		if resp == nil || resp.Content == nil {
+			continue
+		}
+		for _, p := range resp.Content.Parts {
+			if !p.Thought {
+				summary.WriteString(p.Text)
+			}
+		}
+	}
+	if strings.TrimSpace(summary.String()) == "" {
+		return "", fmt.Errorf("failed to summarize history: the model returned no summary")
+	}
+	return strings.TrimSpace(summary.String()), nil
+}
+
+// writeMessage writes a message as transcript lines.
+func writeMessage(b *strings.Builder, m *genai.Content) {
+	if m == nil {
+		return
+	}
+	for _, p := range m.Parts {
+		switch {
+		case p.FunctionCall != nil:
+			args, _ := json.Marshal(p.FunctionCall.Args)
+			fmt.Fprintf(b, "%s called %s(%s)\n", m.Role, p.FunctionCall.Name, args)
+		case p.FunctionResponse != nil:
+			result, _ := json.Marshal(p.FunctionResponse.Response)
+			text := string(result)
+			if len(text) > maxToolResultChars {
+				text = text[:maxToolResultChars] + "…"
+			}
+			fmt.Fprintf(b, "%s returned %s\n", p.FunctionResponse.Name, text)
+		case p.Text != "" && !p.Thought:
+			fmt.Fprintf(b, "%s: %s\n", m.Role, p.Text)
+		}
+	}
+}
+
+// cutPoint returns the index of the message from which keepTurns user
+// messages remain, or 0 if there are no more than that. Cutting before a
+// user message never separates a tool call from its result.
+func cutPoint(contents []*genai.Content, keepTurns int) int {
+	var turns []int
+	for i, c := range contents {
+		if isUserMessage(c) {
+			turns = append(turns, i)
+		}
+	}
+	if len(turns) <= keepTurns {
+		return 0
+	}
+	return turns[len(turns)-keepTurns]
+}
+
+// isUserMessage reports whether c is text from the user rather than tool
+// results, which are sent with the user role too.
+func isUserMessage(c *genai.Content) bool {
+	if c == nil || c.Role != genai.RoleUser {
+		return false
+	}
+	text := false
+	for _, p := range c.Parts {
+		if p.FunctionResponse != nil {
+			return false
+		}
+		text = text || p.Text != ""
+	}
+	return text
+}
+
+// prefixHashes returns the hash of contents[:i+1] for every i.
+func prefixHashes(contents []*genai.Content) []string {
+	h := sha256.New()
+	hashes := make([]string, len(contents))
+	for i, c := range contents {
+		data, _ := json.Marshal(c)
+		h.Write(data)
+		hashes[i] = hex.EncodeToString(h.Sum(nil))
+	}
+	return hashes
+}
+
+func (c *compactor) note(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.notes[key]
+	return n, ok
+}
+
+func (c *compactor) store(key, note string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.notes[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.notes[key] = note
+	for len(c.order) > maxNotes {
+		delete(c.notes, c.order[0])
+		c.order = c.order[1:]
+	}
+}
+
+// imageTokens is the estimated size of an image or other inline file.
+const imageTokens = 258
+
+// estimateTokens estimates the size of a prompt at four characters per
+// token, which is close enough for English text and JSON to decide when to
+// compact without calling a tokenizer.
+func estimateTokens(req *model.LLMRequest) int {
+	chars, tokens := 0, 0
+	add := func(c *genai.Content) {
+		if c == nil {
+			return
+		}
+		for _, p := range c.Parts {
+			chars += len(p.Text)
+			if p.FunctionCall != nil {
+				args, _ := json.Marshal(p.FunctionCall.Args)
+				chars += len(p.FunctionCall.Name) + len(args)
+			}
+			if p.FunctionResponse != nil {
+				result, _ := json.Marshal(p.FunctionResponse.Response)
+				chars += len(p.FunctionResponse.Name) + len(result)
+			}
+			if p.InlineData != nil || p.FileData != nil {
+				tokens += imageTokens
+			}
+		}
+	}
+	if req.Config != nil {
+		add(req.Config.SystemInstruction)
+	}
+	for _, c := range req.Contents {
+		add(c)
+	}
+	return tokens + chars/4
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// notingLLM answers summary requests with a numbered note and records the
+// other requests it receives.
+type notingLLM struct {
+	summaries []string
+	requests  []*model.LLMRequest
+	fail      bool
+}
+
+func (m *notingLLM) Name() string { return "noting" }
+
+func (m *notingLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		if req.Config != nil && req.Config.SystemInstruction != nil && req.Config.SystemInstruction.Parts[0].Text == summaryInstruction {
+			if m.fail {
+				yield(nil, errors.New("model unavailable"))
+				return
+			}
+			m.summaries = append(m.summaries, req.Contents[0].Parts[0].Text)
+			yield(&model.LLMResponse{Content: genai.NewContentFromText(fmt.Sprintf("note %d", len(m.summaries)), genai.RoleModel)}, nil)
+			return
+		}
+		m.requests = append(m.requests, req)
+		yield(&model.LLMResponse{Content: genai.NewContentFromText("answer", genai.RoleModel)}, nil)
+	}
+}
+
+// conversation returns turns questions, each answered with a query and a
+// 400-character result.
+func conversation(turns int) []*genai.Content {
+	var contents []*genai.Content
+	for i := 1; i <= turns; i++ {
+		contents = append(contents,
+			genai.NewContentFromText(fmt.Sprintf("question %d", i), genai.RoleUser),
+			&genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{Name: "query_database", Args: map[string]any{"sql": "SELECT 1"}}}}},
+			&genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{Name: "query_database", Response: map[string]any{"data": strings.Repeat("x", 400)}}}}},
+			genai.NewContentFromText(fmt.Sprintf("answer %d", i), genai.RoleModel),
+		)
+	}
+	return contents
+}
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		name string
+		// turns are the conversation lengths sent in order
+		turns         []int
+		fail          bool
+		wantSummaries int
+		// wantFirst is the first message of the last request the model saw
+		wantFirst string
+		wantLen   int
+	}{
+		{name: "under the limit", turns: []int{2}, wantFirst: "question 1", wantLen: 8},
+		{name: "over the limit", turns: []int{6}, wantSummaries: 1, wantFirst: summaryPrefix + "note 1", wantLen: 1 + 2*4},
+		{name: "summary reused", turns: []int{6, 6}, wantSummaries: 1, wantFirst: summaryPrefix + "note 1", wantLen: 1 + 2*4},
+		{name: "summary extended", turns: []int{6, 7}, wantSummaries: 2, wantFirst: summaryPrefix + "note 2", wantLen: 1 + 2*4},
+		{name: "summary fails", turns: []int{6}, fail: true, wantFirst: "question 1", wantLen: 6 * 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &notingLLM{fail: tt.fail}
+			// Each turn is about 110 tokens, so three turns reach 80% of 400
+			llm := Compact(inner, CompactConfig{Limit: 400, KeepTurns: 2})
+			for _, n := range tt.turns {
+				if _, err := generate(t, llm, &model.LLMRequest{Contents: conversation(n)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(inner.summaries) != tt.wantSummaries {
+				t.Errorf("summaries = %d, want %d", len(inner.summaries), tt.wantSummaries)
+			}
+			last := inner.requests[len(inner.requests)-1]
+			if got := last.Contents[0].Parts[0].Text; got != tt.wantFirst || len(last.Contents) != tt.wantLen {
+				t.Errorf("model saw %d messages starting with %q, want %d starting with %q", len(last.Contents), got, tt.wantLen, tt.wantFirst)
+			}
+		})
+	}
+}
+
+func TestCompactExtendsNote(t *testing.T) {
+	inner := &notingLLM{}
+	llm := Compact(inner, CompactConfig{Limit: 400, KeepTurns: 2})
+	for _, n := range []int{6, 7} {
+		if _, err := generate(t, llm, &model.LLMRequest{Contents: conversation(n)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second summary folds only question 5 into the first note
+	second := inner.summaries[1]
+	if !strings.HasPrefix(second, "Earlier note:\nnote 1") || strings.Contains(second, "question 4") || !strings.Contains(second, "user: question 5") {
+		t.Errorf("second summary request = %q", second)
+	}
+	if !strings.Contains(inner.summaries[0], "model called query_database") || !strings.Contains(inner.summaries[0], "query_database returned") {
+		t.Errorf("first summary request lacks the tool calls: %q", inner.summaries[0])
+	}
+}
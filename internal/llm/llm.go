@@ -0,0 +1,146 @@
+// Package llm creates the configured model provider and allows the active
+// model to be swapped at runtime.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"sync"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	"github.com/anuvratrastogi/multi-agent/pkg/localllm"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/model/gemini"
+	"google.golang.org/genai"
+)
+
+// New creates an LLM for the configured provider using the given model name,
+// behind a response cache if one is configured. The model's reasoning is
+// dropped unless it is configured to be kept, and the conversation history
+// is compacted if the model has a context limit, configured or from its
+// capabilities.
+func New(ctx context.Context, cfg *config.Config, modelName string) (model.LLM, error) {
+	llm, err := newProvider(ctx, cfg, modelName)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.KeepReasoning {
+		llm = DropReasoning(llm)
+	}
+	if cfg.LLMCache != "" && cfg.LLMCache != config.LLMCacheOff {
+		if llm, err = NewCache(llm, cfg.LLMCache, cfg.LLMCacheDir); err != nil {
+			return nil, err
+		}
+	}
+
+	limit, err := cfg.ContextLimit(modelName)
+	if err != nil {
+		return nil, err
+	}
+	if caps, ok := LookupCapabilities(cfg.LLMProvider, modelName); ok && limit == 0 {
+		limit = caps.ContextWindow
+	}
+	if limit > 0 {
+		llm = Compact(llm, CompactConfig{Limit: limit, At: cfg.ContextCompactAt, KeepTurns: cfg.ContextKeepTurns})
+	}
+	return llm, nil
+}
+
+func newProvider(ctx context.Context, cfg *config.Config, modelName string) (model.LLM, error) {
+	if cfg.LLMProvider == config.LLMProviderMock {
+		return mockllm.Load(cfg.MockLLMFixtures)
+	}
+	if cfg.IsOpenAICompatible() {
+		tlsConfig, err := localTLS(cfg)
+		if err != nil {
+			return nil, err
+		}
+		proxy, err := localProxy(cfg)
+		if err != nil {
+			return nil, err
+		}
+		timeout, idleTimeout, err := cfg.LocalLLMTimeouts()
+		if err != nil {
+			return nil, err
+		}
+		headers, err := cfg.LocalLLMHeaderValues()
+		if err != nil {
+			return nil, err
+		}
+		var azure *localllm.Azure
+		baseURL := cfg.LocalLLMURL
+		if cfg.LLMProvider == config.LLMProviderAzure {
+			deployment, err := cfg.AzureOpenAIDeployment(modelName)
+			if err != nil {
+				return nil, err
+			}
+			azure = &localllm.Azure{APIKey: cfg.AzureOpenAIAPIKey, Deployment: deployment, APIVersion: cfg.AzureOpenAIAPIVersion}
+			baseURL = cfg.AzureOpenAIEndpoint
+		}
+		// Unknown models are asked for JSON in the prompt and get images as
+		// they are
+		caps, known := LookupCapabilities(cfg.LLMProvider, modelName)
+		return localllm.New(localllm.Config{
+			BaseURL:          baseURL,
+			Model:            modelName,
+			ToolRepair:       localllm.ToolRepair(cfg.LocalLLMToolRepair),
+			Flavor:           localllm.Flavor(cfg.LocalLLMFlavor),
+			ToolCalling:      ToolCalling(cfg, modelName),
+			JSONMode:         known && caps.JSONMode,
+			TextOnly:         known && !caps.Vision,
+			TLS:              tlsConfig,
+			Proxy:            proxy,
+			Timeout:          timeout,
+			Headers:          headers,
+			DisableKeepAlive: !cfg.LocalLLMKeepAlive,
+			IdleTimeout:      idleTimeout,
+			Azure:            azure,
+		}), nil
+	}
+
+	llm, err := gemini.NewModel(ctx, modelName, &genai.ClientConfig{
+		APIKey: cfg.GoogleAPIKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Gemini model: %w", err)
+	}
+	return llm, nil
+}
+
+// Switchable is a model.LLM that delegates to a model which can be replaced
+// while agents hold a reference to it.
+type Switchable struct {
+	mu      sync.RWMutex
+	current model.LLM
+}
+
+// NewSwitchable creates a Switchable that initially delegates to llm.
+func NewSwitchable(llm model.LLM) *Switchable {
+	return &Switchable{current: llm}
+}
+
+// Set replaces the model used for subsequent requests.
+func (s *Switchable) Set(llm model.LLM) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = llm
+}
+
+// Current returns the model requests are delegated to.
+func (s *Switchable) Current() model.LLM {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Name implements model.LLM.
+func (s *Switchable) Name() string {
+	return s.Current().Name()
+}
+
+// GenerateContent implements model.LLM.
+func (s *Switchable) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return s.Current().GenerateContent(ctx, req, stream)
+}
@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	"google.golang.org/genai"
+)
+
+// modelLister is a provider that can list the models it serves.
+type modelLister interface {
+	Models(ctx context.Context) ([]string, error)
+}
+
+// ListModels lists the models of the configured provider, sorted by name:
+// the Gemini models that generate content, or the models of the local
+// server's /v1/models. Providers that cannot list their models, Azure
+// OpenAI and the mock, return an error wrapping errors.ErrUnsupported.
+func ListModels(ctx context.Context, cfg *config.Config) ([]string, error) {
+	var models []string
+	switch {
+	case cfg.LLMProvider == config.LLMProviderGemini:
+		client, err := genai.NewClient(ctx, &genai.ClientConfig{APIKey: cfg.GoogleAPIKey})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Gemini client: %w", err)
+		}
+		for m, err := range client.Models.All(ctx) {
+			if err != nil {
+				return nil, fmt.Errorf("failed to list Gemini models: %w", err)
+			}
+			if slices.Contains(m.SupportedActions, "generateContent") {
+				models = append(models, strings.TrimPrefix(m.Name, "models/"))
+			}
+		}
+	default:
+		llm, err := newProvider(ctx, cfg, cfg.Model)
+		if err != nil {
+			return nil, err
+		}
+		lister, ok := llm.(modelLister)
+		if !ok {
+			return nil, fmt.Errorf("listing the models of provider %s: %w", cfg.LLMProvider, errors.ErrUnsupported)
+		}
+		if models, err = lister.Models(ctx); err != nil {
+			return nil, err
+		}
+	}
+	slices.Sort(models)
+	return models, nil
+}
+
+// HasModel reports whether models contains the named model. Ollama serves
+// a model without a tag as its ":latest" one.
+func HasModel(models []string, name string) bool {
+	return slices.Contains(models, name) || (!strings.Contains(name, ":") && slices.Contains(models, name+":latest"))
+}
@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+)
+
+func TestListModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"id":"qwen2.5:7b"},{"id":"llama3.1:latest"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := config.NewFromFile("")
+	cfg.LLMProvider, cfg.LocalLLMURL = config.LLMProviderLocal, server.URL
+	models, err := ListModels(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"llama3.1:latest", "qwen2.5:7b"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("ListModels() = %v, want %v", models, want)
+	}
+
+	for name, want := range map[string]bool{"llama3.1": true, "llama3.1:latest": true, "qwen2.5": false, "qwen2.5:7b": true, "mistral": false} {
+		if got := HasModel(models, name); got != want {
+			t.Errorf("HasModel(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	cfg.LLMProvider, cfg.AzureOpenAIEndpoint = config.LLMProviderAzure, server.URL
+	if _, err := ListModels(context.Background(), cfg); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ListModels() on Azure error = %v, want errors.ErrUnsupported", err)
+	}
+}
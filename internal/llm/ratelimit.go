@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/ratelimit"
+	"github.com/anuvratrastogi/multi-agent/pkg/localllm"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// DefaultRateLimitRetries is how often a call the provider refused as rate
+// limited is retried.
+const DefaultRateLimitRetries = 3
+
+// retryDelay is the wait before the first retry of a refused call; it
+// doubles with every further attempt. It is replaced in tests.
+var retryDelay = 2 * time.Second
+
+// RateLimitConfig holds configuration for RateLimit. Zero limits are
+// unlimited.
+type RateLimitConfig struct {
+	// RequestsPerMinute caps model calls
+	RequestsPerMinute int
+	// TokensPerMinute caps prompt and response tokens. Prompts are estimated
+	// before they are sent; the difference to the usage the response
+	// reports is charged to later calls.
+	TokensPerMinute int
+	// Retries is how often a call the provider refused as rate limited
+	// (HTTP 429) is retried, with a doubling delay
+	Retries int
+}
+
+// RateLimit wraps llm so that calls wait in line while they would exceed
+// the configured rates, and calls the provider refuses as rate limited are
+// retried. Every wait is published as a RateLimited event. Wrap a model
+// shared by all agents, so the limits apply to them together.
+func RateLimit(llm model.LLM, cfg RateLimitConfig) model.LLM {
+	return &rateLimited{
+		llm:      llm,
+		requests: ratelimit.New(cfg.RequestsPerMinute, time.Minute),
+		tokens:   ratelimit.New(cfg.TokensPerMinute, time.Minute),
+		retries:  cfg.Retries,
+	}
+}
+
+type rateLimited struct {
+	llm      model.LLM
+	requests *ratelimit.Limiter
+	tokens   *ratelimit.Limiter
+	retries  int
+}
+
+// Name implements model.LLM.
+func (r *rateLimited) Name() string {
+	return r.llm.Name()
+}
+
+// GenerateContent implements model.LLM.
+func (r *rateLimited) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		estimate := estimateTokens(req)
+		for attempt := 0; ; attempt++ {
+			if err := r.requests.Wait(ctx, 1, waitReporter(ctx, "requests per minute")); err != nil {
+				yield(nil, err)
+				return
+			}
+			if err := r.tokens.Wait(ctx, estimate, waitReporter(ctx, "tokens per minute")); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			refused, done := r.generate(ctx, req, stream, estimate, attempt, yield)
+			if done {
+				return
+			}
+
+			// Retry the refused call once the provider had time to recover
+			delay := retryDelay << attempt
+			events.Publish(ctx, &events.RateLimited{
+				Resource: events.ResourceLLM,
+				Limit:    "server",
+				WaitMS:   delay.Milliseconds(),
+				Attempt:  attempt + 1,
+			})
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				yield(nil, errors.Join(refused, ctx.Err()))
+				return
+			}
+		}
+	}
+}
+
+// generate makes one attempt at a call, charging the tokens it used beyond
+// the estimate. It returns the error of a call the provider refused as rate
+// limited and that should be retried; otherwise the call is done.
+func (r *rateLimited) generate(ctx context.Context, req *model.LLMRequest, stream bool, estimate, attempt int, yield func(*model.LLMResponse, error) bool) (refused error, done bool) {
+	var usage *genai.GenerateContentResponseUsageMetadata
+	defer func() {
+		switch {
+		case refused != nil:
+			// The tokens were not used; the request was
+			r.tokens.Charge(-estimate)
+		case usage != nil:
+			r.tokens.Charge(int(usage.TotalTokenCount) - estimate)
+		}
+	}()
+
+	responded := false
+	for resp, err := range r.llm.GenerateContent(ctx, req, stream) {
+		if err != nil {
+			// Only calls that have not responded yet can be retried
+			if !responded && attempt < r.retries && isRateLimited(err) {
+				return err, false
+			}
+			yield(nil, err)
+			return nil, true
+		}
+		responded = true
+		if resp != nil && resp.UsageMetadata != nil {
+			usage = resp.UsageMetadata
+		}
+		if !yield(resp, nil) {
+			return nil, true
+		}
+	}
+	return nil, true
+}
+
+// waitReporter publishes a RateLimited event when a call waits for limit.
+func waitReporter(ctx context.Context, limit string) func(time.Duration) {
+	return func(wait time.Duration) {
+		events.Publish(ctx, &events.RateLimited{
+			Resource: events.ResourceLLM,
+			Limit:    limit,
+			WaitMS:   wait.Milliseconds(),
+		})
+	}
+}
+
+// isRateLimited reports whether err is the provider refusing a call with
+// HTTP 429 Too Many Requests.
+func isRateLimited(err error) bool {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests
+	}
+	var statusErr *localllm.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code == http.StatusTooManyRequests
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/pkg/localllm"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// refusingLLM fails its first refusals calls with err, then answers.
+type refusingLLM struct {
+	refusals int
+	err      error
+	calls    int
+}
+
+func (m *refusingLLM) Name() string { return "refusing" }
+
+func (m *refusingLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		m.calls++
+		if m.calls <= m.refusals {
+			yield(nil, m.err)
+			return
+		}
+		yield(&model.LLMResponse{
+			Content:       genai.NewContentFromText("42", genai.RoleModel),
+			UsageMetadata: &genai.GenerateContentResponseUsageMetadata{TotalTokenCount: 100},
+		}, nil)
+	}
+}
+
+func TestRateLimitRetries(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Millisecond
+
+	tests := []struct {
+		name      string
+		refusals  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{"answered", 0, nil, 1, false},
+		{"Gemini refusal retried", 2, genai.APIError{Code: 429}, 3, false},
+		{"local server refusal retried", 1, &localllm.StatusError{Code: 429}, 2, false},
+		{"refused after the retries", 5, genai.APIError{Code: 429}, 4, true},
+		{"other errors not retried", 1, &localllm.StatusError{Code: 500}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &refusingLLM{refusals: tt.refusals, err: tt.err}
+			llm := RateLimit(inner, RateLimitConfig{Retries: DefaultRateLimitRetries})
+
+			bus := events.New()
+			var retries []int
+			bus.Subscribe(func(e events.Event) {
+				if e, ok := e.(*events.RateLimited); ok {
+					retries = append(retries, e.Attempt)
+				}
+			})
+			ctx := events.NewContext(context.Background(), bus, "u", "s")
+
+			var err error
+			for _, err = range llm.GenerateContent(ctx, request("a"), false) {
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", inner.calls, tt.wantCalls)
+			}
+			if len(retries) != tt.wantCalls-1 {
+				t.Errorf("retry events = %v, want %d", retries, tt.wantCalls-1)
+			}
+		})
+	}
+}
+
+func TestRateLimitWaits(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       RateLimitConfig
+		calls     int
+		wantLimit string
+	}{
+		{"within the limits", RateLimitConfig{RequestsPerMinute: 10, TokensPerMinute: 1000}, 3, ""},
+		{"requests per minute", RateLimitConfig{RequestsPerMinute: 2}, 3, "requests per minute"},
+		// Each call uses 100 tokens, charged after the estimated prompt
+		{"tokens per minute", RateLimitConfig{TokensPerMinute: 150}, 3, "tokens per minute"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := RateLimit(&refusingLLM{}, tt.cfg)
+			bus := events.New()
+			var limits []string
+			bus.Subscribe(func(e events.Event) {
+				if e, ok := e.(*events.RateLimited); ok {
+					limits = append(limits, e.Limit)
+				}
+			})
+
+			// The call over the limit waits until the context is done
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			ctx = events.NewContext(ctx, bus, "u", "s")
+			var err error
+			for i := 0; i < tt.calls && err == nil; i++ {
+				for _, err = range llm.GenerateContent(ctx, request("a"), false) {
+				}
+			}
+
+			if tt.wantLimit == "" {
+				if err != nil || len(limits) != 0 {
+					t.Errorf("error = %v, waits %v; want none", err, limits)
+				}
+				return
+			}
+			if !errors.Is(err, context.DeadlineExceeded) || len(limits) != 1 || limits[0] != tt.wantLimit {
+				t.Errorf("error = %v, waits %v; want a wait for %s", err, limits, tt.wantLimit)
+			}
+		})
+	}
+}
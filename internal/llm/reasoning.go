@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"context"
+	"iter"
+
+	"google.golang.org/adk/model"
+)
+
+// DropReasoning wraps llm so that the thought parts of its responses are
+// discarded before they reach the agents, the session history or a cache.
+func DropReasoning(llm model.LLM) model.LLM {
+	return &noReasoning{llm: llm}
+}
+
+type noReasoning struct {
+	llm model.LLM
+}
+
+// Name implements model.LLM.
+func (n *noReasoning) Name() string {
+	return n.llm.Name()
+}
+
+// GenerateContent implements model.LLM.
+func (n *noReasoning) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		for resp, err := range n.llm.GenerateContent(ctx, req, stream) {
+			if err == nil && resp != nil && resp.Content != nil {
+				stripped := *resp
+				content := *resp.Content
+				content.Parts = nil
+				for _, part := range resp.Content.Parts {
+					if !part.Thought {
+						content.Parts = append(content.Parts, part)
+					}
+				}
+				stripped.Content = &content
+				resp = &stripped
+			}
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
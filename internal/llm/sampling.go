@@ -0,0 +1,37 @@
+package llm
+
+import (
+	"github.com/anuvratrastogi/multi-agent/config"
+	"google.golang.org/genai"
+)
+
+// agentDefaults are the generation parameters each agent uses unless
+// configured otherwise. SQL and routing need deterministic output; charts
+// tolerate a little variety in titles and labels.
+var agentDefaults = map[string]config.Sampling{
+	config.AgentManager: {Temperature: genai.Ptr(0.0)},
+	config.AgentSQL:     {Temperature: genai.Ptr(0.0)},
+	config.AgentChart:   {Temperature: genai.Ptr(0.2)},
+}
+
+// Sampling returns the generation parameters for an agent: its defaults,
+// overridden by the global settings, overridden by the agent's settings.
+func Sampling(cfg *config.Config, agent string) config.Sampling {
+	return agentDefaults[agent].Merge(cfg.Sampling).Merge(cfg.AgentSampling[agent])
+}
+
+// GenerateConfig converts sampling parameters into the request config shared
+// by every provider.
+func GenerateConfig(s config.Sampling) *genai.GenerateContentConfig {
+	gc := &genai.GenerateContentConfig{
+		MaxOutputTokens: int32(s.MaxTokens),
+		StopSequences:   s.Stop,
+	}
+	if s.Temperature != nil {
+		gc.Temperature = genai.Ptr(float32(*s.Temperature))
+	}
+	if s.TopP != nil {
+		gc.TopP = genai.Ptr(float32(*s.TopP))
+	}
+	return gc
+}
@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	"google.golang.org/genai"
+)
+
+func TestSampling(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		agent    string
+		wantTemp float32
+		wantTopP *float32
+		wantMax  int32
+	}{
+		{name: "sql default", agent: config.AgentSQL, wantTemp: 0},
+		{name: "chart default", agent: config.AgentChart, wantTemp: 0.2},
+		{
+			name:     "global overrides default",
+			cfg:      config.Config{Sampling: config.Sampling{Temperature: genai.Ptr(0.7), MaxTokens: 512}},
+			agent:    config.AgentSQL,
+			wantTemp: 0.7,
+			wantMax:  512,
+		},
+		{
+			name: "agent overrides global",
+			cfg: config.Config{
+				Sampling:      config.Sampling{Temperature: genai.Ptr(0.7), TopP: genai.Ptr(0.9)},
+				AgentSampling: map[string]config.Sampling{config.AgentSQL: {Temperature: genai.Ptr(0.0)}},
+			},
+			agent:    config.AgentSQL,
+			wantTemp: 0,
+			wantTopP: genai.Ptr(float32(0.9)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gc := GenerateConfig(Sampling(&tt.cfg, tt.agent))
+			if gc.Temperature == nil || *gc.Temperature != tt.wantTemp {
+				t.Errorf("Temperature = %v, want %v", gc.Temperature, tt.wantTemp)
+			}
+			if (gc.TopP == nil) != (tt.wantTopP == nil) || (gc.TopP != nil && *gc.TopP != *tt.wantTopP) {
+				t.Errorf("TopP = %v, want %v", gc.TopP, tt.wantTopP)
+			}
+			if gc.MaxOutputTokens != tt.wantMax {
+				t.Errorf("MaxOutputTokens = %d, want %d", gc.MaxOutputTokens, tt.wantMax)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+)
+
+// localTLS returns the TLS settings for the local LLM server: its CA
+// bundle, client certificate and whether verification is skipped, or nil
+// if none are configured.
+func localTLS(cfg *config.Config) (*tls.Config, error) {
+	if cfg.LocalLLMCAFile == "" && cfg.LocalLLMCertFile == "" && !cfg.LocalLLMInsecureSkipVerify {
+		return nil, nil
+	}
+	t := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.LocalLLMInsecureSkipVerify,
+	}
+	if cfg.LocalLLMCAFile != "" {
+		pem, err := os.ReadFile(cfg.LocalLLMCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local LLM CA file: %w", err)
+		}
+		// The CA is trusted in addition to the system roots, so a gateway
+		// with a private certificate does not break public endpoints
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.LocalLLMCAFile)
+		}
+		t.RootCAs = pool
+	}
+	if cfg.LocalLLMCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.LocalLLMCertFile, cfg.LocalLLMKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load local LLM client certificate: %w", err)
+		}
+		t.Certificates = []tls.Certificate{cert}
+	}
+	return t, nil
+}
+
+// localProxy returns the configured proxy for the local LLM server, or nil
+// to use the environment's.
+func localProxy(cfg *config.Config) (*url.URL, error) {
+	if cfg.LocalLLMProxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(cfg.LocalLLMProxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse local LLM proxy: %w", err)
+	}
+	return u, nil
+}
@@ -5,9 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"strings"
 
-	"github.com/lib/pq"
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+	_ "github.com/lib/pq"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
@@ -16,6 +16,8 @@ import (
 type PostgresServer struct {
 	server *server.MCPServer
 	db     *sql.DB
+	// meta runs the PostgreSQL dialect's introspection queries
+	meta dialect.Introspector
 }
 
 // NewPostgresServer creates a new PostgreSQL MCP server.
@@ -37,6 +39,7 @@ func NewPostgresServer(databaseURL string) (*PostgresServer, error) {
 	ps := &PostgresServer{
 		server: s,
 		db:     db,
+		meta:   dialect.Introspector{Dialect: dialect.Postgres{}, Query: dialect.SQLQuery(db)},
 	}
 
 	ps.registerTools()
@@ -100,13 +103,7 @@ func (ps *PostgresServer) handleQuery(ctx context.Context, request mcp.CallToolR
 	}
 
 	// Add LIMIT if not present and it's a SELECT query
-	queryUpper := strings.ToUpper(strings.TrimSpace(query))
-	if strings.HasPrefix(queryUpper, "SELECT") && !strings.Contains(queryUpper, "LIMIT") {
-		// Strip trailing semicolon if present
-		query = strings.TrimSpace(query)
-		query = strings.TrimSuffix(query, ";")
-		query = fmt.Sprintf("%s LIMIT %d", query, int(limit))
-	}
+	query = ps.meta.Dialect.Limit(query, int(limit))
 
 	rows, err := ps.db.QueryContext(ctx, query)
 	if err != nil {
@@ -154,119 +151,26 @@ func (ps *PostgresServer) handleGetSchema(ctx context.Context, request mcp.CallT
 		return mcp.NewToolResultError("table_name parameter is required"), nil
 	}
 
-	query := `
-		SELECT column_name, data_type, is_nullable, column_default
-		FROM information_schema.columns
-		WHERE table_name = $1
-		ORDER BY ordinal_position
-	`
-
-	rows, err := ps.db.QueryContext(ctx, query, tableName)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("query error: %v", err)), nil
-	}
-	defer rows.Close()
-
-	var schema []map[string]interface{}
-	for rows.Next() {
-		var columnName, dataType, isNullable string
-		var columnDefault sql.NullString
-
-		if err := rows.Scan(&columnName, &dataType, &isNullable, &columnDefault); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("scan error: %v", err)), nil
-		}
-
-		col := map[string]interface{}{
-			"column_name": columnName,
-			"data_type":   dataType,
-			"nullable":    isNullable == "YES",
-		}
-		if columnDefault.Valid {
-			col["default"] = columnDefault.String
-		}
-		schema = append(schema, col)
-	}
-
-	jsonResult, err := json.Marshal(schema)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("json error: %v", err)), nil
-	}
-
-	return mcp.NewToolResultText(string(jsonResult)), nil
+	return toolResult(ps.meta.GetSchema(ctx, tableName))
 }
 
 // handleListTables lists all tables in the database.
 func (ps *PostgresServer) handleListTables(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	query := `
-		SELECT table_name 
-		FROM information_schema.tables 
-		WHERE table_schema = 'public' 
-		ORDER BY table_name
-	`
-
-	rows, err := ps.db.QueryContext(ctx, query)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("query error: %v", err)), nil
-	}
-	defer rows.Close()
-
-	var tables []string
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("scan error: %v", err)), nil
-		}
-		tables = append(tables, tableName)
-	}
-
-	jsonResult, err := json.Marshal(tables)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("json error: %v", err)), nil
-	}
-
-	return mcp.NewToolResultText(string(jsonResult)), nil
+	return toolResult(ps.meta.ListTables(ctx))
 }
 
 // handleDescribeDatabase provides an overview of the database.
 func (ps *PostgresServer) handleDescribeDatabase(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	query := `
-		SELECT 
-			t.table_name,
-			array_agg(c.column_name || ' ' || c.data_type ORDER BY c.ordinal_position) as columns
-		FROM information_schema.tables t
-		JOIN information_schema.columns c ON t.table_name = c.table_name
-		WHERE t.table_schema = 'public'
-		GROUP BY t.table_name
-		ORDER BY t.table_name
-	`
-
-	rows, err := ps.db.QueryContext(ctx, query)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("query error: %v", err)), nil
-	}
-	defer rows.Close()
-
-	var tables []map[string]interface{}
-	for rows.Next() {
-		var tableName string
-		var columns []string
-
-		if err := rows.Scan(&tableName, pq.Array(&columns)); err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("scan error: %v", err)), nil
-		}
-
-		tables = append(tables, map[string]interface{}{
-			"table":   tableName,
-			"columns": columns,
-		})
-	}
+	return toolResult(ps.meta.DescribeDatabase(ctx))
+}
 
-	jsonResult, err := json.Marshal(tables)
+// toolResult turns the JSON returned by an introspection method into a tool
+// result, reporting an error to the client rather than failing the call.
+func toolResult(jsonResult string, err error) (*mcp.CallToolResult, error) {
 	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("json error: %v", err)), nil
+		return mcp.NewToolResultError(err.Error()), nil
 	}
-
-	return mcp.NewToolResultText(string(jsonResult)), nil
+	return mcp.NewToolResultText(jsonResult), nil
 }
 
 // GetServer returns the underlying MCP server.
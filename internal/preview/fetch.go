@@ -0,0 +1,85 @@
+package preview
+
+import (
+	"fmt"
+
+	"github.com/anuvratrastogi/multi-agent/internal/toolschema"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// FetchToolName is the tool that pages through a previewed result.
+const FetchToolName = "fetch_full_result"
+
+// FetchArgs are the arguments of fetch_full_result.
+type FetchArgs struct {
+	ResultID string `json:"result_id" description:"The result_id of a truncated query_database result"`
+	Offset   int    `json:"offset,omitempty" description:"Index of the first row to return (default: 0)"`
+	Limit    int    `json:"limit,omitempty" description:"Maximum number of rows to return (default and maximum: the number of rows in the preview)"`
+}
+
+// FetchResult is a page of a previewed result.
+type FetchResult struct {
+	Data     string `json:"data,omitempty"`
+	RowCount int    `json:"row_count,omitempty"`
+	// NextOffset is the offset of the next page, if there is one
+	NextOffset int    `json:"next_offset,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Tools returns the fetch_full_result tool. Pages are no larger than a
+// preview, and a session can only read its own results.
+func (s *Store) Tools() ([]tool.Tool, error) {
+	schema, err := toolschema.For[FetchArgs]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s schema: %w", FetchToolName, err)
+	}
+	fetchTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        FetchToolName,
+			Description: "Read more rows of a query_database result that was truncated, by its result_id",
+			InputSchema: schema,
+		},
+		func(ctx tool.Context, args FetchArgs) (FetchResult, error) {
+			return s.fetch(ctx.UserID()+"/"+ctx.SessionID(), args), nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", FetchToolName, err)
+	}
+	return []tool.Tool{fetchTool}, nil
+}
+
+func (s *Store) fetch(session string, args FetchArgs) FetchResult {
+	r, ok := s.result(session, args.ResultID)
+	if !ok {
+		return FetchResult{Error: fmt.Sprintf("unknown result_id %q; run the query again", args.ResultID)}
+	}
+	defer r.release()
+	total := r.len()
+	if args.Offset < 0 || args.Offset > total {
+		return FetchResult{Error: fmt.Sprintf("offset must be between 0 and %d", total)}
+	}
+
+	// Pages are no longer than a preview, so no more rows are read
+	n := min(total-args.Offset, s.cfg.MaxRows)
+	if args.Limit > 0 && args.Limit < n {
+		n = args.Limit
+	}
+	page, err := r.page(args.Offset, max(n, 1))
+	if err != nil {
+		return FetchResult{Error: err.Error()}
+	}
+	if fit := s.fit(page); fit > 0 || len(page) == 0 {
+		page = page[:fit]
+	} else {
+		// A single row larger than MaxBytes is still returned
+		page = page[:1]
+	}
+
+	res := FetchResult{Data: joinRows(page), RowCount: total}
+	if end := args.Offset + len(page); end < total {
+		res.NextOffset = end
+	}
+	return res
+}
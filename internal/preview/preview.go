@@ -0,0 +1,339 @@
+// Package preview keeps large query results out of the model's context.
+// Instead of every row, the model is shown the first rows, the column list
+// and statistics over all rows; the complete result stays in a Store, from
+// which the model pages with the fetch_full_result tool and the App reads
+// the data it returns to the user.
+package preview
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/toolmw"
+	"google.golang.org/adk/tool"
+)
+
+// QueryToolName and ParamsQueryToolName are the tools whose results are
+// previewed.
+const (
+	QueryToolName       = "query_database"
+	ParamsQueryToolName = "query_database_params"
+)
+
+// DefaultKeep is the number of complete results a Store keeps by default.
+const DefaultKeep = 50
+
+// Config holds configuration for a Store.
+type Config struct {
+	// MaxRows is the number of rows shown to the model (0 disables previews)
+	MaxRows int
+	// MaxBytes caps the size of the rows shown to the model (0 = no cap)
+	MaxBytes int
+	// Keep is the number of complete results kept for fetch_full_result,
+	// the oldest being dropped first (defaults to DefaultKeep)
+	Keep int
+}
+
+// Store holds the complete data of previewed results. It is safe for
+// concurrent use.
+type Store struct {
+	cfg Config
+
+	mu      sync.Mutex
+	results map[string]*result
+	// order lists result IDs, oldest first
+	order []string
+	next  int
+}
+
+// result is a complete query result and the session it belongs to. Results
+// that spilled to disk keep their Spill instead of data and rows.
+type result struct {
+	session string
+	data    string
+	rows    []json.RawMessage
+	spill   *sqlagent.Spill
+}
+
+// len returns the number of rows of r.
+func (r *result) len() int {
+	if r.spill != nil {
+		return r.spill.Len()
+	}
+	return len(r.rows)
+}
+
+// page returns at most n rows of r from offset.
+func (r *result) page(offset, n int) ([]json.RawMessage, error) {
+	if r.spill == nil {
+		return r.rows[offset:min(offset+n, len(r.rows))], nil
+	}
+	var rows []json.RawMessage
+	i := 0
+	err := r.spill.Each(func(row json.RawMessage) error {
+		if len(rows) == n {
+			return errPage
+		}
+		if i >= offset {
+			rows = append(rows, bytes.Clone(row))
+		}
+		i++
+		return nil
+	})
+	if err != nil && !errors.Is(err, errPage) {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// errPage ends reading a page of a spilled result.
+var errPage = errors.New("page read")
+
+// New creates a Store.
+func New(cfg Config) *Store {
+	if cfg.Keep <= 0 {
+		cfg.Keep = DefaultKeep
+	}
+	return &Store{cfg: cfg, results: make(map[string]*result)}
+}
+
+// Preview is what the model sees of a result too large to show in full.
+type Preview struct {
+	// Data holds the first rows, as a JSON array like the complete result
+	Data      string                 `json:"data"`
+	Truncated bool                   `json:"truncated"`
+	RowCount  int                    `json:"row_count"`
+	ShownRows int                    `json:"shown_rows"`
+	Columns   []string               `json:"columns"`
+	Stats     map[string]ColumnStats `json:"stats"`
+	ResultID  string                 `json:"result_id"`
+	Notice    string                 `json:"notice"`
+}
+
+// Middleware replaces query tool results larger than the configured
+// rows or bytes with a Preview. Other tools are not changed.
+func (s *Store) Middleware() toolmw.Middleware {
+	return func(t tool.Tool, next toolmw.Handler) toolmw.Handler {
+		if (t.Name() != QueryToolName && t.Name() != ParamsQueryToolName) || s.cfg.MaxRows <= 0 {
+			return next
+		}
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			ctx, capture := sqlagent.CaptureSpill(ctx)
+			defer capture.Close()
+			res, err := next(ctx, args)
+			if err != nil {
+				return res, err
+			}
+			data, _ := res["data"].(string)
+			session := ctx.UserID() + "/" + ctx.SessionID()
+			var p *Preview
+			var ok bool
+			if spill := capture.Spill(); spill != nil && data != "" {
+				p, ok = s.previewSpill(session, spill)
+			} else {
+				p, ok = s.preview(session, data)
+			}
+			if !ok {
+				return res, nil
+			}
+			m, err := toMap(p)
+			if err != nil {
+				return m, err
+			}
+			if res["chart"] != nil {
+				// The recommendation covers every row, not just the preview
+				m["chart"] = res["chart"]
+			}
+			if res["capped"] != nil {
+				// The rows previewed were already cut to the result caps
+				m["capped"] = res["capped"]
+			}
+			return m, nil
+		}
+	}
+}
+
+// Full returns the complete data of the previewed result with the given ID.
+// The data of a result kept on disk is read back.
+func (s *Store) Full(resultID string) (string, bool) {
+	s.mu.Lock()
+	r, ok := s.results[resultID]
+	if ok && r.spill != nil {
+		r.spill.Retain()
+	}
+	s.mu.Unlock()
+	if !ok {
+		return "", false
+	}
+	if r.spill == nil {
+		return r.data, true
+	}
+	defer r.release()
+
+	var b strings.Builder
+	b.WriteByte('[')
+	err := r.spill.Each(func(row json.RawMessage) error {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.Write(row)
+		return nil
+	})
+	if err != nil {
+		return "", false
+	}
+	b.WriteByte(']')
+	return b.String(), true
+}
+
+// preview stores data and returns its Preview, or false if data is small
+// enough to show in full or is not a JSON array of rows.
+func (s *Store) preview(session, data string) (*Preview, bool) {
+	var rows []json.RawMessage
+	if data == "" || json.Unmarshal([]byte(data), &rows) != nil {
+		return nil, false
+	}
+	if len(rows) <= s.cfg.MaxRows && (s.cfg.MaxBytes <= 0 || len(data) <= s.cfg.MaxBytes) {
+		return nil, false
+	}
+
+	columns, stats, err := summarize(rows)
+	if err != nil {
+		return nil, false
+	}
+	shown := s.fit(rows)
+	id := s.put(&result{session: session, data: data, rows: rows})
+	return &Preview{
+		Data:      joinRows(rows[:shown]),
+		Truncated: true,
+		RowCount:  len(rows),
+		ShownRows: shown,
+		Columns:   columns,
+		Stats:     stats,
+		ResultID:  id,
+		Notice: fmt.Sprintf("Showing the first %d of %d rows. The stats cover all rows; call %s with this result_id to read the others.",
+			shown, len(rows), FetchToolName),
+	}, true
+}
+
+// previewSpill stores a result kept on disk and returns its Preview,
+// reading the rows once without holding more than the ones shown.
+func (s *Store) previewSpill(session string, spill *sqlagent.Spill) (*Preview, bool) {
+	sum := newSummary()
+	var leading []json.RawMessage
+	err := spill.Each(func(row json.RawMessage) error {
+		if len(leading) < s.cfg.MaxRows {
+			leading = append(leading, bytes.Clone(row))
+		}
+		return sum.add(row)
+	})
+	if err != nil {
+		return nil, false
+	}
+
+	shown := s.fit(leading)
+	id := s.put(&result{session: session, spill: spill.Retain()})
+	return &Preview{
+		Data:      joinRows(leading[:shown]),
+		Truncated: true,
+		RowCount:  spill.Len(),
+		ShownRows: shown,
+		Columns:   sum.names,
+		Stats:     sum.stats(),
+		ResultID:  id,
+		Notice: fmt.Sprintf("Showing the first %d of %d rows. The stats cover all rows; call %s with this result_id to read the others.",
+			shown, spill.Len(), FetchToolName),
+	}, true
+}
+
+// fit returns how many of the leading rows fit in MaxRows and MaxBytes.
+func (s *Store) fit(rows []json.RawMessage) int {
+	n := min(len(rows), s.cfg.MaxRows)
+	if s.cfg.MaxBytes <= 0 {
+		return n
+	}
+	size := 2 // brackets
+	for i := 0; i < n; i++ {
+		size += len(rows[i])
+		if i > 0 {
+			size++ // comma
+		}
+		if size > s.cfg.MaxBytes {
+			return i
+		}
+	}
+	return n
+}
+
+// put stores r, dropping the oldest results beyond Keep, and returns its ID.
+func (s *Store) put(r *result) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next++
+	id := fmt.Sprintf("res_%d", s.next)
+	s.results[id] = r
+	s.order = append(s.order, id)
+	for len(s.order) > s.cfg.Keep {
+		if old := s.results[s.order[0]]; old.spill != nil {
+			old.spill.Close()
+		}
+		delete(s.results, s.order[0])
+		s.order = s.order[1:]
+	}
+	return id
+}
+
+// result returns a stored result, if it belongs to session. The caller
+// must release it.
+func (s *Store) result(session, resultID string) (*result, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.results[resultID]
+	if !ok || r.session != session {
+		return nil, false
+	}
+	if r.spill != nil {
+		r.spill.Retain()
+	}
+	return r, true
+}
+
+// release drops the reference result took.
+func (r *result) release() {
+	if r.spill != nil {
+		r.spill.Close()
+	}
+}
+
+// joinRows encodes rows as a JSON array.
+func joinRows(rows []json.RawMessage) string {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i, r := range rows {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(r)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// toMap converts a tool result to the map ADK passes to the model, as
+// functiontool does.
+func toMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return m, nil
+}
@@ -0,0 +1,233 @@
+package preview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/dataset"
+	"google.golang.org/adk/tool"
+)
+
+// fakeContext is the part of a tool.Context the store uses.
+type fakeContext struct {
+	tool.Context
+	session string
+}
+
+func (c fakeContext) Value(key any) any     { return context.Background().Value(key) }
+func (c fakeContext) UserID() string        { return "alice" }
+func (c fakeContext) SessionID() string     { return c.session }
+func (c fakeContext) AgentName() string     { return "SQLAgent" }
+func (c fakeContext) Err() error            { return nil }
+func (c fakeContext) Done() <-chan struct{} { return nil }
+
+type fakeTool struct {
+	tool.Tool
+	name string
+}
+
+func (t fakeTool) Name() string { return t.name }
+
+// orders returns n rows as query_database encodes them.
+func orders(n int) string {
+	rows := make([]string, n)
+	for i := range rows {
+		region := []string{"north", "south"}[i%2]
+		rows[i] = fmt.Sprintf(`{"id":%d,"region":%q,"amount":%d,"note":null}`, i+1, region, (i+1)*10)
+	}
+	return "[" + strings.Join(rows, ",") + "]"
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		tool      string
+		data      string
+		wantShown int // -1 means the result is unchanged
+	}{
+		{name: "small result", cfg: Config{MaxRows: 5}, tool: QueryToolName, data: orders(5), wantShown: -1},
+		{name: "too many rows", cfg: Config{MaxRows: 5}, tool: QueryToolName, data: orders(12), wantShown: 5},
+		{name: "too many bytes", cfg: Config{MaxRows: 50, MaxBytes: 200}, tool: QueryToolName, data: orders(12), wantShown: 3},
+		{name: "disabled", cfg: Config{}, tool: QueryToolName, data: orders(12), wantShown: -1},
+		{name: "other tool", cfg: Config{MaxRows: 5}, tool: "get_schema", data: orders(12), wantShown: -1},
+		{name: "not rows", cfg: Config{MaxRows: 1, MaxBytes: 5}, tool: QueryToolName, data: `"a long string"`, wantShown: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.cfg)
+			next := func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+				return map[string]any{"data": tt.data, "chart": map[string]any{"chart": "bar"}}, nil
+			}
+			h := s.Middleware()(fakeTool{name: tt.tool}, next)
+			res, err := h(fakeContext{session: "s1"}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantShown < 0 {
+				if res["data"] != tt.data || res["truncated"] != nil {
+					t.Errorf("result = %v, want it unchanged", res)
+				}
+				return
+			}
+			var shown []json.RawMessage
+			if err := json.Unmarshal([]byte(res["data"].(string)), &shown); err != nil {
+				t.Fatalf("data is not a JSON array: %v", err)
+			}
+			if len(shown) != tt.wantShown || res["shown_rows"] != float64(tt.wantShown) || res["row_count"] != float64(12) {
+				t.Errorf("shown %d rows (shown_rows=%v row_count=%v), want %d of 12", len(shown), res["shown_rows"], res["row_count"], tt.wantShown)
+			}
+			if full, ok := s.Full(res["result_id"].(string)); !ok || full != tt.data {
+				t.Errorf("Full() = %q, %v; want the complete data", full, ok)
+			}
+			if res["chart"] == nil {
+				t.Error("preview dropped the chart recommendation")
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var rows []json.RawMessage
+	if err := json.Unmarshal([]byte(orders(4)), &rows); err != nil {
+		t.Fatal(err)
+	}
+	columns, stats, err := summarize(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(columns, ","); got != "id,region,amount,note" {
+		t.Errorf("columns = %s, want the order of the rows' keys", got)
+	}
+	amount := stats["amount"]
+	if amount.Min != 10.0 || amount.Max != 40.0 || amount.Mean == nil || *amount.Mean != 25 || amount.Distinct != 4 {
+		t.Errorf("amount stats = %+v", amount)
+	}
+	if region := stats["region"]; region.Min != "north" || region.Max != "south" || region.Distinct != 2 || region.Mean != nil {
+		t.Errorf("region stats = %+v", region)
+	}
+	if note := stats["note"]; note.Nulls != 4 || note.Distinct != 0 || note.Min != nil {
+		t.Errorf("note stats = %+v", note)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	s := New(Config{MaxRows: 5})
+	p, ok := s.preview("alice/s1", orders(12))
+	if !ok {
+		t.Fatal("preview() = false, want a preview of 12 rows")
+	}
+
+	tests := []struct {
+		name     string
+		session  string
+		args     FetchArgs
+		wantRows int
+		wantNext int
+		wantErr  string
+	}{
+		{name: "first page", session: "alice/s1", args: FetchArgs{ResultID: p.ResultID}, wantRows: 5, wantNext: 5},
+		{name: "last page", session: "alice/s1", args: FetchArgs{ResultID: p.ResultID, Offset: 10}, wantRows: 2},
+		{name: "smaller limit", session: "alice/s1", args: FetchArgs{ResultID: p.ResultID, Offset: 5, Limit: 2}, wantRows: 2, wantNext: 7},
+		{name: "limit capped", session: "alice/s1", args: FetchArgs{ResultID: p.ResultID, Limit: 100}, wantRows: 5, wantNext: 5},
+		{name: "other session", session: "alice/s2", args: FetchArgs{ResultID: p.ResultID}, wantErr: "unknown result_id"},
+		{name: "unknown id", session: "alice/s1", args: FetchArgs{ResultID: "res_99"}, wantErr: "unknown result_id"},
+		{name: "bad offset", session: "alice/s1", args: FetchArgs{ResultID: p.ResultID, Offset: 13}, wantErr: "offset must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := s.fetch(tt.session, tt.args)
+			if tt.wantErr != "" {
+				if !strings.Contains(res.Error, tt.wantErr) {
+					t.Errorf("error = %q, want %q", res.Error, tt.wantErr)
+				}
+				return
+			}
+			var rows []json.RawMessage
+			if err := json.Unmarshal([]byte(res.Data), &rows); err != nil {
+				t.Fatalf("data is not a JSON array: %v", err)
+			}
+			if len(rows) != tt.wantRows || res.NextOffset != tt.wantNext || res.RowCount != 12 {
+				t.Errorf("got %d rows, next offset %d, row count %d; want %d rows, next offset %d", len(rows), res.NextOffset, res.RowCount, tt.wantRows, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestKeep(t *testing.T) {
+	s := New(Config{MaxRows: 1, Keep: 2})
+	var ids []string
+	for i := 0; i < 3; i++ {
+		p, _ := s.preview("alice/s1", orders(2))
+		ids = append(ids, p.ResultID)
+	}
+	if _, ok := s.Full(ids[0]); ok {
+		t.Error("the oldest result was kept beyond Keep")
+	}
+	if _, ok := s.Full(ids[2]); !ok {
+		t.Error("the newest result was dropped")
+	}
+}
+
+// spillingTool answers with n orders collected as the database clients do,
+// spilling past the first rows to dir.
+func spillingTool(n int, dir string) func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+	return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+		results := sqlagent.NewResults(ctx, "SELECT * FROM orders", []string{"id", "region", "amount"},
+			dataset.Config{Budget: dataset.NewBudget(100), Dir: dir})
+		defer results.Close()
+		for i := range n {
+			if err := results.Append([]interface{}{int64(i + 1), []string{"north", "south"}[i%2], int64((i + 1) * 10)}); err != nil {
+				return nil, err
+			}
+		}
+		data, err := results.JSON()
+		return map[string]any{"data": data}, err
+	}
+}
+
+func TestSpilledResult(t *testing.T) {
+	dir := t.TempDir()
+	s := New(Config{MaxRows: 5, Keep: 1})
+	h := s.Middleware()(fakeTool{name: QueryToolName}, spillingTool(1000, dir))
+	res, err := h(fakeContext{session: "s1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res["shown_rows"] != float64(5) || res["row_count"] != float64(1000) {
+		t.Errorf("shown_rows=%v row_count=%v, want 5 of 1000", res["shown_rows"], res["row_count"])
+	}
+	stats, _ := res["stats"].(map[string]any)
+	if amount, _ := stats["amount"].(map[string]any); amount["max"] != float64(10000) || amount["distinct"] != float64(1000) {
+		t.Errorf("amount stats = %v, want them over all rows", stats["amount"])
+	}
+
+	id := res["result_id"].(string)
+	page := s.fetch("alice/s1", FetchArgs{ResultID: id, Offset: 995, Limit: 10})
+	if page.Data != `[{"id":996,"region":"south","amount":9960},{"id":997,"region":"north","amount":9970},{"id":998,"region":"south","amount":9980},{"id":999,"region":"north","amount":9990},{"id":1000,"region":"south","amount":10000}]` ||
+		page.RowCount != 1000 || page.NextOffset != 0 {
+		t.Errorf("fetch() = %+v, want the last 5 rows", page)
+	}
+	if full, ok := s.Full(id); !ok || strings.Count(full, `"id"`) != 1000 {
+		t.Errorf("Full() = %d rows, %v; want all 1000", strings.Count(full, `"id"`), ok)
+	}
+
+	// The spill file lives as long as the store keeps the result
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Fatalf("%d spill files while the result is kept, want 1", len(files))
+	}
+	s.preview("alice/s1", orders(12))
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d spill files once the result was dropped, want 0", len(files))
+	}
+}
@@ -0,0 +1,159 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ColumnStats summarizes the values of a column over all rows of a result.
+type ColumnStats struct {
+	Nulls    int `json:"nulls"`
+	Distinct int `json:"distinct"`
+	// Min and Max are set for columns of numbers or strings; strings
+	// compare lexically, which orders ISO dates correctly
+	Min any `json:"min,omitempty"`
+	Max any `json:"max,omitempty"`
+	// Mean is set for columns of numbers
+	Mean *float64 `json:"mean,omitempty"`
+}
+
+// column accumulates the statistics of one column.
+type column struct {
+	nulls    int
+	distinct map[string]bool
+	numbers  int
+	strings  int
+	others   int
+	sum      float64
+	minNum   float64
+	maxNum   float64
+	minStr   string
+	maxStr   string
+}
+
+// summary accumulates the statistics of the columns of rows read one at a
+// time.
+type summary struct {
+	// names are the columns in the order of the first row's keys followed
+	// by keys first seen later
+	names []string
+	cols  map[string]*column
+}
+
+func newSummary() *summary {
+	return &summary{cols: make(map[string]*column)}
+}
+
+// add adds a row, a JSON object.
+func (s *summary) add(raw json.RawMessage) error {
+	keys, values, err := decodeRow(raw)
+	if err != nil {
+		return err
+	}
+	for i, k := range keys {
+		c := s.cols[k]
+		if c == nil {
+			c = &column{distinct: make(map[string]bool)}
+			s.cols[k] = c
+			s.names = append(s.names, k)
+		}
+		c.add(values[i])
+	}
+	return nil
+}
+
+func (s *summary) stats() map[string]ColumnStats {
+	stats := make(map[string]ColumnStats, len(s.cols))
+	for name, c := range s.cols {
+		stats[name] = c.stats()
+	}
+	return stats
+}
+
+// summarize returns the columns of rows, in the order of the first row's
+// keys followed by keys first seen later, and their statistics.
+func summarize(rows []json.RawMessage) ([]string, map[string]ColumnStats, error) {
+	sum := newSummary()
+	for _, raw := range rows {
+		if err := sum.add(raw); err != nil {
+			return nil, nil, err
+		}
+	}
+	return sum.names, sum.stats(), nil
+}
+
+func (c *column) add(v any) {
+	switch v := v.(type) {
+	case nil:
+		c.nulls++
+		return
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			c.others++
+			break
+		}
+		if c.numbers == 0 || f < c.minNum {
+			c.minNum = f
+		}
+		if c.numbers == 0 || f > c.maxNum {
+			c.maxNum = f
+		}
+		c.numbers++
+		c.sum += f
+	case string:
+		if c.strings == 0 || v < c.minStr {
+			c.minStr = v
+		}
+		if c.strings == 0 || v > c.maxStr {
+			c.maxStr = v
+		}
+		c.strings++
+	default:
+		c.others++
+	}
+	key, _ := json.Marshal(v)
+	c.distinct[string(key)] = true
+}
+
+func (c *column) stats() ColumnStats {
+	s := ColumnStats{Nulls: c.nulls, Distinct: len(c.distinct)}
+	switch {
+	case c.numbers > 0 && c.strings == 0 && c.others == 0:
+		mean := c.sum / float64(c.numbers)
+		s.Min, s.Max, s.Mean = c.minNum, c.maxNum, &mean
+	case c.strings > 0 && c.numbers == 0 && c.others == 0:
+		s.Min, s.Max = c.minStr, c.maxStr
+	}
+	return s
+}
+
+// decodeRow decodes a JSON object, keeping the order of its keys.
+func decodeRow(raw json.RawMessage) ([]string, []any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("row is not a JSON object")
+	}
+
+	var keys []string
+	var values []any
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("row is not a JSON object")
+		}
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		values = append(values, v)
+	}
+	return keys, values, nil
+}
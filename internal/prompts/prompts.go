@@ -0,0 +1,443 @@
+// Package prompts renders the agents' instructions from text/template
+// files. The built-in templates are compiled in; a directory of
+// <name>.tmpl files overrides them, optionally re-read whenever a file
+// changes, so prompts can be iterated on without recompiling. Files named
+// <name>.<variant>.tmpl hold named variants, which sessions are assigned
+// to by weight for A/B tests.
+package prompts
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"google.golang.org/adk/agent"
+)
+
+// Names of the templates, one per agent.
+const (
+	Manager = "manager"
+	SQL     = "sql"
+	Chart   = "chart"
+	Files   = "files"
+)
+
+// Names lists every template.
+var Names = []string{Manager, SQL, Chart, Files}
+
+// Base is the variant made of the templates without a variant file.
+const Base = "base"
+
+// All keys the guidance appended to every agent's instruction.
+const All = "all"
+
+//go:embed templates/*.tmpl
+var builtin embed.FS
+
+// SQLData are the variables of the sql template.
+type SQLData struct {
+	// Dialect is the database as named to the agent, e.g. "PostgreSQL"
+	Dialect string
+	// Placeholders are the first bind parameters, e.g. $1 and $2
+	Placeholders []string
+	// Hints are the dialect's syntax reminders
+	Hints []string
+	// Schema is the pre-loaded database schema (optional)
+	Schema string
+	// Relationships lists the foreign keys between tables (optional)
+	Relationships string
+	// Casts lists text columns holding typed values with their casts (optional)
+	Casts string
+	// ColumnValues lists the values of low-cardinality columns (optional)
+	ColumnValues string
+	// Glossary defines business terms in terms of tables and columns (optional)
+	Glossary string
+	// WriteMode, Explain, Profile, Transform, Stats and Clarify are set when
+	// the matching tools are available
+	WriteMode bool
+	Explain   bool
+	Profile   bool
+	Transform bool
+	Stats     bool
+	Clarify   bool
+}
+
+// ManagerData are the variables of the manager template.
+type ManagerData struct {
+	// Count is the number of sub-agents in words or digits
+	Count string
+	// Specialists are the sub-agents besides SQLAgent and ChartAgent
+	Specialists []SubAgent
+	// Clarify is set when the Manager can ask clarifying questions
+	Clarify bool
+}
+
+// SubAgent is a sub-agent listed in the manager template.
+type SubAgent struct {
+	Name        string
+	Description string
+}
+
+// Config configures where templates are loaded from.
+type Config struct {
+	// Dir holds <name>.tmpl files replacing the built-in templates (optional)
+	Dir string
+	// Reload re-reads files in Dir that changed, appeared or were removed
+	// before each render. Variants are found when the Store is created.
+	Reload bool
+	// Variants assigns sessions to variants: a variant name, or weights
+	// such as "base=50,concise=50" (default: base)
+	Variants string
+	// Guidance is text appended to the rendered instructions, such as
+	// naming conventions or topics to avoid, keyed by template name or All
+	// for every agent (optional)
+	Guidance map[string]string
+}
+
+// Store holds the templates of the agents.
+type Store struct {
+	cfg Config
+	// variants are the variants found in Dir, sorted
+	variants []string
+	split    []weight
+
+	mu sync.Mutex
+	// templates are keyed by name, or name.variant for variant files
+	templates map[string]*entry
+}
+
+// weight is the share of sessions assigned to a variant.
+type weight struct {
+	variant string
+	n       int
+}
+
+// entry is a parsed template and the file it was read from, if any.
+type entry struct {
+	tmpl *template.Template
+	path string
+	// seen is the modification time of the override when last read, zero
+	// if there was none
+	seen time.Time
+}
+
+// New loads the templates, reading overrides and variants from cfg.Dir.
+func New(cfg Config) (*Store, error) {
+	for key := range cfg.Guidance {
+		if key != All && !slices.Contains(Names, key) {
+			return nil, fmt.Errorf("unknown agent %q in prompt guidance; agents are %s and %s", key, strings.Join(Names, ", "), All)
+		}
+	}
+	s := &Store{cfg: cfg, templates: make(map[string]*entry)}
+	for _, name := range Names {
+		e, err := s.load(name)
+		if err != nil {
+			return nil, err
+		}
+		s.templates[name] = e
+	}
+	if cfg.Dir != "" {
+		if err := s.loadVariants(); err != nil {
+			return nil, err
+		}
+	}
+	split, err := s.parseSplit(cfg.Variants)
+	if err != nil {
+		return nil, err
+	}
+	s.split = split
+	return s, nil
+}
+
+// loadVariants parses the <name>.<variant>.tmpl files in Dir.
+func (s *Store) loadVariants() error {
+	files, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read prompts directory: %w", err)
+	}
+	for _, f := range files {
+		name, variant, ok := strings.Cut(strings.TrimSuffix(f.Name(), ".tmpl"), ".")
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".tmpl") || !ok || !slices.Contains(Names, name) {
+			continue
+		}
+		if variant == "" || variant == Base || strings.Contains(variant, ".") {
+			return fmt.Errorf("invalid prompt variant file %s", f.Name())
+		}
+		e, err := s.load(name + "." + variant)
+		if err != nil {
+			return err
+		}
+		s.templates[name+"."+variant] = e
+		if !slices.Contains(s.variants, variant) {
+			s.variants = append(s.variants, variant)
+		}
+	}
+	slices.Sort(s.variants)
+	return nil
+}
+
+// parseSplit reads Config.Variants.
+func (s *Store) parseSplit(spec string) ([]weight, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	var split []weight
+	total := 0
+	for _, part := range strings.Split(spec, ",") {
+		variant, n := strings.TrimSpace(part), 1
+		if v, w, ok := strings.Cut(variant, "="); ok {
+			var err error
+			variant = strings.TrimSpace(v)
+			if n, err = strconv.Atoi(strings.TrimSpace(w)); err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid weight of prompt variant %q: %q", variant, w)
+			}
+		}
+		if !s.HasVariant(variant) {
+			return nil, fmt.Errorf("unknown prompt variant %q; variants are %s", variant, strings.Join(s.Variants(), ", "))
+		}
+		split = append(split, weight{variant: variant, n: n})
+		total += n
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("prompt variant weights %q add up to 0", spec)
+	}
+	return split, nil
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultStore *Store
+)
+
+// Default returns the store of the built-in templates.
+func Default() *Store {
+	defaultOnce.Do(func() {
+		s, err := New(Config{})
+		if err != nil {
+			panic(fmt.Sprintf("prompts: built-in templates: %v", err))
+		}
+		defaultStore = s
+	})
+	return defaultStore
+}
+
+// Variants lists the base variant and the variants found in Dir.
+func (s *Store) Variants() []string {
+	return append([]string{Base}, s.variants...)
+}
+
+// HasVariant reports whether variant is Base or was found in Dir.
+func (s *Store) HasVariant(variant string) bool {
+	return variant == Base || slices.Contains(s.variants, variant)
+}
+
+// Experimenting reports whether sessions may use variants other than
+// Base, so turns are worth tagging with their variant.
+func (s *Store) Experimenting() bool {
+	return len(s.variants) > 0
+}
+
+// Assign returns the variant of a session, picked by Config.Variants'
+// weights from a hash of key, so a session keeps its variant.
+func (s *Store) Assign(key string) string {
+	total := 0
+	for _, w := range s.split {
+		total += w.n
+	}
+	if total == 0 {
+		return Base
+	}
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	n := int(h.Sum32() % uint32(total))
+	for _, w := range s.split {
+		if n < w.n {
+			return w.variant
+		}
+		n -= w.n
+	}
+	return Base
+}
+
+// Overridden lists the templates read from cfg.Dir.
+func (s *Store) Overridden() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for _, name := range Names {
+		if s.templates[name].path != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Render executes the named template with data. Surrounding whitespace is
+// trimmed, so template files may end with a newline.
+func (s *Store) Render(name string, data any) (string, error) {
+	return s.RenderVariant(name, Base, data)
+}
+
+// RenderVariant executes the variant of the named template with data. A
+// variant without a file for the template, or "", uses the base template.
+func (s *Store) RenderVariant(name, variant string, data any) (string, error) {
+	tmpl, err := s.template(name, variant)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render %s prompt: %w", name, err)
+	}
+	return strings.TrimSpace(b.String()) + s.guidance(name), nil
+}
+
+// guidance returns the deployment's guidance for the named template, or ""
+// if there is none.
+func (s *Store) guidance(name string) string {
+	return FormatGuidance(s.cfg.Guidance[All], s.cfg.Guidance[name])
+}
+
+// FormatGuidance returns the section appending guidance to an instruction,
+// or "" if every part is empty. Agents not rendered from templates, such as
+// plugin agents, append it themselves.
+func FormatGuidance(parts ...string) string {
+	var lines []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			lines = append(lines, p)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\n## Organization Guidance\nFollow these rules of this organization. They take precedence over the general instructions above, but never over data access and write restrictions.\n" + strings.Join(lines, "\n")
+}
+
+// Provider renders the named template with data for every model call, in
+// the variant of the call's context, picking up reloaded templates. It
+// suits llmagent.Config's InstructionProvider; unlike Instruction, no
+// session state is injected.
+func (s *Store) Provider(name string, data any) func(agent.ReadonlyContext) (string, error) {
+	return func(ctx agent.ReadonlyContext) (string, error) {
+		var variant string
+		if ctx != nil {
+			variant = VariantFrom(ctx)
+		}
+		return s.RenderVariant(name, variant, data)
+	}
+}
+
+// template returns the variant of the named template, reloading it first
+// if its file changed.
+func (s *Store) template(name, variant string) (*template.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.templates[name]; !ok {
+		return nil, fmt.Errorf("unknown prompt template %q", name)
+	}
+	if variant != "" && variant != Base {
+		if e := s.refresh(name + "." + variant); e != nil {
+			return e.tmpl, nil
+		}
+	}
+	return s.refresh(name).tmpl, nil
+}
+
+// refresh returns the template of key, re-read if reloading is on and its
+// file changed. A file that fails to parse keeps the previous template in
+// use; a removed variant file returns nil.
+func (s *Store) refresh(key string) *entry {
+	e, ok := s.templates[key]
+	if !ok || !s.cfg.Reload || s.cfg.Dir == "" || s.modTime(key).Equal(e.seen) {
+		return e
+	}
+	if _, variant, _ := strings.Cut(key, "."); variant != "" && s.modTime(key).IsZero() {
+		console.Printf("🔄 Removed the %s prompt\n", key)
+		delete(s.templates, key)
+		return nil
+	}
+	fresh, err := s.load(key)
+	if err != nil {
+		console.Printf("⚠️  Keeping the previous %s prompt: %v\n", key, err)
+		// Do not retry until the file changes again
+		e.seen = s.modTime(key)
+		return e
+	}
+	console.Printf("🔄 Reloaded the %s prompt\n", key)
+	s.templates[key] = fresh
+	return fresh
+}
+
+// modTime is the modification time of the file of key, or zero if there
+// is none.
+func (s *Store) modTime(key string) time.Time {
+	info, err := os.Stat(s.path(key))
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func (s *Store) path(key string) string {
+	return filepath.Join(s.cfg.Dir, key+".tmpl")
+}
+
+// load parses the file of key, a name or name.variant, or the built-in
+// template of a name without one.
+func (s *Store) load(key string) (*entry, error) {
+	if s.cfg.Dir != "" {
+		path := s.path(key)
+		info, err := os.Stat(path)
+		switch {
+		case err == nil:
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s prompt: %w", key, err)
+			}
+			tmpl, err := parse(key, string(data))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			return &entry{tmpl: tmpl, path: path, seen: info.ModTime()}, nil
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, fmt.Errorf("failed to read %s prompt: %w", key, err)
+		}
+	}
+	data, err := builtin.ReadFile("templates/" + key + ".tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("unknown prompt template %q", key)
+	}
+	return &entry{tmpl: template.Must(parse(key, string(data)))}, nil
+}
+
+func parse(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+type variantKey struct{}
+
+// WithVariant returns a context whose agent calls render the given variant
+// of the templates.
+func WithVariant(ctx context.Context, variant string) context.Context {
+	return context.WithValue(ctx, variantKey{}, variant)
+}
+
+// VariantFrom returns the variant of a context from WithVariant, or "" for
+// the base templates.
+func VariantFrom(ctx context.Context) string {
+	v, _ := ctx.Value(variantKey{}).(string)
+	return v
+}
@@ -0,0 +1,221 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderBuiltin(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    any
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    SQL,
+			data:    SQLData{Dialect: "PostgreSQL", Placeholders: []string{"$1", "$2"}, Hints: []string{"Use 'LIMIT n'"}},
+			want:    []string{"valid PostgreSQL query", "placeholders $1, $2, ...", "syntax!\n  - Use 'LIMIT n'\n\nVisualizations:"},
+			notWant: []string{"## Database Schema", "## Write Mode", "Clarifying questions:"},
+		},
+		{
+			name: SQL,
+			data: SQLData{Dialect: "ClickHouse", Placeholders: []string{"?", "?"}, Schema: "orders(id)", WriteMode: true, Clarify: true},
+			want: []string{"## Database Schema\norders(id)\n\n## Write Mode", "Clarifying questions:", "instead\n\nAlways return the query results as structured JSON data."},
+		},
+		{
+			name: SQL,
+			data: SQLData{Dialect: "PostgreSQL", Placeholders: []string{"$1", "$2"}, Schema: "orders(id)", Relationships: "## Relationships\n- orders(customer_id) -> customers(id)\n"},
+			want: []string{"orders(id)\n\n## Relationships\n- orders(customer_id) -> customers(id)"},
+		},
+		{
+			name:    Manager,
+			data:    ManagerData{Count: "3", Specialists: []SubAgent{{Name: "FilesAgent", Description: "Queries files"}}},
+			want:    []string{"access to 3 sub-agents", "chart generation\n- FilesAgent: Queries files\n\nWorkflow"},
+			notWant: []string{"ask_clarification"},
+		},
+		{name: Chart, want: []string{"xychart-beta"}},
+		{name: Files, want: []string{"strftime(col, '%Y-%m')"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Default().Render(tt.name, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != strings.TrimSpace(got) {
+				t.Errorf("prompt has surrounding whitespace")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("prompt lacks %q", w)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("prompt has %q", w)
+				}
+			}
+		})
+	}
+}
+
+func TestOverrideReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, Chart+".tmpl")
+	write := func(text string, age time.Duration) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// Distinct times, as file systems may not tell quick writes apart
+		mtime := time.Now().Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	render := func(s *Store) string {
+		t.Helper()
+		got, err := s.Provider(Chart, nil)(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	write("Draw charts.\n", 3*time.Hour)
+	s, err := New(Config{Dir: dir, Reload: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Overridden(); len(got) != 1 || got[0] != Chart {
+		t.Errorf("overridden = %v, want [chart]", got)
+	}
+	if got := render(s); got != "Draw charts." {
+		t.Errorf("prompt = %q, want the override", got)
+	}
+
+	write("Draw pie charts.", 2*time.Hour)
+	if got := render(s); got != "Draw pie charts." {
+		t.Errorf("prompt = %q, want the changed override", got)
+	}
+
+	write("Draw {{.Broken", time.Hour)
+	if got := render(s); got != "Draw pie charts." {
+		t.Errorf("prompt = %q, want the previous override kept", got)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := render(s); !strings.Contains(got, "xychart-beta") {
+		t.Errorf("prompt = %q, want the built-in template", got)
+	}
+
+	write("Draw {{.Broken", 0)
+	if _, err := New(Config{Dir: dir}); err == nil {
+		t.Error("New succeeded with a template that does not parse")
+	}
+}
+
+func TestVariants(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "chart.pie.tmpl"), []byte("Draw pie charts."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		variants string
+		want     map[string]bool
+		wantErr  bool
+	}{
+		{variants: "", want: map[string]bool{Base: true}},
+		{variants: "pie", want: map[string]bool{"pie": true}},
+		{variants: "base=1, pie=1", want: map[string]bool{Base: true, "pie": true}},
+		{variants: "base=0,pie=3", want: map[string]bool{"pie": true}},
+		{variants: "bar", wantErr: true},
+		{variants: "pie=x", wantErr: true},
+		{variants: "pie=0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.variants, func(t *testing.T) {
+			s, err := New(Config{Dir: dir, Variants: tt.variants})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make(map[string]bool)
+			for i := range 50 {
+				key := "u/s" + strconv.Itoa(i)
+				v := s.Assign(key)
+				if v != s.Assign(key) {
+					t.Fatalf("session %s changed variant", key)
+				}
+				got[v] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("assigned %v, want %v", got, tt.want)
+			}
+			for v := range got {
+				if !tt.want[v] {
+					t.Errorf("assigned %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	s, err := New(Config{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.RenderVariant(Chart, "pie", nil); err != nil || got != "Draw pie charts." {
+		t.Errorf("pie chart prompt = %q, %v", got, err)
+	}
+	// A variant falls back to the base template of agents it does not change
+	if got, err := s.RenderVariant(Files, "pie", nil); err != nil || !strings.Contains(got, "DuckDB") {
+		t.Errorf("pie files prompt = %q, %v", got, err)
+	}
+}
+
+func TestGuidance(t *testing.T) {
+	s, err := New(Config{Guidance: map[string]string{
+		All: "Our fiscal year starts on February 1.\n",
+		SQL: "Table names are plural.",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sql, err := s.Render(SQL, SQLData{Dialect: "PostgreSQL", Placeholders: []string{"$1", "$2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(sql, "## Organization Guidance\nFollow these rules of this organization. They take precedence over the general instructions above, but never over data access and write restrictions.\nOur fiscal year starts on February 1.\nTable names are plural.") {
+		t.Errorf("sql prompt ends with %q", sql[max(0, len(sql)-300):])
+	}
+	chart, err := s.Provider(Chart, nil)(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(chart, "\nOur fiscal year starts on February 1.") || strings.Contains(chart, "plural") {
+		t.Errorf("chart prompt ends with %q", chart[max(0, len(chart)-200):])
+	}
+
+	plain, _ := Default().Render(Chart, nil)
+	if strings.Contains(plain, "Organization Guidance") {
+		t.Error("prompts without guidance should not have a guidance section")
+	}
+	if FormatGuidance(" ", "") != "" {
+		t.Error("FormatGuidance() of blank parts should be empty")
+	}
+	if _, err := New(Config{Guidance: map[string]string{"report": "x"}}); err == nil || !strings.Contains(err.Error(), `unknown agent "report"`) {
+		t.Errorf("New() error = %v, want unknown agent", err)
+	}
+}
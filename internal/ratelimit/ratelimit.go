@@ -0,0 +1,104 @@
+// Package ratelimit paces calls to shared services, such as an LLM endpoint
+// or a database replica, to a number of units per interval. Units are
+// requests, queries or tokens. Calls over the rate wait in line for their
+// turn instead of failing.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// Limiter is a token bucket refilled at a steady rate. Callers reserve
+// units in the order they arrive, so a waiting call is never overtaken by
+// a later one. A nil *Limiter never waits.
+type Limiter struct {
+	// perSecond is the refill rate and burst the bucket's capacity
+	perSecond float64
+	burst     float64
+
+	mu sync.Mutex
+	// tokens is the bucket's level at last; it is negative while calls
+	// wait for units reserved ahead of time
+	tokens float64
+	last   time.Time
+}
+
+// New creates a Limiter allowing n units per interval, up to n of them at
+// once. It returns nil if n or per is not positive.
+func New(n int, per time.Duration) *Limiter {
+	if n <= 0 || per <= 0 {
+		return nil
+	}
+	return &Limiter{
+		perSecond: float64(n) / per.Seconds(),
+		burst:     float64(n),
+		tokens:    float64(n),
+		last:      now(),
+	}
+}
+
+// Wait takes n units, waiting until they are available. If it has to wait,
+// onWait, if not nil, is told how long first. If ctx is done before the
+// units are available, they are given back and ctx's error returned.
+func (l *Limiter) Wait(ctx context.Context, n int, onWait func(time.Duration)) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+	wait := l.reserve(float64(n))
+	if wait <= 0 {
+		return nil
+	}
+	if onWait != nil {
+		onWait(wait)
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.Charge(-n)
+		return ctx.Err()
+	}
+}
+
+// Charge takes n units without waiting, e.g. the tokens a response used
+// beyond the estimate that was waited for; later calls wait longer
+// instead. A negative n gives units back.
+func (l *Limiter) Charge(n int) {
+	if l == nil || n == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.tokens = min(l.tokens-float64(n), l.burst)
+}
+
+// reserve takes n units and returns how long until the bucket has refilled
+// enough to cover them.
+func (l *Limiter) reserve(n float64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.tokens -= n
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.perSecond * float64(time.Second))
+}
+
+// refill adds the units accrued since last, up to burst.
+func (l *Limiter) refill() {
+	t := now()
+	if elapsed := t.Sub(l.last); elapsed > 0 {
+		l.tokens = min(l.tokens+elapsed.Seconds()*l.perSecond, l.burst)
+	}
+	l.last = t
+}
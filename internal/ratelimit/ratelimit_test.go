@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLimiterReserve(t *testing.T) {
+	tests := []struct {
+		name string
+		// calls are the units reserved, each after advancing the clock by
+		// the matching elapsed duration
+		calls   []int
+		elapsed []time.Duration
+		// charge is charged before the last call
+		charge    int
+		wantWaits []time.Duration
+	}{
+		{
+			name:      "within the burst",
+			calls:     []int{1, 1, 1},
+			elapsed:   []time.Duration{0, 0, 0},
+			wantWaits: []time.Duration{0, 0, 0},
+		},
+		{
+			name:      "over the burst waits in line",
+			calls:     []int{3, 1, 1},
+			elapsed:   []time.Duration{0, 0, 0},
+			wantWaits: []time.Duration{0, 20 * time.Second, 40 * time.Second},
+		},
+		{
+			name:      "refilled over time",
+			calls:     []int{3, 1},
+			elapsed:   []time.Duration{0, 30 * time.Second},
+			wantWaits: []time.Duration{0, 0},
+		},
+		{
+			name:      "refill capped at the burst",
+			calls:     []int{1, 3, 1},
+			elapsed:   []time.Duration{0, time.Hour, 0},
+			wantWaits: []time.Duration{0, 0, 20 * time.Second},
+		},
+		{
+			name:      "charged units delay later calls",
+			calls:     []int{1, 1},
+			elapsed:   []time.Duration{0, 0},
+			charge:    2,
+			wantWaits: []time.Duration{0, 20 * time.Second},
+		},
+		{
+			name:      "larger than the burst",
+			calls:     []int{6},
+			elapsed:   []time.Duration{0},
+			wantWaits: []time.Duration{time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			now = func() time.Time { return clock }
+			defer func() { now = time.Now }()
+
+			// 3 per minute: one unit every 20 seconds
+			l := New(3, time.Minute)
+			for i, n := range tt.calls {
+				clock = clock.Add(tt.elapsed[i])
+				if i == len(tt.calls)-1 {
+					l.Charge(tt.charge)
+				}
+				if got := l.reserve(float64(n)); got != tt.wantWaits[i] {
+					t.Errorf("call %d wait = %v, want %v", i, got, tt.wantWaits[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	l := New(1, time.Hour)
+	if err := l.Wait(context.Background(), 1, nil); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var waited time.Duration
+	if err := l.Wait(ctx, 1, func(d time.Duration) { waited = d }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want the deadline", err)
+	}
+	if waited < 59*time.Minute {
+		t.Errorf("reported wait = %v, want about an hour", waited)
+	}
+	// The cancelled call gave its unit back
+	if l.tokens < -0.01 {
+		t.Errorf("tokens = %v after cancelling, want none owed", l.tokens)
+	}
+
+	var unlimited *Limiter
+	if err := unlimited.Wait(context.Background(), 100, nil); err != nil {
+		t.Errorf("nil Limiter Wait() error = %v", err)
+	}
+}
@@ -0,0 +1,569 @@
+package repl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/filters"
+	"github.com/anuvratrastogi/multi-agent/internal/frame"
+	"github.com/anuvratrastogi/multi-agent/internal/llm"
+	"github.com/anuvratrastogi/multi-agent/internal/report"
+)
+
+var (
+	// errQuit ends the REPL.
+	errQuit = errors.New("quit")
+	// errUsage prints the command's usage line.
+	errUsage = errors.New("usage")
+)
+
+// command is a slash-command handled locally without calling the agents.
+type command struct {
+	name string
+	args string
+	help string
+	run  func(r *REPL, ctx context.Context, args string) error
+}
+
+func builtinCommands() []command {
+	return []command{
+		{name: "help", help: "Show this list of commands", run: (*REPL).help},
+		{name: "history", help: "List the questions asked in this session", run: (*REPL).showHistory},
+		{name: "tables", help: "List the tables in the database", run: (*REPL).tables},
+		{name: "schema", args: "<table>|refresh", help: "Show the columns of a table, or describe the database again for the agents", run: (*REPL).schema},
+		{name: "diagram", args: "[table ...]", help: "Draw the tables and their relationships as a Mermaid ER diagram", run: (*REPL).diagram},
+		{name: "reset", help: "Start a new session with an empty conversation", run: (*REPL).reset},
+		{name: "session", args: "[list|new [name]|switch <name>]", help: "List, create or switch between your sessions", run: (*REPL).session},
+		{name: "user", args: "[name]", help: "Show or switch the active user", run: (*REPL).user},
+		{name: "model", args: "[name]", help: "Show or switch the model used by the agents", run: (*REPL).model},
+		{name: "models", args: "[number|name]", help: "List the provider's models, or switch to one of them", run: (*REPL).models},
+		{name: "variant", args: "[name|auto]", help: "Show or pin the prompt variant of this session", run: (*REPL).variant},
+		{name: "export", args: "[file.csv|file.json]", help: "Save the last query result to a file", run: (*REPL).export},
+		{name: "reshape", args: "<pipeline>", help: "Sort, group, pivot or take the top rows of the last result, e.g. 'group region: sum(revenue) | top 5'", run: (*REPL).reshape},
+		{name: "transcript", args: "[file.md|file.html]", help: "Save this session's questions and answers as a report", run: (*REPL).saveTranscript},
+		{name: "dryrun", args: "[on|off]", help: "Generate SQL without executing it", run: (*REPL).dryRunMode},
+		{name: "audit", args: "[n]", help: "Show the n most recent audited queries", run: (*REPL).audit},
+		{name: "fresh", help: "Answer the last question again without the answer cache", run: (*REPL).fresh},
+		{name: "clearfilters", help: "Forget the filters applied so far so follow-ups start unscoped", run: (*REPL).clearFilters},
+		{name: "trace", help: "Show the models' reasoning for the last answer", run: (*REPL).trace},
+		{name: "quit", help: "Exit (same as 'quit' or 'exit')", run: func(*REPL, context.Context, string) error { return errQuit }},
+	}
+}
+
+// dispatch runs a slash-command. It returns true if the REPL should exit.
+func (r *REPL) dispatch(ctx context.Context, input string) bool {
+	name, args, _ := strings.Cut(strings.TrimPrefix(input, "/"), " ")
+	args = strings.TrimSpace(args)
+
+	for _, c := range r.commands {
+		if c.name != strings.ToLower(name) {
+			continue
+		}
+		err := c.run(r, ctx, args)
+		switch {
+		case errors.Is(err, errQuit):
+			return true
+		case errors.Is(err, errUsage):
+			console.Printf("\n❌ Usage: /%s %s\n\n", c.name, c.args)
+		case err != nil:
+			console.Printf("\n%s\n\n", console.Red(fmt.Sprintf("❌ Error: %v", err)))
+		}
+		return false
+	}
+
+	console.Printf("\n❌ Unknown command /%s. Type /help for a list of commands.\n\n", name)
+	return false
+}
+
+func (r *REPL) help(_ context.Context, _ string) error {
+	console.Println()
+	w := tabwriter.NewWriter(console.Out, 0, 0, 2, ' ', 0)
+	for _, c := range r.commands {
+		fmt.Fprintf(w, "  /%s %s\t%s\n", c.name, c.args, c.help)
+	}
+	w.Flush()
+	console.Println()
+	return nil
+}
+
+func (r *REPL) showHistory(_ context.Context, _ string) error {
+	if len(r.history) == 0 {
+		console.Print("\n💡 No questions asked in this session yet.\n\n")
+		return nil
+	}
+	console.Println()
+	for i, q := range r.history {
+		console.Printf("%3d  %s\n", i+1, q)
+	}
+	console.Println()
+	return nil
+}
+
+func (r *REPL) tables(ctx context.Context, _ string) error {
+	if r.cfg.DB == nil {
+		return fmt.Errorf("no database configured")
+	}
+	data, err := r.cfg.DB.ListTables(ctx)
+	if err != nil {
+		return err
+	}
+
+	var tables []string
+	if err := json.Unmarshal([]byte(data), &tables); err != nil {
+		return fmt.Errorf("failed to decode tables: %w", err)
+	}
+	console.Println()
+	for _, t := range tables {
+		console.Printf("  📁 %s\n", t)
+	}
+	console.Printf("\n%d table(s)\n\n", len(tables))
+	return nil
+}
+
+func (r *REPL) schema(ctx context.Context, table string) error {
+	if table == "" {
+		return errUsage
+	}
+	if table == "refresh" {
+		return r.refreshSchema(ctx)
+	}
+	if r.cfg.DB == nil {
+		return fmt.Errorf("no database configured")
+	}
+	data, err := r.cfg.DB.GetSchema(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	columns, rows, err := app.Rows(data)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("table %q not found", table)
+	}
+	console.Println()
+	printTable(columns, rows)
+	console.Println()
+	return nil
+}
+
+func (r *REPL) diagram(ctx context.Context, args string) error {
+	if r.cfg.DB == nil {
+		return fmt.Errorf("no database configured")
+	}
+	diagram, err := sqlagent.ERDiagram(ctx, r.cfg.DB, strings.Fields(args))
+	if err != nil {
+		return err
+	}
+	console.Printf("\n%s\n\n", diagram)
+	return nil
+}
+
+func (r *REPL) refreshSchema(ctx context.Context) error {
+	if r.cfg.Schema == nil {
+		return fmt.Errorf("no database configured")
+	}
+	console.Println("\n📋 Describing the database...")
+	diff, err := r.cfg.Schema.Refresh(ctx)
+	if err != nil {
+		return err
+	}
+	if diff.Empty() {
+		console.Printf("✅ Schema unchanged (version %s)\n\n", r.cfg.Schema.Hash())
+		return nil
+	}
+	console.Printf("✅ Schema refreshed: %s (version %s)\n\n", diff, r.cfg.Schema.Hash())
+	return nil
+}
+
+func (r *REPL) reset(ctx context.Context, _ string) error {
+	return r.session(ctx, "new")
+}
+
+func (r *REPL) session(ctx context.Context, args string) error {
+	sub, name, _ := strings.Cut(args, " ")
+	name = strings.TrimSpace(name)
+	fromUser, fromSession := r.sessions.Current()
+
+	switch strings.ToLower(sub) {
+	case "", "list":
+		list, err := r.sessions.List(ctx)
+		if err != nil {
+			return err
+		}
+		console.Printf("\n👤 Sessions of %s:\n", fromUser)
+		w := tabwriter.NewWriter(console.Out, 0, 0, 2, ' ', 0)
+		for _, s := range list {
+			marker := " "
+			if s.ID == fromSession {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "  %s %s\t%d events\tupdated %s\n", marker, s.ID, s.Events, s.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		w.Flush()
+		console.Println()
+	case "new":
+		created, err := r.sessions.Create(ctx, name)
+		if err != nil {
+			return err
+		}
+		r.switchLocal(fromUser, fromSession)
+		console.Printf("\n🆕 Started %s\n\n", created)
+	case "switch":
+		if name == "" {
+			return errUsage
+		}
+		if err := r.sessions.Switch(ctx, name); err != nil {
+			return err
+		}
+		r.switchLocal(fromUser, fromSession)
+		console.Printf("\n🔀 Switched to %s (%d questions asked here)\n\n", name, len(r.history))
+	default:
+		return errUsage
+	}
+	return nil
+}
+
+func (r *REPL) user(ctx context.Context, name string) error {
+	fromUser, fromSession := r.sessions.Current()
+	if name == "" {
+		console.Printf("\n👤 User: %s (session %s)\n\n", fromUser, fromSession)
+		return nil
+	}
+	session, err := r.sessions.SwitchUser(ctx, name)
+	if err != nil {
+		return err
+	}
+	r.switchLocal(fromUser, fromSession)
+	console.Printf("\n👤 Switched to user %s, session %s\n\n", name, session)
+	return nil
+}
+
+// switchLocal stashes the local state of the session that was active and
+// restores that of the active session, or starts it empty.
+func (r *REPL) switchLocal(fromUser, fromSession string) {
+	r.stashed[fromUser+"/"+fromSession] = localState{history: r.history, last: r.last, transcript: r.transcript}
+
+	user, session := r.sessions.Current()
+	key := user + "/" + session
+	s, ok := r.stashed[key]
+	if !ok {
+		s = localState{transcript: report.Transcript{Created: time.Now()}}
+	}
+	delete(r.stashed, key)
+	r.history, r.last, r.transcript = s.history, s.last, s.transcript
+}
+
+func (r *REPL) model(ctx context.Context, name string) error {
+	if r.cfg.Model == nil {
+		return fmt.Errorf("switching models is not supported")
+	}
+	if name == "" {
+		console.Printf("\n🧠 Model: %s\n\n", r.cfg.Model.Name())
+		return nil
+	}
+	if r.cfg.NewModel == nil {
+		return fmt.Errorf("switching models is not supported")
+	}
+	if r.cfg.ListModels != nil {
+		models, err := r.cfg.ListModels(ctx)
+		if err == nil && !llm.HasModel(models, name) {
+			return fmt.Errorf("model %s is not available; /models lists the models", name)
+		}
+	}
+
+	next, err := r.cfg.NewModel(ctx, name)
+	if err != nil {
+		return err
+	}
+	r.cfg.Model.Set(next)
+	console.Printf("\n🧠 Switched model to %s\n\n", next.Name())
+	return nil
+}
+
+func (r *REPL) models(ctx context.Context, arg string) error {
+	if r.cfg.ListModels == nil {
+		return fmt.Errorf("listing models is not supported")
+	}
+	models, err := r.cfg.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+	if arg != "" {
+		if n, err := strconv.Atoi(arg); err == nil {
+			if n < 1 || n > len(models) {
+				return fmt.Errorf("there is no model %d", n)
+			}
+			arg = models[n-1]
+		}
+		return r.model(ctx, arg)
+	}
+
+	current := ""
+	if r.cfg.Model != nil {
+		current = r.cfg.Model.Name()
+	}
+	console.Printf("\n🧠 Models (%d):\n", len(models))
+	for i, m := range models {
+		marker := " "
+		if m == current || m == current+":latest" {
+			marker = "*"
+		}
+		console.Printf("  %s %3d. %s\n", marker, i+1, m)
+	}
+	console.Println("\nSwitch with /models <number> or /models <name>.")
+	console.Println()
+	return nil
+}
+
+func (r *REPL) variant(_ context.Context, name string) error {
+	userID, sessionID := r.sessions.Current()
+	switch name {
+	case "":
+		console.Printf("\n🧪 Prompt variant: %s (variants: %s)\n\n", r.cfg.App.PromptVariant(userID, sessionID), strings.Join(r.cfg.App.PromptVariants(), ", "))
+		return nil
+	case "auto":
+		name = ""
+	}
+	if err := r.cfg.App.SetPromptVariant(userID, sessionID, name); err != nil {
+		return err
+	}
+	console.Printf("\n🧪 Prompt variant: %s\n\n", r.cfg.App.PromptVariant(userID, sessionID))
+	return nil
+}
+
+// lastResult returns the latest query of the last turn with results.
+func (r *REPL) lastResult() *app.Query {
+	if r.last == nil {
+		return nil
+	}
+	for i := len(r.last.Queries) - 1; i >= 0; i-- {
+		if r.last.Queries[i].Data != "" {
+			return &r.last.Queries[i]
+		}
+	}
+	return nil
+}
+
+func (r *REPL) export(_ context.Context, path string) error {
+	q := r.lastResult()
+	if q == nil {
+		return fmt.Errorf("no query result to export yet")
+	}
+
+	if path == "" {
+		path = "result-" + time.Now().Format("20060102-150405") + ".csv"
+	}
+
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	if format != app.FormatCSV && format != app.FormatJSON {
+		return errUsage
+	}
+	data, err := q.Export(format)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	console.Printf("\n💾 Exported result of %q to %s\n\n", q.SQL, path)
+	return nil
+}
+
+// exportSteps carries out the export steps of a compound request, saving
+// the last query result to a timestamped file as the step asks. Steps the
+// App already saved as artifacts are done.
+func (r *REPL) exportSteps(ctx context.Context, turn *app.Turn) {
+	if turn == nil || turn.Routing == nil || turn.DryRun || len(turn.Queries) == 0 {
+		return
+	}
+	for _, step := range turn.Routing.Steps {
+		if step.Agent != manager.ExportStep || step.Status == manager.StepDone {
+			continue
+		}
+		ext := "." + app.ExportFormat(step.Task)
+		if err := r.export(ctx, "result-"+time.Now().Format("20060102-150405")+ext); err != nil {
+			console.Printf("❌ %s: %v\n\n", step.Task, err)
+		}
+	}
+}
+
+// reshape transforms the last result in memory and makes the outcome the
+// last result, so /export saves it and further steps build on it.
+func (r *REPL) reshape(_ context.Context, pipeline string) error {
+	if pipeline == "" {
+		return errUsage
+	}
+	q := r.lastResult()
+	if q == nil {
+		return fmt.Errorf("no query result to reshape yet")
+	}
+	res := frame.Transform(frame.Result{SQL: q.SQL, Data: q.Data}, pipeline, 0)
+	if res.Error != "" {
+		return errors.New(res.Error)
+	}
+	r.last.Queries = append(r.last.Queries, app.Query{SQL: res.SQL, Data: res.Data})
+	printResults(r.last.Queries[len(r.last.Queries)-1:])
+	console.Println()
+	return nil
+}
+
+func (r *REPL) saveTranscript(_ context.Context, path string) error {
+	if len(r.transcript.Entries) == 0 {
+		return fmt.Errorf("nothing to save yet")
+	}
+	if path == "" {
+		path = "transcript-" + time.Now().Format("20060102-150405") + ".md"
+	}
+
+	t := r.transcript
+	_, sessionID := r.sessions.Current()
+	t.Title = "Analysis transcript (" + sessionID + ")"
+
+	var buf strings.Builder
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md":
+		err = report.WriteTranscriptMarkdown(&buf, t)
+	case ".html", ".htm":
+		err = report.WriteTranscriptHTML(&buf, t)
+	default:
+		return errUsage
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render transcript: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(buf.String()), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	console.Printf("\n📝 Saved %d exchanges to %s\n\n", len(t.Entries), path)
+	return nil
+}
+
+func (r *REPL) dryRunMode(_ context.Context, arg string) error {
+	switch arg {
+	case "on":
+		r.dryRun = true
+	case "off":
+		r.dryRun = false
+	case "":
+		r.dryRun = !r.dryRun
+	default:
+		return errUsage
+	}
+	if r.dryRun {
+		console.Print("\n🧪 Dry-run mode on: SQL is generated but not executed.\n\n")
+	} else {
+		console.Print("\n🧪 Dry-run mode off.\n\n")
+	}
+	return nil
+}
+
+// audit shows the most recent audited queries. arg is an optional count.
+func (r *REPL) audit(_ context.Context, arg string) error {
+	if r.cfg.AuditLog == nil {
+		console.Print("\n💡 Audit log is disabled. Set AUDIT_LOG_FILE to enable it.\n\n")
+		return nil
+	}
+
+	n := 10
+	if arg != "" {
+		v, err := strconv.Atoi(arg)
+		if err != nil || v <= 0 {
+			return errUsage
+		}
+		n = v
+	}
+
+	entries, err := r.cfg.AuditLog.Recent(n)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		console.Print("\n💡 No queries recorded yet.\n\n")
+		return nil
+	}
+
+	console.Println()
+	for _, e := range entries {
+		status := fmt.Sprintf("%d rows", e.RowCount)
+		if e.Error != "" {
+			status = "❌ " + e.Error
+		}
+		console.Printf("🗒️  %s [%s/%s] %dms %s\n    %s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), e.UserID, e.SessionID, e.DurationMS, status, e.SQL)
+		if len(e.Args) > 0 {
+			console.Printf("    args: %v\n", e.Args)
+		}
+	}
+	console.Println()
+	return nil
+}
+
+// fresh asks the last question again, bypassing the answer cache.
+func (r *REPL) fresh(ctx context.Context, _ string) error {
+	if len(r.history) == 0 {
+		console.Print("\n💡 No question to recompute yet.\n\n")
+		return nil
+	}
+	r.ask(ctx, r.history[len(r.history)-1], true)
+	return nil
+}
+
+// clearFilters drops the session's filter scope.
+func (r *REPL) clearFilters(_ context.Context, _ string) error {
+	userID, sessionID := r.sessions.Current()
+	scope := r.cfg.App.Filters(userID, sessionID)
+	r.cfg.App.ClearFilters(userID, sessionID)
+	if len(scope) == 0 {
+		console.Print("\n💡 No filters are applied.\n\n")
+		return nil
+	}
+	console.Printf("\n🧹 Cleared filters: %s\n\n", filters.Format(scope))
+	return nil
+}
+
+// trace shows the reasoning captured during the latest exchange.
+func (r *REPL) trace(_ context.Context, _ string) error {
+	if !r.cfg.App.KeepsReasoning() {
+		console.Print("\n💡 Reasoning is not kept. Set KEEP_REASONING=true to capture it.\n\n")
+		return nil
+	}
+
+	entries := r.transcript.Entries
+	if len(entries) == 0 || entries[len(entries)-1].Turn == nil || len(entries[len(entries)-1].Turn.Reasoning) == 0 {
+		console.Print("\n💡 No reasoning was recorded for the last answer.\n\n")
+		return nil
+	}
+
+	console.Println()
+	for _, t := range entries[len(entries)-1].Turn.Reasoning {
+		console.Printf("🧠 [%s]\n%s\n\n", t.Agent, t.Text)
+	}
+	return nil
+}
+
+// printTable writes rows as aligned columns.
+func printTable(columns []string, rows [][]string) {
+	w := tabwriter.NewWriter(console.Out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  "+strings.Join(columns, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, "  "+strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
@@ -0,0 +1,104 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+)
+
+// capture sends the console output to a buffer for the rest of the test.
+func capture(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	out := console.Out
+	console.Out = &buf
+	t.Cleanup(func() { console.Out = out })
+	return &buf
+}
+
+func TestDispatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		history  []string
+		wantQuit bool
+		wantOut  string
+		wantDry  bool
+	}{
+		{name: "help lists commands", input: "/help", wantOut: "/export [file.csv|file.json]"},
+		{name: "name is case-insensitive", input: "/HELP", wantOut: "/history"},
+		{name: "empty history", input: "/history", wantOut: "No questions asked"},
+		{name: "history", input: "/history", history: []string{"top customers", "by region"}, wantOut: "  2  by region"},
+		{name: "arguments are trimmed", input: "/dryrun   on  ", wantOut: "Dry-run mode on", wantDry: true},
+		{name: "bad argument prints usage", input: "/dryrun maybe", wantOut: "Usage: /dryrun [on|off]"},
+		{name: "errors are printed", input: "/export out.csv", wantOut: "no query result to export yet"},
+		{name: "disabled audit log", input: "/audit", wantOut: "Audit log is disabled"},
+		{name: "unknown command", input: "/frobnicate now", wantOut: "Unknown command /frobnicate"},
+		{name: "quit", input: "/quit", wantQuit: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := capture(t)
+			r := &REPL{commands: builtinCommands(), history: tt.history}
+			if quit := r.dispatch(context.Background(), tt.input); quit != tt.wantQuit {
+				t.Errorf("dispatch(%q) = %v, want %v", tt.input, quit, tt.wantQuit)
+			}
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("dispatch(%q) printed %q, want %q", tt.input, out.String(), tt.wantOut)
+			}
+			if r.dryRun != tt.wantDry {
+				t.Errorf("dryRun = %v, want %v", r.dryRun, tt.wantDry)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	last := &app.Turn{Queries: []app.Query{
+		{SQL: "SELECT name, total FROM orders", Data: `[{"name":"Ann","total":12.5},{"name":"Bo, Jr.","total":3}]`},
+		{SQL: "SELECT broken", Error: "syntax error"},
+	}}
+
+	tests := []struct {
+		file    string
+		want    string
+		wantOut string
+	}{
+		{file: "out.csv", want: "name,total\nAnn,12.5\n\"Bo, Jr.\",3\n", wantOut: "Exported result"},
+		{file: "out.JSON", want: "[\n  {\n    \"name\": \"Ann\",\n    \"total\": 12.5\n  },\n  {\n    \"name\": \"Bo, Jr.\",\n    \"total\": 3\n  }\n]\n", wantOut: "Exported result"},
+		{file: "out.xlsx", wantOut: "Usage: /export [file.csv|file.json]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			out := capture(t)
+			path := filepath.Join(t.TempDir(), tt.file)
+			r := &REPL{commands: builtinCommands(), last: last}
+			r.dispatch(context.Background(), "/export "+path)
+			if !strings.Contains(out.String(), tt.wantOut) {
+				t.Errorf("/export printed %q, want %q", out.String(), tt.wantOut)
+			}
+
+			data, err := os.ReadFile(path)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("/export wrote %s, want no file", tt.file)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("/export wrote\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/peterh/liner"
+)
+
+const (
+	prompt             = "You: "
+	continuationPrompt = "...  "
+)
+
+// sqlStart matches input that begins like a SQL statement. Such input may
+// span several lines and ends with ";" or an empty line. Only the shape of
+// a statement counts, not its first word, so questions such as "Create a
+// bar chart of sales" or "With last year's numbers, ..." stay single lines:
+// SELECT needs a select list ("*", a column followed by ",", FROM or AS, or
+// the end of the line), WITH a "name AS (", and the other statements the
+// keywords that follow theirs.
+var sqlStart = regexp.MustCompile(`(?i)^\s*(` +
+	`SELECT\s+(DISTINCT\s+)?(\*|[\w."]+(\(.*?\))?\s*(,|;|$|(FROM|AS)\b))` +
+	`|WITH\s+(RECURSIVE\s+)?\w+\s+AS\s*\(` +
+	`|INSERT\s+INTO\s|UPDATE\s+[\w."]+\s+SET\s|DELETE\s+FROM\s` +
+	`|EXPLAIN\s+(ANALYZE\s+|VERBOSE\s+|\(.*?\)\s*)*(SELECT|WITH|INSERT|UPDATE|DELETE)\b` +
+	`|CREATE\s+(OR\s+REPLACE\s+)?(TEMP(ORARY)?\s+|UNIQUE\s+|MATERIALIZED\s+)?(TABLE|VIEW|INDEX)\b` +
+	`|(ALTER|DROP)\s+(TABLE|VIEW|INDEX|MATERIALIZED\s+VIEW)\b)`)
+
+// input reads entries from the user. On a terminal it provides line editing,
+// persistent history and Ctrl+R search; otherwise it reads plain lines so
+// input can be piped in.
+type input struct {
+	line        *liner.State
+	scanner     *bufio.Scanner
+	historyFile string
+}
+
+// newInput creates an input reading from in. Line editing is only enabled
+// when in is the terminal.
+func newInput(in io.Reader, historyFile string, commands []string) *input {
+	f, ok := in.(*os.File)
+	if !ok || f != os.Stdin || !liner.TerminalSupported() || !isTerminal(f) {
+		return &input{scanner: bufio.NewScanner(in)}
+	}
+
+	line := liner.NewLiner()
+	line.SetCtrlCAborts(true)
+	line.SetMultiLineMode(true)
+	line.SetCompleter(func(text string) []string {
+		if !strings.HasPrefix(text, "/") {
+			return nil
+		}
+		var matches []string
+		for _, c := range commands {
+			if strings.HasPrefix("/"+c, text) {
+				matches = append(matches, "/"+c)
+			}
+		}
+		return matches
+	})
+
+	if historyFile != "" {
+		if h, err := os.Open(historyFile); err == nil {
+			line.ReadHistory(h)
+			h.Close()
+		}
+	}
+	return &input{line: line, historyFile: historyFile}
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// readLine reads a single line. It returns io.EOF when input ends.
+func (in *input) readLine(p string) (string, error) {
+	if in.line == nil {
+		console.Print(console.Bold(p))
+		if !in.scanner.Scan() {
+			if err := in.scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return in.scanner.Text(), nil
+	}
+
+	text, err := in.line.Prompt(p)
+	if errors.Is(err, liner.ErrPromptAborted) {
+		// Ctrl+C discards the current line
+		return "", nil
+	}
+	return text, err
+}
+
+// read reads one entry: a question, a command, or a multi-line SQL statement
+// terminated by ";" or an empty line. Lines ending in "\" also continue.
+func (in *input) read() (string, error) {
+	first, err := in.readLine(prompt)
+	if err != nil {
+		return "", err
+	}
+	entry := strings.TrimSpace(first)
+
+	multiline := sqlStart.MatchString(entry) && !strings.HasSuffix(entry, ";")
+	if strings.HasSuffix(entry, `\`) {
+		entry = strings.TrimRight(strings.TrimSuffix(entry, `\`), " \t")
+		multiline = true
+	}
+
+	lines := []string{entry}
+	for multiline {
+		next, err := in.readLine(continuationPrompt)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		next = strings.TrimRight(next, " \t")
+		if strings.TrimSpace(next) == "" {
+			break
+		}
+
+		multiline = !strings.HasSuffix(next, ";")
+		if strings.HasSuffix(next, `\`) {
+			next = strings.TrimRight(strings.TrimSuffix(next, `\`), " \t")
+			multiline = true
+		}
+		lines = append(lines, next)
+	}
+
+	entry = strings.TrimSpace(strings.Join(lines, "\n"))
+	if in.line != nil && entry != "" {
+		in.line.AppendHistory(strings.Join(strings.Fields(entry), " "))
+	}
+	return entry, nil
+}
+
+// Close restores the terminal and saves the history.
+func (in *input) Close() error {
+	if in.line == nil {
+		return nil
+	}
+	if in.historyFile != "" {
+		if f, err := os.OpenFile(in.historyFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600); err == nil {
+			if _, err := in.line.WriteHistory(f); err != nil {
+				log.Printf("⚠️  Failed to save history: %v", err)
+			}
+			f.Close()
+		}
+	}
+	return in.line.Close()
+}
@@ -0,0 +1,77 @@
+package repl
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInputRead(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{
+			name: "single line questions",
+			in:   "how many orders?\n/tables\n",
+			want: []string{"how many orders?", "/tables"},
+		},
+		{
+			name: "sql ends with semicolon",
+			in:   "SELECT id,\n  name\nFROM users;\nnext question\n",
+			want: []string{"SELECT id,\n  name\nFROM users;", "next question"},
+		},
+		{
+			name: "sql ends with empty line",
+			in:   "with t as (select 1)\nselect * from t\n\nafter\n",
+			want: []string{"with t as (select 1)\nselect * from t", "after"},
+		},
+		{
+			name: "single line sql",
+			in:   "select 1;\n",
+			want: []string{"select 1;"},
+		},
+		{
+			name: "backslash continuation",
+			in:   "show revenue \\\nby region\n",
+			want: []string{"show revenue\nby region"},
+		},
+		{
+			name: "questions starting with sql keywords",
+			in:   "Create a bar chart of sales by month\nWith last year's numbers, how did we do?\nSelect the top 5 customers by revenue\nUpdate me on open orders\nExplain the revenue drop\n",
+			want: []string{"Create a bar chart of sales by month", "With last year's numbers, how did we do?", "Select the top 5 customers by revenue", "Update me on open orders", "Explain the revenue drop"},
+		},
+		{
+			name: "statements by their shape",
+			in:   "create table t (\n id int);\nselect count(*)\nfrom t;\nexplain analyze select 1\n\n",
+			want: []string{"create table t (\n id int);", "select count(*)\nfrom t;", "explain analyze select 1"},
+		},
+		{
+			name: "sql at end of input",
+			in:   "SELECT *\nFROM orders",
+			want: []string{"SELECT *\nFROM orders"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := newInput(strings.NewReader(tt.in), "", nil)
+			var got []string
+			for {
+				entry, err := in.read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("read() error = %v", err)
+				}
+				got = append(got, entry)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("read() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
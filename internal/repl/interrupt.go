@@ -0,0 +1,49 @@
+package repl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+)
+
+// Interrupter lets Ctrl+C cancel the REPL's running turn instead of the
+// whole program. The zero value is ready to use.
+type Interrupter struct {
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// Interrupt cancels the running turn and reports true. It reports false if
+// no turn is running or the turn was already cancelled, so a second Ctrl+C
+// exits.
+func (i *Interrupter) Interrupt() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.cancel == nil || i.cancelled {
+		return false
+	}
+	i.cancelled = true
+	i.cancel()
+	console.Println("\n⛔ Cancelling the current turn (press Ctrl+C again to exit)")
+	return true
+}
+
+// start returns a context for a turn that Interrupt cancels. end must be
+// called once the turn returns; it reports whether the turn was cancelled.
+func (i *Interrupter) start(ctx context.Context) (turnCtx context.Context, end func() (cancelled bool)) {
+	turnCtx, cancel := context.WithCancel(ctx)
+
+	i.mu.Lock()
+	i.cancel, i.cancelled = cancel, false
+	i.mu.Unlock()
+
+	return turnCtx, func() bool {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		cancel()
+		i.cancel = nil
+		return i.cancelled
+	}
+}
@@ -0,0 +1,38 @@
+package repl
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInterrupter(t *testing.T) {
+	var i Interrupter
+	if i.Interrupt() {
+		t.Fatal("Interrupt() with no running turn = true, want false")
+	}
+
+	ctx, end := i.start(context.Background())
+	if !i.Interrupt() {
+		t.Fatal("first Interrupt() during a turn = false, want true")
+	}
+	if ctx.Err() == nil {
+		t.Error("turn context not cancelled")
+	}
+	if i.Interrupt() {
+		t.Error("second Interrupt() during a turn = true, want false")
+	}
+	if !end() {
+		t.Error("end() = false, want true for a cancelled turn")
+	}
+
+	ctx, end = i.start(context.Background())
+	if end() {
+		t.Error("end() = true, want false for a finished turn")
+	}
+	if ctx.Err() == nil {
+		t.Error("turn context not released by end()")
+	}
+	if i.Interrupt() {
+		t.Error("Interrupt() after the turn ended = true, want false")
+	}
+}
@@ -0,0 +1,340 @@
+// Package repl implements the interactive command-line front-end.
+package repl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/audit"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/filters"
+	"github.com/anuvratrastogi/multi-agent/internal/llm"
+	"github.com/anuvratrastogi/multi-agent/internal/report"
+	"github.com/anuvratrastogi/multi-agent/internal/schema"
+	"github.com/anuvratrastogi/multi-agent/internal/sessions"
+	"google.golang.org/adk/model"
+)
+
+// Config holds configuration for the REPL.
+type Config struct {
+	// App executes the user's questions
+	App *app.App
+	// Events shows the intent and planned workflow of each question (optional)
+	Events *events.Bus
+	// DB answers /tables and /schema without involving the agents
+	DB sqlagent.MCPClient
+	// Schema is the schema the agents are prompted with, refreshed by
+	// /schema refresh (optional)
+	Schema *schema.Cache
+	// AuditLog backs the /audit command (optional)
+	AuditLog *audit.Log
+	// Model is the model shared by the agents, swapped by /model (optional)
+	Model *llm.Switchable
+	// NewModel creates a model by name for /model (optional)
+	NewModel func(ctx context.Context, name string) (model.LLM, error)
+	// ListModels lists the models of the provider for /models, and checks
+	// the models switched to (optional)
+	ListModels func(ctx context.Context) ([]string, error)
+	// UserID identifies the local user at start (defaults to sessions.DefaultUser)
+	UserID string
+	// SessionID is the session to start in (defaults to sessions.DefaultSession)
+	SessionID string
+	// HistoryFile persists input history between runs (optional)
+	HistoryFile string
+	// Interrupter cancels the running turn when the user presses Ctrl+C
+	// (optional)
+	Interrupter *Interrupter
+}
+
+// REPL reads questions and commands from the terminal.
+type REPL struct {
+	cfg      Config
+	commands []command
+	in       *input
+
+	sessions *sessions.Manager
+	dryRun   bool
+	history  []string
+	last     *app.Turn
+	// transcript holds every exchange in the session for /transcript
+	transcript report.Transcript
+	// stashed holds the history, last result and transcript of the
+	// sessions switched away from, by user and session
+	stashed map[string]localState
+}
+
+// localState is what the REPL remembers about a session besides the
+// conversation the App keeps.
+type localState struct {
+	history    []string
+	last       *app.Turn
+	transcript report.Transcript
+}
+
+// New creates a new REPL.
+func New(cfg Config) (*REPL, error) {
+	if cfg.App == nil {
+		return nil, fmt.Errorf("repl requires an app")
+	}
+	if cfg.Interrupter == nil {
+		cfg.Interrupter = &Interrupter{}
+	}
+
+	manager, err := sessions.New(sessions.Config{Store: cfg.App, User: cfg.UserID, Session: cfg.SessionID})
+	if err != nil {
+		return nil, err
+	}
+
+	r := &REPL{
+		cfg:      cfg,
+		sessions: manager,
+		stashed:  make(map[string]localState),
+	}
+	r.transcript.Created = time.Now()
+	r.commands = builtinCommands()
+	return r, nil
+}
+
+// Run reads input until it is exhausted, the user quits, or ctx is cancelled.
+func (r *REPL) Run(ctx context.Context, in io.Reader) error {
+	if err := r.sessions.Start(ctx); err != nil {
+		return err
+	}
+
+	console.Println("Type your queries below. Type /help for commands, 'quit' or 'exit' to stop.")
+	console.Println("Multi-line SQL ends with ';' or an empty line. Use ↑/↓ for history and Ctrl+R to search it.")
+	console.Println("Examples:")
+	console.Println("  - Show me all tables in the database")
+	console.Println("  - How many orders are there per month?")
+	console.Println("  - Create a bar chart of sales by month")
+	console.Println()
+
+	names := make([]string, len(r.commands))
+	for i, c := range r.commands {
+		names[i] = c.name
+	}
+	r.in = newInput(in, r.cfg.HistoryFile, names)
+	defer r.in.Close()
+
+	if r.cfg.Events != nil {
+		defer r.cfg.Events.Subscribe(r.printIntent)()
+	}
+
+	for ctx.Err() == nil {
+		input, err := r.in.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if input == "" {
+			continue
+		}
+
+		if input == "quit" || input == "exit" {
+			console.Println("Goodbye! 👋")
+			return nil
+		}
+
+		if strings.HasPrefix(input, "/") {
+			if quit := r.dispatch(ctx, input); quit {
+				console.Println("Goodbye! 👋")
+				return nil
+			}
+			continue
+		}
+
+		r.ask(ctx, input, false)
+	}
+	return nil
+}
+
+// ask runs a question through the agents and handles any approvals. fresh
+// bypasses the answer cache.
+func (r *REPL) ask(ctx context.Context, input string, fresh bool) {
+	r.history = append(r.history, input)
+	userID, sessionID := r.sessions.Current()
+
+	// Execute through ADK runner
+	turnCtx, end := r.cfg.Interrupter.start(ctx)
+	if r.dryRun {
+		turnCtx = sqlagent.WithDryRun(turnCtx)
+	}
+	if fresh {
+		turnCtx = app.WithFresh(turnCtx)
+	}
+	turn, err := r.cfg.App.Ask(turnCtx, userID, sessionID, input, nil)
+	if end() {
+		r.cancelled(input)
+		return
+	}
+	r.record(input, turn, err)
+	printTurn(turn, err)
+	r.exportSteps(ctx, turn)
+
+	// Ask the user to approve or reject every proposed write, including
+	// those proposed after resuming
+	var pending []app.Approval
+	if turn != nil {
+		pending = turn.Approvals
+	}
+	for len(pending) > 0 {
+		approval := pending[0]
+		pending = pending[1:]
+		console.Printf("✋ The agent wants to run:\n    %s\n", approval.SQL)
+		if approval.Reason != "" {
+			console.Printf("   Reason: %s\n", approval.Reason)
+		}
+		answer, err := r.in.readLine("Approve? [y/N]: ")
+		approved := err == nil && strings.EqualFold(strings.TrimSpace(answer), "y")
+
+		decision := "Rejected"
+		if approved {
+			decision = "Approved"
+		}
+		resolveCtx, end := r.cfg.Interrupter.start(ctx)
+		turn, err = r.cfg.App.Resolve(resolveCtx, userID, sessionID, approval.CallID, approved, nil)
+		if end() {
+			r.cancelled(decision + ": " + approval.SQL)
+			return
+		}
+		r.record(decision+": "+approval.SQL, turn, err)
+		printTurn(turn, err)
+		if turn != nil {
+			pending = append(pending, turn.Approvals...)
+		}
+	}
+
+	// Answer the agent's clarifying question; an empty reply skips it
+	if turn != nil && turn.Clarification != nil {
+		reply, err := r.in.readLine("Your answer: ")
+		if answer := turn.Clarification.Answer(reply); err == nil && answer != "" {
+			r.ask(ctx, answer, fresh)
+		}
+	}
+}
+
+// record adds an exchange to the transcript and remembers the latest turn
+// that produced query results for /export.
+func (r *REPL) record(question string, turn *app.Turn, err error) {
+	entry := report.Entry{Time: time.Now(), Question: question, Turn: turn}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	r.transcript.Entries = append(r.transcript.Entries, entry)
+
+	if turn != nil && len(turn.Queries) > 0 {
+		r.last = turn
+	}
+}
+
+// cancelled records a turn the user cancelled with Ctrl+C.
+func (r *REPL) cancelled(question string) {
+	r.record(question, nil, context.Canceled)
+	console.Print("\n⛔ Turn cancelled.\n\n")
+}
+
+// printIntent shows the intent classification and planned workflow of the
+// local user's questions.
+func (r *REPL) printIntent(e events.Event) {
+	intent, ok := e.(*events.IntentClassified)
+	if userID, _ := r.sessions.Current(); !ok || intent.UserID != userID {
+		return
+	}
+	console.Printf("\n📋 Intent: %s (confidence: %.2f)\n", intent.Intent, intent.Confidence)
+	if intent.Classifier == manager.ClassifierLLM {
+		console.Printf("🧠 Classified by the LLM (keywords: %s, %.2f): %s\n", intent.KeywordIntent, intent.KeywordConfidence, intent.LLMReasoning)
+	}
+	console.Printf("🔄 Workflow: %s\n", intent.Workflow)
+	console.Printf("🤖 Agents: %s\n", strings.Join(intent.Agents, " → "))
+	for i, step := range intent.Steps {
+		console.Printf("   %d. %s: %s\n", i+1, intent.Agents[i], step)
+	}
+	console.Println()
+	console.Println("⏳ Processing...")
+}
+
+// resultRows bounds the rows of each query result printed after a turn.
+const resultRows = 20
+
+// printResults prints the rows of each query of a turn as a table.
+func printResults(queries []app.Query) {
+	for _, q := range queries {
+		columns, rows, err := app.Rows(q.Data)
+		if err != nil || len(columns) == 0 {
+			continue
+		}
+		noun := "rows"
+		if len(rows) == 1 {
+			noun = "row"
+		}
+		console.Printf("\n📊 %d %s:\n%s", len(rows), noun, report.TextTable(columns, rows, resultRows, report.MaxCellWidth))
+		if len(rows) > resultRows {
+			console.Println("   Type /export to save every row.")
+		}
+	}
+}
+
+// printTurn prints the agents' response to a turn.
+func printTurn(turn *app.Turn, err error) {
+	if err != nil {
+		console.Println(console.Red(fmt.Sprintf("❌ Error: %v", err)))
+	}
+
+	if turn != nil {
+		for _, slot := range turn.Slots {
+			console.Printf("  🌐 Read %q as %s\n", slot.Text, slot.Value)
+		}
+	}
+
+	if turn != nil && turn.DryRun {
+		for _, q := range turn.Queries {
+			console.Printf("\n🧪 Generated SQL (not executed):\n%s\n", q.SQL)
+		}
+	}
+
+	if turn != nil && turn.Cached != nil {
+		console.Printf("\n⚡ Cached answer (%.0f%% similar to %q, answered %s). Type /fresh to recompute.\n",
+			turn.Cached.Similarity*100, turn.Cached.Question, turn.Cached.AnsweredAt.Local().Format("2006-01-02 15:04"))
+	}
+
+	if turn != nil && !turn.DryRun {
+		printResults(turn.Queries)
+	}
+
+	switch {
+	case turn != nil && turn.Text != "":
+		console.Printf("\n🤖 Agent: %s\n\n", turn.Text)
+	case turn != nil && (len(turn.Approvals) > 0 || turn.Clarification != nil):
+		console.Println()
+	default:
+		console.Print("\n💡 No response generated.\n\n")
+	}
+
+	if turn != nil && turn.Clarification != nil {
+		console.Println(turn.Clarification)
+	}
+
+	if reason := turn.StopReason(); reason != "" {
+		console.Printf("⛔ %s\n\n", reason)
+	}
+
+	if turn != nil && len(turn.Artifacts) > 0 {
+		for _, a := range turn.Artifacts {
+			console.Printf("📎 Saved %s %s as artifact %s\n", a.Kind, a.Name, a.ID)
+		}
+		console.Println()
+	}
+
+	if turn != nil && len(turn.Filters) > 0 {
+		console.Printf("🔎 Currently scoped to: %s (/clearfilters to reset)\n\n", filters.Format(turn.Filters))
+	}
+}
@@ -0,0 +1,159 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/llm"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+)
+
+// recorder is a model.LLM adding the calls of traced turns to their trace.
+type recorder struct {
+	llm model.LLM
+}
+
+// Model wraps llm so that calls with a context from WithTrace are recorded.
+// Other calls pass straight through.
+func Model(llm model.LLM) model.LLM {
+	return &recorder{llm: llm}
+}
+
+// Name implements model.LLM.
+func (r *recorder) Name() string {
+	return r.llm.Name()
+}
+
+// GenerateContent implements model.LLM.
+func (r *recorder) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	t := FromContext(ctx)
+	if t == nil {
+		return r.llm.GenerateContent(ctx, req, stream)
+	}
+	return func(yield func(*model.LLMResponse, error) bool) {
+		name := r.Name()
+		key, err := llm.CacheKey(name, req, stream)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		// The request is copied, as the agent may reuse its parts
+		call := &LLMCall{Model: name, Key: key, Stream: stream}
+		if err := clone(req.Contents, &call.Contents); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err := clone(req.Config, &call.Config); err != nil {
+			yield(nil, err)
+			return
+		}
+		// The step is added first, so it precedes the tool calls it makes
+		i := t.add(Step{Time: time.Now().UTC(), Agent: agentName(ctx), LLM: call})
+		defer t.finish(i)
+
+		for resp, err := range r.llm.GenerateContent(ctx, req, stream) {
+			if err != nil {
+				call.Error = err.Error()
+				yield(nil, err)
+				return
+			}
+			call.Responses = append(call.Responses, resp)
+			if !yield(resp, nil) {
+				return
+			}
+		}
+	}
+}
+
+// clone deep-copies v into dst through JSON, the form it is saved in.
+func clone(v, dst any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to record model request: %w", err)
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// agentName returns the name of the agent calling the model, or "" outside
+// of an agent, e.g. for the intent classifier.
+func agentName(ctx context.Context) string {
+	if ic, ok := ctx.(agent.InvocationContext); ok && ic.Agent() != nil {
+		return ic.Agent().Name()
+	}
+	return ""
+}
+
+// Player is a model.LLM that answers with the responses of a trace, in
+// order, instead of calling a model. Requests that differ from the recorded
+// ones, e.g. after a prompt or schema change, are still answered; they are
+// reported by Divergences.
+type Player struct {
+	calls []*LLMCall
+
+	mu   sync.Mutex
+	next int
+	// diverged holds the indexes of calls whose request differed
+	diverged []int
+}
+
+// NewPlayer creates a Player for the model calls of t.
+func NewPlayer(t *Trace) *Player {
+	return &Player{calls: t.LLMCalls()}
+}
+
+// Name implements model.LLM with the name of the recorded model.
+func (p *Player) Name() string {
+	if len(p.calls) == 0 {
+		return "replay"
+	}
+	return p.calls[0].Model
+}
+
+// GenerateContent implements model.LLM. A call recorded with an error
+// fails with the same error.
+func (p *Player) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		p.mu.Lock()
+		if p.next >= len(p.calls) {
+			p.mu.Unlock()
+			yield(nil, fmt.Errorf("the trace has no more model responses (%d recorded)", len(p.calls)))
+			return
+		}
+		i := p.next
+		call := p.calls[i]
+		p.next++
+		if key, err := llm.CacheKey(call.Model, req, call.Stream); err != nil || key != call.Key {
+			p.diverged = append(p.diverged, i)
+		}
+		p.mu.Unlock()
+
+		for _, resp := range call.Responses {
+			if !yield(resp, nil) {
+				return
+			}
+		}
+		if call.Error != "" {
+			yield(nil, fmt.Errorf("recorded model error: %s", call.Error))
+		}
+	}
+}
+
+// Divergences returns the indexes, among the trace's model calls, of the
+// calls whose request differed from the recorded one.
+func (p *Player) Divergences() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int(nil), p.diverged...)
+}
+
+// Remaining returns the number of recorded calls that were not replayed.
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.calls) - p.next
+}
@@ -0,0 +1,135 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// previewLen is how much of long texts and results WriteText shows unless
+// full is set.
+const previewLen = 300
+
+// WriteText re-renders a trace step by step: the agent behind each model
+// call with the text and tool calls it responded with, and each tool call
+// with its result. With full, model requests are shown too (system
+// instruction and the latest message) and nothing is shortened.
+func WriteText(w io.Writer, t *Trace, full bool) error {
+	p := &printer{w: w, full: full}
+	kind := "Question"
+	if t.Resumed {
+		kind = "Resumed"
+	}
+	p.printf("%s: %s\n", kind, t.Question)
+	p.printf("User %s, session %s, %s, %d ms\n", t.UserID, t.SessionID, t.Started.Local().Format("2006-01-02 15:04:05"), t.DurationMS)
+
+	for i, s := range t.Steps {
+		n := i + 1
+		agentName := s.Agent
+		if agentName == "" {
+			agentName = "(router)"
+		}
+		switch {
+		case s.LLM != nil:
+			p.printf("\n[%d] %s → model %s (%d ms)\n", n, agentName, s.LLM.Model, s.DurationMS)
+			if full {
+				p.request(s.LLM)
+			}
+			for _, resp := range s.LLM.Responses {
+				if resp == nil || resp.Content == nil || resp.Partial {
+					continue
+				}
+				p.content("    ", resp.Content)
+			}
+			if s.LLM.Error != "" {
+				p.printf("    error: %s\n", s.LLM.Error)
+			}
+		case s.Tool != nil:
+			p.printf("\n[%d] %s → %s %s (%d ms)\n", n, agentName, s.Tool.Name, p.json(s.Tool.Args), s.DurationMS)
+			switch {
+			case s.Tool.Error != "":
+				p.printf("    error: %s\n", s.Tool.Error)
+			case s.Tool.Result == nil:
+				p.printf("    (no result)\n")
+			default:
+				p.printf("    result: %s\n", p.json(s.Tool.Result))
+			}
+		}
+	}
+
+	p.printf("\n")
+	if t.Stopped != "" {
+		p.printf("Stopped: %s\n", t.Stopped)
+	}
+	if t.Error != "" {
+		p.printf("Error: %s\n", t.Error)
+	}
+	p.printf("Answer: %s\n", p.shorten(t.Answer))
+	return p.err
+}
+
+// printer writes a trace, keeping the first error.
+type printer struct {
+	w    io.Writer
+	full bool
+	err  error
+}
+
+func (p *printer) printf(format string, args ...any) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+// request prints the system instruction and the latest message of a call.
+func (p *printer) request(call *LLMCall) {
+	if call.Config != nil && call.Config.SystemInstruction != nil {
+		p.printf("    system:\n")
+		p.content("      ", call.Config.SystemInstruction)
+	}
+	if len(call.Contents) > 0 {
+		last := call.Contents[len(call.Contents)-1]
+		p.printf("    %s, message %d:\n", last.Role, len(call.Contents))
+		p.content("      ", last)
+	}
+}
+
+// content prints the parts of a message; reasoning is marked as such.
+func (p *printer) content(indent string, c *genai.Content) {
+	for _, part := range c.Parts {
+		switch {
+		case part == nil:
+		case part.FunctionCall != nil:
+			p.printf("%scall %s %s\n", indent, part.FunctionCall.Name, p.json(part.FunctionCall.Args))
+		case part.FunctionResponse != nil:
+			p.printf("%sresult of %s: %s\n", indent, part.FunctionResponse.Name, p.json(part.FunctionResponse.Response))
+		case part.Thought && part.Text != "":
+			p.printf("%sthought: %s\n", indent, p.shorten(part.Text))
+		case part.Text != "":
+			p.printf("%s%s\n", indent, strings.ReplaceAll(p.shorten(part.Text), "\n", "\n"+indent))
+		}
+	}
+}
+
+// json encodes v on one line, shortened unless full is set.
+func (p *printer) json(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return p.shorten(string(data))
+}
+
+func (p *printer) shorten(s string) string {
+	s = strings.TrimSpace(s)
+	if p.full {
+		return s
+	}
+	if r := []rune(s); len(r) > previewLen {
+		return string(r[:previewLen]) + "…"
+	}
+	return s
+}
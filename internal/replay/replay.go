@@ -0,0 +1,239 @@
+// Package replay records debug traces of turns: every model request and
+// response and every tool call, in order, saved as one JSON file per turn.
+// A trace can be rendered step by step, or re-run with a Player standing in
+// for the model, to see how the agents and tools handle the same responses
+// after a change.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/secrets"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// Version is the format of traces written by this package.
+const Version = 1
+
+// Trace is the record of one turn.
+type Trace struct {
+	Version   int    `json:"version"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	Question  string `json:"question"`
+	// Resumed is true for turns that resume a session, such as approvals
+	Resumed    bool      `json:"resumed,omitempty"`
+	Started    time.Time `json:"started"`
+	DurationMS int64     `json:"duration_ms"`
+	Steps      []Step    `json:"steps"`
+	Answer     string    `json:"answer,omitempty"`
+	// Stopped is why the turn was cut short by its budget, if it was
+	Stopped string `json:"stopped,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	mu sync.Mutex
+	// pending maps the IDs of unanswered tool calls to their steps
+	pending map[string]int
+}
+
+// Step is a model or tool call made during the turn.
+type Step struct {
+	Time       time.Time `json:"time"`
+	DurationMS int64     `json:"duration_ms"`
+	// Agent is the agent that called the model or tool
+	Agent string    `json:"agent,omitempty"`
+	LLM   *LLMCall  `json:"llm,omitempty"`
+	Tool  *ToolCall `json:"tool,omitempty"`
+}
+
+// LLMCall is a model request and the responses to it.
+type LLMCall struct {
+	Model string `json:"model"`
+	// Key identifies the request, as hashed by the LLM response cache
+	Key       string                       `json:"key"`
+	Stream    bool                         `json:"stream,omitempty"`
+	Contents  []*genai.Content             `json:"contents"`
+	Config    *genai.GenerateContentConfig `json:"config,omitempty"`
+	Responses []*model.LLMResponse         `json:"responses"`
+	Error     string                       `json:"error,omitempty"`
+}
+
+// ToolCall is a tool call and the result the model was given.
+type ToolCall struct {
+	Name string         `json:"name"`
+	ID   string         `json:"id,omitempty"`
+	Args map[string]any `json:"args"`
+	// Result is null until the tool answers
+	Result map[string]any `json:"result"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// NewTrace starts the trace of a turn. Add it to the turn's context with
+// WithTrace.
+func NewTrace(userID, sessionID, question string, resumed bool) *Trace {
+	return &Trace{
+		Version:   Version,
+		UserID:    userID,
+		SessionID: sessionID,
+		Question:  question,
+		Resumed:   resumed,
+		Started:   time.Now().UTC(),
+		pending:   make(map[string]int),
+	}
+}
+
+// add appends a step and returns its index; model and tool calls may run
+// concurrently.
+func (t *Trace) add(s Step) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Steps = append(t.Steps, s)
+	return len(t.Steps) - 1
+}
+
+// finish sets the duration of the step at index i.
+func (t *Trace) finish(i int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Steps[i].DurationMS = time.Since(t.Steps[i].Time).Milliseconds()
+}
+
+// ToolCalled records a tool call made by an agent. It does nothing on a nil
+// Trace, so callers need not check whether the turn is traced.
+func (t *Trace) ToolCalled(agentName string, call *genai.FunctionCall) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if call.ID != "" {
+		t.pending[call.ID] = len(t.Steps)
+	}
+	t.Steps = append(t.Steps, Step{
+		Time:  time.Now().UTC(),
+		Agent: agentName,
+		Tool:  &ToolCall{Name: call.Name, ID: call.ID, Args: call.Args},
+	})
+}
+
+// ToolAnswered records the result of a call from ToolCalled. Results of
+// calls without an ID go to the oldest unanswered call of the same tool.
+func (t *Trace) ToolAnswered(resp *genai.FunctionResponse) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	idx, ok := t.pending[resp.ID]
+	delete(t.pending, resp.ID)
+	if !ok || resp.ID == "" {
+		idx = slices.IndexFunc(t.Steps, func(s Step) bool {
+			return s.Tool != nil && s.Tool.Name == resp.Name && s.Tool.Result == nil && s.Tool.Error == ""
+		})
+		if idx < 0 {
+			return
+		}
+	}
+	step := &t.Steps[idx]
+	step.DurationMS = time.Since(step.Time).Milliseconds()
+	step.Tool.Result = resp.Response
+	if msg, _ := resp.Response["error"].(string); msg != "" {
+		step.Tool.Error = msg
+	}
+}
+
+// Complete records the outcome of the turn.
+func (t *Trace) Complete(answer, stopped string, turnErr error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.DurationMS = time.Since(t.Started).Milliseconds()
+	t.Answer = answer
+	t.Stopped = stopped
+	if turnErr != nil {
+		t.Error = turnErr.Error()
+	}
+}
+
+// LLMCalls returns the model calls of the trace, in order.
+func (t *Trace) LLMCalls() []*LLMCall {
+	var calls []*LLMCall
+	for _, s := range t.Steps {
+		if s.LLM != nil {
+			calls = append(calls, s.LLM)
+		}
+	}
+	return calls
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context whose model and tool calls are added to t.
+func WithTrace(ctx context.Context, t *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// FromContext returns the trace of a context from WithTrace, or nil.
+func FromContext(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// Recorder saves the traces of turns to a directory.
+type Recorder struct {
+	dir string
+}
+
+// NewRecorder creates a Recorder writing to dir.
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create trace directory: %w", err)
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// unsafeChars are replaced in the user and session parts of file names.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Save writes a completed trace to <time>-<user>-<session>.json in the
+// directory, with secrets redacted, and returns its path.
+func (r *Recorder) Save(t *Trace) (string, error) {
+	t.mu.Lock()
+	data, err := json.MarshalIndent(t, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("json error: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.json", t.Started.Format("20060102T150405.000"),
+		unsafeChars.ReplaceAllString(t.UserID, "_"), unsafeChars.ReplaceAllString(t.SessionID, "_"))
+	path := filepath.Join(r.dir, name)
+	if err := os.WriteFile(path, []byte(secrets.Redact(string(data))), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write trace: %w", err)
+	}
+	return path, nil
+}
+
+// Load reads a trace file.
+func Load(path string) (*Trace, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trace: %w", err)
+	}
+	var t Trace
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("failed to decode trace %s: %w", path, err)
+	}
+	if t.Version != Version {
+		return nil, fmt.Errorf("trace %s has version %d, want %d", path, t.Version, Version)
+	}
+	return &t, nil
+}
@@ -0,0 +1,145 @@
+package replay_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/replay"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+	"google.golang.org/adk/model"
+)
+
+// ordersDB answers every query with a single count.
+type ordersDB struct {
+	sqlagent.MCPClient
+	count string
+}
+
+func (db *ordersDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	return `[{"orders":` + db.count + `}]`, nil
+}
+
+func newApp(t *testing.T, llm model.LLM, db sqlagent.MCPClient, traces *replay.Recorder) *app.App {
+	t.Helper()
+	llm = replay.Model(llm)
+	tools, err := sqlagent.CreateMCPTools(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlAgent, err := sqlagent.New(sqlagent.Config{Model: llm, Tools: tools})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartAgent, err := chart.New(chart.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := manager.New(manager.Config{Model: llm, SQLAgent: sqlAgent, ChartAgent: chartAgent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := app.New(app.Config{Manager: mgr, Traces: traces})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{Agent: "SQL expert", After: "query_database", Respond: mockllm.Response{Text: "There are 42 orders."}},
+		mockllm.Rule{Agent: "SQL expert", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) AS orders FROM orders"}}}}},
+	)
+	dir := t.TempDir()
+	traces, err := replay.NewRecorder(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newApp(t, llm, &ordersDB{count: "42"}, traces)
+	if _, err := a.Ask(context.Background(), "u", "s/1", "How many orders are there?", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*-u-s_1.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("trace files = %v, %v; want one", files, err)
+	}
+	recorded, err := replay.Load(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var steps []string
+	for _, s := range recorded.Steps {
+		if s.LLM != nil {
+			steps = append(steps, s.Agent+" model")
+		} else {
+			steps = append(steps, s.Agent+" "+s.Tool.Name)
+		}
+	}
+	want := "ManagerAgent model, ManagerAgent transfer_to_agent, SQLAgent model, SQLAgent query_database, SQLAgent model"
+	if got := strings.Join(steps, ", "); got != want {
+		t.Errorf("steps = %s, want %s", got, want)
+	}
+	if recorded.Steps[1].Tool.Result == nil {
+		t.Error("transfer_to_agent has no result")
+	}
+	if q := recorded.Steps[3].Tool; q.Result["data"] != `[{"orders":42}]` {
+		t.Errorf("query result = %v", q.Result)
+	}
+	if recorded.Answer != "There are 42 orders." {
+		t.Errorf("answer = %q", recorded.Answer)
+	}
+
+	var b strings.Builder
+	if err := replay.WriteText(&b, recorded, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []string{"Question: How many orders are there?", `SQLAgent → query_database {"sql":"SELECT count(*) AS orders FROM orders"}`, `result: {"data":"[{\"orders\":42}]"}`, "Answer: There are 42 orders."} {
+		if !strings.Contains(b.String(), w) {
+			t.Errorf("rendered trace lacks %q:\n%s", w, b.String())
+		}
+	}
+
+	tests := []struct {
+		name         string
+		question     string
+		count        string
+		wantDiverged bool
+	}{
+		{"same turn", recorded.Question, "42", false},
+		{"changed data", recorded.Question, "43", true},
+		{"changed question", "How many orders are there in total?", "42", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			player := replay.NewPlayer(recorded)
+			a := newApp(t, player, &ordersDB{count: tt.count}, nil)
+			rerun := replay.NewTrace("u", "rerun", tt.question, false)
+			turn, err := a.Ask(replay.WithTrace(context.Background(), rerun), "u", "rerun", tt.question, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if turn.Text != recorded.Answer || player.Remaining() != 0 {
+				t.Errorf("text = %q, %d responses left; want the recorded answer, all used", turn.Text, player.Remaining())
+			}
+			if got := len(player.Divergences()) > 0; got != tt.wantDiverged {
+				t.Errorf("divergences = %v, want diverged %v", player.Divergences(), tt.wantDiverged)
+			}
+			if len(rerun.Steps) != len(recorded.Steps) || rerun.Steps[3].Tool.Result["data"] != `[{"orders":`+tt.count+`}]` {
+				t.Errorf("re-run steps = %+v, want the live query result", rerun.Steps)
+			}
+		})
+	}
+
+	if _, err := replay.Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Load succeeded without a file")
+	}
+}
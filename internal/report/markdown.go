@@ -0,0 +1,111 @@
+// Package report renders questions and their answers as shareable documents.
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+// MaxTableRows bounds the rows rendered per result table.
+const MaxTableRows = 50
+
+// MarkdownTable renders rows as a GitHub-flavored Markdown table, noting how
+// many rows were left out beyond maxRows. Numeric columns are right-aligned
+// and cells are cut to MaxCellWidth characters.
+func MarkdownTable(columns []string, rows [][]string, maxRows int) string {
+	if len(columns) == 0 {
+		return ""
+	}
+	shown := rows
+	if maxRows > 0 && len(rows) > maxRows {
+		shown = rows[:maxRows]
+	}
+
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(escapeCells(columns), " | ") + " |\n")
+	b.WriteString("|")
+	for _, numeric := range numericColumns(len(columns), shown) {
+		if numeric {
+			b.WriteString(" ---: |")
+		} else {
+			b.WriteString(" --- |")
+		}
+	}
+	b.WriteString("\n")
+	for _, row := range shown {
+		b.WriteString("| " + strings.Join(escapeCells(truncateCells(row, MaxCellWidth)), " | ") + " |\n")
+	}
+	if maxRows > 0 && len(rows) > maxRows {
+		fmt.Fprintf(&b, "\n_Showing %d of %d rows._\n", maxRows, len(rows))
+	}
+	return b.String()
+}
+
+func escapeCells(cells []string) []string {
+	out := make([]string, len(cells))
+	for i, c := range cells {
+		c = strings.ReplaceAll(c, "|", `\|`)
+		out[i] = lineBreaks.Replace(c)
+	}
+	return out
+}
+
+// WriteMarkdown renders a question and its turn as a Markdown section: the
+// routing, tools called, the agents' answer, each query with its result
+// table, and Mermaid charts.
+func WriteMarkdown(w io.Writer, question string, turn *app.Turn) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", strings.TrimSpace(question))
+
+	if turn.Routing != nil {
+		fmt.Fprintf(&b, "_Intent: %s · Workflow: %s_\n\n", turn.Routing.ClassifiedIntent, turn.Routing.Workflow)
+	}
+	if len(turn.ToolCalls) > 0 {
+		fmt.Fprintf(&b, "_Tools: %s_\n\n", strings.Join(turn.ToolCalls, ", "))
+	}
+
+	if text := chart.StripMermaid(turn.Text); text != "" {
+		b.WriteString(text + "\n\n")
+	}
+	if reason := turn.StopReason(); reason != "" {
+		fmt.Fprintf(&b, "> %s\n\n", reason)
+	}
+
+	for _, q := range turn.Queries {
+		b.WriteString("```sql\n" + strings.TrimSpace(q.SQL) + "\n```\n\n")
+		switch {
+		case q.Error != "":
+			fmt.Fprintf(&b, "> Error: %s\n\n", q.Error)
+		case q.Data != "":
+			columns, rows, err := app.Rows(q.Data)
+			if err == nil && len(columns) > 0 {
+				b.WriteString(MarkdownTable(columns, rows, MaxTableRows) + "\n")
+			} else if err == nil {
+				b.WriteString("_No rows._\n\n")
+			}
+		}
+	}
+
+	for _, c := range turn.Charts {
+		b.WriteString("```mermaid\n" + c + "\n```\n\n")
+	}
+
+	for _, a := range turn.Approvals {
+		fmt.Fprintf(&b, "> Pending approval (not executed):\n>\n> ```sql\n> %s\n> ```\n\n", strings.ReplaceAll(a.SQL, "\n", "\n> "))
+	}
+
+	if c := turn.Clarification; c != nil {
+		fmt.Fprintf(&b, "> Asked: %s\n", c.Question)
+		for i, option := range c.Options {
+			fmt.Fprintf(&b, "> %d. %s\n", i+1, option)
+		}
+		b.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		rows    [][]string
+		maxRows int
+		want    string
+	}{
+		{
+			name:    "basic table",
+			columns: []string{"month", "orders"},
+			rows:    [][]string{{"Jan", "12"}, {"Feb", "30"}},
+			want:    "| month | orders |\n| --- | ---: |\n| Jan | 12 |\n| Feb | 30 |\n",
+		},
+		{
+			name:    "escapes pipes and newlines",
+			columns: []string{"note"},
+			rows:    [][]string{{"a|b\nc"}},
+			want:    "| note |\n| --- |\n| a\\|b c |\n",
+		},
+		{
+			name:    "truncated",
+			columns: []string{"n"},
+			rows:    [][]string{{"1"}, {"2"}, {"3"}},
+			maxRows: 2,
+			want:    "| n |\n| ---: |\n| 1 |\n| 2 |\n\n_Showing 2 of 3 rows._\n",
+		},
+		{
+			name:    "long cells",
+			columns: []string{"note", "n"},
+			rows:    [][]string{{strings.Repeat("x", 50), ""}, {"short", "-1.5"}},
+			want:    "| note | n |\n| --- | ---: |\n| " + strings.Repeat("x", 39) + "… |  |\n| short | -1.5 |\n",
+		},
+		{
+			name: "no columns",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MarkdownTable(tt.columns, tt.rows, tt.maxRows); got != tt.want {
+				t.Errorf("MarkdownTable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
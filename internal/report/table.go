@@ -0,0 +1,113 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxCellWidth bounds the characters shown per table cell; longer values
+// are cut with an ellipsis.
+const MaxCellWidth = 40
+
+// TextTable renders rows as an aligned ASCII table for the terminal.
+// Numeric columns are right-aligned, cells are cut to maxWidth characters
+// (0 = no limit), and rows beyond maxRows (0 = all) are counted below the
+// table.
+func TextTable(columns []string, rows [][]string, maxRows, maxWidth int) string {
+	if len(columns) == 0 {
+		return ""
+	}
+	shown := rows
+	if maxRows > 0 && len(rows) > maxRows {
+		shown = rows[:maxRows]
+	}
+	numeric := numericColumns(len(columns), shown)
+
+	cells := make([][]string, 0, len(shown)+1)
+	cells = append(cells, truncateCells(columns, maxWidth))
+	for _, row := range shown {
+		cells = append(cells, truncateCells(row, maxWidth))
+	}
+	widths := make([]int, len(columns))
+	for _, row := range cells {
+		for i := range widths {
+			if i < len(row) {
+				widths[i] = max(widths[i], utf8.RuneCountInString(row[i]))
+			}
+		}
+	}
+
+	var b strings.Builder
+	rule := "+"
+	for _, w := range widths {
+		rule += strings.Repeat("-", w+2) + "+"
+	}
+	writeRow := func(row []string, header bool) {
+		b.WriteString("|")
+		for i, w := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			pad := strings.Repeat(" ", w-utf8.RuneCountInString(cell))
+			if numeric[i] && !header {
+				b.WriteString(" " + pad + cell + " |")
+			} else {
+				b.WriteString(" " + cell + pad + " |")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString(rule + "\n")
+	writeRow(cells[0], true)
+	b.WriteString(rule + "\n")
+	for _, row := range cells[1:] {
+		writeRow(row, false)
+	}
+	b.WriteString(rule + "\n")
+	if len(shown) < len(rows) {
+		fmt.Fprintf(&b, "(%d of %d rows)\n", len(shown), len(rows))
+	}
+	return b.String()
+}
+
+// numericColumns reports which of n columns hold only numbers, ignoring
+// empty (NULL) cells. Columns with no values at all are not numeric.
+func numericColumns(n int, rows [][]string) []bool {
+	numeric := make([]bool, n)
+	for i := range numeric {
+		seen := false
+		numeric[i] = true
+		for _, row := range rows {
+			if i >= len(row) || row[i] == "" {
+				continue
+			}
+			if _, err := strconv.ParseFloat(row[i], 64); err != nil {
+				numeric[i] = false
+				break
+			}
+			seen = true
+		}
+		numeric[i] = numeric[i] && seen
+	}
+	return numeric
+}
+
+var lineBreaks = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ")
+
+// truncateCells puts each cell on one line and cuts it to maxWidth
+// characters.
+func truncateCells(cells []string, maxWidth int) []string {
+	out := make([]string, len(cells))
+	for i, c := range cells {
+		c = lineBreaks.Replace(c)
+		if maxWidth > 0 && utf8.RuneCountInString(c) > maxWidth {
+			c = string([]rune(c)[:maxWidth-1]) + "…"
+		}
+		out[i] = c
+	}
+	return out
+}
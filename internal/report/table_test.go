@@ -0,0 +1,49 @@
+package report
+
+import "testing"
+
+func TestTextTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		columns  []string
+		rows     [][]string
+		maxRows  int
+		maxWidth int
+		want     string
+	}{
+		{
+			name:    "numbers right-aligned",
+			columns: []string{"region", "revenue"},
+			rows:    [][]string{{"north", "1200.5"}, {"south", "80"}},
+			want: "+--------+---------+\n" +
+				"| region | revenue |\n" +
+				"+--------+---------+\n" +
+				"| north  |  1200.5 |\n" +
+				"| south  |      80 |\n" +
+				"+--------+---------+\n",
+		},
+		{
+			name:     "cut cells and rows",
+			columns:  []string{"note", "n"},
+			rows:     [][]string{{"café au lait", ""}, {"two\nlines", "2"}, {"x", "3"}},
+			maxRows:  2,
+			maxWidth: 8,
+			want: "+----------+---+\n" +
+				"| note     | n |\n" +
+				"+----------+---+\n" +
+				"| café au… |   |\n" +
+				"| two lin… | 2 |\n" +
+				"+----------+---+\n" +
+				"(2 of 3 rows)\n",
+		},
+		{name: "no columns", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TextTable(tt.columns, tt.rows, tt.maxRows, tt.maxWidth); got != tt.want {
+				t.Errorf("TextTable() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/clarify"
+)
+
+// Entry is one exchange in a transcript.
+type Entry struct {
+	Time     time.Time
+	Question string
+	Turn     *app.Turn
+	Error    string
+}
+
+// Transcript is a session's exchanges in order.
+type Transcript struct {
+	Title   string
+	Created time.Time
+	Entries []Entry
+}
+
+// WriteTranscriptMarkdown renders the transcript as Markdown. Charts are kept
+// as ```mermaid blocks, which GitHub, GitLab and most editors render.
+func WriteTranscriptMarkdown(w io.Writer, t Transcript) error {
+	if _, err := fmt.Fprintf(w, "# %s\n\n_%s · %d questions_\n\n", t.Title, t.Created.Format("2006-01-02 15:04"), len(t.Entries)); err != nil {
+		return err
+	}
+	for _, e := range t.Entries {
+		turn := e.Turn
+		if turn == nil {
+			turn = &app.Turn{}
+		}
+		if err := WriteMarkdown(w, e.Question, turn); err != nil {
+			return err
+		}
+		if e.Error != "" {
+			if _, err := fmt.Fprintf(w, "> Error: %s\n\n", e.Error); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// htmlQuery is a query prepared for the HTML template.
+type htmlQuery struct {
+	SQL     string
+	Error   string
+	Columns []string
+	Rows    [][]string
+	More    int
+}
+
+// htmlEntry is an Entry prepared for the HTML template.
+type htmlEntry struct {
+	Time       time.Time
+	Question   string
+	Intent     string
+	Workflow   string
+	ToolCalls  []string
+	Paragraphs []string
+	Queries    []htmlQuery
+	Charts     []string
+	Approvals  []app.Approval
+	// Clarification is the question the agents asked instead of answering
+	Clarification *clarify.Question
+	Error         string
+}
+
+var transcriptTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; color: #222; }
+h2 { border-top: 1px solid #ddd; padding-top: 1rem; }
+.meta { color: #777; font-size: 0.9em; }
+pre { background: #f6f8fa; padding: 0.75rem; overflow-x: auto; }
+table { border-collapse: collapse; margin: 0.5rem 0 1rem; font-size: 0.9em; }
+th, td { border: 1px solid #ddd; padding: 0.25rem 0.5rem; text-align: left; }
+th { background: #f0f0f0; }
+.error { color: #b00020; }
+.pending { border-left: 4px solid #f0a000; padding-left: 0.75rem; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">{{.Created.Format "2006-01-02 15:04"}} · {{len .Entries}} questions</p>
+{{range .Entries}}
+<h2>{{.Question}}</h2>
+<p class="meta">{{.Time.Format "15:04:05"}}{{if .Intent}} · Intent: {{.Intent}} · Workflow: {{.Workflow}}{{end}}{{if .ToolCalls}} · Tools: {{range $i, $t := .ToolCalls}}{{if $i}}, {{end}}{{$t}}{{end}}{{end}}</p>
+{{range .Paragraphs}}<p>{{.}}</p>
+{{end}}
+{{range .Queries}}<pre><code>{{.SQL}}</code></pre>
+{{if .Error}}<p class="error">Error: {{.Error}}</p>
+{{else if .Columns}}<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{if .More}}<p class="meta">{{.More}} more rows not shown.</p>{{end}}
+{{end}}{{end}}
+{{range .Charts}}<pre class="mermaid">{{.}}</pre>
+{{end}}
+{{range .Approvals}}<div class="pending"><p>Pending approval (not executed):</p><pre><code>{{.SQL}}</code></pre></div>
+{{end}}
+{{with .Clarification}}<div class="pending"><p>Asked: {{.Question}}</p>{{if .Options}}<ol>{{range .Options}}<li>{{.}}</li>{{end}}</ol>{{end}}</div>
+{{end}}
+{{if .Error}}<p class="error">Error: {{.Error}}</p>{{end}}
+{{end}}
+<script type="module">
+import mermaid from "https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs";
+mermaid.initialize({ startOnLoad: true });
+</script>
+</body>
+</html>
+`))
+
+// WriteTranscriptHTML renders the transcript as a standalone HTML page.
+// Mermaid charts are drawn in the browser by mermaid.js.
+func WriteTranscriptHTML(w io.Writer, t Transcript) error {
+	entries := make([]htmlEntry, 0, len(t.Entries))
+	for _, e := range t.Entries {
+		he := htmlEntry{Time: e.Time, Question: e.Question, Error: e.Error}
+		if turn := e.Turn; turn != nil {
+			if turn.Routing != nil {
+				he.Intent = turn.Routing.ClassifiedIntent
+				he.Workflow = turn.Routing.Workflow
+			}
+			he.ToolCalls = turn.ToolCalls
+			he.Charts = turn.Charts
+			he.Approvals = turn.Approvals
+			he.Clarification = turn.Clarification
+			for _, p := range strings.Split(chart.StripMermaid(turn.Text), "\n\n") {
+				if p = strings.TrimSpace(p); p != "" {
+					he.Paragraphs = append(he.Paragraphs, p)
+				}
+			}
+			for _, q := range turn.Queries {
+				hq := htmlQuery{SQL: strings.TrimSpace(q.SQL), Error: q.Error}
+				if q.Error == "" {
+					if columns, rows, err := app.Rows(q.Data); err == nil {
+						hq.Columns = columns
+						if len(rows) > MaxTableRows {
+							hq.More = len(rows) - MaxTableRows
+							rows = rows[:MaxTableRows]
+						}
+						hq.Rows = rows
+					}
+				}
+				he.Queries = append(he.Queries, hq)
+			}
+		}
+		entries = append(entries, he)
+	}
+
+	return transcriptTemplate.Execute(w, struct {
+		Title   string
+		Created time.Time
+		Entries []htmlEntry
+	}{t.Title, t.Created, entries})
+}
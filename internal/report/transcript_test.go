@@ -0,0 +1,55 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+func TestWriteTranscript(t *testing.T) {
+	transcript := Transcript{
+		Title:   "Test",
+		Created: time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC),
+		Entries: []Entry{{
+			Question: "Orders <by> month?",
+			Turn: &app.Turn{
+				ToolCalls: []string{"query_database"},
+				Queries:   []app.Query{{SQL: "SELECT month, count(*) FROM orders", Data: `[{"month":"Jan","count":3}]`}},
+				Charts:    []string{"xychart-beta\n    bar [3]"},
+			},
+		}},
+	}
+
+	tests := []struct {
+		name  string
+		write func(*strings.Builder, Transcript) error
+		want  []string
+	}{
+		{
+			name:  "markdown",
+			write: func(b *strings.Builder, t Transcript) error { return WriteTranscriptMarkdown(b, t) },
+			want:  []string{"# Test", "## Orders <by> month?", "_Tools: query_database_", "```sql\nSELECT month", "```mermaid\nxychart-beta"},
+		},
+		{
+			name:  "html",
+			write: func(b *strings.Builder, t Transcript) error { return WriteTranscriptHTML(b, t) },
+			want:  []string{"<h1>Test</h1>", "Orders &lt;by&gt; month?", "Tools: query_database", "<td>Jan</td>", `<pre class="mermaid">xychart-beta`, "mermaid.initialize"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tt.write(&b, transcript); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("output missing %q:\n%s", want, b.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * * funday", "@often"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2024-01-10 is a Wednesday
+	from := time.Date(2024, 1, 10, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "* * * * *", want: time.Date(2024, 1, 10, 9, 31, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", want: time.Date(2024, 1, 10, 9, 45, 0, 0, time.UTC)},
+		{expr: "0 8 * * *", want: time.Date(2024, 1, 11, 8, 0, 0, 0, time.UTC)},
+		{expr: "@hourly", want: time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)},
+		{expr: "0 8 * * MON", want: time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)},
+		{expr: "0 8 * * 7", want: time.Date(2024, 1, 14, 8, 0, 0, 0, time.UTC)},
+		{expr: "0 9 * * mon-fri", want: time.Date(2024, 1, 11, 9, 0, 0, 0, time.UTC)},
+		{expr: "30 6 1 * *", want: time.Date(2024, 2, 1, 6, 30, 0, 0, time.UTC)},
+		{expr: "0 0 29 feb *", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted
+		{expr: "0 0 13 * FRI", want: time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 31 2 *", want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron() error = %v", err)
+			}
+			if got := c.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,423 @@
+// Package schedule runs saved questions on cron schedules and delivers the
+// answers as reports: written to disk as HTML or Markdown, with their charts,
+// and posted to a webhook or Slack.
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/report"
+	"gopkg.in/yaml.v3"
+)
+
+// Report formats.
+const (
+	FormatHTML     = "html"
+	FormatMarkdown = "markdown"
+)
+
+// DefaultUser is the user scheduled questions are asked as.
+const DefaultUser = "schedule"
+
+// DefaultDir is where reports are written when neither the job nor the
+// scheduler names a directory.
+const DefaultDir = "reports"
+
+// now is replaced in tests.
+var now = time.Now
+
+// Job is a saved set of questions asked on a schedule.
+type Job struct {
+	// Name identifies the job in report file names and logs
+	Name string `yaml:"name"`
+	// Cron is when the job runs, e.g. "0 8 * * MON" or "@daily"
+	Cron string `yaml:"cron"`
+	// Timezone is the IANA zone Cron is read in (defaults to the local zone)
+	Timezone string `yaml:"timezone"`
+	// Queries are asked in order within one session
+	Queries []string `yaml:"queries"`
+	// User is the user the questions are asked as (defaults to DefaultUser)
+	User string `yaml:"user"`
+	// Format is FormatHTML (default) or FormatMarkdown
+	Format string `yaml:"format"`
+	// Dir is where reports are written (defaults to the scheduler's)
+	Dir string `yaml:"dir"`
+	// Webhook receives each report as JSON (optional)
+	Webhook string `yaml:"webhook"`
+	// Slack is a Slack incoming webhook URL that receives a summary (optional)
+	Slack string `yaml:"slack"`
+
+	cron *Cron
+	loc  *time.Location
+}
+
+// validJobName keeps job names safe in file names.
+var validJobName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Load reads jobs from a YAML file with a top-level "schedules" list.
+func Load(path string) ([]*Job, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	var file struct {
+		Schedules []*Job `yaml:"schedules"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules: %w", err)
+	}
+
+	names := make(map[string]bool, len(file.Schedules))
+	for _, job := range file.Schedules {
+		if err := job.init(); err != nil {
+			return nil, err
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("duplicate schedule %q", job.Name)
+		}
+		names[job.Name] = true
+	}
+	return file.Schedules, nil
+}
+
+// init validates the job and fills in its defaults.
+func (j *Job) init() error {
+	if !validJobName.MatchString(j.Name) {
+		return fmt.Errorf("invalid schedule name %q: use up to 64 letters, digits, '.', '_' or '-'", j.Name)
+	}
+	if len(j.Queries) == 0 {
+		return fmt.Errorf("schedule %s has no queries", j.Name)
+	}
+	cron, err := ParseCron(j.Cron)
+	if err != nil {
+		return fmt.Errorf("schedule %s: %w", j.Name, err)
+	}
+	j.cron = cron
+	j.loc = time.Local
+	if j.Timezone != "" {
+		if j.loc, err = time.LoadLocation(j.Timezone); err != nil {
+			return fmt.Errorf("schedule %s: unknown timezone %q", j.Name, j.Timezone)
+		}
+	}
+	switch j.Format {
+	case "":
+		j.Format = FormatHTML
+	case FormatHTML, FormatMarkdown:
+	default:
+		return fmt.Errorf("schedule %s: unknown format %q (want %s or %s)", j.Name, j.Format, FormatHTML, FormatMarkdown)
+	}
+	if j.User == "" {
+		j.User = DefaultUser
+	}
+	return nil
+}
+
+// Next returns the job's first run after t.
+func (j *Job) Next(t time.Time) time.Time {
+	return j.cron.Next(t.In(j.loc))
+}
+
+// Asker answers questions; *app.App implements it.
+type Asker interface {
+	Ask(ctx context.Context, userID, sessionID, query string, onEvent app.EventHandler) (*app.Turn, error)
+}
+
+// Config holds configuration for a Scheduler.
+type Config struct {
+	// App answers the questions
+	App Asker
+	// Jobs are the schedules to run
+	Jobs []*Job
+	// Dir is where reports are written unless a job names its own (defaults to DefaultDir)
+	Dir string
+	// HTTPClient posts reports to webhooks (defaults to a client with a 30s timeout)
+	HTTPClient *http.Client
+	// Artifacts also stores every report, for the API to serve (optional)
+	Artifacts artifacts.Store
+}
+
+// Scheduler runs jobs when they are due.
+type Scheduler struct {
+	app       Asker
+	jobs      []*Job
+	dir       string
+	http      *http.Client
+	artifacts artifacts.Store
+}
+
+// New creates a Scheduler.
+func New(cfg Config) (*Scheduler, error) {
+	if cfg.App == nil {
+		return nil, fmt.Errorf("scheduler requires an app")
+	}
+	s := &Scheduler{app: cfg.App, jobs: cfg.Jobs, dir: cfg.Dir, http: cfg.HTTPClient, artifacts: cfg.Artifacts}
+	for _, job := range s.jobs {
+		if job.cron == nil {
+			if err := job.init(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if s.dir == "" {
+		s.dir = DefaultDir
+	}
+	if s.http == nil {
+		s.http = &http.Client{Timeout: 30 * time.Second}
+	}
+	return s, nil
+}
+
+// Jobs returns the scheduler's jobs.
+func (s *Scheduler) Jobs() []*Job {
+	return s.jobs
+}
+
+// Job returns the job named name, or nil.
+func (s *Scheduler) Job(name string) *Job {
+	for _, job := range s.jobs {
+		if job.Name == name {
+			return job
+		}
+	}
+	return nil
+}
+
+// Run runs each job when it is due until ctx is cancelled. Jobs run one at a
+// time; a run that is missed because another was still going is skipped.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if len(s.jobs) == 0 {
+		<-ctx.Done()
+		return nil
+	}
+	next := make(map[*Job]time.Time, len(s.jobs))
+	for _, job := range s.jobs {
+		next[job] = job.Next(now())
+		console.Printf("⏰ [SCHEDULE] %s: next run %s\n", job.Name, next[job].Format("2006-01-02 15:04 MST"))
+	}
+
+	for {
+		var due time.Time
+		for _, t := range next {
+			if !t.IsZero() && (due.IsZero() || t.Before(due)) {
+				due = t
+			}
+		}
+		if due.IsZero() {
+			<-ctx.Done()
+			return nil
+		}
+
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		for _, job := range s.jobs {
+			if t := next[job]; t.IsZero() || t.After(now()) {
+				continue
+			}
+			// A run in progress is not cancelled on shutdown; App.Drain
+			// bounds how long it may take
+			if _, err := s.RunJob(context.WithoutCancel(ctx), job); err != nil {
+				console.Println(console.Red(fmt.Sprintf("❌ [SCHEDULE] %s: %v", job.Name, err)))
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			next[job] = job.Next(now())
+		}
+	}
+}
+
+// Result is the outcome of one question of a run.
+type Result struct {
+	Query string    `json:"query"`
+	Turn  *app.Turn `json:"turn,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// Run is a completed run of a job, as posted to its webhook.
+type Run struct {
+	Schedule string    `json:"schedule"`
+	Started  time.Time `json:"started"`
+	// File is the report written to disk
+	File string `json:"file"`
+	// Artifact is the ID of the report in the artifact store, if any, and
+	// URL where it can be downloaded
+	Artifact string   `json:"artifact,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Results  []Result `json:"results"`
+}
+
+// RunJob asks the job's questions now, writes the report and delivers it.
+// Questions that fail are reported in the run rather than stopping it; the
+// error is for the report that could not be written or delivered.
+func (s *Scheduler) RunJob(ctx context.Context, job *Job) (*Run, error) {
+	started := now()
+	console.Printf("⏰ [SCHEDULE] Running %s (%d questions)\n", job.Name, len(job.Queries))
+
+	run := &Run{Schedule: job.Name, Started: started}
+	transcript := report.Transcript{Title: job.Name + " report", Created: started.In(job.loc)}
+	sessionID := job.Name + "-" + started.UTC().Format("20060102T150405")
+	for _, query := range job.Queries {
+		turn, err := s.app.Ask(ctx, job.User, sessionID, query, nil)
+		result := Result{Query: query, Turn: turn}
+		entry := report.Entry{Time: now(), Question: query, Turn: turn}
+		if err != nil {
+			result.Error, entry.Error = err.Error(), err.Error()
+		}
+		run.Results = append(run.Results, result)
+		transcript.Entries = append(transcript.Entries, entry)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// The server is shutting down; the run is not worth delivering
+		if errors.Is(err, app.ErrDraining) {
+			return nil, err
+		}
+	}
+
+	file, data, err := s.write(job, transcript)
+	if err != nil {
+		return nil, err
+	}
+	run.File = file
+	console.Printf("📄 [SCHEDULE] %s report saved to %s\n", job.Name, file)
+	if s.artifacts != nil {
+		a, err := s.artifacts.Put(ctx, artifacts.Artifact{
+			Kind:        artifacts.KindReport,
+			Name:        filepath.Base(file),
+			ContentType: reportContentType(job.Format),
+			UserID:      job.User,
+			SessionID:   sessionID,
+		}, data)
+		if err != nil {
+			console.Printf("  ⚠️  [SCHEDULE] Failed to store %s report: %v\n", job.Name, err)
+		} else {
+			run.Artifact, run.URL = a.ID, artifacts.URL(s.artifacts, a)
+		}
+	}
+
+	if job.Webhook != "" {
+		if err := s.post(ctx, job.Webhook, run); err != nil {
+			return run, fmt.Errorf("failed to post report to webhook: %w", err)
+		}
+	}
+	if job.Slack != "" {
+		if err := s.post(ctx, job.Slack, map[string]string{"text": slackText(run)}); err != nil {
+			return run, fmt.Errorf("failed to post report to Slack: %w", err)
+		}
+	}
+	return run, nil
+}
+
+// write renders the report into the job's directory as
+// <name>-<time>.html or .md, returning the path and the content.
+func (s *Scheduler) write(job *Job, t report.Transcript) (string, []byte, error) {
+	dir := job.Dir
+	if dir == "" {
+		dir = s.dir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	ext := ".html"
+	var err error
+	if job.Format == FormatMarkdown {
+		ext = ".md"
+		err = report.WriteTranscriptMarkdown(&buf, t)
+	} else {
+		err = report.WriteTranscriptHTML(&buf, t)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to render report: %w", err)
+	}
+
+	path := filepath.Join(dir, job.Name+"-"+t.Created.Format("20060102-1504")+ext)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return "", nil, fmt.Errorf("failed to write report: %w", err)
+	}
+	return path, buf.Bytes(), nil
+}
+
+// reportContentType is the content type of reports in a format.
+func reportContentType(format string) string {
+	if format == FormatMarkdown {
+		return "text/markdown; charset=utf-8"
+	}
+	return "text/html; charset=utf-8"
+}
+
+func (s *Scheduler) post(ctx context.Context, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// maxSlackAnswer bounds each answer in a Slack message, which Slack cuts off
+// at 40,000 characters.
+const maxSlackAnswer = 1500
+
+// slackText summarizes a run in Slack's mrkdwn: each question with its
+// answer, and where the full report with tables and charts is.
+func slackText(run *Run) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s* report, %s\n", run.Schedule, run.Started.Format("2006-01-02 15:04"))
+	for _, r := range run.Results {
+		fmt.Fprintf(&b, "\n*%s*\n", r.Query)
+		switch {
+		case r.Error != "":
+			fmt.Fprintf(&b, ":x: %s\n", r.Error)
+		case r.Turn != nil:
+			answer := strings.TrimSpace(chart.StripMermaid(r.Turn.Text))
+			if len(answer) > maxSlackAnswer {
+				answer = strings.ToValidUTF8(answer[:maxSlackAnswer], "") + "…"
+			}
+			b.WriteString(answer + "\n")
+			if n := len(r.Turn.Charts); n > 0 {
+				fmt.Fprintf(&b, "_%d chart(s) in the full report_\n", n)
+			}
+		}
+	}
+	// A signed URL opens the report; the API's path needs credentials
+	if strings.HasPrefix(run.URL, "http") {
+		fmt.Fprintf(&b, "\nFull report: <%s|%s>", run.URL, filepath.Base(run.File))
+	} else {
+		fmt.Fprintf(&b, "\nFull report: `%s`", run.File)
+	}
+	return b.String()
+}
@@ -0,0 +1,153 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "valid", yaml: "schedules:\n  - name: weekly-sales\n    cron: \"0 8 * * MON\"\n    timezone: Europe/Berlin\n    queries: [\"Revenue last week\"]\n"},
+		{name: "bad cron", yaml: "schedules:\n  - name: a\n    cron: \"0 8 * *\"\n    queries: [q]\n", wantErr: "invalid cron"},
+		{name: "bad timezone", yaml: "schedules:\n  - name: a\n    cron: \"@daily\"\n    timezone: Mars/Olympus\n    queries: [q]\n", wantErr: "unknown timezone"},
+		{name: "no queries", yaml: "schedules:\n  - name: a\n    cron: \"@daily\"\n", wantErr: "no queries"},
+		{name: "unsafe name", yaml: "schedules:\n  - name: ../a\n    cron: \"@daily\"\n    queries: [q]\n", wantErr: "invalid schedule name"},
+		{name: "bad format", yaml: "schedules:\n  - name: a\n    cron: \"@daily\"\n    format: pdf\n    queries: [q]\n", wantErr: "unknown format"},
+		{name: "duplicate", yaml: "schedules:\n  - {name: a, cron: \"@daily\", queries: [q]}\n  - {name: a, cron: \"@daily\", queries: [q]}\n", wantErr: "duplicate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "schedules.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			jobs, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if len(jobs) != 1 || jobs[0].Format != FormatHTML || jobs[0].User != DefaultUser {
+				t.Errorf("Load() = %+v, want one job with defaults", jobs)
+			}
+			// 08:00 in Berlin is 07:00 UTC in winter
+			from := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+			if got, want := jobs[0].Next(from), time.Date(2024, 1, 15, 7, 0, 0, 0, time.UTC); !got.Equal(want) {
+				t.Errorf("Next() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadTable(t *testing.T) {
+	db := fakeDB(`[{"name": "daily", "cron": "@daily", "queries": "Orders today\n\nRevenue today", "user_id": null},
+		{"name": "weekly", "cron": "@weekly", "queries": ["Top customers"], "format": "markdown", "slack": "https://hooks.example/x"}]`)
+	jobs, err := LoadTable(context.Background(), db, "ops.report_schedules")
+	if err != nil {
+		t.Fatalf("LoadTable() error = %v", err)
+	}
+	if len(jobs) != 2 || len(jobs[0].Queries) != 2 || jobs[0].User != DefaultUser || jobs[1].Format != FormatMarkdown || jobs[1].Queries[0] != "Top customers" {
+		t.Errorf("LoadTable() = %+v %+v", jobs[0], jobs[1])
+	}
+	if _, err := LoadTable(context.Background(), db, "schedules; DROP TABLE x"); err == nil {
+		t.Error("LoadTable() accepted an unsafe table name")
+	}
+}
+
+type fakeDB string
+
+func (f fakeDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	return string(f), nil
+}
+
+// fakeApp answers every question with a chart, except "fail".
+type fakeApp struct {
+	users, sessions []string
+}
+
+func (a *fakeApp) Ask(ctx context.Context, userID, sessionID, query string, onEvent app.EventHandler) (*app.Turn, error) {
+	a.users = append(a.users, userID)
+	a.sessions = append(a.sessions, sessionID)
+	if query == "fail" {
+		return nil, errors.New("model unavailable")
+	}
+	chart := "```mermaid\npie\n    \"A\" : 1\n```"
+	return &app.Turn{Text: "Revenue was 42.\n" + chart, Charts: []string{chart}}, nil
+}
+
+func TestRunJob(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	var run Run
+	var slack map[string]string
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&run); err != nil {
+			t.Errorf("webhook payload: %v", err)
+		}
+	}))
+	defer webhook.Close()
+	slackHook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&slack); err != nil {
+			t.Errorf("Slack payload: %v", err)
+		}
+	}))
+	defer slackHook.Close()
+
+	asker := &fakeApp{}
+	dir := t.TempDir()
+	s, err := New(Config{App: asker, Dir: dir, Jobs: []*Job{{
+		Name:     "weekly-sales",
+		Cron:     "0 8 * * MON",
+		Timezone: "UTC",
+		Queries:  []string{"Revenue last week", "fail"},
+		Webhook:  webhook.URL,
+		Slack:    slackHook.URL,
+	}}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := s.RunJob(context.Background(), s.Job("weekly-sales")); err != nil {
+		t.Fatalf("RunJob() error = %v", err)
+	}
+
+	want := filepath.Join(dir, "weekly-sales-20240115-0800.html")
+	html, err := os.ReadFile(want)
+	if err != nil {
+		t.Fatalf("report not written: %v", err)
+	}
+	for _, s := range []string{"Revenue last week", "mermaid", "model unavailable"} {
+		if !strings.Contains(string(html), s) {
+			t.Errorf("report does not contain %q", s)
+		}
+	}
+	if asker.sessions[0] != asker.sessions[1] || asker.users[0] != DefaultUser {
+		t.Errorf("asked as %v in sessions %v, want one session as %s", asker.users, asker.sessions, DefaultUser)
+	}
+
+	if run.Schedule != "weekly-sales" || run.File != want || len(run.Results) != 2 || run.Results[1].Error != "model unavailable" {
+		t.Errorf("webhook received %+v", run)
+	}
+	text := slack["text"]
+	if !strings.Contains(text, "Revenue was 42.") || strings.Contains(text, "mermaid") || !strings.Contains(text, want) {
+		t.Errorf("Slack received %q", text)
+	}
+}
@@ -0,0 +1,104 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Querier runs a query and returns its rows as a JSON array of objects;
+// sqlagent.MCPClient implements it.
+type Querier interface {
+	Query(ctx context.Context, query string, limit int) (string, error)
+}
+
+// maxTableJobs caps the schedules read from a table.
+const maxTableJobs = 1000
+
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// LoadTable reads jobs from a database table with the columns name, cron and
+// queries, and optionally timezone, user_id, format, dir, webhook and slack.
+// queries is a text column with one question per line, or an array of text.
+//
+// Example:
+//
+//	CREATE TABLE report_schedules (
+//	    name text PRIMARY KEY,
+//	    cron text NOT NULL,
+//	    queries text NOT NULL,
+//	    timezone text,
+//	    user_id text,
+//	    format text,
+//	    dir text,
+//	    webhook text,
+//	    slack text
+//	);
+func LoadTable(ctx context.Context, db Querier, table string) ([]*Job, error) {
+	if !validTableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid schedules table name %q", table)
+	}
+	data, err := db.Query(ctx, "SELECT * FROM "+table+" ORDER BY name", maxTableJobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules table: %w", err)
+	}
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode schedules table: %w", err)
+	}
+
+	jobs := make([]*Job, 0, len(rows))
+	names := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		job := &Job{
+			Name:     text(row["name"]),
+			Cron:     text(row["cron"]),
+			Timezone: text(row["timezone"]),
+			Queries:  queries(row["queries"]),
+			User:     text(row["user_id"]),
+			Format:   text(row["format"]),
+			Dir:      text(row["dir"]),
+			Webhook:  text(row["webhook"]),
+			Slack:    text(row["slack"]),
+		}
+		if err := job.init(); err != nil {
+			return nil, err
+		}
+		if names[job.Name] {
+			return nil, fmt.Errorf("duplicate schedule %q", job.Name)
+		}
+		names[job.Name] = true
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func text(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+// queries reads a queries column: an array, or text with one question per
+// line.
+func queries(v interface{}) []string {
+	var lines []string
+	switch v := v.(type) {
+	case []interface{}:
+		for _, q := range v {
+			lines = append(lines, text(q))
+		}
+	case string:
+		lines = strings.Split(v, "\n")
+	}
+	var out []string
+	for _, q := range lines {
+		if q = strings.TrimSpace(q); q != "" {
+			out = append(out, q)
+		}
+	}
+	return out
+}
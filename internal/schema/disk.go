@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshot is the on-disk form of a Cache.
+type snapshot struct {
+	// Database identifies the database described, so a file kept across a
+	// configuration change is not used for another database
+	Database  string    `json:"database"`
+	Hash      string    `json:"hash"`
+	Described time.Time `json:"described"`
+	Schema    string    `json:"schema"`
+}
+
+// Persist keeps the cache in the JSON file at path, for the database
+// identified by database. If the file holds a description of the same
+// database whose hash matches, it is loaded and Persist returns true, so
+// startup can skip describing the database. Every later Refresh saves
+// the file.
+func (c *Cache) Persist(path, database string) (bool, error) {
+	c.mu.Lock()
+	c.path, c.database = path, database
+	c.mu.Unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read schema cache: %w", err)
+	}
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return false, fmt.Errorf("failed to parse schema cache %s: %w", path, err)
+	}
+	// A file for another database, or edited or cut short, is described
+	// again and overwritten
+	if s.Database != database || s.Hash != Hash(s.Schema) || s.Schema == "" {
+		return false, nil
+	}
+
+	c.mu.Lock()
+	c.text, c.hash, c.described = s.Schema, s.Hash, s.Described
+	c.mu.Unlock()
+	return true, nil
+}
+
+// save writes the cache to its file, if persisted.
+func (c *Cache) save() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	c.mu.RLock()
+	path := c.path
+	s := snapshot{Database: c.database, Hash: c.hash, Described: c.described, Schema: c.text}
+	c.mu.RUnlock()
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("json error: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".schema-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DefaultChannel is the channel Listen listens on by default.
+const DefaultChannel = "multi_agent_schema"
+
+// Listener receives the change notifications of a PostgreSQL database.
+type Listener struct {
+	l    *pq.Listener
+	out  chan struct{}
+	done chan struct{}
+}
+
+// Listen connects to the PostgreSQL database at databaseURL and listens on
+// channel, to which event triggers installed by an administrator send a
+// notification for every DDL command (see the README). Notifications
+// arrive on C; a reconnection counts as one, as changes may have been
+// missed meanwhile.
+func Listen(databaseURL, channel string) (*Listener, error) {
+	l := pq.NewListener(databaseURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("⚠️  Schema change listener: %v", err)
+		}
+	})
+	if err := l.Listen(channel); err != nil {
+		l.Close()
+		return nil, fmt.Errorf("failed to listen for schema changes: %w", err)
+	}
+
+	ln := &Listener{l: l, out: make(chan struct{}, 1), done: make(chan struct{})}
+	go ln.forward()
+	return ln, nil
+}
+
+// C delivers a value for each notification, coalescing those not yet
+// received.
+func (ln *Listener) C() <-chan struct{} {
+	return ln.out
+}
+
+// forward passes notifications on to C until the listener is closed.
+func (ln *Listener) forward() {
+	defer close(ln.out)
+	for {
+		select {
+		case <-ln.done:
+			return
+		case _, ok := <-ln.l.Notify:
+			if !ok {
+				return
+			}
+			select {
+			case ln.out <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Close stops listening.
+func (ln *Listener) Close() error {
+	close(ln.done)
+	return ln.l.Close()
+}
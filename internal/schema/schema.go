@@ -0,0 +1,206 @@
+// Package schema keeps the database schema the agents are prompted with
+// current. A Cache holds the description DescribeDatabase returns and
+// Watch refreshes it when DDL changes the database, so long-running
+// sessions do not write SQL against tables that no longer look that way.
+package schema
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Describer describes every table of a database, as the SQL agent's
+// database client does.
+type Describer interface {
+	DescribeDatabase(ctx context.Context) (string, error)
+}
+
+// Cache holds the latest description of a database. It is safe for
+// concurrent use.
+type Cache struct {
+	db Describer
+
+	mu   sync.RWMutex
+	text string
+	hash string
+	// described is when text was read from the database
+	described time.Time
+	// path and database are set by Persist
+	path     string
+	database string
+	// saveMu orders concurrent saves
+	saveMu sync.Mutex
+	// onChange are told about every refresh that changed the schema
+	onChange []func(text string, diff Diff)
+}
+
+// NewCache creates an empty Cache describing db.
+func NewCache(db Describer) *Cache {
+	return &Cache{db: db, hash: Hash("")}
+}
+
+// Text returns the latest description, or "" before the first load.
+func (c *Cache) Text() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.text
+}
+
+// Hash returns the hash of Text, which identifies the schema version.
+func (c *Cache) Hash() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.hash
+}
+
+// Described returns when the description was read from the database,
+// which may be before startup if it was loaded from disk.
+func (c *Cache) Described() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.described
+}
+
+// OnChange registers f to be called after each refresh that changed the
+// schema, with the new description and what changed.
+func (c *Cache) OnChange(f func(text string, diff Diff)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onChange = append(c.onChange, f)
+}
+
+// Refresh describes the database again and returns what changed since the
+// last description, saving it if the cache is persisted. On error the
+// previous description is kept.
+func (c *Cache) Refresh(ctx context.Context) (Diff, error) {
+	text, err := c.db.DescribeDatabase(ctx)
+	if err != nil {
+		return Diff{}, fmt.Errorf("failed to describe database: %w", err)
+	}
+	diff := c.Set(text)
+	c.mu.Lock()
+	c.described = time.Now()
+	c.mu.Unlock()
+	return diff, c.save()
+}
+
+// Set replaces the description with text and returns what changed.
+func (c *Cache) Set(text string) Diff {
+	hash := Hash(text)
+	c.mu.Lock()
+	if hash == c.hash {
+		c.mu.Unlock()
+		return Diff{}
+	}
+	diff := Compare(c.text, text)
+	c.text, c.hash = text, hash
+	onChange := c.onChange
+	c.mu.Unlock()
+
+	for _, f := range onChange {
+		f(text, diff)
+	}
+	return diff
+}
+
+// Hash identifies a schema description.
+func Hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Diff lists the tables that differ between two descriptions.
+type Diff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	// Changed are tables whose columns, types, keys or indexes changed
+	Changed []string `json:"changed,omitempty"`
+	// Unparsed is set if a description was not a list of tables, so only
+	// the fact that it changed is known
+	Unparsed bool `json:"unparsed,omitempty"`
+}
+
+// Empty reports whether nothing changed.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && !d.Unparsed
+}
+
+// String summarizes the diff, e.g. "+orders_archive, -tmp, ~customers".
+func (d Diff) String() string {
+	if d.Unparsed {
+		return "schema changed"
+	}
+	var parts []string
+	for _, t := range d.Added {
+		parts = append(parts, "+"+t)
+	}
+	for _, t := range d.Removed {
+		parts = append(parts, "-"+t)
+	}
+	for _, t := range d.Changed {
+		parts = append(parts, "~"+t)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// table is the name of an entry of a DescribeDatabase description.
+type table struct {
+	Table string `json:"table"`
+}
+
+// Compare returns the tables that differ between two descriptions.
+func Compare(before, after string) Diff {
+	old, err1 := parse(before)
+	cur, err2 := parse(after)
+	if err1 != nil || err2 != nil {
+		return Diff{Unparsed: before != after}
+	}
+
+	var d Diff
+	for name, columns := range cur {
+		prev, ok := old[name]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, name)
+		case !bytes.Equal(prev, columns):
+			d.Changed = append(d.Changed, name)
+		}
+	}
+	for name := range old {
+		if _, ok := cur[name]; !ok {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+	slices.Sort(d.Added)
+	slices.Sort(d.Removed)
+	slices.Sort(d.Changed)
+	return d
+}
+
+// parse reads the entry of each table of a description; "" has none.
+func parse(text string) (map[string]json.RawMessage, error) {
+	tables := make(map[string]json.RawMessage)
+	if text == "" {
+		return tables, nil
+	}
+	var list []json.RawMessage
+	if err := json.Unmarshal([]byte(text), &list); err != nil {
+		return nil, err
+	}
+	for _, entry := range list {
+		var t table
+		if err := json.Unmarshal(entry, &t); err != nil {
+			return nil, err
+		}
+		tables[t.Table] = entry
+	}
+	return tables, nil
+}
@@ -0,0 +1,201 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          Diff
+	}{
+		{"unchanged", `[{"table":"a","columns":["id integer"]}]`, `[{"table":"a","columns":["id integer"]}]`, Diff{}},
+		{"first load", "", `[{"table":"a","columns":["id integer"]}]`, Diff{Added: []string{"a"}}},
+		{
+			"added, removed and changed",
+			`[{"table":"a","columns":["id integer"]},{"table":"b","columns":["id integer"]}]`,
+			`[{"table":"a","columns":["id bigint"]},{"table":"c","columns":["id integer"]}]`,
+			Diff{Added: []string{"c"}, Removed: []string{"b"}, Changed: []string{"a"}},
+		},
+		{"primary key added", `[{"table":"a","columns":["id integer"]}]`, `[{"columns":["id integer"],"primary_key":["id"],"table":"a"}]`, Diff{Changed: []string{"a"}}},
+		{"not a list of tables", "tables: a", "tables: a, b", Diff{Unparsed: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compare(tt.before, tt.after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compare() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffString(t *testing.T) {
+	d := Diff{Added: []string{"orders_archive"}, Removed: []string{"tmp"}, Changed: []string{"customers"}}
+	if got, want := d.String(), "+orders_archive, -tmp, ~customers"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// describer returns its text, or err, and counts the calls.
+type describer struct {
+	mu    sync.Mutex
+	text  string
+	err   error
+	calls int
+}
+
+func (d *describer) DescribeDatabase(ctx context.Context) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls++
+	return d.text, d.err
+}
+
+func (d *describer) set(text string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.text = text
+}
+
+func TestCacheRefresh(t *testing.T) {
+	db := &describer{text: `[{"table":"a","columns":["id integer"]}]`}
+	c := NewCache(db)
+	var changes []Diff
+	c.OnChange(func(text string, diff Diff) { changes = append(changes, diff) })
+
+	if _, err := c.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	first := c.Hash()
+	if _, err := c.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("%d changes reported for one change", len(changes))
+	}
+
+	db.err = errors.New("connection lost")
+	if _, err := c.Refresh(context.Background()); err == nil {
+		t.Fatal("Refresh() succeeded after the describe failed")
+	}
+	if c.Text() != db.text || c.Hash() != first {
+		t.Error("failed refresh replaced the schema")
+	}
+
+	db.err = nil
+	db.set(`[{"table":"a","columns":["id integer"]},{"table":"b","columns":[]}]`)
+	diff, err := c.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(diff.Added, []string{"b"}) || c.Hash() == first || len(changes) != 2 {
+		t.Errorf("diff = %+v, hash %s, %d changes", diff, c.Hash(), len(changes))
+	}
+}
+
+func TestCacheWatch(t *testing.T) {
+	old := debounce
+	debounce = 20 * time.Millisecond
+	defer func() { debounce = old }()
+
+	db := &describer{text: `[{"table":"a","columns":[]}]`}
+	c := NewCache(db)
+	changed := make(chan Diff, 1)
+	c.OnChange(func(text string, diff Diff) { changed <- diff })
+
+	notifications := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Watch(ctx, WatchConfig{Notifications: notifications})
+	}()
+
+	// A migration's statements are refreshed for once
+	for range 5 {
+		notifications <- struct{}{}
+	}
+	select {
+	case diff := <-changed:
+		if !reflect.DeepEqual(diff.Added, []string{"a"}) {
+			t.Errorf("diff = %+v", diff)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("schema not refreshed after a notification")
+	}
+	cancel()
+	<-done
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.calls != 1 {
+		t.Errorf("described the database %d times for one burst of notifications", db.calls)
+	}
+}
+
+func TestCachePersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.json")
+	db := &describer{text: `[{"table":"a","columns":["id integer"]}]`}
+
+	c := NewCache(db)
+	if loaded, err := c.Persist(path, "db1"); err != nil || loaded {
+		t.Fatalf("Persist() = %v, %v without a file", loaded, err)
+	}
+	if _, err := c.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		database   string
+		edit       func(data []byte) []byte
+		wantLoaded bool
+	}{
+		{"same database", "db1", nil, true},
+		{"another database", "db2", nil, false},
+		{"edited file", "db1", func(data []byte) []byte { return bytes.Replace(data, []byte("integer"), []byte("bigint"), 1) }, false},
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := saved
+			if tt.edit != nil {
+				data = tt.edit(data)
+			}
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			db := &describer{}
+			c := NewCache(db)
+			loaded, err := c.Persist(path, tt.database)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded != tt.wantLoaded {
+				t.Fatalf("loaded = %v, want %v", loaded, tt.wantLoaded)
+			}
+			if loaded && (c.Text() != `[{"table":"a","columns":["id integer"]}]` || c.Hash() != Hash(c.Text()) || c.Described().IsZero()) {
+				t.Errorf("loaded %q, hash %s, described %v", c.Text(), c.Hash(), c.Described())
+			}
+			if db.calls != 0 {
+				t.Error("Persist() described the database")
+			}
+		})
+	}
+}
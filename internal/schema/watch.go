@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// debounce is how long Watch waits after a change notification for more,
+// so a migration running many DDL statements causes one refresh. It is
+// replaced in tests.
+var debounce = time.Second
+
+// WatchConfig holds configuration for Watch.
+type WatchConfig struct {
+	// Interval polls the database for changes (0 = no polling)
+	Interval time.Duration
+	// Notifications delivers a value whenever the database reports a DDL
+	// change, such as a Listener's C (optional)
+	Notifications <-chan struct{}
+}
+
+// Watch refreshes the cache on every notification and poll until ctx is
+// done. Refresh errors are logged and the previous schema kept.
+func (c *Cache) Watch(ctx context.Context, cfg WatchConfig) {
+	var tick <-chan time.Time
+	if cfg.Interval > 0 {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case _, ok := <-cfg.Notifications:
+			if !ok {
+				cfg.Notifications = nil
+				continue
+			}
+			if !drain(ctx, cfg.Notifications) {
+				return
+			}
+		}
+		if _, err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️  Failed to refresh the database schema: %v", err)
+		}
+	}
+}
+
+// drain swallows the notifications arriving within debounce of each other.
+// It returns false if ctx is done.
+func drain(ctx context.Context, notifications <-chan struct{}) bool {
+	timer := time.NewTimer(debounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case _, ok := <-notifications:
+			if !ok {
+				return true
+			}
+			timer.Reset(debounce)
+		}
+	}
+}
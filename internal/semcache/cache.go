@@ -0,0 +1,267 @@
+// Package semcache answers questions that closely resemble ones answered
+// before against the same database schema, without running the agents.
+package semcache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+// Defaults for Config.
+const (
+	DefaultThreshold  = 0.95
+	DefaultMaxEntries = 1000
+)
+
+// Config holds configuration for the Cache.
+type Config struct {
+	// Embedder compares questions (defaults to HashEmbedder)
+	Embedder Embedder
+	// Threshold is the minimum cosine similarity of a hit (defaults to DefaultThreshold)
+	Threshold float64
+	// Schema is the database schema the answers were computed against;
+	// answers stored for another schema are never served
+	Schema string
+	// Path persists the cache as a JSON file (optional)
+	Path string
+	// MaxEntries caps the cache, dropping the oldest answers first (defaults to DefaultMaxEntries)
+	MaxEntries int
+}
+
+// entry is a cached answer.
+type entry struct {
+	Question string    `json:"question"`
+	Vector   []float32 `json:"vector"`
+	Literals []string  `json:"literals,omitempty"`
+	Schema   string    `json:"schema"`
+	// Scope is the partition of users the answer may be served to
+	Scope string    `json:"scope,omitempty"`
+	Time  time.Time `json:"time"`
+	Turn  *app.Turn `json:"turn"`
+}
+
+// Cache stores answered questions with their embeddings.
+type Cache struct {
+	cfg    Config
+	schema string
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries []entry
+	// saveMu orders concurrent saves
+	saveMu sync.Mutex
+}
+
+// Open creates a cache, loading the answers stored at cfg.Path for the
+// same schema if the file exists.
+func Open(cfg Config) (*Cache, error) {
+	if cfg.Embedder == nil {
+		cfg.Embedder = HashEmbedder{}
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = DefaultThreshold
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+	c := &Cache{cfg: cfg, schema: SchemaHash(cfg.Schema), now: time.Now}
+	if cfg.Path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(cfg.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read semantic cache: %w", err)
+	}
+	var entries []entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse semantic cache %s: %w", cfg.Path, err)
+	}
+	// Answers about an older schema may no longer be right
+	for _, e := range entries {
+		if e.Schema == c.schema && e.Turn != nil {
+			c.entries = append(c.entries, e)
+		}
+	}
+	return c, nil
+}
+
+// SetSchema switches the cache to a changed schema, dropping the answers
+// computed against the previous one.
+func (c *Cache) SetSchema(schema string) error {
+	hash := SchemaHash(schema)
+	c.mu.Lock()
+	if hash == c.schema {
+		c.mu.Unlock()
+		return nil
+	}
+	c.schema = hash
+	c.entries = slices.DeleteFunc(c.entries, func(e entry) bool { return e.Schema != hash })
+	c.mu.Unlock()
+	return c.save()
+}
+
+// SchemaHash identifies a schema snapshot.
+func SchemaHash(schema string) string {
+	sum := sha256.Sum256([]byte(schema))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Len returns the number of cached answers.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Lookup returns a copy of the answer to the most similar question cached
+// under scope, with Cached describing the match, or nil if no question is
+// similar enough. Questions must mention the same numbers and quoted
+// values, so "top 5 in 2023" never answers "top 5 in 2024".
+func (c *Cache) Lookup(ctx context.Context, scope, question string) (*app.Turn, error) {
+	vec, err := c.cfg.Embedder.Embed(ctx, question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed question: %w", err)
+	}
+	literals := extractLiterals(question)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	best, bestSim := -1, 0.0
+	for i, e := range c.entries {
+		if e.Scope != scope || !slices.Equal(e.Literals, literals) {
+			continue
+		}
+		if sim := Cosine(vec, e.Vector); sim >= c.cfg.Threshold && sim > bestSim {
+			best, bestSim = i, sim
+		}
+	}
+	if best < 0 {
+		return nil, nil
+	}
+
+	e := c.entries[best]
+	turn := *e.Turn
+	turn.Cached = &app.CacheHit{Question: e.Question, Similarity: bestSim, AnsweredAt: e.Time}
+	return &turn, nil
+}
+
+// Store caches the answer to question under scope, replacing the answers
+// to questions similar enough to be answered by it. Incomplete answers are
+// not stored: failed queries, pending approvals, dry runs and answers that
+// were themselves cached.
+func (c *Cache) Store(ctx context.Context, scope, question string, turn *app.Turn) error {
+	if !cacheable(turn) {
+		return nil
+	}
+	vec, err := c.cfg.Embedder.Embed(ctx, question)
+	if err != nil {
+		return fmt.Errorf("failed to embed question: %w", err)
+	}
+
+	stored := *turn
+	stored.Routing, stored.Filters, stored.Tokens = nil, nil, app.TokenUsage{}
+	e := entry{
+		Question: question,
+		Vector:   vec,
+		Literals: extractLiterals(question),
+		Scope:    scope,
+		Time:     c.now(),
+		Turn:     &stored,
+	}
+
+	c.mu.Lock()
+	e.Schema = c.schema
+	c.entries = slices.DeleteFunc(c.entries, func(old entry) bool {
+		return old.Scope == scope && slices.Equal(old.Literals, e.Literals) && Cosine(old.Vector, vec) >= c.cfg.Threshold
+	})
+	c.entries = append(c.entries, e)
+	if over := len(c.entries) - c.cfg.MaxEntries; over > 0 {
+		c.entries = slices.Delete(c.entries, 0, over)
+	}
+	c.mu.Unlock()
+	return c.save()
+}
+
+// Clear removes every cached answer.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+	return c.save()
+}
+
+func cacheable(turn *app.Turn) bool {
+	if turn == nil || turn.Text == "" || turn.DryRun || turn.Cached != nil || len(turn.Approvals) > 0 || turn.Clarification != nil {
+		return false
+	}
+	for _, q := range turn.Queries {
+		if q.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// literalPattern matches quoted values and words containing digits.
+var literalPattern = regexp.MustCompile(`'[^']*'|"[^"]*"|\b\w*\d\w*\b`)
+
+// extractLiterals returns the sorted, lowercased literals of question.
+func extractLiterals(question string) []string {
+	var literals []string
+	for _, m := range literalPattern.FindAllString(question, -1) {
+		literals = append(literals, strings.ToLower(m))
+	}
+	slices.Sort(literals)
+	return literals
+}
+
+// save writes the cache to its file, through a temporary file so a crash
+// never leaves it half-written.
+func (c *Cache) save() error {
+	if c.cfg.Path == "" {
+		return nil
+	}
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	c.mu.Lock()
+	data, err := json.Marshal(c.entries)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("json error: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.cfg.Path), ".semcache-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write semantic cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write semantic cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write semantic cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.cfg.Path); err != nil {
+		return fmt.Errorf("failed to write semantic cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package semcache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+func TestLookup(t *testing.T) {
+	ctx := context.Background()
+	c, err := Open(Config{Schema: "orders(id, region)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	answers := map[string]string{
+		"How many orders are there?":       "42 orders.",
+		"Top 5 customers in 2023":          "Acme leads 2023.",
+		"Show revenue by month":            "Revenue grew.",
+		"Which orders failed to load?":     "",
+		"Total sales for region 'EMEA'":    "EMEA sold 10.",
+		"What is the average order value?": "25.",
+	}
+	for q, text := range answers {
+		if err := c.Store(ctx, "", q, &app.Turn{Text: text}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.Store(ctx, "", "Delete old orders", &app.Turn{Text: "Approve?", Approvals: []app.Approval{{SQL: "DELETE FROM orders"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 5 {
+		t.Errorf("Len() = %d, want 5 (empty and pending answers are not cached)", c.Len())
+	}
+
+	tests := []struct {
+		question string
+		want     string // cached text, "" for a miss
+	}{
+		{"how many orders are there", "42 orders."},
+		{"Show me the revenue by month", "Revenue grew."},
+		{"Show the revenue by month", "Revenue grew."},
+		{"Top 5 customers in 2024", ""},
+		{"Total sales for region 'APAC'", ""},
+		{"What is the average order value per customer?", ""},
+		{"Delete old orders", ""},
+	}
+	for _, tt := range tests {
+		turn, err := c.Lookup(ctx, "", tt.question)
+		if err != nil {
+			t.Fatalf("Lookup(%q) error = %v", tt.question, err)
+		}
+		got := ""
+		if turn != nil {
+			got = turn.Text
+			if turn.Cached == nil || turn.Cached.Similarity < DefaultThreshold {
+				t.Errorf("Lookup(%q) cached = %+v, want the match described", tt.question, turn.Cached)
+			}
+		}
+		if got != tt.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "semcache.json")
+
+	c, err := Open(Config{Schema: "v1", Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Store(ctx, "", "How many orders?", &app.Turn{Text: "42."}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(Config{Schema: "v1", Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn, _ := reopened.Lookup(ctx, "", "how many orders"); turn == nil || turn.Text != "42." {
+		t.Errorf("Lookup() after reopening = %+v, want the stored answer", turn)
+	}
+
+	changed, err := Open(Config{Schema: "v2", Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.Len() != 0 {
+		t.Errorf("Len() = %d after a schema change, want 0", changed.Len())
+	}
+}
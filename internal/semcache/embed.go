@@ -0,0 +1,107 @@
+package semcache
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Embedder turns text into a vector whose cosine similarity to another
+// text's vector measures how alike the texts are.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// hashDims is the size of the vectors made by HashEmbedder.
+const hashDims = 1024
+
+// HashEmbedder embeds text by hashing its words, word pairs and character
+// trigrams into a fixed-size vector. It needs no model and recognizes
+// rephrasings that share most of their wording, such as changes of case,
+// punctuation or filler words; it does not know synonyms.
+type HashEmbedder struct{}
+
+// Embed implements Embedder.
+func (HashEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	v := make([]float32, hashDims)
+	ws := words(text)
+	for i, word := range ws {
+		add(v, "w:"+word, 2)
+		if i > 0 {
+			add(v, "b:"+ws[i-1]+" "+word, 3)
+		}
+		padded := "^" + word + "$"
+		runes := []rune(padded)
+		for i := 0; i+3 <= len(runes); i++ {
+			add(v, "t:"+string(runes[i:i+3]), 1)
+		}
+	}
+	normalize(v)
+	return v, nil
+}
+
+// fillerWords carry no meaning in a question to a database.
+var fillerWords = map[string]bool{
+	"a": true, "an": true, "the": true, "please": true, "me": true,
+	"can": true, "could": true, "would": true, "you": true, "i": true,
+}
+
+// words splits text into lowercase words and numbers, without filler words.
+func words(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, w := range fields {
+		if !fillerWords[w] {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// add adds weight to the dimension of feature, with a sign from the hash
+// so that collisions tend to cancel out.
+func add(v []float32, feature string, weight float32) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+	if sum&1 == 1 {
+		weight = -weight
+	}
+	v[(sum>>1)%uint64(len(v))] += weight
+}
+
+func normalize(v []float32) {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return
+	}
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range v {
+		v[i] *= scale
+	}
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if their lengths
+// differ or either is zero.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+// writeDB records the statements it executes.
+type writeDB struct {
+	sqlagent.MCPClient
+	mu       sync.Mutex
+	executed []string
+}
+
+func (db *writeDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.executed = append(db.executed, query)
+	return `{"rows_affected":1}`, nil
+}
+
+func TestApproval(t *testing.T) {
+	const proposed = "DELETE FROM orders WHERE id = 1"
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: sqlagent.ProposeWriteToolName, Respond: mockllm.Response{Text: "Done."}},
+		mockllm.Rule{Respond: mockllm.Response{Calls: []mockllm.Call{{Name: sqlagent.ProposeWriteToolName, Args: map[string]any{"sql": proposed, "reason": "cancelled"}}}}},
+	)
+	db := &writeDB{}
+	s, err := New(Config{App: testutil.NewWriteApp(t, llm, db)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/ask", bytes.NewBufferString(`{"query":"Delete order 1","session_id":"s1"}`)))
+	var turn app.Turn
+	if err := json.NewDecoder(rec.Body).Decode(&turn); err != nil || len(turn.Approvals) != 1 {
+		t.Fatalf("ask: status %d, approvals %+v, err %v", rec.Code, turn.Approvals, err)
+	}
+	callID := turn.Approvals[0].CallID
+
+	tests := []struct {
+		name       string
+		callID     string
+		sql        string
+		wantStatus int
+	}{
+		{name: "unknown call", callID: "unknown", sql: proposed, wantStatus: http.StatusNotFound},
+		{name: "client sends other SQL", callID: callID, sql: "DROP TABLE orders", wantStatus: http.StatusOK},
+		{name: "answered", callID: callID, sql: proposed, wantStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"session_id":"s1","approval":{"call_id":%q,"sql":%q},"approved":true}`, tt.callID, tt.sql)
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/approvals", bytes.NewBufferString(body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+	if want := []string{proposed}; !slices.Equal(db.executed, want) {
+		t.Errorf("executed %q, want only the proposed %q", db.executed, want)
+	}
+}
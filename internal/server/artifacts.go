@@ -0,0 +1,36 @@
+package server
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+)
+
+// handleStoredArtifact serves a chart, export or report from the artifact
+// store. Artifacts of other users are reported as missing.
+func (s *Server) handleStoredArtifact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUser(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	if s.artifacts == nil {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "artifacts are not stored"})
+		return
+	}
+	a, data, err := s.artifacts.Get(r.Context(), r.PathValue("id"))
+	if errors.Is(err, artifacts.ErrNotFound) || (err == nil && a.UserID != "" && a.UserID != userID) {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: artifacts.ErrNotFound.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Write(data)
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func TestStoredArtifact(t *testing.T) {
+	store, err := artifacts.NewDir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.Put(context.Background(), artifacts.Artifact{Kind: artifacts.KindExport, Name: "result.csv", ContentType: "text/csv", UserID: "alice"}, []byte("orders\n4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(Config{App: testutil.NewApp(t, mockllm.New(), ordersDB{}), Artifacts: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "owner", target: artifacts.Path(a.ID) + "?user_id=alice", wantStatus: http.StatusOK, wantBody: "orders\n4\n"},
+		{name: "other user", target: artifacts.Path(a.ID) + "?user_id=bob", wantStatus: http.StatusNotFound},
+		{name: "missing", target: artifacts.Path(artifacts.NewID()) + "?user_id=alice", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest("GET", tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantBody == "" {
+				return
+			}
+			if rec.Body.String() != tt.wantBody || rec.Header().Get("Content-Disposition") != `attachment; filename=result.csv` {
+				t.Errorf("got %q with %q, want %q as an attachment", rec.Body, rec.Header().Get("Content-Disposition"), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/auth"
+)
+
+// authenticated wraps a route's handler with the client's credentials: the
+// request is rejected without valid ones, for admin routes without an admin
+// key, and over the client's rate limit. Otherwise the identity travels in
+// the request's context, and read-only clients get a read-only context.
+func (s *Server) authenticated(rt route, next http.HandlerFunc) http.HandlerFunc {
+	exempt := rt.path == "/v1/health" || rt.path == "/v1/openapi.json"
+	admin := strings.HasPrefix(rt.path, "/v1/admin/")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if exempt {
+			next(w, r)
+			return
+		}
+		id, err := s.auth.Authenticate(r)
+		if err != nil {
+			if errors.Is(err, auth.ErrUnauthenticated) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="multi-agent"`)
+				writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if admin && !id.Admin {
+			writeJSON(w, http.StatusForbidden, ErrorResponse{Error: fmt.Sprintf("credentials %s may not use admin endpoints", id.Name)})
+			return
+		}
+		if ok, wait := s.auth.Allow(id); !ok {
+			secs := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+			writeJSON(w, http.StatusTooManyRequests, ErrorResponse{Error: fmt.Sprintf("rate limit reached: %d requests per minute; try again in %d seconds", id.RateLimit, secs)})
+			return
+		}
+
+		ctx := auth.NewContext(r.Context(), id)
+		if id.ReadOnly() {
+			ctx = sqlagent.WithReadOnly(ctx)
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// requestUser returns the user a request acts as, writing an error response
+// if the client may not act as userID.
+func requestUser(w http.ResponseWriter, r *http.Request, userID string) (string, bool) {
+	user, err := actingUser(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{Error: err.Error()})
+		return "", false
+	}
+	return user, true
+}
+
+// actingUser returns the user a request acts as. Authenticated clients act
+// as their identity's user and may not name another; without authentication
+// the requested user, or the default, is used.
+func actingUser(ctx context.Context, userID string) (string, error) {
+	id := auth.FromContext(ctx)
+	if id == nil {
+		if userID == "" {
+			userID = defaultUserID
+		}
+		return userID, nil
+	}
+	if userID != "" && userID != id.User {
+		return "", fmt.Errorf("credentials %s may only act as user %s", id.Name, id.User)
+	}
+	return id.User, nil
+}
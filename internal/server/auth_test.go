@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/auth"
+)
+
+func TestAuthenticated(t *testing.T) {
+	a, err := auth.New(auth.Config{Keys: []auth.Key{
+		{Name: "dashboard", Key: "dash-key", RateLimit: 2},
+		{Name: "ops", Key: "ops-key", User: "alice", Profile: auth.ProfileReadWrite, Admin: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{auth: a}
+
+	// whoami reports the user a request acts as
+	whoami := func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requestUser(w, r, r.URL.Query().Get("user_id"))
+		if ok {
+			writeJSON(w, http.StatusOK, map[string]string{"user": user})
+		}
+	}
+	routes := map[string]http.HandlerFunc{
+		"/v1/sessions":      s.authenticated(route{path: "/v1/sessions"}, whoami),
+		"/v1/admin/metrics": s.authenticated(route{path: "/v1/admin/metrics"}, whoami),
+		"/v1/health":        s.authenticated(route{path: "/v1/health"}, whoami),
+		"/v1/approvals":     s.authenticated(route{path: "/v1/approvals"}, s.handleApproval),
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		key        string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "no credentials", target: "/v1/sessions", wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", target: "/v1/sessions", key: "nope", wantStatus: http.StatusUnauthorized},
+		{name: "health is public", target: "/v1/health", wantStatus: http.StatusOK, wantBody: `"user":"api"`},
+		{name: "key acts as its user", target: "/v1/sessions", key: "ops-key", wantStatus: http.StatusOK, wantBody: `"user":"alice"`},
+		{name: "same user named", target: "/v1/sessions?user_id=alice", key: "ops-key", wantStatus: http.StatusOK, wantBody: `"user":"alice"`},
+		{name: "other user named", target: "/v1/sessions?user_id=bob", key: "ops-key", wantStatus: http.StatusForbidden},
+		{name: "admin key", target: "/v1/admin/metrics", key: "ops-key", wantStatus: http.StatusOK},
+		{name: "non-admin key", target: "/v1/admin/metrics", key: "dash-key", wantStatus: http.StatusForbidden},
+		{name: "read-only approval", method: "POST", target: "/v1/approvals", key: "dash-key",
+			body: `{"approval": {"call_id": "c1", "sql": "DELETE FROM orders"}, "approved": true}`, wantStatus: http.StatusForbidden, wantBody: "read-only"},
+		{name: "within the rate limit", target: "/v1/sessions", key: "dash-key", wantStatus: http.StatusOK, wantBody: `"user":"dashboard"`},
+		{name: "over the rate limit", target: "/v1/sessions", key: "dash-key", wantStatus: http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			r := httptest.NewRequest(method, tt.target, strings.NewReader(tt.body))
+			if tt.key != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			w := httptest.NewRecorder()
+			routes[r.URL.Path](w, r)
+
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("%s %s = %d %s, want %d containing %q", method, tt.target, w.Code, w.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/pkg/e2e"
+)
+
+// RequestIDHeader carries the request ID on callbacks.
+const RequestIDHeader = "X-Request-ID"
+
+// callbackRetries are the delays before each retry of a failed callback.
+var callbackRetries = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second}
+
+// AcceptedResponse is returned by /v1/ask when the request registers a
+// callback_url: the turn runs in the background and its Callback is posted
+// to the URL when it completes.
+type AcceptedResponse struct {
+	RequestID string `json:"request_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+}
+
+// Callback is posted to a request's callback_url when its turn completes.
+// Turn holds the structured result: the routing (intent), each query's SQL
+// and data, and the charts.
+type Callback struct {
+	RequestID string    `json:"request_id"`
+	UserID    string    `json:"user_id"`
+	SessionID string    `json:"session_id"`
+	Query     string    `json:"query"`
+	Turn      *app.Turn `json:"turn,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// callbackAllowed reports whether raw is an absolute http(s) URL matching
+// one of the allowed hosts or URL prefixes, if any are configured. Hosts
+// may be "host", "host:port" or "*.domain"; prefixes start with a scheme.
+func callbackAllowed(raw string, allowed []string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return false
+	}
+	if len(allowed) == 0 {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if strings.Contains(pattern, "://") {
+			p, err := url.Parse(pattern)
+			if err == nil && p.Scheme == u.Scheme && strings.EqualFold(p.Host, u.Host) && underPath(u.Path, p.Path) {
+				return true
+			}
+			continue
+		}
+		if pattern == host || pattern == strings.ToLower(u.Host) ||
+			strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// underPath reports whether path is prefix or below it.
+func underPath(path, prefix string) bool {
+	if prefix == "" || path == prefix {
+		return true
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return strings.HasPrefix(path, prefix)
+}
+
+// nonPublic are the address ranges callbacks may not reach besides those
+// net/netip classifies as loopback, private, link-local, multicast or
+// unspecified: "this network", shared address space (carrier-grade NAT)
+// and IETF protocol assignments.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+}
+
+// refusePrivate is a net.Dialer Control function refusing connections to
+// addresses that are not public. It sees the address actually dialed, so
+// host names resolving to internal addresses are refused as well.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || slices.ContainsFunc(nonPublic, func(p netip.Prefix) bool { return p.Contains(ip) }) {
+		return fmt.Errorf("refusing to post a callback to non-public address %s", ip)
+	}
+	return nil
+}
+
+// newCallbackClient returns the client posting callbacks. Unless
+// allowPrivate is set, it only connects to public addresses. Proxies are
+// not used, as the proxy would be dialed instead of the callback host, and
+// redirects are not followed, as they could lead past the allowed hosts.
+func newCallbackClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = refusePrivate
+	}
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// askAsync accepts a question with a callback URL, answers it in the
+// background and posts the Callback when the turn completes.
+func (s *Server) askAsync(w http.ResponseWriter, r *http.Request, req AskRequest) {
+	if !callbackAllowed(req.CallbackURL, s.callbackHosts) {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "callback_url must be an absolute http or https URL of an allowed host"})
+		return
+	}
+
+	if s.app.Draining() {
+		writeJSON(w, http.StatusServiceUnavailable, ErrorResponse{Error: app.ErrDraining.Error()})
+		return
+	}
+
+	id := newRequestID()
+	// The turn outlives the request, but keeps its credentials and options;
+	// it is cancelled when shutdown stops waiting for it
+	parent, stop := context.WithCancel(context.WithoutCancel(r.Context()))
+	stopOnAbort := context.AfterFunc(s.abortCallbacks, stop)
+	s.callbacks.Add(1)
+	go func() {
+		defer s.callbacks.Done()
+		defer stop()
+		defer stopOnAbort()
+
+		lock := s.sessionLock(req.UserID, req.SessionID)
+		lock.Lock()
+		ctx, cancel := context.WithTimeout(askContext(parent, req), s.turnTimeout)
+		console.Printf("  🌐 [API] %s/%s (callback %s): %s\n", req.UserID, req.SessionID, id, logQuery(ctx, req.Query))
+		turn, err := s.app.Ask(ctx, req.UserID, req.SessionID, req.Query, nil)
+		s.record(ctx, req.UserID, req.SessionID, req.Query, turn, err)
+		cancel()
+		lock.Unlock()
+
+		cb := Callback{RequestID: id, UserID: req.UserID, SessionID: req.SessionID, Query: req.Query, Turn: turn}
+		if err != nil {
+			cb.Turn, cb.Error = nil, err.Error()
+		}
+		if err := s.postCallback(parent, req.CallbackURL, cb); err != nil {
+			log.Printf("⚠️  [API] Callback %s failed: %v", id, err)
+		}
+	}()
+
+	writeJSON(w, http.StatusAccepted, AcceptedResponse{RequestID: id, UserID: req.UserID, SessionID: req.SessionID})
+}
+
+// postCallback posts cb to url, sealed if the request was encrypted,
+// retrying after network errors and 5xx responses.
+func (s *Server) postCallback(ctx context.Context, url string, cb Callback) error {
+	body, err := json.Marshal(cb)
+	if err != nil {
+		return fmt.Errorf("json error: %w", err)
+	}
+	contentType := "application/json"
+	key := sealKey(ctx)
+	if key != nil {
+		if body, err = e2e.Seal(key, body); err != nil {
+			return fmt.Errorf("failed to encrypt callback: %w", err)
+		}
+		contentType = "text/plain"
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(RequestIDHeader, cb.RequestID)
+		if key != nil {
+			req.Header.Set(e2e.Header, e2e.Algorithm)
+		}
+
+		resp, err := s.callbackClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode/100 == 2 {
+				return nil
+			}
+			err = fmt.Errorf("%s returned %s", url, resp.Status)
+			if resp.StatusCode < 500 {
+				return err
+			}
+		}
+		if attempt == len(callbackRetries) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (gave up retrying: %w)", err, ctx.Err())
+		case <-time.After(callbackRetries[attempt]):
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/client"
+	"github.com/anuvratrastogi/multi-agent/pkg/e2e"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+type ordersDB struct {
+	sqlagent.MCPClient
+}
+
+func (ordersDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	return `[{"orders":4}]`, nil
+}
+
+func TestAskCallback(t *testing.T) {
+	callbackRetries = []time.Duration{time.Millisecond}
+	defer func() { callbackRetries = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second} }()
+
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 4 orders."}},
+		mockllm.Rule{User: "orders", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) AS orders FROM orders"}}}}},
+	)
+	s, err := New(Config{App: testutil.NewApp(t, llm, ordersDB{}), CallbackAllowPrivate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first delivery fails and is retried
+	var attempts int
+	var got Callback
+	var requestID string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		requestID = r.Header.Get(RequestIDHeader)
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer receiver.Close()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "invalid callback", body: `{"query":"How many orders?","callback_url":"ftp://example.com"}`, wantStatus: http.StatusBadRequest},
+		{name: "accepted", body: `{"query":"How many orders?","session_id":"s1","callback_url":"` + receiver.URL + `"}`, wantStatus: http.StatusAccepted},
+	}
+	var accepted AcceptedResponse
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/ask", bytes.NewBufferString(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code == http.StatusAccepted {
+				json.NewDecoder(rec.Body).Decode(&accepted)
+			}
+		})
+	}
+	s.callbacks.Wait()
+
+	if accepted.RequestID == "" || accepted.SessionID != "s1" {
+		t.Errorf("accepted = %+v", accepted)
+	}
+	if attempts != 2 || requestID != accepted.RequestID || got.RequestID != accepted.RequestID {
+		t.Errorf("%d attempts, request ID %q, callback %+v; want 2 attempts for %s", attempts, requestID, got, accepted.RequestID)
+	}
+	if got.Turn == nil || !strings.Contains(got.Turn.Text, "4 orders") || len(got.Turn.Queries) != 1 || got.Turn.Queries[0].Data == "" {
+		t.Errorf("callback turn = %+v, want the answer with its query and data", got.Turn)
+	}
+}
+
+func TestAskCallbackEncrypted(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, e2e.KeySize)
+	llm := mockllm.New(mockllm.Rule{Respond: mockllm.Response{Text: "There are 4 orders."}})
+	s, err := New(Config{App: testutil.NewApp(t, llm, ordersDB{}), EncryptionKey: key, CallbackAllowPrivate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	c := client.New(client.Config{BaseURL: srv.URL, EncryptionKey: key})
+
+	var wire []byte
+	var got *client.Callback
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wire, _ = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(wire))
+		cb, err := c.ReadCallback(r)
+		if err != nil {
+			t.Errorf("ReadCallback() error = %v", err)
+		}
+		got = cb
+	}))
+	defer receiver.Close()
+
+	accepted, err := c.AskAsync(context.Background(), client.AskRequest{Query: "How many orders?"}, receiver.URL)
+	if err != nil {
+		t.Fatalf("AskAsync() error = %v", err)
+	}
+	s.callbacks.Wait()
+
+	if got == nil || got.RequestID != accepted.RequestID || got.Turn == nil || !strings.Contains(got.Turn.Text, "4 orders") {
+		t.Fatalf("callback = %+v", got)
+	}
+	if bytes.Contains(wire, []byte("orders")) {
+		t.Errorf("callback sent in plaintext: %s", wire)
+	}
+}
+
+func TestCallbackAllowed(t *testing.T) {
+	allowed := []string{"hooks.example.com", "*.example.org", "localhost:9000", "https://api.example.net/turns"}
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://hooks.example.com/turns", want: true},
+		{url: "http://HOOKS.example.com:8443/x", want: true},
+		{url: "https://a.b.example.org/", want: true},
+		{url: "https://example.org/", want: false},
+		{url: "http://localhost:9000/cb", want: true},
+		{url: "http://localhost:9001/cb", want: false},
+		{url: "https://api.example.net/turns/42", want: true},
+		{url: "https://api.example.net/turns", want: true},
+		{url: "https://api.example.net/turnstile", want: false},
+		{url: "http://api.example.net/turns", want: false},
+		{url: "https://hooks.example.com.evil.com/", want: false},
+		{url: "https://hooks.example.com@evil.com/", want: false},
+		{url: "ftp://hooks.example.com/", want: false},
+		{url: "/relative", want: false},
+	}
+	for _, tt := range tests {
+		if got := callbackAllowed(tt.url, allowed); got != tt.want {
+			t.Errorf("callbackAllowed(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+	if !callbackAllowed("https://anywhere.example/", nil) {
+		t.Error("callbackAllowed() without an allowlist refused a public URL")
+	}
+}
+
+func TestCallbackRefusesPrivateAddresses(t *testing.T) {
+	callbackRetries = []time.Duration{time.Millisecond}
+	defer func() { callbackRetries = []time.Duration{time.Second, 5 * time.Second, 30 * time.Second} }()
+
+	s, err := New(Config{App: testutil.NewApp(t, mockllm.New(), ordersDB{})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var delivered bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { delivered = true }))
+	defer receiver.Close()
+
+	// Names are checked on the address they resolve to
+	port := receiver.URL[strings.LastIndex(receiver.URL, ":"):]
+	for _, url := range []string{receiver.URL, "http://localhost" + port, "http://169.254.169.254/latest/meta-data/"} {
+		err := s.postCallback(context.Background(), url, Callback{RequestID: "r1"})
+		if err == nil || !strings.Contains(err.Error(), "non-public address") {
+			t.Errorf("postCallback(%s) error = %v, want the address refused", url, err)
+		}
+	}
+	if delivered {
+		t.Error("a callback reached the loopback receiver")
+	}
+
+	// Retries stop when the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	callbackRetries = []time.Duration{time.Hour}
+	if err := s.postCallback(ctx, receiver.URL, Callback{RequestID: "r2"}); err == nil {
+		t.Error("postCallback() with a cancelled context succeeded")
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/anuvratrastogi/multi-agent/pkg/e2e"
+)
+
+type encryptionKey struct{}
+
+// sealKey returns the key to seal responses with, if the request was
+// encrypted.
+func sealKey(ctx context.Context) []byte {
+	key, _ := ctx.Value(encryptionKey{}).([]byte)
+	return key
+}
+
+// sealingWriter buffers a response so it can be sealed as a whole.
+type sealingWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *sealingWriter) Header() http.Header { return w.header }
+
+func (w *sealingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(p)
+}
+
+func (w *sealingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// encrypted wraps a route's handler with end-to-end encryption. Requests
+// carrying the e2e.Header have their body decrypted in memory, and their
+// response sealed: as a whole, or event by event for streams.
+func (s *Server) encrypted(rt route) http.HandlerFunc {
+	exempt := rt.path == "/v1/health" || rt.path == "/v1/openapi.json"
+	stream := rt.contentType == "text/event-stream"
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		algorithm := r.Header.Get(e2e.Header)
+		if algorithm == "" {
+			if s.requireEncryption && !exempt {
+				writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "this server requires encrypted requests"})
+				return
+			}
+			rt.handler(w, r)
+			return
+		}
+		if s.encryptionKey == nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "encryption is not configured on this server"})
+			return
+		}
+		if algorithm != e2e.Algorithm {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "unsupported encryption " + algorithm})
+			return
+		}
+
+		if rt.request != nil {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "failed to read request body"})
+				return
+			}
+			plaintext, err := e2e.Open(s.encryptionKey, body)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "failed to decrypt request body"})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(plaintext))
+		}
+		r = r.WithContext(context.WithValue(r.Context(), encryptionKey{}, s.encryptionKey))
+
+		if stream {
+			rt.handler(w, r)
+			return
+		}
+
+		sw := &sealingWriter{header: w.Header()}
+		rt.handler(sw, r)
+		sealed, err := e2e.Seal(s.encryptionKey, sw.body.Bytes())
+		if err != nil {
+			w.Header().Del(e2e.Header)
+			writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "failed to encrypt response"})
+			return
+		}
+		w.Header().Set(e2e.Header, e2e.Algorithm)
+		w.Header().Set("Content-Type", "text/plain")
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		w.WriteHeader(sw.status)
+		w.Write(sealed)
+	}
+}
+
+// tlsConfig builds the listener's TLS configuration. With a client CA, every
+// client must present a certificate signed by it (mutual TLS).
+func tlsConfig(clientCAFile string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if clientCAFile == "" {
+		return cfg, nil
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	return cfg, nil
+}
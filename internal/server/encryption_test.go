@@ -0,0 +1,72 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/pkg/client"
+	"github.com/anuvratrastogi/multi-agent/pkg/e2e"
+)
+
+func TestEncryptedRoute(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, e2e.KeySize)
+
+	var received []byte
+	echo := route{path: "/v1/ask", request: AskRequest{}, handler: func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		var req AskRequest
+		json.Unmarshal(received, &req)
+		writeJSON(w, http.StatusOK, app.Turn{Text: "echo: " + req.Query})
+	}}
+
+	tests := []struct {
+		name      string
+		require   bool
+		clientKey []byte
+		wantErr   bool
+	}{
+		{name: "encrypted", clientKey: key},
+		{name: "plaintext allowed", clientKey: nil},
+		{name: "plaintext rejected", require: true, clientKey: nil, wantErr: true},
+		{name: "wrong key", clientKey: bytes.Repeat([]byte{8}, e2e.KeySize), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{encryptionKey: key, requireEncryption: tt.require}
+			var wire bytes.Buffer
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				wire.Write(body)
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				s.encrypted(echo)(w, r)
+			}))
+			defer srv.Close()
+			received = nil
+
+			c := client.New(client.Config{BaseURL: srv.URL, EncryptionKey: tt.clientKey, MaxRetries: -1})
+			turn, err := c.Ask(context.Background(), client.AskRequest{Query: "secret question"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if turn.Text != "echo: secret question" {
+				t.Errorf("turn.Text = %q", turn.Text)
+			}
+			if !bytes.Contains(received, []byte("secret question")) {
+				t.Errorf("handler received %q, want the decrypted request", received)
+			}
+			if encrypted := tt.clientKey != nil; encrypted == bytes.Contains(wire.Bytes(), []byte("secret")) {
+				t.Errorf("request on the wire = %q (encrypted: %v)", wire.String(), encrypted)
+			}
+		})
+	}
+}
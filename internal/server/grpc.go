@@ -0,0 +1,321 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/auth"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/pkg/agentpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCServer returns a gRPC server for the agentpb.AgentService, sharing
+// the HTTP API's sessions, transcripts, credentials and TLS settings.
+func (s *Server) GRPCServer() (*grpc.Server, error) {
+	var opts []grpc.ServerOption
+	if s.tlsCertFile != "" {
+		cfg, err := tlsConfig(s.clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.LoadX509KeyPair(s.tlsCertFile, s.tlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	} else if s.requireEncryption {
+		// gRPC has no end-to-end encryption, so it must at least use TLS
+		return nil, fmt.Errorf("gRPC requires a TLS certificate when encryption is required")
+	}
+	if s.auth != nil {
+		opts = append(opts, grpc.UnaryInterceptor(s.unaryAuth), grpc.StreamInterceptor(s.streamAuth))
+	}
+
+	srv := grpc.NewServer(opts...)
+	agentpb.RegisterAgentServiceServer(srv, &grpcService{s: s})
+	return srv, nil
+}
+
+// ListenAndServeGRPC serves the gRPC API on addr until ctx is cancelled.
+func (s *Server) ListenAndServeGRPC(ctx context.Context, addr string) error {
+	srv, err := s.GRPCServer()
+	if err != nil {
+		return err
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("grpc server error: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		// Calls in flight may finish until the shutdown timeout
+		timer := time.AfterFunc(s.shutdownTimeout, srv.Stop)
+		defer timer.Stop()
+		srv.GracefulStop()
+	}()
+	if err := srv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("grpc server error: %w", err)
+	}
+	return nil
+}
+
+// grpcIdentity checks the credentials in the call's metadata as
+// authenticated does for HTTP requests, and returns the context to serve
+// the call with.
+func (s *Server) grpcIdentity(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var secret string
+	if keys := md.Get(strings.ToLower(auth.KeyHeader)); len(keys) > 0 {
+		secret = keys[0]
+	} else if values := md.Get("authorization"); len(values) > 0 {
+		if scheme, token, ok := strings.Cut(values[0], " "); ok && strings.EqualFold(scheme, "Bearer") {
+			secret = strings.TrimSpace(token)
+		}
+	}
+
+	id, err := s.auth.AuthenticateSecret(ctx, secret)
+	if err != nil {
+		if errors.Is(err, auth.ErrUnauthenticated) {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if ok, wait := s.auth.Allow(id); !ok {
+		secs := int(math.Ceil(wait.Seconds()))
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit reached: %d requests per minute; try again in %d seconds", id.RateLimit, secs)
+	}
+
+	ctx = auth.NewContext(ctx, id)
+	if id.ReadOnly() {
+		ctx = sqlagent.WithReadOnly(ctx)
+	}
+	return ctx, nil
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.grpcIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.grpcIdentity(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream carries the caller's identity in its context.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context { return s.ctx }
+
+// grpcService implements agentpb.AgentService on a Server.
+type grpcService struct {
+	agentpb.UnimplementedAgentServiceServer
+	s *Server
+}
+
+// askRequest validates a QueryRequest as decodeAsk does an AskRequest.
+func askRequest(ctx context.Context, in *agentpb.QueryRequest) (AskRequest, error) {
+	req := AskRequest{UserID: in.UserId, SessionID: in.SessionId, Query: in.Query, DryRun: in.DryRun, Fresh: in.Fresh}
+	if req.Query == "" {
+		return req, status.Error(codes.InvalidArgument, "query is required")
+	}
+	user, err := actingUser(ctx, req.UserID)
+	if err != nil {
+		return req, status.Error(codes.PermissionDenied, err.Error())
+	}
+	req.UserID = user
+	if req.SessionID == "" {
+		req.SessionID = defaultSessionID
+	}
+	return req, nil
+}
+
+func (g *grpcService) Query(ctx context.Context, in *agentpb.QueryRequest) (*agentpb.Turn, error) {
+	req, err := askRequest(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	lock := g.s.sessionLock(req.UserID, req.SessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(askContext(ctx, req), g.s.turnTimeout)
+	defer cancel()
+
+	console.Printf("  🌐 [gRPC] %s/%s: %s\n", req.UserID, req.SessionID, logQuery(ctx, req.Query))
+	turn, err := g.s.app.Ask(ctx, req.UserID, req.SessionID, req.Query, nil)
+	g.s.record(ctx, req.UserID, req.SessionID, req.Query, turn, err)
+	if err != nil {
+		return nil, turnError(err)
+	}
+	return protoTurn(turn), nil
+}
+
+func (g *grpcService) StreamQuery(in *agentpb.QueryRequest, stream grpc.ServerStreamingServer[agentpb.QueryEvent]) error {
+	req, err := askRequest(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+	lock := g.s.sessionLock(req.UserID, req.SessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(askContext(stream.Context(), req), g.s.turnTimeout)
+	defer cancel()
+
+	console.Printf("  🌐 [gRPC] %s/%s (stream): %s\n", req.UserID, req.SessionID, logQuery(ctx, req.Query))
+	if err := g.s.streamAsk(ctx, req, &grpcStream{stream: stream}); err != nil {
+		return turnError(err)
+	}
+	return nil
+}
+
+func (g *grpcService) GetSession(ctx context.Context, in *agentpb.GetSessionRequest) (*agentpb.Session, error) {
+	userID, err := actingUser(ctx, in.UserId)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if in.SessionId == "" {
+		in.SessionId = defaultSessionID
+	}
+
+	info, messages, err := g.s.app.GetSession(ctx, userID, in.SessionId)
+	if errors.Is(err, app.ErrSessionNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	session := protoSession(*info)
+	for _, m := range messages {
+		session.Messages = append(session.Messages, &agentpb.Message{Author: m.Author, Text: m.Text, Time: timestamppb.New(m.Time)})
+	}
+	return session, nil
+}
+
+func (g *grpcService) ListSessions(ctx context.Context, in *agentpb.ListSessionsRequest) (*agentpb.ListSessionsResponse, error) {
+	userID, err := actingUser(ctx, in.UserId)
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	sessions, err := g.s.app.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &agentpb.ListSessionsResponse{}
+	for _, info := range sessions {
+		resp.Sessions = append(resp.Sessions, protoSession(info))
+	}
+	return resp, nil
+}
+
+// turnError maps a failed turn to a gRPC status.
+func turnError(err error) error {
+	switch {
+	case errors.Is(err, app.ErrDraining):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// grpcStream sends the events of streamAsk as QueryEvents.
+type grpcStream struct {
+	mu     sync.Mutex
+	stream grpc.ServerStreamingServer[agentpb.QueryEvent]
+}
+
+func (g *grpcStream) send(event string, data interface{}) {
+	var e agentpb.QueryEvent
+	switch d := data.(type) {
+	case *manager.Result:
+		e.Event = &agentpb.QueryEvent_Routing{Routing: protoRouting(d)}
+	case ToolCallEvent:
+		e.Event = &agentpb.QueryEvent_ToolCall{ToolCall: &agentpb.ToolCall{Name: d.Name}}
+	case ProgressEvent:
+		e.Event = &agentpb.QueryEvent_Progress{Progress: &agentpb.Progress{Message: d.Message}}
+	case RowsEvent:
+		e.Event = &agentpb.QueryEvent_Rows{Rows: &agentpb.Rows{Sql: d.SQL, Columns: d.Columns, Total: int64(d.Total)}}
+	case ChartEvent:
+		e.Event = &agentpb.QueryEvent_Chart{Chart: &agentpb.Chart{Sql: d.SQL, Mermaid: d.Mermaid, Rows: int64(d.Rows), Partial: d.Partial}}
+	case TextEvent:
+		e.Event = &agentpb.QueryEvent_Text{Text: &agentpb.Text{Author: d.Author, Text: d.Text}}
+	case *app.Turn:
+		e.Event = &agentpb.QueryEvent_Turn{Turn: protoTurn(d)}
+	default:
+		return
+	}
+	// Tools report rows from their own goroutines
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stream.Send(&e)
+}
+
+func protoTurn(t *app.Turn) *agentpb.Turn {
+	out := &agentpb.Turn{
+		Routing:   protoRouting(t.Routing),
+		Text:      t.Text,
+		ToolCalls: t.ToolCalls,
+		Charts:    t.Charts,
+		DryRun:    t.DryRun,
+		Tokens:    &agentpb.TokenUsage{Prompt: int32(t.Tokens.Prompt), Completion: int32(t.Tokens.Completion)},
+		Cached:    t.Cached != nil,
+		Stopped:   t.Stopped,
+	}
+	for _, q := range t.Queries {
+		out.Queries = append(out.Queries, &agentpb.QueryResult{Sql: q.SQL, Data: q.Data, Error: q.Error})
+	}
+	for _, a := range t.Approvals {
+		out.Approvals = append(out.Approvals, &agentpb.Approval{CallId: a.CallID, Sql: a.SQL, Reason: a.Reason})
+	}
+	for _, f := range t.Filters {
+		out.Filters = append(out.Filters, &agentpb.Filter{Column: f.Column, Op: f.Op, Value: f.Value})
+	}
+	return out
+}
+
+func protoRouting(r *manager.Result) *agentpb.Routing {
+	if r == nil {
+		return nil
+	}
+	return &agentpb.Routing{
+		Query:            r.Query,
+		ClassifiedIntent: r.ClassifiedIntent,
+		Confidence:       r.Confidence,
+		AgentsUsed:       r.AgentsUsed,
+		Workflow:         r.Workflow,
+	}
+}
+
+func protoSession(info app.SessionInfo) *agentpb.Session {
+	return &agentpb.Session{Id: info.ID, UserId: info.UserID, Events: int32(info.Events), UpdatedAt: timestamppb.New(info.UpdatedAt)}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/auth"
+	"github.com/anuvratrastogi/multi-agent/internal/testutil"
+	"github.com/anuvratrastogi/multi-agent/pkg/agentpb"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPC(t *testing.T) {
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 4 orders."}},
+		mockllm.Rule{User: "orders", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) AS orders FROM orders"}}}}},
+	)
+	a, err := auth.New(auth.Config{Keys: []auth.Key{{Name: "alice", Key: "alice-key", User: "alice"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(Config{App: testutil.NewApp(t, llm, ordersDB{}), Auth: a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := s.GRPCServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := agentpb.NewAgentServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer alice-key")
+
+	queryTests := []struct {
+		name     string
+		ctx      context.Context
+		req      *agentpb.QueryRequest
+		wantCode codes.Code
+	}{
+		{name: "no credentials", ctx: context.Background(), req: &agentpb.QueryRequest{Query: "How many orders?"}, wantCode: codes.Unauthenticated},
+		{name: "other user", ctx: ctx, req: &agentpb.QueryRequest{UserId: "bob", Query: "How many orders?"}, wantCode: codes.PermissionDenied},
+		{name: "no query", ctx: ctx, req: &agentpb.QueryRequest{}, wantCode: codes.InvalidArgument},
+		{name: "query", ctx: ctx, req: &agentpb.QueryRequest{SessionId: "s1", Query: "How many orders?"}, wantCode: codes.OK},
+	}
+	for _, tt := range queryTests {
+		t.Run(tt.name, func(t *testing.T) {
+			turn, err := c.Query(tt.ctx, tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("code = %v, want %v: %v", status.Code(err), tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			if !strings.Contains(turn.Text, "4 orders") || len(turn.Queries) != 1 || turn.Queries[0].Data != `[{"orders":4}]` {
+				t.Errorf("turn = %v", turn)
+			}
+			if turn.Routing.GetClassifiedIntent() == "" {
+				t.Errorf("routing = %v", turn.Routing)
+			}
+		})
+	}
+
+	t.Run("stream", func(t *testing.T) {
+		stream, err := c.StreamQuery(ctx, &agentpb.QueryRequest{SessionId: "s2", Query: "How many orders?"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var events []*agentpb.QueryEvent
+		for {
+			e, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, e)
+		}
+		if len(events) < 2 || events[0].GetRouting() == nil {
+			t.Fatalf("events = %v", events)
+		}
+		if turn := events[len(events)-1].GetTurn(); turn == nil || !strings.Contains(turn.Text, "4 orders") {
+			t.Errorf("last event = %v", events[len(events)-1])
+		}
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		list, err := c.ListSessions(ctx, &agentpb.ListSessionsRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Sessions) != 2 || list.Sessions[0].UserId != "alice" {
+			t.Errorf("sessions = %v", list.Sessions)
+		}
+
+		session, err := c.GetSession(ctx, &agentpb.GetSessionRequest{SessionId: "s1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(session.Messages) < 2 || session.Messages[0].Author != "user" || session.Messages[0].Text != "How many orders?" {
+			t.Errorf("messages = %v", session.Messages)
+		}
+
+		_, err = c.GetSession(ctx, &agentpb.GetSessionRequest{SessionId: "missing"})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("missing session: %v", err)
+		}
+	})
+}
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/auth"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/usage"
+)
+
+// APIVersion is the version of the /v1 API surface described by the OpenAPI
+// document. The minor version grows with each backwards-compatible addition.
+const APIVersion = "1.8.0"
+
+// route describes an endpoint for both the mux and the OpenAPI document.
+type route struct {
+	method  string
+	path    string
+	summary string
+	// query lists the endpoint's optional query parameters
+	query []string
+	// request and response are zero values of the JSON body types
+	request  interface{}
+	response interface{}
+	// accepted is the body of a 202 response, and callback the body posted
+	// to the request's callback_url, for requests answered in the background
+	accepted interface{}
+	callback interface{}
+	// contentType overrides the JSON response, e.g. for the event stream
+	contentType string
+	handler     http.HandlerFunc
+}
+
+// routes lists every endpoint of the API. New registers them on the mux and
+// OpenAPI documents them, so the spec cannot drift from the server.
+func (s *Server) routes() []route {
+	return []route{
+		{method: "POST", path: "/v1/ask", summary: "Answer a question and return the complete turn, or answer it in the background and post it to callback_url",
+			request: AskRequest{}, response: app.Turn{}, accepted: AcceptedResponse{}, callback: Callback{}, handler: s.handleAsk},
+		{method: "POST", path: "/v1/ask/stream", summary: "Answer a question as a stream of Server-Sent Events",
+			request: AskRequest{}, contentType: "text/event-stream", handler: s.handleAskStream},
+		{method: "POST", path: "/v1/approvals", summary: "Approve or reject a write proposed in write mode",
+			request: ApprovalRequest{}, response: app.Turn{}, handler: s.handleApproval},
+		{method: "GET", path: "/v1/sessions", summary: "List the user's sessions, most recent first",
+			query: []string{"user_id"}, response: SessionsResponse{}, handler: s.handleListSessions},
+		{method: "GET", path: "/v1/sessions/{session_id}/artifacts/{name}", summary: "Download a session artifact (transcript.md or transcript.html)",
+			query: []string{"user_id"}, contentType: "text/plain", handler: s.handleArtifact},
+		{method: "GET", path: "/v1/sessions/{session_id}/trace", summary: "Get the models' reasoning for each turn (requires KEEP_REASONING)",
+			query: []string{"user_id"}, response: TraceResponse{}, handler: s.handleTrace},
+		{method: "GET", path: "/artifacts/{id}", summary: "Download a stored chart, export or report listed in a turn's artifacts",
+			query: []string{"user_id"}, contentType: "application/octet-stream", handler: s.handleStoredArtifact},
+		{method: "GET", path: "/v1/admin/usage", summary: "Report usage per user or team over the last days (default 7)",
+			query: []string{"days", "group_by"}, response: usage.Report{}, handler: s.handleUsage},
+		{method: "GET", path: "/v1/admin/metrics", summary: "Count turns, intents, agents, tools, queries and charts since startup",
+			response: events.MetricsSnapshot{}, handler: s.handleMetrics},
+		{method: "GET", path: "/v1/openapi.json", summary: "This document",
+			contentType: "application/json", handler: s.handleOpenAPI},
+		{method: "GET", path: "/v1/health", summary: "Health check",
+			response: map[string]string{}, handler: s.handleHealth},
+	}
+}
+
+// streamEvents maps the event types of /v1/ask/stream to their data.
+var streamEvents = []struct {
+	name string
+	data interface{}
+}{
+	{EventRouting, manager.Result{}},
+	{EventToolCall, ToolCallEvent{}},
+	{EventProgress, ProgressEvent{}},
+	{EventRows, RowsEvent{}},
+	{EventChart, ChartEvent{}},
+	{EventText, TextEvent{}},
+	{EventTurn, app.Turn{}},
+	{EventError, ErrorResponse{}},
+}
+
+// schemaNames renames types whose Go names are ambiguous in the API.
+var schemaNames = map[reflect.Type]string{
+	reflect.TypeOf(manager.Result{}): "Routing",
+	reflect.TypeOf(usage.Report{}):   "UsageReport",
+	reflect.TypeOf(usage.Summary{}):  "UsageSummary",
+	reflect.TypeOf(usage.Day{}):      "UsageDay",
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// OpenAPI returns the OpenAPI 3 document for the API.
+func OpenAPI() map[string]interface{} {
+	return (&Server{}).openAPI()
+}
+
+func (s *Server) openAPI() map[string]interface{} {
+	g := &schemaGen{schemas: map[string]interface{}{}}
+	errorRef := g.schema(reflect.TypeOf(ErrorResponse{}))
+
+	paths := map[string]map[string]interface{}{}
+	for _, rt := range s.routes() {
+		op := map[string]interface{}{
+			"summary":     rt.summary,
+			"operationId": operationID(rt.method, rt.path),
+		}
+
+		var params []interface{}
+		for _, m := range pathParam.FindAllStringSubmatch(rt.path, -1) {
+			params = append(params, map[string]interface{}{
+				"name": m[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, q := range rt.query {
+			params = append(params, map[string]interface{}{
+				"name": q, "in": "query", "schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+
+		if rt.request != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(g.schema(reflect.TypeOf(rt.request))),
+			}
+		}
+
+		ok := map[string]interface{}{"description": "OK"}
+		switch {
+		case rt.contentType == "text/event-stream":
+			ok["description"] = "Server-Sent Events. Each event's data is the JSON schema listed for its type in x-events; the stream ends after a turn or error event."
+			events := map[string]interface{}{}
+			for _, e := range streamEvents {
+				events[e.name] = g.schema(reflect.TypeOf(e.data))
+			}
+			ok["content"] = map[string]interface{}{"text/event-stream": map[string]interface{}{
+				"schema":   map[string]interface{}{"type": "string"},
+				"x-events": events,
+			}}
+		case rt.contentType != "":
+			ok["content"] = map[string]interface{}{rt.contentType: map[string]interface{}{
+				"schema": map[string]interface{}{"type": "string"},
+			}}
+		case rt.response != nil:
+			ok["content"] = jsonContent(g.schema(reflect.TypeOf(rt.response)))
+		}
+
+		responses := map[string]interface{}{
+			"200":     ok,
+			"default": map[string]interface{}{"description": "Error", "content": jsonContent(errorRef)},
+		}
+		if rt.accepted != nil {
+			responses["202"] = map[string]interface{}{
+				"description": "Accepted; the turn runs in the background",
+				"content":     jsonContent(g.schema(reflect.TypeOf(rt.accepted))),
+			}
+		}
+		op["responses"] = responses
+		if rt.callback != nil {
+			op["callbacks"] = map[string]interface{}{
+				"turnCompleted": map[string]interface{}{
+					"{$request.body#/callback_url}": map[string]interface{}{
+						"post": map[string]interface{}{
+							"summary": "Posted when the turn completes",
+							"requestBody": map[string]interface{}{
+								"required": true,
+								"content":  jsonContent(g.schema(reflect.TypeOf(rt.callback))),
+							},
+							"responses": map[string]interface{}{"2XX": map[string]interface{}{"description": "Received"}},
+						},
+					},
+				},
+			}
+		}
+
+		if rt.path == "/v1/health" || rt.path == "/v1/openapi.json" {
+			op["security"] = []interface{}{}
+		}
+
+		if paths[rt.path] == nil {
+			paths[rt.path] = map[string]interface{}{}
+		}
+		paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Multi-Agent API",
+			"version": APIVersion,
+			"description": "Ask questions about a PostgreSQL database in natural language. " +
+				"Within /v1, changes are additive only: new endpoints, optional fields, response fields and event types may be added, " +
+				"so clients must ignore unknown fields and events. Breaking changes are published under a new path prefix.",
+		},
+		"paths": paths,
+		// Credentials are only checked when the server is configured with
+		// API keys or an OIDC issuer, hence the empty requirement
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{},
+		},
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "description": "An API key or an OIDC access token"},
+				"apiKey":     map[string]interface{}{"type": "apiKey", "in": "header", "name": auth.KeyHeader},
+			},
+		},
+	}
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.openAPI())
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// operationID derives a stable camel-case ID, e.g. "postV1AskStream".
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '.' || r == '_' }) {
+		part = strings.Trim(part, "{}")
+		id += strings.ToUpper(part[:1]) + part[1:]
+	}
+	return id
+}
+
+// schemaGen builds JSON schemas from Go types, collecting named structs in
+// components/schemas.
+type schemaGen struct {
+	schemas map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *schemaGen) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		return g.structRef(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	}
+	return map[string]interface{}{}
+}
+
+// structRef adds a struct's schema to the components and returns a
+// reference to it.
+func (g *schemaGen) structRef(t reflect.Type) map[string]interface{} {
+	name, ok := schemaNames[t]
+	if !ok {
+		name = t.Name()
+	}
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	if _, done := g.schemas[name]; done {
+		return ref
+	}
+	g.schemas[name] = nil // placeholder for recursive types
+
+	properties := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		fieldName, opts, _ := strings.Cut(tag, ",")
+		if fieldName == "" {
+			fieldName = f.Name
+		}
+		properties[fieldName] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, fieldName)
+		}
+	}
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	g.schemas[name] = schema
+	return ref
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	doc := OpenAPI()
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("failed to marshal document: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "ask path", want: `"/v1/ask":{"post"`},
+		{name: "stream events", want: `"x-events":{`},
+		{name: "path parameter", want: `"in":"path","name":"session_id"`},
+		{name: "renamed routing schema", want: `"routing":{"$ref":"#/components/schemas/Routing"}`},
+		{name: "required query", want: `"required":["query"]`},
+		{name: "callbacks", want: `"callbacks":{"turnCompleted":{"{$request.body#/callback_url}"`},
+		{name: "security schemes", want: `"bearerAuth":{"description"`},
+		{name: "timestamps", want: `"updated_at":{"format":"date-time","type":"string"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("document missing %s", tt.want)
+			}
+		})
+	}
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, ref := range strings.Split(string(data), `"$ref":"#/components/schemas/`)[1:] {
+		name, _, _ := strings.Cut(ref, `"`)
+		if schemas[name] == nil {
+			t.Errorf("unresolved reference to %s", name)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+// Package server exposes the agents over HTTP, including a Server-Sent Events
+// stream of a turn's progress for dashboards and web front-ends.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+	"github.com/anuvratrastogi/multi-agent/internal/auth"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/report"
+	"github.com/anuvratrastogi/multi-agent/internal/usage"
+)
+
+const (
+	// defaultUserID and defaultSessionID are used when a request omits them.
+	defaultUserID    = "api"
+	defaultSessionID = "default"
+	// defaultTurnTimeout bounds how long a single request may take to answer.
+	defaultTurnTimeout = 10 * time.Minute
+	// defaultShutdownTimeout bounds how long shutdown waits for requests in
+	// flight.
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// Config holds configuration for the HTTP server.
+type Config struct {
+	// App executes the user's questions
+	App *app.App
+	// TLSCertFile and TLSKeyFile serve the API over HTTPS (optional)
+	TLSCertFile string
+	TLSKeyFile  string
+	// ClientCAFile requires clients to present a certificate signed by this CA (optional)
+	ClientCAFile string
+	// EncryptionKey decrypts requests and seals responses sent with the
+	// e2e.Header (optional)
+	EncryptionKey []byte
+	// RequireEncryption rejects requests that are not encrypted
+	RequireEncryption bool
+	// Auth requires API keys or OIDC tokens on every endpoint but health and
+	// the OpenAPI document (optional)
+	Auth *auth.Authenticator
+	// TurnTimeout bounds how long a request may take to answer (defaults to 10m)
+	TurnTimeout time.Duration
+	// ShutdownTimeout bounds how long shutdown waits for requests in flight
+	// and answers being posted to callback URLs (defaults to 30s)
+	ShutdownTimeout time.Duration
+	// Usage serves usage reports on /v1/admin/usage (optional)
+	Usage *usage.Tracker
+	// Metrics serves event counters on /v1/admin/metrics (optional)
+	Metrics *events.Metrics
+	// Artifacts serves the stored charts, exports and reports on
+	// /artifacts/{id} (optional)
+	Artifacts artifacts.Store
+	// CallbackHosts limits callback URLs to these hosts ("host",
+	// "host:port" or "*.domain") and URL prefixes (optional; any host)
+	CallbackHosts []string
+	// CallbackAllowPrivate lets callbacks reach loopback, private and
+	// link-local addresses, which are refused by default
+	CallbackAllowPrivate bool
+}
+
+// Server serves the HTTP API.
+type Server struct {
+	app         *app.App
+	mux         *http.ServeMux
+	turnTimeout time.Duration
+	usage       *usage.Tracker
+	metrics     *events.Metrics
+	auth        *auth.Authenticator
+	artifacts   artifacts.Store
+	// shutdownTimeout bounds the wait for requests in flight on shutdown
+	shutdownTimeout time.Duration
+
+	tlsCertFile       string
+	tlsKeyFile        string
+	clientCAFile      string
+	encryptionKey     []byte
+	requireEncryption bool
+
+	// callbacks tracks the turns answered in the background
+	callbacks      sync.WaitGroup
+	callbackClient *http.Client
+	callbackHosts  []string
+	// abortCallbacks is cancelled when shutdown stops waiting for them
+	abortCallbacks  context.Context
+	cancelCallbacks context.CancelFunc
+
+	mu          sync.Mutex
+	sessions    map[string]*sync.Mutex
+	transcripts map[string]*report.Transcript
+	// sealed marks sessions whose questions were sent encrypted
+	sealed map[string]bool
+}
+
+// New creates a new HTTP server.
+func New(cfg Config) (*Server, error) {
+	if cfg.App == nil {
+		return nil, fmt.Errorf("server requires an app")
+	}
+
+	s := &Server{
+		app:         cfg.App,
+		mux:         http.NewServeMux(),
+		sessions:    make(map[string]*sync.Mutex),
+		transcripts: make(map[string]*report.Transcript),
+		sealed:      make(map[string]bool),
+		turnTimeout: cfg.TurnTimeout,
+		usage:       cfg.Usage,
+		metrics:     cfg.Metrics,
+		auth:        cfg.Auth,
+		artifacts:   cfg.Artifacts,
+
+		shutdownTimeout: cfg.ShutdownTimeout,
+
+		callbackClient: newCallbackClient(cfg.CallbackAllowPrivate),
+		callbackHosts:  cfg.CallbackHosts,
+
+		tlsCertFile:       cfg.TLSCertFile,
+		tlsKeyFile:        cfg.TLSKeyFile,
+		clientCAFile:      cfg.ClientCAFile,
+		encryptionKey:     cfg.EncryptionKey,
+		requireEncryption: cfg.RequireEncryption,
+	}
+	s.abortCallbacks, s.cancelCallbacks = context.WithCancel(context.Background())
+	if s.requireEncryption && s.encryptionKey == nil {
+		return nil, fmt.Errorf("server requires an encryption key to require encryption")
+	}
+	if s.turnTimeout <= 0 {
+		s.turnTimeout = defaultTurnTimeout
+	}
+	if s.shutdownTimeout <= 0 {
+		s.shutdownTimeout = defaultShutdownTimeout
+	}
+	for _, rt := range s.routes() {
+		handler := s.encrypted(rt)
+		if s.auth != nil {
+			handler = s.authenticated(rt, handler)
+		}
+		s.mux.HandleFunc(rt.method+" "+rt.path, handler)
+	}
+	return s, nil
+}
+
+// AskRequest is the body of /v1/ask and /v1/ask/stream.
+type AskRequest struct {
+	UserID    string `json:"user_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Query     string `json:"query"`
+	// DryRun generates SQL without executing it
+	DryRun bool `json:"dry_run,omitempty"`
+	// Fresh answers the question with the agents even if a similar
+	// question's answer is cached
+	Fresh bool `json:"fresh,omitempty"`
+	// CallbackURL answers the question in the background: /v1/ask returns
+	// an AcceptedResponse at once and the Callback is posted here when the
+	// turn completes
+	CallbackURL string `json:"callback_url,omitempty"`
+	// PromptVariant pins the session to a prompt variant for this and
+	// later questions; "auto" unpins it
+	PromptVariant string `json:"prompt_variant,omitempty"`
+	// ChartStyle changes how this question's charts look (optional)
+	ChartStyle *chart.Style `json:"chart_style,omitempty"`
+}
+
+// ApprovalRequest is the body of /v1/approvals. Only the approval's call_id
+// is read: the statement executed is the one the agent proposed under it.
+type ApprovalRequest struct {
+	UserID    string       `json:"user_id,omitempty"`
+	SessionID string       `json:"session_id,omitempty"`
+	Approval  app.Approval `json:"approval"`
+	Approved  bool         `json:"approved"`
+}
+
+// ErrorResponse is returned for failed requests.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled, over TLS if
+// a certificate is configured.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s}
+	if s.tlsCertFile != "" {
+		tlsCfg, err := tlsConfig(s.clientCAFile)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsCfg
+	}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("⚠️  [API] Stopped waiting for requests in flight: %v", err)
+		}
+		if !wait(shutdownCtx, &s.callbacks) {
+			log.Printf("⚠️  [API] Stopped waiting for answers to callback URLs")
+			s.cancelCallbacks()
+		}
+	}()
+
+	var err error
+	if s.tlsCertFile != "" {
+		err = srv.ListenAndServeTLS(s.tlsCertFile, s.tlsKeyFile)
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("api server error: %w", err)
+	}
+	<-stopped
+	return nil
+}
+
+// wait waits for wg until ctx is done, reporting whether wg finished.
+func wait(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sessionLock serializes turns within a session so its history stays ordered.
+func (s *Server) sessionLock(userID, sessionID string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := userID + "/" + sessionID
+	m, ok := s.sessions[key]
+	if !ok {
+		m = &sync.Mutex{}
+		s.sessions[key] = m
+	}
+	return m
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	// Load balancers stop routing to a server that is shutting down
+	if s.app.Draining() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeAsk(w, r)
+	if !ok {
+		return
+	}
+	if req.CallbackURL != "" {
+		s.askAsync(w, r, req)
+		return
+	}
+
+	lock := s.sessionLock(req.UserID, req.SessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(askContext(r.Context(), req), s.turnTimeout)
+	defer cancel()
+
+	console.Printf("  🌐 [API] %s/%s: %s\n", req.UserID, req.SessionID, logQuery(ctx, req.Query))
+	turn, err := s.app.Ask(ctx, req.UserID, req.SessionID, req.Query, nil)
+	s.record(ctx, req.UserID, req.SessionID, req.Query, turn, err)
+	if err != nil {
+		writeJSON(w, turnStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, turn)
+}
+
+func (s *Server) handleApproval(w http.ResponseWriter, r *http.Request) {
+	var req ApprovalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return
+	}
+	if req.Approval.CallID == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "approval.call_id is required"})
+		return
+	}
+	if req.Approved && sqlagent.IsReadOnly(r.Context()) {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{Error: "these credentials are read-only and may only reject writes"})
+		return
+	}
+	if !requestIDs(w, r, &req.UserID, &req.SessionID) {
+		return
+	}
+
+	lock := s.sessionLock(req.UserID, req.SessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.turnTimeout)
+	defer cancel()
+
+	approval, err := s.app.PendingApproval(ctx, req.UserID, req.SessionID, req.Approval.CallID)
+	if err != nil {
+		writeJSON(w, turnStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	turn, err := s.app.Resolve(ctx, req.UserID, req.SessionID, approval.CallID, req.Approved, nil)
+	decision := "Rejected"
+	if req.Approved {
+		decision = "Approved"
+	}
+	s.record(ctx, req.UserID, req.SessionID, decision+": "+approval.SQL, turn, err)
+	if err != nil {
+		writeJSON(w, turnStatus(err), ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, turn)
+}
+
+// turnStatus is the HTTP status of a failed turn.
+func turnStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrDraining):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, app.ErrSessionNotFound), errors.Is(err, app.ErrApprovalNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrApprovalAnswered):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// decodeAsk reads an AskRequest, writing an error response if it is
+// invalid, and pins the session to the request's prompt variant.
+func (s *Server) decodeAsk(w http.ResponseWriter, r *http.Request) (AskRequest, bool) {
+	var req AskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+		return req, false
+	}
+	if req.Query == "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "query is required"})
+		return req, false
+	}
+	if !requestIDs(w, r, &req.UserID, &req.SessionID) {
+		return req, false
+	}
+	if req.ChartStyle != nil {
+		if err := req.ChartStyle.Validate(); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return req, false
+		}
+	}
+	if req.PromptVariant != "" {
+		variant := req.PromptVariant
+		if variant == "auto" {
+			variant = ""
+		}
+		if err := s.app.SetPromptVariant(req.UserID, req.SessionID, variant); err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// askContext applies the request's options to ctx.
+func askContext(ctx context.Context, req AskRequest) context.Context {
+	if req.DryRun {
+		ctx = sqlagent.WithDryRun(ctx)
+	}
+	if req.Fresh {
+		ctx = app.WithFresh(ctx)
+	}
+	if req.ChartStyle != nil {
+		ctx = chart.WithStyle(ctx, *req.ChartStyle)
+	}
+	return ctx
+}
+
+// logQuery returns the question for the console, unless it was sent
+// encrypted.
+func logQuery(ctx context.Context, query string) string {
+	if sealKey(ctx) != nil {
+		return "(encrypted)"
+	}
+	return query
+}
+
+// requestIDs fills in the user and session of a request body, writing an
+// error response if the client may not act as the user.
+func requestIDs(w http.ResponseWriter, r *http.Request, userID, sessionID *string) bool {
+	user, ok := requestUser(w, r, *userID)
+	if !ok {
+		return false
+	}
+	*userID = user
+	if *sessionID == "" {
+		*sessionID = defaultSessionID
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("⚠️  [API] Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/report"
+)
+
+// Artifacts served for every session by /v1/sessions/{session_id}/artifacts.
+const (
+	ArtifactTranscriptMarkdown = "transcript.md"
+	ArtifactTranscriptHTML     = "transcript.html"
+)
+
+// SessionsResponse is returned by /v1/sessions.
+type SessionsResponse struct {
+	Sessions []app.SessionInfo `json:"sessions"`
+}
+
+// TraceResponse is returned by /v1/sessions/{session_id}/trace.
+type TraceResponse struct {
+	SessionID string       `json:"session_id"`
+	Turns     []TraceEntry `json:"turns"`
+}
+
+// TraceEntry is the reasoning the models emitted while answering a question.
+type TraceEntry struct {
+	Time      time.Time     `json:"time"`
+	Question  string        `json:"question"`
+	Reasoning []app.Thought `json:"reasoning"`
+}
+
+// record adds an exchange to the session's transcript. Transcripts of
+// encrypted sessions are only served to encrypted requests.
+func (s *Server) record(ctx context.Context, userID, sessionID, question string, turn *app.Turn, err error) {
+	entry := report.Entry{Time: time.Now(), Question: question, Turn: turn}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := userID + "/" + sessionID
+	t, ok := s.transcripts[key]
+	if !ok {
+		t = &report.Transcript{Title: "Analysis transcript (" + sessionID + ")", Created: entry.Time}
+		s.transcripts[key] = t
+	}
+	t.Entries = append(t.Entries, entry)
+	if sealKey(ctx) != nil {
+		s.sealed[key] = true
+	}
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUser(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+
+	sessions, err := s.app.ListSessions(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, SessionsResponse{Sessions: sessions})
+}
+
+// handleTrace returns the reasoning kept for each of the session's turns.
+func (s *Server) handleTrace(w http.ResponseWriter, r *http.Request) {
+	if !s.app.KeepsReasoning() {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "reasoning is not kept; set KEEP_REASONING=true to enable traces"})
+		return
+	}
+	userID, ok := requestUser(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	sessionID := r.PathValue("session_id")
+	key := userID + "/" + sessionID
+
+	resp := TraceResponse{SessionID: sessionID, Turns: []TraceEntry{}}
+	s.mu.Lock()
+	sealed := s.sealed[key]
+	stored, ok := s.transcripts[key]
+	if ok {
+		for _, e := range stored.Entries {
+			entry := TraceEntry{Time: e.Time, Question: e.Question, Reasoning: []app.Thought{}}
+			if e.Turn != nil {
+				entry.Reasoning = append(entry.Reasoning, e.Turn.Reasoning...)
+			}
+			resp.Turns = append(resp.Turns, entry)
+		}
+	}
+	s.mu.Unlock()
+	if sealed && sealKey(r.Context()) == nil {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{Error: "this session's trace is only available to encrypted requests"})
+		return
+	}
+	if !ok {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "session has no turns"})
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleArtifact renders a session document, such as its transcript.
+func (s *Server) handleArtifact(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requestUser(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	key := userID + "/" + r.PathValue("session_id")
+
+	s.mu.Lock()
+	sealed := s.sealed[key]
+	var t report.Transcript
+	if stored, ok := s.transcripts[key]; ok {
+		t = *stored
+		t.Entries = append([]report.Entry(nil), stored.Entries...)
+	}
+	s.mu.Unlock()
+	if sealed && sealKey(r.Context()) == nil {
+		writeJSON(w, http.StatusForbidden, ErrorResponse{Error: "this session's transcript is only available to encrypted requests"})
+		return
+	}
+	if len(t.Entries) == 0 {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "session has no transcript"})
+		return
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch name := r.PathValue("name"); name {
+	case ArtifactTranscriptMarkdown:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		err = report.WriteTranscriptMarkdown(&buf, t)
+	case ArtifactTranscriptHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err = report.WriteTranscriptHTML(&buf, t)
+	default:
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "unknown artifact " + name})
+		return
+	}
+	if err != nil {
+		w.Header().Del("Content-Type")
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	w.Write(buf.Bytes())
+}
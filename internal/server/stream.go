@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/limits"
+	"github.com/anuvratrastogi/multi-agent/pkg/e2e"
+	"google.golang.org/adk/session"
+)
+
+// Event types sent on /v1/ask/stream.
+const (
+	// EventRouting carries the manager.Result for the question.
+	EventRouting = "routing"
+	// EventToolCall carries a ToolCallEvent when an agent invokes a tool.
+	EventToolCall = "tool_call"
+	// EventProgress carries a ProgressEvent, e.g. while waiting for a
+	// database slot.
+	EventProgress = "progress"
+	// EventRows carries a RowsEvent as a query's rows are read.
+	EventRows = "rows"
+	// EventChart carries a ChartEvent: provisional charts while rows arrive,
+	// then the Chart agent's final chart.
+	EventChart = "chart"
+	// EventText carries a TextEvent for each piece of agent narrative.
+	EventText = "text"
+	// EventTurn carries the complete app.Turn and ends the stream.
+	EventTurn = "turn"
+	// EventError carries an ErrorResponse and ends the stream.
+	EventError = "error"
+)
+
+// ToolCallEvent reports a tool invocation.
+type ToolCallEvent struct {
+	Name string `json:"name"`
+}
+
+// ProgressEvent reports a status change that is not yet a result.
+type ProgressEvent struct {
+	Message string `json:"message"`
+}
+
+// RowsEvent reports the progress of a running query.
+type RowsEvent struct {
+	SQL     string   `json:"sql"`
+	Columns []string `json:"columns"`
+	// Total is the number of rows read so far.
+	Total int `json:"total"`
+}
+
+// ChartEvent carries a Mermaid chart definition. Partial charts are drawn
+// from the rows read so far and are superseded by later chart events.
+type ChartEvent struct {
+	SQL     string `json:"sql,omitempty"`
+	Mermaid string `json:"mermaid"`
+	Rows    int    `json:"rows,omitempty"`
+	Partial bool   `json:"partial"`
+}
+
+// TextEvent carries agent narrative.
+type TextEvent struct {
+	Author string `json:"author,omitempty"`
+	Text   string `json:"text"`
+}
+
+// eventStream writes Server-Sent Events. Tools may report rows from other
+// goroutines, so writes are serialized.
+type eventStream struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	// key seals each event's data for encrypted requests
+	key []byte
+}
+
+func (s *eventStream) send(event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		payload, _ = json.Marshal(ErrorResponse{Error: err.Error()})
+		event = EventError
+	}
+	if s.key != nil {
+		if payload, err = e2e.Seal(s.key, payload); err != nil {
+			payload, event = []byte(`{"error":"failed to encrypt event"}`), EventError
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	s.flusher.Flush()
+}
+
+// handleAskStream answers a question as a stream of Server-Sent Events.
+func (s *Server) handleAskStream(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.decodeAsk(w, r)
+	if !ok {
+		return
+	}
+	if req.CallbackURL != "" {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "callback_url is only supported on /v1/ask"})
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "streaming is not supported"})
+		return
+	}
+
+	lock := s.sessionLock(req.UserID, req.SessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	key := sealKey(r.Context())
+	if key != nil {
+		w.Header().Set(e2e.Header, e2e.Algorithm)
+	}
+	w.WriteHeader(http.StatusOK)
+	stream := &eventStream{w: w, flusher: flusher, key: key}
+
+	ctx, cancel := context.WithTimeout(askContext(r.Context(), req), s.turnTimeout)
+	defer cancel()
+
+	console.Printf("  🌐 [API] %s/%s (stream): %s\n", req.UserID, req.SessionID, logQuery(ctx, req.Query))
+	if err := s.streamAsk(ctx, req, stream); err != nil {
+		stream.send(EventError, ErrorResponse{Error: err.Error()})
+	}
+}
+
+// eventSender delivers the events of a streamed turn, as Server-Sent Events
+// or gRPC messages.
+type eventSender interface {
+	send(event string, data interface{})
+}
+
+// streamAsk answers a question, sending its events to stream and ending
+// with the turn. When the question is routed to the Chart agent,
+// provisional charts are sent as each batch of rows arrives so dashboards
+// can draw partial results early.
+func (s *Server) streamAsk(ctx context.Context, req AskRequest, stream eventSender) error {
+	routing, err := s.app.Route(ctx, req.Query)
+	if err != nil {
+		return err
+	}
+	stream.send(EventRouting, routing)
+	// With guardrails, only the checked turn may show text and data
+	guarded := s.app.Guarded()
+	ctx = sqlagent.WithRowHandler(ctx, rowStreamer(stream, wantsChart(routing) && !guarded))
+	ctx = limits.WithWaitHandler(ctx, func(scope string) {
+		stream.send(EventProgress, ProgressEvent{Message: fmt.Sprintf("Waiting for a database slot (%s limit reached)", scope)})
+	})
+
+	turn, err := s.app.Ask(ctx, req.UserID, req.SessionID, req.Query, func(event *session.Event) {
+		if event.LLMResponse.Content == nil {
+			return
+		}
+		for _, part := range event.LLMResponse.Content.Parts {
+			if part.FunctionCall != nil {
+				stream.send(EventToolCall, ToolCallEvent{Name: part.FunctionCall.Name})
+			}
+			if part.Text != "" && !part.Thought && !guarded {
+				stream.send(EventText, TextEvent{Author: event.Author, Text: part.Text})
+			}
+		}
+	})
+	s.record(ctx, req.UserID, req.SessionID, req.Query, turn, err)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range turn.Charts {
+		stream.send(EventChart, ChartEvent{Mermaid: c})
+	}
+	stream.send(EventTurn, turn)
+	return nil
+}
+
+// wantsChart reports whether the Chart agent will visualize the query results.
+func wantsChart(routing *manager.Result) bool {
+	return slices.Contains(routing.AgentsUsed, "ChartAgent") && slices.Contains(routing.AgentsUsed, "SQLAgent")
+}
+
+// rowStreamer reports query progress and, if charts is set, redraws a
+// provisional chart from the rows accumulated so far.
+func rowStreamer(stream eventSender, charts bool) sqlagent.RowHandler {
+	var mu sync.Mutex
+	var sql string
+	var rows []map[string]interface{}
+
+	return func(batch sqlagent.RowBatch) {
+		stream.send(EventRows, RowsEvent{SQL: batch.SQL, Columns: batch.Columns, Total: batch.Total})
+		if !charts {
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if batch.SQL != sql {
+			sql, rows = batch.SQL, nil
+		}
+		rows = append(rows, batch.Rows...)
+
+		if mermaid, ok := chart.Preview("Preview", batch.Columns, rows); ok {
+			stream.send(EventChart, ChartEvent{SQL: sql, Mermaid: mermaid, Rows: batch.Total, Partial: true})
+		}
+	}
+}
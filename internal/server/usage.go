@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// defaultUsageDays is the period of /v1/admin/usage when days is omitted.
+const defaultUsageDays = 7
+
+// handleUsage reports usage per user or team over the last days.
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	if s.usage == nil {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "usage tracking is not enabled"})
+		return
+	}
+
+	days := defaultUsageDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "days must be a number"})
+			return
+		}
+		days = n
+	}
+
+	report, err := s.usage.Report(days, r.URL.Query().Get("group_by"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleMetrics reports the event counters since startup.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "metrics are not enabled"})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.metrics.Snapshot())
+}
@@ -0,0 +1,194 @@
+// Package sessions manages the named users and conversation sessions of an
+// interactive front-end: which user is active, which of their sessions the
+// next question goes to, and creating and switching between them.
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+// Defaults for the first user and session.
+const (
+	DefaultUser    = "user-1"
+	DefaultSession = "session-1"
+)
+
+// Store creates and lists sessions; *app.App implements it.
+type Store interface {
+	EnsureSession(ctx context.Context, userID, sessionID string) error
+	ListSessions(ctx context.Context, userID string) ([]app.SessionInfo, error)
+}
+
+// Config holds configuration for a Manager.
+type Config struct {
+	Store Store
+	// User is the active user at start (defaults to DefaultUser)
+	User string
+	// Session is the active session at start (defaults to DefaultSession)
+	Session string
+}
+
+// Manager tracks the active user and each user's active session. It is
+// safe for concurrent use.
+type Manager struct {
+	store Store
+
+	mu   sync.Mutex
+	user string
+	// active holds the active session of every user seen so far
+	active map[string]string
+}
+
+// validName restricts user and session names to characters safe in
+// session keys, file names and URLs.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._@-]{0,63}$`)
+
+// New creates a Manager. Call Start before using the session.
+func New(cfg Config) (*Manager, error) {
+	if cfg.Store == nil {
+		return nil, fmt.Errorf("session manager requires a store")
+	}
+	if cfg.User == "" {
+		cfg.User = DefaultUser
+	}
+	if cfg.Session == "" {
+		cfg.Session = DefaultSession
+	}
+	if err := checkName("user", cfg.User); err != nil {
+		return nil, err
+	}
+	if err := checkName("session", cfg.Session); err != nil {
+		return nil, err
+	}
+	return &Manager{
+		store:  cfg.Store,
+		user:   cfg.User,
+		active: map[string]string{cfg.User: cfg.Session},
+	}, nil
+}
+
+// Start creates the initial session if it does not exist yet.
+func (m *Manager) Start(ctx context.Context) error {
+	user, session := m.Current()
+	return m.store.EnsureSession(ctx, user, session)
+}
+
+// Current returns the active user and session.
+func (m *Manager) Current() (user, session string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.user, m.active[m.user]
+}
+
+// List returns the active user's sessions, most recently updated first.
+func (m *Manager) List(ctx context.Context) ([]app.SessionInfo, error) {
+	user, _ := m.Current()
+	return m.store.ListSessions(ctx, user)
+}
+
+// Create starts a new session for the active user and makes it active. An
+// empty name picks the next free session-N.
+func (m *Manager) Create(ctx context.Context, name string) (string, error) {
+	user, _ := m.Current()
+	existing, err := m.names(ctx, user)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		for n := len(existing) + 1; ; n++ {
+			name = fmt.Sprintf("session-%d", n)
+			if !slices.Contains(existing, name) {
+				break
+			}
+		}
+	}
+	if err := checkName("session", name); err != nil {
+		return "", err
+	}
+	if slices.Contains(existing, name) {
+		return "", fmt.Errorf("session %q already exists", name)
+	}
+
+	if err := m.store.EnsureSession(ctx, user, name); err != nil {
+		return "", err
+	}
+	m.activate(user, name)
+	return name, nil
+}
+
+// Switch makes an existing session of the active user active.
+func (m *Manager) Switch(ctx context.Context, name string) error {
+	user, _ := m.Current()
+	existing, err := m.names(ctx, user)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(existing, name) {
+		return fmt.Errorf("session %q not found", name)
+	}
+	m.activate(user, name)
+	return nil
+}
+
+// SwitchUser makes user active, in the session they used last or, for a
+// new user, their most recent session or a new one.
+func (m *Manager) SwitchUser(ctx context.Context, user string) (string, error) {
+	if err := checkName("user", user); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	session, seen := m.active[user]
+	m.mu.Unlock()
+
+	if !seen {
+		sessions, err := m.store.ListSessions(ctx, user)
+		if err != nil {
+			return "", err
+		}
+		session = DefaultSession
+		if len(sessions) > 0 {
+			session = sessions[0].ID
+		}
+	}
+	if err := m.store.EnsureSession(ctx, user, session); err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.user = user
+	m.active[user] = session
+	return session, nil
+}
+
+func (m *Manager) activate(user, session string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active[user] = session
+}
+
+// names returns the IDs of user's sessions.
+func (m *Manager) names(ctx context.Context, user string) ([]string, error) {
+	sessions, err := m.store.ListSessions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(sessions))
+	for i, s := range sessions {
+		names[i] = s.ID
+	}
+	return names, nil
+}
+
+func checkName(kind, name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid %s name %q: use up to 64 letters, digits, '.', '_', '@' or '-'", kind, name)
+	}
+	return nil
+}
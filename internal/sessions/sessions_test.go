@@ -0,0 +1,91 @@
+package sessions
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+// memStore keeps session IDs per user, newest first.
+type memStore map[string][]string
+
+func (m memStore) EnsureSession(ctx context.Context, userID, sessionID string) error {
+	if !slices.Contains(m[userID], sessionID) {
+		m[userID] = append([]string{sessionID}, m[userID]...)
+	}
+	return nil
+}
+
+func (m memStore) ListSessions(ctx context.Context, userID string) ([]app.SessionInfo, error) {
+	var list []app.SessionInfo
+	for _, id := range m[userID] {
+		list = append(list, app.SessionInfo{ID: id, UserID: userID})
+	}
+	return list, nil
+}
+
+func TestManager(t *testing.T) {
+	tests := []struct {
+		name string
+		// steps are run in order: "new [name]", "switch <name>" or "user <name>"
+		steps       []string
+		wantUser    string
+		wantSession string
+		wantErr     string
+	}{
+		{name: "defaults", wantUser: DefaultUser, wantSession: DefaultSession},
+		{name: "next free name", steps: []string{"new", "new"}, wantUser: DefaultUser, wantSession: "session-3"},
+		{name: "named session", steps: []string{"new q3-review"}, wantUser: DefaultUser, wantSession: "q3-review"},
+		{name: "duplicate name", steps: []string{"new q3", "new q3"}, wantErr: "already exists"},
+		{name: "switch back", steps: []string{"new q3", "switch session-1"}, wantUser: DefaultUser, wantSession: "session-1"},
+		{name: "switch to unknown", steps: []string{"switch nope"}, wantErr: "not found"},
+		{name: "invalid name", steps: []string{"new ../etc"}, wantErr: "invalid session name"},
+		{name: "new user", steps: []string{"user bob"}, wantUser: "bob", wantSession: DefaultSession},
+		{name: "user keeps session", steps: []string{"new q3", "user bob", "user " + DefaultUser}, wantUser: DefaultUser, wantSession: "q3"},
+		{name: "users are separate", steps: []string{"new q3", "user bob", "switch q3"}, wantErr: "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m, err := New(Config{Store: memStore{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, step := range tt.steps {
+				op, arg, _ := strings.Cut(step, " ")
+				switch op {
+				case "new":
+					_, err = m.Create(ctx, arg)
+				case "switch":
+					err = m.Switch(ctx, arg)
+				case "user":
+					_, err = m.SwitchUser(ctx, arg)
+				}
+				if err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user, session := m.Current(); user != tt.wantUser || session != tt.wantSession {
+				t.Errorf("Current() = %s, %s; want %s, %s", user, session, tt.wantUser, tt.wantSession)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Package sqlcompare judges whether two SQL statements are the same query,
+// by their text or by the rows they return. The eval and canary commands
+// share it to compare the agents' SQL with expected or recorded SQL.
+package sqlcompare
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+// MaxRows bounds the rows of each statement compared by Rows.
+const MaxRows = 1000
+
+var spaces = regexp.MustCompile(`\s+`)
+
+// Normalize folds case, whitespace and a trailing semicolon.
+func Normalize(sql string) string {
+	sql = strings.TrimSuffix(strings.TrimSpace(sql), ";")
+	return strings.ToLower(spaces.ReplaceAllString(strings.TrimSpace(sql), " "))
+}
+
+// OneLine collapses a statement onto a single line.
+func OneLine(sql string) string {
+	return spaces.ReplaceAllString(strings.TrimSpace(sql), " ")
+}
+
+// RowSet returns the rows of a query result as sorted strings, so results
+// compare equal in any row order and under any column names.
+func RowSet(data string) ([]string, error) {
+	_, rows, err := app.Rows(data)
+	if err != nil {
+		return nil, err
+	}
+	set := make([]string, len(rows))
+	for i, r := range rows {
+		set[i] = strings.Join(r, "\x1f")
+	}
+	slices.Sort(set)
+	return set, nil
+}
+
+// Rows runs sql with a limit of MaxRows and returns its RowSet. Both sides
+// of a comparison should be run this way, so results longer than MaxRows
+// are cut alike.
+func Rows(ctx context.Context, db sqlagent.MCPClient, sql string) ([]string, error) {
+	data, err := db.Query(ctx, sql, MaxRows)
+	if err != nil {
+		return nil, err
+	}
+	return RowSet(data)
+}
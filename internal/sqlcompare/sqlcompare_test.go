@@ -0,0 +1,62 @@
+package sqlcompare
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+)
+
+func TestNormalize(t *testing.T) {
+	if a, b := Normalize("select count(*)\n  FROM orders;"), Normalize("SELECT COUNT(*) FROM orders"); a != b {
+		t.Errorf("Normalize() = %q and %q, want them equal", a, b)
+	}
+	if got := OneLine("SELECT id\n\tFROM orders "); got != "SELECT id FROM orders" {
+		t.Errorf("OneLine() = %q", got)
+	}
+}
+
+// numbersDB returns the numbers 1 to n, in ascending or descending order,
+// up to the limit.
+type numbersDB struct {
+	sqlagent.MCPClient
+	n int
+}
+
+func (db numbersDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	var rows []string
+	for i := 1; i <= db.n && (limit <= 0 || len(rows) < limit); i++ {
+		v := i
+		if strings.Contains(query, "DESC") {
+			v = db.n + 1 - i
+		}
+		rows = append(rows, fmt.Sprintf(`{"n":%d}`, v))
+	}
+	return "[" + strings.Join(rows, ",") + "]", nil
+}
+
+func TestRows(t *testing.T) {
+	db := numbersDB{n: 3}
+	a, err := Rows(context.Background(), db, "SELECT n FROM t ORDER BY n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Rows(context.Background(), db, "SELECT n AS value FROM t ORDER BY n DESC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(a, b) {
+		t.Errorf("Rows() = %v and %v, want the same set", a, b)
+	}
+
+	long, err := Rows(context.Background(), numbersDB{n: MaxRows + 10}, "SELECT n FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(long) != MaxRows {
+		t.Errorf("Rows() returned %d rows, want %d", len(long), MaxRows)
+	}
+}
@@ -0,0 +1,319 @@
+// Package stats computes statistics over query results: anomalies in time
+// series, descriptive statistics and correlations, so questions such as
+// "anything unusual in daily signups?" or "is discount correlated with
+// order size?" are answered with numbers rather than impressions.
+package stats
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// Methods of anomaly detection.
+const (
+	// MethodZScore scores each value against the median of the series
+	MethodZScore = "zscore"
+	// MethodSTL scores each value against a trend and seasonal fit, so
+	// weekly or yearly patterns are not flagged
+	MethodSTL = "stl"
+)
+
+// Kinds of anomalies.
+const (
+	KindSpike      = "spike"
+	KindDrop       = "drop"
+	KindLevelShift = "level_shift"
+)
+
+// DefaultThreshold is the robust z-score from which values are flagged.
+const DefaultThreshold = 3.5
+
+// madScale turns a median absolute deviation into a standard deviation of
+// normally distributed values.
+const madScale = 1.4826
+
+// Point is a value of a time series.
+type Point struct {
+	Time  string
+	Value float64
+}
+
+// Options configure Detect.
+type Options struct {
+	// Method is MethodZScore or MethodSTL (default: STL when the series
+	// covers two seasons, z-score otherwise)
+	Method string
+	// Period is the length of a season in points (default: 7 for days, 12
+	// for months, 24 for hours, judged from the times)
+	Period int
+	// Threshold is the robust z-score from which values are flagged
+	// (default: DefaultThreshold)
+	Threshold float64
+}
+
+// Anomaly is a point, or for a level shift the start of a stretch, that
+// does not fit the rest of the series.
+type Anomaly struct {
+	Time  string  `json:"time"`
+	Kind  string  `json:"kind"`
+	Value float64 `json:"value"`
+	// Expected is the fitted value, or the level before a level shift
+	Expected float64 `json:"expected"`
+	// Score is the deviation in robust standard deviations
+	Score float64 `json:"score"`
+}
+
+// Detection is the outcome of Detect.
+type Detection struct {
+	Method    string    `json:"method"`
+	Period    int       `json:"period,omitempty"`
+	Threshold float64   `json:"threshold"`
+	Points    int       `json:"points"`
+	Anomalies []Anomaly `json:"anomalies"`
+}
+
+// minPoints is the shortest series anomalies are looked for in.
+const minPoints = 5
+
+// Detect flags the points of a time series, in time order, whose
+// deviation from the expected value is at least the threshold in robust
+// z-scores (median and median absolute deviation, so the outliers do not
+// hide themselves), and the largest shift in level between two stretches
+// of the series if it is as large.
+func Detect(points []Point, opts Options) (Detection, error) {
+	if len(points) < minPoints {
+		return Detection{}, fmt.Errorf("need at least %d points to look for anomalies, got %d", minPoints, len(points))
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	if opts.Period <= 0 {
+		opts.Period = guessPeriod(points)
+	}
+	switch opts.Method {
+	case "":
+		opts.Method = MethodZScore
+		if opts.Period > 1 && len(points) >= 2*opts.Period {
+			opts.Method = MethodSTL
+		}
+	case MethodZScore, MethodSTL:
+	default:
+		return Detection{}, fmt.Errorf("unknown method %q (want %s or %s)", opts.Method, MethodZScore, MethodSTL)
+	}
+
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	expected := make([]float64, len(values))
+	// Level shifts are looked for once the seasonal pattern is removed
+	adjusted := slices.Clone(values)
+	if opts.Method == MethodSTL {
+		if opts.Period < 2 {
+			opts.Period = 0
+		}
+		trend, seasonal := decompose(values, opts.Period)
+		for i := range values {
+			expected[i] = trend[i] + seasonal[i]
+			adjusted[i] -= seasonal[i]
+		}
+	} else {
+		opts.Period = 0
+		m := median(values)
+		for i := range expected {
+			expected[i] = m
+		}
+	}
+
+	d := Detection{Method: opts.Method, Period: opts.Period, Threshold: opts.Threshold, Points: len(points), Anomalies: []Anomaly{}}
+	residuals := make([]float64, len(values))
+	for i := range values {
+		residuals[i] = values[i] - expected[i]
+		// Exact fits leave rounding errors, not deviations
+		if math.Abs(residuals[i]) < 1e-9*max(1, math.Abs(values[i])) {
+			residuals[i] = 0
+		}
+	}
+	center, spread := median(residuals), robustSpread(residuals)
+	if opts.Method == MethodSTL {
+		// Medians fit a few seasons so closely that their residuals
+		// understate the noise; the changes from point to point do not
+		spread = noise(adjusted)
+	}
+	if spread > 0 {
+		for i, r := range residuals {
+			score := (r - center) / spread
+			if math.Abs(score) < opts.Threshold {
+				continue
+			}
+			kind := KindSpike
+			if score < 0 {
+				kind = KindDrop
+			}
+			d.Anomalies = append(d.Anomalies, Anomaly{
+				Time:     points[i].Time,
+				Kind:     kind,
+				Value:    values[i],
+				Expected: round(expected[i]),
+				Score:    round(score),
+			})
+		}
+	}
+	if shift, ok := levelShift(points, adjusted, opts.Threshold); ok {
+		d.Anomalies = append(d.Anomalies, shift)
+	}
+	return d, nil
+}
+
+// decompose splits a series into a trend, as a moving median over a
+// season (or five points) so outliers do not bend it, and a seasonal component, as the median
+// deviation from the trend at each position in the season, and returns
+// both for each point. Windows are centered, and moved inwards at the ends
+// so they always cover a whole season.
+func decompose(values []float64, period int) (trend, seasonal []float64) {
+	window := period
+	if window < 2 {
+		window = 5
+	}
+	window = min(window, len(values))
+	trend = make([]float64, len(values))
+	for i := range values {
+		lo := min(max(i-window/2, 0), len(values)-window)
+		trend[i] = median(values[lo : lo+window])
+	}
+
+	seasonal = make([]float64, len(values))
+	if period < 2 {
+		return trend, seasonal
+	}
+	phases := make([]float64, period)
+	for phase := range phases {
+		var deviations []float64
+		for i := phase; i < len(values); i += period {
+			deviations = append(deviations, values[i]-trend[i])
+		}
+		phases[phase] = median(deviations)
+	}
+	for i := range seasonal {
+		seasonal[i] = phases[i%period]
+	}
+	return trend, seasonal
+}
+
+// levelShift finds the point with the largest difference between the
+// medians of the stretches just before and from it, and reports it if the
+// difference is at least threshold times the noise of the series, estimated
+// from the changes between consecutive points. Comparing neighbouring
+// stretches rather than halves keeps steady growth from counting as a
+// shift.
+func levelShift(points []Point, values []float64, threshold float64) (Anomaly, bool) {
+	minSegment := max(3, len(values)/10)
+	if len(values) < 2*minSegment {
+		return Anomaly{}, false
+	}
+	sigma := noise(values)
+	if sigma == 0 {
+		return Anomaly{}, false
+	}
+
+	best, bestShift := -1, 0.0
+	var before, after float64
+	for k := minSegment; k <= len(values)-minSegment; k++ {
+		b, a := median(values[k-minSegment:k]), median(values[k:k+minSegment])
+		if shift := math.Abs(a - b); shift > bestShift {
+			best, bestShift, before, after = k, shift, b, a
+		}
+	}
+	score := (after - before) / sigma
+	if best < 0 || math.Abs(score) < threshold {
+		return Anomaly{}, false
+	}
+	return Anomaly{
+		Time:     points[best].Time,
+		Kind:     KindLevelShift,
+		Value:    round(after),
+		Expected: round(before),
+		Score:    round(score),
+	}, true
+}
+
+// noise estimates the standard deviation of the noise of a series from
+// the changes between consecutive points, which a trend barely affects.
+func noise(values []float64) float64 {
+	diffs := make([]float64, len(values)-1)
+	for i := range diffs {
+		diffs[i] = values[i+1] - values[i]
+	}
+	// Consecutive differences have twice the variance of the noise
+	return robustSpread(diffs) / math.Sqrt2
+}
+
+// guessPeriod is the season of a series judged from the step between its
+// first two times: a week of days, a year of months or a day of hours.
+func guessPeriod(points []Point) int {
+	a, okA := parseTime(points[0].Time)
+	b, okB := parseTime(points[1].Time)
+	if !okA || !okB {
+		return 0
+	}
+	step := b.Sub(a)
+	switch {
+	case step == time.Hour:
+		return 24
+	case step == 24*time.Hour:
+		return 7
+	case step >= 28*24*time.Hour && step <= 31*24*time.Hour:
+		return 12
+	}
+	return 0
+}
+
+// timeLayouts are the formats of dates and timestamps in query results.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02", "2006-01"}
+
+func parseTime(s string) (time.Time, bool) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// robustSpread estimates the standard deviation of values from their median
+// absolute deviation, falling back to the mean absolute deviation when more
+// than half the values are equal.
+func robustSpread(values []float64) float64 {
+	m := median(values)
+	deviations := make([]float64, len(values))
+	sum := 0.0
+	for i, v := range values {
+		deviations[i] = math.Abs(v - m)
+		sum += deviations[i]
+	}
+	if mad := median(deviations); mad > 0 {
+		return mad * madScale
+	}
+	// The mean absolute deviation of normal values is 0.7979 sigma
+	return sum / float64(len(values)) * 1.2533
+}
+
+// round keeps two decimals, enough to read scores and fitted values.
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
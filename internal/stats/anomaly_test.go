@@ -0,0 +1,124 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// daily builds a series of days from 2026-03-01 with the given values.
+func daily(values ...float64) []Point {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]Point, len(values))
+	for i, v := range values {
+		points[i] = Point{Time: start.AddDate(0, 0, i).Format("2006-01-02"), Value: v}
+	}
+	return points
+}
+
+// weekly repeats a week of values with a little noise.
+func weekly(weeks int) []float64 {
+	week := []float64{100, 104, 98, 102, 101, 40, 42}
+	var values []float64
+	for w := 0; w < weeks; w++ {
+		for i, v := range week {
+			values = append(values, v+float64((w*7+i)*37%5)-2)
+		}
+	}
+	return values
+}
+
+func TestDetect(t *testing.T) {
+	spiked := weekly(4)
+	spiked[17] = 180
+
+	shifted := make([]float64, 40)
+	for i := range shifted {
+		shifted[i] = 10 + float64(i%3)
+		if i >= 20 {
+			shifted[i] += 20
+		}
+	}
+
+	tests := []struct {
+		name       string
+		points     []Point
+		opts       Options
+		wantMethod string
+		want       []string // kind@time of each anomaly
+		wantErr    bool
+	}{
+		{
+			name:       "weekends are not anomalies",
+			points:     daily(weekly(4)...),
+			wantMethod: MethodSTL,
+		},
+		{
+			name:       "spike on a weekday",
+			points:     daily(spiked...),
+			wantMethod: MethodSTL,
+			want:       []string{"spike@2026-03-18"},
+		},
+		{
+			name:       "level shift",
+			points:     daily(shifted...),
+			opts:       Options{Method: MethodZScore},
+			wantMethod: MethodZScore,
+			want:       []string{"level_shift@2026-03-21"},
+		},
+		{
+			name:       "drop in a short series",
+			points:     []Point{{"a", 10}, {"b", 11}, {"c", 9}, {"d", 10}, {"e", -30}, {"f", 10}},
+			wantMethod: MethodZScore,
+			want:       []string{"drop@e"},
+		},
+		{
+			name:       "constant",
+			points:     daily(5, 5, 5, 5, 5, 5),
+			wantMethod: MethodZScore,
+		},
+		{name: "too short", points: daily(1, 2, 3), wantErr: true},
+		{name: "unknown method", points: daily(1, 2, 3, 4, 5), opts: Options{Method: "prophet"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Detect(tt.points, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Detect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if d.Method != tt.wantMethod {
+				t.Errorf("method = %q, want %q", d.Method, tt.wantMethod)
+			}
+			var got []string
+			for _, a := range d.Anomalies {
+				got = append(got, a.Kind+"@"+a.Time)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("anomalies = %v, want %v (%+v)", got, tt.want, d.Anomalies)
+			}
+		})
+	}
+}
+
+func TestGuessPeriod(t *testing.T) {
+	tests := []struct {
+		first, second string
+		want          int
+	}{
+		{"2026-01-01 00:00:00", "2026-01-01 01:00:00", 24},
+		{"2026-01-01", "2026-01-02", 7},
+		{"2026-01", "2026-02", 12},
+		{"2026-02-01T00:00:00Z", "2026-03-01T00:00:00Z", 12},
+		{"2026-01-01", "2026-01-08", 0},
+		{"north", "south", 0},
+	}
+	for _, tt := range tests {
+		if got := guessPeriod([]Point{{Time: tt.first}, {Time: tt.second}}); got != tt.want {
+			t.Errorf("guessPeriod(%s, %s) = %d, want %d", tt.first, tt.second, got, tt.want)
+		}
+	}
+}
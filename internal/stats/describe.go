@@ -0,0 +1,224 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/frame"
+)
+
+// Summary describes the values of a numeric column.
+type Summary struct {
+	Column string  `json:"column"`
+	Count  int     `json:"count"`
+	Nulls  int     `json:"nulls"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+	Min    float64 `json:"min"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	P75    float64 `json:"p75"`
+	Max    float64 `json:"max"`
+}
+
+// Correlation relates two numeric columns over the rows where both are set.
+type Correlation struct {
+	X string `json:"x"`
+	Y string `json:"y"`
+	// Pearson measures how close the relationship is to a straight line
+	Pearson float64 `json:"pearson"`
+	// Spearman measures how consistently one grows with the other
+	Spearman float64 `json:"spearman"`
+	Rows     int     `json:"rows"`
+	Strength string  `json:"strength"`
+}
+
+// Strengths of correlations, by the larger of |Pearson| and |Spearman|.
+const (
+	StrengthNone     = "none"
+	StrengthWeak     = "weak"
+	StrengthModerate = "moderate"
+	StrengthStrong   = "strong"
+)
+
+// minCorrelationRows is the fewest rows a correlation is computed over.
+const minCorrelationRows = 3
+
+// Description is the outcome of Describe.
+type Description struct {
+	Rows         int           `json:"rows"`
+	Columns      []Summary     `json:"columns"`
+	Correlations []Correlation `json:"correlations,omitempty"`
+}
+
+// idColumnName matches identifier columns, whose numbers are not amounts.
+var idColumnName = regexp.MustCompile(`(?i)(^|_)id$`)
+
+// Describe summarizes the numeric columns of a frame other than
+// identifiers, or the given ones, and correlates each pair of them.
+func Describe(f *frame.Frame, columns []string) (Description, error) {
+	var indexes []int
+	if len(columns) == 0 {
+		for i, name := range f.Columns {
+			if numericColumn(f, i) && !idColumnName.MatchString(name) {
+				indexes = append(indexes, i)
+			}
+		}
+		if len(indexes) == 0 {
+			return Description{}, fmt.Errorf("no numeric columns among %s", strings.Join(f.Columns, ", "))
+		}
+	}
+	for _, name := range columns {
+		i, err := pickColumn(f, name, nil)
+		if err != nil {
+			return Description{}, err
+		}
+		if !numericColumn(f, i) {
+			return Description{}, fmt.Errorf("column %s is not numeric", f.Columns[i])
+		}
+		indexes = append(indexes, i)
+	}
+
+	d := Description{Rows: len(f.Rows), Columns: []Summary{}}
+	values := make([][]float64, len(indexes))
+	set := make([][]bool, len(indexes))
+	for k, col := range indexes {
+		values[k], set[k] = make([]float64, len(f.Rows)), make([]bool, len(f.Rows))
+		for r, row := range f.Rows {
+			values[k][r], set[k][r] = number(row[col])
+		}
+		d.Columns = append(d.Columns, summarize(f.Columns[col], values[k], set[k]))
+	}
+
+	for a := range indexes {
+		for b := a + 1; b < len(indexes); b++ {
+			var xs, ys []float64
+			for r := range f.Rows {
+				if set[a][r] && set[b][r] {
+					xs, ys = append(xs, values[a][r]), append(ys, values[b][r])
+				}
+			}
+			if len(xs) < minCorrelationRows {
+				continue
+			}
+			c := Correlation{
+				X:        f.Columns[indexes[a]],
+				Y:        f.Columns[indexes[b]],
+				Pearson:  round(pearson(xs, ys)),
+				Spearman: round(pearson(ranks(xs), ranks(ys))),
+				Rows:     len(xs),
+			}
+			c.Strength = strength(math.Max(math.Abs(c.Pearson), math.Abs(c.Spearman)))
+			d.Correlations = append(d.Correlations, c)
+		}
+	}
+	return d, nil
+}
+
+// summarize describes the set values of a column.
+func summarize(column string, values []float64, set []bool) Summary {
+	var xs []float64
+	for i, v := range values {
+		if set[i] {
+			xs = append(xs, v)
+		}
+	}
+	s := Summary{Column: column, Count: len(xs), Nulls: len(values) - len(xs)}
+	if len(xs) == 0 {
+		return s
+	}
+	slices.Sort(xs)
+	mean := 0.0
+	for _, x := range xs {
+		mean += x / float64(len(xs))
+	}
+	variance := 0.0
+	for _, x := range xs {
+		variance += (x - mean) * (x - mean)
+	}
+	if len(xs) > 1 {
+		variance /= float64(len(xs) - 1)
+	}
+	s.Mean, s.StdDev = round(mean), round(math.Sqrt(variance))
+	s.Min, s.Max = xs[0], xs[len(xs)-1]
+	s.P25, s.Median, s.P75 = round(quantile(xs, 0.25)), round(quantile(xs, 0.5)), round(quantile(xs, 0.75))
+	return s
+}
+
+// quantile interpolates linearly between the sorted values around q.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	if lo+1 >= len(sorted) {
+		return sorted[lo]
+	}
+	return sorted[lo] + (pos-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+// pearson is the correlation coefficient of xs and ys, or 0 if either is
+// constant.
+func pearson(xs, ys []float64) float64 {
+	n := float64(len(xs))
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i] / n
+		my += ys[i] / n
+	}
+	var sxy, sxx, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return 0
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// ranks replaces values by their ranks, ties by the mean of their ranks.
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case values[a] < values[b]:
+			return -1
+		case values[a] > values[b]:
+			return 1
+		}
+		return 0
+	})
+	out := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			out[order[k]] = rank
+		}
+		i = j + 1
+	}
+	return out
+}
+
+// strength describes a correlation coefficient in words.
+func strength(r float64) string {
+	switch {
+	case r >= 0.7:
+		return StrengthStrong
+	case r >= 0.4:
+		return StrengthModerate
+	case r >= 0.2:
+		return StrengthWeak
+	}
+	return StrengthNone
+}
@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/frame"
+)
+
+func TestDescribeRows(t *testing.T) {
+	data := `[
+		{"order_id":1,"region":"EU","discount":0,"total":"20.00","returns":3},
+		{"order_id":2,"region":"US","discount":5,"total":"35.50","returns":1},
+		{"order_id":3,"region":"EU","discount":10,"total":"48.00","returns":2},
+		{"order_id":4,"region":"US","discount":15,"total":"80.00","returns":null},
+		{"order_id":5,"region":"EU","discount":null,"total":"51.00","returns":3}
+	]`
+
+	got := DescribeRows(frame.Result{Data: data}, DescribeArgs{})
+	if got.Error != "" {
+		t.Fatalf("unexpected error: %s", got.Error)
+	}
+	if got.Rows != 5 || len(got.Columns) != 3 {
+		t.Fatalf("got %d rows, columns %+v; want 5 rows of discount, total and returns", got.Rows, got.Columns)
+	}
+	wantDiscount := Summary{Column: "discount", Count: 4, Nulls: 1, Mean: 7.5, StdDev: 6.45, Min: 0, P25: 3.75, Median: 7.5, P75: 11.25, Max: 15}
+	if !reflect.DeepEqual(got.Columns[0], wantDiscount) {
+		t.Errorf("discount = %+v, want %+v", got.Columns[0], wantDiscount)
+	}
+	if total := got.Columns[1]; total.Median != 48 || total.Min != 20 || total.Max != 80 {
+		t.Errorf("total = %+v", total)
+	}
+
+	if len(got.Correlations) != 3 {
+		t.Fatalf("correlations = %+v, want 3 pairs", got.Correlations)
+	}
+	c := got.Correlations[0]
+	if c.X != "discount" || c.Y != "total" || c.Rows != 4 || c.Spearman != 1 || c.Pearson < 0.95 || c.Strength != StrengthStrong {
+		t.Errorf("discount/total = %+v, want a strong correlation over 4 rows", c)
+	}
+
+	got = DescribeRows(frame.Result{Data: data}, DescribeArgs{Columns: []string{"Total", "order_id"}})
+	if got.Error != "" || len(got.Columns) != 2 || got.Columns[1].Column != "order_id" || len(got.Correlations) != 1 {
+		t.Errorf("named columns = %+v", got)
+	}
+
+	for columns, wantErr := range map[string]string{"region": "not numeric", "margin": `unknown column "margin"`} {
+		if got := DescribeRows(frame.Result{Data: data}, DescribeArgs{Columns: []string{columns}}); !strings.Contains(got.Error, wantErr) {
+			t.Errorf("error = %q, want %q", got.Error, wantErr)
+		}
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	xs := []float64{1, 2, 3, 4, 5}
+	tests := []struct {
+		name         string
+		ys           []float64
+		wantPearson  float64
+		wantSpearman float64
+	}{
+		{name: "linear", ys: []float64{2, 4, 6, 8, 10}, wantPearson: 1, wantSpearman: 1},
+		{name: "inverse", ys: []float64{5, 4, 3, 2, 1}, wantPearson: -1, wantSpearman: -1},
+		{name: "monotonic", ys: []float64{1, 2, 3, 4, 100}, wantPearson: 0.72, wantSpearman: 1},
+		{name: "constant", ys: []float64{3, 3, 3, 3, 3}},
+		{name: "ties", ys: []float64{1, 1, 2, 2, 3}, wantPearson: 0.94, wantSpearman: 0.95},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, s := round(pearson(xs, tt.ys)), round(pearson(ranks(xs), ranks(tt.ys)))
+			if math.Abs(p-tt.wantPearson) > 0.01 || math.Abs(s-tt.wantSpearman) > 0.01 {
+				t.Errorf("pearson, spearman = %v, %v, want %v, %v", p, s, tt.wantPearson, tt.wantSpearman)
+			}
+		})
+	}
+	if got := ranks([]float64{10, 30, 20, 30}); !reflect.DeepEqual(got, []float64{1, 3.5, 2, 3.5}) {
+		t.Errorf("ranks() = %v", got)
+	}
+}
@@ -0,0 +1,255 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/internal/frame"
+	"github.com/anuvratrastogi/multi-agent/internal/toolschema"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// AnomaliesToolName is the tool flagging anomalies in the latest query
+// result.
+const AnomaliesToolName = "detect_anomalies"
+
+// DescribeToolName is the tool summarizing and correlating the numeric
+// columns of the latest query result.
+const DescribeToolName = "describe_result"
+
+// AnomaliesArgs are the arguments of detect_anomalies.
+type AnomaliesArgs struct {
+	TimeColumn  string  `json:"time_column,omitempty" description:"The date or timestamp column; the first column holding dates if empty"`
+	ValueColumn string  `json:"value_column,omitempty" description:"The numeric column to check; the first numeric column other than the time column if empty"`
+	Method      string  `json:"method,omitempty" description:"zscore to compare values with the median, stl to compare them with a trend and weekly, monthly or hourly pattern; chosen from the data if empty"`
+	Threshold   float64 `json:"threshold,omitempty" description:"The robust z-score from which values are flagged (default 3.5); lower finds more"`
+}
+
+// AnomaliesResult lists the anomalies of a time series.
+type AnomaliesResult struct {
+	TimeColumn  string `json:"time_column,omitempty"`
+	ValueColumn string `json:"value_column,omitempty"`
+	Detection
+	Error string `json:"error,omitempty"`
+}
+
+// DescribeArgs are the arguments of describe_result.
+type DescribeArgs struct {
+	Columns []string `json:"columns,omitempty" description:"The numeric columns to describe and correlate; all numeric columns if empty"`
+}
+
+// DescribeResult summarizes the numeric columns of a query result.
+type DescribeResult struct {
+	Description
+	Error string `json:"error,omitempty"`
+}
+
+// Tools returns the detect_anomalies and describe_result tools, working on
+// the latest query result recorded by frames.
+func Tools(frames *frame.Store) ([]tool.Tool, error) {
+	anomaliesTool, err := anomaliesTool(frames)
+	if err != nil {
+		return nil, err
+	}
+	describeTool, err := describeTool(frames)
+	if err != nil {
+		return nil, err
+	}
+	return []tool.Tool{anomaliesTool, describeTool}, nil
+}
+
+func anomaliesTool(frames *frame.Store) (tool.Tool, error) {
+	schema, err := toolschema.For[AnomaliesArgs]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s schema: %w", AnomaliesToolName, err)
+	}
+	anomaliesTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        AnomaliesToolName,
+			Description: "Flag unusual values in the latest query result as a time series: spikes and drops against the median or a trend and seasonal fit, and shifts in level, each with its date, value, expected value and score.",
+			InputSchema: schema,
+		},
+		func(ctx tool.Context, args AnomaliesArgs) (AnomaliesResult, error) {
+			r, ok := frames.Last(ctx.UserID() + "/" + ctx.SessionID())
+			if !ok {
+				return AnomaliesResult{Error: "no query result to check yet; run a query first"}, nil
+			}
+			defer r.Close()
+			return Anomalies(r, args), nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", AnomaliesToolName, err)
+	}
+	return anomaliesTool, nil
+}
+
+func describeTool(frames *frame.Store) (tool.Tool, error) {
+	schema, err := toolschema.For[DescribeArgs]()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s schema: %w", DescribeToolName, err)
+	}
+	describeTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        DescribeToolName,
+			Description: "Compute descriptive statistics (count, NULLs, mean, standard deviation, min, quartiles, max) of the numeric columns of the latest query result, and the Pearson and Spearman correlation of each pair of them.",
+			InputSchema: schema,
+		},
+		func(ctx tool.Context, args DescribeArgs) (DescribeResult, error) {
+			r, ok := frames.Last(ctx.UserID() + "/" + ctx.SessionID())
+			if !ok {
+				return DescribeResult{Error: "no query result to describe yet; run a query first"}, nil
+			}
+			defer r.Close()
+			return DescribeRows(r, args), nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s tool: %w", DescribeToolName, err)
+	}
+	return describeTool, nil
+}
+
+// DescribeRows summarizes and correlates the numeric columns of a query
+// result.
+func DescribeRows(r frame.Result, args DescribeArgs) DescribeResult {
+	f, err := r.Frame()
+	if err != nil {
+		return DescribeResult{Error: err.Error()}
+	}
+	d, err := Describe(f, args.Columns)
+	if err != nil {
+		return DescribeResult{Error: err.Error()}
+	}
+	return DescribeResult{Description: d}
+}
+
+// Anomalies looks for anomalies in a column of a query result over its time
+// column, in time order. Rows whose time or value is NULL are skipped.
+func Anomalies(r frame.Result, args AnomaliesArgs) AnomaliesResult {
+	f, err := r.Frame()
+	if err != nil {
+		return AnomaliesResult{Error: err.Error()}
+	}
+	timeCol, err := pickColumn(f, args.TimeColumn, func(i int) bool { return timeColumn(f, i) })
+	if err != nil {
+		return AnomaliesResult{Error: err.Error()}
+	}
+	valueCol, err := pickColumn(f, args.ValueColumn, func(i int) bool { return i != timeCol && numericColumn(f, i) })
+	if err != nil {
+		return AnomaliesResult{Error: err.Error()}
+	}
+
+	var points []Point
+	for _, row := range f.Rows {
+		t, v := row[timeCol], row[valueCol]
+		if t == nil || v == nil {
+			continue
+		}
+		value, ok := number(v)
+		if !ok {
+			return AnomaliesResult{Error: fmt.Sprintf("column %s is not numeric: %v", f.Columns[valueCol], v)}
+		}
+		points = append(points, Point{Time: text(t), Value: value})
+	}
+	sort.SliceStable(points, func(i, j int) bool { return timeLess(points[i].Time, points[j].Time) })
+
+	d, err := Detect(points, Options{Method: args.Method, Threshold: args.Threshold})
+	if err != nil {
+		return AnomaliesResult{Error: err.Error()}
+	}
+	return AnomaliesResult{TimeColumn: f.Columns[timeCol], ValueColumn: f.Columns[valueCol], Detection: d}
+}
+
+// pickColumn returns the index of the named column, or of the first column
+// matching fallback if name is empty.
+func pickColumn(f *frame.Frame, name string, fallback func(int) bool) (int, error) {
+	if name != "" {
+		for i, c := range f.Columns {
+			if strings.EqualFold(c, name) {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("unknown column %q; columns are %s", name, strings.Join(f.Columns, ", "))
+	}
+	for i := range f.Columns {
+		if fallback(i) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no suitable column among %s; name it", strings.Join(f.Columns, ", "))
+}
+
+// timeColumn reports whether every non-null value of a column is a date or
+// timestamp.
+func timeColumn(f *frame.Frame, col int) bool {
+	seen := false
+	for _, row := range f.Rows {
+		if row[col] == nil {
+			continue
+		}
+		if _, ok := parseTime(text(row[col])); !ok {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+// numericColumn reports whether every non-null value of a column is a
+// number.
+func numericColumn(f *frame.Frame, col int) bool {
+	seen := false
+	for _, row := range f.Rows {
+		if row[col] == nil {
+			continue
+		}
+		if _, ok := number(row[col]); !ok {
+			return false
+		}
+		seen = true
+	}
+	return seen
+}
+
+// timeLess orders times chronologically when both parse, numerically when
+// both are numbers (years) and as text otherwise.
+func timeLess(a, b string) bool {
+	if ta, ok := parseTime(a); ok {
+		if tb, ok := parseTime(b); ok {
+			return ta.Before(tb)
+		}
+	}
+	if na, err := strconv.ParseFloat(a, 64); err == nil {
+		if nb, err := strconv.ParseFloat(b, 64); err == nil {
+			return na < nb
+		}
+	}
+	return a < b
+}
+
+func text(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// number converts a numeric value; NUMERIC columns arrive as strings.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
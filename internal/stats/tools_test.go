@@ -0,0 +1,56 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/frame"
+)
+
+func TestAnomalies(t *testing.T) {
+	// Signups per day, out of order, with a NULL and a spike on the 9th
+	rows := []string{`{"day":"2026-05-10","signups":"21"}`, `{"day":null,"signups":3}`}
+	for i, n := range []int{20, 22, 19, 21, 20, 23, 21, 95} {
+		rows = append(rows, fmt.Sprintf(`{"day":"2026-05-%02d","signups":%d}`, i+2, n))
+	}
+	data := "[" + strings.Join(rows, ",") + "]"
+
+	tests := []struct {
+		name     string
+		args     AnomaliesArgs
+		want     string
+		wantCols string
+		wantErr  string
+	}{
+		{name: "columns found", want: "spike@2026-05-09", wantCols: "day/signups"},
+		{name: "columns named", args: AnomaliesArgs{TimeColumn: "DAY", ValueColumn: "signups", Method: MethodZScore}, want: "spike@2026-05-09", wantCols: "day/signups"},
+		{name: "unknown column", args: AnomaliesArgs{ValueColumn: "revenue"}, wantErr: `unknown column "revenue"`},
+		{name: "unknown method", args: AnomaliesArgs{Method: "arima"}, wantErr: `unknown method "arima"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Anomalies(frame.Result{SQL: "SELECT day, signups FROM daily", Data: data}, tt.args)
+			if tt.wantErr != "" {
+				if !strings.Contains(got.Error, tt.wantErr) {
+					t.Errorf("error = %q, want %q", got.Error, tt.wantErr)
+				}
+				return
+			}
+			if got.Error != "" {
+				t.Fatalf("unexpected error: %s", got.Error)
+			}
+			if cols := got.TimeColumn + "/" + got.ValueColumn; cols != tt.wantCols {
+				t.Errorf("columns = %s, want %s", cols, tt.wantCols)
+			}
+			if got.Points != 9 || len(got.Anomalies) != 1 || got.Anomalies[0].Kind+"@"+got.Anomalies[0].Time != tt.want {
+				t.Errorf("got %d points, anomalies %+v, want %s", got.Points, got.Anomalies, tt.want)
+			}
+		})
+	}
+
+	if got := Anomalies(frame.Result{Data: `[{"region":"EU","n":1}]`}, AnomaliesArgs{}); !strings.Contains(got.Error, "no suitable column") {
+		t.Errorf("error = %q, want no suitable column", got.Error)
+	}
+}
@@ -0,0 +1,130 @@
+package teams
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/jwt"
+)
+
+const (
+	openIDMetadataURL  = "https://login.botframework.com/v1/.well-known/openidconfiguration"
+	botFrameworkIssuer = "https://api.botframework.com"
+	botFrameworkScope  = "https://api.botframework.com/.default"
+	defaultTokenTenant = "botframework.com"
+	keyRefreshInterval = 24 * time.Hour
+	tokenSkew          = 5 * time.Minute
+)
+
+// tokenSource obtains outbound access tokens for the Bot Connector API.
+type tokenSource struct {
+	appID       string
+	appPassword string
+	tenantID    string
+	client      *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token returns a cached access token, refreshing it shortly before expiry.
+func (ts *tokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && time.Now().Before(ts.expires.Add(-time.Minute)) {
+		return ts.token, nil
+	}
+
+	tenant := ts.tenantID
+	if tenant == "" {
+		tenant = defaultTokenTenant
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {ts.appID},
+		"client_secret": {ts.appPassword},
+		"scope":         {botFrameworkScope},
+	}
+	endpoint := fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", tenant)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	ts.token = body.AccessToken
+	ts.expires = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return ts.token, nil
+}
+
+// verifier validates the JWT that the Bot Framework attaches to inbound activities.
+type verifier struct {
+	appID string
+	jwt   *jwt.Verifier
+}
+
+func newVerifier(appID string, client *http.Client) *verifier {
+	return &verifier{
+		appID: appID,
+		jwt: &jwt.Verifier{
+			MetadataURL: openIDMetadataURL,
+			Algorithms:  []string{"RS256"},
+			Skew:        tokenSkew,
+			MaxAge:      keyRefreshInterval,
+			Refresh:     time.Minute,
+			Client:      client,
+		},
+	}
+}
+
+// Verify checks the Authorization header of an inbound request. The token must
+// be signed by a published Bot Framework key, issued for this bot's app ID,
+// unexpired and bound to the activity's service URL.
+func (v *verifier) Verify(ctx context.Context, authHeader, serviceURL string) error {
+	raw, ok := strings.CutPrefix(authHeader, "Bearer ")
+	if !ok {
+		return fmt.Errorf("missing bearer token")
+	}
+
+	claims, err := v.jwt.Verify(ctx, raw)
+	if err != nil {
+		return err
+	}
+	if iss := claims.String("iss"); iss != botFrameworkIssuer {
+		return fmt.Errorf("unexpected token issuer %q", iss)
+	}
+	if !claims.HasAudience(v.appID) {
+		return fmt.Errorf("token audience does not match app ID")
+	}
+	if claimed := claims.String("serviceurl"); claimed != "" && !strings.EqualFold(strings.TrimSuffix(claimed, "/"), strings.TrimSuffix(serviceURL, "/")) {
+		return fmt.Errorf("token service URL does not match activity")
+	}
+	return nil
+}
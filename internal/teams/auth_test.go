@@ -0,0 +1,136 @@
+package teams
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestVerifier returns a verifier for app ID "app" trusting key under
+// ID "k1", served by a fake OpenID endpoint.
+func newTestVerifier(t *testing.T, key *rsa.PrivateKey) *verifier {
+	t.Helper()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": srv.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	v := newVerifier("app", srv.Client())
+	v.jwt.MetadataURL = srv.URL + "/metadata"
+	return v
+}
+
+// signToken returns an RS256 token for claims, signed by key under kid.
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := encode(map[string]string{"alg": "RS256", "kid": kid}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := newTestVerifier(t, key)
+
+	const serviceURL = "https://smba.example.com/"
+	claims := func(change func(map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":        botFrameworkIssuer,
+			"aud":        "app",
+			"exp":        time.Now().Add(time.Hour).Unix(),
+			"serviceurl": serviceURL,
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr string
+	}{
+		{name: "valid", header: "Bearer " + signToken(t, key, "k1", claims(nil))},
+		{
+			name:   "audience among several",
+			header: "Bearer " + signToken(t, key, "k1", claims(func(c map[string]interface{}) { c["aud"] = []string{"other", "app"} })),
+		},
+		{name: "no bearer token", header: "Basic abc", wantErr: "missing bearer token"},
+		{name: "bad signature", header: "Bearer " + signToken(t, other, "k1", claims(nil)), wantErr: "invalid token signature"},
+		{name: "unknown key", header: "Bearer " + signToken(t, key, "k2", claims(nil)), wantErr: `unknown signing key "k2"`},
+		{
+			name:    "wrong issuer",
+			header:  "Bearer " + signToken(t, key, "k1", claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" })),
+			wantErr: "unexpected token issuer",
+		},
+		{
+			name:    "wrong audience",
+			header:  "Bearer " + signToken(t, key, "k1", claims(func(c map[string]interface{}) { c["aud"] = "other" })),
+			wantErr: "token audience does not match",
+		},
+		{
+			name:    "missing expiry",
+			header:  "Bearer " + signToken(t, key, "k1", claims(func(c map[string]interface{}) { delete(c, "exp") })),
+			wantErr: "token has no expiry",
+		},
+		{
+			name:    "expired",
+			header:  "Bearer " + signToken(t, key, "k1", claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			wantErr: "token expired",
+		},
+		{
+			name:    "other service URL",
+			header:  "Bearer " + signToken(t, key, "k1", claims(func(c map[string]interface{}) { c["serviceurl"] = "https://evil.example.com" })),
+			wantErr: "token service URL does not match",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(context.Background(), tt.header, serviceURL)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Verify() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
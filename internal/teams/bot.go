@@ -0,0 +1,349 @@
+// Package teams implements a Microsoft Teams bot adapter for the agents using
+// the Bot Framework REST protocol.
+package teams
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+)
+
+const (
+	// defaultTurnTimeout bounds how long a single message may take to answer.
+	defaultTurnTimeout = 5 * time.Minute
+	// defaultShutdownTimeout bounds how long shutdown waits for messages
+	// being answered.
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+var mentionPattern = regexp.MustCompile(`<at>[^<]*</at>`)
+
+// Config holds configuration for the Teams bot.
+type Config struct {
+	// AppID is the Microsoft App ID of the bot registration
+	AppID string
+	// AppPassword is the client secret of the bot registration
+	AppPassword string
+	// TenantID restricts outbound tokens to a single-tenant bot (optional)
+	TenantID string
+	// App executes the user's questions
+	App *app.App
+	// TurnTimeout bounds how long a message may take to answer (defaults to 5m)
+	TurnTimeout time.Duration
+	// ShutdownTimeout bounds how long shutdown waits for the messages being
+	// answered (defaults to 30s)
+	ShutdownTimeout time.Duration
+	// ChartStyle sets the size of chart images (optional)
+	ChartStyle chart.Style
+}
+
+// Bot receives Bot Framework activities and answers them with the agents.
+type Bot struct {
+	app      *app.App
+	client   *http.Client
+	tokens   *tokenSource
+	verifier *verifier
+
+	turnTimeout     time.Duration
+	shutdownTimeout time.Duration
+	chartStyle      chart.Style
+	// handlers tracks the messages being answered
+	handlers sync.WaitGroup
+}
+
+// New creates a new Teams bot.
+func New(cfg Config) (*Bot, error) {
+	if cfg.AppID == "" || cfg.AppPassword == "" {
+		return nil, fmt.Errorf("teams bot requires an app ID and password")
+	}
+	if cfg.App == nil {
+		return nil, fmt.Errorf("teams bot requires an app")
+	}
+
+	turnTimeout := cfg.TurnTimeout
+	if turnTimeout <= 0 {
+		turnTimeout = defaultTurnTimeout
+	}
+	shutdownTimeout := cfg.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	return &Bot{
+		app:    cfg.App,
+		client: client,
+		tokens: &tokenSource{
+			appID:       cfg.AppID,
+			appPassword: cfg.AppPassword,
+			tenantID:    cfg.TenantID,
+			client:      client,
+		},
+		verifier:        newVerifier(cfg.AppID, client),
+		turnTimeout:     turnTimeout,
+		shutdownTimeout: shutdownTimeout,
+		chartStyle:      cfg.ChartStyle,
+	}, nil
+}
+
+// Activity is the subset of the Bot Framework activity schema used by the bot.
+type Activity struct {
+	Type         string          `json:"type"`
+	ID           string          `json:"id,omitempty"`
+	ServiceURL   string          `json:"serviceUrl,omitempty"`
+	ChannelID    string          `json:"channelId,omitempty"`
+	From         *ChannelAccount `json:"from,omitempty"`
+	Recipient    *ChannelAccount `json:"recipient,omitempty"`
+	Conversation *Conversation   `json:"conversation,omitempty"`
+	Text         string          `json:"text,omitempty"`
+	TextFormat   string          `json:"textFormat,omitempty"`
+	ReplyToID    string          `json:"replyToId,omitempty"`
+	Attachments  []Attachment    `json:"attachments,omitempty"`
+}
+
+// ChannelAccount identifies a user or bot.
+type ChannelAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// AADObjectID is the user's Azure AD object ID, set for Teams users
+	AADObjectID string `json:"aadObjectId,omitempty"`
+}
+
+// Conversation identifies a chat, channel thread or group conversation.
+type Conversation struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenantId,omitempty"`
+}
+
+// Attachment carries rich content such as Adaptive Cards.
+type Attachment struct {
+	ContentType string      `json:"contentType"`
+	Content     interface{} `json:"content"`
+}
+
+// ServeHTTP implements the Bot Framework messaging endpoint.
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var activity Activity
+	if err := json.NewDecoder(r.Body).Decode(&activity); err != nil {
+		http.Error(w, "invalid activity", http.StatusBadRequest)
+		return
+	}
+
+	if err := b.verifier.Verify(r.Context(), r.Header.Get("Authorization"), activity.ServiceURL); err != nil {
+		log.Printf("⚠️  [TEAMS] Rejected activity: %v", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// The connector retries messages refused while shutting down
+	if b.app.Draining() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Agent turns routinely exceed the connector's response deadline, so
+	// acknowledge immediately and reply asynchronously.
+	w.WriteHeader(http.StatusAccepted)
+
+	if activity.Type != "message" || activity.Conversation == nil {
+		return
+	}
+	b.handlers.Add(1)
+	go func() {
+		defer b.handlers.Done()
+		b.handleMessage(activity)
+	}()
+}
+
+// ListenAndServe serves the messaging endpoint at /api/messages until ctx
+// is cancelled, then waits up to the shutdown timeout for the messages
+// being answered. Their turns are not cancelled with ctx; App.Drain bounds
+// how long they may take.
+func (b *Bot) ListenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/api/messages", b)
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), b.shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+		if !wait(shutdownCtx, &b.handlers) {
+			log.Printf("⚠️  [TEAMS] Stopped waiting for messages being answered")
+		}
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("teams server error: %w", err)
+	}
+	<-stopped
+	return nil
+}
+
+// wait waits for wg until ctx is done, reporting whether wg finished.
+func wait(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (b *Bot) handleMessage(activity Activity) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.turnTimeout)
+	defer cancel()
+
+	query := strings.TrimSpace(mentionPattern.ReplaceAllString(activity.Text, ""))
+	userID := UserID(activity.From)
+	if query == "" || userID == "" {
+		return
+	}
+
+	sessionID := SessionID(activity.Conversation.ID)
+	console.Printf("  💬 [TEAMS] %s: %s\n", sessionID, query)
+
+	if err := b.send(ctx, activity, Activity{Type: "typing"}); err != nil {
+		log.Printf("⚠️  [TEAMS] Failed to send typing indicator: %v", err)
+	}
+
+	turn, err := b.app.Ask(ctx, userID, sessionID, query, nil)
+	if err != nil {
+		b.reply(ctx, activity, Activity{Type: "message", Text: fmt.Sprintf("❌ Error: %v", err)})
+		return
+	}
+
+	b.reply(ctx, activity, renderTurn(turn, b.chartStyle))
+}
+
+// UserID identifies the sender of an activity by their Azure AD object ID,
+// which is stable across chats and channels, or else by their channel
+// account ID, so that access policies, usage and audit apply per person.
+func UserID(from *ChannelAccount) string {
+	if from == nil {
+		return ""
+	}
+	if from.AADObjectID != "" {
+		return from.AADObjectID
+	}
+	return from.ID
+}
+
+// SessionID maps a Teams conversation to an agent session. Channel threads
+// carry the root message ID in the conversation ID, so each thread gets its
+// own session while one-to-one chats keep a single running session.
+func SessionID(conversationID string) string {
+	sum := sha256.Sum256([]byte(conversationID))
+	return "teams-" + hex.EncodeToString(sum[:8])
+}
+
+// renderTurn converts a turn into a message with Adaptive Card attachments
+// for each result table and chart.
+func renderTurn(turn *app.Turn, style chart.Style) Activity {
+	msg := Activity{
+		Type:       "message",
+		Text:       chart.StripMermaid(turn.Text),
+		TextFormat: "markdown",
+	}
+	if turn.Clarification != nil {
+		msg.Text = strings.TrimSpace(msg.Text + "\n\n" + turn.Clarification.String())
+	}
+	if strings.TrimSpace(msg.Text) == "" && len(turn.Queries) == 0 && len(turn.Charts) == 0 {
+		msg.Text = "💡 No response generated."
+	}
+	if reason := turn.StopReason(); reason != "" {
+		msg.Text = strings.TrimSpace(msg.Text + "\n\n⛔ " + reason)
+	}
+
+	for _, q := range turn.Queries {
+		if card, ok := tableCard(q); ok {
+			msg.Attachments = append(msg.Attachments, Attachment{
+				ContentType: adaptiveCardContentType,
+				Content:     card,
+			})
+		}
+	}
+	for _, c := range turn.Charts {
+		msg.Attachments = append(msg.Attachments, Attachment{
+			ContentType: adaptiveCardContentType,
+			Content:     chartCard(style.ImageURL(c)),
+		})
+	}
+	return msg
+}
+
+func (b *Bot) reply(ctx context.Context, incoming Activity, msg Activity) {
+	msg.ReplyToID = incoming.ID
+	if err := b.send(ctx, incoming, msg); err != nil {
+		log.Printf("⚠️  [TEAMS] Failed to send reply: %v", err)
+	}
+}
+
+// send posts an activity into the conversation of incoming via the Bot Connector API.
+func (b *Bot) send(ctx context.Context, incoming Activity, msg Activity) error {
+	msg.From = incoming.Recipient
+	msg.Recipient = incoming.From
+	msg.Conversation = incoming.Conversation
+
+	endpoint := fmt.Sprintf("%s/v3/conversations/%s/activities",
+		strings.TrimSuffix(incoming.ServiceURL, "/"), url.PathEscape(incoming.Conversation.ID))
+	if msg.ReplyToID != "" {
+		endpoint += "/" + url.PathEscape(msg.ReplyToID)
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity: %w", err)
+	}
+
+	token, err := b.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send activity: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("connector returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package teams
+
+import "testing"
+
+func TestUserID(t *testing.T) {
+	tests := []struct {
+		name string
+		from *ChannelAccount
+		want string
+	}{
+		{name: "azure ad object id", from: &ChannelAccount{ID: "29:1abc", AADObjectID: "6f1c-aad"}, want: "6f1c-aad"},
+		{name: "account id", from: &ChannelAccount{ID: "29:1abc"}, want: "29:1abc"},
+		{name: "no sender", from: nil, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UserID(tt.from); got != tt.want {
+				t.Errorf("UserID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
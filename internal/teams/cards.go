@@ -0,0 +1,98 @@
+package teams
+
+import (
+	"fmt"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+const (
+	adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+	adaptiveCardVersion     = "1.5"
+	// maxCardRows keeps cards under the Teams message size limit.
+	maxCardRows = 20
+)
+
+// tableCard renders a query result as an Adaptive Card table.
+func tableCard(q app.Query) (map[string]interface{}, bool) {
+	columns, rows, err := app.Rows(q.Data)
+	if err != nil || len(columns) == 0 {
+		return nil, false
+	}
+
+	cardColumns := make([]map[string]interface{}, len(columns))
+	for i := range columns {
+		cardColumns[i] = map[string]interface{}{"width": 1}
+	}
+
+	tableRows := []map[string]interface{}{tableRow(columns, true)}
+	for i, row := range rows {
+		if i == maxCardRows {
+			break
+		}
+		tableRows = append(tableRows, tableRow(row, false))
+	}
+
+	body := []map[string]interface{}{
+		{
+			"type":              "Table",
+			"columns":           cardColumns,
+			"rows":              tableRows,
+			"firstRowAsHeaders": true,
+			"gridStyle":         "accent",
+		},
+	}
+	if len(rows) > maxCardRows {
+		body = append(body, map[string]interface{}{
+			"type":     "TextBlock",
+			"text":     fmt.Sprintf("Showing %d of %d rows.", maxCardRows, len(rows)),
+			"isSubtle": true,
+			"size":     "Small",
+		})
+	}
+
+	return adaptiveCard(body), true
+}
+
+func tableRow(cells []string, header bool) map[string]interface{} {
+	items := make([]map[string]interface{}, len(cells))
+	for i, cell := range cells {
+		text := map[string]interface{}{
+			"type": "TextBlock",
+			"text": cell,
+			"wrap": true,
+		}
+		if header {
+			text["weight"] = "Bolder"
+		}
+		items[i] = map[string]interface{}{
+			"type":  "TableCell",
+			"items": []map[string]interface{}{text},
+		}
+	}
+	return map[string]interface{}{
+		"type":  "TableRow",
+		"cells": items,
+	}
+}
+
+// chartCard shows the image of a chart rendered at url as a card.
+func chartCard(url string) map[string]interface{} {
+	return adaptiveCard([]map[string]interface{}{
+		{
+			"type":    "Image",
+			"url":     url,
+			"altText": "Chart",
+			"size":    "Stretch",
+		},
+	})
+}
+
+func adaptiveCard(body []map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "AdaptiveCard",
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"version": adaptiveCardVersion,
+		"body":    body,
+	}
+}
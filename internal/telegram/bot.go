@@ -0,0 +1,366 @@
+// Package telegram implements a long-polling Telegram bot for personal
+// deployments, answering questions from an allow-list of chats.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+)
+
+const (
+	apiBaseURL = "https://api.telegram.org/bot"
+	// pollTimeout is the long-polling timeout passed to getUpdates.
+	pollTimeout = 30
+	// defaultTurnTimeout bounds how long a single message may take to answer.
+	defaultTurnTimeout = 5 * time.Minute
+	// maxMessageLen is Telegram's limit on message text length, counted
+	// after HTML entities are parsed.
+	maxMessageLen = 4096
+	// retryDelay is how long polling waits after a failed getUpdates.
+	retryDelay = 5 * time.Second
+	// maxTableRows keeps result tables readable on a phone screen.
+	maxTableRows = 20
+)
+
+// Config holds configuration for the Telegram bot.
+type Config struct {
+	// Token is the bot token issued by @BotFather
+	Token string
+	// AllowedChats lists the chat IDs the bot will answer
+	AllowedChats []int64
+	// App executes the user's questions
+	App *app.App
+	// TurnTimeout bounds how long a message may take to answer (defaults to 5m)
+	TurnTimeout time.Duration
+	// ChartStyle sets the size of chart images (optional)
+	ChartStyle chart.Style
+}
+
+// Bot polls Telegram for messages and answers them with the agents.
+type Bot struct {
+	token string
+	// apiURL is the Bot API endpoint the token is appended to
+	apiURL  string
+	allowed map[int64]bool
+	app     *app.App
+	client  *http.Client
+
+	turnTimeout time.Duration
+	chartStyle  chart.Style
+	// handlers tracks the messages being answered
+	handlers sync.WaitGroup
+
+	mu    sync.Mutex
+	chats map[int64]*sync.Mutex
+}
+
+// New creates a new Telegram bot.
+func New(cfg Config) (*Bot, error) {
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("telegram bot requires a token")
+	}
+	if len(cfg.AllowedChats) == 0 {
+		return nil, fmt.Errorf("telegram bot requires at least one allowed chat ID")
+	}
+	if cfg.App == nil {
+		return nil, fmt.Errorf("telegram bot requires an app")
+	}
+
+	allowed := make(map[int64]bool, len(cfg.AllowedChats))
+	for _, id := range cfg.AllowedChats {
+		allowed[id] = true
+	}
+
+	turnTimeout := cfg.TurnTimeout
+	if turnTimeout <= 0 {
+		turnTimeout = defaultTurnTimeout
+	}
+
+	return &Bot{
+		token:      cfg.Token,
+		apiURL:     apiBaseURL,
+		allowed:    allowed,
+		app:        cfg.App,
+		client:     &http.Client{Timeout: (pollTimeout + 10) * time.Second},
+		chartStyle: cfg.ChartStyle,
+		chats:      make(map[int64]*sync.Mutex),
+
+		turnTimeout: turnTimeout,
+	}, nil
+}
+
+type update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *message `json:"message"`
+}
+
+type message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      chat   `json:"chat"`
+	Text      string `json:"text"`
+}
+
+type chat struct {
+	ID int64 `json:"id"`
+}
+
+type apiResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	Description string          `json:"description"`
+}
+
+// Run polls for updates until ctx is cancelled, then waits for the messages
+// being answered. Their turns are not cancelled with ctx; App.Drain bounds
+// how long they may take.
+func (b *Bot) Run(ctx context.Context) error {
+	defer b.handlers.Wait()
+	var offset int64
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var updates []update
+		err := b.call(ctx, "getUpdates", map[string]interface{}{
+			"offset":          offset,
+			"timeout":         pollTimeout,
+			"allowed_updates": []string{"message"},
+		}, &updates)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("⚠️  [TELEGRAM] Polling failed: %v", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			if u.Message == nil || strings.TrimSpace(u.Message.Text) == "" {
+				continue
+			}
+			if !b.allowed[u.Message.Chat.ID] {
+				log.Printf("⚠️  [TELEGRAM] Ignoring message from chat %d (not in allow-list)", u.Message.Chat.ID)
+				continue
+			}
+			b.handlers.Add(1)
+			go func() {
+				defer b.handlers.Done()
+				b.handleMessage(context.WithoutCancel(ctx), *u.Message)
+			}()
+		}
+	}
+}
+
+// chatLock serializes turns within a chat so the session history stays ordered.
+func (b *Bot) chatLock(chatID int64) *sync.Mutex {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	m, ok := b.chats[chatID]
+	if !ok {
+		m = &sync.Mutex{}
+		b.chats[chatID] = m
+	}
+	return m
+}
+
+func (b *Bot) handleMessage(ctx context.Context, msg message) {
+	lock := b.chatLock(msg.Chat.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, b.turnTimeout)
+	defer cancel()
+
+	query := strings.TrimSpace(msg.Text)
+	if query == "/start" {
+		b.sendText(ctx, msg.Chat.ID, "🤖 Ask me anything about your database.", "")
+		return
+	}
+
+	id := fmt.Sprintf("telegram-%d", msg.Chat.ID)
+	console.Printf("  💬 [TELEGRAM] %s: %s\n", id, query)
+	b.call(ctx, "sendChatAction", map[string]interface{}{"chat_id": msg.Chat.ID, "action": "typing"}, nil)
+
+	turn, err := b.app.Ask(ctx, id, id, query, nil)
+	if err != nil {
+		b.sendText(ctx, msg.Chat.ID, fmt.Sprintf("❌ Error: %v", err), "")
+		return
+	}
+
+	text := chart.StripMermaid(turn.Text)
+	if turn.Clarification != nil {
+		text = strings.TrimSpace(text + "\n\n" + turn.Clarification.String())
+	}
+	if text == "" && len(turn.Queries) == 0 && len(turn.Charts) == 0 {
+		text = "💡 No response generated."
+	}
+	if reason := turn.StopReason(); reason != "" {
+		text = strings.TrimSpace(text + "\n\n⛔ " + reason)
+	}
+	if text != "" {
+		b.sendText(ctx, msg.Chat.ID, text, "")
+	}
+
+	for _, q := range turn.Queries {
+		if table := formatTable(q); table != "" {
+			b.sendText(ctx, msg.Chat.ID, table, "HTML")
+		}
+	}
+
+	for _, c := range turn.Charts {
+		err := b.call(ctx, "sendPhoto", map[string]interface{}{
+			"chat_id": msg.Chat.ID,
+			"photo":   b.chartStyle.ImageURL(c),
+		}, nil)
+		if err != nil {
+			log.Printf("⚠️  [TELEGRAM] Failed to send chart: %v", err)
+		}
+	}
+}
+
+// sendText sends text to a chat. Plain text longer than a message is split
+// into several; HTML must already fit, as cutting it could break its tags.
+func (b *Bot) sendText(ctx context.Context, chatID int64, text, parseMode string) {
+	parts := []string{text}
+	if parseMode == "" {
+		parts = splitText(text, maxMessageLen)
+	}
+	for _, part := range parts {
+		params := map[string]interface{}{
+			"chat_id": chatID,
+			"text":    part,
+		}
+		if parseMode != "" {
+			params["parse_mode"] = parseMode
+		}
+		if err := b.call(ctx, "sendMessage", params, nil); err != nil {
+			log.Printf("⚠️  [TELEGRAM] Failed to send message: %v", err)
+			return
+		}
+	}
+}
+
+// splitText splits text into parts of at most limit runes, at the last line
+// break of each part, or else its last space.
+func splitText(text string, limit int) []string {
+	var parts []string
+	r := []rune(text)
+	for len(r) > limit {
+		cut := limit
+		if i := lastIndex(r[:limit], '\n'); i > 0 {
+			cut = i
+		} else if i := lastIndex(r[:limit], ' '); i > 0 {
+			cut = i
+		}
+		parts = append(parts, strings.TrimRight(string(r[:cut]), " \n"))
+		r = []rune(strings.TrimLeft(string(r[cut:]), " \n"))
+	}
+	if len(r) > 0 || len(parts) == 0 {
+		parts = append(parts, string(r))
+	}
+	return parts
+}
+
+// lastIndex returns the index of the last c in r, or -1.
+func lastIndex(r []rune, c rune) int {
+	for i := len(r) - 1; i >= 0; i-- {
+		if r[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// formatTable renders a query result as a monospaced HTML block.
+func formatTable(q app.Query) string {
+	columns, rows, err := app.Rows(q.Data)
+	if err != nil || len(columns) == 0 {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(columns, "\t"))
+	for i, row := range rows {
+		if i == maxTableRows {
+			break
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+
+	var footer string
+	if len(rows) > maxTableRows {
+		footer = fmt.Sprintf("\nShowing %d of %d rows.", maxTableRows, len(rows))
+	}
+	// Telegram counts the text without tags and with entities parsed, so the
+	// plain table is cut to fit before it is escaped and wrapped in <pre>
+	const cut = "\n…"
+	table := buf.String()
+	if limit := maxMessageLen - len([]rune(footer)); len([]rune(table)) > limit {
+		table = splitText(table, limit-len([]rune(cut)))[0] + cut
+	}
+	return "<pre>" + html.EscapeString(table) + "</pre>" + footer
+}
+
+// call invokes a Bot API method and decodes its result into out (if non-nil).
+func (b *Bot) call(ctx context.Context, method string, params map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := b.apiURL + b.token + "/" + url.PathEscape(method)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		// Avoid leaking the bot token embedded in the request URL.
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var apiResp apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	if !apiResp.OK {
+		return fmt.Errorf("%s failed: %s", method, apiResp.Description)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(apiResp.Result, out); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{name: "fits", text: "hello world", limit: 11, want: []string{"hello world"}},
+		{name: "at a line break", text: "first line\nsecond line", limit: 15, want: []string{"first line", "second line"}},
+		{name: "at a space", text: "one two three four", limit: 9, want: []string{"one two", "three", "four"}},
+		{name: "line break before space", text: "ab cd\nef gh ij", limit: 10, want: []string{"ab cd", "ef gh ij"}},
+		{name: "no break", text: "abcdefghij", limit: 4, want: []string{"abcd", "efgh", "ij"}},
+		{name: "runes", text: "ééé ééé", limit: 4, want: []string{"ééé", "ééé"}},
+		{name: "empty", text: "", limit: 4, want: []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitText(tt.text, tt.limit); !slices.Equal(got, tt.want) {
+				t.Errorf("splitText(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatTable(t *testing.T) {
+	var records []map[string]string
+	for i := range 30 {
+		records = append(records, map[string]string{"id": fmt.Sprint(i), "note": strings.Repeat("<&>", 100)})
+	}
+	data, _ := json.Marshal(records)
+
+	got := formatTable(app.Query{Data: string(data)})
+	if !strings.HasPrefix(got, "<pre>") || !strings.Contains(got, "</pre>\nShowing 20 of 30 rows.") {
+		t.Fatalf("formatTable() = %q, want a closed <pre> block and the row count", got)
+	}
+	// Telegram's limit applies to the text with tags removed and entities parsed
+	text := html.UnescapeString(regexp.MustCompile(`</?pre>`).ReplaceAllString(got, ""))
+	if n := len([]rune(text)); n > maxMessageLen {
+		t.Errorf("formatTable() is %d characters, over the %d limit", n, maxMessageLen)
+	}
+	if !strings.Contains(text, "\n…\nShowing") {
+		t.Errorf("formatTable() does not mark the table as cut: %q", text[len(text)-100:])
+	}
+}
+
+// fakeAPI answers getUpdates with updates once, then with none, and records
+// the texts sent by chat.
+type fakeAPI struct {
+	updates []update
+
+	mu   sync.Mutex
+	sent map[int64][]string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params map[string]interface{}
+	json.NewDecoder(r.Body).Decode(&params)
+	var result interface{} = true
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+		f.mu.Lock()
+		updates := f.updates
+		f.updates = nil
+		f.mu.Unlock()
+		if updates == nil {
+			updates = []update{}
+			time.Sleep(10 * time.Millisecond)
+		}
+		result = updates
+	case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+		f.mu.Lock()
+		chatID := int64(params["chat_id"].(float64))
+		f.sent[chatID] = append(f.sent[chatID], params["text"].(string))
+		f.mu.Unlock()
+	}
+	out, _ := json.Marshal(result)
+	json.NewEncoder(w).Encode(apiResponse{OK: true, Result: out})
+}
+
+func TestRunAllowList(t *testing.T) {
+	api := &fakeAPI{
+		updates: []update{
+			{UpdateID: 1, Message: &message{MessageID: 1, Chat: chat{ID: 42}, Text: "/start"}},
+			{UpdateID: 2, Message: &message{MessageID: 2, Chat: chat{ID: 666}, Text: "/start"}},
+		},
+		sent: map[int64][]string{},
+	}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	b, err := New(Config{Token: "token", AllowedChats: []int64{42}, App: &app.App{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.apiURL = srv.URL + "/bot"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Run(ctx) }()
+	deadline := time.After(5 * time.Second)
+	for {
+		api.mu.Lock()
+		n := len(api.sent[42])
+		api.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("no reply to the allowed chat")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(api.sent[42]) != 1 || len(api.sent[666]) != 0 {
+		t.Errorf("replies = %v, want one to chat 42 and none to chat 666", api.sent)
+	}
+}
+
+func TestRunStopsWhileRetrying(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(apiResponse{OK: false, Description: "Unauthorized"})
+	}))
+	defer srv.Close()
+
+	b, err := New(Config{Token: "token", AllowedChats: []int64{42}, App: &app.App{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.apiURL = srv.URL + "/bot"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- b.Run(ctx) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return while waiting to retry polling")
+	}
+}
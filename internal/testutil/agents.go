@@ -0,0 +1,70 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"google.golang.org/adk/model"
+)
+
+// NewApp wires the Manager, SQL and Chart agents to llm and db the way the
+// command does, and returns an App running them.
+func NewApp(t testing.TB, llm model.LLM, db sqlagent.MCPClient) *app.App {
+	t.Helper()
+	return newApp(t, newManager(t, llm, db, false), db)
+}
+
+// NewWriteApp is NewApp in write mode: the SQL agent may propose writes,
+// which db executes once they are approved.
+func NewWriteApp(t testing.TB, llm model.LLM, db sqlagent.MCPClient) *app.App {
+	t.Helper()
+	return newApp(t, newManager(t, llm, db, true), db)
+}
+
+func newApp(t testing.TB, mgr *manager.Agent, db sqlagent.MCPClient) *app.App {
+	t.Helper()
+	a, err := app.New(app.Config{Manager: mgr, DB: db})
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	return a
+}
+
+// NewManager wires the SQL and Chart agents to llm and db under a Manager,
+// for tests that configure the App themselves.
+func NewManager(t testing.TB, llm model.LLM, db sqlagent.MCPClient) *manager.Agent {
+	t.Helper()
+	return newManager(t, llm, db, false)
+}
+
+func newManager(t testing.TB, llm model.LLM, db sqlagent.MCPClient, write bool) *manager.Agent {
+	t.Helper()
+
+	tools, err := sqlagent.CreateMCPTools(db)
+	if err != nil {
+		t.Fatalf("failed to create SQL tools: %v", err)
+	}
+	if write {
+		writeTools, err := sqlagent.CreateWriteTools()
+		if err != nil {
+			t.Fatalf("failed to create write tools: %v", err)
+		}
+		tools = append(tools, writeTools...)
+	}
+	sqlAgent, err := sqlagent.New(sqlagent.Config{Model: llm, Tools: tools, WriteMode: write})
+	if err != nil {
+		t.Fatalf("failed to create SQL agent: %v", err)
+	}
+	chartAgent, err := chart.New(chart.Config{Model: llm})
+	if err != nil {
+		t.Fatalf("failed to create Chart agent: %v", err)
+	}
+	mgr, err := manager.New(manager.Config{Model: llm, SQLAgent: sqlAgent, ChartAgent: chartAgent})
+	if err != nil {
+		t.Fatalf("failed to create Manager agent: %v", err)
+	}
+	return mgr
+}
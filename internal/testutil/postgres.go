@@ -0,0 +1,120 @@
+// Package testutil provides the fixtures shared by integration tests: a
+// disposable PostgreSQL database seeded with a sample schema, and the agent
+// hierarchy wired to a model of the test's choosing.
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// PostgresImage is the image started when TEST_DATABASE_URL is not set.
+const PostgresImage = "postgres:16"
+
+// startupTimeout bounds how long the container may take to accept connections.
+const startupTimeout = 90 * time.Second
+
+// Schema is the sample store schema loaded by Seed: customers, products
+// and ten orders placed between January and March 2024.
+//
+//go:embed schema.sql
+var Schema string
+
+// StartPostgres returns the URL of an empty PostgreSQL database. It uses
+// TEST_DATABASE_URL if set, and otherwise starts a throwaway container with
+// Docker that is removed when the test ends. The test is skipped in -short
+// mode or when neither is available.
+func StartPostgres(t testing.TB) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		return url
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("skipping integration test: set TEST_DATABASE_URL or install Docker")
+	}
+
+	const password = "multi-agent"
+	out, err := exec.Command("docker", "run", "--detach", "--rm",
+		"--env", "POSTGRES_PASSWORD="+password,
+		"--publish", "127.0.0.1::5432",
+		PostgresImage).Output()
+	if err != nil {
+		t.Fatalf("failed to start postgres container: %v", commandError(err))
+	}
+	id := strings.TrimSpace(string(out))
+	t.Cleanup(func() {
+		exec.Command("docker", "rm", "--force", id).Run()
+	})
+
+	out, err = exec.Command("docker", "port", id, "5432/tcp").Output()
+	if err != nil {
+		t.Fatalf("failed to read postgres port: %v", commandError(err))
+	}
+	// docker port prints one line per address, e.g. "127.0.0.1:55012"
+	addr, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	url := fmt.Sprintf("postgres://postgres:%s@%s/postgres?sslmode=disable", password, addr)
+
+	if err := waitForPostgres(url, startupTimeout); err != nil {
+		t.Fatalf("postgres container %s did not become ready: %v", id, err)
+	}
+	return url
+}
+
+// Seed loads Schema into the database, replacing the sample tables if they
+// already exist.
+func Seed(t testing.TB, url string) {
+	t.Helper()
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(Schema); err != nil {
+		t.Fatalf("failed to seed database: %v", err)
+	}
+}
+
+// waitForPostgres pings url until it answers or timeout elapses. The server
+// only listens on TCP once the image's initialization has finished.
+func waitForPostgres(url string, timeout time.Duration) error {
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for {
+		err = db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// commandError includes the stderr of a failed command in the error.
+func commandError(err error) error {
+	if exit, ok := err.(*exec.ExitError); ok && len(exit.Stderr) > 0 {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exit.Stderr)))
+	}
+	return err
+}
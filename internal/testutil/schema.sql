@@ -0,0 +1,46 @@
+-- Sample store schema seeded by testutil.Seed.
+DROP TABLE IF EXISTS orders, products, customers;
+
+CREATE TABLE customers (
+    id     integer PRIMARY KEY,
+    name   text NOT NULL,
+    region text NOT NULL
+);
+
+CREATE TABLE products (
+    id       integer PRIMARY KEY,
+    name     text NOT NULL,
+    category text NOT NULL,
+    price    numeric(10, 2) NOT NULL
+);
+
+CREATE TABLE orders (
+    id          integer PRIMARY KEY,
+    customer_id integer NOT NULL REFERENCES customers (id),
+    product_id  integer NOT NULL REFERENCES products (id),
+    quantity    integer NOT NULL,
+    amount      numeric(10, 2) NOT NULL,
+    order_date  date NOT NULL
+);
+
+INSERT INTO customers VALUES
+    (1, 'Acme Corp', 'EMEA'),
+    (2, 'Globex', 'AMER'),
+    (3, 'Initech', 'APAC');
+
+INSERT INTO products VALUES
+    (1, 'Widget', 'Hardware', 10.00),
+    (2, 'Gadget', 'Hardware', 25.00),
+    (3, 'Support Plan', 'Services', 100.00);
+
+INSERT INTO orders VALUES
+    (1, 1, 1, 5, 50.00, '2024-01-05'),
+    (2, 2, 2, 2, 50.00, '2024-01-17'),
+    (3, 3, 3, 1, 100.00, '2024-01-28'),
+    (4, 1, 2, 4, 100.00, '2024-02-03'),
+    (5, 2, 1, 10, 100.00, '2024-02-14'),
+    (6, 3, 1, 3, 30.00, '2024-02-21'),
+    (7, 1, 3, 2, 200.00, '2024-03-02'),
+    (8, 2, 3, 1, 100.00, '2024-03-09'),
+    (9, 3, 2, 6, 150.00, '2024-03-15'),
+    (10, 1, 1, 8, 80.00, '2024-03-30');
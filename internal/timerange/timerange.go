@@ -0,0 +1,186 @@
+// Package timerange resolves relative time expressions such as "last
+// quarter", "past 30 days" or "YTD" into concrete date ranges, so
+// time-filtered SQL does not depend on the model's idea of today.
+package timerange
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format of the dates in a Range's SQL.
+const DateLayout = "2006-01-02"
+
+// Range is a span of whole days from Start, inclusive, to End, exclusive.
+type Range struct {
+	Start time.Time
+	End   time.Time
+	// Label names the range, e.g. "Q2 2026"
+	Label string
+}
+
+// Last is the last day of the range.
+func (r Range) Last() time.Time {
+	return r.End.AddDate(0, 0, -1)
+}
+
+// String describes the range, e.g. "Q2 2026 (2026-04-01 to 2026-06-30)".
+func (r Range) String() string {
+	return fmt.Sprintf("%s (%s to %s)", r.Label, r.Start.Format(DateLayout), r.Last().Format(DateLayout))
+}
+
+// Filter is the WHERE condition selecting the range on column, e.g.
+// "order_date >= '2026-04-01' AND order_date < '2026-07-01'".
+func (r Range) Filter(column string) string {
+	return fmt.Sprintf("%s >= '%s' AND %s < '%s'", column, r.Start.Format(DateLayout), column, r.End.Format(DateLayout))
+}
+
+// Supported lists examples of the expressions Resolve understands.
+var Supported = []string{
+	"today", "yesterday", "this week", "last month", "next quarter", "this year",
+	"past 30 days", "last 6 months", "YTD", "MTD", "QTD", "WTD",
+	"Q3 2024", "Q1", "March 2024", "2024", "2024-03-15", "since 2024-01-01",
+}
+
+var (
+	rollingRe = regexp.MustCompile(`^(?:last|past|previous|prior|trailing) (\d+) (day|week|month|quarter|year)s?$`)
+	periodRe  = regexp.MustCompile(`^(this|current|last|previous|prior|next) (day|week|month|quarter|year)$`)
+	quarterRe = regexp.MustCompile(`^q([1-4])(?: (\d{4}))?$|^(\d{4}) q([1-4])$`)
+	yearRe    = regexp.MustCompile(`^\d{4}$`)
+	sinceRe   = regexp.MustCompile(`^(?:since|from) (.+)$`)
+)
+
+var toDate = map[string]string{
+	"ytd": "year", "year to date": "year",
+	"qtd": "quarter", "quarter to date": "quarter",
+	"mtd": "month", "month to date": "month",
+	"wtd": "week", "week to date": "week",
+}
+
+// Resolve turns expr into the range it means on the day of now, in now's
+// location. Weeks start on Monday. Rolling ranges such as "past 30 days"
+// and to-date ranges such as "YTD" end with today.
+func Resolve(expr string, now time.Time) (Range, error) {
+	e := strings.Join(strings.Fields(strings.ToLower(expr)), " ")
+	e = strings.TrimPrefix(e, "the ")
+	today := day(now)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	switch e {
+	case "today":
+		return Range{today, tomorrow, "today"}, nil
+	case "yesterday":
+		return Range{today.AddDate(0, 0, -1), today, "yesterday"}, nil
+	case "tomorrow":
+		return Range{tomorrow, tomorrow.AddDate(0, 0, 1), "tomorrow"}, nil
+	}
+
+	if unit, ok := toDate[e]; ok {
+		start := periodStart(today, unit)
+		return Range{start, tomorrow, unit + " to date"}, nil
+	}
+
+	if m := rollingRe.FindStringSubmatch(e); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > 10000 {
+			return Range{}, fmt.Errorf("invalid count in %q", expr)
+		}
+		label := fmt.Sprintf("past %d %ss", n, m[2])
+		if n == 1 {
+			label = "past " + m[2]
+		}
+		return Range{add(tomorrow, m[2], -n), tomorrow, label}, nil
+	}
+
+	if m := periodRe.FindStringSubmatch(e); m != nil {
+		start := periodStart(today, m[2])
+		label := "this " + m[2]
+		switch m[1] {
+		case "last", "previous", "prior":
+			start = add(start, m[2], -1)
+			label = "last " + m[2]
+		case "next":
+			start = add(start, m[2], 1)
+			label = "next " + m[2]
+		}
+		return Range{start, add(start, m[2], 1), label}, nil
+	}
+
+	if m := quarterRe.FindStringSubmatch(e); m != nil {
+		q, year := m[1], m[2]
+		if q == "" {
+			q, year = m[4], m[3]
+		}
+		y := today.Year()
+		if year != "" {
+			y, _ = strconv.Atoi(year)
+		}
+		n, _ := strconv.Atoi(q)
+		start := time.Date(y, time.Month(3*n-2), 1, 0, 0, 0, 0, now.Location())
+		return Range{start, start.AddDate(0, 3, 0), fmt.Sprintf("Q%d %d", n, y)}, nil
+	}
+
+	if yearRe.MatchString(e) {
+		y, _ := strconv.Atoi(e)
+		start := time.Date(y, 1, 1, 0, 0, 0, 0, now.Location())
+		return Range{start, start.AddDate(1, 0, 0), e}, nil
+	}
+
+	if m := sinceRe.FindStringSubmatch(e); m != nil {
+		r, err := Resolve(m[1], now)
+		if err != nil {
+			return Range{}, err
+		}
+		return Range{r.Start, tomorrow, "since " + r.Start.Format(DateLayout)}, nil
+	}
+
+	if t, err := time.ParseInLocation(DateLayout, e, now.Location()); err == nil {
+		return Range{t, t.AddDate(0, 0, 1), e}, nil
+	}
+	for _, layout := range []string{"January 2006", "Jan 2006"} {
+		if t, err := time.ParseInLocation(layout, e, now.Location()); err == nil {
+			return Range{t, t.AddDate(0, 1, 0), t.Format("January 2006")}, nil
+		}
+	}
+
+	return Range{}, fmt.Errorf("unrecognized time range %q; try e.g. %s", expr, strings.Join(Supported[:8], ", "))
+}
+
+// day is midnight at the start of t's day.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// periodStart is the first day of the day, week, month, quarter or year
+// holding today.
+func periodStart(today time.Time, unit string) time.Time {
+	switch unit {
+	case "week":
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	case "month":
+		return today.AddDate(0, 0, 1-today.Day())
+	case "quarter":
+		return time.Date(today.Year(), (today.Month()-1)/3*3+1, 1, 0, 0, 0, 0, today.Location())
+	case "year":
+		return time.Date(today.Year(), 1, 1, 0, 0, 0, 0, today.Location())
+	}
+	return today
+}
+
+// add moves t by n units.
+func add(t time.Time, unit string, n int) time.Time {
+	switch unit {
+	case "week":
+		return t.AddDate(0, 0, 7*n)
+	case "month":
+		return t.AddDate(0, n, 0)
+	case "quarter":
+		return t.AddDate(0, 3*n, 0)
+	case "year":
+		return t.AddDate(n, 0, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
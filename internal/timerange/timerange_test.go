@@ -0,0 +1,77 @@
+package timerange
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	// A Friday in the second quarter
+	now := time.Date(2026, 5, 15, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr        string
+		start, last string
+		label       string
+		wantErr     bool
+	}{
+		{expr: "today", start: "2026-05-15", last: "2026-05-15", label: "today"},
+		{expr: "Yesterday", start: "2026-05-14", last: "2026-05-14", label: "yesterday"},
+		{expr: "this week", start: "2026-05-11", last: "2026-05-17", label: "this week"},
+		{expr: "last week", start: "2026-05-04", last: "2026-05-10", label: "last week"},
+		{expr: "last month", start: "2026-04-01", last: "2026-04-30", label: "last month"},
+		{expr: "the previous quarter", start: "2026-01-01", last: "2026-03-31", label: "last quarter"},
+		{expr: "next quarter", start: "2026-07-01", last: "2026-09-30", label: "next quarter"},
+		{expr: "last year", start: "2025-01-01", last: "2025-12-31", label: "last year"},
+		{expr: "past 30 days", start: "2026-04-16", last: "2026-05-15", label: "past 30 days"},
+		{expr: "last  6 months", start: "2025-11-16", last: "2026-05-15", label: "past 6 months"},
+		{expr: "past 1 year", start: "2025-05-16", last: "2026-05-15", label: "past year"},
+		{expr: "YTD", start: "2026-01-01", last: "2026-05-15", label: "year to date"},
+		{expr: "quarter to date", start: "2026-04-01", last: "2026-05-15", label: "quarter to date"},
+		{expr: "MTD", start: "2026-05-01", last: "2026-05-15", label: "month to date"},
+		{expr: "Q3 2024", start: "2024-07-01", last: "2024-09-30", label: "Q3 2024"},
+		{expr: "2024 q1", start: "2024-01-01", last: "2024-03-31", label: "Q1 2024"},
+		{expr: "q4", start: "2026-10-01", last: "2026-12-31", label: "Q4 2026"},
+		{expr: "2024", start: "2024-01-01", last: "2024-12-31", label: "2024"},
+		{expr: "February 2024", start: "2024-02-01", last: "2024-02-29", label: "February 2024"},
+		{expr: "mar 2025", start: "2025-03-01", last: "2025-03-31", label: "March 2025"},
+		{expr: "2024-03-15", start: "2024-03-15", last: "2024-03-15", label: "2024-03-15"},
+		{expr: "since Q1 2026", start: "2026-01-01", last: "2026-05-15", label: "since 2026-01-01"},
+		{expr: "a while ago", wantErr: true},
+		{expr: "past 0 days", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			r, err := Resolve(tt.expr, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := r.Start.Format(DateLayout); got != tt.start {
+				t.Errorf("start = %s, want %s", got, tt.start)
+			}
+			if got := r.Last().Format(DateLayout); got != tt.last {
+				t.Errorf("last = %s, want %s", got, tt.last)
+			}
+			if r.Label != tt.label {
+				t.Errorf("label = %q, want %q", r.Label, tt.label)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	r, err := Resolve("last quarter", time.Date(2026, 5, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Filter("order_date"), "order_date >= '2026-01-01' AND order_date < '2026-04-01'"; got != want {
+		t.Errorf("Filter = %q, want %q", got, want)
+	}
+	if got, want := r.String(), "last quarter (2026-01-01 to 2026-03-31)"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+}
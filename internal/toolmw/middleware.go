@@ -0,0 +1,97 @@
+package toolmw
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/secrets"
+	"google.golang.org/adk/tool"
+)
+
+// AnyTool is the key of RateLimit limits that applies to tools without a
+// limit of their own.
+const AnyTool = "*"
+
+// now is replaced in tests.
+var now = time.Now
+
+// Logging logs every call with its agent, tool, duration and outcome.
+func Logging() Middleware {
+	return func(t tool.Tool, next Handler) Handler {
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			start := now()
+			result, err := next(ctx, args)
+			status := "ok"
+			if msg := toolError(result, err); msg != "" {
+				status = fmt.Sprintf("error error=%q", secrets.Redact(msg))
+			}
+			log.Printf("🔧 [TOOL] agent=%s tool=%s call=%s duration_ms=%d status=%s",
+				ctx.AgentName(), t.Name(), ctx.FunctionCallID(), now().Sub(start).Milliseconds(), status)
+			return result, err
+		}
+	}
+}
+
+// Timing publishes a ToolCompleted event with the duration and outcome of
+// every call, from which Metrics derives per-tool latencies.
+func Timing() Middleware {
+	return func(t tool.Tool, next Handler) Handler {
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			start := now()
+			result, err := next(ctx, args)
+			events.Publish(ctx, &events.ToolCompleted{
+				Agent:      ctx.AgentName(),
+				Tool:       t.Name(),
+				DurationMS: now().Sub(start).Milliseconds(),
+				Error:      toolError(result, err),
+			})
+			return result, err
+		}
+	}
+}
+
+// RateLimit allows each tool at most limits[name] calls per minute, or
+// limits[AnyTool] for tools without their own limit, counted across all
+// sessions. Calls over the limit are not run; the model is told how long to
+// wait instead.
+func RateLimit(limits map[string]int) Middleware {
+	var mu sync.Mutex
+	calls := make(map[string][]time.Time)
+
+	return func(t tool.Tool, next Handler) Handler {
+		limit, ok := limits[t.Name()]
+		if !ok {
+			limit = limits[AnyTool]
+		}
+		if limit <= 0 {
+			return next
+		}
+		name := t.Name()
+
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			mu.Lock()
+			// Forget calls older than the one-minute window
+			cutoff := now().Add(-time.Minute)
+			recent := calls[name]
+			for len(recent) > 0 && !recent[0].After(cutoff) {
+				recent = recent[1:]
+			}
+			if len(recent) >= limit {
+				wait := recent[0].Sub(cutoff)
+				calls[name] = recent
+				mu.Unlock()
+				return map[string]any{
+					"error": fmt.Sprintf("rate limit reached: %s may be called %d times per minute; try again in %d seconds", name, limit, int(math.Ceil(wait.Seconds()))),
+				}, nil
+			}
+			calls[name] = append(recent, now())
+			mu.Unlock()
+
+			return next(ctx, args)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package toolmw wraps agent tools in middleware, so behavior every tool
+// needs, such as logging, latency metrics and rate limits, is written once
+// and applied uniformly instead of inside each tool's handler.
+package toolmw
+
+import (
+	"fmt"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// Handler runs a tool call with the arguments the model sent and returns the
+// result passed back to it.
+type Handler func(ctx tool.Context, args map[string]any) (map[string]any, error)
+
+// Middleware wraps the handler of tool t. It may inspect or change the
+// arguments and result, or answer without calling next.
+type Middleware func(t tool.Tool, next Handler) Handler
+
+// functionTool is the interface ADK runs tools through; tools created with
+// functiontool implement it.
+type functionTool interface {
+	tool.Tool
+	Declaration() *genai.FunctionDeclaration
+	Run(ctx tool.Context, args any) (map[string]any, error)
+	ProcessRequest(ctx tool.Context, req *model.LLMRequest) error
+}
+
+// Wrap returns tools whose calls run through mw, the first middleware being
+// the outermost. Tools that ADK does not run as function tools are returned
+// unchanged.
+func Wrap(tools []tool.Tool, mw ...Middleware) []tool.Tool {
+	wrapped := make([]tool.Tool, len(tools))
+	for i, t := range tools {
+		ft, ok := t.(functionTool)
+		if !ok || len(mw) == 0 {
+			wrapped[i] = t
+			continue
+		}
+		w := &wrappedTool{functionTool: ft}
+		h := w.runInner
+		for j := len(mw) - 1; j >= 0; j-- {
+			h = mw[j](ft, h)
+		}
+		w.handler = h
+		wrapped[i] = w
+	}
+	return wrapped
+}
+
+// wrappedTool runs a function tool through its middleware.
+type wrappedTool struct {
+	functionTool
+	handler Handler
+}
+
+// Run implements ADK's function tool interface.
+func (w *wrappedTool) Run(ctx tool.Context, args any) (map[string]any, error) {
+	m, ok := args.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected args type, got: %T", args)
+	}
+	return w.handler(ctx, m)
+}
+
+// ProcessRequest adds the tool to the request. The wrapped tool registers
+// itself, so the registration is replaced to route calls through w.
+func (w *wrappedTool) ProcessRequest(ctx tool.Context, req *model.LLMRequest) error {
+	if err := w.functionTool.ProcessRequest(ctx, req); err != nil {
+		return err
+	}
+	req.Tools[w.Name()] = w
+	return nil
+}
+
+func (w *wrappedTool) runInner(ctx tool.Context, args map[string]any) (map[string]any, error) {
+	return w.functionTool.Run(ctx, args)
+}
+
+// toolError returns the error a call failed with: err, or the error the
+// tool reported in its result.
+func toolError(result map[string]any, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	msg, _ := result["error"].(string)
+	return msg
+}
@@ -0,0 +1,158 @@
+package toolmw
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// fakeContext is the part of a tool.Context the middleware uses.
+type fakeContext struct {
+	tool.Context
+	ctx context.Context
+}
+
+func (c fakeContext) Value(key any) any           { return c.ctx.Value(key) }
+func (c fakeContext) AgentName() string           { return "SQLAgent" }
+func (c fakeContext) FunctionCallID() string      { return "call_1" }
+func (c fakeContext) Done() <-chan struct{}       { return c.ctx.Done() }
+func (c fakeContext) Err() error                  { return c.ctx.Err() }
+func (c fakeContext) Deadline() (time.Time, bool) { return c.ctx.Deadline() }
+
+type echoArgs struct {
+	Text string `json:"text"`
+}
+
+type echoResult struct {
+	Text  string `json:"text,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func newEcho(t *testing.T, name string) tool.Tool {
+	t.Helper()
+	echo, err := functiontool.New(functiontool.Config{Name: name}, func(ctx tool.Context, args echoArgs) (echoResult, error) {
+		if args.Text == "" {
+			return echoResult{Error: "nothing to echo"}, nil
+		}
+		return echoResult{Text: args.Text}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return echo
+}
+
+func run(t *testing.T, ctx tool.Context, tl tool.Tool, args map[string]any) map[string]any {
+	t.Helper()
+	result, err := tl.(functionTool).Run(ctx, args)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return result
+}
+
+func TestWrap(t *testing.T) {
+	var calls []string
+	trace := func(label string) Middleware {
+		return func(tl tool.Tool, next Handler) Handler {
+			return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+				calls = append(calls, label+" "+tl.Name())
+				return next(ctx, args)
+			}
+		}
+	}
+	shout := func(tl tool.Tool, next Handler) Handler {
+		return func(ctx tool.Context, args map[string]any) (map[string]any, error) {
+			result, err := next(ctx, args)
+			if s, ok := result["text"].(string); ok {
+				result["text"] = strings.ToUpper(s)
+			}
+			return result, err
+		}
+	}
+
+	tools := Wrap([]tool.Tool{newEcho(t, "echo")}, trace("outer"), shout, trace("inner"))
+	ctx := fakeContext{ctx: context.Background()}
+
+	if got := run(t, ctx, tools[0], map[string]any{"text": "hi"}); got["text"] != "HI" {
+		t.Errorf("result = %v, want the middleware's change", got)
+	}
+	if strings.Join(calls, ", ") != "outer echo, inner echo" {
+		t.Errorf("calls = %v, want outer before inner", calls)
+	}
+
+	// Calls the model makes must reach the wrapper, not the wrapped tool
+	req := &model.LLMRequest{}
+	if err := tools[0].(functionTool).ProcessRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Tools["echo"] != tools[0] {
+		t.Errorf("request tool = %T, want the wrapper", req.Tools["echo"])
+	}
+	if decls := req.Config.Tools[0].FunctionDeclarations; len(decls) != 1 || decls[0].Name != "echo" {
+		t.Errorf("declarations = %v, want echo", decls)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	tools := Wrap([]tool.Tool{newEcho(t, "query_database"), newEcho(t, "list_tables")}, RateLimit(map[string]int{"query_database": 2, AnyTool: 1}))
+	ctx := fakeContext{ctx: context.Background()}
+	args := map[string]any{"text": "hi"}
+
+	steps := []struct {
+		advance time.Duration
+		tool    int
+		wantErr string
+	}{
+		{tool: 0},
+		{advance: 20 * time.Second, tool: 0},
+		{advance: 10 * time.Second, tool: 0, wantErr: "try again in 30 seconds"},
+		{tool: 1},
+		{tool: 1, wantErr: "list_tables may be called 1 times per minute"},
+		{advance: 31 * time.Second, tool: 0},
+		{tool: 0, wantErr: "try again in 19 seconds"},
+	}
+	for i, s := range steps {
+		clock = clock.Add(s.advance)
+		result := run(t, ctx, tools[s.tool], args)
+		msg, _ := result["error"].(string)
+		if s.wantErr == "" && msg != "" || !strings.Contains(msg, s.wantErr) {
+			t.Errorf("step %d: error = %q, want %q", i, msg, s.wantErr)
+		}
+	}
+}
+
+func TestTiming(t *testing.T) {
+	bus := events.New()
+	metrics := events.NewMetrics()
+	bus.Subscribe(metrics.Handle)
+	var completed []*events.ToolCompleted
+	bus.Subscribe(func(e events.Event) {
+		if c, ok := e.(*events.ToolCompleted); ok {
+			completed = append(completed, c)
+		}
+	})
+
+	tools := Wrap([]tool.Tool{newEcho(t, "echo")}, Timing())
+	ctx := fakeContext{ctx: events.NewContext(context.Background(), bus, "u", "s")}
+	run(t, ctx, tools[0], map[string]any{"text": "hi"})
+	run(t, ctx, tools[0], map[string]any{"text": ""})
+
+	if len(completed) != 2 || completed[0].Agent != "SQLAgent" || completed[0].Error != "" || completed[1].Error != "nothing to echo" {
+		t.Fatalf("completed = %+v, want a success and a reported error", completed)
+	}
+	snap := metrics.Snapshot()
+	if _, ok := snap.AvgToolMS["echo"]; !ok || snap.FailedTools["echo"] != 1 {
+		t.Errorf("metrics = avg %v, failed %v", snap.AvgToolMS, snap.FailedTools)
+	}
+}
@@ -0,0 +1,153 @@
+// Package toolschema generates the JSON schema of a tool's parameters from
+// its Go argument struct, so every model provider sees the same, complete
+// description of a tool without a hand-written schema to keep in sync.
+//
+// Property names come from json tags, and fields without omitempty are
+// required. A description tag documents a property:
+//
+//	type QueryArgs struct {
+//		SQL   string `json:"sql" description:"The SQL query to execute"`
+//		Limit int    `json:"limit,omitempty" description:"Maximum number of rows"`
+//	}
+package toolschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// For returns the schema of T, which must be a struct.
+func For[T any]() (*jsonschema.Schema, error) {
+	t := reflect.TypeFor[T]()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("tool arguments must be a struct, not %s", t)
+	}
+	return schemaOf(t, map[reflect.Type]bool{})
+}
+
+var (
+	timeType      = reflect.TypeFor[time.Time]()
+	rawJSONType   = reflect.TypeFor[json.RawMessage]()
+	marshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// schemaOf returns the schema of t. seen holds the structs being expanded,
+// which may not contain themselves.
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) (*jsonschema.Schema, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &jsonschema.Schema{Type: "string", Format: "date-time"}, nil
+	case t == rawJSONType, t.Implements(marshalerType):
+		return anyValue(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &jsonschema.Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &jsonschema.Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &jsonschema.Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &jsonschema.Schema{Type: "number"}, nil
+	case reflect.Interface:
+		return anyValue(), nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaOf(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := schemaOf(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonschema.Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		return structSchema(t, seen)
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// anyValue accepts any JSON value. It lists the types rather than being
+// the empty schema, which marshals to true and which some servers reject.
+func anyValue() *jsonschema.Schema {
+	return &jsonschema.Schema{Types: []string{"string", "number", "integer", "boolean", "object", "array", "null"}}
+}
+
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) (*jsonschema.Schema, error) {
+	if seen[t] {
+		return nil, fmt.Errorf("recursive type %s", t)
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	s := &jsonschema.Schema{Type: "object", Properties: map[string]*jsonschema.Schema{}}
+	if err := addFields(s, t, seen); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// addFields adds the properties of struct t to s. Embedded structs without
+// a json name are flattened, as encoding/json does.
+func addFields(s *jsonschema.Schema, t reflect.Type, seen map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := addFields(s, embedded, seen); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		prop, err := schemaOf(f.Type, seen)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		prop.Description = f.Tag.Get("description")
+		s.Properties[name] = prop
+
+		optional := f.Type.Kind() == reflect.Pointer
+		for _, o := range strings.Split(opts, ",") {
+			optional = optional || o == "omitempty" || o == "omitzero"
+		}
+		if !optional {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
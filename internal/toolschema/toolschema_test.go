@@ -0,0 +1,97 @@
+package toolschema
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type filter struct {
+	Column string `json:"column" description:"Column to filter on"`
+	Value  any    `json:"value"`
+}
+
+type page struct {
+	Offset int `json:"offset,omitempty"`
+}
+
+type searchArgs struct {
+	page
+	Query   string            `json:"query" description:"What to search for"`
+	Limit   int               `json:"limit,omitempty"`
+	Exact   *bool             `json:"exact"`
+	Tables  []string          `json:"tables,omitempty"`
+	Filters []filter          `json:"filters,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Since   time.Time         `json:"since,omitzero"`
+	Score   float64           `json:"score"`
+	Debug   bool              `json:"-"`
+	secret  string
+}
+
+type emptyArgs struct{}
+
+type node struct {
+	Children []node `json:"children"`
+}
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  func() (any, error)
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "struct",
+			schema: func() (any, error) { return For[searchArgs]() },
+			want: `{"type":"object","required":["query","score"],"properties":{` +
+				`"exact":{"type":"boolean"},` +
+				`"filters":{"type":"array","items":{"type":"object","required":["column","value"],"properties":{"column":{"type":"string","description":"Column to filter on"},"value":{"type":["string","number","integer","boolean","object","array","null"]}}}},` +
+				`"labels":{"type":"object","additionalProperties":{"type":"string"}},` +
+				`"limit":{"type":"integer"},` +
+				`"offset":{"type":"integer"},` +
+				`"query":{"type":"string","description":"What to search for"},` +
+				`"score":{"type":"number"},` +
+				`"since":{"type":"string","format":"date-time"},` +
+				`"tables":{"type":"array","items":{"type":"string"}}}}`,
+		},
+		{
+			name:   "no parameters",
+			schema: func() (any, error) { return For[emptyArgs]() },
+			want:   `{"type":"object"}`,
+		},
+		{
+			name:    "not a struct",
+			schema:  func() (any, error) { return For[string]() },
+			wantErr: true,
+		},
+		{
+			name:    "recursive",
+			schema:  func() (any, error) { return For[node]() },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := tt.schema()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("For() = %v, want an error", schema)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("For() error = %v", err)
+			}
+			got, err := json.Marshal(schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("For() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package usage
+
+import (
+	"context"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/governance"
+)
+
+// Client wraps an MCPClient and adds every executed query to the usage of
+// the session's user.
+type Client struct {
+	sqlagent.MCPClient
+	tracker *Tracker
+}
+
+// NewClient creates a client that tracks queries sent to inner.
+func NewClient(inner sqlagent.MCPClient, tracker *Tracker) *Client {
+	return &Client{MCPClient: inner, tracker: tracker}
+}
+
+// Query executes the query and records it. Failed queries are not counted.
+func (c *Client) Query(ctx context.Context, query string, limit int) (string, error) {
+	data, err := c.MCPClient.Query(ctx, query, limit)
+	if err != nil {
+		return data, err
+	}
+
+	userID, _, _ := sqlagent.SessionFrom(ctx)
+	c.tracker.RecordQuery(userID, sqlagent.ResultRows(ctx, data), governance.ReferencedTables(query))
+	return data, nil
+}
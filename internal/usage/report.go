@@ -0,0 +1,192 @@
+package usage
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Groupings of a Report.
+const (
+	ByUser = "user"
+	ByTeam = "team"
+)
+
+// Unassigned is the team of users that are not listed in any team.
+const Unassigned = "(unassigned)"
+
+// TopTables is the number of most-queried tables listed per summary.
+const TopTables = 5
+
+// TableCount is the number of queries that read a table.
+type TableCount struct {
+	Table   string `json:"table"`
+	Queries int    `json:"queries"`
+}
+
+// Summary is the usage of a user, a team or everyone over a report's period.
+type Summary struct {
+	Name             string       `json:"name,omitempty"`
+	Users            int          `json:"users"`
+	Questions        int          `json:"questions"`
+	PromptTokens     int          `json:"prompt_tokens"`
+	CompletionTokens int          `json:"completion_tokens"`
+	Queries          int          `json:"queries"`
+	Rows             int          `json:"rows"`
+	TopTables        []TableCount `json:"top_tables,omitempty"`
+}
+
+// Day is the usage of everyone on a day.
+type Day struct {
+	Date string `json:"date"`
+	// Users is the number of active users
+	Users            int `json:"users"`
+	Questions        int `json:"questions"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	Queries          int `json:"queries"`
+	Rows             int `json:"rows"`
+}
+
+// Report summarizes the usage of the last days, per user or team.
+type Report struct {
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	GroupBy string    `json:"group_by"`
+	Total   Summary   `json:"total"`
+	Groups  []Summary `json:"groups"`
+	Days    []Day     `json:"days"`
+}
+
+// summary accumulates the stats of a group.
+type summary struct {
+	stats Stats
+	users map[string]bool
+}
+
+func (s *summary) add(userID string, stats *Stats) {
+	if s.users == nil {
+		s.users = make(map[string]bool)
+	}
+	s.users[userID] = true
+	s.stats.add(stats)
+}
+
+func (s *summary) summary(name string) Summary {
+	out := Summary{
+		Name:             name,
+		Users:            len(s.users),
+		Questions:        s.stats.Questions,
+		PromptTokens:     s.stats.PromptTokens,
+		CompletionTokens: s.stats.CompletionTokens,
+		Queries:          s.stats.Queries,
+		Rows:             s.stats.Rows,
+	}
+	for table, n := range s.stats.Tables {
+		out.TopTables = append(out.TopTables, TableCount{Table: table, Queries: n})
+	}
+	slices.SortFunc(out.TopTables, func(a, b TableCount) int {
+		return cmp.Or(cmp.Compare(b.Queries, a.Queries), cmp.Compare(a.Table, b.Table))
+	})
+	if len(out.TopTables) > TopTables {
+		out.TopTables = out.TopTables[:TopTables]
+	}
+	return out
+}
+
+// Report summarizes the usage of the last days (including today), grouped
+// by ByUser or ByTeam. Groups are ordered by questions asked.
+func (t *Tracker) Report(days int, groupBy string) (*Report, error) {
+	if days <= 0 {
+		return nil, fmt.Errorf("days must be positive")
+	}
+	if groupBy == "" {
+		groupBy = ByUser
+	}
+	if groupBy != ByUser && groupBy != ByTeam {
+		return nil, fmt.Errorf("unknown grouping %q: use %s or %s", groupBy, ByUser, ByTeam)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	today := t.now().UTC()
+	r := &Report{
+		From:    today.AddDate(0, 0, 1-days).Format(dateLayout),
+		To:      today.Format(dateLayout),
+		GroupBy: groupBy,
+	}
+
+	var total summary
+	groups := make(map[string]*summary)
+	for i := days - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i).Format(dateLayout)
+		var day summary
+		for userID, stats := range t.days[date] {
+			day.add(userID, stats)
+			total.add(userID, stats)
+
+			name := userID
+			if groupBy == ByTeam {
+				if name = t.teams[userID]; name == "" {
+					name = Unassigned
+				}
+			}
+			g, ok := groups[name]
+			if !ok {
+				g = &summary{}
+				groups[name] = g
+			}
+			g.add(userID, stats)
+		}
+		r.Days = append(r.Days, Day{
+			Date:             date,
+			Users:            len(day.users),
+			Questions:        day.stats.Questions,
+			PromptTokens:     day.stats.PromptTokens,
+			CompletionTokens: day.stats.CompletionTokens,
+			Queries:          day.stats.Queries,
+			Rows:             day.stats.Rows,
+		})
+	}
+
+	r.Total = total.summary("")
+	r.Groups = make([]Summary, 0, len(groups))
+	for name, g := range groups {
+		r.Groups = append(r.Groups, g.summary(name))
+	}
+	slices.SortFunc(r.Groups, func(a, b Summary) int {
+		return cmp.Or(cmp.Compare(b.Questions, a.Questions), cmp.Compare(a.Name, b.Name))
+	})
+	return r, nil
+}
+
+// WriteText renders a report as plain-text tables.
+func WriteText(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Usage from %s to %s, by %s\n\n", r.From, r.To, r.GroupBy)
+
+	fmt.Fprintf(tw, "%s\tUSERS\tQUESTIONS\tTOKENS\tQUERIES\tROWS\tTOP TABLES\n", strings.ToUpper(r.GroupBy))
+	for _, g := range append(slices.Clip(r.Groups), r.Total) {
+		name := g.Name
+		if name == "" {
+			name = "TOTAL"
+		}
+		tables := make([]string, 0, len(g.TopTables))
+		for _, t := range g.TopTables {
+			tables = append(tables, fmt.Sprintf("%s (%d)", t.Table, t.Queries))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", name, g.Users, g.Questions,
+			g.PromptTokens+g.CompletionTokens, g.Queries, g.Rows, strings.Join(tables, ", "))
+	}
+
+	fmt.Fprintln(tw, "\nDATE\tUSERS\tQUESTIONS\tTOKENS\tQUERIES\tROWS\t")
+	for _, d := range r.Days {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t\n", d.Date, d.Users, d.Questions,
+			d.PromptTokens+d.CompletionTokens, d.Queries, d.Rows)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,188 @@
+// Package usage aggregates per-user usage of the agents (questions, LLM
+// tokens, queries, rows and tables) into daily rollups, so platform owners
+// can see who relies on the deployment and what it costs.
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+)
+
+// dateLayout keys the daily rollups, in UTC.
+const dateLayout = "2006-01-02"
+
+// Stats are the usage totals of a user on a day.
+type Stats struct {
+	Questions        int `json:"questions"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	Queries          int `json:"queries"`
+	Rows             int `json:"rows"`
+	// Tables counts the queries that read each table
+	Tables map[string]int `json:"tables,omitempty"`
+}
+
+func (s *Stats) add(o *Stats) {
+	s.Questions += o.Questions
+	s.PromptTokens += o.PromptTokens
+	s.CompletionTokens += o.CompletionTokens
+	s.Queries += o.Queries
+	s.Rows += o.Rows
+	for table, n := range o.Tables {
+		if s.Tables == nil {
+			s.Tables = make(map[string]int)
+		}
+		s.Tables[table] += n
+	}
+}
+
+// Tracker accumulates usage in memory and persists it to a JSON file.
+type Tracker struct {
+	path string
+	// teams maps user IDs to their team
+	teams map[string]string
+	now   func() time.Time
+
+	mu sync.Mutex
+	// days maps dates to per-user stats
+	days map[string]map[string]*Stats
+	// saveMu orders concurrent saves
+	saveMu sync.Mutex
+}
+
+// Open loads the rollups stored at path, or starts empty if the file does not
+// exist yet. With an empty path usage is kept in memory only. teams lists the
+// user IDs of each team, for reports grouped by team.
+func Open(path string, teams map[string][]string) (*Tracker, error) {
+	t := &Tracker{
+		path:  path,
+		teams: make(map[string]string),
+		now:   time.Now,
+		days:  make(map[string]map[string]*Stats),
+	}
+	for team, users := range teams {
+		for _, user := range users {
+			t.teams[user] = team
+		}
+	}
+	if path == "" {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	if err := json.Unmarshal(data, &t.days); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Team returns the team of a user, or "" if it has none.
+func (t *Tracker) Team(userID string) string {
+	return t.teams[userID]
+}
+
+// stats returns today's stats of a user. The caller holds t.mu.
+func (t *Tracker) stats(userID string) *Stats {
+	date := t.now().UTC().Format(dateLayout)
+	users, ok := t.days[date]
+	if !ok {
+		users = make(map[string]*Stats)
+		t.days[date] = users
+	}
+	s, ok := users[userID]
+	if !ok {
+		s = &Stats{}
+		users[userID] = s
+	}
+	return s
+}
+
+// RecordTurn implements app.UsageRecorder. The rollups are saved after
+// every turn.
+func (t *Tracker) RecordTurn(userID string, question bool, tokens app.TokenUsage) {
+	t.mu.Lock()
+	s := t.stats(userID)
+	if question {
+		s.Questions++
+	}
+	s.PromptTokens += tokens.Prompt
+	s.CompletionTokens += tokens.Completion
+	t.mu.Unlock()
+
+	if err := t.Save(); err != nil {
+		console.Printf("  ⚠️  [USAGE] %v\n", err)
+	}
+}
+
+// RecordQuery adds an executed query, the rows it returned and the tables it
+// read to the user's totals. A table read twice by the query counts once.
+func (t *Tracker) RecordQuery(userID string, rows int, tables []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.stats(userID)
+	s.Queries++
+	s.Rows += rows
+	for i, table := range tables {
+		if slices.Contains(tables[:i], table) {
+			continue
+		}
+		if s.Tables == nil {
+			s.Tables = make(map[string]int)
+		}
+		s.Tables[table]++
+	}
+}
+
+// Save writes the rollups to the usage file, replacing it atomically.
+func (t *Tracker) Save() error {
+	if t.path == "" {
+		return nil
+	}
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+
+	t.mu.Lock()
+	data, err := json.MarshalIndent(t.days, "", "  ")
+	t.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("json error: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), ".usage-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), t.path); err != nil {
+		return fmt.Errorf("failed to write usage file: %w", err)
+	}
+	return nil
+}
+
+// Close saves the rollups.
+func (t *Tracker) Close() error {
+	return t.Save()
+}
@@ -0,0 +1,170 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+)
+
+// fakeClient returns fixed query results.
+type fakeClient struct {
+	sqlagent.MCPClient
+	data string
+	err  error
+}
+
+func (c *fakeClient) Query(ctx context.Context, query string, limit int) (string, error) {
+	return c.data, c.err
+}
+
+func at(date string) func() time.Time {
+	return func() time.Time {
+		t, _ := time.Parse(dateLayout, date)
+		return t.Add(12 * time.Hour)
+	}
+}
+
+func TestReport(t *testing.T) {
+	tracker, err := Open("", map[string][]string{"analytics": {"alice", "bob"}})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	tracker.now = at("2026-03-01")
+	tracker.RecordTurn("alice", true, app.TokenUsage{Prompt: 100, Completion: 10})
+	tracker.RecordQuery("alice", 5, []string{"orders", "customers", "orders"})
+	tracker.now = at("2026-03-02")
+	tracker.RecordTurn("bob", true, app.TokenUsage{Prompt: 50, Completion: 5})
+	tracker.RecordTurn("bob", false, app.TokenUsage{Prompt: 20, Completion: 2})
+	tracker.RecordQuery("bob", 3, []string{"orders"})
+	tracker.RecordTurn("carol", true, app.TokenUsage{})
+	tracker.now = at("2026-03-03")
+
+	tests := []struct {
+		name       string
+		days       int
+		groupBy    string
+		wantGroups map[string]int // name -> questions
+		wantTotal  Summary
+	}{
+		{
+			name:       "by user",
+			days:       7,
+			groupBy:    ByUser,
+			wantGroups: map[string]int{"alice": 1, "bob": 1, "carol": 1},
+			wantTotal:  Summary{Users: 3, Questions: 3, PromptTokens: 170, CompletionTokens: 17, Queries: 2, Rows: 8},
+		},
+		{
+			name:       "by team",
+			days:       7,
+			groupBy:    ByTeam,
+			wantGroups: map[string]int{"analytics": 2, Unassigned: 1},
+			wantTotal:  Summary{Users: 3, Questions: 3, PromptTokens: 170, CompletionTokens: 17, Queries: 2, Rows: 8},
+		},
+		{
+			name:       "window excludes older days",
+			days:       2,
+			groupBy:    ByUser,
+			wantGroups: map[string]int{"bob": 1, "carol": 1},
+			wantTotal:  Summary{Users: 2, Questions: 2, PromptTokens: 70, CompletionTokens: 7, Queries: 1, Rows: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tracker.Report(tt.days, tt.groupBy)
+			if err != nil {
+				t.Fatalf("Report() error = %v", err)
+			}
+			if len(r.Days) != tt.days || r.To != "2026-03-03" {
+				t.Errorf("Report() days = %d to %s, want %d to 2026-03-03", len(r.Days), r.To, tt.days)
+			}
+
+			got := r.Total
+			got.TopTables = nil
+			if !reflect.DeepEqual(got, tt.wantTotal) {
+				t.Errorf("Report() total = %+v, want %+v", got, tt.wantTotal)
+			}
+			if len(r.Groups) != len(tt.wantGroups) {
+				t.Fatalf("Report() groups = %+v, want %v", r.Groups, tt.wantGroups)
+			}
+			for _, g := range r.Groups {
+				if want, ok := tt.wantGroups[g.Name]; !ok || g.Questions != want {
+					t.Errorf("Report() group %s questions = %d, want %d", g.Name, g.Questions, want)
+				}
+			}
+		})
+	}
+
+	r, _ := tracker.Report(7, ByTeam)
+	want := []TableCount{{"orders", 2}, {"customers", 1}}
+	if got := r.Groups[0].TopTables; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Report() top tables = %v, want %v", got, want)
+	}
+
+	if _, err := tracker.Report(7, "region"); err == nil {
+		t.Error("Report() with unknown grouping succeeded")
+	}
+}
+
+func TestTrackerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	tracker, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	tracker.RecordTurn("alice", true, app.TokenUsage{Prompt: 7})
+
+	reopened, err := Open(path, nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	r, err := reopened.Report(1, ByUser)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if r.Total.Questions != 1 || r.Total.PromptTokens != 7 {
+		t.Errorf("reopened total = %+v, want 1 question and 7 prompt tokens", r.Total)
+	}
+
+	var out strings.Builder
+	if err := WriteText(&out, r); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "alice") || !strings.Contains(out.String(), "TOTAL") {
+		t.Errorf("WriteText() = %q, want rows for alice and the total", out.String())
+	}
+}
+
+func TestClientRecordsQueries(t *testing.T) {
+	tests := []struct {
+		name        string
+		inner       *fakeClient
+		wantQueries int
+		wantRows    int
+	}{
+		{"counts rows", &fakeClient{data: `[{"id":1},{"id":2}]`}, 1, 2},
+		{"skips failed queries", &fakeClient{err: errors.New("boom")}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, _ := Open("", nil)
+			c := NewClient(tt.inner, tracker)
+			ctx := sqlagent.WithSession(context.Background(), "alice", "s1")
+			c.Query(ctx, "SELECT * FROM orders", 10)
+
+			r, _ := tracker.Report(1, ByUser)
+			if r.Total.Queries != tt.wantQueries || r.Total.Rows != tt.wantRows {
+				t.Errorf("total = %+v, want %d queries and %d rows", r.Total, tt.wantQueries, tt.wantRows)
+			}
+		})
+	}
+}
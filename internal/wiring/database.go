@@ -0,0 +1,102 @@
+package wiring
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/bigquery"
+	"github.com/anuvratrastogi/multi-agent/internal/clickhouse"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/dataset"
+	"github.com/anuvratrastogi/multi-agent/internal/dialect"
+	"github.com/anuvratrastogi/multi-agent/internal/duckdb"
+)
+
+// Conn is a database connection opened by Connect.
+type Conn interface {
+	sqlagent.MCPClient
+	// SQLDialect is the SQL the database speaks
+	SQLDialect() dialect.Dialect
+	Close() error
+}
+
+// Connect opens the database selected by DB_DRIVER, keeping large results
+// within the memory budget by spilling them to disk.
+func Connect(ctx context.Context, cfg *config.Config) (Conn, error) {
+	memoryLimit, err := cfg.ResultMemoryBytes()
+	if err != nil {
+		return nil, err
+	}
+	results := dataset.Config{
+		Budget: dataset.NewBudget(memoryLimit),
+		Dir:    cfg.ResultSpillDir,
+	}
+
+	switch cfg.DBDriver {
+	case config.DBDriverBigQuery:
+		console.Printf("📊 Connecting to BigQuery dataset %s.%s...\n", cfg.BigQueryProject, cfg.BigQueryDataset)
+		maxBytes, err := cfg.BigQueryMaxBytesValue()
+		if err != nil {
+			return nil, err
+		}
+		client, err := bigquery.New(ctx, bigquery.Config{
+			Project:         cfg.BigQueryProject,
+			Dataset:         cfg.BigQueryDataset,
+			Location:        cfg.BigQueryLocation,
+			CredentialsFile: cfg.BigQueryCredentialsFile,
+			MaxBytes:        maxBytes,
+			Results:         results,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		return client, nil
+	case config.DBDriverClickHouse:
+		console.Println("📊 Connecting to ClickHouse...")
+		client, err := clickhouse.New(ctx, clickhouse.Config{URL: cfg.DatabaseURL, Results: results})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		return client, nil
+	case config.DBDriverDuckDB:
+		console.Println("📊 Starting DuckDB...")
+		client, err := duckdb.New(ctx, duckdb.Config{
+			Binary:   cfg.DuckDBBinary,
+			Database: cfg.DatabaseURL,
+			Dir:      cfg.FilesDir,
+			Results:  results,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		return client, nil
+	default:
+		console.Println("📊 Connecting to PostgreSQL...")
+		if cfg.DBSSLMode != "" {
+			console.Printf("🔐 PostgreSQL sslmode: %s\n", cfg.DBSSLMode)
+		}
+		client, err := sqlagent.NewDirectMCPClient(cfg.PostgresURL())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		client.SetResultStorage(results)
+		client.SetSearchPath(cfg.SearchPath())
+		if path := cfg.SearchPath(); !slices.Equal(path, []string{dialect.PublicSchema}) {
+			console.Printf("📂 Schemas: %s\n", strings.Join(path, ", "))
+		}
+		return client, nil
+	}
+}
+
+// sqlDialect returns the SQL spoken by db, or nil (PostgreSQL) for a client
+// that does not say, such as one passed in Options.DB.
+func sqlDialect(db sqlagent.MCPClient) dialect.Dialect {
+	if d, ok := db.(interface{ SQLDialect() dialect.Dialect }); ok {
+		return d.SQLDialect()
+	}
+	return nil
+}
@@ -0,0 +1,854 @@
+// Package wiring assembles the model, database client, tools, agents and
+// App from the configuration. The command and pkg/multiagent both start
+// the system through Build.
+package wiring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/files"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/registry"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/artifacts"
+	"github.com/anuvratrastogi/multi-agent/internal/audit"
+	"github.com/anuvratrastogi/multi-agent/internal/caps"
+	"github.com/anuvratrastogi/multi-agent/internal/casts"
+	"github.com/anuvratrastogi/multi-agent/internal/clarify"
+	"github.com/anuvratrastogi/multi-agent/internal/colstats"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/dictionary"
+	"github.com/anuvratrastogi/multi-agent/internal/duckdb"
+	"github.com/anuvratrastogi/multi-agent/internal/events"
+	"github.com/anuvratrastogi/multi-agent/internal/frame"
+	"github.com/anuvratrastogi/multi-agent/internal/governance"
+	"github.com/anuvratrastogi/multi-agent/internal/guardrails"
+	"github.com/anuvratrastogi/multi-agent/internal/i18n"
+	"github.com/anuvratrastogi/multi-agent/internal/limits"
+	"github.com/anuvratrastogi/multi-agent/internal/llm"
+	"github.com/anuvratrastogi/multi-agent/internal/preview"
+	"github.com/anuvratrastogi/multi-agent/internal/prompts"
+	"github.com/anuvratrastogi/multi-agent/internal/replay"
+	"github.com/anuvratrastogi/multi-agent/internal/schema"
+	"github.com/anuvratrastogi/multi-agent/internal/secrets"
+	"github.com/anuvratrastogi/multi-agent/internal/semcache"
+	"github.com/anuvratrastogi/multi-agent/internal/stats"
+	"github.com/anuvratrastogi/multi-agent/internal/toolmw"
+	"github.com/anuvratrastogi/multi-agent/internal/usage"
+	"github.com/anuvratrastogi/multi-agent/pkg/bert"
+	"github.com/anuvratrastogi/multi-agent/pkg/localllm"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+)
+
+// Options overrides parts of the system the configuration would create.
+type Options struct {
+	// Model replaces the model configured by the LLM settings
+	Model model.LLM
+	// DB replaces the database connection selected by DB_DRIVER
+	DB sqlagent.MCPClient
+	// SessionService stores conversations (default: in memory)
+	SessionService session.Service
+}
+
+// System is the assembled system.
+type System struct {
+	// App runs turns through the agents.
+	App *app.App
+	// Model is shared by all agents so it can be switched at runtime.
+	Model *llm.Switchable
+	// Database is the connection opened by Connect, or nil if Options.DB
+	// was set.
+	Database Conn
+	// Tools is the database client the agents query through, with the
+	// limits, policies, audit log and usage tracking applied.
+	Tools sqlagent.MCPClient
+	// AuditLog records executed queries, if configured.
+	AuditLog *audit.Log
+	Usage    *usage.Tracker
+	Events   *events.Bus
+	Metrics  *events.Metrics
+	// Schema holds the database schema the agents are prompted with.
+	Schema *schema.Cache
+	// Artifacts stores the charts, exports and reports turns produce, if
+	// configured.
+	Artifacts artifacts.Store
+
+	closers []func() error
+}
+
+// Close releases the database connection and closes the logs.
+func (s *System) Close() error {
+	var first error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i](); err != nil && first == nil {
+			first = err
+		}
+	}
+	s.closers = nil
+	return first
+}
+
+// Build creates the system described by cfg, reporting progress on the
+// console. On error, whatever was opened is closed again.
+func Build(ctx context.Context, cfg *config.Config, opts Options) (*System, error) {
+	s := &System{}
+	if err := s.build(ctx, cfg, opts); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *System) build(ctx context.Context, cfg *config.Config, opts Options) error {
+	// Keep credentials and sensitive columns out of the console, logs and
+	// traces of the whole process
+	secrets.Configure(secrets.Config{Values: cfg.SecretValues(), Columns: cfg.LogRedactColumnNames()})
+	if columns := cfg.LogRedactColumnNames(); len(columns) > 0 {
+		console.Printf("🔒 Masking in logs: %s\n", strings.Join(columns, ", "))
+	}
+
+	// Initialize LLM based on provider
+	baseLLM := opts.Model
+	if baseLLM == nil {
+		switch {
+		case cfg.LLMProvider == config.LLMProviderMock:
+			console.Printf("🎭 Using mock LLM: %s\n", cfg.MockLLMFixtures)
+		case cfg.IsOpenAICompatible():
+			if cfg.IsLocalLLM() {
+				console.Printf("🔧 Using Local LLM: %s\n", secrets.Redact(cfg.LocalLLMURL))
+				console.Printf("   Model: %s\n", cfg.Model)
+				if cfg.LocalLLMFlavor != "" {
+					console.Printf("   Server: %s\n", cfg.LocalLLMFlavor)
+				}
+				if llm.ToolCalling(cfg, cfg.Model) == localllm.ToolCallingPrompt {
+					console.Println("   Tools: described in the prompt")
+				}
+			} else {
+				deployment, _ := cfg.AzureOpenAIDeployment(cfg.Model)
+				console.Printf("🔧 Using Azure OpenAI: %s\n", secrets.Redact(cfg.AzureOpenAIEndpoint))
+				console.Printf("   Model: %s (deployment %s)\n", cfg.Model, deployment)
+			}
+			if cfg.LocalLLMProxy != "" {
+				console.Printf("   Proxy: %s\n", secrets.Redact(cfg.LocalLLMProxy))
+			}
+			if headers, _ := cfg.LocalLLMHeaderValues(); len(headers) > 0 {
+				console.Printf("   Headers: %s\n", strings.Join(slices.Sorted(maps.Keys(headers)), ", "))
+			}
+			if cfg.LocalLLMInsecureSkipVerify {
+				console.Println("⚠️  Not verifying the LLM server's certificate (LOCAL_LLM_INSECURE_SKIP_VERIFY)")
+			}
+		default:
+			console.Printf("🔧 Using Gemini: %s\n", cfg.Model)
+		}
+		if caps, ok := llm.LookupCapabilities(cfg.LLMProvider, cfg.Model); ok && !caps.ToolCalling && llm.ToolCalling(cfg, cfg.Model) == localllm.ToolCallingNative {
+			console.Printf("⚠️  %s cannot call tools, which the agents need to query the database; set LOCAL_LLM_TOOL_CALLING=prompt or choose another model\n", cfg.Model)
+		}
+		if cfg.LLMCache != config.LLMCacheOff {
+			where := "memory"
+			if cfg.LLMCacheDir != "" {
+				where = cfg.LLMCacheDir
+			}
+			console.Printf("💾 LLM cache: %s (%s)\n", cfg.LLMCache, where)
+		}
+		if cfg.KeepReasoning {
+			console.Println("🧠 Keeping model reasoning for /trace")
+		}
+		var err error
+		baseLLM, err = llm.New(ctx, cfg, cfg.Model)
+		if err != nil {
+			return fmt.Errorf("failed to initialize model: %w", err)
+		}
+		if cfg.CheckModel {
+			if err := checkModel(ctx, cfg); err != nil {
+				return err
+			}
+		}
+		console.Println()
+	}
+	// Shared by all agents so /model can switch it at runtime
+	s.Model = llm.NewSwitchable(baseLLM)
+	// Pace the calls of all agents together, whichever model is active
+	agentModel := llm.RateLimit(s.Model, llm.RateLimitConfig{
+		RequestsPerMinute: cfg.LLMRequestsPerMinute,
+		TokensPerMinute:   cfg.LLMTokensPerMinute,
+		Retries:           cfg.LLMRateLimitRetries,
+	})
+	if cfg.LLMRequestsPerMinute > 0 || cfg.LLMTokensPerMinute > 0 {
+		console.Printf("🚦 LLM rate limits: %d requests, %d tokens per minute (0 = unlimited)\n", cfg.LLMRequestsPerMinute, cfg.LLMTokensPerMinute)
+	}
+	// The agents' calls are added to the debug trace of traced turns
+	agentModel = replay.Model(agentModel)
+
+	// Initialize database client
+	db := opts.DB
+	if db == nil {
+		conn, err := Connect(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		s.closers = append(s.closers, conn.Close)
+		console.Println("✅ Database connected")
+		s.Database, db = conn, conn
+	}
+
+	// Fetch database schema for SQL agent, from disk if it was kept there,
+	// with the descriptions of the data dictionary merged in
+	describer, terms, err := dataDictionary(cfg, db)
+	if err != nil {
+		return err
+	}
+	s.Schema = schema.NewCache(describer)
+	loaded := false
+	if cfg.SchemaCacheFile != "" {
+		var err error
+		loaded, err = s.Schema.Persist(cfg.SchemaCacheFile, databaseID(cfg))
+		if err != nil {
+			log.Printf("⚠️  Warning: %v", err)
+		}
+	}
+	if loaded {
+		console.Printf("✅ Schema loaded from %s (described %s, /schema refresh to update)\n", cfg.SchemaCacheFile, s.Schema.Described().Format(time.DateTime))
+	} else {
+		console.Println("📋 Loading database schema...")
+		if _, err = s.Schema.Refresh(ctx); err != nil {
+			log.Printf("⚠️  Warning: Could not load schema: %v", err)
+		} else {
+			console.Println("✅ Schema loaded")
+		}
+	}
+	dbSchema := s.Schema.Text()
+
+	// Suggest casts for text columns that hold numbers, dates or booleans
+	var columnCasts []casts.Suggestion
+	if pg, ok := s.Database.(*sqlagent.DirectMCPClient); ok && cfg.InferColumnTypes {
+		columnCasts, err = pg.InferColumnTypes(ctx, cfg.InferColumnTypesSample)
+		if err != nil {
+			log.Printf("⚠️  Warning: Could not infer column types: %v", err)
+		} else {
+			console.Printf("🔢 Suggested casts for %d text columns\n", len(columnCasts))
+		}
+	}
+
+	// Tell the SQL agent the values of enum-like columns
+	var columnStats []colstats.Column
+	if pg, ok := s.Database.(*sqlagent.DirectMCPClient); ok && cfg.ColumnStats {
+		columnStats, err = pg.ColumnStats(ctx, cfg.ColumnStatsMaxValues)
+		if err != nil {
+			log.Printf("⚠️  Warning: Could not read column statistics: %v", err)
+		}
+	}
+
+	// Queue queries beyond the concurrency and rate limits
+	var toolClient sqlagent.MCPClient = limits.NewClient(db, limits.Config{
+		Global:           cfg.DBMaxConcurrent,
+		PerSession:       cfg.DBMaxConcurrentPerSession,
+		QueriesPerSecond: cfg.DBQueriesPerSecond,
+	})
+	if cfg.DBQueriesPerSecond > 0 {
+		console.Printf("🚦 Database rate limit: %d queries per second\n", cfg.DBQueriesPerSecond)
+	}
+
+	// Cut every result to the hard caps, whatever the model asks for
+	maxResultBytes, err := cfg.ResultMaxBytes()
+	if err != nil {
+		return err
+	}
+	toolClient = caps.NewClient(toolClient, caps.Config{
+		MaxRows:    cfg.ResultMaxRows,
+		MaxColumns: cfg.ResultMaxColumns,
+		MaxBytes:   maxResultBytes,
+	})
+
+	// Apply the redaction policy to everything the agents can read
+	if cfg.RedactionPolicyFile != "" {
+		policy, err := governance.LoadRedactionPolicy(cfg.RedactionPolicyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load redaction policy: %w", err)
+		}
+		toolClient = governance.NewRedactingClient(toolClient, policy)
+		columnStats = slices.DeleteFunc(columnStats, func(c colstats.Column) bool { return policy.Redacts(c.Table, c.Column) })
+		console.Printf("🛡️  Redaction policy loaded: %s\n", cfg.RedactionPolicyFile)
+	}
+
+	// Reject queries on tables the user's roles do not grant
+	var access *governance.AccessPolicy
+	if cfg.AccessPolicyFile != "" {
+		policy, err := governance.LoadAccessPolicy(cfg.AccessPolicyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load access policy: %w", err)
+		}
+		access = policy
+		// Names without a schema are checked in every schema they may be in
+		if _, ok := s.Database.(*sqlagent.DirectMCPClient); ok {
+			schemas, err := governance.SearchPath(ctx, db)
+			if err != nil {
+				return err
+			}
+			policy.SetSearchPath(schemas)
+		}
+		toolClient = governance.NewAccessClient(toolClient, policy)
+		// The instruction is shared by all users
+		columnStats = slices.DeleteFunc(columnStats, func(c colstats.Column) bool { return !policy.Permits(governance.AnyUser, c.Table) })
+		console.Printf("🛡️  Access policy loaded: %s\n", cfg.AccessPolicyFile)
+	}
+	if cfg.ColumnStats && len(columnStats) > 0 {
+		console.Printf("📈 Common values for %d columns\n", len(columnStats))
+	}
+
+	// Record every executed query in the audit log
+	if cfg.AuditLogFile != "" {
+		s.AuditLog, err = audit.Open(cfg.AuditLogFile)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		s.closers = append(s.closers, s.AuditLog.Close)
+		toolClient = audit.NewClient(toolClient, s.AuditLog)
+		console.Printf("🗒️  Auditing queries to %s\n", cfg.AuditLogFile)
+	}
+
+	// Roll up usage per user; kept in memory unless USAGE_FILE is set
+	s.Usage, err = usage.Open(cfg.UsageFile, cfg.UsageTeams)
+	if err != nil {
+		return fmt.Errorf("failed to open usage file: %w", err)
+	}
+	s.closers = append(s.closers, s.Usage.Close)
+	toolClient = usage.NewClient(toolClient, s.Usage)
+	if cfg.UsageFile != "" {
+		console.Printf("📊 Recording usage to %s\n", cfg.UsageFile)
+	}
+	s.Tools = toolClient
+
+	// Create tools for SQL agent
+	sqlTools, err := sqlagent.CreateMCPTools(toolClient)
+	if err != nil {
+		return fmt.Errorf("failed to create SQL tools: %w", err)
+	}
+	if cfg.SQLWriteMode {
+		writeTools, err := sqlagent.CreateWriteTools()
+		if err != nil {
+			return fmt.Errorf("failed to create write tools: %w", err)
+		}
+		sqlTools = append(sqlTools, writeTools...)
+		console.Println("✍️  Write mode enabled: data changes require approval")
+	}
+	explainTools, err := sqlagent.CreateExplainTools(toolClient, sqlDialect(db))
+	if err != nil {
+		return fmt.Errorf("failed to create explain tools: %w", err)
+	}
+	sqlTools = append(sqlTools, explainTools...)
+	profileTools, err := sqlagent.CreateProfileTools(toolClient, sqlDialect(db))
+	if err != nil {
+		return fmt.Errorf("failed to create profile tools: %w", err)
+	}
+	sqlTools = append(sqlTools, profileTools...)
+
+	// Show the model a preview of large results; it pages through the rest
+	previewBytes, err := cfg.ResultPreviewBytes()
+	if err != nil {
+		return err
+	}
+	results := preview.New(preview.Config{MaxRows: cfg.ResultPreviewRows, MaxBytes: previewBytes})
+	if cfg.ResultPreviewRows > 0 {
+		fetchTools, err := results.Tools()
+		if err != nil {
+			return fmt.Errorf("failed to create result tools: %w", err)
+		}
+		sqlTools = append(sqlTools, fetchTools...)
+	}
+
+	// Let the agent reshape the latest result without another query
+	frames := frame.NewStore()
+	transformTools, err := frames.Tools()
+	if err != nil {
+		return fmt.Errorf("failed to create transform tools: %w", err)
+	}
+	sqlTools = append(sqlTools, transformTools...)
+
+	// Flag anomalies in time series instead of eyeballing the rows
+	statsTools, err := stats.Tools(frames)
+	if err != nil {
+		return fmt.Errorf("failed to create stats tools: %w", err)
+	}
+	sqlTools = append(sqlTools, statsTools...)
+
+	// Ask the user about ambiguous questions instead of guessing
+	clarifyTools, err := clarify.Tools()
+	if err != nil {
+		return fmt.Errorf("failed to create clarification tools: %w", err)
+	}
+	sqlTools = append(sqlTools, clarifyTools...)
+
+	// Apply logging, latency metrics and rate limits to every tool call
+	toolMiddleware := []toolmw.Middleware{toolmw.Timing()}
+	if cfg.ToolLog {
+		toolMiddleware = append([]toolmw.Middleware{toolmw.Logging()}, toolMiddleware...)
+	}
+	if rates, _ := cfg.ToolRateLimitValues(); rates != nil {
+		toolMiddleware = append(toolMiddleware, toolmw.RateLimit(rates))
+		console.Printf("🚦 Tool rate limits: %s calls per minute\n", cfg.ToolRateLimits)
+	}
+	// Check tool arguments, and later the answers, against content rules
+	var guard *guardrails.Guard
+	if cfg.GuardrailsFile != "" {
+		policy, err := guardrails.LoadPolicy(cfg.GuardrailsFile)
+		if err != nil {
+			return err
+		}
+		guard, err = guardrails.New(guardrails.Config{Policy: policy, Model: agentModel})
+		if err != nil {
+			return fmt.Errorf("failed to create guardrails: %w", err)
+		}
+		toolMiddleware = append(toolMiddleware, guard.Middleware())
+		console.Printf("🛡️  Guardrails loaded: %s (%d rules)\n", cfg.GuardrailsFile, guard.Rules())
+	}
+	toolMiddleware = append(toolMiddleware, results.Middleware(), frames.Middleware())
+	sqlTools = toolmw.Wrap(sqlTools, toolMiddleware...)
+
+	// Load the agents' instructions, replaced by templates in PromptsDir
+	guidance, err := cfg.Guidance()
+	if err != nil {
+		return fmt.Errorf("failed to read prompt guidance: %w", err)
+	}
+	agentPrompts, err := prompts.New(prompts.Config{Dir: cfg.PromptsDir, Reload: cfg.PromptsReload, Variants: cfg.PromptVariants, Guidance: guidance})
+	if err != nil {
+		return fmt.Errorf("failed to load prompts: %w", err)
+	}
+	if names := guided(guidance); len(names) > 0 {
+		console.Printf("📝 Organization guidance for %s\n", strings.Join(names, ", "))
+	}
+	if overridden := agentPrompts.Overridden(); len(overridden) > 0 {
+		console.Printf("📝 Prompts from %s: %s\n", cfg.PromptsDir, strings.Join(overridden, ", "))
+	}
+	if agentPrompts.Experimenting() {
+		split := cfg.PromptVariants
+		if split == "" {
+			split = prompts.Base
+		}
+		console.Printf("🧪 Prompt variants: %s; new sessions use %s\n", strings.Join(agentPrompts.Variants(), ", "), split)
+	}
+	if cfg.PromptsReload && cfg.PromptsDir != "" {
+		console.Printf("🔄 Reloading prompts in %s when they change\n", cfg.PromptsDir)
+	}
+
+	// Initialize Chart Agent
+	console.Println("📈 Initializing Chart Agent...")
+	chartAgent, err := chart.New(chart.Config{
+		Model:          agentModel,
+		Prompts:        agentPrompts,
+		GenerateConfig: llm.GenerateConfig(llm.Sampling(cfg, config.AgentChart)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Chart agent: %w", err)
+	}
+	console.Println("✅ Chart Agent ready")
+
+	// Initialize SQL Agent with schema
+	console.Println("🔧 Initializing SQL Agent...")
+	sqlAgent, err := sqlagent.New(sqlagent.Config{
+		Model:          agentModel,
+		Tools:          sqlTools,
+		DatabaseSchema: dbSchema,
+		SchemaSource:   s.Schema.Text,
+		ColumnCasts:    columnCasts,
+		ColumnStats:    columnStats,
+		Terms:          terms,
+		WriteMode:      cfg.SQLWriteMode,
+		Explain:        len(explainTools) > 0,
+		Profile:        len(profileTools) > 0,
+		Transform:      len(transformTools) > 0,
+		Stats:          len(statsTools) > 0,
+		Clarify:        true,
+		GenerateConfig: llm.GenerateConfig(llm.Sampling(cfg, config.AgentSQL)),
+		Dialect:        sqlDialect(db),
+		Prompts:        agentPrompts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create SQL agent: %w", err)
+	}
+	console.Println("✅ SQL Agent ready")
+
+	// Initialize the specialist agents compiled in as plugins
+	specialists, err := registry.Build(registry.Deps{Model: agentModel, DB: toolClient, Schema: dbSchema, CurrentSchema: s.Schema.Text, Guidance: prompts.FormatGuidance(guidance[prompts.All])})
+	if err != nil {
+		return fmt.Errorf("failed to create plugin agents: %w", err)
+	}
+	for _, a := range specialists {
+		console.Printf("🧩 %s ready\n", a.Spec.Name)
+	}
+
+	// Initialize the Files agent over the data files directory
+	if cfg.FilesDir != "" {
+		console.Printf("📁 Initializing Files Agent over %s...\n", cfg.FilesDir)
+		fileClient, err := duckdb.New(ctx, duckdb.Config{Binary: cfg.DuckDBBinary, Dir: cfg.FilesDir})
+		if err != nil {
+			return fmt.Errorf("failed to start DuckDB for the Files agent: %w", err)
+		}
+		filesAgent, err := files.New(files.Config{
+			Model:          agentModel,
+			Files:          fileClient,
+			Prompts:        agentPrompts,
+			GenerateConfig: llm.GenerateConfig(llm.Sampling(cfg, config.AgentSQL)),
+		})
+		if err != nil {
+			return err
+		}
+		specialists = append(specialists, filesAgent)
+		console.Println("✅ Files Agent ready")
+	}
+
+	// Initialize Manager Agent
+	console.Println("👔 Initializing Manager Agent...")
+	var classifierModel *bert.Model
+	if cfg.ClassifierModelFile != "" {
+		classifierModel, err = bert.LoadModel(cfg.ClassifierModelFile)
+		if err != nil {
+			return err
+		}
+		console.Printf("🎯 Classifier model loaded: %s\n", cfg.ClassifierModelFile)
+	}
+	managerAgent, err := manager.New(manager.Config{
+		Model:       agentModel,
+		SQLAgent:    sqlAgent,
+		ChartAgent:  chartAgent,
+		Specialists: specialists,
+		Tools:       clarifyTools,
+		// Ask the LLM for the intent when the keywords are inconclusive
+		FallbackBelow: cfg.ClassifierFallbackConfidence,
+		Embedder:      intentEmbedder(cfg),
+		// Keyword weights and examples tuned by classify-tune
+		ClassifierModel: classifierModel,
+
+		GenerateConfig: llm.GenerateConfig(llm.Sampling(cfg, config.AgentManager)),
+		Prompts:        agentPrompts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Manager agent: %w", err)
+	}
+	console.Println("✅ Manager Agent ready")
+
+	// Create the app that runs turns through the ADK runner
+	console.Println("🏃 Creating ADK Runner...")
+	locale, err := i18n.ParseLocale(cfg.Locale)
+	if err != nil {
+		return fmt.Errorf("invalid locale: %w", err)
+	}
+
+	// Publish agent lifecycle events to the console, metrics and the event log
+	s.Events = events.New()
+	s.Events.Subscribe(events.Print)
+	s.Metrics = events.NewMetrics()
+	s.Events.Subscribe(s.Metrics.Handle)
+	if cfg.EventLogFile != "" {
+		eventLog, err := events.OpenLog(cfg.EventLogFile)
+		if err != nil {
+			return fmt.Errorf("failed to open event log: %w", err)
+		}
+		s.closers = append(s.closers, eventLog.Close)
+		s.Events.Subscribe(eventLog.Handle)
+		console.Printf("🗒️  Logging agent events to %s\n", cfg.EventLogFile)
+	}
+
+	// Reuse answers to questions similar to ones answered before
+	var answerCache app.AnswerCache
+	var cacheScope func(userID string) string
+	if cfg.SemanticCache {
+		cache, err := semcache.Open(semcache.Config{
+			Threshold: cfg.SemanticCacheThreshold,
+			Schema:    dbSchema,
+			Path:      cfg.SemanticCacheFile,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to open semantic cache: %w", err)
+		}
+		answerCache = cache
+		console.Printf("⚡ Semantic cache: %d answers, similarity ≥ %.2f\n", cache.Len(), cfg.SemanticCacheThreshold)
+		// Answers are only shared by users granted the same tables
+		if access != nil {
+			cacheScope = access.Scope
+		}
+		// Answers about the old schema may no longer hold
+		s.Schema.OnChange(func(text string, _ schema.Diff) {
+			if err := cache.SetSchema(text); err != nil {
+				log.Printf("⚠️  Failed to save semantic cache: %v", err)
+			}
+		})
+	}
+
+	// Refresh the schema the agents see when the database changes
+	if err := s.watchSchema(cfg); err != nil {
+		return err
+	}
+
+	// Save every turn's model and tool calls for "multi-agent replay"
+	var traces *replay.Recorder
+	if cfg.DebugTraceDir != "" {
+		traces, err = replay.NewRecorder(cfg.DebugTraceDir)
+		if err != nil {
+			return err
+		}
+		console.Printf("🐞 Saving debug traces to %s\n", cfg.DebugTraceDir)
+	}
+
+	// Keep charts, exports and reports for download, and clean up old ones
+	if cfg.ArtifactStore != "" {
+		retention, err := cfg.ArtifactRetentionDuration()
+		if err != nil {
+			return err
+		}
+		s.Artifacts, err = artifactStore(cfg)
+		if err != nil {
+			return err
+		}
+		go artifacts.Retain(ctx, s.Artifacts, retention, time.Hour)
+		console.Printf("📎 Storing artifacts in %s for %s\n", cfg.ArtifactStore, retention)
+	}
+
+	// Stop runaway turns, such as local models stuck in a tool-call loop
+	maxDuration, err := cfg.TurnMaxDurationValue()
+	if err != nil {
+		return err
+	}
+	policies, err := stepPolicies(cfg)
+	if err != nil {
+		return err
+	}
+	chartStyle, err := cfg.ChartStyle()
+	if err != nil {
+		return err
+	}
+
+	sessions := opts.SessionService
+	if sessions == nil {
+		sessions = session.InMemoryService()
+	}
+	s.App, err = app.New(app.Config{
+		Manager:        managerAgent,
+		SessionService: sessions,
+		DB:             toolClient,
+		Locale:         &locale,
+		Usage:          s.Usage,
+		KeepReasoning:  cfg.KeepReasoning,
+		Cache:          answerCache,
+		CacheScope:     cacheScope,
+		Events:         s.Events,
+		Results:        results,
+		ClarifyBelow:   cfg.ClarifyConfidence,
+		Prompts:        agentPrompts,
+		Traces:         traces,
+		PlanExecute:    cfg.PlanExecute,
+		MaxReplans:     cfg.PlanMaxReplans,
+		StepPolicies:   policies,
+		AppendSummary:  cfg.TurnSummary,
+		Artifacts:      s.Artifacts,
+		ChartStyle:     chartStyle,
+		Guardrails:     guard,
+		Budget: app.Budget{
+			MaxDuration:  maxDuration,
+			MaxLLMCalls:  cfg.TurnMaxLLMCalls,
+			MaxToolCalls: cfg.TurnMaxToolCalls,
+			MaxTokens:    cfg.TurnMaxTokens,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create runner: %w", err)
+	}
+	console.Println("✅ Runner ready")
+	console.Println()
+	return nil
+}
+
+// guided lists the agents given guidance, "all" first.
+func guided(guidance map[string]string) []string {
+	var names []string
+	for _, name := range append([]string{prompts.All}, prompts.Names...) {
+		if strings.TrimSpace(guidance[name]) != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// artifactStore opens the directory or bucket named by ArtifactStore.
+func artifactStore(cfg *config.Config) (artifacts.Store, error) {
+	if !cfg.ArtifactStoreIsBucket() {
+		return artifacts.NewDir(cfg.ArtifactStore)
+	}
+	expiry, err := cfg.ArtifactURLExpiryDuration()
+	if err != nil {
+		return nil, err
+	}
+	return artifacts.NewBucket(artifacts.BucketConfig{
+		URL:             cfg.ArtifactStore,
+		Endpoint:        cfg.ArtifactStoreEndpoint,
+		Region:          cfg.ArtifactStoreRegion,
+		AccessKeyID:     cfg.ArtifactStoreAccessKey,
+		SecretAccessKey: cfg.ArtifactStoreSecretKey,
+		SessionToken:    cfg.ArtifactStoreSessionToken,
+		URLExpiry:       expiry,
+	})
+}
+
+// stepPolicies returns the default recovery policies of plan steps with the
+// configured retries and recoveries.
+func stepPolicies(cfg *config.Config) (app.StepPolicies, error) {
+	retries, err := cfg.StepRetryValues()
+	if err != nil {
+		return nil, err
+	}
+	recoveries, err := cfg.StepOnFailureValues()
+	if err != nil {
+		return nil, err
+	}
+	policies := app.DefaultStepPolicies()
+	for stepType, n := range retries {
+		p := policies[stepType]
+		p.Retries = n
+		policies[stepType] = p
+	}
+	for stepType, recovery := range recoveries {
+		p := policies[stepType]
+		p.OnFailure = app.Recovery(recovery)
+		policies[stepType] = p
+	}
+	return policies, nil
+}
+
+// checkModel fails if the provider lists its models and the configured
+// model is not among them. The default local model is only checked for
+// being loaded, since servers answer it with whichever model is. Providers
+// that cannot list their models, or fail to, are not checked.
+func checkModel(ctx context.Context, cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	models, err := llm.ListModels(ctx, cfg)
+	switch {
+	case errors.Is(err, errors.ErrUnsupported):
+		return nil
+	case err != nil:
+		console.Printf("⚠️  Could not list the available models: %v\n", err)
+		return nil
+	case cfg.Model == config.DefaultLocalModel:
+		if len(models) == 0 {
+			return fmt.Errorf("the LLM server has no models loaded")
+		}
+		console.Printf("   Models: %s\n", strings.Join(models, ", "))
+		return nil
+	case !llm.HasModel(models, cfg.Model):
+		return fmt.Errorf("model %s is not available (%s); set LLM_MODEL to one of: %s", cfg.Model, cfg.LLMProvider, strings.Join(models, ", "))
+	}
+	console.Printf("✅ Model %s is available\n", cfg.Model)
+	return nil
+}
+
+// intentEmbedder returns the sentence embedder classifying intents, or
+// nil to classify by keywords.
+func intentEmbedder(cfg *config.Config) bert.Embedder {
+	switch cfg.IntentEmbedder {
+	case config.IntentEmbedderOllama:
+		console.Printf("🧭 Classifying intents with %s embeddings from Ollama\n", cfg.IntentEmbedderModel)
+		return bert.OllamaEmbedder{URL: cfg.IntentEmbedderEndpoint(), Model: cfg.IntentEmbedderModel}
+	case config.IntentEmbedderOpenAI:
+		console.Printf("🧭 Classifying intents with %s embeddings from %s\n", cfg.IntentEmbedderModel, secrets.Redact(cfg.IntentEmbedderEndpoint()))
+		return bert.OpenAIEmbedder{URL: cfg.IntentEmbedderEndpoint(), Model: cfg.IntentEmbedderModel, APIKey: cfg.IntentEmbedderAPIKey}
+	default:
+		return nil
+	}
+}
+
+// dataDictionary wraps db so the descriptions of the configured data
+// dictionary are merged into its schema, and returns the dictionary's
+// business terms.
+func dataDictionary(cfg *config.Config, db sqlagent.MCPClient) (schema.Describer, map[string]string, error) {
+	if cfg.DataDictionaryFile == "" && cfg.DataDictionaryTable == "" && !cfg.DataDictionaryComments {
+		return db, nil, nil
+	}
+
+	d := dictionary.Describer{DB: db}
+	if cfg.DataDictionaryFile != "" {
+		dict, err := dictionary.Load(cfg.DataDictionaryFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		d.Dictionary = dict
+		console.Printf("📖 Data dictionary loaded: %s (%d tables, %d terms)\n", cfg.DataDictionaryFile, len(dict.Tables), len(dict.Terms))
+	}
+	if cfg.DataDictionaryTable != "" {
+		d.Sources = append(d.Sources, dictionary.FromTable(db, cfg.DataDictionaryTable))
+	}
+	if pg, ok := db.(*sqlagent.DirectMCPClient); ok && cfg.DataDictionaryComments {
+		d.Sources = append(d.Sources, pg.Comments)
+	}
+	var terms map[string]string
+	if d.Dictionary != nil {
+		terms = d.Dictionary.Terms
+	}
+	return d, terms, nil
+}
+
+// databaseID identifies the configured database, and the data dictionary
+// merged into its schema, in the schema cache file without keeping its
+// credentials there. Editing the dictionary file changes the ID.
+func databaseID(cfg *config.Config) string {
+	var dict []byte
+	if cfg.DataDictionaryFile != "" {
+		dict, _ = os.ReadFile(cfg.DataDictionaryFile)
+	}
+	return schema.Hash(strings.Join([]string{
+		string(cfg.DBDriver), cfg.DatabaseURL, cfg.MCPServerAddr, cfg.DBSearchPath,
+		cfg.BigQueryProject, cfg.BigQueryDataset, cfg.FilesDir,
+		string(dict), cfg.DataDictionaryTable, strconv.FormatBool(cfg.DataDictionaryComments),
+	}, "\x00"))
+}
+
+// watchSchema starts refreshing s.Schema as cfg.SchemaWatch says, until
+// the system is closed.
+func (s *System) watchSchema(cfg *config.Config) error {
+	interval, err := cfg.SchemaPollDuration()
+	if err != nil {
+		return err
+	}
+	watch := schema.WatchConfig{Interval: interval}
+	switch cfg.SchemaWatch {
+	case config.SchemaWatchPoll:
+		console.Printf("🔄 Checking the schema for changes every %s\n", interval)
+	case config.SchemaWatchNotify:
+		ln, err := schema.Listen(cfg.PostgresURL(), cfg.SchemaNotifyChannel)
+		if err != nil {
+			return err
+		}
+		s.closers = append(s.closers, ln.Close)
+		watch.Notifications = ln.C()
+		console.Printf("🔄 Listening for schema changes on %q\n", cfg.SchemaNotifyChannel)
+	default:
+		return nil
+	}
+
+	s.Schema.OnChange(func(_ string, diff schema.Diff) {
+		console.Printf("🔄 Schema changed: %s\n", diff)
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Schema.Watch(ctx, watch)
+	}()
+	s.closers = append(s.closers, func() error {
+		cancel()
+		<-done
+		return nil
+	})
+	return nil
+}
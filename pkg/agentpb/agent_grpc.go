@@ -0,0 +1,179 @@
+package agentpb
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Full method names of AgentService.
+const (
+	AgentService_Query_FullMethodName        = "/multiagent.v1.AgentService/Query"
+	AgentService_StreamQuery_FullMethodName  = "/multiagent.v1.AgentService/StreamQuery"
+	AgentService_GetSession_FullMethodName   = "/multiagent.v1.AgentService/GetSession"
+	AgentService_ListSessions_FullMethodName = "/multiagent.v1.AgentService/ListSessions"
+)
+
+// AgentServiceClient is the client API for AgentService.
+type AgentServiceClient interface {
+	// Query answers a question and returns the complete turn.
+	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Turn, error)
+	// StreamQuery answers a question as a stream of events, ending with the
+	// turn.
+	StreamQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[QueryEvent], error)
+	// GetSession returns a session and its conversation.
+	GetSession(ctx context.Context, in *GetSessionRequest, opts ...grpc.CallOption) (*Session, error)
+	// ListSessions returns the user's sessions, most recent first.
+	ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error)
+}
+
+type agentServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewAgentServiceClient returns a client of the AgentService served on cc.
+func NewAgentServiceClient(cc grpc.ClientConnInterface) AgentServiceClient {
+	return &agentServiceClient{cc}
+}
+
+func (c *agentServiceClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*Turn, error) {
+	out := new(Turn)
+	if err := c.cc.Invoke(ctx, AgentService_Query_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) StreamQuery(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[QueryEvent], error) {
+	stream, err := c.cc.NewStream(ctx, &AgentService_ServiceDesc.Streams[0], AgentService_StreamQuery_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[QueryRequest, QueryEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (c *agentServiceClient) GetSession(ctx context.Context, in *GetSessionRequest, opts ...grpc.CallOption) (*Session, error) {
+	out := new(Session)
+	if err := c.cc.Invoke(ctx, AgentService_GetSession_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) ListSessions(ctx context.Context, in *ListSessionsRequest, opts ...grpc.CallOption) (*ListSessionsResponse, error) {
+	out := new(ListSessionsResponse)
+	if err := c.cc.Invoke(ctx, AgentService_ListSessions_FullMethodName, in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AgentServiceServer is the server API for AgentService. Implementations
+// must embed UnimplementedAgentServiceServer so that methods added later
+// return Unimplemented instead of breaking the build.
+type AgentServiceServer interface {
+	Query(context.Context, *QueryRequest) (*Turn, error)
+	StreamQuery(*QueryRequest, grpc.ServerStreamingServer[QueryEvent]) error
+	GetSession(context.Context, *GetSessionRequest) (*Session, error)
+	ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error)
+	mustEmbedUnimplementedAgentServiceServer()
+}
+
+// UnimplementedAgentServiceServer answers every method with Unimplemented.
+type UnimplementedAgentServiceServer struct{}
+
+func (UnimplementedAgentServiceServer) Query(context.Context, *QueryRequest) (*Turn, error) {
+	return nil, status.Error(codes.Unimplemented, "method Query not implemented")
+}
+func (UnimplementedAgentServiceServer) StreamQuery(*QueryRequest, grpc.ServerStreamingServer[QueryEvent]) error {
+	return status.Error(codes.Unimplemented, "method StreamQuery not implemented")
+}
+func (UnimplementedAgentServiceServer) GetSession(context.Context, *GetSessionRequest) (*Session, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetSession not implemented")
+}
+func (UnimplementedAgentServiceServer) ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListSessions not implemented")
+}
+func (UnimplementedAgentServiceServer) mustEmbedUnimplementedAgentServiceServer() {}
+
+// RegisterAgentServiceServer registers srv on s.
+func RegisterAgentServiceServer(s grpc.ServiceRegistrar, srv AgentServiceServer) {
+	s.RegisterService(&AgentService_ServiceDesc, srv)
+}
+
+func _AgentService_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).Query(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: AgentService_Query_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).Query(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_StreamQuery_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServiceServer).StreamQuery(m, &grpc.GenericServerStream[QueryRequest, QueryEvent]{ServerStream: stream})
+}
+
+func _AgentService_GetSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).GetSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: AgentService_GetSession_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).GetSession(ctx, req.(*GetSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: AgentService_ListSessions_FullMethodName}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ListSessions(ctx, req.(*ListSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AgentService_ServiceDesc describes AgentService for grpc.Server.
+var AgentService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "multiagent.v1.AgentService",
+	HandlerType: (*AgentServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Query", Handler: _AgentService_Query_Handler},
+		{MethodName: "GetSession", Handler: _AgentService_GetSession_Handler},
+		{MethodName: "ListSessions", Handler: _AgentService_ListSessions_Handler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "StreamQuery", Handler: _AgentService_StreamQuery_Handler, ServerStreams: true},
+	},
+	Metadata: "agent.proto",
+}
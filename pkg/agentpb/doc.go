@@ -0,0 +1,15 @@
+// Package agentpb is the gRPC API of the multi-agent server: the messages
+// generated from agent.proto, and the AgentService client and server
+// bindings.
+//
+//	conn, err := grpc.NewClient("localhost:9090", grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	if err != nil {
+//		return err
+//	}
+//	c := agentpb.NewAgentServiceClient(conn)
+//	turn, err := c.Query(ctx, &agentpb.QueryRequest{Query: "How many orders are there per month?"})
+//
+// Services in other languages generate their stubs from agent.proto.
+package agentpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative agent.proto
@@ -2,6 +2,7 @@ package bert
 
 import (
 	"math"
+	"slices"
 	"strings"
 )
 
@@ -19,6 +20,9 @@ const (
 type Classifier struct {
 	// Prototype embeddings for each intent
 	intentPrototypes map[Intent][]string
+	// weights and threshold are set from a tuned Model (optional)
+	weights   map[Intent]map[string]float64
+	threshold float64
 }
 
 // NewClassifier creates a new intent classifier.
@@ -45,11 +49,24 @@ func NewClassifier() *Classifier {
 	}
 }
 
+// AddIntent adds an intent recognized by the given keywords, or extends the
+// keywords of an existing one.
+func (c *Classifier) AddIntent(intent Intent, keywords []string) {
+	for _, k := range keywords {
+		c.intentPrototypes[intent] = append(c.intentPrototypes[intent], strings.ToLower(k))
+	}
+}
+
 // Classify determines the intent of a user query.
 func (c *Classifier) Classify(query string) Intent {
-	queryLower := strings.ToLower(query)
-	words := strings.Fields(queryLower)
+	intent, _ := c.ClassifyWithConfidence(query)
+	return intent
+}
 
+// scores scores every intent by the keywords found in the lowercased
+// query.
+func (c *Classifier) scores(queryLower string) map[Intent]float64 {
+	words := strings.Fields(queryLower)
 	scores := make(map[Intent]float64)
 
 	// Calculate keyword match scores
@@ -57,18 +74,20 @@ func (c *Classifier) Classify(query string) Intent {
 		score := 0.0
 		for _, keyword := range keywords {
 			if strings.Contains(queryLower, keyword) {
+				match := 0.0
 				// Weight exact word matches higher
 				for _, word := range words {
 					if word == keyword {
-						score += 2.0
+						match += 2.0
 					} else if strings.Contains(word, keyword) || strings.Contains(keyword, word) {
-						score += 1.0
+						match += 1.0
 					}
 				}
 				// Substring match
-				if score == 0 {
-					score += 0.5
+				if score+match == 0 {
+					match += 0.5
 				}
+				score += c.weight(intent, keyword) * match
 			}
 		}
 		// Normalize by keyword count
@@ -76,25 +95,25 @@ func (c *Classifier) Classify(query string) Intent {
 	}
 
 	// Apply heuristic rules for better classification
-	scores = c.applyHeuristics(queryLower, scores)
-
-	// Find highest scoring intent
-	maxScore := 0.0
-	bestIntent := IntentGeneral
+	return c.applyHeuristics(queryLower, scores)
+}
 
-	for intent, score := range scores {
-		if score > maxScore {
-			maxScore = score
-			bestIntent = intent
-		}
+// weight is the tuned weight of a keyword of intent, 1 by default.
+func (c *Classifier) weight(intent Intent, keyword string) float64 {
+	if w, ok := c.weights[intent][keyword]; ok {
+		return w
 	}
+	return 1
+}
 
-	// If the score is too low, default to general
-	if maxScore < 0.1 {
-		return IntentGeneral
+// Intents returns the intents the classifier knows, sorted.
+func (c *Classifier) Intents() []Intent {
+	intents := make([]Intent, 0, len(c.intentPrototypes))
+	for intent := range c.intentPrototypes {
+		intents = append(intents, intent)
 	}
-
-	return bestIntent
+	slices.Sort(intents)
+	return intents
 }
 
 // applyHeuristics applies additional rules to improve classification.
@@ -137,39 +156,16 @@ func containsAny(s string, substrs []string) bool {
 
 // ClassifyWithConfidence returns the intent along with a confidence score.
 func (c *Classifier) ClassifyWithConfidence(query string) (Intent, float64) {
-	queryLower := strings.ToLower(query)
-	words := strings.Fields(queryLower)
-
-	scores := make(map[Intent]float64)
+	scores := c.scores(strings.ToLower(query))
 
-	// Calculate keyword match scores
-	for intent, keywords := range c.intentPrototypes {
-		score := 0.0
-		for _, keyword := range keywords {
-			if strings.Contains(queryLower, keyword) {
-				for _, word := range words {
-					if word == keyword {
-						score += 2.0
-					} else if strings.Contains(word, keyword) || strings.Contains(keyword, word) {
-						score += 1.0
-					}
-				}
-				if score == 0 {
-					score += 0.5
-				}
-			}
-		}
-		scores[intent] = score / float64(len(keywords))
-	}
-
-	scores = c.applyHeuristics(queryLower, scores)
-
-	// Find highest and second highest
+	// Find highest and second highest, visiting intents in order so ties
+	// are broken the same way every time
 	maxScore := 0.0
 	secondScore := 0.0
 	bestIntent := IntentGeneral
 
-	for intent, score := range scores {
+	for _, intent := range c.Intents() {
+		score := scores[intent]
 		if score > maxScore {
 			secondScore = maxScore
 			maxScore = score
@@ -189,7 +185,8 @@ func (c *Classifier) ClassifyWithConfidence(query string) (Intent, float64) {
 		}
 	}
 
-	if maxScore < 0.1 {
+	// If the score is too low, default to general
+	if maxScore < c.minScore() {
 		return IntentGeneral, 0.0
 	}
 
@@ -93,3 +93,22 @@ func TestNewClassifier(t *testing.T) {
 		t.Errorf("Expected 3 intents, got %d", len(c.intentPrototypes))
 	}
 }
+
+func TestClassifier_AddIntent(t *testing.T) {
+	c := NewClassifier()
+	c.AddIntent("forecast", []string{"Forecast", "predict", "projection"})
+
+	tests := []struct {
+		query    string
+		expected Intent
+	}{
+		{query: "Forecast revenue for next quarter", expected: "forecast"},
+		{query: "Predict next month's orders", expected: "forecast"},
+		{query: "Create a bar chart of sales by month", expected: IntentVisualization},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(tt.query); got != tt.expected {
+			t.Errorf("Classify(%q) = %v, want %v", tt.query, got, tt.expected)
+		}
+	}
+}
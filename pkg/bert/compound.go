@@ -0,0 +1,93 @@
+package bert
+
+import (
+	"regexp"
+	"strings"
+)
+
+// IntentExport is a step of a compound request that saves a result to a
+// file. The keyword classifier does not return it; Steps recognizes it.
+const IntentExport Intent = "export"
+
+// Step is a clause of a compound request with its intent.
+type Step struct {
+	Intent Intent `json:"intent"`
+	Text   string `json:"text"`
+}
+
+var (
+	// clauseBreak separates the clauses of a compound request
+	clauseBreak = regexp.MustCompile(`(?i)\s*(?:[,;]\s*(?:and\s+)?(?:then\s+)?|[.!?]\s+(?:then\s+)?|\s+(?:and\s+)?then\s+|\s+after\s+that\s+|\s+and\s+also\s+)`)
+	// andVerb starts a clause after a plain "and"
+	andVerb = regexp.MustCompile(`(?i)\s+and\s+(chart|plot|graph|visuali[sz]e|draw|export|save|download|write|show|list|get|fetch|count|find|compare)\b`)
+	// exportClause asks for a result to be saved to a file
+	exportClause = regexp.MustCompile(`(?i)^(?:please\s+)?(?:export|save|download|write)\b.*\b(?:csv|json|excel|xlsx|file|disk)\b|^(?:please\s+)?export\b`)
+)
+
+// Steps splits a compound request such as "get monthly sales, chart it,
+// and export to CSV" into its clauses, in order, each with its intent.
+// Consecutive clauses of the same intent, and general clauses such as
+// "for 2024", join their neighbour, so a request with one intent is one
+// step.
+func (c *Classifier) Steps(query string) []Step {
+	var steps []Step
+	var pending string // general clauses before the first step
+	for _, clause := range clauses(query) {
+		intent := IntentExport
+		if !exportClause.MatchString(clause) {
+			intent = c.classifyClause(clause)
+		}
+		n := len(steps)
+		switch {
+		case n > 0 && (intent == IntentGeneral || intent == steps[n-1].Intent):
+			steps[n-1].Text += ", " + clause
+		case intent == IntentGeneral:
+			pending = join(pending, clause)
+		default:
+			steps = append(steps, Step{Intent: intent, Text: join(pending, clause)})
+			pending = ""
+		}
+	}
+	if len(steps) == 0 {
+		return []Step{{Intent: c.Classify(query), Text: strings.TrimSpace(query)}}
+	}
+	if pending != "" {
+		steps[0].Text = join(pending, steps[0].Text)
+	}
+	return steps
+}
+
+// classifyClause returns the intent scoring highest on a clause. Clauses
+// are short, so any keyword match counts, without the minimum score
+// whole queries need.
+func (c *Classifier) classifyClause(clause string) Intent {
+	scores := c.scores(strings.ToLower(clause))
+	best, bestScore := IntentGeneral, 0.0
+	for _, intent := range c.Intents() {
+		if scores[intent] > bestScore {
+			best, bestScore = intent, scores[intent]
+		}
+	}
+	return best
+}
+
+// clauses splits a request at commas, semicolons, sentence ends, "then"
+// and "and" followed by an action.
+func clauses(query string) []string {
+	query = andVerb.ReplaceAllString(query, ", $1")
+	var out []string
+	for _, part := range clauseBreak.Split(strings.TrimSpace(query), -1) {
+		if part = strings.Trim(strings.TrimSpace(part), ".!?"); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// join joins two clauses with a comma, either of which may be empty.
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + ", " + b
+}
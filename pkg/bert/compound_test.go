@@ -0,0 +1,55 @@
+package bert
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSteps(t *testing.T) {
+	c := NewClassifier()
+
+	tests := []struct {
+		query string
+		want  []Step
+	}{
+		{
+			query: "Get monthly sales, chart it, and export to CSV",
+			want: []Step{
+				{Intent: IntentSQLQuery, Text: "Get monthly sales"},
+				{Intent: IntentVisualization, Text: "chart it"},
+				{Intent: IntentExport, Text: "export to CSV"},
+			},
+		},
+		{
+			query: "for 2024, list the orders and then draw a line chart",
+			want: []Step{
+				{Intent: IntentSQLQuery, Text: "for 2024, list the orders"},
+				{Intent: IntentVisualization, Text: "draw a line chart"},
+			},
+		},
+		{
+			query: "Get all users and plot them as a bar chart. Save to json!",
+			want: []Step{
+				{Intent: IntentSQLQuery, Text: "Get all users"},
+				{Intent: IntentVisualization, Text: "plot them as a bar chart"},
+				{Intent: IntentExport, Text: "Save to json"},
+			},
+		},
+		{
+			query: "Create a bar chart of sales by month",
+			want:  []Step{{Intent: IntentVisualization, Text: "Create a bar chart of sales by month"}},
+		},
+		{
+			query: "count orders by region, country and city",
+			want:  []Step{{Intent: IntentSQLQuery, Text: "count orders by region, country and city"}},
+		},
+		{query: "hello", want: []Step{{Intent: IntentGeneral, Text: "hello"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := c.Steps(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Steps(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package bert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// Embedder turns a sentence into a vector whose cosine similarity to
+// another sentence's vector measures how alike their meanings are.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// DefaultOllamaURL is where Ollama listens by default.
+const DefaultOllamaURL = "http://localhost:11434"
+
+// OllamaEmbedder embeds sentences with an embedding model served by
+// Ollama, such as nomic-embed-text or all-minilm (a BERT sentence model).
+type OllamaEmbedder struct {
+	// URL is the Ollama server (default DefaultOllamaURL)
+	URL string
+	// Model is the embedding model, e.g. "nomic-embed-text"
+	Model string
+	// Client sends the requests (default http.DefaultClient)
+	Client *http.Client
+}
+
+// Embed implements Embedder through Ollama's /api/embed endpoint.
+func (e OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	url := e.URL
+	if url == "" {
+		url = DefaultOllamaURL
+	}
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	body := map[string]string{"model": e.Model, "input": text}
+	if err := post(ctx, e.Client, strings.TrimRight(url, "/")+"/api/embed", "", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) == 0 || len(resp.Embeddings[0]) == 0 {
+		return nil, fmt.Errorf("embedding model %s returned no embedding", e.Model)
+	}
+	return resp.Embeddings[0], nil
+}
+
+// OpenAIEmbedder embeds sentences through an OpenAI-compatible
+// /v1/embeddings endpoint, as served by LM Studio, vLLM or OpenAI.
+type OpenAIEmbedder struct {
+	// URL is the server, without the /v1 path
+	URL string
+	// Model is the embedding model
+	Model string
+	// APIKey is sent as a bearer token (optional)
+	APIKey string
+	// Client sends the requests (default http.DefaultClient)
+	Client *http.Client
+}
+
+// Embed implements Embedder.
+func (e OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	var resp struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	body := map[string]string{"model": e.Model, "input": text}
+	if err := post(ctx, e.Client, strings.TrimRight(e.URL, "/")+"/v1/embeddings", e.APIKey, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("embedding model %s returned no embedding", e.Model)
+	}
+	return resp.Data[0].Embedding, nil
+}
+
+// post sends body as JSON and decodes the JSON response into out.
+func post(ctx context.Context, client *http.Client, url, apiKey string, body, out interface{}) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode embedding request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request embedding: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("embedding request failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode embedding response: %w", err)
+	}
+	return nil
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if their lengths
+// differ or either is zero.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,92 @@
+package bert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultMinScore is the keyword score below which a query is general.
+const defaultMinScore = 0.1
+
+// Model is a classifier tuned on labeled queries, as written by
+// cmd/classify-tune and loaded at startup.
+type Model struct {
+	// Weights scale the score of keywords of each intent; keywords not
+	// listed keep weight 1
+	Weights map[Intent]map[string]float64 `json:"weights,omitempty"`
+	// MinScore is the keyword score below which a query is general
+	// (default 0.1)
+	MinScore float64 `json:"min_score,omitempty"`
+	// Examples are example questions of each intent, compared with
+	// queries by a SemanticClassifier in addition to DefaultExamples
+	Examples map[Intent][]string `json:"examples,omitempty"`
+	// Accuracy is the accuracy measured on the training queries
+	Accuracy float64 `json:"accuracy,omitempty"`
+}
+
+// LoadModel reads a model file.
+func LoadModel(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read classifier model: %w", err)
+	}
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse classifier model: %w", err)
+	}
+	return &m, nil
+}
+
+// Save writes the model to path as JSON.
+func (m *Model) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode classifier model: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write classifier model: %w", err)
+	}
+	return nil
+}
+
+// Apply sets the keyword weights and threshold of m on c.
+func (c *Classifier) Apply(m *Model) {
+	c.weights = make(map[Intent]map[string]float64, len(m.Weights))
+	for intent, weights := range m.Weights {
+		c.weights[intent] = make(map[string]float64, len(weights))
+		for keyword, w := range weights {
+			c.weights[intent][strings.ToLower(keyword)] = w
+		}
+	}
+	c.threshold = m.MinScore
+}
+
+// Model returns the classifier's keyword weights and threshold as a Model.
+func (c *Classifier) Model() *Model {
+	m := &Model{MinScore: c.minScore()}
+	for intent, weights := range c.weights {
+		for keyword, w := range weights {
+			if w == 1 {
+				continue
+			}
+			if m.Weights == nil {
+				m.Weights = make(map[Intent]map[string]float64)
+			}
+			if m.Weights[intent] == nil {
+				m.Weights[intent] = make(map[string]float64)
+			}
+			m.Weights[intent][keyword] = w
+		}
+	}
+	return m
+}
+
+// minScore is the score below which a query is general.
+func (c *Classifier) minScore() float64 {
+	if c.threshold > 0 {
+		return c.threshold
+	}
+	return defaultMinScore
+}
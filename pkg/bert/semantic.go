@@ -0,0 +1,154 @@
+package bert
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// DefaultExamples are example questions of the built-in intents that a
+// SemanticClassifier compares queries with.
+var DefaultExamples = map[Intent][]string{
+	IntentSQLQuery: {
+		"how many orders were placed last month",
+		"list the top 10 customers by revenue",
+		"show me the users who signed up this week",
+		"what is the average order value per country",
+		"which tables are in the database",
+		"find the products that are out of stock",
+	},
+	IntentVisualization: {
+		"plot monthly revenue as a line chart",
+		"draw a bar chart of sales by region",
+		"visualize the distribution of order sizes",
+		"make a pie chart of customers by country",
+		"graph the trend of signups over time",
+	},
+	IntentGeneral: {
+		"hello",
+		"what can you do",
+		"how do I use this assistant",
+		"thank you",
+		"explain what a left join is",
+	},
+}
+
+// defaultTemperature turns the lead of the best intent into a confidence:
+// a lead of 0.1 in cosine similarity gives about 0.86, 0.02 about 0.33.
+const defaultTemperature = 0.05
+
+// SemanticClassifier classifies queries by the cosine similarity of their
+// embedding to those of example questions of each intent. When the
+// embedder fails, the keyword Classifier decides instead.
+type SemanticClassifier struct {
+	embedder Embedder
+	keywords *Classifier
+	// Temperature scales the lead of the best intent over the next before
+	// it is turned into a confidence; lower is more confident (default 0.05)
+	Temperature float64
+
+	mu       sync.Mutex
+	examples map[Intent][]string
+	// vectors holds the embeddings of examples, computed on first use
+	vectors map[Intent][][]float32
+}
+
+// NewSemanticClassifier creates a classifier comparing queries with
+// DefaultExamples through embedder, falling back to keywords (a new
+// keyword Classifier if nil).
+func NewSemanticClassifier(embedder Embedder, keywords *Classifier) *SemanticClassifier {
+	if keywords == nil {
+		keywords = NewClassifier()
+	}
+	c := &SemanticClassifier{embedder: embedder, keywords: keywords, Temperature: defaultTemperature, examples: make(map[Intent][]string)}
+	for intent, examples := range DefaultExamples {
+		c.examples[intent] = append([]string(nil), examples...)
+	}
+	return c
+}
+
+// AddIntent adds an intent recognized by the given example questions or
+// keywords, or extends the examples of an existing one.
+func (c *SemanticClassifier) AddIntent(intent Intent, examples []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.examples[intent] = append(c.examples[intent], examples...)
+	c.vectors = nil
+}
+
+// Keywords returns the keyword classifier used as the fallback.
+func (c *SemanticClassifier) Keywords() *Classifier {
+	return c.keywords
+}
+
+// ClassifyWithConfidence returns the intent whose examples are most
+// similar to query and a confidence between 0 and 1 from how far its
+// similarity leads the next intent's, 1 - exp(-lead/Temperature). If the query or the examples cannot
+// be embedded, the keyword classifier's reading is returned with the
+// error.
+func (c *SemanticClassifier) ClassifyWithConfidence(ctx context.Context, query string) (Intent, float64, error) {
+	vectors, err := c.exampleVectors(ctx)
+	if err == nil {
+		var v []float32
+		if v, err = c.embedder.Embed(ctx, query); err == nil {
+			intent, confidence := c.closest(v, vectors)
+			return intent, confidence, nil
+		}
+		err = fmt.Errorf("failed to embed query: %w", err)
+	}
+	intent, confidence := c.keywords.ClassifyWithConfidence(query)
+	return intent, confidence, err
+}
+
+// closest scores each intent by its most similar example.
+func (c *SemanticClassifier) closest(v []float32, vectors map[Intent][][]float32) (Intent, float64) {
+	best, bestScore, secondScore := IntentGeneral, math.Inf(-1), math.Inf(-1)
+	for intent, examples := range vectors {
+		score := math.Inf(-1)
+		for _, e := range examples {
+			score = math.Max(score, Cosine(v, e))
+		}
+		switch {
+		case score > bestScore || (score == bestScore && intent < best):
+			secondScore = bestScore
+			best, bestScore = intent, score
+		case score > secondScore:
+			secondScore = score
+		}
+	}
+	if math.IsInf(bestScore, -1) {
+		return IntentGeneral, 0
+	}
+	if math.IsInf(secondScore, -1) {
+		return best, 1
+	}
+
+	temperature := c.Temperature
+	if temperature <= 0 {
+		temperature = defaultTemperature
+	}
+	return best, 1 - math.Exp(-(bestScore-secondScore)/temperature)
+}
+
+// exampleVectors embeds the examples of every intent, once.
+func (c *SemanticClassifier) exampleVectors(ctx context.Context) (map[Intent][][]float32, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.vectors != nil {
+		return c.vectors, nil
+	}
+
+	vectors := make(map[Intent][][]float32, len(c.examples))
+	for intent, examples := range c.examples {
+		for _, example := range examples {
+			v, err := c.embedder.Embed(ctx, example)
+			if err != nil {
+				return nil, fmt.Errorf("failed to embed examples of intent %s: %w", intent, err)
+			}
+			vectors[intent] = append(vectors[intent], v)
+		}
+	}
+	c.vectors = vectors
+	return vectors, nil
+}
@@ -0,0 +1,113 @@
+package bert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// wordEmbedder embeds a sentence as the bag of its words.
+type wordEmbedder struct {
+	err error
+}
+
+func (e wordEmbedder) Embed(_ context.Context, text string) ([]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	v := make([]float32, 64)
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		v[h.Sum32()%64]++
+	}
+	return v, nil
+}
+
+func TestSemanticClassifier(t *testing.T) {
+	c := NewSemanticClassifier(wordEmbedder{}, nil)
+	c.AddIntent("forecast", []string{"forecast next quarter", "predict future demand"})
+
+	tests := []struct {
+		query string
+		want  Intent
+	}{
+		{query: "how many customers signed up", want: IntentSQLQuery},
+		{query: "draw a line chart of orders", want: IntentVisualization},
+		{query: "hello", want: IntentGeneral},
+		{query: "predict demand for next quarter", want: "forecast"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, confidence, err := c.ClassifyWithConfidence(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || confidence <= 0 || confidence > 1 {
+				t.Errorf("ClassifyWithConfidence(%q) = %s, %.2f; want %s with a confidence in (0, 1]", tt.query, got, confidence, tt.want)
+			}
+		})
+	}
+
+	failing := NewSemanticClassifier(wordEmbedder{err: errors.New("connection refused")}, nil)
+	got, _, err := failing.ClassifyWithConfidence(context.Background(), "Create a bar chart of sales by month")
+	if err == nil || got != IntentVisualization {
+		t.Errorf("ClassifyWithConfidence() = %s, %v; want the keyword reading and an error", got, err)
+	}
+}
+
+func TestEmbedders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Model, Input string }
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "mini" || req.Input != "hi" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/embed":
+			w.Write([]byte(`{"embeddings":[[0.5,1]]}`))
+		case "/v1/embeddings":
+			if r.Header.Get("Authorization") != "Bearer key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"data":[{"embedding":[1,0.5]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		embedder Embedder
+		want     []float32
+		wantErr  string
+	}{
+		{name: "ollama", embedder: OllamaEmbedder{URL: srv.URL, Model: "mini"}, want: []float32{0.5, 1}},
+		{name: "openai", embedder: OpenAIEmbedder{URL: srv.URL + "/", Model: "mini", APIKey: "key"}, want: []float32{1, 0.5}},
+		{name: "error status", embedder: OpenAIEmbedder{URL: srv.URL, Model: "mini"}, wantErr: "401 Unauthorized: unauthorized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.embedder.Embed(context.Background(), "hi")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Embed() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if Cosine(got, tt.want) < 0.9999 || len(got) != len(tt.want) {
+				t.Errorf("Embed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
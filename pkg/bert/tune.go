@@ -0,0 +1,196 @@
+package bert
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Sample is a query labeled with its intent.
+type Sample struct {
+	Query  string
+	Intent Intent
+}
+
+// ReadSamples reads labeled queries from CSV with a query and an intent
+// column. A header row naming the columns query and intent is optional and
+// may list them in either order; without one the query comes first.
+func ReadSamples(r io.Reader) ([]Sample, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	queryCol, intentCol := 0, 1
+	var samples []Sample
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read samples: %w", err)
+		}
+		if line == 1 {
+			q := slices.IndexFunc(record, func(s string) bool { return strings.EqualFold(strings.TrimSpace(s), "query") })
+			i := slices.IndexFunc(record, func(s string) bool { return strings.EqualFold(strings.TrimSpace(s), "intent") })
+			if q >= 0 && i >= 0 {
+				queryCol, intentCol = q, i
+				continue
+			}
+		}
+		if len(record) <= max(queryCol, intentCol) {
+			return nil, fmt.Errorf("line %d: want a query and an intent", line)
+		}
+		query, intent := strings.TrimSpace(record[queryCol]), strings.TrimSpace(record[intentCol])
+		if query == "" || intent == "" {
+			return nil, fmt.Errorf("line %d: want a query and an intent", line)
+		}
+		samples = append(samples, Sample{Query: query, Intent: Intent(strings.ToLower(intent))})
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no labeled queries")
+	}
+	return samples, nil
+}
+
+// Split divides samples into a training set and every n-th sample held out
+// to measure the tuned classifier on queries it was not tuned on.
+// fraction is the share held out, between 0 and 0.5.
+func Split(samples []Sample, fraction float64) (train, holdout []Sample) {
+	if fraction <= 0 {
+		return samples, nil
+	}
+	every := max(int(1/min(fraction, 0.5)+0.5), 2)
+	for i, s := range samples {
+		if i%every == every-1 {
+			holdout = append(holdout, s)
+		} else {
+			train = append(train, s)
+		}
+	}
+	return train, holdout
+}
+
+// Miss is a query classified as the wrong intent.
+type Miss struct {
+	Sample
+	Got Intent
+}
+
+// Evaluation measures a classifier on labeled queries.
+type Evaluation struct {
+	Total   int
+	Correct int
+	// PerIntent counts the queries and correct ones by expected intent
+	PerIntent map[Intent]*IntentCount
+	// Misses are the queries classified wrongly, in order
+	Misses []Miss
+}
+
+// IntentCount counts the queries of an intent and those classified right.
+type IntentCount struct {
+	Total   int
+	Correct int
+}
+
+// Accuracy is the share of queries classified right.
+func (e Evaluation) Accuracy() float64 {
+	if e.Total == 0 {
+		return 0
+	}
+	return float64(e.Correct) / float64(e.Total)
+}
+
+// Evaluate classifies every sample.
+func Evaluate(classify func(query string) Intent, samples []Sample) Evaluation {
+	e := Evaluation{PerIntent: make(map[Intent]*IntentCount)}
+	for _, s := range samples {
+		count := e.PerIntent[s.Intent]
+		if count == nil {
+			count = &IntentCount{}
+			e.PerIntent[s.Intent] = count
+		}
+		e.Total++
+		count.Total++
+		if got := classify(s.Query); got == s.Intent {
+			e.Correct++
+			count.Correct++
+		} else {
+			e.Misses = append(e.Misses, Miss{Sample: s, Got: got})
+		}
+	}
+	return e
+}
+
+var (
+	// tuneWeights are the keyword weights Tune tries
+	tuneWeights = []float64{0, 0.5, 1, 1.5, 2, 3}
+	// tuneThresholds are the minimum scores Tune tries
+	tuneThresholds = []float64{0.02, 0.05, 0.1, 0.15, 0.2, 0.3}
+)
+
+// Tune searches the keyword weights and minimum score that classify
+// samples best, and sets them on c. It is a coordinate ascent: each
+// keyword's weight in turn, then the threshold, is set to the candidate
+// that classifies the most samples right, for up to rounds passes or until
+// a pass changes nothing. Only strict improvements are kept, so weights
+// stay at 1 unless the samples call for a change.
+func (c *Classifier) Tune(samples []Sample, rounds int) *Model {
+	if c.weights == nil {
+		c.weights = make(map[Intent]map[string]float64)
+	}
+	correct := func() int {
+		return Evaluate(c.Classify, samples).Correct
+	}
+
+	best := correct()
+	for round := 0; round < rounds; round++ {
+		improved := false
+		for _, intent := range c.Intents() {
+			if c.weights[intent] == nil {
+				c.weights[intent] = make(map[string]float64)
+			}
+			seen := make(map[string]bool)
+			for _, keyword := range c.intentPrototypes[intent] {
+				if seen[keyword] {
+					continue
+				}
+				seen[keyword] = true
+				current := c.weight(intent, keyword)
+				for _, w := range tuneWeights {
+					if w == current {
+						continue
+					}
+					c.weights[intent][keyword] = w
+					if n := correct(); n > best {
+						best, current, improved = n, w, true
+					}
+				}
+				c.weights[intent][keyword] = current
+			}
+		}
+
+		current := c.minScore()
+		for _, t := range tuneThresholds {
+			if t == current {
+				continue
+			}
+			c.threshold = t
+			if n := correct(); n > best {
+				best, current, improved = n, t, true
+			}
+		}
+		c.threshold = current
+
+		if !improved {
+			break
+		}
+	}
+
+	m := c.Model()
+	m.Accuracy = float64(best) / float64(max(len(samples), 1))
+	return m
+}
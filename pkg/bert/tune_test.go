@@ -0,0 +1,94 @@
+package bert
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadSamples(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []Sample
+		wantErr string
+	}{
+		{
+			name: "header",
+			csv:  "intent,query\nSQL_QUERY,\"count orders, by day\"\n",
+			want: []Sample{{Query: "count orders, by day", Intent: IntentSQLQuery}},
+		},
+		{
+			name: "no header",
+			csv:  "hello,general\nplot sales,visualization\n",
+			want: []Sample{{Query: "hello", Intent: IntentGeneral}, {Query: "plot sales", Intent: IntentVisualization}},
+		},
+		{name: "missing intent", csv: "query,intent\nhello\n", wantErr: "line 2"},
+		{name: "empty", csv: "query,intent\n", wantErr: "no labeled queries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadSamples(strings.NewReader(tt.csv))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ReadSamples() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ReadSamples() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("sample %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	samples := make([]Sample, 10)
+	if train, holdout := Split(samples, 0.2); len(train) != 8 || len(holdout) != 2 {
+		t.Errorf("Split(10, 0.2) = %d, %d; want 8, 2", len(train), len(holdout))
+	}
+	if train, holdout := Split(samples, 0); len(train) != 10 || holdout != nil {
+		t.Errorf("Split(10, 0) = %d, %d; want 10, 0", len(train), len(holdout))
+	}
+}
+
+func TestTune(t *testing.T) {
+	samples := []Sample{
+		{Query: "list the overdue invoices", Intent: IntentSQLQuery},
+		{Query: "show the list of open tickets", Intent: IntentSQLQuery},
+		{Query: "what is a join", Intent: IntentGeneral},
+		{Query: "Create a bar chart of sales by month", Intent: IntentVisualization},
+	}
+	c := NewClassifier()
+	before := Evaluate(c.Classify, samples).Correct
+
+	m := c.Tune(samples, 3)
+	after := Evaluate(c.Classify, samples).Correct
+	if after < before || m.Accuracy != float64(after)/float64(len(samples)) {
+		t.Errorf("Tune() classified %d right, %d before, accuracy %.2f", after, before, m.Accuracy)
+	}
+
+	path := filepath.Join(t.TempDir(), "classifier.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadModel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuned := NewClassifier()
+	tuned.Apply(loaded)
+	for _, s := range samples {
+		if got, want := tuned.Classify(s.Query), c.Classify(s.Query); got != want {
+			t.Errorf("loaded model classifies %q as %s, tuned classifier as %s", s.Query, got, want)
+		}
+	}
+}
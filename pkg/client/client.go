@@ -0,0 +1,618 @@
+// Package client is a Go client for the multi-agent HTTP API.
+//
+//	c := client.New(client.Config{BaseURL: "http://localhost:8080"})
+//	turn, err := c.Ask(ctx, client.AskRequest{Query: "How many orders are there per month?"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anuvratrastogi/multi-agent/pkg/e2e"
+)
+
+// Config holds configuration for the client.
+type Config struct {
+	// BaseURL is the address of the agent server (e.g., "http://localhost:8080")
+	BaseURL string
+	// HTTPClient sends the requests (defaults to http.DefaultClient)
+	HTTPClient *http.Client
+	// MaxRetries is the number of times a failed request is retried
+	// (defaults to 3; negative disables retries)
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled after each
+	// attempt (defaults to 500ms)
+	RetryBackoff time.Duration
+	// EncryptionKey seals requests and opens responses end to end, for
+	// servers configured with the same key (optional, see package e2e).
+	// Use HTTPClient to configure TLS client certificates.
+	EncryptionKey []byte
+	// APIKey is sent as a bearer token to servers that require credentials;
+	// an OIDC access token works the same way (optional)
+	APIKey string
+}
+
+// Client calls the agent server's /v1 API.
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	maxRetries int
+	backoff    time.Duration
+	key        []byte
+	apiKey     string
+}
+
+// New creates a new Client.
+func New(cfg Config) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		http:       cfg.HTTPClient,
+		maxRetries: cfg.MaxRetries,
+		backoff:    cfg.RetryBackoff,
+		key:        cfg.EncryptionKey,
+		apiKey:     cfg.APIKey,
+	}
+	if c.http == nil {
+		c.http = http.DefaultClient
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = 3
+	}
+	if c.backoff <= 0 {
+		c.backoff = 500 * time.Millisecond
+	}
+	return c
+}
+
+// AskRequest asks a question in a session. Empty IDs use the server's
+// defaults.
+type AskRequest struct {
+	UserID    string `json:"user_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Query     string `json:"query"`
+	// DryRun generates SQL without executing it
+	DryRun bool `json:"dry_run,omitempty"`
+	// Fresh answers the question with the agents even if a similar
+	// question's answer is cached
+	Fresh bool `json:"fresh,omitempty"`
+	// PromptVariant pins the session to a prompt variant for this and
+	// later questions; "auto" unpins it
+	PromptVariant string `json:"prompt_variant,omitempty"`
+}
+
+// ApprovalRequest approves or rejects a write proposed in an earlier turn.
+// The server executes the statement it recorded under Approval.CallID.
+type ApprovalRequest struct {
+	UserID    string   `json:"user_id,omitempty"`
+	SessionID string   `json:"session_id,omitempty"`
+	Approval  Approval `json:"approval"`
+	Approved  bool     `json:"approved"`
+}
+
+// Turn is the answer to a question.
+type Turn struct {
+	Routing   *Routing   `json:"routing,omitempty"`
+	Text      string     `json:"text"`
+	ToolCalls []string   `json:"tool_calls,omitempty"`
+	Queries   []Query    `json:"queries,omitempty"`
+	Charts    []string   `json:"charts,omitempty"`
+	Approvals []Approval `json:"approvals,omitempty"`
+	// Clarification is a question the agents asked instead of answering;
+	// send the reply as the next question of the session
+	Clarification *Clarification `json:"clarification,omitempty"`
+	DryRun        bool           `json:"dry_run,omitempty"`
+	Slots         []Slot         `json:"slots,omitempty"`
+	// Filters is the union of the filters applied so far in the session
+	Filters []Filter   `json:"filters,omitempty"`
+	Tokens  TokenUsage `json:"tokens"`
+	// Cached is set if the answer was reused from a similar question
+	Cached *CacheHit `json:"cached,omitempty"`
+	// Stopped says which limit of the server's turn budget cut the turn
+	// short; the rest of the turn holds what was done until then
+	Stopped string `json:"stopped,omitempty"`
+	// Summary accounts for the agents, queries, charts, tokens and time of
+	// the turn
+	Summary *Summary `json:"summary,omitempty"`
+	// Artifacts are the charts, exports and reports stored by the turn;
+	// download them with GetStoredArtifact
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	// Guardrails lists the content rules that redacted or blocked parts of
+	// the turn
+	Guardrails []GuardrailViolation `json:"guardrails,omitempty"`
+}
+
+// GuardrailViolation records a content rule that matched.
+type GuardrailViolation struct {
+	Rule string `json:"rule"`
+	// Target is "response" or "tool_args"
+	Target string `json:"target"`
+	Tool   string `json:"tool,omitempty"`
+	// Action is "block" or "redact"
+	Action string `json:"action"`
+	// Reason is the model's explanation for policies
+	Reason string `json:"reason,omitempty"`
+	// Error is set if the output was blocked because the model could not
+	// judge it
+	Error string `json:"error,omitempty"`
+}
+
+// Artifact describes a stored chart, export or report.
+type Artifact struct {
+	ID string `json:"id"`
+	// Kind is "chart", "export" or "report"
+	Kind string `json:"kind"`
+	// Name is the file name to save the artifact as
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	UserID      string    `json:"user_id,omitempty"`
+	SessionID   string    `json:"session_id,omitempty"`
+	Created     time.Time `json:"created"`
+	// URL is where the artifact can be downloaded: a signed URL if the
+	// server stores artifacts in a bucket, or else a path on the API
+	URL string `json:"url,omitempty"`
+}
+
+// Summary is what the agents did in a turn.
+type Summary struct {
+	// Agents are the agents that responded, in order
+	Agents  []string       `json:"agents,omitempty"`
+	Queries []QuerySummary `json:"queries,omitempty"`
+	Charts  int            `json:"charts,omitempty"`
+	// StepsDone and Steps count the steps of a plan carried out and planned
+	StepsDone int `json:"steps_done,omitempty"`
+	Steps     int `json:"steps,omitempty"`
+	// Cached is true if the answer came from the answer cache
+	Cached     bool       `json:"cached,omitempty"`
+	Tokens     TokenUsage `json:"tokens"`
+	DurationMS int64      `json:"duration_ms"`
+}
+
+// QuerySummary is a query of a turn and its outcome.
+type QuerySummary struct {
+	SQL   string `json:"sql"`
+	Rows  int    `json:"rows"`
+	Error string `json:"error,omitempty"`
+	// DryRun is true if the query was generated but not run
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// Clarification is a question asked to resolve an ambiguous request, with
+// the likely answers.
+type Clarification struct {
+	Question string   `json:"question"`
+	Options  []string `json:"options,omitempty"`
+}
+
+// CacheHit describes the earlier question whose answer was reused.
+type CacheHit struct {
+	Question   string    `json:"question"`
+	Similarity float64   `json:"similarity"`
+	AnsweredAt time.Time `json:"answered_at"`
+}
+
+// Filter is a condition on a column taken from a query's WHERE clause.
+type Filter struct {
+	Column string `json:"column"`
+	Op     string `json:"op"`
+	Value  string `json:"value"`
+}
+
+// TokenUsage counts the LLM tokens consumed by a turn.
+type TokenUsage struct {
+	Prompt     int `json:"prompt"`
+	Completion int `json:"completion"`
+}
+
+// Routing is the intent classification and planned workflow of a question.
+type Routing struct {
+	Query            string   `json:"query"`
+	ClassifiedIntent string   `json:"classified_intent"`
+	Confidence       float64  `json:"confidence"`
+	AgentsUsed       []string `json:"agents_used"`
+	Workflow         string   `json:"workflow"`
+}
+
+// Query is a query run during a turn. Data holds the rows as a JSON array
+// of objects.
+type Query struct {
+	SQL   string `json:"sql"`
+	Data  string `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Rows decodes the query's result rows.
+func (q Query) Rows() ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	if q.Data == "" {
+		return nil, nil
+	}
+	if err := json.Unmarshal([]byte(q.Data), &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode rows: %w", err)
+	}
+	return rows, nil
+}
+
+// Approval is a write awaiting the user's decision.
+type Approval struct {
+	CallID string `json:"call_id"`
+	SQL    string `json:"sql"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Slot is a locale-formatted number or date read from the question.
+type Slot struct {
+	Text  string `json:"text"`
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Session summarizes a conversation session.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Events    int       `json:"events"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Thought is a reasoning segment emitted by an agent's model.
+type Thought struct {
+	Agent string `json:"agent"`
+	Text  string `json:"text"`
+}
+
+// TraceEntry is the reasoning kept for one question of a session.
+type TraceEntry struct {
+	Time      time.Time `json:"time"`
+	Question  string    `json:"question"`
+	Reasoning []Thought `json:"reasoning"`
+}
+
+// Artifacts available for every session.
+const (
+	ArtifactTranscriptMarkdown = "transcript.md"
+	ArtifactTranscriptHTML     = "transcript.html"
+)
+
+// APIError is returned when the server answers with an error status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("agent server returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Ask answers a question and returns the complete turn.
+func (c *Client) Ask(ctx context.Context, req AskRequest) (*Turn, error) {
+	var turn Turn
+	if err := c.doJSON(ctx, http.MethodPost, "/v1/ask", req, &turn); err != nil {
+		return nil, err
+	}
+	return &turn, nil
+}
+
+// Accepted identifies a question answered in the background.
+type Accepted struct {
+	RequestID string `json:"request_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+}
+
+// Callback is posted to the callback URL of AskAsync when the turn
+// completes.
+type Callback struct {
+	RequestID string `json:"request_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"session_id"`
+	Query     string `json:"query"`
+	Turn      *Turn  `json:"turn,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AskAsync asks a question without waiting for the answer: the server posts
+// a Callback to callbackURL when the turn completes. Use ReadCallback in the
+// handler receiving it.
+func (c *Client) AskAsync(ctx context.Context, req AskRequest, callbackURL string) (*Accepted, error) {
+	body := struct {
+		AskRequest
+		CallbackURL string `json:"callback_url"`
+	}{req, callbackURL}
+	var accepted Accepted
+	if err := c.doJSON(ctx, http.MethodPost, "/v1/ask", body, &accepted); err != nil {
+		return nil, err
+	}
+	return &accepted, nil
+}
+
+// ReadCallback decodes a Callback posted by the server, opening it if it
+// was sealed because the question was sent encrypted.
+func (c *Client) ReadCallback(r *http.Request) (*Callback, error) {
+	body := r.Body
+	if r.Header.Get(e2e.Header) != "" {
+		resp := &http.Response{Body: r.Body}
+		if err := c.open(resp); err != nil {
+			return nil, err
+		}
+		body = resp.Body
+	}
+	var cb Callback
+	if err := json.NewDecoder(body).Decode(&cb); err != nil {
+		return nil, fmt.Errorf("failed to decode callback: %w", err)
+	}
+	return &cb, nil
+}
+
+// Approve approves or rejects a pending write and returns the resulting turn.
+func (c *Client) Approve(ctx context.Context, req ApprovalRequest) (*Turn, error) {
+	var turn Turn
+	if err := c.doJSON(ctx, http.MethodPost, "/v1/approvals", req, &turn); err != nil {
+		return nil, err
+	}
+	return &turn, nil
+}
+
+// ListSessions returns the user's sessions, most recently updated first.
+func (c *Client) ListSessions(ctx context.Context, userID string) ([]Session, error) {
+	var resp struct {
+		Sessions []Session `json:"sessions"`
+	}
+	path := "/v1/sessions"
+	if userID != "" {
+		path += "?user_id=" + url.QueryEscape(userID)
+	}
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Sessions, nil
+}
+
+// GetArtifact downloads a session document such as
+// ArtifactTranscriptMarkdown.
+func (c *Client) GetArtifact(ctx context.Context, userID, sessionID, name string) ([]byte, error) {
+	path := "/v1/sessions/" + url.PathEscape(sessionID) + "/artifacts/" + url.PathEscape(name)
+	if userID != "" {
+		path += "?user_id=" + url.QueryEscape(userID)
+	}
+	resp, err := c.do(ctx, http.MethodGet, path, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return data, nil
+}
+
+// GetStoredArtifact downloads a stored chart, export or report by its
+// Artifact.ID. Only the user whose turn stored it may download it.
+func (c *Client) GetStoredArtifact(ctx context.Context, userID, id string) ([]byte, error) {
+	path := "/artifacts/" + url.PathEscape(id)
+	if userID != "" {
+		path += "?user_id=" + url.QueryEscape(userID)
+	}
+	resp, err := c.do(ctx, http.MethodGet, path, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return data, nil
+}
+
+// GetTrace returns the models' reasoning for each of the session's turns.
+// The server only keeps reasoning when KEEP_REASONING is set.
+func (c *Client) GetTrace(ctx context.Context, userID, sessionID string) ([]TraceEntry, error) {
+	var resp struct {
+		Turns []TraceEntry `json:"turns"`
+	}
+	path := "/v1/sessions/" + url.PathEscape(sessionID) + "/trace"
+	if userID != "" {
+		path += "?user_id=" + url.QueryEscape(userID)
+	}
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Turns, nil
+}
+
+// Health checks that the server is up.
+func (c *Client) Health(ctx context.Context) error {
+	var status map[string]string
+	return c.doJSON(ctx, http.MethodGet, "/v1/health", nil, &status)
+}
+
+// doJSON sends body as JSON and decodes the response into out.
+func (c *Client) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	resp, err := c.do(ctx, method, path, payload, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// do sends a request, retrying transport errors and temporary server errors
+// with exponential backoff. POST requests, which ask questions and decide
+// on writes, are only retried when they cannot have reached the server
+// (see retryable and unsent). The caller closes the body of a successful
+// response, which has been decrypted unless it is an event stream.
+func (c *Client) do(ctx context.Context, method, path string, payload []byte, accept string) (*http.Response, error) {
+	contentType := "application/json"
+	if c.key != nil && payload != nil {
+		sealed, err := e2e.Seal(c.key, payload)
+		if err != nil {
+			return nil, err
+		}
+		payload, contentType = sealed, "text/plain"
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if c.key != nil {
+			req.Header.Set(e2e.Header, e2e.Algorithm)
+		}
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+
+		resp, err := c.http.Do(req)
+		if err == nil && resp.Header.Get(e2e.Header) != "" && !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			if err := c.open(resp); err != nil {
+				return nil, err
+			}
+		}
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		var delay time.Duration
+		if err == nil {
+			err = readError(resp)
+			if !retryable(method, resp.StatusCode) {
+				return nil, err
+			}
+			delay = retryAfter(resp)
+		} else {
+			err = fmt.Errorf("request failed: %w", err)
+			// A question or approval that may have reached the server
+			// must not be sent twice
+			if !idempotent(method) && !unsent(err) {
+				return nil, err
+			}
+		}
+
+		if attempt >= c.maxRetries || ctx.Err() != nil {
+			return nil, err
+		}
+		if delay == 0 {
+			delay = backoff
+			backoff *= 2
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// open replaces a sealed response body with its plaintext.
+func (c *Client) open(resp *http.Response) error {
+	defer resp.Body.Close()
+	if c.key == nil {
+		return fmt.Errorf("response is encrypted but no encryption key is configured")
+	}
+	sealed, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	plaintext, err := e2e.Open(c.key, sealed)
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(plaintext))
+	return nil
+}
+
+// readError turns an error response into an *APIError and closes its body.
+func readError(resp *http.Response) error {
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	var e struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error != "" {
+		apiErr.Message = e.Error
+	}
+	return apiErr
+}
+
+// retryable reports whether a request with method that failed with status
+// may be sent again. The server turns requests away with 429 and 503 before
+// handling them, so those are always retried; a gateway's 502 or 504 may
+// come after the server acted on the request, so only idempotent requests
+// are retried then.
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+// idempotent reports whether sending a request with method twice has the
+// same effect as sending it once.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// unsent reports whether a request that failed with err never reached the
+// server: the connection could not be made.
+func unsent(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryAfter returns the delay requested by a Retry-After header in seconds.
+func retryAfter(resp *http.Response) time.Duration {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+// IsNotFound reports whether err is an APIError for a missing resource.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAskRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		status    int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "retries unavailable", failures: 2, status: http.StatusServiceUnavailable, wantCalls: 3},
+		{name: "gives up", failures: 10, status: http.StatusServiceUnavailable, wantCalls: 4, wantErr: true},
+		{name: "no retry on bad request", failures: 10, status: http.StatusBadRequest, wantCalls: 1, wantErr: true},
+		{name: "no retry after a gateway timeout", failures: 10, status: http.StatusGatewayTimeout, wantCalls: 1, wantErr: true},
+		{name: "retries rate limits", failures: 1, status: http.StatusTooManyRequests, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(calls.Add(1)) <= tt.failures {
+					w.WriteHeader(tt.status)
+					fmt.Fprint(w, `{"error":"try again"}`)
+					return
+				}
+				fmt.Fprint(w, `{"text":"42 orders","queries":[{"sql":"SELECT 42","data":"[{\"n\":42}]"}]}`)
+			}))
+			defer srv.Close()
+
+			c := New(Config{BaseURL: srv.URL, RetryBackoff: time.Millisecond})
+			turn, err := c.Ask(context.Background(), AskRequest{Query: "How many orders?"})
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+			if tt.wantErr {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.Message != "try again" {
+					t.Errorf("err = %v, want APIError %q", err, "try again")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Ask failed: %v", err)
+			}
+			rows, err := turn.Queries[0].Rows()
+			if err != nil || len(rows) != 1 || rows[0]["n"] != 42.0 {
+				t.Errorf("rows = %v (%v), want [{n: 42}]", rows, err)
+			}
+		})
+	}
+}
+
+func TestStreamAsk(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: tool_call\ndata: {\"name\":\"query_database\"}\n\n")
+		fmt.Fprint(w, "event: chart\ndata: {\"mermaid\":\"pie\",\"partial\":true}\n\n")
+		fmt.Fprint(w, "event: turn\ndata: {\"text\":\"done\"}\n\n")
+	}))
+	defer srv.Close()
+
+	stream, err := New(Config{BaseURL: srv.URL}).StreamAsk(context.Background(), AskRequest{Query: "q"})
+	if err != nil {
+		t.Fatalf("StreamAsk failed: %v", err)
+	}
+	defer stream.Close()
+
+	var types []string
+	turn, err := stream.Wait(func(e Event) { types = append(types, e.Type) })
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if turn.Text != "done" {
+		t.Errorf("turn.Text = %q, want %q", turn.Text, "done")
+	}
+	want := []string{EventToolCall, EventChart, EventTurn}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Errorf("events = %v, want %v", types, want)
+	}
+}
+
+func TestRetriesIdempotentOnly(t *testing.T) {
+	var asks, lists atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if lists.Add(1) == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			fmt.Fprint(w, `{"sessions":[]}`)
+			return
+		}
+		// The question reached the server, but the connection drops
+		// before the answer
+		asks.Add(1)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer srv.Close()
+	c := New(Config{BaseURL: srv.URL, RetryBackoff: time.Millisecond})
+
+	if _, err := c.Ask(context.Background(), AskRequest{Query: "Delete order 1"}); err == nil {
+		t.Error("Ask() succeeded over a dropped connection")
+	}
+	if got := asks.Load(); got != 1 {
+		t.Errorf("question sent %d times, want once", got)
+	}
+	if _, err := c.ListSessions(context.Background(), "u"); err != nil {
+		t.Errorf("ListSessions() error = %v, want a retry after the bad gateway", err)
+	}
+	if got := lists.Load(); got != 2 {
+		t.Errorf("sessions listed %d times, want 2", got)
+	}
+
+	// Requests that could not connect are retried whatever their method
+	srv.Close()
+	if _, err := c.Ask(context.Background(), AskRequest{Query: "q"}); !unsent(err) {
+		t.Errorf("Ask() to a closed server error = %v, want the request unsent", err)
+	}
+}
+
+func TestTurnFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"text":"Partial answer.","stopped":"reached the limit of 5 LLM calls",`+
+			`"summary":{"agents":["SQLAgent"],"queries":[{"sql":"SELECT 1","rows":1}],"tokens":{"prompt":10,"completion":2},"duration_ms":1500},`+
+			`"artifacts":[{"id":"a1","kind":"chart","name":"chart.png","content_type":"image/png","size":3,"created":"2026-01-02T03:04:05Z","url":"/artifacts/a1"}],`+
+			`"guardrails":[{"rule":"emails","target":"response","action":"redact"}]}`)
+	}))
+	defer srv.Close()
+
+	turn, err := New(Config{BaseURL: srv.URL}).Ask(context.Background(), AskRequest{Query: "q"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if turn.Stopped != "reached the limit of 5 LLM calls" {
+		t.Errorf("Stopped = %q", turn.Stopped)
+	}
+	if s := turn.Summary; s == nil || len(s.Queries) != 1 || s.Queries[0].Rows != 1 || s.Tokens.Prompt != 10 || s.DurationMS != 1500 {
+		t.Errorf("Summary = %+v", turn.Summary)
+	}
+	if len(turn.Artifacts) != 1 || turn.Artifacts[0].ID != "a1" || turn.Artifacts[0].Kind != "chart" || turn.Artifacts[0].Created.IsZero() {
+		t.Errorf("Artifacts = %+v", turn.Artifacts)
+	}
+	if want := (GuardrailViolation{Rule: "emails", Target: "response", Action: "redact"}); len(turn.Guardrails) != 1 || turn.Guardrails[0] != want {
+		t.Errorf("Guardrails = %+v, want [%+v]", turn.Guardrails, want)
+	}
+}
+
+func TestGetStoredArtifact(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/artifacts/a1" || r.URL.Query().Get("user_id") != "u1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "png")
+	}))
+	defer srv.Close()
+	c := New(Config{BaseURL: srv.URL})
+
+	data, err := c.GetStoredArtifact(context.Background(), "u1", "a1")
+	if err != nil || string(data) != "png" {
+		t.Errorf("GetStoredArtifact() = %q, %v", data, err)
+	}
+	if _, err := c.GetStoredArtifact(context.Background(), "u2", "a1"); err == nil {
+		t.Error("GetStoredArtifact() of another user's artifact succeeded")
+	}
+}
@@ -0,0 +1,62 @@
+package client_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/anuvratrastogi/multi-agent/pkg/client"
+)
+
+func ExampleClient_Ask() {
+	c := client.New(client.Config{BaseURL: "http://localhost:8080"})
+
+	turn, err := c.Ask(context.Background(), client.AskRequest{
+		SessionID: "monthly-report",
+		Query:     "How many orders are there per month?",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(turn.Text)
+	for _, q := range turn.Queries {
+		fmt.Println(q.SQL)
+	}
+}
+
+func ExampleClient_StreamAsk() {
+	c := client.New(client.Config{BaseURL: "http://localhost:8080"})
+
+	stream, err := c.StreamAsk(context.Background(), client.AskRequest{Query: "Chart sales by month"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stream.Close()
+
+	turn, err := stream.Wait(func(e client.Event) {
+		if e.Type == client.EventChart {
+			var chart client.ChartEvent
+			if e.Decode(&chart) == nil {
+				fmt.Printf("chart (partial=%v):\n%s\n", chart.Partial, chart.Mermaid)
+			}
+		}
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(turn.Text)
+}
+
+func ExampleClient_GetArtifact() {
+	c := client.New(client.Config{BaseURL: "http://localhost:8080"})
+
+	sessions, err := c.ListSessions(context.Background(), "api")
+	if err != nil || len(sessions) == 0 {
+		log.Fatal("no sessions")
+	}
+	report, err := c.GetArtifact(context.Background(), "api", sessions[0].ID, client.ArtifactTranscriptMarkdown)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(report))
+}
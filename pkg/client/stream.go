@@ -0,0 +1,185 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/anuvratrastogi/multi-agent/pkg/e2e"
+)
+
+// Event types sent by StreamAsk.
+const (
+	EventRouting  = "routing"
+	EventToolCall = "tool_call"
+	EventProgress = "progress"
+	EventRows     = "rows"
+	EventChart    = "chart"
+	EventText     = "text"
+	EventTurn     = "turn"
+	EventError    = "error"
+)
+
+// Event is one Server-Sent Event of a streamed turn. Use Decode, or the
+// typed accessors, to read its data.
+type Event struct {
+	Type string
+	Data json.RawMessage
+}
+
+// Decode unmarshals the event's data into v.
+func (e Event) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("failed to decode %s event: %w", e.Type, err)
+	}
+	return nil
+}
+
+// ToolCallEvent reports a tool invocation.
+type ToolCallEvent struct {
+	Name string `json:"name"`
+}
+
+// ProgressEvent reports a status change, e.g. waiting for a database slot.
+type ProgressEvent struct {
+	Message string `json:"message"`
+}
+
+// RowsEvent reports the progress of a running query.
+type RowsEvent struct {
+	SQL     string   `json:"sql"`
+	Columns []string `json:"columns"`
+	Total   int      `json:"total"`
+}
+
+// ChartEvent carries a Mermaid chart. Partial charts are drawn from the rows
+// read so far and are superseded by later chart events.
+type ChartEvent struct {
+	SQL     string `json:"sql,omitempty"`
+	Mermaid string `json:"mermaid"`
+	Rows    int    `json:"rows,omitempty"`
+	Partial bool   `json:"partial"`
+}
+
+// TextEvent carries agent narrative.
+type TextEvent struct {
+	Author string `json:"author,omitempty"`
+	Text   string `json:"text"`
+}
+
+// Stream reads the events of a streamed turn.
+type Stream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	done    bool
+	// key opens event data when the server sealed the stream
+	key []byte
+}
+
+// StreamAsk answers a question as a stream of events. Only establishing the
+// stream is retried; the caller must Close the stream.
+func (c *Client) StreamAsk(ctx context.Context, req AskRequest) (*Stream, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	resp, err := c.do(ctx, http.MethodPost, "/v1/ask/stream", payload, "text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	stream := &Stream{body: resp.Body, scanner: scanner}
+	if resp.Header.Get(e2e.Header) != "" {
+		if c.key == nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("stream is encrypted but no encryption key is configured")
+		}
+		stream.key = c.key
+	}
+	return stream, nil
+}
+
+// Next returns the next event. It returns io.EOF after the turn or error
+// event that ends the stream.
+func (s *Stream) Next() (Event, error) {
+	if s.done {
+		return Event{}, io.EOF
+	}
+
+	var event Event
+	var data strings.Builder
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "":
+			if event.Type == "" && data.Len() == 0 {
+				continue
+			}
+			event.Data = json.RawMessage(data.String())
+			if s.key != nil {
+				plaintext, err := e2e.Open(s.key, event.Data)
+				if err != nil {
+					return Event{}, err
+				}
+				event.Data = plaintext
+			}
+			if event.Type == EventTurn || event.Type == EventError {
+				s.done = true
+			}
+			return event, nil
+		case strings.HasPrefix(line, "event:"):
+			event.Type = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return Event{}, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return Event{}, io.ErrUnexpectedEOF
+}
+
+// Wait reads the remaining events, passing each to handle if it is not nil,
+// and returns the final turn.
+func (s *Stream) Wait(handle func(Event)) (*Turn, error) {
+	for {
+		event, err := s.Next()
+		if err != nil {
+			return nil, err
+		}
+		if handle != nil {
+			handle(event)
+		}
+
+		switch event.Type {
+		case EventTurn:
+			var turn Turn
+			if err := event.Decode(&turn); err != nil {
+				return nil, err
+			}
+			return &turn, nil
+		case EventError:
+			var e struct {
+				Error string `json:"error"`
+			}
+			if err := event.Decode(&e); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("turn failed: %s", e.Error)
+		}
+	}
+}
+
+// Close releases the stream's connection.
+func (s *Stream) Close() error {
+	return s.body.Close()
+}
@@ -0,0 +1,98 @@
+// Package e2e encrypts API payloads with a key shared between clients and
+// the server, so questions and results stay confidential beyond the TLS
+// connection, e.g. through proxies and load balancers that terminate TLS.
+//
+// A sealed payload is base64(nonce || AES-256-GCM ciphertext).
+package e2e
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Header is the HTTP header that marks a request or response as sealed.
+const Header = "X-Encryption"
+
+// Algorithm is the value of Header for payloads sealed by this package.
+const Algorithm = "aes-256-gcm"
+
+// KeySize is the length of a key in bytes.
+const KeySize = 32
+
+// ErrInvalidKey is returned for keys that are not KeySize bytes.
+var ErrInvalidKey = errors.New("encryption key must be 32 bytes, base64-encoded")
+
+// ParseKey decodes a base64-encoded key, e.g. from `openssl rand -base64 32`.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// GenerateKey returns a new random key, base64-encoded.
+func GenerateKey() (string, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Seal encrypts plaintext with key.
+func Seal(key, plaintext []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(out, sealed)
+	return out, nil
+}
+
+// Open decrypts a payload produced by Seal.
+func Open(key, sealed []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(sealed)))
+	n, err := base64.StdEncoding.Decode(data, []byte(strings.TrimSpace(string(sealed))))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	data = data[:n]
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("payload is too short")
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,58 @@
+package e2e
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	encoded, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseKey(encoded)
+	if err != nil {
+		t.Fatalf("ParseKey(GenerateKey()) failed: %v", err)
+	}
+	other := bytes.Repeat([]byte{1}, KeySize)
+
+	tests := []struct {
+		name    string
+		sealKey []byte
+		openKey []byte
+		mutate  func([]byte) []byte
+		wantErr bool
+	}{
+		{name: "round trip", sealKey: key, openKey: key},
+		{name: "wrong key", sealKey: key, openKey: other, wantErr: true},
+		{name: "short key", sealKey: key, openKey: key[:16], wantErr: true},
+		{name: "tampered", sealKey: key, openKey: key, wantErr: true, mutate: func(b []byte) []byte {
+			b[len(b)/2] ^= 'A' ^ 'B'
+			return b
+		}},
+	}
+
+	plaintext := []byte(`{"query":"What was revenue per customer?"}`)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := Seal(tt.sealKey, plaintext)
+			if err != nil {
+				t.Fatalf("Seal failed: %v", err)
+			}
+			if bytes.Contains(sealed, []byte("revenue")) {
+				t.Fatal("sealed payload contains plaintext")
+			}
+			if tt.mutate != nil {
+				sealed = tt.mutate(sealed)
+			}
+
+			got, err := Open(tt.openKey, sealed)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, plaintext) {
+				t.Errorf("Open() = %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
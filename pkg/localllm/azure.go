@@ -0,0 +1,38 @@
+package localllm
+
+import (
+	"net/url"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI REST API version used when
+// Azure.APIVersion is not set.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// Azure addresses a model deployed in an Azure OpenAI resource. Azure
+// serves each deployment at its own URL,
+// {endpoint}/openai/deployments/{deployment}/chat/completions, takes the API
+// version as a query parameter and authenticates with an api-key header
+// instead of a bearer token.
+type Azure struct {
+	// APIKey is a key of the Azure OpenAI resource
+	APIKey string
+	// Deployment is the deployment requests go to (defaults to Config.Model)
+	Deployment string
+	// APIVersion is the REST API version (defaults to DefaultAzureAPIVersion)
+	APIVersion string
+}
+
+// endpoint returns the chat completions URL of the deployment of model in
+// the resource at baseURL.
+func (a *Azure) endpoint(baseURL, model string) string {
+	deployment := a.Deployment
+	if deployment == "" {
+		deployment = model
+	}
+	version := a.APIVersion
+	if version == "" {
+		version = DefaultAzureAPIVersion
+	}
+	return baseURL + "/openai/deployments/" + url.PathEscape(deployment) +
+		"/chat/completions?" + url.Values{"api-version": {version}}.Encode()
+}
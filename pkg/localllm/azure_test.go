@@ -0,0 +1,42 @@
+package localllm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAzure(t *testing.T) {
+	var gotURL, gotKey, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL, gotKey, gotAuth = r.URL.String(), r.Header.Get("api-key"), r.Header.Get("Authorization")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		azure   Azure
+		wantURL string
+	}{
+		{name: "model as deployment", azure: Azure{APIKey: "key-1"}, wantURL: "/openai/deployments/gpt-4o/chat/completions?api-version=" + DefaultAzureAPIVersion},
+		{name: "mapped deployment", azure: Azure{APIKey: "key-1", Deployment: "prod gpt4o", APIVersion: "2025-01-01-preview"}, wantURL: "/openai/deployments/prod%20gpt4o/chat/completions?api-version=2025-01-01-preview"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{"X-Team": "analytics"}
+			l := New(Config{BaseURL: server.URL + "/", Model: "gpt-4o", Azure: &tt.azure, Headers: headers})
+			if _, err := l.send(context.Background(), &chatRequest{Model: l.Name()}); err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+			if gotURL != tt.wantURL || gotKey != "key-1" || gotAuth != "" {
+				t.Errorf("request to %s with api-key %q and Authorization %q, want %s with the key", gotURL, gotKey, gotAuth, tt.wantURL)
+			}
+			if len(headers) != 1 {
+				t.Errorf("New() changed the caller's headers: %v", headers)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package localllm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// ToolCalling selects how the model is given tools.
+type ToolCalling string
+
+const (
+	// ToolCallingNative sends tools through the tools API.
+	ToolCallingNative ToolCalling = "native"
+	// ToolCallingPrompt describes the tools in the system message and
+	// parses calls from the text the model writes, for models and servers
+	// without tool support.
+	ToolCallingPrompt ToolCalling = "prompt"
+	// ToolCallingAuto uses the tools API until the server rejects it, and
+	// prompts from then on.
+	ToolCallingAuto ToolCalling = "auto"
+)
+
+// toolPrompt tells the model how to call tools in text.
+const toolPrompt = `To call a tool, reply with a block like this for each call, and nothing after the blocks:
+<tool_call>
+{"name": "tool_name", "arguments": {"parameter": "value"}}
+</tool_call>
+Tool results come back in <tool_result> blocks. Once you have what you need, answer normally, without a block. Only call the tools listed here.`
+
+var (
+	// toolCallBlock matches a call in the requested format
+	toolCallBlock = regexp.MustCompile(`(?s)<tool_call>\s*(.*?)\s*(?:</tool_call>|$)`)
+	// fencedJSON matches a JSON code block, which small models often
+	// write instead
+	fencedJSON = regexp.MustCompile("(?s)```(?:json)?\\s*(\\{.*?\\})\\s*```")
+	// reactAction matches a ReAct-style call: Action: name, then
+	// Action Input: arguments up to an Observation or the end
+	reactAction = regexp.MustCompile(`(?s)Action:\s*([A-Za-z0-9_.-]+)\s*\n\s*Action Input:\s*(.*?)\s*(?:\n\s*Observation:|$)`)
+	// reactFinal marks the answer of a ReAct-style response
+	reactFinal = regexp.MustCompile(`(?s)Final Answer:\s*(.*)$`)
+)
+
+// emulateTools returns req with its tools described in the system message
+// instead of sent through the tools API, and earlier calls and results
+// written as text in the same format.
+func emulateTools(req chatRequest) chatRequest {
+	if len(req.Tools) == 0 {
+		return req
+	}
+	var b strings.Builder
+	b.WriteString("## Tools\n\nYou can call these tools. Their parameters are given as JSON Schema.\n\n")
+	for _, t := range req.Tools {
+		params, _ := json.Marshal(t.Function.Parameters)
+		fmt.Fprintf(&b, "- %s: %s\n  Parameters: %s\n", t.Function.Name, strings.TrimSpace(t.Function.Description), params)
+	}
+	b.WriteString("\n" + toolPrompt)
+
+	messages := make([]chatMessage, 0, len(req.Messages)+1)
+	if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+		system := req.Messages[0]
+		system.Content += "\n\n" + b.String()
+		messages = append(messages, system)
+		req.Messages = req.Messages[1:]
+	} else {
+		messages = append(messages, chatMessage{Role: "system", Content: b.String()})
+	}
+
+	for _, msg := range req.Messages {
+		switch {
+		case len(msg.ToolCalls) > 0:
+			text := []string{msg.Content}
+			for _, tc := range msg.ToolCalls {
+				args := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(args) {
+					args = json.RawMessage("{}")
+				}
+				call, _ := json.Marshal(struct {
+					Name      string          `json:"name"`
+					Arguments json.RawMessage `json:"arguments"`
+				}{tc.Function.Name, args})
+				text = append(text, "<tool_call>\n"+string(call)+"\n</tool_call>")
+			}
+			msg = chatMessage{Role: "assistant", Content: strings.TrimSpace(strings.Join(text, "\n"))}
+		case msg.Role == "tool":
+			msg = chatMessage{Role: "user", Content: fmt.Sprintf("<tool_result name=%q>\n%s\n</tool_result>", msg.toolName, msg.Content)}
+		}
+		// Results of several calls, and the question after them, are
+		// sent as one user message
+		if last := len(messages) - 1; last > 0 && messages[last].Role == msg.Role && msg.Role == "user" {
+			messages[last].Content += "\n" + msg.Content
+			messages[last].Images = append(messages[last].Images, msg.Images...)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+
+	req.Messages = messages
+	req.Tools, req.ToolChoice, req.ParallelToolCalls = nil, "", nil
+	return req
+}
+
+// extractToolCalls parses the tool calls the model wrote in the text of
+// msg into its ToolCalls, leaving the rest of the text as its content.
+// Calls may be in <tool_call> blocks, JSON code blocks or bare JSON objects
+// with a name and arguments, or ReAct-style Action and Action Input lines.
+func extractToolCalls(msg *chatMessage) {
+	reasoning, text := splitReasoning(msg.Content)
+	if reasoning != "" {
+		msg.Reasoning = strings.TrimSpace(msg.Reasoning + "\n" + reasoning)
+	}
+	msg.Content = text
+
+	if blocks := toolCallBlock.FindAllStringSubmatchIndex(text, -1); len(blocks) > 0 {
+		for _, m := range blocks {
+			msg.ToolCalls = append(msg.ToolCalls, parseToolCall(text[m[2]:m[3]]))
+		}
+		msg.Content = strings.TrimSpace(text[:blocks[0][0]])
+		return
+	}
+
+	for _, m := range fencedJSON.FindAllStringSubmatchIndex(text, -1) {
+		if tc, ok := jsonToolCall(text[m[2]:m[3]], true); ok {
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+			msg.Content = strings.TrimSpace(strings.Replace(msg.Content, text[m[0]:m[1]], "", 1))
+		}
+	}
+	if len(msg.ToolCalls) > 0 {
+		return
+	}
+	if tc, ok := jsonToolCall(strings.TrimSpace(text), true); ok {
+		msg.ToolCalls, msg.Content = []toolCall{tc}, ""
+		return
+	}
+
+	if m := reactFinal.FindStringSubmatch(text); m != nil {
+		msg.Content = strings.TrimSpace(m[1])
+		return
+	}
+	if actions := reactAction.FindAllStringSubmatchIndex(text, -1); len(actions) > 0 {
+		for _, m := range actions {
+			args := strings.TrimSpace(text[m[4]:m[5]])
+			msg.ToolCalls = append(msg.ToolCalls, toolCall{Type: "function", Function: functionCall{Name: text[m[2]:m[3]], Arguments: args}})
+		}
+		msg.Content = strings.TrimSpace(text[:actions[0][0]])
+	}
+}
+
+// parseToolCall parses the JSON of a <tool_call> block. A block that is
+// not a valid call becomes a call with malformed arguments, so the model
+// is asked to write it again.
+func parseToolCall(s string) toolCall {
+	if tc, ok := jsonToolCall(s, false); ok {
+		return tc
+	}
+	log.Printf("⚠️  [LOCAL LLM] Could not parse tool call: %s", s)
+	var named struct {
+		Name string `json:"name"`
+	}
+	json.Unmarshal([]byte(s), &named)
+	return toolCall{Type: "function", Function: functionCall{Name: named.Name, Arguments: s}}
+}
+
+// jsonToolCall parses a JSON object with a name and its arguments, also
+// accepted as "parameters", as a tool call. Without requireArgs a call
+// without arguments has none; with it, such an object is not a call, so
+// JSON in an answer is not taken for one.
+func jsonToolCall(s string, requireArgs bool) (toolCall, bool) {
+	var call struct {
+		Name       string          `json:"name"`
+		Arguments  json.RawMessage `json:"arguments"`
+		Parameters json.RawMessage `json:"parameters"`
+	}
+	if !strings.HasPrefix(s, "{") || json.Unmarshal([]byte(s), &call) != nil || call.Name == "" {
+		return toolCall{}, false
+	}
+	args := call.Arguments
+	if args == nil {
+		args = call.Parameters
+	}
+	if args == nil {
+		if requireArgs {
+			return toolCall{}, false
+		}
+		args = json.RawMessage("{}")
+	}
+	// Arguments may be a JSON-encoded string, as in the tools API
+	var encoded string
+	if json.Unmarshal(args, &encoded) == nil {
+		args = json.RawMessage(encoded)
+	}
+	return toolCall{Type: "function", Function: functionCall{Name: call.Name, Arguments: string(args)}}, true
+}
+
+// rejectsTools reports whether err is a server refusing a request because
+// of its tools, such as vLLM without --enable-auto-tool-choice or a server
+// whose chat template has no tool support.
+func rejectsTools(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code < 400 {
+		return false
+	}
+	body := strings.ToLower(statusErr.Body)
+	return strings.Contains(body, "tool") || strings.Contains(body, "function")
+}
@@ -0,0 +1,162 @@
+package localllm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestExtractToolCalls(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantText    string
+		wantCalls   []string
+		wantArgs    string
+		wantReasons bool
+	}{
+		{name: "answer", content: "There are 4 orders.", wantText: "There are 4 orders."},
+		{name: "answer with JSON", content: `{"orders": 4}`, wantText: `{"orders": 4}`},
+		{
+			name:      "tool_call block",
+			content:   "Let me look.\n<tool_call>\n{\"name\": \"query_database\", \"arguments\": {\"sql\": \"SELECT 1\"}}\n</tool_call>",
+			wantText:  "Let me look.",
+			wantCalls: []string{"query_database"},
+			wantArgs:  `{"sql": "SELECT 1"}`,
+		},
+		{
+			name:      "several blocks, one unclosed",
+			content:   "<tool_call>{\"name\": \"list_tables\"}</tool_call>\n<tool_call>{\"name\": \"get_schema\", \"arguments\": \"{\\\"table_name\\\":\\\"orders\\\"}\"}",
+			wantCalls: []string{"list_tables", "get_schema"},
+			wantArgs:  `{}`,
+		},
+		{
+			name:      "fenced JSON",
+			content:   "```json\n{\"name\": \"get_schema\", \"parameters\": {\"table_name\": \"orders\"}}\n```",
+			wantCalls: []string{"get_schema"},
+			wantArgs:  `{"table_name": "orders"}`,
+		},
+		{
+			name:      "bare JSON",
+			content:   `{"name": "list_tables", "arguments": {}}`,
+			wantCalls: []string{"list_tables"},
+			wantArgs:  `{}`,
+		},
+		{
+			name:      "ReAct",
+			content:   "Thought: I need the tables.\nAction: list_tables\nAction Input: {}\nObservation:",
+			wantText:  "Thought: I need the tables.",
+			wantCalls: []string{"list_tables"},
+			wantArgs:  `{}`,
+		},
+		{name: "ReAct answer", content: "Thought: done.\nFinal Answer: There are 4 orders.", wantText: "There are 4 orders."},
+		{
+			name:        "calls in reasoning are ignored",
+			content:     "<think>maybe <tool_call>{\"name\": \"drop\"}</tool_call></think>There are 4 orders.",
+			wantText:    "There are 4 orders.",
+			wantReasons: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := chatMessage{Role: "assistant", Content: tt.content}
+			extractToolCalls(&msg)
+			if msg.Content != tt.wantText {
+				t.Errorf("content = %q, want %q", msg.Content, tt.wantText)
+			}
+			var names []string
+			for _, tc := range msg.ToolCalls {
+				names = append(names, tc.Function.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Errorf("calls = %v, want %v", names, tt.wantCalls)
+			}
+			if len(msg.ToolCalls) > 0 && msg.ToolCalls[0].Function.Arguments != tt.wantArgs {
+				t.Errorf("arguments = %q, want %q", msg.ToolCalls[0].Function.Arguments, tt.wantArgs)
+			}
+			if (msg.Reasoning != "") != tt.wantReasons {
+				t.Errorf("reasoning = %q", msg.Reasoning)
+			}
+		})
+	}
+}
+
+func TestEmulateTools(t *testing.T) {
+	req := emulateTools(chatRequest{
+		Model: "tiny",
+		Messages: []chatMessage{
+			{Role: "system", Content: "You are the SQL agent."},
+			{Role: "user", Content: "How many orders?"},
+			{Role: "assistant", ToolCalls: []toolCall{
+				{ID: "a", Type: "function", Function: functionCall{Name: "query_database", Arguments: `{"sql":"SELECT count(*) FROM orders"}`}},
+				{ID: "b", Type: "function", Function: functionCall{Name: "list_tables", Arguments: ``}},
+			}},
+			{Role: "tool", Content: `[{"count":4}]`, ToolCallID: "a", toolName: "query_database"},
+			{Role: "tool", Content: `["orders"]`, ToolCallID: "b", toolName: "list_tables"},
+		},
+		Tools:      []toolDef{{Type: "function", Function: functionDef{Name: "query_database", Description: "Run SQL", Parameters: map[string]any{"type": "object"}}}},
+		ToolChoice: "auto",
+	})
+
+	if req.Tools != nil || req.ToolChoice != "" {
+		t.Errorf("emulateTools() kept the tools API: %+v, %q", req.Tools, req.ToolChoice)
+	}
+	if len(req.Messages) != 4 {
+		t.Fatalf("emulateTools() = %d messages, want system, user, assistant and one user message of results", len(req.Messages))
+	}
+	if !strings.HasPrefix(req.Messages[0].Content, "You are the SQL agent.") || !strings.Contains(req.Messages[0].Content, `- query_database: Run SQL
+  Parameters: {"type":"object"}`) {
+		t.Errorf("system message = %q", req.Messages[0].Content)
+	}
+	if want := "<tool_call>\n{\"name\":\"query_database\",\"arguments\":{\"sql\":\"SELECT count(*) FROM orders\"}}\n</tool_call>\n<tool_call>\n{\"name\":\"list_tables\",\"arguments\":{}}\n</tool_call>"; req.Messages[2].Content != want {
+		t.Errorf("assistant message = %q, want %q", req.Messages[2].Content, want)
+	}
+	if want := "<tool_result name=\"query_database\">\n[{\"count\":4}]\n</tool_result>\n<tool_result name=\"list_tables\">\n[\"orders\"]\n</tool_result>"; req.Messages[3].Role != "user" || req.Messages[3].Content != want {
+		t.Errorf("results message = %+v, want %q", req.Messages[3], want)
+	}
+}
+
+func TestToolCallingAuto(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		json.Unmarshal(data, &body)
+		requests = append(requests, body)
+		if body["tools"] != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"\"auto\" tool choice requires --enable-auto-tool-choice"}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"<tool_call>{\"name\": \"list_tables\", \"arguments\": {}}</tool_call>"}}]}`))
+	}))
+	defer server.Close()
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Which tables are there?", genai.RoleUser)},
+		Config:   &genai.GenerateContentConfig{Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "list_tables"}}}}},
+	}
+	l := New(Config{BaseURL: server.URL, ToolCalling: ToolCallingAuto})
+	for range 2 {
+		for resp, err := range l.GenerateContent(context.Background(), req, false) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Content.Parts) != 1 || resp.Content.Parts[0].FunctionCall == nil || resp.Content.Parts[0].FunctionCall.Name != "list_tables" {
+				t.Errorf("GenerateContent() parts = %+v, want a list_tables call", resp.Content.Parts)
+			}
+		}
+	}
+	// The tools API is tried once, then tools are emulated
+	if len(requests) != 3 || requests[0]["tools"] == nil || requests[1]["tools"] != nil || requests[2]["tools"] != nil {
+		t.Errorf("sent %d requests, want one with tools and two emulating them", len(requests))
+	}
+}
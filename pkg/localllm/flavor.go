@@ -0,0 +1,157 @@
+package localllm
+
+import (
+	"encoding/json"
+	"log"
+
+	"google.golang.org/genai"
+)
+
+// Flavor names the OpenAI-compatible server the model runs on, to work
+// around where it differs from the OpenAI API.
+type Flavor string
+
+const (
+	// FlavorGeneric assumes the OpenAI API.
+	FlavorGeneric Flavor = ""
+	// FlavorLMStudio is LM Studio.
+	FlavorLMStudio Flavor = "lmstudio"
+	// FlavorVLLM is vLLM. Tool calling needs --enable-auto-tool-choice and
+	// a --tool-call-parser on the server.
+	FlavorVLLM Flavor = "vllm"
+	// FlavorTGI is Hugging Face Text Generation Inference.
+	FlavorTGI Flavor = "tgi"
+	// FlavorLlamaCpp is the llama.cpp server, started with --jinja for
+	// tool calling.
+	FlavorLlamaCpp Flavor = "llamacpp"
+)
+
+// Flavors lists the known flavors.
+var Flavors = []Flavor{FlavorGeneric, FlavorLMStudio, FlavorVLLM, FlavorTGI, FlavorLlamaCpp}
+
+// quirks are the differences of a server from the OpenAI API.
+type quirks struct {
+	// toolChoice is sent with the tools, for servers that otherwise force
+	// a tool call or reject requests without it
+	toolChoice string
+	// singleToolCall asks for one tool call per response and drops any
+	// further calls, for servers and chat templates that cannot answer
+	// several calls at once
+	singleToolCall bool
+	// strictRoles merges consecutive user or assistant messages, for chat
+	// templates that require the roles to alternate
+	strictRoles bool
+}
+
+// quirks returns the quirks of f.
+func (f Flavor) quirks() quirks {
+	switch f {
+	case FlavorVLLM:
+		return quirks{toolChoice: "auto", strictRoles: true}
+	case FlavorTGI:
+		return quirks{toolChoice: "auto", singleToolCall: true, strictRoles: true}
+	case FlavorLlamaCpp:
+		return quirks{singleToolCall: true, strictRoles: true}
+	default:
+		return quirks{}
+	}
+}
+
+// prepare applies the quirks to a request.
+func (q quirks) prepare(req *chatRequest) {
+	if len(req.Tools) == 0 {
+		return
+	}
+	req.ToolChoice = q.toolChoice
+	if q.singleToolCall {
+		parallel := false
+		req.ParallelToolCalls = &parallel
+	}
+}
+
+// trimToolCalls drops all but the first tool call of msg if the server
+// handles only one call per response. The model makes the others once it
+// has the first result.
+func (q quirks) trimToolCalls(msg *chatMessage) {
+	if q.singleToolCall && len(msg.ToolCalls) > 1 {
+		log.Printf("🔧 [LOCAL LLM] Kept the first of %d parallel tool calls", len(msg.ToolCalls))
+		msg.ToolCalls = msg.ToolCalls[:1]
+	}
+}
+
+// arrange orders messages the way the server's chat templates accept. Tool
+// results are always followed by an assistant message before the next user
+// message, which Mistral templates and LM Studio require; with strictRoles
+// consecutive text messages of the same role are merged as well.
+func (q quirks) arrange(messages []chatMessage) []chatMessage {
+	var arranged []chatMessage
+	for _, msg := range messages {
+		if len(arranged) == 0 {
+			arranged = append(arranged, msg)
+			continue
+		}
+		last := &arranged[len(arranged)-1]
+		if msg.Role == "user" && last.Role == "tool" {
+			// Inject a neutral assistant message to satisfy alternation
+			arranged = append(arranged, chatMessage{Role: "assistant", Content: "Action completed."})
+			last = &arranged[len(arranged)-1]
+		}
+		if q.strictRoles && mergeable(*last, msg) {
+			switch {
+			case last.Content == "":
+				last.Content = msg.Content
+			case msg.Content != "":
+				last.Content += "\n" + msg.Content
+			}
+			last.Images = append(last.Images, msg.Images...)
+			last.ToolCalls = msg.ToolCalls
+			continue
+		}
+		arranged = append(arranged, msg)
+	}
+	return arranged
+}
+
+// mergeable reports whether next can be merged into msg: both are user or
+// assistant messages of the same role, and only next may call tools.
+func mergeable(msg, next chatMessage) bool {
+	return msg.Role == next.Role && (msg.Role == "user" || msg.Role == "assistant") && len(msg.ToolCalls) == 0
+}
+
+// finishReason maps the finish_reason of a choice to genai's. Servers name
+// the same reasons differently: TGI reports "eos_token" and llama.cpp
+// "stop" after tool calls, for example.
+func finishReason(reason string) genai.FinishReason {
+	switch reason {
+	case "":
+		return genai.FinishReasonUnspecified
+	case "stop", "eos", "eos_token", "stop_sequence", "end_turn", "tool_calls", "function_call":
+		return genai.FinishReasonStop
+	case "length", "max_tokens", "model_length":
+		return genai.FinishReasonMaxTokens
+	case "content_filter":
+		return genai.FinishReasonSafety
+	default:
+		return genai.FinishReasonOther
+	}
+}
+
+// UnmarshalJSON accepts arguments given as a JSON object, as TGI sends
+// them, besides the JSON-encoded string of the OpenAI API.
+func (f *functionCall) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	f.Name, f.Arguments = raw.Name, ""
+	if len(raw.Arguments) > 0 && raw.Arguments[0] == '"' {
+		return json.Unmarshal(raw.Arguments, &f.Arguments)
+	}
+	if len(raw.Arguments) > 0 && string(raw.Arguments) != "null" {
+		f.Arguments = string(raw.Arguments)
+	}
+	return nil
+}
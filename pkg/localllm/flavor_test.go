@@ -0,0 +1,120 @@
+package localllm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestArrange(t *testing.T) {
+	messages := []chatMessage{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "How many orders?"},
+		{Role: "assistant", Content: "Let me check."},
+		{Role: "assistant", ToolCalls: []toolCall{{ID: "1", Type: "function", Function: functionCall{Name: "query_database", Arguments: "{}"}}}},
+		{Role: "tool", Content: `[{"count":4}]`, ToolCallID: "1"},
+		{Role: "user", Content: "And customers?"},
+	}
+
+	tests := []struct {
+		flavor Flavor
+		want   string
+	}{
+		{
+			flavor: FlavorGeneric,
+			want:   `[{"role":"system","content":"You are helpful."},{"role":"user","content":"How many orders?"},{"role":"assistant","content":"Let me check."},{"role":"assistant","tool_calls":[{"id":"1","type":"function","function":{"name":"query_database","arguments":"{}"}}]},{"role":"tool","content":"[{\"count\":4}]","tool_call_id":"1"},{"role":"assistant","content":"Action completed."},{"role":"user","content":"And customers?"}]`,
+		},
+		{
+			flavor: FlavorVLLM,
+			want:   `[{"role":"system","content":"You are helpful."},{"role":"user","content":"How many orders?"},{"role":"assistant","content":"Let me check.","tool_calls":[{"id":"1","type":"function","function":{"name":"query_database","arguments":"{}"}}]},{"role":"tool","content":"[{\"count\":4}]","tool_call_id":"1"},{"role":"assistant","content":"Action completed."},{"role":"user","content":"And customers?"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.flavor), func(t *testing.T) {
+			got, _ := json.Marshal(tt.flavor.quirks().arrange(messages))
+			if string(got) != tt.want {
+				t.Errorf("arrange() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlavorRequests(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = nil
+		json.Unmarshal(data, &body)
+		// TGI's reply: two calls, arguments as objects and its own finish_reason
+		w.Write([]byte(`{"choices":[{"finish_reason":"eos_token","message":{"role":"assistant","tool_calls":[
+			{"id":"0","type":"function","function":{"name":"list_tables","arguments":{}}},
+			{"id":"1","type":"function","function":{"name":"get_schema","arguments":{"table_name":"orders"}}}]}}]}`))
+	}))
+	defer server.Close()
+
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Which tables are there?", genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{
+			{Name: "list_tables"}, {Name: "get_schema"},
+		}}}},
+	}
+
+	tests := []struct {
+		flavor       Flavor
+		wantChoice   any
+		wantParallel any
+		wantCalls    int
+	}{
+		{flavor: FlavorGeneric, wantCalls: 2},
+		{flavor: FlavorVLLM, wantChoice: "auto", wantCalls: 2},
+		{flavor: FlavorTGI, wantChoice: "auto", wantParallel: false, wantCalls: 1},
+		{flavor: FlavorLlamaCpp, wantParallel: false, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.flavor), func(t *testing.T) {
+			l := New(Config{BaseURL: server.URL, Flavor: tt.flavor})
+			for resp, err := range l.GenerateContent(context.Background(), req, false) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if body["tool_choice"] != tt.wantChoice || body["parallel_tool_calls"] != tt.wantParallel {
+					t.Errorf("request tool_choice = %v, parallel_tool_calls = %v; want %v, %v", body["tool_choice"], body["parallel_tool_calls"], tt.wantChoice, tt.wantParallel)
+				}
+				if len(resp.Content.Parts) != tt.wantCalls || resp.Content.Parts[0].FunctionCall.Name != "list_tables" {
+					t.Errorf("response parts = %d, want %d calls", len(resp.Content.Parts), tt.wantCalls)
+				}
+				if tt.wantCalls == 2 && resp.Content.Parts[1].FunctionCall.Args["table_name"] != "orders" {
+					t.Errorf("arguments = %v, want the object decoded", resp.Content.Parts[1].FunctionCall.Args)
+				}
+				if resp.FinishReason != genai.FinishReasonStop {
+					t.Errorf("finish reason = %q, want %q", resp.FinishReason, genai.FinishReasonStop)
+				}
+			}
+		})
+	}
+}
+
+func TestFinishReason(t *testing.T) {
+	tests := map[string]genai.FinishReason{
+		"stop":           genai.FinishReasonStop,
+		"tool_calls":     genai.FinishReasonStop,
+		"eos_token":      genai.FinishReasonStop,
+		"length":         genai.FinishReasonMaxTokens,
+		"content_filter": genai.FinishReasonSafety,
+		"abort":          genai.FinishReasonOther,
+		"":               genai.FinishReasonUnspecified,
+	}
+	for reason, want := range tests {
+		if got := finishReason(reason); got != want {
+			t.Errorf("finishReason(%q) = %q, want %q", reason, got, want)
+		}
+	}
+}
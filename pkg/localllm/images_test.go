@@ -0,0 +1,86 @@
+package localllm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestImageParts(t *testing.T) {
+	png := []byte{0x89, 'P', 'N', 'G'}
+	tests := []struct {
+		name     string
+		contents []*genai.Content
+		want     string
+	}{
+		{
+			name:     "text only",
+			contents: []*genai.Content{genai.NewContentFromText("How many orders?", genai.RoleUser)},
+			want:     `[{"role":"user","content":"How many orders?"}]`,
+		},
+		{
+			name: "inline image",
+			contents: []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+				genai.NewPartFromText("Describe this chart"),
+				genai.NewPartFromBytes(png, "image/png"),
+			}, genai.RoleUser)},
+			want: `[{"role":"user","content":[{"type":"text","text":"Describe this chart"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw=="}}]}]`,
+		},
+		{
+			name: "remote image merged into previous text",
+			contents: []*genai.Content{
+				genai.NewContentFromText("Compare", genai.RoleUser),
+				genai.NewContentFromParts([]*genai.Part{
+					genai.NewPartFromURI("https://example.com/a.jpg", "image/jpeg"),
+				}, genai.RoleUser),
+			},
+			want: `[{"role":"user","content":[{"type":"text","text":"Compare"},{"type":"image_url","image_url":{"url":"https://example.com/a.jpg"}}]}]`,
+		},
+		{
+			name: "unsupported parts are dropped",
+			contents: []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+				genai.NewPartFromText("Read this"),
+				genai.NewPartFromBytes([]byte("%PDF"), "application/pdf"),
+				genai.NewPartFromURI("gs://bucket/chart.png", "image/png"),
+			}, genai.RoleUser)},
+			want: `[{"role":"user","content":"Read this"}]`,
+		},
+		{
+			name: "model images are dropped",
+			contents: []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+				genai.NewPartFromText("Here it is"),
+				genai.NewPartFromBytes(png, "image/png"),
+			}, genai.RoleModel)},
+			want: `[{"role":"assistant","content":"Here it is"}]`,
+		},
+	}
+
+	l := &LocalLLM{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := l.convertToMessages(&model.LLMRequest{Contents: tt.contents})
+			got, err := json.Marshal(messages)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("messages =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextOnly(t *testing.T) {
+	l := &LocalLLM{textOnly: true}
+	messages := l.convertToMessages(&model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+		genai.NewPartFromText("Describe this chart"),
+		genai.NewPartFromBytes([]byte{0x89, 'P', 'N', 'G'}, "image/png"),
+	}, genai.RoleUser)}})
+	got, _ := json.Marshal(messages)
+	want := `[{"role":"user","content":"Describe this chart\n` + imageNote + `"}]`
+	if string(got) != want {
+		t.Errorf("messages =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -3,12 +3,20 @@ package localllm
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"iter"
+	"log"
+	"maps"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"google.golang.org/adk/model"
 	"google.golang.org/genai"
@@ -20,13 +28,64 @@ type Config struct {
 	BaseURL string
 	// Model is the model name to use
 	Model string
+	// ToolRepair handles calls to unknown tools (defaults to RepairCorrect)
+	ToolRepair ToolRepair
+	// MaxRegenerations bounds how often the model is asked to fix invalid
+	// tool calls before the request fails (defaults to 2)
+	MaxRegenerations int
+	// TLS configures HTTPS to the server, e.g. a custom CA or a client
+	// certificate (defaults to the system roots)
+	TLS *tls.Config
+	// Proxy is the proxy requests go through (defaults to HTTPS_PROXY and
+	// HTTP_PROXY from the environment)
+	Proxy *url.URL
+	// Timeout bounds each request, including reading the response
+	// (defaults to DefaultTimeout)
+	Timeout time.Duration
+	// Headers are sent with every request, e.g. the Authorization header
+	// of a gateway such as LiteLLM
+	Headers map[string]string
+	// DisableKeepAlive opens a new connection for every request
+	DisableKeepAlive bool
+	// IdleTimeout is how long an idle connection is kept for reuse
+	// (defaults to 90s)
+	IdleTimeout time.Duration
+	// Azure sends requests to an Azure OpenAI deployment, with BaseURL the
+	// resource endpoint (optional)
+	Azure *Azure
+	// Flavor works around the differences of the server from the OpenAI
+	// API (defaults to FlavorGeneric)
+	Flavor Flavor
+	// ToolCalling selects how the model is given tools (defaults to
+	// ToolCallingNative)
+	ToolCalling ToolCalling
+	// JSONMode asks the server for JSON output with response_format when a
+	// request wants JSON; without it JSON is asked for in the system message
+	JSONMode bool
+	// TextOnly replaces the images of user messages with a note, for
+	// models without vision
+	TextOnly bool
 }
 
+// DefaultTimeout bounds requests when Config.Timeout is not set. Local
+// models can take minutes to answer long prompts.
+const DefaultTimeout = 5 * time.Minute
+
 // LocalLLM implements model.LLM for OpenAI-compatible local LLM servers.
 type LocalLLM struct {
-	baseURL string
-	model   string
-	client  *http.Client
+	endpoint         string
+	modelsURL        string
+	model            string
+	client           *http.Client
+	headers          map[string]string
+	quirks           quirks
+	toolCalling      ToolCalling
+	toolRepair       ToolRepair
+	maxRegenerations int
+	jsonMode         bool
+	textOnly         bool
+	// emulating is set once the server has rejected tools in auto mode
+	emulating atomic.Bool
 }
 
 // New creates a new LocalLLM instance.
@@ -36,11 +95,56 @@ func New(cfg Config) *LocalLLM {
 	if model == "" {
 		model = "local-model"
 	}
-	return &LocalLLM{
-		baseURL: baseURL,
-		model:   model,
-		client:  &http.Client{},
+	l := &LocalLLM{
+		endpoint:         baseURL + "/v1/chat/completions",
+		modelsURL:        baseURL + "/v1/models",
+		model:            model,
+		client:           &http.Client{Transport: newTransport(cfg), Timeout: cfg.Timeout},
+		headers:          cfg.Headers,
+		quirks:           cfg.Flavor.quirks(),
+		toolCalling:      cfg.ToolCalling,
+		toolRepair:       cfg.ToolRepair,
+		maxRegenerations: cfg.MaxRegenerations,
+		jsonMode:         cfg.JSONMode,
+		textOnly:         cfg.TextOnly,
+	}
+	if l.toolRepair == "" {
+		l.toolRepair = RepairCorrect
+	}
+	if l.maxRegenerations <= 0 {
+		l.maxRegenerations = 2
+	}
+	if l.client.Timeout <= 0 {
+		l.client.Timeout = DefaultTimeout
+	}
+	if cfg.Azure != nil {
+		l.endpoint = cfg.Azure.endpoint(baseURL, model)
+		// Deployments are managed outside the data plane API
+		l.modelsURL = ""
+		l.headers = maps.Clone(cfg.Headers)
+		if l.headers == nil {
+			l.headers = make(map[string]string)
+		}
+		l.headers["api-key"] = cfg.Azure.APIKey
+	}
+	return l
+}
+
+// newTransport returns the HTTP transport for cfg: the default transport
+// with its TLS, proxy and keep-alive settings applied.
+func newTransport(cfg Config) http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLS != nil {
+		t.TLSClientConfig = cfg.TLS
+	}
+	if cfg.Proxy != nil {
+		t.Proxy = http.ProxyURL(cfg.Proxy)
+	}
+	if cfg.IdleTimeout > 0 {
+		t.IdleConnTimeout = cfg.IdleTimeout
 	}
+	t.DisableKeepAlives = cfg.DisableKeepAlive
+	return t
 }
 
 // Name implements model.LLM.
@@ -48,21 +152,133 @@ func (l *LocalLLM) Name() string {
 	return l.model
 }
 
+// Models lists the IDs of the models the server serves, from /v1/models.
+// Azure OpenAI deployments cannot be listed this way and return an error
+// wrapping errors.ErrUnsupported.
+func (l *LocalLLM) Models(ctx context.Context) ([]string, error) {
+	if l.modelsURL == "" {
+		return nil, fmt.Errorf("listing Azure OpenAI deployments: %w", errors.ErrUnsupported)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", l.modelsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, value := range l.headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := l.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(body)}
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode models: %w", err)
+	}
+	models := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
 // OpenAI-compatible request/response types
 type chatRequest struct {
 	Model       string        `json:"model"`
 	Messages    []chatMessage `json:"messages"`
-	Temperature float64       `json:"temperature,omitempty"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stop        []string      `json:"stop,omitempty"`
 	Stream      bool          `json:"stream"`
 	Tools       []toolDef     `json:"tools,omitempty"`
+	ToolChoice  string        `json:"tool_choice,omitempty"`
+	// ParallelToolCalls is set to false for servers that handle one tool
+	// call per response
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+	// ResponseFormat constrains the output, e.g. to a JSON object
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
 }
 
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+// imageNote stands in for images sent to models without vision.
+const imageNote = "[An image was attached, but this model cannot see images.]"
+
+// jsonPrompt asks for JSON output from models without a JSON mode.
+const jsonPrompt = "Respond with a single valid JSON object only, without code fences or any other text."
+
 type chatMessage struct {
-	Role       string     `json:"role"`
-	Content    string     `json:"content,omitempty"`
-	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
-	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content,omitempty"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+	// Reasoning is the thinking returned separately by reasoning models
+	Reasoning  string `json:"reasoning_content,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// Images are the URLs of images attached to a user message. They turn
+	// the content into an array of parts for vision models.
+	Images []string `json:"-"`
+	// toolName is the tool whose result a tool message holds
+	toolName string
+}
+
+// contentPart is an element of a multimodal message's content.
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// MarshalJSON sends messages with images as an array of text and image_url
+// parts, and all others with plain string content.
+func (m chatMessage) MarshalJSON() ([]byte, error) {
+	type plain chatMessage
+	if len(m.Images) == 0 {
+		return json.Marshal(plain(m))
+	}
+	var parts []contentPart
+	if m.Content != "" {
+		parts = append(parts, contentPart{Type: "text", Text: m.Content})
+	}
+	for _, url := range m.Images {
+		parts = append(parts, contentPart{Type: "image_url", ImageURL: &imageURL{URL: url}})
+	}
+	return json.Marshal(struct {
+		plain
+		Content []contentPart `json:"content"`
+	}{plain(m), parts})
+}
+
+// imageURLOf returns the URL to send for an image part: inline bytes as a
+// base64 data URI, or the URI of a remote or data file. Other parts return
+// "".
+func imageURLOf(part *genai.Part) string {
+	switch {
+	case part.InlineData != nil && strings.HasPrefix(part.InlineData.MIMEType, "image/"):
+		return "data:" + part.InlineData.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(part.InlineData.Data)
+	case part.FileData != nil && strings.HasPrefix(part.FileData.MIMEType, "image/"):
+		uri := part.FileData.FileURI
+		if strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") || strings.HasPrefix(uri, "data:") {
+			return uri
+		}
+	}
+	return ""
 }
 
 type toolDef struct {
@@ -119,50 +335,152 @@ func (l *LocalLLM) GenerateContent(ctx context.Context, req *model.LLMRequest, s
 			Tools:    tools,
 		}
 
-		// Add temperature if specified
-		if req.Config != nil && req.Config.Temperature != nil {
-			chatReq.Temperature = float64(*req.Config.Temperature)
+		// Add sampling parameters if specified
+		if req.Config != nil {
+			if req.Config.Temperature != nil {
+				t := float64(*req.Config.Temperature)
+				chatReq.Temperature = &t
+			}
+			if req.Config.TopP != nil {
+				p := float64(*req.Config.TopP)
+				chatReq.TopP = &p
+			}
+			chatReq.MaxTokens = int(req.Config.MaxOutputTokens)
+			chatReq.Stop = req.Config.StopSequences
+			if req.Config.ResponseMIMEType == "application/json" {
+				l.requestJSON(&chatReq)
+			}
 		}
-
-		reqBody, err := json.Marshal(chatReq)
-		if err != nil {
-			yield(nil, fmt.Errorf("failed to marshal request: %w", err))
-			return
+		l.quirks.prepare(&chatReq)
+
+		// Ask the model to fix invalid tool calls before ADK sees them
+		var total usage
+		for attempt := 0; ; attempt++ {
+			chatResp, emulated, err := l.complete(ctx, &chatReq)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			total.PromptTokens += chatResp.Usage.PromptTokens
+			total.CompletionTokens += chatResp.Usage.CompletionTokens
+			total.TotalTokens += chatResp.Usage.TotalTokens
+
+			var feedback []chatMessage
+			if len(chatResp.Choices) > 0 {
+				if emulated {
+					extractToolCalls(&chatResp.Choices[0].Message)
+				}
+				l.quirks.trimToolCalls(&chatResp.Choices[0].Message)
+				assignToolCallIDs(&chatResp.Choices[0].Message, toolCallIDs(chatReq.Messages))
+				feedback = validateToolCalls(&chatResp.Choices[0].Message, tools, l.toolRepair)
+			}
+			if len(feedback) == 0 {
+				chatResp.Usage = total
+				// Convert OpenAI response to ADK format
+				yield(l.convertToLLMResponse(chatResp), nil)
+				return
+			}
+			if attempt >= l.maxRegenerations {
+				var names []string
+				for _, tc := range chatResp.Choices[0].Message.ToolCalls {
+					names = append(names, tc.Function.Name)
+				}
+				yield(nil, fmt.Errorf("model kept making invalid tool calls (%s) after %d attempts", strings.Join(names, ", "), attempt+1))
+				return
+			}
+			// Reasoning is not sent back to the model
+			retry := chatResp.Choices[0].Message
+			retry.Reasoning = ""
+			_, retry.Content = splitReasoning(retry.Content)
+			chatReq.Messages = append(chatReq.Messages, retry)
+			chatReq.Messages = append(chatReq.Messages, feedback...)
 		}
+	}
+}
 
-		// DEBUG: Print request JSON
-		fmt.Printf("\n🔎 [DEBUG] Sending to LLM:\n%s\n\n", string(reqBody))
+// requestJSON asks for JSON output, through response_format if the model
+// has a JSON mode and in the system message otherwise.
+func (l *LocalLLM) requestJSON(chatReq *chatRequest) {
+	if l.jsonMode {
+		chatReq.ResponseFormat = &responseFormat{Type: "json_object"}
+		return
+	}
+	if len(chatReq.Messages) > 0 && chatReq.Messages[0].Role == "system" {
+		chatReq.Messages[0].Content += "\n\n" + jsonPrompt
+		return
+	}
+	chatReq.Messages = append([]chatMessage{{Role: "system", Content: jsonPrompt}}, chatReq.Messages...)
+}
 
-		httpReq, err := http.NewRequestWithContext(ctx, "POST", l.baseURL+"/v1/chat/completions", bytes.NewReader(reqBody))
-		if err != nil {
-			yield(nil, fmt.Errorf("failed to create request: %w", err))
-			return
-		}
-		httpReq.Header.Set("Content-Type", "application/json")
+// StatusError is returned when the server answers a request with a status
+// other than 200 OK, e.g. 429 when it is rate limited.
+type StatusError struct {
+	Code int
+	Body string
+}
 
-		resp, err := l.client.Do(httpReq)
-		if err != nil {
-			yield(nil, fmt.Errorf("failed to send request: %w", err))
-			return
-		}
-		defer resp.Body.Close()
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("LLM request failed with status %d: %s", e.Code, e.Body)
+}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			yield(nil, fmt.Errorf("LLM request failed with status %d: %s", resp.StatusCode, string(body)))
-			return
-		}
+// complete sends chatReq, with its tools described in the prompt if they
+// are emulated, and reports whether they were. In auto mode a request the
+// server rejects because of its tools is sent again with them emulated,
+// as are all later requests.
+func (l *LocalLLM) complete(ctx context.Context, chatReq *chatRequest) (*chatResponse, bool, error) {
+	if len(chatReq.Tools) == 0 {
+		resp, err := l.send(ctx, chatReq)
+		return resp, false, err
+	}
+	if l.toolCalling == ToolCallingPrompt || l.emulating.Load() {
+		emulated := emulateTools(*chatReq)
+		resp, err := l.send(ctx, &emulated)
+		return resp, true, err
+	}
+	resp, err := l.send(ctx, chatReq)
+	if err != nil && l.toolCalling == ToolCallingAuto && rejectsTools(err) {
+		log.Printf("🔧 [LOCAL LLM] The server rejected tools (%v); describing them in the prompt instead", err)
+		l.emulating.Store(true)
+		return l.complete(ctx, chatReq)
+	}
+	return resp, false, err
+}
 
-		var chatResp chatResponse
-		if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-			yield(nil, fmt.Errorf("failed to decode response: %w", err))
-			return
-		}
+// send posts a chat completion request.
+func (l *LocalLLM) send(ctx context.Context, chatReq *chatRequest) (*chatResponse, error) {
+	reqBody, err := json.Marshal(chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
 
-		// Convert OpenAI response to ADK format
-		llmResp := l.convertToLLMResponse(&chatResp)
-		yield(llmResp, nil)
+	// DEBUG: Print request JSON
+	log.Printf("🔎 [DEBUG] Sending to LLM:\n%s\n", string(reqBody))
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", l.endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for name, value := range l.headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	resp, err := l.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{Code: resp.StatusCode, Body: string(body)}
+	}
+
+	var chatResp chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &chatResp, nil
 }
 
 func (l *LocalLLM) convertToMessages(req *model.LLMRequest) []chatMessage {
@@ -192,6 +510,7 @@ func (l *LocalLLM) convertToMessages(req *model.LLMRequest) []chatMessage {
 		}
 
 		var textContent string
+		var images []string
 		var funcCalls []toolCall
 		var funcResponses []struct {
 			id       string
@@ -200,9 +519,21 @@ func (l *LocalLLM) convertToMessages(req *model.LLMRequest) []chatMessage {
 		}
 
 		for _, part := range content.Parts {
+			// Earlier reasoning is not replayed to the model
+			if part.Thought {
+				continue
+			}
 			if part.Text != "" {
 				textContent += part.Text
 			}
+			// Only user messages may carry images
+			if url := imageURLOf(part); url != "" && role == "user" {
+				if l.textOnly {
+					textContent = strings.TrimSpace(textContent + "\n" + imageNote)
+				} else {
+					images = append(images, url)
+				}
+			}
 			// Handle function calls from model
 			if part.FunctionCall != nil {
 				argsJSON, _ := json.Marshal(part.FunctionCall.Args)
@@ -238,7 +569,7 @@ func (l *LocalLLM) convertToMessages(req *model.LLMRequest) []chatMessage {
 				Content:   textContent,
 				ToolCalls: funcCalls,
 			})
-		} else if textContent != "" {
+		} else if textContent != "" || len(images) > 0 {
 			// Check if we can merge with previous message
 			merged := false
 			if len(messages) > 0 {
@@ -247,7 +578,13 @@ func (l *LocalLLM) convertToMessages(req *model.LLMRequest) []chatMessage {
 
 				// Only merge if roles match and neither has tool calls/ids (simple text messages)
 				if lastMsg.Role == role && lastMsg.ToolCalls == nil && lastMsg.ToolCallID == "" {
-					lastMsg.Content += "\n" + textContent
+					switch {
+					case lastMsg.Content == "":
+						lastMsg.Content = textContent
+					case textContent != "":
+						lastMsg.Content += "\n" + textContent
+					}
+					lastMsg.Images = append(lastMsg.Images, images...)
 					merged = true
 				}
 			}
@@ -256,6 +593,7 @@ func (l *LocalLLM) convertToMessages(req *model.LLMRequest) []chatMessage {
 				messages = append(messages, chatMessage{
 					Role:    role,
 					Content: textContent,
+					Images:  images,
 				})
 			}
 		}
@@ -266,85 +604,12 @@ func (l *LocalLLM) convertToMessages(req *model.LLMRequest) []chatMessage {
 				Role:       "tool",
 				Content:    fr.response,
 				ToolCallID: fr.id,
+				toolName:   fr.name,
 			})
 		}
 	}
-
-	// Post-processing: specific fix for Mistral/LM Studio
-	// Ensure Tool messages are always followed by Assistant messages before the next User message
-	var finalMessages []chatMessage
-	for _, msg := range messages {
-		// If we are about to add a User message, and the previous one was a Tool message
-		if msg.Role == "user" && len(finalMessages) > 0 {
-			lastMsg := finalMessages[len(finalMessages)-1]
-			if lastMsg.Role == "tool" {
-				// Inject a neutral assistant message to satisfy alternation
-				finalMessages = append(finalMessages, chatMessage{
-					Role:    "assistant",
-					Content: "Action completed.",
-				})
-			}
-		}
-		finalMessages = append(finalMessages, msg)
-	}
-
-	return finalMessages
-}
-
-// knownToolSchemas provides fallback schemas for tools that don't have Parameters set.
-// This is needed because ADK's functiontool doesn't populate genai.FunctionDeclaration.Parameters
-// when converted for local LLM usage.
-var knownToolSchemas = map[string]map[string]interface{}{
-	"query_database": {
-		"type": "object",
-		"properties": map[string]interface{}{
-			"sql": map[string]interface{}{
-				"type":        "string",
-				"description": "The SQL query to execute",
-			},
-			"limit": map[string]interface{}{
-				"type":        "integer",
-				"description": "Maximum number of rows to return (default: 100)",
-			},
-		},
-		"required": []string{"sql"},
-	},
-	"get_schema": {
-		"type": "object",
-		"properties": map[string]interface{}{
-			"table_name": map[string]interface{}{
-				"type":        "string",
-				"description": "The name of the table to get schema for",
-			},
-		},
-		"required": []string{"table_name"},
-	},
-	"list_tables": {
-		"type":       "object",
-		"properties": map[string]interface{}{},
-	},
-	"describe_database": {
-		"type":       "object",
-		"properties": map[string]interface{}{},
-	},
-	"generate_chart": {
-		"type": "object",
-		"properties": map[string]interface{}{
-			"chart_type": map[string]interface{}{
-				"type":        "string",
-				"description": "Type of chart: bar, line, pie, scatter",
-			},
-			"title": map[string]interface{}{
-				"type":        "string",
-				"description": "Chart title",
-			},
-			"data": map[string]interface{}{
-				"type":        "string",
-				"description": "JSON string containing the chart data",
-			},
-		},
-		"required": []string{"chart_type", "title", "data"},
-	},
+	pairToolResults(messages)
+	return l.quirks.arrange(messages)
 }
 
 func (l *LocalLLM) convertToTools(req *model.LLMRequest) []toolDef {
@@ -365,22 +630,19 @@ func (l *LocalLLM) convertToTools(req *model.LLMRequest) []toolDef {
 			for _, fd := range t.FunctionDeclarations {
 				var params interface{} = emptyParams
 
-				if fd.Parameters != nil {
+				switch {
+				case fd.ParametersJsonSchema != nil:
+					// Generated from the tool's argument struct
+					params = jsonSchema(fd.ParametersJsonSchema)
+				case fd.Parameters != nil:
 					// Normalize the schema to ensure compatibility
 					params = normalizeSchema(fd.Parameters)
-
-					// DEBUG: Print parameter details
-					paramJSON, _ := json.Marshal(params)
-					fmt.Printf("🔎 [DEBUG] Tool %s normalized params: %s\n", fd.Name, string(paramJSON))
-				} else if schema, ok := knownToolSchemas[fd.Name]; ok {
-					// Use fallback schema for known tools
-					params = schema
-
-					// DEBUG: Print fallback
-					paramJSON, _ := json.Marshal(params)
-					fmt.Printf("🔎 [DEBUG] Tool %s using fallback schema: %s\n", fd.Name, string(paramJSON))
 				}
 
+				// DEBUG: Print parameter details
+				paramJSON, _ := json.Marshal(params)
+				log.Printf("🔎 [DEBUG] Tool %s params: %s", fd.Name, string(paramJSON))
+
 				tools = append(tools, toolDef{
 					Type: "function",
 					Function: functionDef{
@@ -396,6 +658,24 @@ func (l *LocalLLM) convertToTools(req *model.LLMRequest) []toolDef {
 	return tools
 }
 
+// jsonSchema converts a JSON schema to a map, adding the empty properties
+// OpenAI-compatible servers expect of tools without parameters.
+func jsonSchema(schema interface{}) interface{} {
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return schema
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return schema
+	}
+	if raw["type"] == "object" && raw["properties"] == nil {
+		raw["properties"] = map[string]interface{}{}
+	}
+	return raw
+}
+
 // normalizeSchema ensures the schema is in standard OpenAI JSON schema format
 // converting from potentially capitalized matching (genai) to lowercase
 func normalizeSchema(params interface{}) interface{} {
@@ -456,16 +736,28 @@ func (l *LocalLLM) convertToLLMResponse(chatResp *chatResponse) *model.LLMRespon
 	choice := chatResp.Choices[0]
 	var parts []*genai.Part
 
+	// Keep reasoning in thought parts, apart from the answer
+	reasoning, text := splitReasoning(choice.Message.Content)
+	if r := strings.TrimSpace(choice.Message.Reasoning); r != "" {
+		reasoning = strings.TrimSpace(r + "\n" + reasoning)
+	}
+	if reasoning != "" {
+		parts = append(parts, &genai.Part{Text: reasoning, Thought: true})
+	}
+
 	// Add text content
-	if choice.Message.Content != "" {
-		parts = append(parts, genai.NewPartFromText(choice.Message.Content))
+	if text != "" {
+		parts = append(parts, genai.NewPartFromText(text))
 	}
 
 	// Add function calls
 	for _, tc := range choice.Message.ToolCalls {
 		var args map[string]interface{}
 		json.Unmarshal([]byte(tc.Function.Arguments), &args)
-		parts = append(parts, genai.NewPartFromFunctionCall(tc.Function.Name, args))
+		part := genai.NewPartFromFunctionCall(tc.Function.Name, args)
+		// Keep the server's ID so results are sent back under it
+		part.FunctionCall.ID = tc.ID
+		parts = append(parts, part)
 	}
 
 	content := &genai.Content{
@@ -474,7 +766,8 @@ func (l *LocalLLM) convertToLLMResponse(chatResp *chatResponse) *model.LLMRespon
 	}
 
 	return &model.LLMResponse{
-		Content: content,
+		Content:      content,
+		FinishReason: finishReason(choice.FinishReason),
 		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
 			PromptTokenCount:     int32(chatResp.Usage.PromptTokens),
 			CandidatesTokenCount: int32(chatResp.Usage.CompletionTokens),
@@ -0,0 +1,83 @@
+package localllm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer sk-1234" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"object":"list","data":[{"id":"llama3.1:8b","object":"model"},{"id":"qwen2.5:7b","object":"model"}]}`))
+	}))
+	defer server.Close()
+
+	l := New(Config{BaseURL: server.URL, Headers: map[string]string{"Authorization": "Bearer sk-1234"}})
+	models, err := l.Models(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"llama3.1:8b", "qwen2.5:7b"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("Models() = %v, want %v", models, want)
+	}
+
+	var statusErr *StatusError
+	if _, err := New(Config{BaseURL: server.URL}).Models(context.Background()); !errors.As(err, &statusErr) || statusErr.Code != http.StatusNotFound {
+		t.Errorf("Models() without the header error = %v, want status 404", err)
+	}
+	if _, err := New(Config{BaseURL: server.URL, Azure: &Azure{APIKey: "key"}}).Models(context.Background()); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Models() on Azure error = %v, want errors.ErrUnsupported", err)
+	}
+}
+
+func TestRequestJSON(t *testing.T) {
+	system := chatMessage{Role: "system", Content: "You plan queries."}
+	user := chatMessage{Role: "user", Content: "Plan it"}
+
+	tests := []struct {
+		name         string
+		jsonMode     bool
+		messages     []chatMessage
+		wantFormat   *responseFormat
+		wantMessages []chatMessage
+	}{
+		{
+			name:         "JSON mode",
+			jsonMode:     true,
+			messages:     []chatMessage{system, user},
+			wantFormat:   &responseFormat{Type: "json_object"},
+			wantMessages: []chatMessage{system, user},
+		},
+		{
+			name:         "instruction in the system message",
+			messages:     []chatMessage{system, user},
+			wantMessages: []chatMessage{{Role: "system", Content: system.Content + "\n\n" + jsonPrompt}, user},
+		},
+		{
+			name:         "instruction as a new system message",
+			messages:     []chatMessage{user},
+			wantMessages: []chatMessage{{Role: "system", Content: jsonPrompt}, user},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &LocalLLM{jsonMode: tt.jsonMode}
+			req := chatRequest{Messages: append([]chatMessage(nil), tt.messages...)}
+			l.requestJSON(&req)
+			if !reflect.DeepEqual(req.ResponseFormat, tt.wantFormat) {
+				t.Errorf("response_format = %+v, want %+v", req.ResponseFormat, tt.wantFormat)
+			}
+			if !reflect.DeepEqual(req.Messages, tt.wantMessages) {
+				t.Errorf("messages = %+v, want %+v", req.Messages, tt.wantMessages)
+			}
+		})
+	}
+}
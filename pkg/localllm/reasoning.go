@@ -0,0 +1,52 @@
+package localllm
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// splitReasoning separates the <think>...</think> segments that reasoning
+// models such as DeepSeek-R1 and Qwen3 write into their content from the
+// answer. A closing tag without an opening one means the chat template
+// opened the segment, so everything before it is reasoning; an unclosed
+// segment runs to the end of the content.
+func splitReasoning(content string) (reasoning, text string) {
+	if !strings.Contains(content, thinkClose) && !strings.Contains(content, thinkOpen) {
+		return "", content
+	}
+
+	var thoughts, answer []string
+	rest := content
+	if open, end := strings.Index(rest, thinkOpen), strings.Index(rest, thinkClose); end >= 0 && (open < 0 || end < open) {
+		thoughts = append(thoughts, rest[:end])
+		rest = rest[end+len(thinkClose):]
+	}
+	for {
+		open := strings.Index(rest, thinkOpen)
+		if open < 0 {
+			answer = append(answer, rest)
+			break
+		}
+		answer = append(answer, rest[:open])
+		rest = rest[open+len(thinkOpen):]
+
+		end := strings.Index(rest, thinkClose)
+		if end < 0 {
+			thoughts = append(thoughts, rest)
+			break
+		}
+		thoughts = append(thoughts, rest[:end])
+		rest = rest[end+len(thinkClose):]
+	}
+
+	for i := range thoughts {
+		thoughts[i] = strings.TrimSpace(thoughts[i])
+	}
+	thoughts = slices.DeleteFunc(thoughts, func(t string) bool { return t == "" })
+	return strings.Join(thoughts, "\n\n"), strings.TrimSpace(strings.Join(answer, ""))
+}
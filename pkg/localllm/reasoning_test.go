@@ -0,0 +1,80 @@
+package localllm
+
+import (
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestSplitReasoning(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		wantReasoning string
+		wantText      string
+	}{
+		{
+			name:     "no reasoning",
+			content:  "There are 42 orders.",
+			wantText: "There are 42 orders.",
+		},
+		{
+			name:          "think block before the answer",
+			content:       "<think>\nCount the orders table.\n</think>\n\nThere are 42 orders.",
+			wantReasoning: "Count the orders table.",
+			wantText:      "There are 42 orders.",
+		},
+		{
+			name:          "opening tag added by the chat template",
+			content:       "Count the orders table.</think>There are 42 orders.",
+			wantReasoning: "Count the orders table.",
+			wantText:      "There are 42 orders.",
+		},
+		{
+			name:          "several blocks",
+			content:       "<think>First.</think>Answer <think>Second.</think>continues.",
+			wantReasoning: "First.\n\nSecond.",
+			wantText:      "Answer continues.",
+		},
+		{
+			name:          "unclosed block",
+			content:       "<think>Still thinking",
+			wantReasoning: "Still thinking",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasoning, text := splitReasoning(tt.content)
+			if reasoning != tt.wantReasoning {
+				t.Errorf("reasoning = %q, want %q", reasoning, tt.wantReasoning)
+			}
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestReasoningIsNotReplayed(t *testing.T) {
+	l := &LocalLLM{}
+	resp := l.convertToLLMResponse(&chatResponse{Choices: []choice{{Message: chatMessage{
+		Role:      "assistant",
+		Content:   "<think>Look at orders.</think>42 orders.",
+		Reasoning: "Use count(*).",
+	}}}})
+
+	parts := resp.Content.Parts
+	if len(parts) != 2 || !parts[0].Thought || parts[0].Text != "Use count(*).\nLook at orders." || parts[1].Thought || parts[1].Text != "42 orders." {
+		t.Fatalf("parts = %+v, want a thought and the answer", parts)
+	}
+
+	messages := l.convertToMessages(&model.LLMRequest{Contents: []*genai.Content{
+		genai.NewContentFromText("How many orders?", genai.RoleUser),
+		resp.Content,
+	}})
+	if got := messages[len(messages)-1].Content; got != "42 orders." {
+		t.Errorf("replayed content = %q, want only the answer", got)
+	}
+}
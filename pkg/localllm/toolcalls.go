@@ -0,0 +1,236 @@
+package localllm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// ToolRepair controls how tool calls the model makes to unknown tools are
+// handled.
+type ToolRepair string
+
+const (
+	// RepairCorrect renames a call to the closest registered tool when the
+	// names are similar enough, and falls back to RepairFeedback otherwise.
+	RepairCorrect ToolRepair = "correct"
+	// RepairFeedback sends the model structured feedback listing the
+	// available tools and asks it to regenerate its response.
+	RepairFeedback ToolRepair = "feedback"
+	// RepairOff passes tool calls through unchecked.
+	RepairOff ToolRepair = "off"
+)
+
+// toolFeedback is the tool result sent back for an invalid tool call.
+type toolFeedback struct {
+	Error          string   `json:"error"`
+	Tool           string   `json:"tool"`
+	DidYouMean     string   `json:"did_you_mean,omitempty"`
+	AvailableTools []string `json:"available_tools,omitempty"`
+	Message        string   `json:"message"`
+}
+
+// validateToolCalls checks the tool calls of msg against the registered
+// tools, correcting names in place when repair allows. It returns one tool
+// message per call if any call is still invalid, to be sent back to the
+// model with msg; every call must be answered, so valid calls are reported
+// as not executed.
+func validateToolCalls(msg *chatMessage, tools []toolDef, repair ToolRepair) []chatMessage {
+	if repair == RepairOff || len(msg.ToolCalls) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		names = append(names, t.Function.Name)
+	}
+
+	feedback := make([]*toolFeedback, len(msg.ToolCalls))
+	invalid := false
+	for i := range msg.ToolCalls {
+		tc := &msg.ToolCalls[i]
+		name := tc.Function.Name
+		if !slices.Contains(names, name) {
+			closest, ok := closestTool(name, names)
+			if ok && repair == RepairCorrect {
+				log.Printf("🔧 [LOCAL LLM] Corrected call to unknown tool %q to %q", name, closest)
+				tc.Function.Name = closest
+			} else {
+				feedback[i] = &toolFeedback{
+					Error:          "unknown_tool",
+					Tool:           name,
+					DidYouMean:     closest,
+					AvailableTools: names,
+					Message:        fmt.Sprintf("There is no tool named %q. Call one of the available tools by its exact name.", name),
+				}
+				invalid = true
+				continue
+			}
+		}
+
+		if args := strings.TrimSpace(tc.Function.Arguments); args != "" {
+			var v map[string]interface{}
+			if err := json.Unmarshal([]byte(args), &v); err != nil {
+				feedback[i] = &toolFeedback{
+					Error:   "malformed_arguments",
+					Tool:    tc.Function.Name,
+					Message: fmt.Sprintf("The arguments are not a valid JSON object (%v). Call the tool again with a JSON object matching its parameters.", err),
+				}
+				invalid = true
+			}
+		}
+	}
+	if !invalid {
+		return nil
+	}
+
+	messages := make([]chatMessage, 0, len(msg.ToolCalls))
+	for i, tc := range msg.ToolCalls {
+		fb := feedback[i]
+		if fb == nil {
+			fb = &toolFeedback{
+				Error:   "not_executed",
+				Tool:    tc.Function.Name,
+				Message: "This call was not run because another call in the same response was invalid. Repeat it if it is still needed.",
+			}
+		} else {
+			log.Printf("⚠️  [LOCAL LLM] Invalid tool call %q: %s", tc.Function.Name, fb.Error)
+		}
+		content, _ := json.Marshal(fb)
+		messages = append(messages, chatMessage{Role: "tool", Content: string(content), ToolCallID: tc.ID})
+	}
+	return messages
+}
+
+// assignToolCallIDs gives every call of msg an ID that no other call in the
+// conversation uses. Some servers return parallel calls without IDs, or
+// number them from zero in every response, which would pair later results
+// with earlier calls. used holds the IDs already in the conversation.
+func assignToolCallIDs(msg *chatMessage, used map[string]bool) {
+	seen := make(map[string]bool, len(msg.ToolCalls))
+	for i := range msg.ToolCalls {
+		tc := &msg.ToolCalls[i]
+		if tc.ID == "" || used[tc.ID] || seen[tc.ID] {
+			tc.ID = newToolCallID()
+		}
+		seen[tc.ID] = true
+	}
+}
+
+// toolCallIDs returns the IDs of the calls in messages.
+func toolCallIDs(messages []chatMessage) map[string]bool {
+	ids := make(map[string]bool)
+	for _, m := range messages {
+		for _, tc := range m.ToolCalls {
+			ids[tc.ID] = true
+		}
+	}
+	return ids
+}
+
+func newToolCallID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
+
+// pairToolResults makes the tool messages after each assistant message with
+// tool calls answer those calls in order. Results without a known call ID
+// are given the ID of the first unanswered call to the same tool, or else
+// of the first unanswered call, since strict servers reject results that
+// do not match a call of the preceding message.
+func pairToolResults(messages []chatMessage) {
+	for i := 0; i < len(messages); i++ {
+		calls := messages[i].ToolCalls
+		if len(calls) == 0 {
+			continue
+		}
+		end := i + 1
+		for end < len(messages) && messages[end].Role == "tool" {
+			end++
+		}
+		results := messages[i+1 : end]
+
+		answered := make(map[string]bool)
+		for _, r := range results {
+			if slices.ContainsFunc(calls, func(tc toolCall) bool { return tc.ID == r.ToolCallID }) {
+				answered[r.ToolCallID] = true
+			}
+		}
+		for j := range results {
+			r := &results[j]
+			if answered[r.ToolCallID] {
+				continue
+			}
+			match := slices.IndexFunc(calls, func(tc toolCall) bool { return !answered[tc.ID] && tc.Function.Name == r.toolName })
+			if match < 0 {
+				match = slices.IndexFunc(calls, func(tc toolCall) bool { return !answered[tc.ID] })
+			}
+			if match >= 0 {
+				r.ToolCallID = calls[match].ID
+				answered[r.ToolCallID] = true
+			}
+		}
+
+		// Results follow the order of the calls; any extras go last
+		order := func(r chatMessage) int {
+			if k := slices.IndexFunc(calls, func(tc toolCall) bool { return tc.ID == r.ToolCallID }); k >= 0 {
+				return k
+			}
+			return len(calls)
+		}
+		slices.SortStableFunc(results, func(a, b chatMessage) int { return order(a) - order(b) })
+		i = end - 1
+	}
+}
+
+// closestTool returns the registered tool most similar to name, and whether
+// it is close enough to be what the model meant. Case, separators and
+// namespace prefixes such as "functions." are ignored.
+func closestTool(name string, names []string) (string, bool) {
+	target := normalizeToolName(name)
+	best, bestDist := "", -1
+	for _, candidate := range names {
+		d := levenshtein(target, normalizeToolName(candidate))
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	if bestDist < 0 {
+		return "", false
+	}
+	return best, bestDist <= max(1, len(normalizeToolName(best))/4)
+}
+
+func normalizeToolName(name string) string {
+	name = strings.ToLower(strings.Trim(name, " \t\n`\"'"))
+	if i := strings.LastIndexAny(name, ".:/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.NewReplacer("-", "_", " ", "_").Replace(name)
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
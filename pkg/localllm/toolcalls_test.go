@@ -0,0 +1,193 @@
+package localllm
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestValidateToolCalls(t *testing.T) {
+	tools := []toolDef{
+		{Type: "function", Function: functionDef{Name: "query_database"}},
+		{Type: "function", Function: functionDef{Name: "list_tables"}},
+	}
+
+	tests := []struct {
+		name         string
+		calls        []functionCall
+		repair       ToolRepair
+		wantNames    []string
+		wantFeedback []string // error of each feedback message, none if valid
+	}{
+		{
+			name:      "valid call",
+			calls:     []functionCall{{Name: "query_database", Arguments: `{"sql":"SELECT 1"}`}},
+			repair:    RepairCorrect,
+			wantNames: []string{"query_database"},
+		},
+		{
+			name:      "corrects namespaced and misspelled names",
+			calls:     []functionCall{{Name: "functions.query_database"}, {Name: "list-tabels"}},
+			repair:    RepairCorrect,
+			wantNames: []string{"query_database", "list_tables"},
+		},
+		{
+			name:         "feedback instead of correcting",
+			calls:        []functionCall{{Name: "Query_Database"}},
+			repair:       RepairFeedback,
+			wantNames:    []string{"Query_Database"},
+			wantFeedback: []string{"unknown_tool"},
+		},
+		{
+			name:         "unrelated name and valid sibling",
+			calls:        []functionCall{{Name: "send_email"}, {Name: "list_tables"}},
+			repair:       RepairCorrect,
+			wantNames:    []string{"send_email", "list_tables"},
+			wantFeedback: []string{"unknown_tool", "not_executed"},
+		},
+		{
+			name:         "malformed arguments",
+			calls:        []functionCall{{Name: "query_database", Arguments: `{"sql": SELECT`}},
+			repair:       RepairCorrect,
+			wantNames:    []string{"query_database"},
+			wantFeedback: []string{"malformed_arguments"},
+		},
+		{
+			name:      "off",
+			calls:     []functionCall{{Name: "send_email"}},
+			repair:    RepairOff,
+			wantNames: []string{"send_email"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := chatMessage{Role: "assistant"}
+			for _, c := range tt.calls {
+				msg.ToolCalls = append(msg.ToolCalls, toolCall{Type: "function", Function: c})
+			}
+
+			assignToolCallIDs(&msg, nil)
+			feedback := validateToolCalls(&msg, tools, tt.repair)
+			for i, tc := range msg.ToolCalls {
+				if tc.Function.Name != tt.wantNames[i] {
+					t.Errorf("call %d name = %q, want %q", i, tc.Function.Name, tt.wantNames[i])
+				}
+			}
+			if len(feedback) != len(tt.wantFeedback) {
+				t.Fatalf("got %d feedback messages, want %d", len(feedback), len(tt.wantFeedback))
+			}
+			for i, m := range feedback {
+				var fb toolFeedback
+				if err := json.Unmarshal([]byte(m.Content), &fb); err != nil {
+					t.Fatalf("feedback is not JSON: %v", err)
+				}
+				if fb.Error != tt.wantFeedback[i] || m.ToolCallID != msg.ToolCalls[i].ID || m.ToolCallID == "" {
+					t.Errorf("feedback %d = %s (id %q), want %s for call %q", i, fb.Error, m.ToolCallID, tt.wantFeedback[i], msg.ToolCalls[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignToolCallIDs(t *testing.T) {
+	msg := chatMessage{Role: "assistant", ToolCalls: []toolCall{
+		{ID: "call_0", Function: functionCall{Name: "list_tables"}},
+		{ID: "", Function: functionCall{Name: "get_schema"}},
+		{ID: "call_x", Function: functionCall{Name: "get_schema"}},
+		{ID: "call_x", Function: functionCall{Name: "get_schema"}},
+	}}
+	assignToolCallIDs(&msg, map[string]bool{"call_0": true})
+
+	seen := make(map[string]bool)
+	for i, tc := range msg.ToolCalls {
+		if tc.ID == "" || tc.ID == "call_0" || seen[tc.ID] {
+			t.Errorf("call %d ID = %q, want a new unique ID", i, tc.ID)
+		}
+		seen[tc.ID] = true
+	}
+	if msg.ToolCalls[2].ID != "call_x" {
+		t.Errorf("call 2 ID = %q, want the server's ID kept", msg.ToolCalls[2].ID)
+	}
+}
+
+func TestParallelToolCalls(t *testing.T) {
+	call := func(id, name, sql string) *genai.Part {
+		p := genai.NewPartFromFunctionCall(name, map[string]any{"sql": sql})
+		p.FunctionCall.ID = id
+		return p
+	}
+	result := func(id, name, data string) *genai.Part {
+		p := genai.NewPartFromFunctionResponse(name, map[string]any{"data": data})
+		p.FunctionResponse.ID = id
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		calls   []*genai.Part
+		results []*genai.Part
+		want    []string // tool_call_id and content of each tool message
+	}{
+		{
+			name:    "results in call order",
+			calls:   []*genai.Part{call("a", "query_database", "q1"), call("b", "query_database", "q2")},
+			results: []*genai.Part{result("b", "query_database", "r2"), result("a", "query_database", "r1")},
+			want:    []string{`a {"data":"r1"}`, `b {"data":"r2"}`},
+		},
+		{
+			name:    "missing IDs matched by tool",
+			calls:   []*genai.Part{call("a", "list_tables", ""), call("b", "query_database", "q")},
+			results: []*genai.Part{result("", "query_database", "rows"), result("", "list_tables", "tables")},
+			want:    []string{`a {"data":"tables"}`, `b {"data":"rows"}`},
+		},
+		{
+			name:    "missing IDs matched in order",
+			calls:   []*genai.Part{call("a", "query_database", "q1"), call("b", "query_database", "q2")},
+			results: []*genai.Part{result("", "query_database", "r1"), result("", "query_database", "r2")},
+			want:    []string{`a {"data":"r1"}`, `b {"data":"r2"}`},
+		},
+	}
+
+	l := &LocalLLM{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := l.convertToMessages(&model.LLMRequest{Contents: []*genai.Content{
+				genai.NewContentFromText("Compare", genai.RoleUser),
+				genai.NewContentFromParts(tt.calls, genai.RoleModel),
+				genai.NewContentFromParts(tt.results, genai.RoleUser),
+			}})
+			var got []string
+			for _, m := range messages {
+				if m.Role == "tool" {
+					got = append(got, m.ToolCallID+" "+m.Content)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("tool messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolCallIDsReachADK(t *testing.T) {
+	l := &LocalLLM{}
+	resp := l.convertToLLMResponse(&chatResponse{Choices: []choice{{Message: chatMessage{
+		Role: "assistant",
+		ToolCalls: []toolCall{
+			{ID: "call_1", Type: "function", Function: functionCall{Name: "query_database", Arguments: `{"sql":"SELECT 1"}`}},
+			{ID: "call_2", Type: "function", Function: functionCall{Name: "query_database", Arguments: `{"sql":"SELECT 2"}`}},
+		},
+	}}}})
+
+	var ids []string
+	for _, p := range resp.Content.Parts {
+		ids = append(ids, p.FunctionCall.ID)
+	}
+	if !slices.Equal(ids, []string{"call_1", "call_2"}) {
+		t.Errorf("function call IDs = %q, want the server's", ids)
+	}
+}
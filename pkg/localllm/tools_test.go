@@ -0,0 +1,49 @@
+package localllm
+
+import (
+	"encoding/json"
+	"testing"
+
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestConvertToToolsUsesGeneratedSchemas(t *testing.T) {
+	tools, err := sqlagent.CreateMCPTools(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decls []*genai.FunctionDeclaration
+	for _, tl := range tools {
+		decls = append(decls, tl.(interface {
+			Declaration() *genai.FunctionDeclaration
+		}).Declaration())
+	}
+
+	l := &LocalLLM{}
+	defs := l.convertToTools(&model.LLMRequest{Config: &genai.GenerateContentConfig{
+		Tools: []*genai.Tool{{FunctionDeclarations: decls}},
+	}})
+
+	want := map[string]string{
+		"query_database":    `{"properties":{"limit":{"description":"Maximum number of rows to return (default: 100)","type":"integer"},"sql":{"description":"The SQL query to execute","type":"string"}},"required":["sql"],"type":"object"}`,
+		"get_schema":        `{"properties":{"table_name":{"description":"The name of the table to get schema for","type":"string"}},"required":["table_name"],"type":"object"}`,
+		"list_tables":       `{"properties":{},"type":"object"}`,
+		"describe_database": `{"properties":{},"type":"object"}`,
+	}
+	for _, d := range defs {
+		w, ok := want[d.Function.Name]
+		if !ok {
+			continue
+		}
+		got, _ := json.Marshal(d.Function.Parameters)
+		if string(got) != w {
+			t.Errorf("%s parameters =\n%s\nwant\n%s", d.Function.Name, got, w)
+		}
+		delete(want, d.Function.Name)
+	}
+	for name := range want {
+		t.Errorf("no tool definition for %s", name)
+	}
+}
@@ -0,0 +1,81 @@
+package localllm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	answer := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + r.Host + `"}}]}`))
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(answer))
+	defer server.Close()
+	proxy := httptest.NewServer(http.HandlerFunc(answer))
+	defer proxy.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		wantErr  bool
+		wantHost string
+	}{
+		{name: "unknown CA", cfg: Config{BaseURL: server.URL}, wantErr: true},
+		{name: "custom CA", cfg: Config{BaseURL: server.URL, TLS: &tls.Config{RootCAs: roots}}, wantHost: server.Listener.Addr().String()},
+		{name: "skip verification", cfg: Config{BaseURL: server.URL, TLS: &tls.Config{InsecureSkipVerify: true}}, wantHost: server.Listener.Addr().String()},
+		{name: "proxy", cfg: Config{BaseURL: "http://llm.internal:1234", Proxy: proxyURL}, wantHost: "llm.internal:1234"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := New(tt.cfg).send(context.Background(), &chatRequest{Model: "m"})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("send() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+			if len(resp.Choices) != 1 || resp.Choices[0].Message.Content != tt.wantHost {
+				t.Errorf("send() = %+v, want it answered for %s", resp, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestHeadersAndTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Slow") != "" {
+			<-release
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"` + r.Header.Get("Authorization") + `"}}]}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	resp, err := New(Config{BaseURL: server.URL, Headers: map[string]string{"Authorization": "Bearer sk-gateway"}}).send(context.Background(), &chatRequest{Model: "m"})
+	if err != nil || resp.Choices[0].Message.Content != "Bearer sk-gateway" {
+		t.Errorf("send() = %+v, %v; want the Authorization header sent", resp, err)
+	}
+
+	l := New(Config{BaseURL: server.URL, Headers: map[string]string{"X-Slow": "1"}, Timeout: 50 * time.Millisecond})
+	if _, err := l.send(context.Background(), &chatRequest{Model: "m"}); err == nil {
+		t.Error("send() to a server slower than the timeout succeeded")
+	}
+	if New(Config{}).client.Timeout != DefaultTimeout {
+		t.Error("New() without a timeout does not use DefaultTimeout")
+	}
+}
@@ -0,0 +1,312 @@
+// Package mockdb implements the SQL agent's database client in memory, so
+// agent flows can be tested without PostgreSQL. Together with mockllm it
+// lets a test drive a whole turn from canned fixtures.
+//
+// Tables describe the schema the agents discover and hold rows for simple
+// queries; results answer every other query. Both usually come from a YAML
+// or JSON fixture file:
+//
+//	tables:
+//	  - name: orders
+//	    columns:
+//	      - {name: id, type: integer}
+//	      - {name: customer_id, type: integer}
+//	      - {name: amount, type: numeric, nullable: true}
+//	    rows:
+//	      - {id: 1, customer_id: 7, amount: 12.5}
+//	foreign_keys:
+//	  - {table: orders, columns: [customer_id], ref_table: customers, ref_columns: [id]}
+//	results:
+//	  - sql: count(*) as orders from orders     # substring of the query
+//	    rows: [{orders: 42}]
+//	  - sql: from refunds
+//	    error: relation "refunds" does not exist
+//
+// Without a matching result, "SELECT * FROM <table>" returns the table's
+// rows and "SELECT count(*) FROM <table>" counts them. Any other query
+// fails, so a test never passes on data it did not declare.
+package mockdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/anuvratrastogi/multi-agent/internal/joins"
+	"gopkg.in/yaml.v3"
+)
+
+// Column is a column of a fixture table.
+type Column struct {
+	Name     string `yaml:"name" json:"name"`
+	Type     string `yaml:"type" json:"type"`
+	Nullable bool   `yaml:"nullable" json:"nullable"`
+	Default  string `yaml:"default" json:"default"`
+}
+
+// Table is a fixture table.
+type Table struct {
+	Name    string           `yaml:"name" json:"name"`
+	Columns []Column         `yaml:"columns" json:"columns"`
+	Rows    []map[string]any `yaml:"rows" json:"rows"`
+}
+
+// ForeignKey links columns of one fixture table to another.
+type ForeignKey struct {
+	Name       string   `yaml:"name" json:"name"`
+	Table      string   `yaml:"table" json:"table"`
+	Columns    []string `yaml:"columns" json:"columns"`
+	RefTable   string   `yaml:"ref_table" json:"ref_table"`
+	RefColumns []string `yaml:"ref_columns" json:"ref_columns"`
+}
+
+// Result answers the queries that contain SQL. Matching ignores case and
+// collapses whitespace.
+type Result struct {
+	SQL string `yaml:"sql" json:"sql"`
+	// Columns orders the columns of Rows (defaults to alphabetical order)
+	Columns []string         `yaml:"columns" json:"columns"`
+	Rows    []map[string]any `yaml:"rows" json:"rows"`
+	// Error fails the query with this message instead
+	Error string `yaml:"error" json:"error"`
+}
+
+// Fixture is the layout of a fixture file.
+type Fixture struct {
+	Tables      []Table      `yaml:"tables" json:"tables"`
+	ForeignKeys []ForeignKey `yaml:"foreign_keys" json:"foreign_keys"`
+	Results     []Result     `yaml:"results" json:"results"`
+}
+
+// DB answers queries from a fixture. It is safe for concurrent use.
+type DB struct {
+	fixture Fixture
+
+	mu      sync.Mutex
+	queries []string
+}
+
+// New creates a DB answering from f.
+func New(f Fixture) *DB {
+	return &DB{fixture: f}
+}
+
+// Load creates a DB from a YAML or JSON fixture file.
+func Load(path string) (*DB, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture file: %w", err)
+	}
+
+	var f Fixture
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture file %s: %w", path, err)
+	}
+	if len(f.Tables) == 0 && len(f.Results) == 0 {
+		return nil, fmt.Errorf("fixture file %s has no tables or results", path)
+	}
+	return New(f), nil
+}
+
+// Queries returns the queries received so far, for assertions in tests.
+func (d *DB) Queries() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.queries...)
+}
+
+var (
+	spaces      = regexp.MustCompile(`\s+`)
+	selectAll   = regexp.MustCompile(`^select \* from "?(\w+)"?$`)
+	selectCount = regexp.MustCompile(`^select count\(\*\)(?: as "?(\w+)"?)? from "?(\w+)"?$`)
+)
+
+// normalize folds case, whitespace and a trailing semicolon.
+func normalize(sql string) string {
+	sql = strings.TrimSuffix(strings.TrimSpace(sql), ";")
+	return strings.ToLower(spaces.ReplaceAllString(strings.TrimSpace(sql), " "))
+}
+
+// Query implements the SQL agent's MCPClient. Results are limited to limit
+// rows, like the real client's default LIMIT. Bind parameters are ignored.
+func (d *DB) Query(ctx context.Context, query string, limit int) (string, error) {
+	d.mu.Lock()
+	d.queries = append(d.queries, query)
+	d.mu.Unlock()
+
+	q := normalize(query)
+	for _, r := range d.fixture.Results {
+		if !strings.Contains(q, normalize(r.SQL)) {
+			continue
+		}
+		if r.Error != "" {
+			return "", fmt.Errorf("query error: %s", r.Error)
+		}
+		return encodeRows(r.Columns, r.Rows, limit)
+	}
+
+	if m := selectAll.FindStringSubmatch(q); m != nil {
+		if t := d.table(m[1]); t != nil {
+			return encodeRows(t.columnNames(), t.Rows, limit)
+		}
+		return "", fmt.Errorf("query error: relation %q does not exist", m[1])
+	}
+	if m := selectCount.FindStringSubmatch(q); m != nil {
+		if t := d.table(m[2]); t != nil {
+			name := m[1]
+			if name == "" {
+				name = "count"
+			}
+			return encodeRows([]string{name}, []map[string]any{{name: len(t.Rows)}}, limit)
+		}
+		return "", fmt.Errorf("query error: relation %q does not exist", m[2])
+	}
+	return "", fmt.Errorf("mockdb: no result matches query %q", query)
+}
+
+// GetSchema implements the SQL agent's MCPClient. Unknown tables have a
+// null schema, as with the real client.
+func (d *DB) GetSchema(ctx context.Context, tableName string) (string, error) {
+	var schema []map[string]any
+	if t := d.table(tableName); t != nil {
+		for _, c := range t.Columns {
+			col := map[string]any{
+				"column_name": c.Name,
+				"data_type":   c.Type,
+				"nullable":    c.Nullable,
+			}
+			if c.Default != "" {
+				col["default"] = c.Default
+			}
+			schema = append(schema, col)
+		}
+	}
+	return marshal(schema)
+}
+
+// ListTables implements the SQL agent's MCPClient.
+func (d *DB) ListTables(ctx context.Context) (string, error) {
+	var tables []string
+	for _, t := range d.fixture.Tables {
+		tables = append(tables, t.Name)
+	}
+	slices.Sort(tables)
+	return marshal(tables)
+}
+
+// DescribeDatabase implements the SQL agent's MCPClient.
+func (d *DB) DescribeDatabase(ctx context.Context) (string, error) {
+	var tables []map[string]any
+	for _, t := range d.sortedTables() {
+		columns := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			columns[i] = c.Name + " " + c.Type
+		}
+		tables = append(tables, map[string]any{
+			"table":   t.Name,
+			"columns": columns,
+		})
+	}
+	return marshal(tables)
+}
+
+// ForeignKeys implements the SQL agent's MCPClient.
+func (d *DB) ForeignKeys(ctx context.Context) ([]joins.ForeignKey, error) {
+	var keys []joins.ForeignKey
+	for _, k := range d.fixture.ForeignKeys {
+		name := k.Name
+		if name == "" {
+			name = k.Table + "_" + strings.Join(k.Columns, "_") + "_fkey"
+		}
+		keys = append(keys, joins.ForeignKey{
+			Name:       name,
+			Table:      k.Table,
+			Columns:    k.Columns,
+			RefTable:   k.RefTable,
+			RefColumns: k.RefColumns,
+		})
+	}
+	return keys, nil
+}
+
+// table returns the fixture table with the given name, or nil.
+func (d *DB) table(name string) *Table {
+	for i := range d.fixture.Tables {
+		if strings.EqualFold(d.fixture.Tables[i].Name, name) {
+			return &d.fixture.Tables[i]
+		}
+	}
+	return nil
+}
+
+func (d *DB) sortedTables() []Table {
+	tables := slices.Clone(d.fixture.Tables)
+	slices.SortFunc(tables, func(a, b Table) int { return strings.Compare(a.Name, b.Name) })
+	return tables
+}
+
+func (t *Table) columnNames() []string {
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// encodeRows writes at most limit rows as a JSON array of objects, with
+// their keys in the order of columns.
+func encodeRows(columns []string, rows []map[string]any, limit int) (string, error) {
+	if limit > 0 && len(rows) > limit {
+		rows = rows[:limit]
+	}
+	if len(columns) == 0 {
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			for k := range row {
+				if !seen[k] {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+		}
+		slices.Sort(columns)
+	}
+
+	var out strings.Builder
+	out.WriteByte('[')
+	for i, row := range rows {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		out.WriteByte('{')
+		for j, col := range columns {
+			if j > 0 {
+				out.WriteByte(',')
+			}
+			key, _ := json.Marshal(col)
+			value, err := json.Marshal(row[col])
+			if err != nil {
+				return "", fmt.Errorf("json error: %w", err)
+			}
+			out.Write(key)
+			out.WriteByte(':')
+			out.Write(value)
+		}
+		out.WriteByte('}')
+	}
+	out.WriteByte(']')
+	return out.String(), nil
+}
+
+func marshal(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("json error: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,104 @@
+package mockdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	db, err := Load("testdata/store.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		query   string
+		limit   int
+		want    string
+		wantErr string
+	}{
+		{name: "result", query: "SELECT SUM(amount)  AS revenue\nFROM orders;", want: `[{"revenue":42.5}]`},
+		{name: "result error", query: "SELECT * FROM refunds", wantErr: `relation "refunds" does not exist`},
+		{name: "table rows in column order", query: "select * from customers", want: `[{"id":1,"name":"Ada"},{"id":2,"name":"Grace"}]`},
+		{name: "limit", query: "SELECT * FROM orders", limit: 1, want: `[{"id":1,"customer_id":1,"amount":12.5,"status":"shipped"}]`},
+		{name: "count", query: "SELECT count(*) AS orders FROM orders", want: `[{"orders":3}]`},
+		{name: "unknown table", query: "SELECT count(*) FROM payments", wantErr: `relation "payments" does not exist`},
+		{name: "unmatched query", query: "SELECT name FROM customers WHERE id = 1", wantErr: "no result matches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.Query(ctx, tt.query, tt.limit)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Query() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Query() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := len(db.Queries()); got != len(tests) {
+		t.Errorf("Queries() recorded %d queries, want %d", got, len(tests))
+	}
+}
+
+func TestSchema(t *testing.T) {
+	db, err := Load("testdata/store.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() (string, error)
+		want string
+	}{
+		{
+			name: "list tables",
+			call: func() (string, error) { return db.ListTables(ctx) },
+			want: `["customers","orders"]`,
+		},
+		{
+			name: "table schema",
+			call: func() (string, error) { return db.GetSchema(ctx, "customers") },
+			want: `[{"column_name":"id","data_type":"integer","nullable":false},{"column_name":"name","data_type":"text","nullable":false}]`,
+		},
+		{
+			name: "unknown table schema",
+			call: func() (string, error) { return db.GetSchema(ctx, "payments") },
+			want: `null`,
+		},
+		{
+			name: "describe database",
+			call: func() (string, error) { return db.DescribeDatabase(ctx) },
+			want: `[{"columns":["id integer","name text"],"table":"customers"},{"columns":["id integer","customer_id integer","amount numeric","status text"],"table":"orders"}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call()
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	keys, err := db.ForeignKeys(ctx)
+	if err != nil || len(keys) != 1 || keys[0].String() != "orders(customer_id) -> customers(id)" || keys[0].Name != "orders_customer_id_fkey" {
+		t.Errorf("ForeignKeys() = %v, %v", keys, err)
+	}
+}
@@ -0,0 +1,217 @@
+// Package mockllm implements model.LLM by replaying canned responses, so the
+// agents, manager routing and front-ends can be exercised without a model.
+//
+// Responses come from rules, usually loaded from a YAML or JSON fixture
+// file. The first rule whose conditions all match a request answers it:
+//
+//	rules:
+//	  - agent: manager agent            # substring of the system instruction
+//	    respond:
+//	      calls: [{name: transfer_to_agent, args: {agent_name: SQLAgent}}]
+//	  - agent: SQL expert
+//	    after: query_database           # the last message is this tool's result
+//	    respond:
+//	      text: There were 42 orders last month.
+//	  - agent: SQL expert
+//	    user: how many orders           # substring of the user's question
+//	    respond:
+//	      calls: [{name: query_database, args: {sql: "SELECT count(*) FROM orders"}}]
+package mockllm
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+	"gopkg.in/yaml.v3"
+)
+
+// Rule answers the requests that match all of its conditions. Conditions
+// compare case-insensitively; empty conditions match every request.
+type Rule struct {
+	// Agent is a substring of the system instruction, which identifies the
+	// agent making the request
+	Agent string `yaml:"agent" json:"agent"`
+	// User is a substring of the latest question from the user
+	User string `yaml:"user" json:"user"`
+	// After is the name of the tool whose result ends the conversation
+	After string `yaml:"after" json:"after"`
+	// Times limits how often the rule answers (0 = unlimited)
+	Times int `yaml:"times" json:"times"`
+	// Respond is the canned response
+	Respond Response `yaml:"respond" json:"respond"`
+}
+
+// Response is a canned model response.
+type Response struct {
+	// Reasoning is emitted as a thought part before the text
+	Reasoning string `yaml:"reasoning" json:"reasoning"`
+	Text      string `yaml:"text" json:"text"`
+	Calls     []Call `yaml:"calls" json:"calls"`
+	// Error fails the request with this message instead of responding
+	Error string `yaml:"error" json:"error"`
+}
+
+// Call is a tool call made by the model.
+type Call struct {
+	Name string         `yaml:"name" json:"name"`
+	Args map[string]any `yaml:"args" json:"args"`
+}
+
+// Fixture is the layout of a fixture file.
+type Fixture struct {
+	Rules []Rule `yaml:"rules" json:"rules"`
+}
+
+// LLM replays the responses of its rules.
+type LLM struct {
+	name  string
+	rules []Rule
+
+	mu       sync.Mutex
+	used     []int
+	requests []*model.LLMRequest
+}
+
+// New creates an LLM answering from rules.
+func New(rules ...Rule) *LLM {
+	return &LLM{name: "mock", rules: rules, used: make([]int, len(rules))}
+}
+
+// Load creates an LLM from a YAML or JSON fixture file.
+func Load(path string) (*LLM, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture file: %w", err)
+	}
+
+	var f Fixture
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture file %s: %w", path, err)
+	}
+	if len(f.Rules) == 0 {
+		return nil, fmt.Errorf("fixture file %s has no rules", path)
+	}
+	return New(f.Rules...), nil
+}
+
+// Name implements model.LLM.
+func (m *LLM) Name() string {
+	return m.name
+}
+
+// Requests returns the requests received so far, for assertions in tests.
+func (m *LLM) Requests() []*model.LLMRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*model.LLMRequest(nil), m.requests...)
+}
+
+// GenerateContent implements model.LLM. Requests that no rule matches fail.
+func (m *LLM) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		rule, err := m.match(req)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if rule.Respond.Error != "" {
+			yield(nil, fmt.Errorf("%s", rule.Respond.Error))
+			return
+		}
+
+		var parts []*genai.Part
+		if rule.Respond.Reasoning != "" {
+			parts = append(parts, &genai.Part{Text: rule.Respond.Reasoning, Thought: true})
+		}
+		if rule.Respond.Text != "" {
+			parts = append(parts, genai.NewPartFromText(rule.Respond.Text))
+		}
+		for _, call := range rule.Respond.Calls {
+			parts = append(parts, genai.NewPartFromFunctionCall(call.Name, call.Args))
+		}
+		yield(&model.LLMResponse{
+			Content:      &genai.Content{Role: genai.RoleModel, Parts: parts},
+			TurnComplete: true,
+		}, nil)
+	}
+}
+
+// match records req and returns the first rule that answers it.
+func (m *LLM) match(req *model.LLMRequest) (*Rule, error) {
+	system := strings.ToLower(systemText(req))
+	user := strings.ToLower(lastUserText(req))
+	after := strings.ToLower(lastToolResult(req))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, req)
+
+	for i := range m.rules {
+		r := &m.rules[i]
+		if r.Times > 0 && m.used[i] >= r.Times {
+			continue
+		}
+		if !strings.Contains(system, strings.ToLower(r.Agent)) || !strings.Contains(user, strings.ToLower(r.User)) {
+			continue
+		}
+		if r.After != "" && after != strings.ToLower(r.After) {
+			continue
+		}
+		m.used[i]++
+		return r, nil
+	}
+	return nil, fmt.Errorf("mockllm: no rule matches the request (question %q, last tool %q)", lastUserText(req), lastToolResult(req))
+}
+
+func systemText(req *model.LLMRequest) string {
+	if req.Config == nil || req.Config.SystemInstruction == nil {
+		return ""
+	}
+	var text strings.Builder
+	for _, part := range req.Config.SystemInstruction.Parts {
+		text.WriteString(part.Text)
+	}
+	return text.String()
+}
+
+// lastUserText returns the latest text written by the user. Messages that
+// relay other agents' output ("For context: ...") are skipped.
+func lastUserText(req *model.LLMRequest) string {
+	for i := len(req.Contents) - 1; i >= 0; i-- {
+		c := req.Contents[i]
+		if c == nil || c.Role != genai.RoleUser || len(c.Parts) == 0 || c.Parts[0].Text == "For context:" {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range c.Parts {
+			text.WriteString(part.Text)
+		}
+		if text.Len() > 0 {
+			return text.String()
+		}
+	}
+	return ""
+}
+
+// lastToolResult returns the tool whose result is the last message, or "".
+func lastToolResult(req *model.LLMRequest) string {
+	if len(req.Contents) == 0 {
+		return ""
+	}
+	last := req.Contents[len(req.Contents)-1]
+	if last == nil {
+		return ""
+	}
+	for _, part := range last.Parts {
+		if part.FunctionResponse != nil {
+			return part.FunctionResponse.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package mockllm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/internal/agents/chart"
+	"github.com/anuvratrastogi/multi-agent/internal/agents/manager"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// ordersDB answers every query with a single count.
+type ordersDB struct {
+	sqlagent.MCPClient
+	queries []string
+}
+
+func (db *ordersDB) Query(ctx context.Context, query string, limit int) (string, error) {
+	db.queries = append(db.queries, query)
+	return `[{"orders":42}]`, nil
+}
+
+func TestMatch(t *testing.T) {
+	m := mockllm.New(
+		mockllm.Rule{Agent: "sql", After: "query_database", Respond: mockllm.Response{Text: "done"}},
+		mockllm.Rule{User: "orders", Times: 1, Respond: mockllm.Response{Text: "first"}},
+		mockllm.Rule{Respond: mockllm.Response{Error: "no answer"}},
+	)
+
+	sqlSystem := &genai.GenerateContentConfig{SystemInstruction: genai.NewContentFromText("You are a SQL expert", genai.RoleUser)}
+	question := genai.NewContentFromText("How many ORDERS?", genai.RoleUser)
+	toolResult := &genai.Content{Role: genai.RoleUser, Parts: []*genai.Part{
+		genai.NewPartFromFunctionResponse("query_database", map[string]any{"data": "[]"}),
+	}}
+
+	tests := []struct {
+		name     string
+		req      *model.LLMRequest
+		wantText string
+		wantErr  string
+	}{
+		{"user substring", &model.LLMRequest{Contents: []*genai.Content{question}}, "first", ""},
+		{"times exhausted", &model.LLMRequest{Contents: []*genai.Content{question}}, "", "no answer"},
+		{"after tool result", &model.LLMRequest{Contents: []*genai.Content{question, toolResult}, Config: sqlSystem}, "done", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for resp, err := range m.GenerateContent(context.Background(), tt.req, false) {
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("GenerateContent() error = %v, want %q", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("GenerateContent() error = %v", err)
+				}
+				if got := resp.Content.Parts[0].Text; got != tt.wantText {
+					t.Errorf("GenerateContent() = %q, want %q", got, tt.wantText)
+				}
+			}
+		})
+	}
+	if len(m.Requests()) != len(tests) {
+		t.Errorf("Requests() = %d, want %d", len(m.Requests()), len(tests))
+	}
+}
+
+func TestFixtureDrivesAgents(t *testing.T) {
+	llm, err := mockllm.Load("testdata/orders.yaml")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	db := &ordersDB{}
+
+	tools, err := sqlagent.CreateMCPTools(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlAgent, err := sqlagent.New(sqlagent.Config{Model: llm, Tools: tools})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chartAgent, err := chart.New(chart.Config{Model: llm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := manager.New(manager.Config{Model: llm, SQLAgent: sqlAgent, ChartAgent: chartAgent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := app.New(app.Config{Manager: mgr, KeepReasoning: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	turn, err := a.Ask(context.Background(), "u", "s", "How many orders are there?", nil)
+	if err != nil {
+		t.Fatalf("Ask() error = %v", err)
+	}
+	if !strings.Contains(turn.Text, "42 orders") {
+		t.Errorf("Ask() text = %q, want the fixture's answer", turn.Text)
+	}
+	if len(db.queries) != 1 || len(turn.Queries) != 1 || turn.Queries[0].Data != `[{"orders":42}]` {
+		t.Errorf("queries = %v, turn queries = %+v", db.queries, turn.Queries)
+	}
+	if strings.Contains(turn.Text, "single row") || len(turn.Reasoning) != 1 || turn.Reasoning[0].Agent != "SQLAgent" {
+		t.Errorf("text = %q, reasoning = %+v, want the reasoning kept apart", turn.Text, turn.Reasoning)
+	}
+}
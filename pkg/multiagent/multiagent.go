@@ -0,0 +1,168 @@
+// Package multiagent embeds the multi-agent system in a Go program: the
+// Manager, SQL and Chart agents wired to a model and database exactly as
+// the command wires them, without the REPL, servers or bots.
+//
+//	sys, err := multiagent.New(ctx, multiagent.Config{Settings: config.New()})
+//	if err != nil {
+//		return err
+//	}
+//	defer sys.Close()
+//	res, err := sys.Ask(ctx, "report-1", "How many orders are there per month?")
+//
+// Settings take the same options as the command's environment variables
+// and config file. Model and DB replace the configured model and database,
+// e.g. with pkg/mockllm and pkg/mockdb in tests.
+package multiagent
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	sqlagent "github.com/anuvratrastogi/multi-agent/internal/agents/sql"
+	"github.com/anuvratrastogi/multi-agent/internal/app"
+	"github.com/anuvratrastogi/multi-agent/internal/console"
+	"github.com/anuvratrastogi/multi-agent/internal/wiring"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+)
+
+const (
+	// DefaultUserID owns the sessions of a System unless Config.UserID is set.
+	DefaultUserID = "multiagent"
+	// DefaultSessionID is used when Ask or Stream get an empty session ID.
+	DefaultSessionID = "default"
+)
+
+// Database is the client the agents query. pkg/mockdb implements it for
+// tests; by default the System connects to Settings.DatabaseURL.
+type Database = sqlagent.MCPClient
+
+// Config configures a System.
+type Config struct {
+	// Settings configure the model, database, agents and limits (nil reads
+	// the environment and config file, like the command)
+	Settings *config.Config
+	// Model replaces the model described by Settings (optional)
+	Model model.LLM
+	// DB replaces the PostgreSQL connection to Settings.DatabaseURL (optional)
+	DB Database
+	// SessionService stores conversations (default: in memory)
+	SessionService session.Service
+	// UserID owns the System's sessions (default: DefaultUserID)
+	UserID string
+	// Verbose prints startup and agent progress to stdout, as the command
+	// does. Console output is process-wide, so it is discarded otherwise.
+	Verbose bool
+}
+
+// System answers questions with the agents.
+type System struct {
+	sys    *wiring.System
+	userID string
+
+	// mu serializes turns of the same session
+	mu       sync.Mutex
+	sessions map[string]*sync.Mutex
+}
+
+// New starts a System: it connects to the model and database, loads the
+// schema and creates the agents.
+func New(ctx context.Context, cfg Config) (*System, error) {
+	settings := cfg.Settings
+	if settings == nil {
+		settings = config.New()
+	}
+	validate := settings.Validate
+	if cfg.Model != nil || cfg.DB != nil {
+		validate = settings.ValidateSettings
+	}
+	if err := validate(); err != nil {
+		return nil, err
+	}
+	if !cfg.Verbose {
+		console.Out = io.Discard
+	}
+
+	sys, err := wiring.Build(ctx, settings, wiring.Options{
+		Model:          cfg.Model,
+		DB:             cfg.DB,
+		SessionService: cfg.SessionService,
+	})
+	if err != nil {
+		return nil, err
+	}
+	userID := cfg.UserID
+	if userID == "" {
+		userID = DefaultUserID
+	}
+	return &System{sys: sys, userID: userID, sessions: make(map[string]*sync.Mutex)}, nil
+}
+
+// Close releases the database connection and closes the logs.
+func (s *System) Close() error {
+	return s.sys.Close()
+}
+
+// Ask answers a question in a session, which remembers the questions
+// asked before it. Sessions are created on first use.
+func (s *System) Ask(ctx context.Context, sessionID, query string) (Result, error) {
+	return s.Stream(ctx, sessionID, query, nil)
+}
+
+// Stream answers a question like Ask, reporting tool calls, agent text and
+// query progress to onEvent as they happen. onEvent is never called
+// concurrently.
+func (s *System) Stream(ctx context.Context, sessionID, query string, onEvent func(Event)) (Result, error) {
+	if sessionID == "" {
+		sessionID = DefaultSessionID
+	}
+	lock := s.sessionLock(sessionID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	var handler app.EventHandler
+	if onEvent != nil {
+		var mu sync.Mutex
+		emit := func(e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			onEvent(e)
+		}
+		ctx = sqlagent.WithRowHandler(ctx, func(batch sqlagent.RowBatch) {
+			emit(Event{Type: EventRows, SQL: batch.SQL, Rows: batch.Total})
+		})
+		handler = func(event *session.Event) {
+			if event.LLMResponse.Content == nil {
+				return
+			}
+			for _, part := range event.LLMResponse.Content.Parts {
+				if part.FunctionCall != nil {
+					emit(Event{Type: EventToolCall, Agent: event.Author, Tool: part.FunctionCall.Name})
+				}
+				if part.Text != "" && !part.Thought {
+					emit(Event{Type: EventText, Agent: event.Author, Text: part.Text})
+				}
+			}
+		}
+	}
+
+	turn, err := s.sys.App.Ask(ctx, s.userID, sessionID, query, handler)
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(turn), nil
+}
+
+// sessionLock returns the mutex serializing the turns of a session.
+func (s *System) sessionLock(sessionID string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.sessions[sessionID]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.sessions[sessionID] = lock
+	}
+	return lock
+}
@@ -0,0 +1,81 @@
+package multiagent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/anuvratrastogi/multi-agent/config"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockdb"
+	"github.com/anuvratrastogi/multi-agent/pkg/mockllm"
+)
+
+func newSystem(t *testing.T) *System {
+	t.Helper()
+	llm := mockllm.New(
+		mockllm.Rule{Agent: "manager agent", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "transfer_to_agent", Args: map[string]any{"agent_name": "SQLAgent"}}}}},
+		mockllm.Rule{After: "query_database", Respond: mockllm.Response{Text: "There are 2 orders."}},
+		mockllm.Rule{User: "orders", Respond: mockllm.Response{Calls: []mockllm.Call{{Name: "query_database", Args: map[string]any{"sql": "SELECT count(*) FROM orders"}}}}},
+	)
+	db := mockdb.New(mockdb.Fixture{Tables: []mockdb.Table{{
+		Name:    "orders",
+		Columns: []mockdb.Column{{Name: "id", Type: "integer"}},
+		Rows:    []map[string]any{{"id": 1}, {"id": 2}},
+	}}})
+
+	sys, err := New(context.Background(), Config{Settings: config.NewFromFile(""), Model: llm, DB: db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sys.Close() })
+	return sys
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	_, err := New(context.Background(), Config{Settings: config.NewFromFile("")})
+	if !errors.Is(err, config.ErrMissingDatabaseURL) {
+		t.Errorf("err = %v, want %v", err, config.ErrMissingDatabaseURL)
+	}
+}
+
+func TestAsk(t *testing.T) {
+	sys := newSystem(t)
+
+	res, err := sys.Ask(context.Background(), "s1", "How many orders are there?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res.Text, "2 orders") || res.Intent == "" || len(res.Agents) == 0 {
+		t.Errorf("result = %+v", res)
+	}
+	if len(res.Queries) != 1 || res.Queries[0].Data != `[{"count":2}]` {
+		t.Errorf("queries = %+v", res.Queries)
+	}
+}
+
+func TestStream(t *testing.T) {
+	sys := newSystem(t)
+
+	var tools []string
+	var text bool
+	res, err := sys.Stream(context.Background(), "", "How many orders are there?", func(e Event) {
+		switch e.Type {
+		case EventToolCall:
+			tools = append(tools, e.Tool)
+		case EventText:
+			text = true
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(tools, "query_database") || !text {
+		t.Errorf("tools = %v, text = %v", tools, text)
+	}
+	if !strings.Contains(res.Text, "2 orders") {
+		t.Errorf("text = %q", res.Text)
+	}
+}
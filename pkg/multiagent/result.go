@@ -0,0 +1,67 @@
+package multiagent
+
+import "github.com/anuvratrastogi/multi-agent/internal/app"
+
+// Result is the answer to a question.
+type Result struct {
+	// Intent is the classified intent of the question, e.g. "sql_query".
+	Intent     string
+	Confidence float64
+	// Agents lists the agents the question was routed to.
+	Agents []string
+	// Text is the agents' narrative.
+	Text string
+	// Queries holds every query run and its result.
+	Queries []Query
+	// Charts holds Mermaid chart definitions.
+	Charts []string
+	// Cached is set if the answer was reused from a similar question.
+	Cached bool
+	// Stopped says which turn budget limit cut the answer short.
+	Stopped string
+}
+
+// Query is a query run while answering.
+type Query struct {
+	SQL string
+	// Data holds the rows as a JSON array of objects.
+	Data  string
+	Error string
+}
+
+// Event types reported by Stream.
+const (
+	EventToolCall = "tool_call"
+	EventText     = "text"
+	EventRows     = "rows"
+)
+
+// Event is a step of a streamed answer.
+type Event struct {
+	Type string
+	// Agent is the agent that called Tool or wrote Text.
+	Agent string
+	Tool  string
+	Text  string
+	// SQL and Rows report a running query: the rows read so far.
+	SQL  string
+	Rows int
+}
+
+func newResult(turn *app.Turn) Result {
+	res := Result{
+		Text:    turn.Text,
+		Charts:  turn.Charts,
+		Cached:  turn.Cached != nil,
+		Stopped: turn.Stopped,
+	}
+	if turn.Routing != nil {
+		res.Intent = turn.Routing.ClassifiedIntent
+		res.Confidence = turn.Routing.Confidence
+		res.Agents = turn.Routing.AgentsUsed
+	}
+	for _, q := range turn.Queries {
+		res.Queries = append(res.Queries, Query{SQL: q.SQL, Data: q.Data, Error: q.Error})
+	}
+	return res
+}